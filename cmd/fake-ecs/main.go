@@ -0,0 +1,57 @@
+// fake-ecs serves the in-memory ECS simulator of pkg/cloud/fake over HTTP,
+// e.g. to run csi-sanity against the disk plugin without a cloud account.
+// Start the plugin with ECS_ENDPOINT=<listen address> and ALICLOUD_CLIENT_SCHEME=HTTP.
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/fake"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+)
+
+func main() {
+	var (
+		listen          string
+		region          string
+		zones           []string
+		instances       []string
+		latency         time.Duration
+		transitionDelay time.Duration
+	)
+	flag.StringVar(&listen, "listen", "127.0.0.1:8080", "address to serve the ECS OpenAPIs on")
+	flag.StringVar(&region, "region", "cn-hangzhou", "region ID")
+	flag.StringArrayVar(&zones, "zone", nil, "zone and its disk categories, e.g. cn-hangzhou-a=cloud_essd,cloud_auto. Can be repeated")
+	flag.StringArrayVar(&instances, "instance", nil, "instance and its zone, e.g. i-1=cn-hangzhou-a. Can be repeated")
+	flag.DurationVar(&latency, "latency", 0, "latency added to every call")
+	flag.DurationVar(&transitionDelay, "transition-delay", time.Second, "how long disks and snapshots stay in intermediate status")
+	utils.AddKlogFlags(flag.CommandLine)
+	flag.Parse()
+
+	c := fake.New(fake.Options{
+		RegionID:        region,
+		Latency:         latency,
+		TransitionDelay: transitionDelay,
+	})
+	for _, z := range zones {
+		zone, categories, _ := strings.Cut(z, "=")
+		c.AddZone(zone, strings.Split(categories, ",")...)
+	}
+	for _, i := range instances {
+		instance, zone, ok := strings.Cut(i, "=")
+		if !ok {
+			klog.Fatalf("invalid --instance %q, expecting <instance ID>=<zone ID>", i)
+		}
+		c.AddInstance(instance, zone)
+	}
+
+	klog.InfoS("Serving fake ECS", "address", listen, "region", region)
+	if err := http.ListenAndServe(listen, c); err != nil {
+		klog.Fatalf("serve fake ECS: %v", err)
+	}
+}
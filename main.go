@@ -32,6 +32,7 @@ import (
 	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v7/client"
 	sts20150401 "github.com/alibabacloud-go/sts-20150401/v2/client"
 	alicred_old "github.com/aliyun/credentials-go/credentials"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/audit"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/bmcpfs"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/credentials"
@@ -45,10 +46,12 @@ import (
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/options"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/oss"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/pov"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/tracing"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/version"
 	"github.com/sirupsen/logrus"
 	flag "github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/attribute"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/component-base/logs"
@@ -137,6 +140,13 @@ func main() {
 	klog.Infof("Multi CSI Driver Name: %s, nodeID: %s, endPoints: %s", *driver, *nodeID, *endpoint)
 	klog.Infof("CSI Driver, Version: %s, Release time: %s", version.VERSION, version.GetTime())
 
+	tracing.InitFromEnv(context.Background(),
+		attribute.String("service.version", version.VERSION),
+		attribute.String("k8s.node.name", os.Getenv("KUBE_NODE_NAME")))
+	if err := audit.InitFromEnv(utils.NewEventRecorder); err != nil {
+		klog.Fatalf("Failed to init audit log: %v", err)
+	}
+
 	multiDriverNames := *driver
 	driverNames := strings.Split(multiDriverNames, ",")
 	var wg sync.WaitGroup
@@ -146,19 +156,27 @@ func main() {
 
 	// initialize node metadata
 	meta := metadata.NewMetadata()
-	meta.EnableEcs(http.DefaultTransport)
 
+	var kubeClient kubernetes.Interface
 	cfg, err := options.GetRestConfig()
 	if err != nil {
 		klog.Warningf("newGlobalConfig: build kubeconfig failed: %v", err)
 	} else {
-		kubeClient, err := kubernetes.NewForConfig(cfg)
+		kubeClient, err = kubernetes.NewForConfig(cfg)
 		if err != nil {
 			klog.Warningf("Error building kubernetes clientset: %v", err)
-		} else {
-			meta.EnableKubernetes(kubeClient)
+			kubeClient = nil
 		}
 	}
+	meta.EnableEcs(http.DefaultTransport)
+	if kubeClient != nil {
+		meta.EnableDeclarative(kubeClient)
+		meta.EnableKubernetes(kubeClient)
+	}
+	externalNode := metadata.IsExternalNode(meta)
+	if externalNode {
+		klog.Info("Running on external node, ECS-only features are disabled")
+	}
 
 	regionID, err := meta.Get(metadata.RegionID)
 	if err != nil {
@@ -201,6 +219,7 @@ func main() {
 	}
 
 	csiCfg := getCSIPluginConfig()
+	features.WatchConfig(utils.CSIPluginConfig)
 
 	for _, driverName := range driverNames {
 		wg.Add(1)
@@ -221,6 +240,12 @@ func main() {
 				driver.Run()
 			}(endPointName)
 		case TypePluginDISK:
+			if externalNode && serviceType&utils.Node != 0 {
+				// disks can only be attached to ECS instances
+				klog.Errorf("%s is not supported on external node, skipped", driverName)
+				wg.Done()
+				continue
+			}
 			go func(endPoint string) {
 				defer wg.Done()
 				driver := disk.NewDriver(meta, endPoint, serviceType, csiCfg)
@@ -281,6 +306,8 @@ func main() {
 	csiMux := http.NewServeMux()
 	csiMux.HandleFunc("/healthz", healthHandler)
 	klog.Infof("Healthz listening on address: /healthz")
+	csiMux.Handle("/debug/config", utils.CSIPluginConfig)
+	klog.Infof("Effective config on address: /debug/config")
 	if enableMetric {
 		metricHandler := metric.NewMetricHandler(driverNames, serviceType)
 		csiMux.Handle("/metrics", metricHandler)
@@ -326,12 +353,16 @@ func getCSIPluginConfig() (config utils.Config) {
 		}
 	}
 
-	cm, err := client.CoreV1().ConfigMaps("kube-system").Get(context.Background(), "csi-plugin", metav1.GetOptions{})
+	// Follow later changes. Consumers subscribe to utils.CSIPluginConfig for values that can be reloaded.
+	defer func() { go utils.CSIPluginConfig.Run(context.Background(), client) }()
+
+	cm, err := client.CoreV1().ConfigMaps(utils.CSIPluginConfigMapNamespace).Get(context.Background(), utils.CSIPluginConfigMapName, metav1.GetOptions{})
 	if err != nil {
 		klog.ErrorS(err, "failed to get config map csi-plugin")
 		return
 	}
 
 	config.ConfigMap = cm.Data
+	utils.CSIPluginConfig.Update(cm.Data)
 	return
 }
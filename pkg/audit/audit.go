@@ -0,0 +1,168 @@
+// Package audit records every cloud-side mutation made by the driver,
+// e.g. creating, attaching, resizing or deleting disks, NAS directories and filesets.
+//
+// Records are written by pkg/cloud/wrap, so every OpenAPI call made through it is audited.
+// Identity of the CSI request is attached to the context by UnaryServerInterceptor.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+)
+
+const (
+	OutcomeSuccess = "Success"
+	OutcomeFailure = "Failure"
+)
+
+// Record is one cloud-side mutation.
+type Record struct {
+	Time time.Time `json:"time"`
+	// Method is the CSI method triggered this mutation, if any.
+	Method       string `json:"method,omitempty"`
+	VolumeID     string `json:"volumeID,omitempty"`
+	PVName       string `json:"pvName,omitempty"`
+	PVCNamespace string `json:"pvcNamespace,omitempty"`
+	PVCName      string `json:"pvcName,omitempty"`
+
+	Product    string            `json:"product"`
+	Action     string            `json:"action"`
+	RequestID  string            `json:"requestID,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	Outcome   string `json:"outcome"`
+	ErrorCode string `json:"errorCode,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Sink receives audit records. Write must be safe for concurrent use and should not block for long.
+type Sink interface {
+	Write(r *Record)
+}
+
+var (
+	sinksLock sync.RWMutex
+	sinks     []Sink
+)
+
+// SetSinks replaces all the sinks. Auditing is disabled if no sink is set.
+func SetSinks(s ...Sink) {
+	sinksLock.Lock()
+	defer sinksLock.Unlock()
+	sinks = s
+}
+
+func currentSinks() []Sink {
+	sinksLock.RLock()
+	defer sinksLock.RUnlock()
+	return sinks
+}
+
+// mutationPrefixes are the prefixes of OpenAPI actions that change cloud resources.
+var mutationPrefixes = []string{
+	"Create", "Delete", "Attach", "Detach", "Resize", "Modify", "Set", "Cancel",
+	"Tag", "Untag", "Add", "Remove", "Apply", "Reset", "Reinit", "Enable", "Disable",
+}
+
+// IsMutation reports whether the OpenAPI action changes cloud resources.
+func IsMutation(action string) bool {
+	for _, p := range mutationPrefixes {
+		if strings.HasPrefix(action, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Call describes an OpenAPI call to be audited.
+type Call struct {
+	Product   string
+	Action    string
+	Request   any
+	RequestID string
+	ErrorCode string
+	Err       error
+}
+
+// Log writes c to all sinks, if c is a mutation.
+func Log(ctx context.Context, c Call) {
+	s := currentSinks()
+	if len(s) == 0 || !IsMutation(c.Action) {
+		return
+	}
+	id := identityFromContext(ctx)
+	r := &Record{
+		Time:         time.Now(),
+		Method:       id.Method,
+		VolumeID:     id.VolumeID,
+		PVName:       id.PVName,
+		PVCNamespace: id.PVCNamespace,
+		PVCName:      id.PVCName,
+		Product:      c.Product,
+		Action:       c.Action,
+		RequestID:    c.RequestID,
+		Parameters:   parameters(c.Request),
+		Outcome:      OutcomeSuccess,
+	}
+	if c.Err != nil {
+		r.Outcome = OutcomeFailure
+		r.ErrorCode = c.ErrorCode
+		r.Error = c.Err.Error()
+	}
+	for _, sink := range s {
+		sink.Write(r)
+	}
+}
+
+// Signature and SignatureNonce are filled into the query of SDK v1 requests when sent.
+var secretKey = regexp.MustCompile(`(?i)password|secret|accesskey|securitytoken|credential|signature`)
+
+const redacted = "[REDACTED]"
+
+// parameters flattens the request into a map, with secrets stripped.
+func parameters(req any) map[string]string {
+	params := map[string]string{}
+	if r, ok := req.(requests.AcsRequest); ok {
+		// Params are filled by the SDK when sending. Fill them here in case the request was never sent.
+		if err := requests.InitParams(r); err != nil {
+			return map[string]string{"error": fmt.Sprintf("failed to init params: %v", err)}
+		}
+		for _, m := range []map[string]string{r.GetQueryParams(), r.GetFormParams()} {
+			for k, v := range m {
+				params[k] = v
+			}
+		}
+	} else if req != nil {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return map[string]string{"error": fmt.Sprintf("failed to marshal request: %v", err)}
+		}
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return map[string]string{"request": string(data)}
+		}
+		for k, v := range fields {
+			var s string
+			switch {
+			case string(v) == "null":
+			case json.Unmarshal(v, &s) == nil:
+				params[k] = s
+			default:
+				params[k] = string(v)
+			}
+		}
+	}
+	for k := range params {
+		if secretKey.MatchString(k) {
+			params[k] = redacted
+		}
+	}
+	return params
+}
@@ -0,0 +1,207 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"k8s.io/client-go/tools/record"
+)
+
+type memorySink struct {
+	records []*Record
+}
+
+func (s *memorySink) Write(r *Record) {
+	s.records = append(s.records, r)
+}
+
+func TestIsMutation(t *testing.T) {
+	assert.True(t, IsMutation("CreateDisk"))
+	assert.True(t, IsMutation("AttachVscToFilesystems"))
+	assert.True(t, IsMutation("ResizeDisk"))
+	assert.False(t, IsMutation("DescribeDisks"))
+	assert.False(t, IsMutation("ListTagResources"))
+}
+
+func TestParametersV1(t *testing.T) {
+	req := ecs.CreateCreateDiskRequest()
+	req.DiskName = "pv-1"
+	req.Size = "20"
+	req.KMSKeyId = "key-1"
+	req.QueryParams["AccessKeyId"] = "ak"
+	req.QueryParams["Signature"] = "sig"
+	req.QueryParams["SignatureNonce"] = "nonce"
+
+	params := parameters(req)
+	assert.Equal(t, "pv-1", params["DiskName"])
+	assert.Equal(t, "20", params["Size"])
+	assert.Equal(t, "key-1", params["KMSKeyId"])
+	assert.Equal(t, redacted, params["AccessKeyId"])
+	assert.Equal(t, redacted, params["Signature"])
+	assert.Equal(t, redacted, params["SignatureNonce"])
+}
+
+func TestParametersJSON(t *testing.T) {
+	type request struct {
+		FileSystemId *string
+		Path         *string
+		Quota        *struct{ SizeLimit int64 }
+		UserPassword *string
+		Empty        *string
+	}
+	fs, path, pass := "cpfs-1", "/a", "p"
+	params := parameters(&request{
+		FileSystemId: &fs,
+		Path:         &path,
+		Quota:        &struct{ SizeLimit int64 }{SizeLimit: 10},
+		UserPassword: &pass,
+	})
+	assert.Equal(t, map[string]string{
+		"FileSystemId": "cpfs-1",
+		"Path":         "/a",
+		"Quota":        `{"SizeLimit":10}`,
+		"UserPassword": redacted,
+	}, params)
+}
+
+func TestLog(t *testing.T) {
+	sink := &memorySink{}
+	SetSinks(sink)
+	t.Cleanup(func() { SetSinks() })
+
+	ctx := WithIdentity(context.Background(), Identity{
+		Method:       "DeleteVolume",
+		VolumeID:     "d-1",
+		PVName:       "pv-1",
+		PVCNamespace: "default",
+		PVCName:      "pvc-1",
+	})
+	req := ecs.CreateDeleteDiskRequest()
+	req.DiskId = "d-1"
+
+	Log(ctx, Call{Product: "ecs", Action: "DescribeDisks", Request: ecs.CreateDescribeDisksRequest()})
+	assert.Empty(t, sink.records, "read-only actions should not be audited")
+
+	Log(ctx, Call{Product: "ecs", Action: "DeleteDisk", Request: req, RequestID: "req-1"})
+	Log(ctx, Call{Product: "ecs", Action: "DeleteDisk", Request: req, ErrorCode: "IncorrectDiskStatus", Err: errors.New("disk in use")})
+	require.Len(t, sink.records, 2)
+
+	r := sink.records[0]
+	assert.Equal(t, "DeleteVolume", r.Method)
+	assert.Equal(t, "d-1", r.VolumeID)
+	assert.Equal(t, "pvc-1", r.PVCName)
+	assert.Equal(t, "req-1", r.RequestID)
+	assert.Equal(t, "d-1", r.Parameters["DiskId"])
+	assert.Equal(t, OutcomeSuccess, r.Outcome)
+
+	r = sink.records[1]
+	assert.Equal(t, OutcomeFailure, r.Outcome)
+	assert.Equal(t, "IncorrectDiskStatus", r.ErrorCode)
+	assert.Equal(t, "disk in use", r.Error)
+}
+
+func TestLogNoSink(t *testing.T) {
+	SetSinks()
+	// should not panic or marshal anything
+	Log(context.Background(), Call{Product: "ecs", Action: "CreateDisk", Request: func() {}})
+}
+
+func TestIdentityFromRequest(t *testing.T) {
+	cases := []struct {
+		name     string
+		req      any
+		expected Identity
+	}{
+		{
+			name: "CreateVolume",
+			req: &csi.CreateVolumeRequest{
+				Name: "pv-1",
+				Parameters: map[string]string{
+					pvcNameKey:      "pvc-1",
+					pvcNamespaceKey: "default",
+				},
+			},
+			expected: Identity{PVName: "pv-1", PVCName: "pvc-1", PVCNamespace: "default"},
+		},
+		{
+			name:     "DeleteVolume",
+			req:      &csi.DeleteVolumeRequest{VolumeId: "d-1"},
+			expected: Identity{VolumeID: "d-1"},
+		},
+		{
+			name: "ControllerPublishVolume",
+			req: &csi.ControllerPublishVolumeRequest{
+				VolumeId:      "d-1",
+				VolumeContext: map[string]string{pvNameKey: "pv-1"},
+			},
+			expected: Identity{VolumeID: "d-1", PVName: "pv-1"},
+		},
+		{
+			name:     "CreateSnapshot",
+			req:      &csi.CreateSnapshotRequest{Name: "snap-1", SourceVolumeId: "d-1"},
+			expected: Identity{VolumeID: "d-1"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, identityFromRequest(c.req))
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/DeleteVolume"}
+	_, err := UnaryServerInterceptor(context.Background(), &csi.DeleteVolumeRequest{VolumeId: "d-1"}, info,
+		func(ctx context.Context, req any) (any, error) {
+			assert.Equal(t, Identity{Method: "DeleteVolume", VolumeID: "d-1"}, identityFromContext(ctx))
+			return nil, nil
+		})
+	assert.NoError(t, err)
+}
+
+func TestJSONSink(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewJSONSink(buf)
+	s.Write(&Record{Product: "ecs", Action: "CreateDisk", Outcome: OutcomeSuccess})
+	s.Write(&Record{Product: "ecs", Action: "DeleteDisk", Outcome: OutcomeFailure, Error: "failed"})
+
+	dec := json.NewDecoder(buf)
+	var r Record
+	require.NoError(t, dec.Decode(&r))
+	assert.Equal(t, "CreateDisk", r.Action)
+	require.NoError(t, dec.Decode(&r))
+	assert.Equal(t, "DeleteDisk", r.Action)
+	assert.Equal(t, "failed", r.Error)
+}
+
+func TestEventSink(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	s := NewEventSink(recorder)
+
+	s.Write(&Record{Product: "ecs", Action: "CreateDisk", PVCName: "pvc-1", PVCNamespace: "default", RequestID: "req-1", Method: "CreateVolume", Outcome: OutcomeSuccess})
+	s.Write(&Record{Product: "ecs", Action: "DeleteDisk", PVName: "pv-1", Outcome: OutcomeFailure, Error: "disk in use"})
+	s.Write(&Record{Product: "ecs", Action: "DeleteDisk", Outcome: OutcomeSuccess})
+
+	require.Len(t, recorder.Events, 2)
+	assert.Equal(t, "Normal AuditCreateDisk ecs.CreateDisk succeeded, requestID: req-1, by CSI CreateVolume", <-recorder.Events)
+	assert.Equal(t, "Warning AuditDeleteDisk ecs.DeleteDisk failed: disk in use", <-recorder.Events)
+}
+
+func TestInitFromEnv(t *testing.T) {
+	t.Cleanup(func() { SetSinks() })
+
+	t.Setenv("AUDIT_LOG", "stdout,events")
+	require.NoError(t, InitFromEnv(func() record.EventRecorder { return record.NewFakeRecorder(1) }))
+	assert.Len(t, currentSinks(), 2)
+
+	t.Setenv("AUDIT_LOG", "syslog")
+	assert.Error(t, InitFromEnv(nil))
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	pvcNameKey      = "csi.storage.k8s.io/pvc/name"
+	pvcNamespaceKey = "csi.storage.k8s.io/pvc/namespace"
+	pvNameKey       = "csi.storage.k8s.io/pv/name"
+)
+
+// Identity tells which CSI request and volume a mutation is made for.
+type Identity struct {
+	Method       string
+	VolumeID     string
+	PVName       string
+	PVCNamespace string
+	PVCName      string
+}
+
+type identityKey struct{}
+
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+func identityFromContext(ctx context.Context) Identity {
+	id, _ := ctx.Value(identityKey{}).(Identity)
+	return id
+}
+
+// identityFromRequest extracts the identity from CSI request messages.
+func identityFromRequest(req any) Identity {
+	var id Identity
+	if r, ok := req.(interface{ GetVolumeId() string }); ok {
+		id.VolumeID = r.GetVolumeId()
+	}
+	if r, ok := req.(interface{ GetSourceVolumeId() string }); ok && id.VolumeID == "" {
+		id.VolumeID = r.GetSourceVolumeId()
+	}
+	var params map[string]string
+	if r, ok := req.(interface{ GetParameters() map[string]string }); ok {
+		params = r.GetParameters()
+	} else if r, ok := req.(interface{ GetVolumeContext() map[string]string }); ok {
+		params = r.GetVolumeContext()
+	}
+	id.PVName = params[pvNameKey]
+	id.PVCName = params[pvcNameKey]
+	id.PVCNamespace = params[pvcNamespaceKey]
+	if r, ok := req.(interface{ GetName() string }); ok && id.PVName == "" && id.VolumeID == "" {
+		// CreateVolume, the name is the PV name given by external-provisioner
+		id.PVName = r.GetName()
+	}
+	return id
+}
+
+// UnaryServerInterceptor attaches the identity of each CSI request to the context.
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	id := identityFromRequest(req)
+	id.Method = info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+	return handler(WithIdentity(ctx, id), req)
+}
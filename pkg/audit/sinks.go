@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+// JSONSink writes one JSON object per line.
+type JSONSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+func (s *JSONSink) Write(r *Record) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		klog.ErrorS(err, "failed to marshal audit record", "action", r.Action)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		klog.ErrorS(err, "failed to write audit record", "action", r.Action)
+	}
+}
+
+// EventSink emits Kubernetes events on the PVC, or the PV if the PVC is unknown.
+// Records of unknown PVC and PV are dropped.
+type EventSink struct {
+	recorder record.EventRecorder
+}
+
+func NewEventSink(recorder record.EventRecorder) *EventSink {
+	return &EventSink{recorder: recorder}
+}
+
+func (s *EventSink) Write(r *Record) {
+	var ref *v1.ObjectReference
+	switch {
+	case r.PVCName != "":
+		ref = &v1.ObjectReference{Kind: "PersistentVolumeClaim", APIVersion: "v1", Namespace: r.PVCNamespace, Name: r.PVCName}
+	case r.PVName != "":
+		ref = &v1.ObjectReference{Kind: "PersistentVolume", APIVersion: "v1", Name: r.PVName}
+	default:
+		return
+	}
+	eventType := v1.EventTypeNormal
+	msg := fmt.Sprintf("%s.%s succeeded", r.Product, r.Action)
+	if r.Outcome != OutcomeSuccess {
+		eventType = v1.EventTypeWarning
+		msg = fmt.Sprintf("%s.%s failed: %s", r.Product, r.Action, r.Error)
+	}
+	if r.RequestID != "" {
+		msg += ", requestID: " + r.RequestID
+	}
+	if r.Method != "" {
+		msg += ", by CSI " + r.Method
+	}
+	s.recorder.Event(ref, eventType, "Audit"+r.Action, msg)
+}
+
+// InitFromEnv sets sinks from environment variable AUDIT_LOG,
+// a comma-separated list of "stdout", "events", or absolute file paths.
+// newRecorder is only called if "events" is requested.
+func InitFromEnv(newRecorder func() record.EventRecorder) error {
+	conf := os.Getenv("AUDIT_LOG")
+	if conf == "" {
+		return nil
+	}
+	var s []Sink
+	for dest := range strings.SplitSeq(conf, ",") {
+		dest = strings.TrimSpace(dest)
+		switch {
+		case dest == "":
+		case dest == "stdout":
+			s = append(s, NewJSONSink(os.Stdout))
+		case dest == "events":
+			s = append(s, NewEventSink(newRecorder()))
+		case strings.HasPrefix(dest, "/"):
+			f, err := os.OpenFile(dest, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+			if err != nil {
+				return fmt.Errorf("open audit log: %w", err)
+			}
+			s = append(s, NewJSONSink(f))
+		default:
+			return fmt.Errorf("unknown audit log destination %q", dest)
+		}
+	}
+	SetSinks(s...)
+	klog.InfoS("audit log enabled", "destinations", conf)
+	return nil
+}
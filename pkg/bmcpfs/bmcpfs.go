@@ -64,7 +64,11 @@ func NewDriver(meta *metadata.Metadata, endpoint string, serviceType utils.Servi
 	driver.servers.IdentityServer = newIdentityServer()
 
 	if serviceType&utils.Controller != 0 {
-		cs, err := newControllerServer(metadata.MustGet(meta, metadata.RegionID))
+		region, err := meta.Get(metadata.RegionID)
+		if err != nil {
+			klog.Fatalf("Init controller server: region ID is required, declare it in %s on nodes outside ECS: %v", metadata.METADATA_FILE_ENV, err)
+		}
+		cs, err := newControllerServer(region)
 		if err != nil {
 			klog.Fatalf("Init controller server: %v", err)
 		}
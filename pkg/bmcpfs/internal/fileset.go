@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	nasclient "github.com/alibabacloud-go/nas-20170626/v4/client"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
 	"k8s.io/klog/v2"
 )
 
@@ -69,7 +70,7 @@ func (m *cpfsFileSetManager) CreateFileSet(ctx context.Context, fsID, pvName, fi
 		Quota:              quota,
 	}
 	klog.InfoS("before create fileset", "request", *request)
-	response, err := m.client.CreateFileset(request)
+	response, err := wrap.V2(ctx, m.client.CreateFileset)(request)
 	if err != nil {
 		return "", fmt.Errorf("create fileset %s/%s failed: %v", fsID, pvName, err)
 	}
@@ -87,7 +88,7 @@ func (m *cpfsFileSetManager) DeleteFileSet(ctx context.Context, fsID, fileSetID
 		FsetId:       &fileSetID,
 	}
 
-	response, err := m.client.DeleteFileset(request)
+	response, err := wrap.V2(ctx, m.client.DeleteFileset)(request)
 	if err != nil {
 		return fmt.Errorf("delete fileset %s/%s failed: %v", fsID, fileSetID, err)
 	}
@@ -27,6 +27,7 @@ import (
 	efloclient "github.com/alibabacloud-go/eflo-controller-20221215/v3/client"
 	nasclient "github.com/alibabacloud-go/nas-20170626/v4/client"
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
 	"golang.org/x/time/rate"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
@@ -49,7 +50,7 @@ type Vsc struct {
 }
 
 type VscManager interface {
-	CreatePrimaryVscFor(instanceId string) (string, error)
+	CreatePrimaryVscFor(ctx context.Context, instanceId string) (string, error)
 	GetPrimaryVscOf(instanceId string) (*Vsc, error)
 	GetVsc(vscId string) (*Vsc, error)
 }
@@ -62,12 +63,12 @@ type LingjunVscManager struct {
 	client *efloclient.Client
 }
 
-func (m *LingjunVscManager) CreatePrimaryVscFor(instanceId string) (string, error) {
+func (m *LingjunVscManager) CreatePrimaryVscFor(ctx context.Context, instanceId string) (string, error) {
 	req := &efloclient.CreateVscRequest{
 		NodeId:  &instanceId,
 		VscType: new(VscTypePrimary),
 	}
-	resp, err := m.client.CreateVsc(req)
+	resp, err := wrap.V2(ctx, m.client.CreateVsc)(req)
 	if err != nil {
 		return "", fmt.Errorf("eflo:CreateVsc failed: %w", err)
 	}
@@ -136,6 +137,8 @@ type PrimaryVscManagerWithCache struct {
 	cond *sync.Cond
 	// Instance ID to VSC
 	cache map[string]vscWithErr
+	// Instance ID to the context of the request waiting for it
+	requests map[string]context.Context
 	// To create primary vsc for node
 	queue workqueue.TypedRateLimitingInterface[string]
 }
@@ -153,6 +156,7 @@ func NewPrimaryVscManagerWithCache(efloClient *efloclient.Client) *PrimaryVscMan
 		cacheTTL:   defaultVscCacheTTL,
 		cond:       sync.NewCond(&sync.Mutex{}),
 		cache:      make(map[string]vscWithErr),
+		requests:   make(map[string]context.Context),
 		queue: workqueue.NewTypedRateLimitingQueue(
 			workqueue.NewTypedMaxOfRateLimiter(
 				workqueue.NewTypedItemExponentialFailureRateLimiter[string](500*time.Millisecond, 1000*time.Second),
@@ -177,21 +181,25 @@ func (m *PrimaryVscManagerWithCache) handleNext() bool {
 	}
 	defer m.queue.Done(instanceId)
 
-	newVsc, err := m.getOrCreatePrimaryFor(instanceId)
+	m.cond.L.Lock()
+	ctx := m.requests[instanceId]
+	m.cond.L.Unlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	newVsc, err := m.getOrCreatePrimaryFor(ctx, instanceId)
 
 	m.cond.L.Lock()
 	m.cache[instanceId] = vscWithErr{Vsc: newVsc, err: err, cachedAt: time.Now()}
 	m.cond.L.Unlock()
 
 	if err == nil {
-		m.queue.Forget(instanceId)
-		m.cond.Broadcast()
+		m.forget(instanceId)
 	} else {
 		sdkErr := &tea.SDKError{}
 		if errors.As(err, &sdkErr) || m.queue.NumRequeues(instanceId) > m.retryTimes {
 			klog.ErrorS(err, "Failed to ensure VSC", "instance", instanceId)
-			m.queue.Forget(instanceId)
-			m.cond.Broadcast()
+			m.forget(instanceId)
 		} else {
 			klog.InfoS("Retrying to ensure VSC", "instance", instanceId, "error", err)
 			m.queue.AddRateLimited(instanceId)
@@ -200,7 +208,15 @@ func (m *PrimaryVscManagerWithCache) handleNext() bool {
 	return true
 }
 
-func (m *PrimaryVscManagerWithCache) getOrCreatePrimaryFor(instanceId string) (*Vsc, error) {
+func (m *PrimaryVscManagerWithCache) forget(instanceId string) {
+	m.queue.Forget(instanceId)
+	m.cond.L.Lock()
+	delete(m.requests, instanceId)
+	m.cond.L.Unlock()
+	m.cond.Broadcast()
+}
+
+func (m *PrimaryVscManagerWithCache) getOrCreatePrimaryFor(ctx context.Context, instanceId string) (*Vsc, error) {
 	var err error
 	// try to get existing vsc
 	vsc, err := m.VscManager.GetPrimaryVscOf(instanceId)
@@ -210,7 +226,7 @@ func (m *PrimaryVscManagerWithCache) getOrCreatePrimaryFor(instanceId string) (*
 	// primary vsc of the instance not found, create it
 	var vscId string
 	if vsc == nil {
-		vscId, err = m.CreatePrimaryVscFor(instanceId)
+		vscId, err = m.CreatePrimaryVscFor(ctx, instanceId)
 		if err != nil {
 			return nil, err
 		}
@@ -241,6 +257,10 @@ func (m *PrimaryVscManagerWithCache) EnsurePrimaryVsc(ctx context.Context, insta
 	}
 
 	delete(m.cache, instanceId)
+	if _, ok := m.requests[instanceId]; !ok {
+		// the VSC is created in background, keep the identity of the request for auditing
+		m.requests[instanceId] = context.WithoutCancel(ctx)
+	}
 	m.queue.Add(instanceId)
 	for {
 		vsc, exists := m.cache[instanceId]
@@ -376,7 +396,7 @@ func (ad *cpfsAttachDetacher) Attach(ctx context.Context, fsId, vscId string) er
 			return fmt.Errorf("unexpected attachinfo status: %v", tea.StringValue(attachInfo.Status))
 		}
 	} else {
-		if err := ad.attach(fsId, vscId); err != nil {
+		if err := ad.attach(ctx, fsId, vscId); err != nil {
 			if strings.Contains(err.Error(), VscAttachNotSupported) {
 				return newAttachNotSupportedError(err, fsId, vscId)
 			}
@@ -399,7 +419,7 @@ func (ad *cpfsAttachDetacher) Attach(ctx context.Context, fsId, vscId string) er
 }
 
 func (ad *cpfsAttachDetacher) Detach(ctx context.Context, fsId, vscId string) error {
-	if err := ad.detach(fsId, vscId); err != nil {
+	if err := ad.detach(ctx, fsId, vscId); err != nil {
 		sdkErr := new(tea.SDKError)
 		if errors.As(err, &sdkErr) {
 			errCode := tea.StringValue(sdkErr.Code)
@@ -452,7 +472,7 @@ func (ad *cpfsAttachDetacher) waitFor(ctx context.Context, fsId, vscId string, c
 	}
 }
 
-func (ad *cpfsAttachDetacher) attach(fsId, vscId string) error {
+func (ad *cpfsAttachDetacher) attach(ctx context.Context, fsId, vscId string) error {
 	req := &nasclient.AttachVscToFilesystemsRequest{
 		ResourceIds: []*nasclient.AttachVscToFilesystemsRequestResourceIds{
 			{
@@ -461,7 +481,7 @@ func (ad *cpfsAttachDetacher) attach(fsId, vscId string) error {
 			},
 		},
 	}
-	resp, err := ad.client.AttachVscToFilesystems(req)
+	resp, err := wrap.V2(ctx, ad.client.AttachVscToFilesystems)(req)
 	if err != nil {
 		return fmt.Errorf("nas:AttachVscToFilesystems failed: %w", err)
 	}
@@ -469,7 +489,7 @@ func (ad *cpfsAttachDetacher) attach(fsId, vscId string) error {
 	return nil
 }
 
-func (ad *cpfsAttachDetacher) detach(fsId, vscId string) error {
+func (ad *cpfsAttachDetacher) detach(ctx context.Context, fsId, vscId string) error {
 	req := &nasclient.DetachVscFromFilesystemsRequest{
 		ResourceIds: []*nasclient.DetachVscFromFilesystemsRequestResourceIds{
 			{
@@ -478,7 +498,7 @@ func (ad *cpfsAttachDetacher) detach(fsId, vscId string) error {
 			},
 		},
 	}
-	resp, err := ad.client.DetachVscFromFilesystems(req)
+	resp, err := wrap.V2(ctx, ad.client.DetachVscFromFilesystems)(req)
 	if err != nil {
 		return fmt.Errorf("nas:DetachVscFromFilesystems failed: %w", err)
 	}
@@ -18,6 +18,12 @@ type ECSInterface interface {
 	ResizeDisk(request *ecs.ResizeDiskRequest) (response *ecs.ResizeDiskResponse, err error)
 	CreateSnapshot(request *ecs.CreateSnapshotRequest) (response *ecs.CreateSnapshotResponse, err error)
 	DescribeSnapshots(request *ecs.DescribeSnapshotsRequest) (response *ecs.DescribeSnapshotsResponse, err error)
+	DeleteSnapshot(request *ecs.DeleteSnapshotRequest) (response *ecs.DeleteSnapshotResponse, err error)
+	AddTags(request *ecs.AddTagsRequest) (response *ecs.AddTagsResponse, err error)
+	DescribeTags(request *ecs.DescribeTagsRequest) (response *ecs.DescribeTagsResponse, err error)
+	RemoveTags(request *ecs.RemoveTagsRequest) (response *ecs.RemoveTagsResponse, err error)
+	ModifyDiskAttribute(request *ecs.ModifyDiskAttributeRequest) (response *ecs.ModifyDiskAttributeResponse, err error)
+	ModifyDiskSpec(request *ecs.ModifyDiskSpecRequest) (response *ecs.ModifyDiskSpecResponse, err error)
 }
 
 type ECSv2Interface interface {
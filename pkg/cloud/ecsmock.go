@@ -35,6 +35,21 @@ func (m *MockECSInterface) EXPECT() *MockECSInterfaceMockRecorder {
 	return m.recorder
 }
 
+// AddTags mocks base method.
+func (m *MockECSInterface) AddTags(request *ecs.AddTagsRequest) (*ecs.AddTagsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddTags", request)
+	ret0, _ := ret[0].(*ecs.AddTagsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddTags indicates an expected call of AddTags.
+func (mr *MockECSInterfaceMockRecorder) AddTags(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddTags", reflect.TypeOf((*MockECSInterface)(nil).AddTags), request)
+}
+
 // AttachDisk mocks base method.
 func (m *MockECSInterface) AttachDisk(request *ecs.AttachDiskRequest) (*ecs.AttachDiskResponse, error) {
 	m.ctrl.T.Helper()
@@ -95,6 +110,21 @@ func (mr *MockECSInterfaceMockRecorder) DeleteDisk(request interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDisk", reflect.TypeOf((*MockECSInterface)(nil).DeleteDisk), request)
 }
 
+// DeleteSnapshot mocks base method.
+func (m *MockECSInterface) DeleteSnapshot(request *ecs.DeleteSnapshotRequest) (*ecs.DeleteSnapshotResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSnapshot", request)
+	ret0, _ := ret[0].(*ecs.DeleteSnapshotResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteSnapshot indicates an expected call of DeleteSnapshot.
+func (mr *MockECSInterfaceMockRecorder) DeleteSnapshot(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSnapshot", reflect.TypeOf((*MockECSInterface)(nil).DeleteSnapshot), request)
+}
+
 // DescribeAvailableResource mocks base method.
 func (m *MockECSInterface) DescribeAvailableResource(request *ecs.DescribeAvailableResourceRequest) (*ecs.DescribeAvailableResourceResponse, error) {
 	m.ctrl.T.Helper()
@@ -185,6 +215,21 @@ func (mr *MockECSInterfaceMockRecorder) DescribeSnapshots(request interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeSnapshots", reflect.TypeOf((*MockECSInterface)(nil).DescribeSnapshots), request)
 }
 
+// DescribeTags mocks base method.
+func (m *MockECSInterface) DescribeTags(request *ecs.DescribeTagsRequest) (*ecs.DescribeTagsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DescribeTags", request)
+	ret0, _ := ret[0].(*ecs.DescribeTagsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeTags indicates an expected call of DescribeTags.
+func (mr *MockECSInterfaceMockRecorder) DescribeTags(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeTags", reflect.TypeOf((*MockECSInterface)(nil).DescribeTags), request)
+}
+
 // DetachDisk mocks base method.
 func (m *MockECSInterface) DetachDisk(request *ecs.DetachDiskRequest) (*ecs.DetachDiskResponse, error) {
 	m.ctrl.T.Helper()
@@ -200,6 +245,51 @@ func (mr *MockECSInterfaceMockRecorder) DetachDisk(request interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachDisk", reflect.TypeOf((*MockECSInterface)(nil).DetachDisk), request)
 }
 
+// ModifyDiskAttribute mocks base method.
+func (m *MockECSInterface) ModifyDiskAttribute(request *ecs.ModifyDiskAttributeRequest) (*ecs.ModifyDiskAttributeResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModifyDiskAttribute", request)
+	ret0, _ := ret[0].(*ecs.ModifyDiskAttributeResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ModifyDiskAttribute indicates an expected call of ModifyDiskAttribute.
+func (mr *MockECSInterfaceMockRecorder) ModifyDiskAttribute(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModifyDiskAttribute", reflect.TypeOf((*MockECSInterface)(nil).ModifyDiskAttribute), request)
+}
+
+// ModifyDiskSpec mocks base method.
+func (m *MockECSInterface) ModifyDiskSpec(request *ecs.ModifyDiskSpecRequest) (*ecs.ModifyDiskSpecResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModifyDiskSpec", request)
+	ret0, _ := ret[0].(*ecs.ModifyDiskSpecResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ModifyDiskSpec indicates an expected call of ModifyDiskSpec.
+func (mr *MockECSInterfaceMockRecorder) ModifyDiskSpec(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModifyDiskSpec", reflect.TypeOf((*MockECSInterface)(nil).ModifyDiskSpec), request)
+}
+
+// RemoveTags mocks base method.
+func (m *MockECSInterface) RemoveTags(request *ecs.RemoveTagsRequest) (*ecs.RemoveTagsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveTags", request)
+	ret0, _ := ret[0].(*ecs.RemoveTagsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveTags indicates an expected call of RemoveTags.
+func (mr *MockECSInterfaceMockRecorder) RemoveTags(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveTags", reflect.TypeOf((*MockECSInterface)(nil).RemoveTags), request)
+}
+
 // ResizeDisk mocks base method.
 func (m *MockECSInterface) ResizeDisk(request *ecs.ResizeDiskRequest) (*ecs.ResizeDiskResponse, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,857 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+)
+
+const (
+	DiskStatusCreating  = "Creating"
+	DiskStatusAvailable = "Available"
+	DiskStatusAttaching = "Attaching"
+	DiskStatusInUse     = "In_use"
+	DiskStatusDetaching = "Detaching"
+
+	SnapshotStatusProgressing  = "progressing"
+	SnapshotStatusAccomplished = "accomplished"
+
+	timeFormat = "2006-01-02T15:04:05Z"
+)
+
+type instance struct {
+	ecs.Instance
+}
+
+type disk struct {
+	ecs.Disk
+	createdAt time.Time
+	pending   *transition
+}
+
+func (d *disk) advance(now time.Time) {
+	if d.pending != nil && !now.Before(d.pending.at) {
+		d.pending.apply()
+		d.pending = nil
+	}
+}
+
+type snapshot struct {
+	ecs.Snapshot
+	createdAt time.Time
+	pending   *transition
+}
+
+func (s *snapshot) advance(now time.Time) {
+	if s.pending != nil && !now.Before(s.pending.at) {
+		s.pending.apply()
+		s.pending = nil
+	}
+}
+
+// AddZone makes the disk categories available in zoneID.
+// Use empty zoneID for regional disk categories.
+func (c *Cloud) AddZone(zoneID string, categories ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.zones[zoneID] = append(c.zones[zoneID], categories...)
+}
+
+// AddInstance registers an ECS instance, disks can only be attached to registered instances.
+func (c *Cloud) AddInstance(instanceID, zoneID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.instances[instanceID] = &instance{ecs.Instance{
+		InstanceId: instanceID,
+		ZoneId:     zoneID,
+		RegionId:   c.opts.RegionID,
+		Status:     "Running",
+	}}
+}
+
+// SetInstanceStatus changes the status of a registered instance, e.g. to "Stopped".
+func (c *Cloud) SetInstanceStatus(instanceID, status string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.instances[instanceID].Status = status
+}
+
+// Disk returns a snapshot of the current state of the disk, bypassing eventual consistency.
+func (c *Cloud) Disk(diskID string) (ecs.Disk, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advance()
+	d, ok := c.disks[diskID]
+	if !ok {
+		return ecs.Disk{}, false
+	}
+	return copyDisk(&d.Disk), true
+}
+
+func copyDisk(d *ecs.Disk) ecs.Disk {
+	res := *d
+	res.Tags.Tag = slices.Clone(d.Tags.Tag)
+	res.Attachments.Attachment = slices.Clone(d.Attachments.Attachment)
+	return res
+}
+
+func parseIDs(ids string) ([]string, error) {
+	if ids == "" {
+		return nil, nil
+	}
+	var res []string
+	if err := json.Unmarshal([]byte(ids), &res); err != nil {
+		return nil, ServerError("InvalidParameter", fmt.Sprintf("invalid ID list %q: %v", ids, err))
+	}
+	return res, nil
+}
+
+func parseInteger(name string, v requests.Integer) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(string(v))
+	if err != nil {
+		return 0, ServerError("InvalidParameter", fmt.Sprintf("invalid %s %q", name, v))
+	}
+	return i, nil
+}
+
+func hasTags(tags []ecs.Tag, filter map[string]string) bool {
+	for k, v := range filter {
+		if !slices.ContainsFunc(tags, func(t ecs.Tag) bool {
+			return t.TagKey == k && (v == "" || t.TagValue == v)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// paginate applies MaxResults/NextToken or PageSize/PageNumber to items.
+func paginate[T any](items []T, maxResults requests.Integer, nextToken string, pageSize, pageNumber requests.Integer) ([]T, string, error) {
+	if maxResults != "" || nextToken != "" {
+		start := 0
+		if nextToken != "" {
+			var err error
+			start, err = strconv.Atoi(nextToken)
+			if err != nil || start < 0 || start > len(items) {
+				return nil, "", ServerError("InvalidParameter.NextToken", "The specified NextToken is invalid.")
+			}
+		}
+		n, err := parseInteger("MaxResults", maxResults)
+		if err != nil {
+			return nil, "", err
+		}
+		if n <= 0 {
+			n = 10
+		}
+		// ECS returns at least 10 results
+		n = max(n, 10)
+		end := min(start+n, len(items))
+		token := ""
+		if end < len(items) {
+			token = strconv.Itoa(end)
+		}
+		return items[start:end], token, nil
+	}
+	size, err := parseInteger("PageSize", pageSize)
+	if err != nil {
+		return nil, "", err
+	}
+	if size <= 0 {
+		size = 10
+	}
+	page, err := parseInteger("PageNumber", pageNumber)
+	if err != nil {
+		return nil, "", err
+	}
+	page = max(page, 1)
+	start := min((page-1)*size, len(items))
+	end := min(start+size, len(items))
+	return items[start:end], "", nil
+}
+
+func (c *Cloud) CreateDisk(req *ecs.CreateDiskRequest) (*ecs.CreateDiskResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("CreateDisk"); err != nil {
+		return nil, err
+	}
+
+	size, err := parseInteger("Size", req.Size)
+	if err != nil {
+		return nil, err
+	}
+	var snap *snapshot
+	if req.SnapshotId != "" {
+		snap = c.snapshots[req.SnapshotId]
+		if snap == nil {
+			return nil, ServerError("InvalidSnapshotId.NotFound", "The specified snapshot does not exist.")
+		}
+		if size == 0 {
+			size, _ = strconv.Atoi(snap.SourceDiskSize)
+		}
+	}
+	if size <= 0 {
+		return nil, ServerError("MissingParameter", "The input parameter Size that is mandatory for processing this request is not supplied.")
+	}
+	category := req.DiskCategory
+	if category == "" {
+		category = "cloud"
+	}
+	if c.stockOut[zoneCategory{req.ZoneId, category}] {
+		return nil, ServerError("InvalidDataDiskCategory.NotSupported", "The specified disk category is not supported in the specified zone.")
+	}
+
+	params := fmt.Sprintf("%s/%s/%d/%s/%s/%s", req.ZoneId, category, size, req.PerformanceLevel, req.SnapshotId, req.MultiAttach)
+	if req.ClientToken != "" {
+		if created, ok := c.clientTokens[req.ClientToken]; ok {
+			if created.params != params {
+				return nil, ServerError("IdempotentParameterMismatch", "The specified parameter has changed while using an already used clientToken.")
+			}
+			resp := ecs.CreateCreateDiskResponse()
+			resp.RequestId = c.requestID()
+			resp.DiskId = created.id
+			return resp, nil
+		}
+	}
+
+	now := c.clk.Now()
+	id := c.newID("d")
+	d := &disk{
+		Disk: ecs.Disk{
+			DiskId:           id,
+			DiskName:         req.DiskName,
+			Description:      req.Description,
+			RegionId:         c.opts.RegionID,
+			ZoneId:           req.ZoneId,
+			Category:         category,
+			PerformanceLevel: req.PerformanceLevel,
+			Size:             size,
+			Status:           DiskStatusCreating,
+			Type:             "data",
+			Portable:         true,
+			MultiAttach:      "Disabled",
+			KMSKeyId:         req.KMSKeyId,
+			ResourceGroupId:  req.ResourceGroupId,
+			SourceSnapshotId: req.SnapshotId,
+			SerialNumber:     strings.TrimPrefix(id, "d-"),
+			CreationTime:     now.UTC().Format(timeFormat),
+		},
+		createdAt: now,
+	}
+	if req.MultiAttach == "Enabled" {
+		d.MultiAttach = "Enabled"
+	}
+	if encrypted, err := req.Encrypted.GetValue(); err == nil {
+		d.Encrypted = encrypted
+	}
+	if req.Tag != nil {
+		for _, t := range *req.Tag {
+			d.Tags.Tag = append(d.Tags.Tag, ecs.Tag{TagKey: t.Key, TagValue: t.Value})
+		}
+	}
+	d.pending = c.after(func() { d.Status = DiskStatusAvailable })
+	c.disks[id] = d
+	if req.ClientToken != "" {
+		c.clientTokens[req.ClientToken] = createdByToken{id: id, params: params}
+	}
+
+	resp := ecs.CreateCreateDiskResponse()
+	resp.RequestId = c.requestID()
+	resp.DiskId = id
+	return resp, nil
+}
+
+func (c *Cloud) DeleteDisk(req *ecs.DeleteDiskRequest) (*ecs.DeleteDiskResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DeleteDisk"); err != nil {
+		return nil, err
+	}
+	d, ok := c.disks[req.DiskId]
+	if !ok {
+		return nil, ServerError("InvalidDiskId.NotFound", "The specified disk does not exist.")
+	}
+	switch d.Status {
+	case DiskStatusCreating:
+		return nil, ServerError("IncorrectDiskStatus.Initializing", "The specified disk is initializing.")
+	case DiskStatusAvailable:
+	default:
+		return nil, ServerError("IncorrectDiskStatus", "The current disk status does not support this operation.")
+	}
+	delete(c.disks, req.DiskId)
+
+	resp := ecs.CreateDeleteDiskResponse()
+	resp.RequestId = c.requestID()
+	return resp, nil
+}
+
+func (c *Cloud) AttachDisk(req *ecs.AttachDiskRequest) (*ecs.AttachDiskResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("AttachDisk"); err != nil {
+		return nil, err
+	}
+	d, ok := c.disks[req.DiskId]
+	if !ok {
+		return nil, ServerError("InvalidDiskId.NotFound", "The specified disk does not exist.")
+	}
+	inst, ok := c.instances[req.InstanceId]
+	if !ok {
+		return nil, ServerError("InvalidInstanceId.NotFound", "The specified InstanceId does not exist.")
+	}
+	if d.ZoneId != "" && d.ZoneId != inst.ZoneId {
+		return nil, ServerError("InvalidParameter.ZoneIdNotMatch", "The disk and instance are not in the same zone.")
+	}
+	force, _ := req.Force.GetValue()
+
+	multiAttach := d.MultiAttach == "Enabled"
+	switch d.Status {
+	case DiskStatusAvailable:
+	case DiskStatusInUse:
+		if multiAttach {
+			if slices.ContainsFunc(d.Attachments.Attachment, func(a ecs.Attachment) bool { return a.InstanceId == req.InstanceId }) {
+				return nil, ServerError("IncorrectDiskStatus", "The disk is already attached to the instance.")
+			}
+			break
+		}
+		if !force || d.InstanceId == req.InstanceId {
+			return nil, ServerError("IncorrectDiskStatus", "The current disk status does not support this operation.")
+		}
+	default:
+		return nil, ServerError("IncorrectDiskStatus", "The current disk status does not support this operation.")
+	}
+
+	d.Status = DiskStatusAttaching
+	if force && !multiAttach {
+		// the disk is detached from the old instance immediately
+		d.InstanceId = ""
+		d.Attachments.Attachment = nil
+	}
+	instanceID := req.InstanceId
+	deleteWithInstance, _ := req.DeleteWithInstance.GetValue()
+	d.pending = c.after(func() {
+		d.Status = DiskStatusInUse
+		d.DeleteWithInstance = deleteWithInstance
+		d.AttachedTime = c.clk.Now().UTC().Format(timeFormat)
+		d.Attachments.Attachment = append(d.Attachments.Attachment, ecs.Attachment{
+			InstanceId:   instanceID,
+			AttachedTime: d.AttachedTime,
+		})
+		if !multiAttach {
+			d.InstanceId = instanceID
+		}
+	})
+
+	resp := ecs.CreateAttachDiskResponse()
+	resp.RequestId = c.requestID()
+	return resp, nil
+}
+
+func (c *Cloud) DetachDisk(req *ecs.DetachDiskRequest) (*ecs.DetachDiskResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DetachDisk"); err != nil {
+		return nil, err
+	}
+	d, ok := c.disks[req.DiskId]
+	if !ok {
+		return nil, ServerError("InvalidDiskId.NotFound", "The specified disk does not exist.")
+	}
+	if _, ok := c.instances[req.InstanceId]; !ok {
+		return nil, ServerError("InvalidInstanceId.NotFound", "The specified InstanceId does not exist.")
+	}
+	if d.Status != DiskStatusInUse || !slices.ContainsFunc(d.Attachments.Attachment, func(a ecs.Attachment) bool { return a.InstanceId == req.InstanceId }) {
+		return nil, ServerError("IncorrectDiskStatus", "The current disk status does not support this operation.")
+	}
+
+	instanceID := req.InstanceId
+	d.Status = DiskStatusDetaching
+	d.pending = c.after(func() {
+		d.Attachments.Attachment = slices.DeleteFunc(d.Attachments.Attachment, func(a ecs.Attachment) bool { return a.InstanceId == instanceID })
+		if d.InstanceId == instanceID {
+			d.InstanceId = ""
+		}
+		if len(d.Attachments.Attachment) == 0 {
+			d.Status = DiskStatusAvailable
+			d.AttachedTime = ""
+		} else {
+			d.Status = DiskStatusInUse
+		}
+	})
+
+	resp := ecs.CreateDetachDiskResponse()
+	resp.RequestId = c.requestID()
+	return resp, nil
+}
+
+func (c *Cloud) ResizeDisk(req *ecs.ResizeDiskRequest) (*ecs.ResizeDiskResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("ResizeDisk"); err != nil {
+		return nil, err
+	}
+	d, ok := c.disks[req.DiskId]
+	if !ok {
+		return nil, ServerError("InvalidDiskId.NotFound", "The specified disk does not exist.")
+	}
+	size, err := parseInteger("NewSize", req.NewSize)
+	if err != nil {
+		return nil, err
+	}
+	if size < d.Size {
+		return nil, ServerError("InvalidDiskSize.TooSmall", "The specified new disk size is less than the original disk size.")
+	}
+	if d.Status != DiskStatusAvailable && d.Status != DiskStatusInUse {
+		return nil, ServerError("IncorrectDiskStatus", "The current disk status does not support this operation.")
+	}
+	d.Size = size
+
+	resp := ecs.CreateResizeDiskResponse()
+	resp.RequestId = c.requestID()
+	return resp, nil
+}
+
+func (c *Cloud) DescribeDisks(req *ecs.DescribeDisksRequest) (*ecs.DescribeDisksResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DescribeDisks"); err != nil {
+		return nil, err
+	}
+	ids, err := parseIDs(req.DiskIds)
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]string{}
+	if req.Tag != nil {
+		for _, t := range *req.Tag {
+			tags[t.Key] = t.Value
+		}
+	}
+
+	var disks []ecs.Disk
+	for _, d := range c.disks {
+		switch {
+		case !c.visible(d.createdAt),
+			len(ids) > 0 && !slices.Contains(ids, d.DiskId),
+			req.DiskName != "" && req.DiskName != d.DiskName,
+			req.ZoneId != "" && req.ZoneId != d.ZoneId,
+			req.Category != "" && req.Category != d.Category,
+			req.Status != "" && req.Status != d.Status,
+			req.InstanceId != "" && !slices.ContainsFunc(d.Attachments.Attachment, func(a ecs.Attachment) bool { return a.InstanceId == req.InstanceId }),
+			!hasTags(d.Tags.Tag, tags):
+			continue
+		}
+		disks = append(disks, copyDisk(&d.Disk))
+	}
+	slices.SortFunc(disks, func(a, b ecs.Disk) int { return strings.Compare(a.DiskId, b.DiskId) })
+	total := len(disks)
+	disks, token, err := paginate(disks, req.MaxResults, req.NextToken, req.PageSize, req.PageNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := ecs.CreateDescribeDisksResponse()
+	resp.RequestId = c.requestID()
+	resp.Disks.Disk = disks
+	resp.NextToken = token
+	resp.TotalCount = total
+	return resp, nil
+}
+
+func (c *Cloud) CreateSnapshot(req *ecs.CreateSnapshotRequest) (*ecs.CreateSnapshotResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("CreateSnapshot"); err != nil {
+		return nil, err
+	}
+	d, ok := c.disks[req.DiskId]
+	if !ok {
+		return nil, ServerError("InvalidDiskId.NotFound", "The specified disk does not exist.")
+	}
+	if d.Status != DiskStatusAvailable && d.Status != DiskStatusInUse {
+		return nil, ServerError("IncorrectDiskStatus", "The current disk status does not support this operation.")
+	}
+	params := fmt.Sprintf("%s/%s", req.DiskId, req.SnapshotName)
+	if req.ClientToken != "" {
+		if created, ok := c.clientTokens[req.ClientToken]; ok {
+			if created.params != params {
+				return nil, ServerError("IdempotentParameterMismatch", "The specified parameter has changed while using an already used clientToken.")
+			}
+			resp := ecs.CreateCreateSnapshotResponse()
+			resp.RequestId = c.requestID()
+			resp.SnapshotId = created.id
+			return resp, nil
+		}
+	}
+	retentionDays, err := parseInteger("RetentionDays", req.RetentionDays)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []ecs.Tag
+	if req.Tag != nil {
+		for _, t := range *req.Tag {
+			tags = append(tags, ecs.Tag{TagKey: t.Key, TagValue: t.Value})
+		}
+	}
+	s := c.newSnapshot(d, req.SnapshotName, req.Description, req.ResourceGroupId, tags)
+	s.RetentionDays = retentionDays
+	id := s.SnapshotId
+	if req.ClientToken != "" {
+		c.clientTokens[req.ClientToken] = createdByToken{id: id, params: params}
+	}
+
+	resp := ecs.CreateCreateSnapshotResponse()
+	resp.RequestId = c.requestID()
+	resp.SnapshotId = id
+	return resp, nil
+}
+
+// newSnapshot cuts a snapshot of d, which becomes accomplished after the transition delay.
+func (c *Cloud) newSnapshot(d *disk, name, description, resourceGroupID string, tags []ecs.Tag) *snapshot {
+	now := c.clk.Now()
+	id := c.newID("s")
+	s := &snapshot{
+		Snapshot: ecs.Snapshot{
+			SnapshotId:      id,
+			SnapshotName:    name,
+			Description:     description,
+			SourceDiskId:    d.DiskId,
+			SourceDiskSize:  strconv.Itoa(d.Size),
+			SourceDiskType:  d.Type,
+			Category:        "standard",
+			Status:          SnapshotStatusProgressing,
+			Progress:        "0%",
+			ResourceGroupId: resourceGroupID,
+			Encrypted:       d.Encrypted,
+			KMSKeyId:        d.KMSKeyId,
+			// the snapshot is cut once created
+			CreationTime: now.UTC().Format(timeFormat),
+		},
+		createdAt: now,
+	}
+	s.Tags.Tag = tags
+	s.pending = c.after(func() {
+		s.Status = SnapshotStatusAccomplished
+		s.Progress = "100%"
+		s.Available = true
+	})
+	c.snapshots[id] = s
+	return s
+}
+
+func (c *Cloud) DescribeSnapshots(req *ecs.DescribeSnapshotsRequest) (*ecs.DescribeSnapshotsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DescribeSnapshots"); err != nil {
+		return nil, err
+	}
+	ids, err := parseIDs(req.SnapshotIds)
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]string{}
+	if req.Tag != nil {
+		for _, t := range *req.Tag {
+			tags[t.Key] = t.Value
+		}
+	}
+
+	var snapshots []ecs.Snapshot
+	for _, s := range c.snapshots {
+		switch {
+		case !c.visible(s.createdAt),
+			len(ids) > 0 && !slices.Contains(ids, s.SnapshotId),
+			req.DiskId != "" && req.DiskId != s.SourceDiskId,
+			req.SnapshotName != "" && req.SnapshotName != s.SnapshotName,
+			req.Status != "" && req.Status != "all" && req.Status != s.Status,
+			!hasTags(s.Tags.Tag, tags):
+			continue
+		}
+		res := s.Snapshot
+		res.Tags.Tag = slices.Clone(s.Tags.Tag)
+		snapshots = append(snapshots, res)
+	}
+	slices.SortFunc(snapshots, func(a, b ecs.Snapshot) int { return strings.Compare(a.SnapshotId, b.SnapshotId) })
+	total := len(snapshots)
+	snapshots, token, err := paginate(snapshots, req.MaxResults, req.NextToken, req.PageSize, req.PageNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := ecs.CreateDescribeSnapshotsResponse()
+	resp.RequestId = c.requestID()
+	resp.Snapshots.Snapshot = snapshots
+	resp.NextToken = token
+	resp.TotalCount = total
+	return resp, nil
+}
+
+func (c *Cloud) DeleteSnapshot(req *ecs.DeleteSnapshotRequest) (*ecs.DeleteSnapshotResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DeleteSnapshot"); err != nil {
+		return nil, err
+	}
+	if _, ok := c.snapshots[req.SnapshotId]; !ok {
+		return nil, ServerError("InvalidSnapshotId.NotFound", "The specified snapshot does not exist.")
+	}
+	delete(c.snapshots, req.SnapshotId)
+
+	resp := ecs.CreateDeleteSnapshotResponse()
+	resp.RequestId = c.requestID()
+	return resp, nil
+}
+
+func (c *Cloud) DescribeTags(req *ecs.DescribeTagsRequest) (*ecs.DescribeTagsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DescribeTags"); err != nil {
+		return nil, err
+	}
+	resp := ecs.CreateDescribeTagsResponse()
+	resp.RequestId = c.requestID()
+	return resp, nil
+}
+
+func setTag(tags []ecs.Tag, key, value string) []ecs.Tag {
+	for i := range tags {
+		if tags[i].TagKey == key {
+			tags[i].TagValue = value
+			return tags
+		}
+	}
+	return append(tags, ecs.Tag{TagKey: key, TagValue: value})
+}
+
+func (c *Cloud) AddTags(req *ecs.AddTagsRequest) (*ecs.AddTagsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("AddTags"); err != nil {
+		return nil, err
+	}
+	var tags *[]ecs.Tag
+	switch req.ResourceType {
+	case "disk":
+		d, ok := c.disks[req.ResourceId]
+		if !ok {
+			return nil, ServerError("InvalidResourceId.NotFound", "The specified ResourceId is not found in our records.")
+		}
+		tags = &d.Tags.Tag
+	case "snapshot":
+		s, ok := c.snapshots[req.ResourceId]
+		if !ok {
+			return nil, ServerError("InvalidResourceId.NotFound", "The specified ResourceId is not found in our records.")
+		}
+		tags = &s.Tags.Tag
+	default:
+		return nil, ServerError("InvalidResourceType.NotFound", "The ResourceType provided does not exist in our records.")
+	}
+	if req.Tag != nil {
+		for _, t := range *req.Tag {
+			*tags = setTag(*tags, t.Key, t.Value)
+		}
+	}
+
+	resp := ecs.CreateAddTagsResponse()
+	resp.RequestId = c.requestID()
+	return resp, nil
+}
+
+func (c *Cloud) RemoveTags(req *ecs.RemoveTagsRequest) (*ecs.RemoveTagsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("RemoveTags"); err != nil {
+		return nil, err
+	}
+	var tags *[]ecs.Tag
+	switch req.ResourceType {
+	case "disk":
+		d, ok := c.disks[req.ResourceId]
+		if !ok {
+			return nil, ServerError("InvalidResourceId.NotFound", "The specified ResourceId is not found in our records.")
+		}
+		tags = &d.Tags.Tag
+	case "snapshot":
+		s, ok := c.snapshots[req.ResourceId]
+		if !ok {
+			return nil, ServerError("InvalidResourceId.NotFound", "The specified ResourceId is not found in our records.")
+		}
+		tags = &s.Tags.Tag
+	default:
+		return nil, ServerError("InvalidResourceType.NotFound", "The ResourceType provided does not exist in our records.")
+	}
+	if req.Tag != nil {
+		for _, t := range *req.Tag {
+			*tags = slices.DeleteFunc(*tags, func(tag ecs.Tag) bool {
+				return tag.TagKey == t.Key && (t.Value == "" || tag.TagValue == t.Value)
+			})
+		}
+	}
+
+	resp := ecs.CreateRemoveTagsResponse()
+	resp.RequestId = c.requestID()
+	return resp, nil
+}
+
+func (c *Cloud) ModifyDiskAttribute(req *ecs.ModifyDiskAttributeRequest) (*ecs.ModifyDiskAttributeResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("ModifyDiskAttribute"); err != nil {
+		return nil, err
+	}
+	d, ok := c.disks[req.DiskId]
+	if !ok {
+		return nil, ServerError("InvalidDiskId.NotFound", "The specified disk does not exist.")
+	}
+	if req.DiskName != "" {
+		d.DiskName = req.DiskName
+	}
+	if req.Description != "" {
+		d.Description = req.Description
+	}
+	if req.DeleteWithInstance != "" {
+		d.DeleteWithInstance = req.DeleteWithInstance == "true"
+	}
+
+	resp := ecs.CreateModifyDiskAttributeResponse()
+	resp.RequestId = c.requestID()
+	return resp, nil
+}
+
+func (c *Cloud) ModifyDiskSpec(req *ecs.ModifyDiskSpecRequest) (*ecs.ModifyDiskSpecResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("ModifyDiskSpec"); err != nil {
+		return nil, err
+	}
+	d, ok := c.disks[req.DiskId]
+	if !ok {
+		return nil, ServerError("InvalidDiskId.NotFound", "The specified disk does not exist.")
+	}
+	if d.Status != DiskStatusAvailable && d.Status != DiskStatusInUse {
+		return nil, ServerError("IncorrectDiskStatus", "The current disk status does not support this operation.")
+	}
+	provisionedIops, err := parseInteger("ProvisionedIops", req.ProvisionedIops)
+	if err != nil {
+		return nil, err
+	}
+	if req.DiskCategory != "" {
+		d.Category = req.DiskCategory
+	}
+	if req.PerformanceLevel != "" {
+		d.PerformanceLevel = req.PerformanceLevel
+	}
+	if req.ProvisionedIops != "" {
+		d.ProvisionedIops = int64(provisionedIops)
+	}
+
+	resp := ecs.CreateModifyDiskSpecResponse()
+	resp.RequestId = c.requestID()
+	resp.TaskId = c.newID("t")
+	return resp, nil
+}
+
+func (c *Cloud) DescribeInstances(req *ecs.DescribeInstancesRequest) (*ecs.DescribeInstancesResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DescribeInstances"); err != nil {
+		return nil, err
+	}
+	ids, err := parseIDs(req.InstanceIds)
+	if err != nil {
+		return nil, err
+	}
+	var instances []ecs.Instance
+	for _, inst := range c.instances {
+		if len(ids) > 0 && !slices.Contains(ids, inst.InstanceId) {
+			continue
+		}
+		if req.ZoneId != "" && req.ZoneId != inst.ZoneId {
+			continue
+		}
+		instances = append(instances, inst.Instance)
+	}
+	slices.SortFunc(instances, func(a, b ecs.Instance) int { return strings.Compare(a.InstanceId, b.InstanceId) })
+	total := len(instances)
+	instances, token, err := paginate(instances, req.MaxResults, req.NextToken, req.PageSize, req.PageNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := ecs.CreateDescribeInstancesResponse()
+	resp.RequestId = c.requestID()
+	resp.Instances.Instance = instances
+	resp.NextToken = token
+	resp.TotalCount = total
+	return resp, nil
+}
+
+func (c *Cloud) DescribeInstanceTypes(req *ecs.DescribeInstanceTypesRequest) (*ecs.DescribeInstanceTypesResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DescribeInstanceTypes"); err != nil {
+		return nil, err
+	}
+	resp := ecs.CreateDescribeInstanceTypesResponse()
+	resp.RequestId = c.requestID()
+	return resp, nil
+}
+
+func (c *Cloud) DescribeInstanceHistoryEvents(req *ecs.DescribeInstanceHistoryEventsRequest) (*ecs.DescribeInstanceHistoryEventsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DescribeInstanceHistoryEvents"); err != nil {
+		return nil, err
+	}
+	resp := ecs.CreateDescribeInstanceHistoryEventsResponse()
+	resp.RequestId = c.requestID()
+	return resp, nil
+}
+
+// DescribeAvailableResource returns the disk categories added by AddZone, except the ones out of stock.
+func (c *Cloud) DescribeAvailableResource(req *ecs.DescribeAvailableResourceRequest) (*ecs.DescribeAvailableResourceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DescribeAvailableResource"); err != nil {
+		return nil, err
+	}
+	resp := ecs.CreateDescribeAvailableResourceResponse()
+	resp.RequestId = c.requestID()
+
+	zoneID := req.ZoneId
+	if req.Scope == "region" {
+		zoneID = ""
+	}
+	categories, ok := c.zones[zoneID]
+	if !ok {
+		return resp, nil
+	}
+	resource := ecs.AvailableResource{Type: "DataDisk"}
+	for _, category := range categories {
+		if c.stockOut[zoneCategory{zoneID, category}] {
+			continue
+		}
+		resource.SupportedResources.SupportedResource = append(resource.SupportedResources.SupportedResource, ecs.SupportedResource{
+			Value:  category,
+			Status: "Available",
+		})
+	}
+	zone := ecs.AvailableZone{
+		RegionId: c.opts.RegionID,
+		ZoneId:   zoneID,
+		Status:   "Available",
+	}
+	zone.AvailableResources.AvailableResource = []ecs.AvailableResource{resource}
+	resp.AvailableZones.AvailableZone = []ecs.AvailableZone{zone}
+	return resp, nil
+}
@@ -0,0 +1,353 @@
+package fake
+
+import (
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	alicloudErr "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func assertErrorCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var aliErr *alicloudErr.ServerError
+	require.True(t, errors.As(err, &aliErr), "unexpected error: %v", err)
+	assert.Equal(t, code, aliErr.ErrorCode())
+}
+
+func createDisk(t *testing.T, c *Cloud, token string) string {
+	t.Helper()
+	req := ecs.CreateCreateDiskRequest()
+	req.ZoneId = "cn-hangzhou-a"
+	req.DiskCategory = "cloud_essd"
+	req.Size = requests.NewInteger(20)
+	req.ClientToken = token
+	req.Tag = &[]ecs.CreateDiskTag{{Key: "csi.alibabacloud.com/volume-name", Value: "pv-1"}}
+	resp, err := c.CreateDisk(req)
+	require.NoError(t, err)
+	return resp.DiskId
+}
+
+func describeDisk(t *testing.T, c *Cloud, id string) *ecs.Disk {
+	t.Helper()
+	req := ecs.CreateDescribeDisksRequest()
+	req.DiskIds = `["` + id + `"]`
+	resp, err := c.DescribeDisks(req)
+	require.NoError(t, err)
+	if len(resp.Disks.Disk) == 0 {
+		return nil
+	}
+	require.Len(t, resp.Disks.Disk, 1)
+	return &resp.Disks.Disk[0]
+}
+
+func TestDiskLifecycle(t *testing.T) { synctest.Test(t, testDiskLifecycle) }
+func testDiskLifecycle(t *testing.T) {
+	c := New(Options{TransitionDelay: 5 * time.Second})
+	c.AddInstance("i-1", "cn-hangzhou-a")
+
+	id := createDisk(t, c, "")
+	assert.Equal(t, DiskStatusCreating, describeDisk(t, c, id).Status)
+
+	del := ecs.CreateDeleteDiskRequest()
+	del.DiskId = id
+	_, err := c.DeleteDisk(del)
+	assertErrorCode(t, err, "IncorrectDiskStatus.Initializing")
+
+	time.Sleep(5 * time.Second)
+	assert.Equal(t, DiskStatusAvailable, describeDisk(t, c, id).Status)
+
+	attach := ecs.CreateAttachDiskRequest()
+	attach.DiskId = id
+	attach.InstanceId = "i-1"
+	_, err = c.AttachDisk(attach)
+	require.NoError(t, err)
+	d := describeDisk(t, c, id)
+	assert.Equal(t, DiskStatusAttaching, d.Status)
+	assert.Empty(t, d.Attachments.Attachment)
+
+	time.Sleep(5 * time.Second)
+	d = describeDisk(t, c, id)
+	assert.Equal(t, DiskStatusInUse, d.Status)
+	assert.Equal(t, "i-1", d.InstanceId)
+	assert.Equal(t, []string{"i-1"}, attachedInstances(d))
+
+	byInstance := ecs.CreateDescribeDisksRequest()
+	byInstance.InstanceId = "i-1"
+	resp, err := c.DescribeDisks(byInstance)
+	require.NoError(t, err)
+	assert.Len(t, resp.Disks.Disk, 1)
+
+	resize := ecs.CreateResizeDiskRequest()
+	resize.DiskId = id
+	resize.NewSize = requests.NewInteger(10)
+	_, err = c.ResizeDisk(resize)
+	assertErrorCode(t, err, "InvalidDiskSize.TooSmall")
+	resize.NewSize = requests.NewInteger(40)
+	_, err = c.ResizeDisk(resize)
+	require.NoError(t, err)
+	assert.Equal(t, 40, describeDisk(t, c, id).Size)
+
+	snap := ecs.CreateCreateSnapshotRequest()
+	snap.DiskId = id
+	snap.SnapshotName = "snap-1"
+	snapResp, err := c.CreateSnapshot(snap)
+	require.NoError(t, err)
+
+	descSnap := ecs.CreateDescribeSnapshotsRequest()
+	descSnap.DiskId = id
+	snaps, err := c.DescribeSnapshots(descSnap)
+	require.NoError(t, err)
+	require.Len(t, snaps.Snapshots.Snapshot, 1)
+	assert.Equal(t, snapResp.SnapshotId, snaps.Snapshots.Snapshot[0].SnapshotId)
+	assert.False(t, snaps.Snapshots.Snapshot[0].Available)
+	assert.Equal(t, "40", snaps.Snapshots.Snapshot[0].SourceDiskSize)
+
+	_, err = c.DeleteDisk(del)
+	assertErrorCode(t, err, "IncorrectDiskStatus")
+
+	detach := ecs.CreateDetachDiskRequest()
+	detach.DiskId = id
+	detach.InstanceId = "i-1"
+	_, err = c.DetachDisk(detach)
+	require.NoError(t, err)
+	assert.Equal(t, DiskStatusDetaching, describeDisk(t, c, id).Status)
+
+	time.Sleep(5 * time.Second)
+	d = describeDisk(t, c, id)
+	assert.Equal(t, DiskStatusAvailable, d.Status)
+	assert.Empty(t, d.InstanceId)
+	assert.Empty(t, d.Attachments.Attachment)
+
+	snaps, err = c.DescribeSnapshots(descSnap)
+	require.NoError(t, err)
+	assert.True(t, snaps.Snapshots.Snapshot[0].Available)
+	assert.Equal(t, SnapshotStatusAccomplished, snaps.Snapshots.Snapshot[0].Status)
+
+	restore := ecs.CreateCreateDiskRequest()
+	restore.ZoneId = "cn-hangzhou-a"
+	restore.DiskCategory = "cloud_essd"
+	restore.SnapshotId = snapResp.SnapshotId
+	restored, err := c.CreateDisk(restore)
+	require.NoError(t, err)
+	restoredDisk, ok := c.Disk(restored.DiskId)
+	require.True(t, ok)
+	assert.Equal(t, 40, restoredDisk.Size)
+
+	_, err = c.DeleteDisk(del)
+	require.NoError(t, err)
+	assert.Nil(t, describeDisk(t, c, id))
+}
+
+func attachedInstances(d *ecs.Disk) []string {
+	var res []string
+	for _, a := range d.Attachments.Attachment {
+		res = append(res, a.InstanceId)
+	}
+	return res
+}
+
+func TestAttachDiskConflict(t *testing.T) {
+	c := New(Options{})
+	c.AddInstance("i-1", "cn-hangzhou-a")
+	c.AddInstance("i-2", "cn-hangzhou-a")
+	c.AddInstance("i-3", "cn-hangzhou-b")
+	id := createDisk(t, c, "")
+
+	attach := ecs.CreateAttachDiskRequest()
+	attach.DiskId = id
+	attach.InstanceId = "i-3"
+	_, err := c.AttachDisk(attach)
+	assertErrorCode(t, err, "InvalidParameter.ZoneIdNotMatch")
+
+	attach.InstanceId = "i-404"
+	_, err = c.AttachDisk(attach)
+	assertErrorCode(t, err, "InvalidInstanceId.NotFound")
+
+	attach.InstanceId = "i-1"
+	_, err = c.AttachDisk(attach)
+	require.NoError(t, err)
+
+	attach.InstanceId = "i-2"
+	_, err = c.AttachDisk(attach)
+	assertErrorCode(t, err, "IncorrectDiskStatus")
+
+	attach.Force = requests.NewBoolean(true)
+	_, err = c.AttachDisk(attach)
+	require.NoError(t, err)
+	d, _ := c.Disk(id)
+	assert.Equal(t, "i-2", d.InstanceId)
+	assert.Equal(t, []string{"i-2"}, attachedInstances(&d))
+}
+
+func TestCreateDiskIdempotent(t *testing.T) {
+	c := New(Options{})
+	id := createDisk(t, c, "token-1")
+	assert.Equal(t, id, createDisk(t, c, "token-1"))
+
+	req := ecs.CreateCreateDiskRequest()
+	req.ZoneId = "cn-hangzhou-a"
+	req.DiskCategory = "cloud_essd"
+	req.Size = requests.NewInteger(30)
+	req.ClientToken = "token-1"
+	_, err := c.CreateDisk(req)
+	assertErrorCode(t, err, "IdempotentParameterMismatch")
+}
+
+func TestRetagDisk(t *testing.T) {
+	c := New(Options{})
+	id := createDisk(t, c, "")
+
+	add := ecs.CreateAddTagsRequest()
+	add.ResourceType = "disk"
+	add.ResourceId = id
+	add.Tag = &[]ecs.AddTagsTag{{Key: "pool", Value: "a"}}
+	_, err := c.AddTags(add)
+	require.NoError(t, err)
+
+	remove := ecs.CreateRemoveTagsRequest()
+	remove.ResourceType = "disk"
+	remove.ResourceId = id
+	remove.Tag = &[]ecs.RemoveTagsTag{{Key: "pool"}}
+	_, err = c.RemoveTags(remove)
+	require.NoError(t, err)
+
+	modify := ecs.CreateModifyDiskAttributeRequest()
+	modify.DiskId = id
+	modify.DiskName = "pv-2"
+	_, err = c.ModifyDiskAttribute(modify)
+	require.NoError(t, err)
+
+	d, _ := c.Disk(id)
+	assert.Equal(t, "pv-2", d.DiskName)
+	assert.Equal(t, []ecs.Tag{{TagKey: "csi.alibabacloud.com/volume-name", TagValue: "pv-1"}}, d.Tags.Tag)
+
+	modify.DiskId = "d-missing"
+	_, err = c.ModifyDiskAttribute(modify)
+	assertErrorCode(t, err, "InvalidDiskId.NotFound")
+}
+
+func TestModifyDiskSpec(t *testing.T) {
+	c := New(Options{})
+	id := createDisk(t, c, "")
+
+	req := ecs.CreateModifyDiskSpecRequest()
+	req.DiskId = id
+	req.ProvisionedIops = requests.NewInteger(5000)
+	_, err := c.ModifyDiskSpec(req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), describeDisk(t, c, id).ProvisionedIops)
+
+	req.DiskId = "d-missing"
+	_, err = c.ModifyDiskSpec(req)
+	assertErrorCode(t, err, "InvalidDiskId.NotFound")
+}
+
+func TestStockOut(t *testing.T) {
+	c := New(Options{})
+	c.AddZone("cn-hangzhou-a", "cloud_essd", "cloud_auto")
+	c.AddZone("", "cloud_regional_disk_auto")
+	c.SetStockOut("cn-hangzhou-a", "cloud_essd", true)
+
+	req := ecs.CreateCreateDiskRequest()
+	req.ZoneId = "cn-hangzhou-a"
+	req.DiskCategory = "cloud_essd"
+	req.Size = requests.NewInteger(20)
+	_, err := c.CreateDisk(req)
+	assertErrorCode(t, err, "InvalidDataDiskCategory.NotSupported")
+
+	available := func(zoneID, scope string) []string {
+		req := ecs.CreateDescribeAvailableResourceRequest()
+		req.ZoneId = zoneID
+		req.Scope = scope
+		resp, err := c.DescribeAvailableResource(req)
+		require.NoError(t, err)
+		var res []string
+		for _, z := range resp.AvailableZones.AvailableZone {
+			for _, r := range z.AvailableResources.AvailableResource {
+				for _, s := range r.SupportedResources.SupportedResource {
+					res = append(res, s.Value)
+				}
+			}
+		}
+		return res
+	}
+	assert.Equal(t, []string{"cloud_auto"}, available("cn-hangzhou-a", ""))
+	assert.Equal(t, []string{"cloud_regional_disk_auto"}, available("", "region"))
+
+	c.SetStockOut("cn-hangzhou-a", "cloud_essd", false)
+	_, err = c.CreateDisk(req)
+	assert.NoError(t, err)
+}
+
+func TestInjectError(t *testing.T) {
+	c := New(Options{})
+	c.InjectThrottling("DescribeDisks", 2)
+	c.InjectError("DescribeDisks", errors.New("network error"))
+
+	req := ecs.CreateDescribeDisksRequest()
+	for range 2 {
+		_, err := c.DescribeDisks(req)
+		assertErrorCode(t, err, "Throttling")
+	}
+	_, err := c.DescribeDisks(req)
+	assert.EqualError(t, err, "network error")
+	_, err = c.DescribeDisks(req)
+	assert.NoError(t, err)
+}
+
+func TestListDelay(t *testing.T) { synctest.Test(t, testListDelay) }
+func testListDelay(t *testing.T) {
+	c := New(Options{ListDelay: 3 * time.Second})
+	id := createDisk(t, c, "")
+	assert.Nil(t, describeDisk(t, c, id))
+	_, ok := c.Disk(id)
+	assert.True(t, ok)
+
+	time.Sleep(3 * time.Second)
+	assert.NotNil(t, describeDisk(t, c, id))
+}
+
+func TestLatency(t *testing.T) { synctest.Test(t, testLatency) }
+func testLatency(t *testing.T) {
+	c := New(Options{Latency: 100 * time.Millisecond})
+	start := time.Now()
+	_, err := c.DescribeDisks(ecs.CreateDescribeDisksRequest())
+	require.NoError(t, err)
+	assert.Equal(t, 100*time.Millisecond, time.Since(start))
+}
+
+func TestDescribeDisksPagination(t *testing.T) {
+	c := New(Options{})
+	var ids []string
+	for range 15 {
+		ids = append(ids, createDisk(t, c, ""))
+	}
+
+	req := ecs.CreateDescribeDisksRequest()
+	req.MaxResults = requests.NewInteger(10)
+	resp, err := c.DescribeDisks(req)
+	require.NoError(t, err)
+	assert.Len(t, resp.Disks.Disk, 10)
+	assert.Equal(t, 15, resp.TotalCount)
+	require.NotEmpty(t, resp.NextToken)
+
+	req.NextToken = resp.NextToken
+	resp, err = c.DescribeDisks(req)
+	require.NoError(t, err)
+	assert.Len(t, resp.Disks.Disk, 5)
+	assert.Empty(t, resp.NextToken)
+	assert.Equal(t, ids[14], resp.Disks.Disk[4].DiskId)
+
+	req = ecs.CreateDescribeDisksRequest()
+	req.PageSize = requests.NewInteger(4)
+	req.PageNumber = requests.NewInteger(4)
+	resp, err = c.DescribeDisks(req)
+	require.NoError(t, err)
+	assert.Len(t, resp.Disks.Disk, 3)
+}
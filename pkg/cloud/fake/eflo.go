@@ -0,0 +1,54 @@
+package fake
+
+import (
+	eflo "github.com/alibabacloud-go/eflo-controller-20221215/v3/client"
+	"k8s.io/utils/ptr"
+)
+
+// AddEFLONode registers a Lingjun node of nodeType, which supports diskQuantity disks.
+func (c *Cloud) AddEFLONode(nodeID, nodeType string, diskQuantity int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.efloNodes[nodeID] = nodeType
+	c.efloNodeType[nodeType] = diskQuantity
+}
+
+func (c *Cloud) DescribeNode(req *eflo.DescribeNodeRequest) (*eflo.DescribeNodeResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DescribeNode"); err != nil {
+		return nil, err
+	}
+	nodeType, ok := c.efloNodes[ptr.Deref(req.NodeId, "")]
+	if !ok {
+		return nil, SDKError("InvalidNode.NotFound", "The specified node does not exist.")
+	}
+	return &eflo.DescribeNodeResponse{
+		StatusCode: ptr.To[int32](200),
+		Body: &eflo.DescribeNodeResponseBody{
+			RequestId:      ptr.To(c.requestID()),
+			NodeId:         req.NodeId,
+			NodeType:       &nodeType,
+			OperatingState: ptr.To("Using"),
+		},
+	}, nil
+}
+
+func (c *Cloud) DescribeNodeType(req *eflo.DescribeNodeTypeRequest) (*eflo.DescribeNodeTypeResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DescribeNodeType"); err != nil {
+		return nil, err
+	}
+	quantity, ok := c.efloNodeType[ptr.Deref(req.NodeType, "")]
+	if !ok {
+		return nil, SDKError("InvalidNodeType.NotFound", "The specified node type does not exist.")
+	}
+	return &eflo.DescribeNodeTypeResponse{
+		StatusCode: ptr.To[int32](200),
+		Body: &eflo.DescribeNodeTypeResponseBody{
+			RequestId:    ptr.To(c.requestID()),
+			DiskQuantity: &quantity,
+		},
+	}, nil
+}
@@ -0,0 +1,193 @@
+// Package fake provides a stateful in-memory implementation of the cloud interfaces,
+// so that the whole controller stack can be exercised in unit tests without
+// mocking every OpenAPI call.
+// The ECS part, including snapshot groups which are called through *ecs.Client,
+// can also be served over HTTP, see Cloud.ServeHTTP and cmd/fake-ecs.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	alicloudErr "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"k8s.io/utils/clock"
+)
+
+var (
+	_ cloud.ECSInterface  = &Cloud{}
+	_ cloud.NasInterface  = &Cloud{}
+	_ cloud.EFLOInterface = &Cloud{}
+)
+
+type Options struct {
+	RegionID string
+	// Clock defaults to the real clock. Use it with testing/synctest or a fake clock to skip the delays.
+	Clock clock.Clock
+	// Latency is added to every call.
+	Latency time.Duration
+	// TransitionDelay is how long a resource stays in an intermediate status,
+	// e.g. Creating, Attaching, Detaching for disks, and progressing for snapshots.
+	TransitionDelay time.Duration
+	// ListDelay is how long a newly created resource is invisible to Describe calls,
+	// to simulate eventual consistency.
+	ListDelay time.Duration
+}
+
+// Cloud simulates ECS, NAS and EFLO OpenAPIs in memory. It is safe for concurrent use.
+type Cloud struct {
+	opts Options
+	clk  clock.Clock
+
+	mu        sync.Mutex
+	nextID    int
+	errors    map[string][]error
+	stockOut  map[zoneCategory]bool
+	zones     map[string][]string // zone ID -> disk categories
+	instances map[string]*instance
+	disks     map[string]*disk
+	snapshots map[string]*snapshot
+	groups    map[string]*snapshotGroup
+	// clientTokens maps the client token of a creation request to the created resource
+	clientTokens map[string]createdByToken
+
+	fileSystems  map[string]*fileSystem
+	efloNodes    map[string]string // node ID -> node type
+	efloNodeType map[string]int32  // node type -> disk quantity
+}
+
+type zoneCategory struct {
+	zoneID, category string
+}
+
+type createdByToken struct {
+	id string
+	// params is compared to detect IdempotentParameterMismatch
+	params string
+}
+
+func New(opts Options) *Cloud {
+	if opts.RegionID == "" {
+		opts.RegionID = "cn-hangzhou"
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.RealClock{}
+	}
+	return &Cloud{
+		opts:         opts,
+		clk:          opts.Clock,
+		errors:       map[string][]error{},
+		stockOut:     map[zoneCategory]bool{},
+		zones:        map[string][]string{},
+		instances:    map[string]*instance{},
+		disks:        map[string]*disk{},
+		snapshots:    map[string]*snapshot{},
+		groups:       map[string]*snapshotGroup{},
+		clientTokens: map[string]createdByToken{},
+		fileSystems:  map[string]*fileSystem{},
+		efloNodes:    map[string]string{},
+		efloNodeType: map[string]int32{},
+	}
+}
+
+// InjectError makes the next call to action fail with err.
+// Call it multiple times to fail multiple calls in order.
+func (c *Cloud) InjectError(action string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors[action] = append(c.errors[action], err)
+}
+
+// InjectThrottling makes the next n calls to action fail with Throttling error.
+func (c *Cloud) InjectThrottling(action string, n int) {
+	for range n {
+		c.InjectError(action, ServerError("Throttling", "Request was denied due to request throttling."))
+	}
+}
+
+// SetStockOut makes CreateDisk of category in zone fail as if the resource is sold out.
+func (c *Cloud) SetStockOut(zoneID, category string, out bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stockOut[zoneCategory{zoneID, category}] = out
+}
+
+// ServerError returns an error like the ones returned by the v1 SDK, e.g. ECS.
+func ServerError(code, message string) error {
+	content, err := json.Marshal(map[string]string{
+		"Code":      code,
+		"Message":   message,
+		"RequestId": "fake-error-request-id",
+	})
+	if err != nil {
+		panic(err)
+	}
+	return alicloudErr.NewServerError(400, string(content), "")
+}
+
+// SDKError returns an error like the ones returned by the v2 SDK, e.g. NAS.
+func SDKError(code, message string) error {
+	return tea.NewSDKError(map[string]any{
+		"code":       code,
+		"message":    message,
+		"statusCode": 400,
+		"data": map[string]any{
+			"Message":   message,
+			"RequestId": "fake-error-request-id",
+		},
+	})
+}
+
+// begin is called at the start of every API call, with c.mu held.
+// It returns the injected error if any, and materializes all due status transitions.
+func (c *Cloud) begin(action string) error {
+	if c.opts.Latency > 0 {
+		c.mu.Unlock()
+		c.clk.Sleep(c.opts.Latency)
+		c.mu.Lock()
+	}
+	c.advance()
+	if errs := c.errors[action]; len(errs) > 0 {
+		c.errors[action] = errs[1:]
+		return errs[0]
+	}
+	return nil
+}
+
+func (c *Cloud) advance() {
+	now := c.clk.Now()
+	for _, d := range c.disks {
+		d.advance(now)
+	}
+	for _, s := range c.snapshots {
+		s.advance(now)
+	}
+}
+
+func (c *Cloud) newID(prefix string) string {
+	c.nextID++
+	return fmt.Sprintf("%s-fake%08d", prefix, c.nextID)
+}
+
+func (c *Cloud) requestID() string {
+	c.nextID++
+	return fmt.Sprintf("fake-request-%08d", c.nextID)
+}
+
+// visible reports whether a resource created at createdAt can be listed now.
+func (c *Cloud) visible(createdAt time.Time) bool {
+	return !c.clk.Now().Before(createdAt.Add(c.opts.ListDelay))
+}
+
+// transition is a pending status change, applied lazily once due.
+type transition struct {
+	at    time.Time
+	apply func()
+}
+
+func (c *Cloud) after(apply func()) *transition {
+	return &transition{at: c.clk.Now().Add(c.opts.TransitionDelay), apply: apply}
+}
@@ -0,0 +1,141 @@
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	alicloudErr "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+)
+
+const ecsPackage = "github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+
+// ServeHTTP serves the simulated ECS OpenAPIs over the RPC protocol of the v1 SDK,
+// so that a real *ecs.Client, e.g. the one used for snapshot groups, or the whole plugin under csi-sanity
+// can talk to it. Point the client to it with ECS_ENDPOINT and ALICLOUD_CLIENT_SCHEME=HTTP.
+// Signatures are not verified.
+func (c *Cloud) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, ServerError("InvalidParameter", err.Error()))
+		return
+	}
+	action := r.Form.Get("Action")
+	method := reflect.ValueOf(c).MethodByName(action)
+	if !method.IsValid() || !isECSAction(method.Type()) {
+		writeError(w, ServerError("InvalidAction.NotFound", fmt.Sprintf("The specified action %q is not found.", action)))
+		return
+	}
+	req := reflect.New(method.Type().In(0).Elem())
+	if err := decodeParams(r.Form, "", req.Elem()); err != nil {
+		writeError(w, ServerError("InvalidParameter", err.Error()))
+		return
+	}
+	out := method.Call([]reflect.Value{req})
+	if err, _ := out[1].Interface().(error); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out[0].Interface())
+}
+
+// isECSAction reports whether t is func(*ecs.XxxRequest) (*ecs.XxxResponse, error).
+func isECSAction(t reflect.Type) bool {
+	return t.NumIn() == 1 && t.NumOut() == 2 &&
+		t.In(0).Kind() == reflect.Pointer && t.In(0).Elem().PkgPath() == ecsPackage &&
+		t.Out(1) == reflect.TypeFor[error]()
+}
+
+// decodeParams fills the fields of an SDK request from the flattened params, reversing what the SDK does:
+// simple fields are "Name", repeated fields are "Name.1", "Name.2" or "Name.1.Key" for structs,
+// and JSON fields are encoded as a whole.
+func decodeParams(form url.Values, prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, ok := field.Tag.Lookup("name")
+		if !ok {
+			continue
+		}
+		key := prefix + name
+		fv := v.Field(i)
+		switch field.Tag.Get("type") {
+		case "":
+			if fv.Kind() == reflect.String {
+				fv.SetString(form.Get(key))
+			}
+		case "Json":
+			if s := form.Get(key); s != "" {
+				if err := json.Unmarshal([]byte(s), fv.Addr().Interface()); err != nil {
+					return fmt.Errorf("invalid %s: %w", key, err)
+				}
+			}
+		case "Repeated":
+			slice := fv
+			if fv.Kind() == reflect.Pointer {
+				slice = reflect.New(fv.Type().Elem()).Elem()
+			}
+			elem := slice.Type().Elem()
+			for n := 1; ; n++ {
+				elemKey := key + "." + strconv.Itoa(n)
+				e := reflect.New(elem).Elem()
+				if elem.Kind() == reflect.String {
+					if !form.Has(elemKey) {
+						break
+					}
+					e.SetString(form.Get(elemKey))
+				} else {
+					if !hasPrefix(form, elemKey+".") {
+						break
+					}
+					if err := decodeParams(form, elemKey+".", e); err != nil {
+						return err
+					}
+				}
+				slice = reflect.Append(slice, e)
+			}
+			if slice.Len() > 0 && fv.Kind() == reflect.Pointer {
+				p := reflect.New(slice.Type())
+				p.Elem().Set(slice)
+				fv.Set(p)
+			} else if slice.Len() > 0 {
+				fv.Set(slice)
+			}
+		}
+	}
+	return nil
+}
+
+func hasPrefix(form url.Values, prefix string) bool {
+	for k := range form {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeError writes err in the format parsed by the v1 SDK.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	body := map[string]string{
+		"Code":      "InternalError",
+		"Message":   err.Error(),
+		"RequestId": "fake-error-request-id",
+	}
+	var serverErr *alicloudErr.ServerError
+	if errors.As(err, &serverErr) {
+		status = serverErr.HttpStatus()
+		body["Code"] = serverErr.ErrorCode()
+		body["Message"] = serverErr.Message()
+		body["RequestId"] = serverErr.RequestId()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,77 @@
+package fake
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHTTPClient(t *testing.T, c *Cloud) *ecs.Client {
+	server := httptest.NewServer(c)
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	client, err := ecs.NewClientWithAccessKey("cn-hangzhou", "fake-ak", "fake-sk")
+	require.NoError(t, err)
+	client.Domain = u.Host
+	return client
+}
+
+func TestServeHTTPSnapshotGroup(t *testing.T) {
+	c := New(Options{TransitionDelay: time.Second})
+	c.AddZone("cn-hangzhou-a", "cloud_essd")
+	c.AddInstance("i-1", "cn-hangzhou-a")
+	d1 := createDisk(t, c, "")
+	d2 := createDisk(t, c, "")
+	time.Sleep(time.Second)
+	client := newHTTPClient(t, c)
+
+	create := ecs.CreateCreateSnapshotGroupRequest()
+	create.DiskId = &[]string{d1, d2}
+	create.Name = "group-1"
+	create.Tag = &[]ecs.CreateSnapshotGroupTag{{Key: "k", Value: "v"}}
+	created, err := client.CreateSnapshotGroup(create)
+	require.NoError(t, err)
+
+	describe := ecs.CreateDescribeSnapshotGroupsRequest()
+	describe.SnapshotGroupId = &[]string{created.SnapshotGroupId}
+	groups, err := client.DescribeSnapshotGroups(describe)
+	require.NoError(t, err)
+	require.Len(t, groups.SnapshotGroups.SnapshotGroup, 1)
+	g := groups.SnapshotGroups.SnapshotGroup[0]
+	assert.Equal(t, "group-1", g.Name)
+	assert.Equal(t, SnapshotStatusProgressing, g.Status)
+	assert.Equal(t, []ecs.Tag{{TagKey: "k", TagValue: "v"}}, g.Tags.Tag)
+	require.Len(t, g.Snapshots.Snapshot, 2)
+	assert.ElementsMatch(t, []string{d1, d2}, []string{g.Snapshots.Snapshot[0].SourceDiskId, g.Snapshots.Snapshot[1].SourceDiskId})
+
+	time.Sleep(time.Second)
+	describe.SnapshotGroupId = nil
+	describe.Name = "group-1"
+	groups, err = client.DescribeSnapshotGroups(describe)
+	require.NoError(t, err)
+	require.Len(t, groups.SnapshotGroups.SnapshotGroup, 1)
+	assert.Equal(t, SnapshotStatusAccomplished, groups.SnapshotGroups.SnapshotGroup[0].Status)
+
+	del := ecs.CreateDeleteSnapshotGroupRequest()
+	del.SnapshotGroupId = created.SnapshotGroupId
+	_, err = client.DeleteSnapshotGroup(del)
+	require.NoError(t, err)
+	snaps, err := c.DescribeSnapshots(ecs.CreateDescribeSnapshotsRequest())
+	require.NoError(t, err)
+	assert.Empty(t, snaps.Snapshots.Snapshot, "snapshots are deleted with the group")
+
+	_, err = client.DeleteSnapshotGroup(del)
+	assertErrorCode(t, err, "InvalidSnapshotGroupId.NotFound")
+}
+
+func TestServeHTTPUnknownAction(t *testing.T) {
+	client := newHTTPClient(t, New(Options{}))
+	_, err := client.DescribeRegions(ecs.CreateDescribeRegionsRequest())
+	assertErrorCode(t, err, "InvalidAction.NotFound")
+}
@@ -0,0 +1,282 @@
+package fake
+
+import (
+	"path"
+	"strings"
+
+	nas "github.com/alibabacloud-go/nas-20170626/v4/client"
+	"k8s.io/utils/ptr"
+)
+
+type fileSystem struct {
+	nas.DescribeFileSystemsResponseBodyFileSystemsFileSystem
+	recycleBin   bool
+	dirs         map[string]bool
+	quotas       map[string]*nas.SetDirQuotaRequest // by path
+	accessPoints map[string]*nas.DescribeAccessPointResponseBodyAccessPoint
+}
+
+// AddFileSystem registers a NAS file system with an empty root directory.
+func (c *Cloud) AddFileSystem(fileSystemID, fileSystemType string, recycleBin bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fs := &fileSystem{
+		recycleBin:   recycleBin,
+		dirs:         map[string]bool{"/": true},
+		quotas:       map[string]*nas.SetDirQuotaRequest{},
+		accessPoints: map[string]*nas.DescribeAccessPointResponseBodyAccessPoint{},
+	}
+	fs.FileSystemId = &fileSystemID
+	fs.FileSystemType = &fileSystemType
+	fs.RegionId = &c.opts.RegionID
+	fs.Status = ptr.To("Running")
+	c.fileSystems[fileSystemID] = fs
+}
+
+// DirQuota returns the quota set on the dir, or nil if none.
+func (c *Cloud) DirQuota(fileSystemID, dir string) *nas.SetDirQuotaRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fs, ok := c.fileSystems[fileSystemID]
+	if !ok {
+		return nil
+	}
+	return fs.quotas[path.Clean(dir)]
+}
+
+func (c *Cloud) getFileSystem(id *string) (*fileSystem, error) {
+	fs, ok := c.fileSystems[ptr.Deref(id, "")]
+	if !ok {
+		return nil, SDKError("InvalidFileSystem.NotFound", "The specified file system does not exist.")
+	}
+	return fs, nil
+}
+
+func (c *Cloud) CreateDir(req *nas.CreateDirRequest) (*nas.CreateDirResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("CreateDir"); err != nil {
+		return nil, err
+	}
+	fs, err := c.getFileSystem(req.FileSystemId)
+	if err != nil {
+		return nil, err
+	}
+	dir := path.Clean(ptr.Deref(req.RootDirectory, ""))
+	if !path.IsAbs(dir) {
+		return nil, SDKError("InvalidParameter.RootDirectory", "The specified root directory is invalid.")
+	}
+	if !fs.dirs[path.Dir(dir)] && !ptr.Deref(req.Recursion, false) {
+		return nil, SDKError("InvalidParameter.ParentDirNotExist", "The parent directory does not exist.")
+	}
+	for d := dir; !fs.dirs[d]; d = path.Dir(d) {
+		fs.dirs[d] = true
+	}
+	return &nas.CreateDirResponse{
+		StatusCode: ptr.To[int32](200),
+		Body:       &nas.CreateDirResponseBody{RequestId: ptr.To(c.requestID())},
+	}, nil
+}
+
+func (c *Cloud) SetDirQuota(req *nas.SetDirQuotaRequest) (*nas.SetDirQuotaResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("SetDirQuota"); err != nil {
+		return nil, err
+	}
+	fs, err := c.getFileSystem(req.FileSystemId)
+	if err != nil {
+		return nil, err
+	}
+	dir := path.Clean(ptr.Deref(req.Path, ""))
+	if !fs.dirs[dir] {
+		return nil, SDKError("InvalidParameter.PathNotExist", "The specified path does not exist.")
+	}
+	quota := *req
+	fs.quotas[dir] = &quota
+	return &nas.SetDirQuotaResponse{
+		StatusCode: ptr.To[int32](200),
+		Body: &nas.SetDirQuotaResponseBody{
+			RequestId: ptr.To(c.requestID()),
+			Success:   ptr.To(true),
+		},
+	}, nil
+}
+
+func (c *Cloud) CancelDirQuota(req *nas.CancelDirQuotaRequest) (*nas.CancelDirQuotaResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("CancelDirQuota"); err != nil {
+		return nil, err
+	}
+	fs, err := c.getFileSystem(req.FileSystemId)
+	if err != nil {
+		return nil, err
+	}
+	dir := path.Clean(ptr.Deref(req.Path, ""))
+	if _, ok := fs.quotas[dir]; !ok {
+		return nil, SDKError("InvalidParameter.QuotaNotExistOnPath", "The quota does not exist on the specified path.")
+	}
+	delete(fs.quotas, dir)
+	return &nas.CancelDirQuotaResponse{
+		StatusCode: ptr.To[int32](200),
+		Body: &nas.CancelDirQuotaResponseBody{
+			RequestId: ptr.To(c.requestID()),
+			Success:   ptr.To(true),
+		},
+	}, nil
+}
+
+func (c *Cloud) CreateAccessPoint(req *nas.CreateAccessPointRequest) (*nas.CreateAccessPointResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("CreateAccessPoint"); err != nil {
+		return nil, err
+	}
+	fs, err := c.getFileSystem(req.FileSystemId)
+	if err != nil {
+		return nil, err
+	}
+	root := path.Clean(ptr.Deref(req.RootDirectory, "/"))
+	for d := root; !fs.dirs[d]; d = path.Dir(d) {
+		fs.dirs[d] = true
+	}
+	id := c.newID("ap")
+	domain := id + "." + *fs.FileSystemId + "." + c.opts.RegionID + ".nas.aliyuncs.com"
+	ap := &nas.DescribeAccessPointResponseBodyAccessPoint{
+		AccessPointId:   &id,
+		AccessPointName: req.AccessPointName,
+		AccessGroup:     req.AccessGroup,
+		DomainName:      &domain,
+		EnabledRam:      req.EnabledRam,
+		FileSystemId:    fs.FileSystemId,
+		RegionId:        &c.opts.RegionID,
+		RootPath:        &root,
+		RootPathStatus:  ptr.To("Active"),
+		Status:          ptr.To("Active"),
+		VpcId:           req.VpcId,
+		VSwitchId:       req.VswId,
+		CreateTime:      ptr.To(c.clk.Now().UTC().Format(timeFormat)),
+		PosixUser: &nas.DescribeAccessPointResponseBodyAccessPointPosixUser{
+			PosixUserId:  req.PosixUserId,
+			PosixGroupId: req.PosixGroupId,
+		},
+		RootPathPermission: &nas.DescribeAccessPointResponseBodyAccessPointRootPathPermission{
+			OwnerUserId:  req.OwnerUserId,
+			OwnerGroupId: req.OwnerGroupId,
+			Permission:   req.Permission,
+		},
+	}
+	for _, t := range req.Tag {
+		ap.Tags = append(ap.Tags, &nas.DescribeAccessPointResponseBodyAccessPointTags{Key: t.Key, Value: t.Value})
+	}
+	fs.accessPoints[id] = ap
+	return &nas.CreateAccessPointResponse{
+		StatusCode: ptr.To[int32](200),
+		Body: &nas.CreateAccessPointResponseBody{
+			RequestId: ptr.To(c.requestID()),
+			AccessPoint: &nas.CreateAccessPointResponseBodyAccessPoint{
+				AccessPointId:     &id,
+				AccessPointDomain: &domain,
+			},
+		},
+	}, nil
+}
+
+func (c *Cloud) DeleteAccessPoint(req *nas.DeleteAccessPointRequest) (*nas.DeleteAccessPointResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DeleteAccessPoint"); err != nil {
+		return nil, err
+	}
+	fs, err := c.getFileSystem(req.FileSystemId)
+	if err != nil {
+		return nil, err
+	}
+	id := ptr.Deref(req.AccessPointId, "")
+	if _, ok := fs.accessPoints[id]; !ok {
+		return nil, SDKError("InvalidAccessPoint.NotFound", "The specified access point does not exist.")
+	}
+	delete(fs.accessPoints, id)
+	return &nas.DeleteAccessPointResponse{
+		StatusCode: ptr.To[int32](200),
+		Body:       &nas.DeleteAccessPointResponseBody{RequestId: ptr.To(c.requestID())},
+	}, nil
+}
+
+func (c *Cloud) DescribeAccessPoint(req *nas.DescribeAccessPointRequest) (*nas.DescribeAccessPointResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DescribeAccessPoint"); err != nil {
+		return nil, err
+	}
+	fs, err := c.getFileSystem(req.FileSystemId)
+	if err != nil {
+		return nil, err
+	}
+	ap, ok := fs.accessPoints[ptr.Deref(req.AccessPointId, "")]
+	if !ok {
+		return nil, SDKError("InvalidAccessPoint.NotFound", "The specified access point does not exist.")
+	}
+	copied := *ap
+	return &nas.DescribeAccessPointResponse{
+		StatusCode: ptr.To[int32](200),
+		Body: &nas.DescribeAccessPointResponseBody{
+			RequestId:   ptr.To(c.requestID()),
+			AccessPoint: &copied,
+		},
+	}, nil
+}
+
+func (c *Cloud) DescribeFileSystems(req *nas.DescribeFileSystemsRequest) (*nas.DescribeFileSystemsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DescribeFileSystems"); err != nil {
+		return nil, err
+	}
+	var fileSystems []*nas.DescribeFileSystemsResponseBodyFileSystemsFileSystem
+	for id, fs := range c.fileSystems {
+		if req.FileSystemId != nil && !strings.EqualFold(*req.FileSystemId, id) {
+			continue
+		}
+		if req.FileSystemType != nil && *req.FileSystemType != "all" && *req.FileSystemType != *fs.FileSystemType {
+			continue
+		}
+		copied := fs.DescribeFileSystemsResponseBodyFileSystemsFileSystem
+		fileSystems = append(fileSystems, &copied)
+	}
+	return &nas.DescribeFileSystemsResponse{
+		StatusCode: ptr.To[int32](200),
+		Body: &nas.DescribeFileSystemsResponseBody{
+			RequestId:   ptr.To(c.requestID()),
+			TotalCount:  ptr.To(int32(len(fileSystems))),
+			FileSystems: &nas.DescribeFileSystemsResponseBodyFileSystems{FileSystem: fileSystems},
+		},
+	}, nil
+}
+
+func (c *Cloud) GetRecycleBinAttribute(req *nas.GetRecycleBinAttributeRequest) (*nas.GetRecycleBinAttributeResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("GetRecycleBinAttribute"); err != nil {
+		return nil, err
+	}
+	fs, err := c.getFileSystem(req.FileSystemId)
+	if err != nil {
+		return nil, err
+	}
+	status := "Disable"
+	if fs.recycleBin {
+		status = "Enable"
+	}
+	return &nas.GetRecycleBinAttributeResponse{
+		StatusCode: ptr.To[int32](200),
+		Body: &nas.GetRecycleBinAttributeResponseBody{
+			RequestId: ptr.To(c.requestID()),
+			RecycleBinAttribute: &nas.GetRecycleBinAttributeResponseBodyRecycleBinAttribute{
+				Status:       &status,
+				ReservedDays: ptr.To[int64](3),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,106 @@
+package fake
+
+import (
+	"testing"
+
+	eflo "github.com/alibabacloud-go/eflo-controller-20221215/v3/client"
+	nas "github.com/alibabacloud-go/nas-20170626/v4/client"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/klog/v2/ktesting"
+	"k8s.io/utils/ptr"
+)
+
+func TestDirQuota(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	c := New(Options{})
+	c.AddFileSystem("fs-1", "standard", false)
+
+	_, err := wrap.V2(ctx, c.CreateDir)(&nas.CreateDirRequest{
+		FileSystemId:  ptr.To("fs-1"),
+		RootDirectory: ptr.To("/a/b"),
+	})
+	assert.ErrorIs(t, err, wrap.ErrorCode("InvalidParameter.ParentDirNotExist"))
+
+	_, err = c.CreateDir(&nas.CreateDirRequest{
+		FileSystemId:  ptr.To("fs-1"),
+		RootDirectory: ptr.To("/a/b"),
+		Recursion:     ptr.To(true),
+	})
+	require.NoError(t, err)
+
+	resp, err := c.SetDirQuota(&nas.SetDirQuotaRequest{
+		FileSystemId: ptr.To("fs-1"),
+		Path:         ptr.To("/a/b"),
+		QuotaType:    ptr.To("Enforcement"),
+		SizeLimit:    ptr.To[int64](10),
+		UserType:     ptr.To("AllUsers"),
+	})
+	require.NoError(t, err)
+	assert.True(t, *resp.Body.Success)
+	quota := c.DirQuota("fs-1", "/a/b/")
+	require.NotNil(t, quota)
+	assert.Equal(t, int64(10), *quota.SizeLimit)
+
+	cancel := &nas.CancelDirQuotaRequest{
+		FileSystemId: ptr.To("fs-1"),
+		Path:         ptr.To("/a/b"),
+		UserType:     ptr.To("AllUsers"),
+	}
+	_, err = c.CancelDirQuota(cancel)
+	require.NoError(t, err)
+	assert.Nil(t, c.DirQuota("fs-1", "/a/b"))
+
+	_, err = wrap.V2(ctx, c.CancelDirQuota)(cancel)
+	assert.ErrorIs(t, err, wrap.ErrorCode("InvalidParameter.QuotaNotExistOnPath"))
+}
+
+func TestAccessPoint(t *testing.T) {
+	c := New(Options{})
+	c.AddFileSystem("fs-1", "standard", true)
+
+	created, err := c.CreateAccessPoint(&nas.CreateAccessPointRequest{
+		FileSystemId:  ptr.To("fs-1"),
+		RootDirectory: ptr.To("/ap"),
+		PosixUserId:   ptr.To[int32](1000),
+	})
+	require.NoError(t, err)
+	id := created.Body.AccessPoint.AccessPointId
+
+	described, err := c.DescribeAccessPoint(&nas.DescribeAccessPointRequest{FileSystemId: ptr.To("fs-1"), AccessPointId: id})
+	require.NoError(t, err)
+	assert.Equal(t, "/ap", *described.Body.AccessPoint.RootPath)
+	assert.Equal(t, int32(1000), *described.Body.AccessPoint.PosixUser.PosixUserId)
+	assert.Equal(t, *created.Body.AccessPoint.AccessPointDomain, *described.Body.AccessPoint.DomainName)
+
+	_, err = c.DeleteAccessPoint(&nas.DeleteAccessPointRequest{FileSystemId: ptr.To("fs-1"), AccessPointId: id})
+	require.NoError(t, err)
+	_, err = c.DescribeAccessPoint(&nas.DescribeAccessPointRequest{FileSystemId: ptr.To("fs-1"), AccessPointId: id})
+	assert.Error(t, err)
+
+	recycleBin, err := c.GetRecycleBinAttribute(&nas.GetRecycleBinAttributeRequest{FileSystemId: ptr.To("fs-1")})
+	require.NoError(t, err)
+	assert.Equal(t, "Enable", *recycleBin.Body.RecycleBinAttribute.Status)
+
+	fileSystems, err := c.DescribeFileSystems(&nas.DescribeFileSystemsRequest{FileSystemId: ptr.To("fs-1")})
+	require.NoError(t, err)
+	require.Len(t, fileSystems.Body.FileSystems.FileSystem, 1)
+	assert.Equal(t, "standard", *fileSystems.Body.FileSystems.FileSystem[0].FileSystemType)
+}
+
+func TestEFLO(t *testing.T) {
+	c := New(Options{})
+	c.AddEFLONode("e01-1", "efg1.nvga1", 8)
+
+	node, err := c.DescribeNode(&eflo.DescribeNodeRequest{NodeId: ptr.To("e01-1")})
+	require.NoError(t, err)
+	assert.Equal(t, "efg1.nvga1", *node.Body.NodeType)
+
+	nodeType, err := c.DescribeNodeType(&eflo.DescribeNodeTypeRequest{NodeType: node.Body.NodeType})
+	require.NoError(t, err)
+	assert.Equal(t, int32(8), *nodeType.Body.DiskQuantity)
+
+	_, err = c.DescribeNode(&eflo.DescribeNodeRequest{NodeId: ptr.To("e01-404")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,206 @@
+package fake
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+)
+
+// snapshotGroup is a consistent group of snapshots of multiple disks.
+// Its status follows the snapshots in it.
+type snapshotGroup struct {
+	ecs.SnapshotGroup
+	snapshotIDs []string
+	createdAt   time.Time
+}
+
+// describeGroup returns the group with its snapshots, with c.mu held.
+func (c *Cloud) describeGroup(g *snapshotGroup) ecs.SnapshotGroup {
+	res := g.SnapshotGroup
+	res.Tags.Tag = slices.Clone(g.Tags.Tag)
+	res.Status = SnapshotStatusAccomplished
+	for _, id := range g.snapshotIDs {
+		s, ok := c.snapshots[id]
+		if !ok {
+			continue
+		}
+		if s.Status != SnapshotStatusAccomplished {
+			res.Status = SnapshotStatusProgressing
+		}
+		snap := s.Snapshot
+		snap.Tags.Tag = slices.Clone(s.Tags.Tag)
+		res.Snapshots.Snapshot = append(res.Snapshots.Snapshot, snap)
+	}
+	return res
+}
+
+func (c *Cloud) CreateSnapshotGroup(req *ecs.CreateSnapshotGroupRequest) (*ecs.CreateSnapshotGroupResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("CreateSnapshotGroup"); err != nil {
+		return nil, err
+	}
+	var diskIDs []string
+	if req.DiskId != nil {
+		diskIDs = slices.Clone(*req.DiskId)
+	}
+	if req.InstanceId != "" {
+		if _, ok := c.instances[req.InstanceId]; !ok {
+			return nil, ServerError("InvalidInstanceId.NotFound", "The specified InstanceId does not exist.")
+		}
+		if len(diskIDs) == 0 {
+			for _, d := range c.disks {
+				if d.InstanceId == req.InstanceId {
+					diskIDs = append(diskIDs, d.DiskId)
+				}
+			}
+		}
+	}
+	if req.ExcludeDiskId != nil {
+		diskIDs = slices.DeleteFunc(diskIDs, func(id string) bool { return slices.Contains(*req.ExcludeDiskId, id) })
+	}
+	if len(diskIDs) == 0 {
+		return nil, ServerError("MissingParameter", "DiskId or InstanceId is required.")
+	}
+	slices.Sort(diskIDs)
+	params := fmt.Sprintf("%s/%s", strings.Join(diskIDs, ","), req.Name)
+	if req.ClientToken != "" {
+		if created, ok := c.clientTokens[req.ClientToken]; ok {
+			if created.params != params {
+				return nil, ServerError("IdempotentParameterMismatch", "The specified parameter has changed while using an already used clientToken.")
+			}
+			resp := ecs.CreateCreateSnapshotGroupResponse()
+			resp.RequestId = c.requestID()
+			resp.SnapshotGroupId = created.id
+			return resp, nil
+		}
+	}
+	disks := make([]*disk, 0, len(diskIDs))
+	for _, id := range diskIDs {
+		d, ok := c.disks[id]
+		if !ok {
+			return nil, ServerError("InvalidDiskId.NotFound", fmt.Sprintf("The specified disk %s does not exist.", id))
+		}
+		if d.Status != DiskStatusAvailable && d.Status != DiskStatusInUse {
+			return nil, ServerError("IncorrectDiskStatus", "The current disk status does not support this operation.")
+		}
+		if req.InstanceId != "" && d.InstanceId != req.InstanceId {
+			return nil, ServerError("InvalidDiskId.NotBelongToInstance", fmt.Sprintf("The specified disk %s is not attached to the instance.", id))
+		}
+		disks = append(disks, d)
+	}
+
+	var tags []ecs.Tag
+	if req.Tag != nil {
+		for _, t := range *req.Tag {
+			tags = append(tags, ecs.Tag{TagKey: t.Key, TagValue: t.Value})
+		}
+	}
+	now := c.clk.Now()
+	id := c.newID("ssg")
+	g := &snapshotGroup{
+		SnapshotGroup: ecs.SnapshotGroup{
+			SnapshotGroupId: id,
+			Name:            req.Name,
+			Description:     req.Description,
+			InstanceId:      req.InstanceId,
+			ResourceGroupId: req.ResourceGroupId,
+			CreationTime:    now.UTC().Format(timeFormat),
+		},
+		createdAt: now,
+	}
+	g.Tags.Tag = tags
+	for _, d := range disks {
+		s := c.newSnapshot(d, "", "", req.ResourceGroupId, slices.Clone(tags))
+		s.InstantAccess = req.InstantAccess == "true"
+		g.snapshotIDs = append(g.snapshotIDs, s.SnapshotId)
+	}
+	c.groups[id] = g
+	if req.ClientToken != "" {
+		c.clientTokens[req.ClientToken] = createdByToken{id: id, params: params}
+	}
+
+	resp := ecs.CreateCreateSnapshotGroupResponse()
+	resp.RequestId = c.requestID()
+	resp.SnapshotGroupId = id
+	return resp, nil
+}
+
+func (c *Cloud) DescribeSnapshotGroups(req *ecs.DescribeSnapshotGroupsRequest) (*ecs.DescribeSnapshotGroupsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DescribeSnapshotGroups"); err != nil {
+		return nil, err
+	}
+	var ids, statuses []string
+	if req.SnapshotGroupId != nil {
+		ids = *req.SnapshotGroupId
+	}
+	if req.Status != nil {
+		statuses = *req.Status
+	}
+	tags := map[string]string{}
+	if req.Tag != nil {
+		for _, t := range *req.Tag {
+			tags[t.Key] = t.Value
+		}
+	}
+
+	var groups []ecs.SnapshotGroup
+	for _, g := range c.groups {
+		if !c.visible(g.createdAt) {
+			continue
+		}
+		res := c.describeGroup(g)
+		switch {
+		case len(ids) > 0 && !slices.Contains(ids, res.SnapshotGroupId),
+			req.Name != "" && req.Name != res.Name,
+			req.InstanceId != "" && req.InstanceId != res.InstanceId,
+			req.ResourceGroupId != "" && req.ResourceGroupId != res.ResourceGroupId,
+			len(statuses) > 0 && !slices.Contains(statuses, res.Status),
+			!hasTags(res.Tags.Tag, tags):
+			continue
+		}
+		groups = append(groups, res)
+	}
+	slices.SortFunc(groups, func(a, b ecs.SnapshotGroup) int { return strings.Compare(a.SnapshotGroupId, b.SnapshotGroupId) })
+	groups, token, err := paginate(groups, req.MaxResults, req.NextToken, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	resp := ecs.CreateDescribeSnapshotGroupsResponse()
+	resp.RequestId = c.requestID()
+	resp.SnapshotGroups.SnapshotGroup = groups
+	resp.NextToken = token
+	return resp, nil
+}
+
+// DeleteSnapshotGroup deletes the group together with its snapshots.
+func (c *Cloud) DeleteSnapshotGroup(req *ecs.DeleteSnapshotGroupRequest) (*ecs.DeleteSnapshotGroupResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.begin("DeleteSnapshotGroup"); err != nil {
+		return nil, err
+	}
+	g, ok := c.groups[req.SnapshotGroupId]
+	if !ok {
+		return nil, ServerError("InvalidSnapshotGroupId.NotFound", "The specified snapshot group does not exist.")
+	}
+	resp := ecs.CreateDeleteSnapshotGroupResponse()
+	resp.RequestId = c.requestID()
+	for _, id := range g.snapshotIDs {
+		delete(c.snapshots, id)
+		resp.OperationProgressSet.OperationProgress = append(resp.OperationProgressSet.OperationProgress, ecs.OperationProgress{
+			OperationStatus: "Success",
+			RelatedItemSet: ecs.RelatedItemSetInDeleteSnapshotGroup{
+				RelatedItem: []ecs.RelatedItem{{Name: "SnapshotId", Value: id}},
+			},
+		})
+	}
+	delete(c.groups, req.SnapshotGroupId)
+	return resp, nil
+}
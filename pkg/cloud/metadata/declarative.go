@@ -0,0 +1,113 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// METADATA_FILE_ENV points to a JSON file declaring the metadata of the node, e.g.
+//
+//	{"regionId": "cn-hangzhou", "zoneId": "cn-hangzhou-k", "vpcId": "vpc-xxx", "hostType": "external"}
+const METADATA_FILE_ENV = "ALIBABA_CLOUD_METADATA_FILE"
+
+// MetadataAnnotationPrefix is the prefix of node annotations declaring the metadata of the node,
+// e.g. metadata.csi.alibabacloud.com/region-id
+const MetadataAnnotationPrefix = "metadata.csi.alibabacloud.com/"
+
+type declaredKey struct {
+	field      string
+	annotation string
+}
+
+var declaredKeys = map[MetadataKey]declaredKey{
+	RegionID:     {"regionId", "region-id"},
+	ZoneID:       {"zoneId", "zone-id"},
+	InstanceID:   {"instanceId", "instance-id"},
+	InstanceType: {"instanceType", "instance-type"},
+	AccountID:    {"accountId", "account-id"},
+	ClusterID:    {"clusterId", "cluster-id"},
+	VpcID:        {"vpcId", "vpc-id"},
+	VSwitchID:    {"vSwitchId", "vswitch-id"},
+	HostType:     {"hostType", "host-type"},
+}
+
+// DeclarativeMetadata is metadata declared by the user, for nodes where ECS metadata service is not available.
+type DeclarativeMetadata struct {
+	values map[MetadataKey]string
+}
+
+func validateHostType(values map[MetadataKey]string) error {
+	switch values[HostType] {
+	case "", HostTypeECS, HostTypeExternal:
+		return nil
+	default:
+		return fmt.Errorf("invalid hostType %q, should be %s or %s", values[HostType], HostTypeECS, HostTypeExternal)
+	}
+}
+
+func NewDeclarativeMetadataFromFile(path string) (*DeclarativeMetadata, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]string{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("invalid metadata file %s: %w", path, err)
+	}
+	m := &DeclarativeMetadata{values: map[MetadataKey]string{}}
+	for key, k := range declaredKeys {
+		if v := strings.TrimSpace(fields[k.field]); v != "" {
+			m.values[key] = v
+		}
+	}
+	if err := validateHostType(m.values); err != nil {
+		return nil, fmt.Errorf("invalid metadata file %s: %w", path, err)
+	}
+	return m, nil
+}
+
+func NewDeclarativeMetadataFromAnnotations(annotations map[string]string) (*DeclarativeMetadata, error) {
+	m := &DeclarativeMetadata{values: map[MetadataKey]string{}}
+	for key, k := range declaredKeys {
+		if v := strings.TrimSpace(annotations[MetadataAnnotationPrefix+k.annotation]); v != "" {
+			m.values[key] = v
+		}
+	}
+	if err := validateHostType(m.values); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *DeclarativeMetadata) Get(key MetadataKey) (string, error) {
+	if v, ok := m.values[key]; ok {
+		return v, nil
+	}
+	return "", ErrUnknownMetadataKey
+}
+
+type NodeAnnotationFetcher struct {
+	client   corev1.NodeInterface
+	nodeName string
+}
+
+func (f *NodeAnnotationFetcher) FetchFor(key MetadataKey) (MetadataProvider, error) {
+	if _, ok := declaredKeys[key]; !ok {
+		return nil, ErrUnknownMetadataKey
+	}
+	node, err := f.client.Get(context.Background(), f.nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewDeclarativeMetadataFromAnnotations(node.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	return newImmutableProvider(p, "NodeAnnotation"), nil
+}
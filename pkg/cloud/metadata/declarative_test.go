@@ -0,0 +1,117 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata/imds"
+)
+
+func writeMetadataFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestDeclarativeMetadataFromFile(t *testing.T) {
+	path := writeMetadataFile(t, `{"regionId": "cn-hangzhou", "vpcId": "vpc-xxx", "hostType": "external", "unknown": "x"}`)
+	m, err := NewDeclarativeMetadataFromFile(path)
+	require.NoError(t, err)
+
+	v, err := m.Get(RegionID)
+	assert.NoError(t, err)
+	assert.Equal(t, "cn-hangzhou", v)
+	v, err = m.Get(VpcID)
+	assert.NoError(t, err)
+	assert.Equal(t, "vpc-xxx", v)
+	assert.True(t, IsExternalNode(m))
+
+	_, err = m.Get(ZoneID)
+	assert.ErrorIs(t, err, ErrUnknownMetadataKey)
+}
+
+func TestDeclarativeMetadataFromFileInvalid(t *testing.T) {
+	_, err := NewDeclarativeMetadataFromFile(writeMetadataFile(t, `not json`))
+	assert.Error(t, err)
+	_, err = NewDeclarativeMetadataFromFile(writeMetadataFile(t, `{"hostType": "onprem"}`))
+	assert.ErrorContains(t, err, "invalid hostType")
+	_, err = NewDeclarativeMetadataFromFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestDeclarativeMetadataFromAnnotations(t *testing.T) {
+	m, err := NewDeclarativeMetadataFromAnnotations(map[string]string{
+		MetadataAnnotationPrefix + "region-id":  "cn-beijing",
+		MetadataAnnotationPrefix + "vswitch-id": "vsw-xxx",
+		"other":                                 "x",
+	})
+	require.NoError(t, err)
+	v, err := m.Get(RegionID)
+	assert.NoError(t, err)
+	assert.Equal(t, "cn-beijing", v)
+	v, err = m.Get(VSwitchID)
+	assert.NoError(t, err)
+	assert.Equal(t, "vsw-xxx", v)
+	assert.False(t, IsExternalNode(m))
+
+	_, err = NewDeclarativeMetadataFromAnnotations(map[string]string{MetadataAnnotationPrefix + "host-type": "onprem"})
+	assert.Error(t, err)
+}
+
+func TestEnableDeclarative(t *testing.T) {
+	t.Setenv("KUBE_NODE_NAME", "node-1")
+	client := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-1",
+			Annotations: map[string]string{
+				MetadataAnnotationPrefix + "zone-id":   "cn-hangzhou-k",
+				MetadataAnnotationPrefix + "host-type": "external",
+			},
+		},
+	})
+	m := NewMetadata()
+	m.EnableDeclarative(client)
+	v, err := m.Get(ZoneID)
+	assert.NoError(t, err)
+	assert.Equal(t, "cn-hangzhou-k", v)
+	assert.True(t, IsExternalNode(m))
+}
+
+func TestEcsSkippedOnExternalNode(t *testing.T) {
+	t.Setenv(METADATA_FILE_ENV, writeMetadataFile(t, `{"regionId": "cn-hangzhou", "hostType": "external"}`))
+	m := NewMetadata()
+	c1 := len(m.providers)
+	// any request to ECS metadata service would fail
+	m.EnableEcs(httpmock.NewMockTransport())
+	assert.Equal(t, c1, len(m.providers))
+
+	v, err := m.Get(RegionID)
+	assert.NoError(t, err)
+	assert.Equal(t, "cn-hangzhou", v)
+}
+
+func TestDeclarativeNotReadOnEcs(t *testing.T) {
+	t.Setenv("KUBE_NODE_NAME", "node-1")
+	trans := httpmock.NewMockTransport()
+	trans.RegisterResponder("PUT", imds.ECSTokenEndpoint, httpmock.NewStringResponder(200, "fake_metadata_token"))
+	trans.RegisterResponder("GET", imds.ECSMetadataEndpoint+ECSIdentityPath, httpmock.NewStringResponder(200, testIdDoc))
+	client := fake.NewSimpleClientset()
+
+	m := NewMetadata()
+	m.EnableEcs(trans)
+	m.EnableDeclarative(client)
+	assert.False(t, IsExternalNode(m))
+	v, err := m.Get(ZoneID)
+	assert.NoError(t, err)
+	assert.Equal(t, "cn-beijing-k", v)
+	assert.Empty(t, client.Actions(), "node should not be read on ECS")
+}
@@ -61,6 +61,8 @@ func (m *ECSMetadata) Get(key MetadataKey) (string, error) {
 		return m.idDoc.InstanceType, nil
 	case AccountID:
 		return m.idDoc.OwnerAccountID, nil
+	case HostType:
+		return HostTypeECS, nil
 	default:
 		return "", ErrUnknownMetadataKey
 	}
@@ -72,7 +74,7 @@ type EcsFetcher struct {
 
 func (f *EcsFetcher) FetchFor(key MetadataKey) (MetadataProvider, error) {
 	switch key {
-	case RegionID, ZoneID, InstanceID, InstanceType, AccountID:
+	case RegionID, ZoneID, InstanceID, InstanceType, AccountID, HostType:
 	default:
 		return nil, ErrUnknownMetadataKey
 	}
@@ -29,6 +29,10 @@ func (m *ECSDynamic) Get(key MetadataKey) (string, error) {
 	switch key {
 	case RAMRoleName:
 		return m.fetch("meta-data/ram/security-credentials/")
+	case VpcID:
+		return m.fetch("meta-data/vpc-id")
+	case VSwitchID:
+		return m.fetch("meta-data/vswitch-id")
 	default:
 		return "", ErrUnknownMetadataKey
 	}
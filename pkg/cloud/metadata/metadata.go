@@ -27,6 +27,17 @@ const (
 	DataPlaneZoneID
 	RAMRoleName
 	RRSATokenFile
+	VpcID
+	VSwitchID
+	HostType
+)
+
+// values of HostType
+const (
+	HostTypeECS = "ecs"
+	// HostTypeExternal is a node outside Alibaba Cloud, e.g. an IDC node in ACK One registered cluster.
+	// ECS metadata service is not reachable, and ECS-only features are disabled.
+	HostTypeExternal = "external"
 )
 
 const LingjunConfigFile = "/host/etc/eflo_config/lingjun_config"
@@ -53,6 +64,12 @@ func (k MetadataKey) String() string {
 		return "RAMRoleName"
 	case RRSATokenFile:
 		return "RRSATokenFile"
+	case VpcID:
+		return "VpcID"
+	case VSwitchID:
+		return "VSwitchID"
+	case HostType:
+		return "HostType"
 	default:
 		return fmt.Sprintf("MetadataKey(%d)", k)
 	}
@@ -143,6 +160,14 @@ func NewMetadata() *Metadata {
 			newImmutableProvider(&ENVMetadata{}, "env"),
 		},
 	}
+	if path := os.Getenv(METADATA_FILE_ENV); path != "" {
+		dm, err := NewDeclarativeMetadataFromFile(path)
+		if err != nil {
+			klog.ErrorS(err, "failed to load metadata file", "file", path)
+		} else {
+			defaultMetadata.providers = append(defaultMetadata.providers, newImmutableProvider(dm, "file"))
+		}
+	}
 	lm, err := NewLingJunMetadata(LingjunConfigFile)
 	if err != nil {
 		return defaultMetadata
@@ -153,11 +178,30 @@ func NewMetadata() *Metadata {
 	return defaultMetadata
 }
 
+// EnableDeclarative reads metadata from annotations of the current node.
+// Call it after EnableEcs, so that the node is only read when ECS metadata is unavailable.
+func (m *Metadata) EnableDeclarative(client kubernetes.Interface) {
+	nodeName := os.Getenv(KUBE_NODE_NAME_ENV)
+	if nodeName == "" {
+		return
+	}
+	m.providers = append(m.providers, &lazyInitProvider{
+		fetcher: &NodeAnnotationFetcher{
+			client:   client.CoreV1().Nodes(),
+			nodeName: nodeName,
+		},
+	})
+}
+
 func (m *Metadata) EnableEcs(httpRT http.RoundTripper) {
 	if os.Getenv(DISABLE_ECS_ENV) != "" {
 		klog.Infof("ECS metadata is disabled by environment variable %s", DISABLE_ECS_ENV)
 		return
 	}
+	if IsExternalNode(m) {
+		klog.Info("ECS metadata is disabled on external node")
+		return
+	}
 	m.providers = append(m.providers, &lazyInitProvider{
 		fetcher: &EcsFetcher{httpRT: httpRT},
 	}, NewEcsDynamic(httpRT))
@@ -247,6 +291,12 @@ func GetFallbackZoneID(m MetadataProvider) (string, error) {
 	}
 }
 
+// IsExternalNode reports whether the node is declared to be outside Alibaba Cloud ECS.
+func IsExternalNode(m MetadataProvider) bool {
+	hostType, err := m.Get(HostType)
+	return err == nil && hostType == HostTypeExternal
+}
+
 // FakeProvider is a fake metadata provider for testing
 type FakeProvider struct {
 	Values map[MetadataKey]string
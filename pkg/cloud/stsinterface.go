@@ -3,5 +3,6 @@ package cloud
 import sts20150401 "github.com/alibabacloud-go/sts-20150401/v2/client"
 
 type STSInterface interface {
+	AssumeRole(request *sts20150401.AssumeRoleRequest) (response *sts20150401.AssumeRoleResponse, err error)
 	GetCallerIdentity() (response *sts20150401.GetCallerIdentityResponse, err error)
 }
@@ -34,6 +34,21 @@ func (m *MockSTSInterface) EXPECT() *MockSTSInterfaceMockRecorder {
 	return m.recorder
 }
 
+// AssumeRole mocks base method.
+func (m *MockSTSInterface) AssumeRole(request *client.AssumeRoleRequest) (*client.AssumeRoleResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssumeRole", request)
+	ret0, _ := ret[0].(*client.AssumeRoleResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssumeRole indicates an expected call of AssumeRole.
+func (mr *MockSTSInterfaceMockRecorder) AssumeRole(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssumeRole", reflect.TypeOf((*MockSTSInterface)(nil).AssumeRole), request)
+}
+
 // GetCallerIdentity mocks base method.
 func (m *MockSTSInterface) GetCallerIdentity() (*client.GetCallerIdentityResponse, error) {
 	m.ctrl.T.Helper()
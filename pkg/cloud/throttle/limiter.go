@@ -0,0 +1,322 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	alierrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// Priority of a request waiting for the Limiter.
+// Requests of higher priority are always served first.
+type Priority int
+
+const (
+	// PriorityLow is for bulk operations, e.g. creating disks.
+	PriorityLow Priority = iota
+	PriorityNormal
+	// PriorityHigh is for operations releasing resources, e.g. detaching or deleting disks,
+	// which should not be starved by bulk creations.
+	PriorityHigh
+	numPriorities
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+	return "unknown"
+}
+
+const (
+	// increaseInterval is the minimum interval between rate increases.
+	// OpenAPI quota is usually enforced every minute, so don't increase too fast.
+	increaseInterval = 10 * time.Second
+	// increaseStep is the portion of MaxRate added on each increase.
+	increaseStep = 0.1
+)
+
+type LimiterConfig struct {
+	// MaxRate is the initial and maximum rate in requests per second.
+	MaxRate float64
+	// MinRate is the rate the Limiter will never go below, even if throttling persists.
+	MinRate float64
+	// Burst is the maximum number of requests sent at once.
+	Burst int
+}
+
+// Limiter is a token bucket whose rate is learned from observed throttling.
+// The rate is halved every time OpenAPI returns Throttling,
+// and slowly increased back to MaxRate when requests succeed.
+type Limiter struct {
+	name string
+	cfg  LimiterConfig
+	clk  clock.WithDelayedExecution
+
+	mu         sync.Mutex
+	rate       float64
+	tokens     float64
+	last       time.Time
+	lastAdjust time.Time
+	waiters    [numPriorities][]*waiter
+	timer      clock.Timer
+	throttled  int
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func (cfg LimiterConfig) normalize() LimiterConfig {
+	cfg.Burst = max(cfg.Burst, 1)
+	cfg.MinRate = min(cfg.MinRate, cfg.MaxRate)
+	return cfg
+}
+
+func NewLimiter(clk clock.WithDelayedExecution, name string, cfg LimiterConfig) *Limiter {
+	cfg = cfg.normalize()
+	now := clk.Now()
+	return &Limiter{
+		name:       name,
+		cfg:        cfg,
+		clk:        clk,
+		rate:       cfg.MaxRate,
+		tokens:     float64(cfg.Burst),
+		last:       now,
+		lastAdjust: now,
+	}
+}
+
+func (l *Limiter) Name() string {
+	return l.name
+}
+
+func (l *Limiter) Config() LimiterConfig {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cfg
+}
+
+func (l *Limiter) configure(cfg LimiterConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.cfg = cfg.normalize()
+	l.rate = min(max(l.rate, l.cfg.MinRate), l.cfg.MaxRate)
+	l.tokens = min(l.tokens, float64(l.cfg.Burst))
+}
+
+// refill must be called with l.mu held.
+func (l *Limiter) refill() {
+	now := l.clk.Now()
+	l.tokens = min(float64(l.cfg.Burst), l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+}
+
+func (l *Limiter) waitingFrom(p Priority) bool {
+	for i := p; i < numPriorities; i++ {
+		if len(l.waiters[i]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Wait blocks until a request of priority p is allowed, or ctx is done.
+func (l *Limiter) Wait(ctx context.Context, p Priority) error {
+	l.mu.Lock()
+	l.refill()
+	if !l.waitingFrom(p) && l.tokens >= 1 {
+		l.tokens--
+		l.mu.Unlock()
+		return nil
+	}
+	w := &waiter{ready: make(chan struct{})}
+	l.waiters[p] = append(l.waiters[p], w)
+	l.schedule()
+	l.mu.Unlock()
+
+	klog.FromContext(ctx).V(4).Info("waiting for OpenAPI rate limiter", "limiter", l.name, "priority", p)
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if w.granted {
+			return nil
+		}
+		l.waiters[p] = slices.DeleteFunc(l.waiters[p], func(o *waiter) bool { return o == w })
+		return ctx.Err()
+	}
+}
+
+// schedule must be called with l.mu held.
+func (l *Limiter) schedule() {
+	if l.timer != nil || l.rate <= 0 {
+		return
+	}
+	d := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	l.timer = l.clk.AfterFunc(max(d, 0), l.dispatch)
+}
+
+func (l *Limiter) dispatch() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timer = nil
+	l.refill()
+	for p := numPriorities - 1; p >= 0 && l.tokens >= 1; {
+		if len(l.waiters[p]) == 0 {
+			p--
+			continue
+		}
+		w := l.waiters[p][0]
+		l.waiters[p] = l.waiters[p][1:]
+		w.granted = true
+		close(w.ready)
+		l.tokens--
+	}
+	if l.waitingFrom(PriorityLow) {
+		l.schedule()
+	}
+}
+
+// Observe adjusts the rate according to the result of a request.
+func (l *Limiter) Observe(err error) {
+	throttling := IsThrottling(err)
+	if err != nil && !throttling && !isServerError(err) {
+		// We are not sure whether the request reached the server.
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clk.Now()
+	if throttling {
+		l.refill()
+		l.throttled++
+		l.tokens = 0
+		newRate := max(l.rate/2, l.cfg.MinRate)
+		if newRate != l.rate {
+			klog.V(2).InfoS("OpenAPI throttling, decreasing rate", "limiter", l.name, "rate", newRate)
+		}
+		l.rate = newRate
+		l.lastAdjust = now
+		return
+	}
+	if l.rate < l.cfg.MaxRate && now.Sub(l.lastAdjust) >= increaseInterval {
+		l.refill()
+		l.rate = min(l.rate+l.cfg.MaxRate*increaseStep, l.cfg.MaxRate)
+		l.lastAdjust = now
+		klog.V(4).InfoS("increasing OpenAPI rate", "limiter", l.name, "rate", l.rate)
+	}
+}
+
+type LimiterStats struct {
+	Name string
+	// Rate is the current learned rate in requests per second
+	Rate   float64
+	Tokens float64
+	// Waiting is the number of requests waiting, by priority
+	Waiting [numPriorities]int
+	// Throttled is the total number of Throttling errors observed
+	Throttled int
+}
+
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	s := LimiterStats{
+		Name:      l.name,
+		Rate:      l.rate,
+		Tokens:    l.tokens,
+		Throttled: l.throttled,
+	}
+	for p := range numPriorities {
+		s.Waiting[p] = len(l.waiters[p])
+	}
+	return s
+}
+
+// IsThrottling reports whether err is a Throttling error from either v1 or v2 SDK.
+func IsThrottling(err error) bool {
+	if err == nil {
+		return false
+	}
+	var alierr *alierrors.ServerError
+	if errors.As(err, &alierr) {
+		return isThrottlingCode(alierr.ErrorCode())
+	}
+	var teaErr *tea.SDKError
+	if errors.As(err, &teaErr) && teaErr.Code != nil {
+		return isThrottlingCode(*teaErr.Code)
+	}
+	return false
+}
+
+func isThrottlingCode(code string) bool {
+	return code == "Throttling" || strings.HasPrefix(code, "Throttling.")
+}
+
+func isServerError(err error) bool {
+	var alierr *alierrors.ServerError
+	var teaErr *tea.SDKError
+	return errors.As(err, &alierr) || errors.As(err, &teaErr)
+}
+
+// Registry holds the limiters shared by all clients in this process, one for each OpenAPI.
+type Registry struct {
+	clk clock.WithDelayedExecution
+
+	mu       sync.Mutex
+	limiters map[string]*Limiter
+}
+
+var DefaultRegistry = NewRegistry(clock.RealClock{})
+
+func NewRegistry(clk clock.WithDelayedExecution) *Registry {
+	return &Registry{
+		clk:      clk,
+		limiters: map[string]*Limiter{},
+	}
+}
+
+// Get returns the limiter of name, creating it with cfg if not exists.
+// An existing limiter is reconfigured with cfg, unless cfg is zero.
+func (r *Registry) Get(name string, cfg LimiterConfig) *Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.limiters[name]
+	if !ok {
+		l = NewLimiter(r.clk, name, cfg)
+		r.limiters[name] = l
+	} else if cfg != (LimiterConfig{}) {
+		l.configure(cfg)
+	}
+	return l
+}
+
+// Limiters returns all the limiters sorted by name.
+func (r *Registry) Limiters() []*Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := make([]*Limiter, 0, len(r.limiters))
+	for _, l := range r.limiters {
+		res = append(res, l)
+	}
+	slices.SortFunc(res, func(a, b *Limiter) int { return strings.Compare(a.name, b.name) })
+	return res
+}
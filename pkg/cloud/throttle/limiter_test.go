@@ -0,0 +1,168 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/klog/v2/ktesting"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
+)
+
+func TestLimiterRate(t *testing.T) { synctest.Test(t, testLimiterRateSync) }
+func testLimiterRateSync(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	l := NewLimiter(clock.RealClock{}, "test", LimiterConfig{MaxRate: 2, Burst: 4})
+
+	start := time.Now()
+	for range 10 {
+		require.NoError(t, l.Wait(ctx, PriorityNormal))
+	}
+	// 4 from burst, then 2 per second
+	assert.Equal(t, 3*time.Second, time.Since(start))
+}
+
+func TestLimiterPriority(t *testing.T) { synctest.Test(t, testLimiterPrioritySync) }
+func testLimiterPrioritySync(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	l := NewLimiter(clock.RealClock{}, "test", LimiterConfig{MaxRate: 1, Burst: 1})
+	require.NoError(t, l.Wait(ctx, PriorityNormal))
+
+	order := make(chan Priority, 3)
+	for _, p := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		go func() {
+			assert.NoError(t, l.Wait(ctx, p))
+			order <- p
+		}()
+		synctest.Wait()
+	}
+	assert.Equal(t, [numPriorities]int{1, 1, 1}, l.Stats().Waiting)
+
+	time.Sleep(3 * time.Second)
+	synctest.Wait()
+	close(order)
+	var got []Priority
+	for p := range order {
+		got = append(got, p)
+	}
+	assert.Equal(t, []Priority{PriorityHigh, PriorityNormal, PriorityLow}, got)
+}
+
+func TestLimiterCancel(t *testing.T) { synctest.Test(t, testLimiterCancelSync) }
+func testLimiterCancelSync(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	l := NewLimiter(clock.RealClock{}, "test", LimiterConfig{MaxRate: 1, Burst: 1})
+	require.NoError(t, l.Wait(ctx, PriorityNormal))
+
+	ctxToCancel, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.Wait(ctxToCancel, PriorityHigh), context.DeadlineExceeded)
+	assert.Equal(t, [numPriorities]int{}, l.Stats().Waiting)
+
+	// canceled waiter should not block others
+	start := time.Now()
+	require.NoError(t, l.Wait(ctx, PriorityLow))
+	assert.Equal(t, 500*time.Millisecond, time.Since(start))
+}
+
+func TestLimiterAdaptive(t *testing.T) { synctest.Test(t, testLimiterAdaptiveSync) }
+func testLimiterAdaptiveSync(t *testing.T) {
+	l := NewLimiter(clock.RealClock{}, "test", LimiterConfig{MaxRate: 10, MinRate: 2, Burst: 10})
+
+	l.Observe(ErrThrottling)
+	s := l.Stats()
+	assert.Equal(t, 5.0, s.Rate)
+	assert.Equal(t, 0.0, s.Tokens)
+	assert.Equal(t, 1, s.Throttled)
+
+	l.Observe(ErrThrottling)
+	l.Observe(ErrThrottling)
+	assert.Equal(t, 2.0, l.Stats().Rate, "should not go below MinRate")
+
+	// unknown errors are ignored
+	l.Observe(errors.New("connection reset"))
+	assert.Equal(t, 3, l.Stats().Throttled)
+
+	l.Observe(nil)
+	assert.Equal(t, 2.0, l.Stats().Rate, "should not increase too fast")
+
+	for range 100 {
+		time.Sleep(increaseInterval)
+		l.Observe(nil)
+	}
+	assert.Equal(t, 10.0, l.Stats().Rate, "should not exceed MaxRate")
+}
+
+func TestIsThrottling(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{errors.New("Throttling"), false},
+		{ErrThrottling, true},
+		{fmt.Errorf("wrapped: %w", ErrThrottling), true},
+		{&tea.SDKError{Code: ptr.To("Throttling.User")}, true},
+		{&tea.SDKError{Code: ptr.To("InvalidParameter")}, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, IsThrottling(c.err), "%v", c.err)
+	}
+}
+
+func TestThrottlerWithLimiter(t *testing.T) { synctest.Test(t, testThrottlerWithLimiterSync) }
+func testThrottlerWithLimiterSync(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	r := NewRegistry(clock.RealClock{})
+	l := r.Get("ecs.CreateDisk", LimiterConfig{MaxRate: 4, Burst: 1})
+	assert.Same(t, l, r.Get("ecs.CreateDisk", LimiterConfig{}))
+	throttler := NewThrottler(clock.RealClock{}, 1*time.Second, 10*time.Second).WithLimiter(l, PriorityLow)
+
+	calls := 0
+	err := throttler.Throttle(ctx, func() error {
+		calls++
+		if calls == 1 {
+			return ErrThrottling
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 2.0, l.Stats().Rate)
+	assert.Equal(t, []*Limiter{l}, r.Limiters())
+
+	ctxToCancel, cancel := context.WithCancel(ctx)
+	cancel()
+	err = throttler.Throttle(ctxToCancel, func() error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestThrottlerWaitDuration(t *testing.T) { synctest.Test(t, testThrottlerWaitDurationSync) }
+func testThrottlerWaitDurationSync(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	l := NewLimiter(clock.RealClock{}, "test", LimiterConfig{MaxRate: 1, Burst: 1})
+	throttler := NewThrottler(clock.RealClock{}, 1*time.Second, 10*time.Second).Named("ecs.Test").WithLimiter(l, PriorityNormal)
+
+	for range 2 {
+		err := throttler.Throttle(ctx, func() error {
+			time.Sleep(500 * time.Millisecond) // should not be counted
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	m := &dto.Metric{}
+	require.NoError(t, WaitDuration.WithLabelValues("ecs.Test").(prometheus.Metric).Write(m))
+	assert.Equal(t, uint64(2), m.GetHistogram().GetSampleCount())
+	// The second request waits for the limiter to refill the remaining half token.
+	assert.Equal(t, 0.5, m.GetHistogram().GetSampleSum())
+}
@@ -0,0 +1,14 @@
+package throttle
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WaitDuration is the time requests spent waiting for throttling or rate limiting before sent.
+var WaitDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "csi",
+	Subsystem: "openapi",
+	Name:      "throttle_wait_seconds",
+	Help:      "Time spent waiting for OpenAPI throttling or rate limiter before sending requests.",
+	Buckets:   []float64{.01, .1, .5, 1, 2.5, 5, 10, 30, 60, 120},
+}, []string{"api"})
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	alierrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 )
@@ -24,6 +26,9 @@ func (t throttlingError) Unwrap() error {
 	return t.err
 }
 
+// minTracedWait is the minimum wait time to be recorded as a span.
+const minTracedWait = 10 * time.Millisecond
+
 type throttlingContext struct {
 	probing chan struct{}
 
@@ -37,6 +42,10 @@ type Throttler struct {
 
 	clk clock.Clock
 
+	name     string
+	limiter  *Limiter
+	priority Priority
+
 	current atomic.Pointer[throttlingContext]
 }
 
@@ -48,6 +57,20 @@ func NewThrottler(clk clock.Clock, initDelay, maxDelay time.Duration) *Throttler
 	}
 }
 
+// Named sets the api name used in metrics. Wait time is only recorded for named throttlers.
+func (t *Throttler) Named(name string) *Throttler {
+	t.name = name
+	return t
+}
+
+// WithLimiter makes every request wait for l with priority p before sent,
+// and feeds the result back to l. It should be called right after NewThrottler.
+func (t *Throttler) WithLimiter(l *Limiter, p Priority) *Throttler {
+	t.limiter = l
+	t.priority = p
+	return t
+}
+
 // Throttle will call f and retry after delay when Throttling error code is returned.
 // If one Throttling error is observed, all subsequent requests will be delayed.
 // A random delayed request will be used to probe whether the throttling has ended.
@@ -58,10 +81,17 @@ func NewThrottler(clk clock.Clock, initDelay, maxDelay time.Duration) *Throttler
 func (t *Throttler) Throttle(ctx context.Context, f func() error) error {
 	logger := klog.FromContext(ctx)
 	start := t.clk.Now()
+	var inFlight time.Duration
+	if t.name != "" {
+		defer func() {
+			WaitDuration.WithLabelValues(t.name).Observe((t.clk.Since(start) - inFlight).Seconds())
+		}()
+	}
 	for {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
+		waitStart := t.clk.Now()
 		tCtx := t.current.Load()
 		if tCtx != nil {
 			logger.V(3).Info("OpenAPI throttling in effect")
@@ -86,7 +116,25 @@ func (t *Throttler) Throttle(ctx context.Context, f func() error) error {
 			}
 		}
 
+		if t.limiter != nil {
+			if err := t.limiter.Wait(ctx, t.priority); err != nil {
+				if tCtx != nil {
+					// Let others probe.
+					tCtx.probing <- struct{}{}
+				}
+				return throttlingError{err}
+			}
+		}
+
+		sent := t.clk.Now()
+		if sent.Sub(waitStart) >= minTracedWait {
+			tracing.Record(ctx, "throttle.Wait", waitStart, attribute.String("api", t.name))
+		}
 		err := f()
+		inFlight += t.clk.Since(sent)
+		if t.limiter != nil {
+			t.limiter.Observe(err)
+		}
 
 		var alierr *alierrors.ServerError
 		if err != nil && !errors.As(err, &alierr) {
@@ -0,0 +1,163 @@
+package wrap
+
+import (
+	"errors"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of ErrorInfo in gRPC status details returned for classified OpenAPI errors.
+const ErrorDomain = "openapi.alibabacloud.com"
+
+// ErrorClass explains a kind of OpenAPI errors to users, so they can fix it without reading controller logs.
+type ErrorClass struct {
+	// Reason is a short CamelCase name, used as the reason of Events and in metrics.
+	Reason string
+	// Hint tells users what to do about it.
+	Hint string
+	// Code is the gRPC code to return.
+	Code codes.Code
+}
+
+var errorClasses = []struct {
+	// error codes, matched exactly or as a prefix followed by "."
+	codes []string
+	class ErrorClass
+}{
+	{
+		codes: []string{"OperationDenied.NoStock", "Zone.NotOnSale", "ResourceNotAvailable", "InvalidResourceType.NotSupported"},
+		class: ErrorClass{
+			Reason: "OutOfStock",
+			Hint:   "The requested resource is out of stock in this zone. Try another zone, or allow more disk categories in the StorageClass.",
+			Code:   codes.ResourceExhausted,
+		},
+	},
+	{
+		codes: []string{"QuotaExceed", "QuotaExhausted", "QuotaExceeded"},
+		class: ErrorClass{
+			Reason: "QuotaExceeded",
+			Hint:   "The quota of the account is exceeded. Release unused resources, or request a higher quota in Quota Center.",
+			Code:   codes.ResourceExhausted,
+		},
+	},
+	{
+		codes: []string{"Forbidden.RAM", "Forbidden.SubUser", "Forbidden.NoPermission", "NoPermission", "Forbidden.AccessDenied"},
+		class: ErrorClass{
+			Reason: "PermissionDenied",
+			Hint:   "The CSI driver is not authorized to call this API. Grant the RAM policy of the CSI driver to its role.",
+			Code:   codes.PermissionDenied,
+		},
+	},
+	{
+		codes: []string{"InvalidAccountStatus", "Account.Arrearage", "Forbidden.Arrearage"},
+		class: ErrorClass{
+			Reason: "AccountUnavailable",
+			Hint:   "The account has overdue payments or is not enabled for this service. Check the account status and balance.",
+			Code:   codes.FailedPrecondition,
+		},
+	},
+	{
+		codes: []string{"InstanceLockedForSecurity", "IncorrectInstanceStatus.LockedForSecurity", "DiskLockedForSecurity"},
+		class: ErrorClass{
+			Reason: "ResourceLocked",
+			Hint:   "The resource is locked for security reasons. Check the security notifications of the account.",
+			Code:   codes.FailedPrecondition,
+		},
+	},
+	{
+		codes: []string{"InvalidVSwitchId.IpNotEnough", "InvalidVSwitchId.IpInsufficient"},
+		class: ErrorClass{
+			Reason: "IPExhausted",
+			Hint:   "No IP address left in the vSwitch. Use another vSwitch, or release unused IP addresses.",
+			Code:   codes.ResourceExhausted,
+		},
+	},
+	{
+		codes: []string{"InvalidParameter", "MissingParameter", "InvalidDiskCategory", "InvalidDiskSize", "InvalidPerformanceLevel"},
+		class: ErrorClass{
+			Reason: "InvalidParameter",
+			Hint:   "The request is rejected. Check the parameters of the StorageClass.",
+			Code:   codes.InvalidArgument,
+		},
+	},
+	{
+		codes: []string{"Throttling"},
+		class: ErrorClass{
+			Reason: "Throttled",
+			Hint:   "OpenAPI is throttled, the request will be retried.",
+			Code:   codes.Unavailable,
+		},
+	},
+}
+
+// ClassifyCode returns the class of an OpenAPI error code.
+func ClassifyCode(code string) (ErrorClass, bool) {
+	for _, c := range errorClasses {
+		for _, p := range c.codes {
+			if code == p || strings.HasPrefix(code, p+".") {
+				return c.class, true
+			}
+		}
+	}
+	return ErrorClass{}, false
+}
+
+// errorCode returns the OpenAPI error code in the chain of err.
+func errorCode(err error) string {
+	var code ErrorCode
+	if errors.As(err, &code) {
+		return string(code)
+	}
+	var aliErr aliError
+	if errors.As(err, &aliErr) {
+		return aliErr.ErrorCode()
+	}
+	return ""
+}
+
+// Classify returns the class and error code of the OpenAPI error in the chain of err.
+func Classify(err error) (ErrorClass, string, bool) {
+	code := errorCode(err)
+	if code == "" {
+		return ErrorClass{}, "", false
+	}
+	class, ok := ClassifyCode(code)
+	return class, code, ok
+}
+
+// ToStatus converts err to a gRPC status error.
+// If err is a classified OpenAPI error, the code of its class is used instead of defaultCode,
+// the hint is appended to the message, and an ErrorInfo is attached as details.
+func ToStatus(defaultCode codes.Code, msg string, err error) error {
+	class, code, ok := Classify(err)
+	if !ok {
+		return status.Error(defaultCode, msg)
+	}
+	st := status.New(class.Code, msg+". "+class.Hint)
+	withDetails, dErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   class.Reason,
+		Domain:   ErrorDomain,
+		Metadata: map[string]string{"errorCode": code, "hint": class.Hint},
+	})
+	if dErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// ErrorInfo returns the ErrorInfo attached by ToStatus, or nil.
+func ErrorInfo(err error) *errdetails.ErrorInfo {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == ErrorDomain {
+			return info
+		}
+	}
+	return nil
+}
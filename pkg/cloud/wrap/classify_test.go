@@ -0,0 +1,72 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	alierrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func TestClassifyCode(t *testing.T) {
+	cases := map[string]string{
+		"OperationDenied.NoStock":                  "OutOfStock",
+		"QuotaExceed.PostPaidDisk":                 "QuotaExceeded",
+		"Forbidden.RAM":                            "PermissionDenied",
+		"InvalidAccountStatus.NotEnoughBalance":    "AccountUnavailable",
+		"InstanceLockedForSecurity":                "ResourceLocked",
+		"InvalidParameter.Encrypted.KmsNotEnabled": "InvalidParameter",
+		"Throttling.User":                          "Throttled",
+	}
+	for code, reason := range cases {
+		class, ok := ClassifyCode(code)
+		assert.True(t, ok, code)
+		assert.Equal(t, reason, class.Reason, code)
+	}
+
+	for _, code := range []string{"", "InvalidDiskId.NotFound", "InvalidParameterX", "ForbiddenX"} {
+		_, ok := ClassifyCode(code)
+		assert.False(t, ok, code)
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	ctrl := gomock.NewController(t)
+	ecsClient := cloud.NewMockECSInterface(ctrl)
+
+	reason := ErrorReasonsTotal.WithLabelValues("ecs", "CreateDisk", "OutOfStock")
+	before := counterValue(t, reason)
+
+	ecsClient.EXPECT().CreateDisk(gomock.Any()).Return(nil,
+		alierrors.NewServerError(403, `{"Code": "OperationDenied.NoStock", "Message": "no stock"}`, ""))
+	_, err := V1(ctx, ecsClient.CreateDisk)(ecs.CreateCreateDiskRequest())
+	require.Error(t, err)
+	assert.Equal(t, before+1, counterValue(t, reason))
+
+	// wrapped errors are classified too
+	err = ToStatus(codes.Internal, "create disk failed", fmt.Errorf("attempt failed: %w", err))
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Contains(t, st.Message(), "create disk failed. The requested resource is out of stock")
+
+	info := ErrorInfo(err)
+	require.NotNil(t, info)
+	assert.Equal(t, "OutOfStock", info.Reason)
+	assert.Equal(t, ErrorDomain, info.Domain)
+	assert.Equal(t, "OperationDenied.NoStock", info.Metadata["errorCode"])
+
+	err = ToStatus(codes.Internal, "network error", errors.New("network error"))
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Nil(t, ErrorInfo(err))
+	assert.Nil(t, ErrorInfo(errors.New("not a status")))
+}
@@ -0,0 +1,63 @@
+package wrap
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// RequestDuration is the latency of every OpenAPI call wrapped by V1 or V2.
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "csi",
+		Subsystem: "openapi",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of Alibaba Cloud OpenAPI requests.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"product", "action"})
+	// RequestsTotal counts every OpenAPI call wrapped by V1 or V2, by error code.
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "csi",
+		Subsystem: "openapi",
+		Name:      "requests_total",
+		Help:      "Number of Alibaba Cloud OpenAPI requests by error code.",
+	}, []string{"product", "action", "code"})
+	// ErrorReasonsTotal counts OpenAPI errors by the reason of their ErrorClass.
+	ErrorReasonsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "csi",
+		Subsystem: "openapi",
+		Name:      "error_reasons_total",
+		Help:      "Number of Alibaba Cloud OpenAPI errors by user-facing reason, e.g. OutOfStock.",
+	}, []string{"product", "action", "reason"})
+)
+
+const (
+	codeOK = "OK"
+	// codeUnknown is for errors without an error code, e.g. network errors.
+	codeUnknown = "Unknown"
+)
+
+func observe(product, action, code string, err error, duration time.Duration) {
+	if code == "" {
+		code = codeOK
+		if err != nil {
+			code = codeUnknown
+		}
+	}
+	RequestDuration.WithLabelValues(product, action).Observe(duration.Seconds())
+	RequestsTotal.WithLabelValues(product, action, code).Inc()
+	if class, ok := ClassifyCode(code); ok {
+		ErrorReasonsTotal.WithLabelValues(product, action, class.Reason).Inc()
+	}
+}
+
+// v2Product guesses the product from the package of the request type.
+// e.g. github.com/alibabacloud-go/nas-20170626/v4/client -> nas
+func v2Product(t reflect.Type) string {
+	pkg := strings.TrimPrefix(t.PkgPath(), "github.com/alibabacloud-go/")
+	pkg, _, _ = strings.Cut(pkg, "/")
+	product, _, _ := strings.Cut(pkg, "-")
+	return product
+}
@@ -0,0 +1,72 @@
+package wrap
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	nas20170626 "github.com/alibabacloud-go/nas-20170626/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/klog/v2/ktesting"
+	"k8s.io/utils/ptr"
+)
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	require.NoError(t, c.Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func TestV2Product(t *testing.T) {
+	assert.Equal(t, "nas", v2Product(reflect.TypeFor[nas20170626.CreateDirRequest]()))
+}
+
+func TestMetricsV1(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	ctrl := gomock.NewController(t)
+	ecsClient := cloud.NewMockECSInterface(ctrl)
+
+	ok := RequestsTotal.WithLabelValues("ecs", "DescribeDisks", "OK")
+	unknown := RequestsTotal.WithLabelValues("ecs", "DescribeDisks", "Unknown")
+	okBefore, unknownBefore := counterValue(t, ok), counterValue(t, unknown)
+
+	ecsClient.EXPECT().DescribeDisks(gomock.Any()).Return(ecs.CreateDescribeDisksResponse(), nil)
+	ecsClient.EXPECT().DescribeDisks(gomock.Any()).Return(nil, errors.New("network error"))
+	_, err := V1(ctx, ecsClient.DescribeDisks)(ecs.CreateDescribeDisksRequest())
+	require.NoError(t, err)
+	_, err = V1(ctx, ecsClient.DescribeDisks)(ecs.CreateDescribeDisksRequest())
+	require.Error(t, err)
+
+	assert.Equal(t, okBefore+1, counterValue(t, ok))
+	assert.Equal(t, unknownBefore+1, counterValue(t, unknown))
+}
+
+func TestMetricsV2(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	ctrl := gomock.NewController(t)
+	nasClient := cloud.NewMockNasInterface(ctrl)
+
+	notFound := RequestsTotal.WithLabelValues("nas", "CreateDir", "InvalidFileSystem.NotFound")
+	before := counterValue(t, notFound)
+
+	nasClient.EXPECT().CreateDir(gomock.Any()).Return(nil, &tea.SDKError{Code: ptr.To("InvalidFileSystem.NotFound")})
+	_, err := V2(ctx, nasClient.CreateDir)(&nas20170626.CreateDirRequest{})
+	require.Error(t, err)
+
+	assert.Equal(t, before+1, counterValue(t, notFound))
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(RequestDuration)
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	require.NotEmpty(t, families)
+	assert.Equal(t, "csi_openapi_request_duration_seconds", families[0].GetName())
+}
@@ -1,13 +1,17 @@
 package wrap
 
 import (
+	"context"
 	"net/http"
+	"reflect"
+	"strings"
 	"time"
 
 	alierrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
-	"github.com/go-logr/logr"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/audit"
+	"k8s.io/klog/v2"
 )
 
 func transformErrorForLog(errIn error) (code, requestID string, err error) {
@@ -24,16 +28,36 @@ func transformErrorForLog(errIn error) (code, requestID string, err error) {
 	return
 }
 
+// v1RequestID reads the request ID from response headers.
+// Responses not from the SDK (e.g. in tests) have no BaseResponse, fallback to the RequestId field.
+func v1RequestID(resp responses.AcsResponse) string {
+	v := reflect.ValueOf(resp)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ""
+	}
+	if base := v.Elem().FieldByName("BaseResponse"); base.Kind() == reflect.Pointer && base.IsNil() {
+		id := v.Elem().FieldByName("RequestId")
+		if id.Kind() != reflect.String {
+			return ""
+		}
+		return id.String()
+	}
+	var header http.Header = resp.GetHttpHeaders()
+	return header.Get("X-Acs-Request-Id")
+}
+
 type tResp interface {
 	responses.AcsResponse
 	comparable
 }
 
-func V1[TReq requests.AcsRequest, TResp tResp](logger logr.Logger, f func(TReq) (TResp, error)) func(TReq) (TResp, error) {
+func V1[TReq requests.AcsRequest, TResp tResp](ctx context.Context, f func(TReq) (TResp, error)) func(TReq) (TResp, error) {
 	return func(req TReq) (TResp, error) {
-		helper, logger := logger.WithCallStackHelper()
+		helper, logger := klog.FromContext(ctx).WithCallStackHelper()
 		logger = logger.WithValues("api", req.GetActionName())
 		helper()
+		product := strings.ToLower(req.GetProduct())
+		_, span := startSpan(ctx, product, req.GetActionName())
 		logger.V(5).Info("OpenAPI trace", "request", req)
 		logger.V(3).Info("OpenAPI start")
 
@@ -45,15 +69,17 @@ func V1[TReq requests.AcsRequest, TResp tResp](logger logr.Logger, f func(TReq)
 		duration := time.Since(t)
 		attrs := []any{"elapsed", duration}
 		var reqID string
+		var code string
 
 		var respZero TResp
 		if resp != respZero {
-			var header http.Header = resp.GetHttpHeaders()
-			reqID = header.Get("X-Acs-Request-Id")
+			reqID = v1RequestID(resp)
 		}
 		if err != nil {
 			level = 1
-			code, r, e := transformErrorForLog(err)
+			var r string
+			var e error
+			code, r, e = transformErrorForLog(err)
 			err = e
 			if code != "" {
 				attrs = append(attrs, "errorCode", code)
@@ -69,6 +95,12 @@ func V1[TReq requests.AcsRequest, TResp tResp](logger logr.Logger, f func(TReq)
 			attrs = append(attrs, "requestID", reqID)
 		}
 		logger.V(level).Info("OpenAPI finish", attrs...)
+		observe(product, req.GetActionName(), code, err, duration)
+		endSpan(span, reqID, err)
+		audit.Log(ctx, audit.Call{
+			Product: product, Action: req.GetActionName(), Request: req,
+			RequestID: reqID, ErrorCode: code, Err: err,
+		})
 		return resp, err
 	}
 }
@@ -7,13 +7,14 @@ import (
 	alierrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
 	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/audit"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/klog/v2/ktesting"
 )
 
 func TestV1_OK(t *testing.T) {
-	logger := ktesting.NewLogger(t, ktesting.DefaultConfig)
+	_, ctx := ktesting.NewTestContext(t)
 
 	ctrl := gomock.NewController(t)
 	ecsClient := cloud.NewMockECSInterface(ctrl)
@@ -24,13 +25,13 @@ func TestV1_OK(t *testing.T) {
 	ecsClient.EXPECT().DescribeDisks(gomock.Any()).Return(resp, nil)
 
 	req := ecs.CreateDescribeDisksRequest()
-	resp, err := V1(logger, ecsClient.DescribeDisks)(req)
+	resp, err := V1(ctx, ecsClient.DescribeDisks)(req)
 	assert.NoError(t, err)
 	assert.Equal(t, "test-disk-id", resp.Disks.Disk[0].DiskId)
 }
 
 func TestV1_Error(t *testing.T) {
-	logger := ktesting.NewLogger(t, ktesting.DefaultConfig)
+	_, ctx := ktesting.NewTestContext(t)
 
 	ctrl := gomock.NewController(t)
 	ecsClient := cloud.NewMockECSInterface(ctrl)
@@ -43,7 +44,7 @@ func TestV1_Error(t *testing.T) {
 	ecsClient.EXPECT().DescribeDisks(gomock.Any()).Return(nil, expectedErr)
 
 	req := ecs.CreateDescribeDisksRequest()
-	resp, err := V1(logger, ecsClient.DescribeDisks)(req)
+	resp, err := V1(ctx, ecsClient.DescribeDisks)(req)
 
 	var svrErr *alierrors.ServerError
 	assert.ErrorAs(t, err, &svrErr)
@@ -58,7 +59,7 @@ func TestV1_Error(t *testing.T) {
 }
 
 func TestV1_ErrorUnknown(t *testing.T) {
-	logger := ktesting.NewLogger(t, ktesting.DefaultConfig)
+	_, ctx := ktesting.NewTestContext(t)
 
 	ctrl := gomock.NewController(t)
 	ecsClient := cloud.NewMockECSInterface(ctrl)
@@ -67,8 +68,49 @@ func TestV1_ErrorUnknown(t *testing.T) {
 	ecsClient.EXPECT().DescribeDisks(gomock.Any()).Return(nil, expectedErr)
 
 	req := ecs.CreateDescribeDisksRequest()
-	resp, err := V1(logger, ecsClient.DescribeDisks)(req)
+	resp, err := V1(ctx, ecsClient.DescribeDisks)(req)
 
 	assert.ErrorIs(t, err, expectedErr)
 	assert.Nil(t, resp)
 }
+
+type auditSink []*audit.Record
+
+func (s *auditSink) Write(r *audit.Record) {
+	*s = append(*s, r)
+}
+
+func TestV1_Audit(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	sink := &auditSink{}
+	audit.SetSinks(sink)
+	t.Cleanup(func() { audit.SetSinks() })
+
+	ctrl := gomock.NewController(t)
+	ecsClient := cloud.NewMockECSInterface(ctrl)
+	ecsClient.EXPECT().DescribeDisks(gomock.Any()).Return(ecs.CreateDescribeDisksResponse(), nil)
+	ecsClient.EXPECT().DeleteDisk(gomock.Any()).Return(nil, alierrors.NewServerError(400, `{
+		"RequestId": "test-request-id",
+		"Code": "IncorrectDiskStatus",
+		"Message": "The current disk status does not support this operation."
+	}`, ""))
+
+	ctx = audit.WithIdentity(ctx, audit.Identity{Method: "DeleteVolume", VolumeID: "d-test"})
+	_, err := V1(ctx, ecsClient.DescribeDisks)(ecs.CreateDescribeDisksRequest())
+	assert.NoError(t, err)
+
+	req := ecs.CreateDeleteDiskRequest()
+	req.DiskId = "d-test"
+	_, err = V1(ctx, ecsClient.DeleteDisk)(req)
+	assert.Error(t, err)
+
+	assert.Len(t, *sink, 1)
+	r := (*sink)[0]
+	assert.Equal(t, "DeleteVolume", r.Method)
+	assert.Equal(t, "ecs", r.Product)
+	assert.Equal(t, "DeleteDisk", r.Action)
+	assert.Equal(t, "d-test", r.Parameters["DiskId"])
+	assert.Equal(t, "test-request-id", r.RequestID)
+	assert.Equal(t, audit.OutcomeFailure, r.Outcome)
+	assert.Equal(t, "IncorrectDiskStatus", r.ErrorCode)
+}
@@ -1,6 +1,7 @@
 package wrap
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"strings"
@@ -8,6 +9,9 @@ import (
 
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/go-logr/logr"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/audit"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
 )
 
@@ -64,31 +68,38 @@ type v2Resp interface {
 	GetHeaders() map[string]*string
 }
 
-func V2[TReq any, TResp v2Resp](logger logr.Logger, f func(TReq) (TResp, error)) func(TReq) (TResp, error) {
+func V2[TReq any, TResp v2Resp](ctx context.Context, f func(TReq) (TResp, error)) func(TReq) (TResp, error) {
 	t := reflect.TypeFor[TReq]()
 	if t.Kind() == reflect.Pointer {
 		t = t.Elem()
 	}
 	action := strings.TrimSuffix(t.Name(), "Request")
-	logger = logger.WithValues("api", action)
+	product := v2Product(t)
+	logger := klog.FromContext(ctx).WithValues("api", action)
 
 	return func(req TReq) (TResp, error) {
 		helper, logger := logger.WithCallStackHelper()
 		helper()
 		logger.V(5).Info("OpenAPI trace", "request", req)
 
-		return v2Impl(logger, func() (TResp, error) { return f(req) })
+		_, span := startSpan(ctx, product, action)
+		resp, reqID, code, err := v2Impl(logger, span, product, action, func() (TResp, error) { return f(req) })
+		audit.Log(ctx, audit.Call{
+			Product: product, Action: action, Request: req,
+			RequestID: reqID, ErrorCode: code, Err: err,
+		})
+		return resp, err
 	}
 }
 
-func v2Impl[TResp v2Resp](logger logr.Logger, f func() (TResp, error)) (TResp, error) {
+func v2Impl[TResp v2Resp](logger logr.Logger, span trace.Span, product, action string, f func() (TResp, error)) (resp TResp, reqID, code string, err error) {
 	helper, logger := logger.WithCallStackHelper()
 	helper()
 
 	logger.V(3).Info("OpenAPI start")
 
 	t := time.Now()
-	resp, err := f()
+	resp, err = f()
 	logger.V(5).Info("OpenAPI trace", "response", resp, "error", err)
 
 	level := 2
@@ -96,14 +107,15 @@ func v2Impl[TResp v2Resp](logger logr.Logger, f func() (TResp, error)) (TResp, e
 	attrs := []any{"elapsed", duration}
 
 	var respZero TResp
-	var reqID string
 	if resp != respZero {
 		header := resp.GetHeaders()
 		reqID = ptr.Deref(header["x-acs-request-id"], "")
 	}
 	if err != nil {
 		level = 1
-		code, r, e := transformV2ErrorForLog(err)
+		var r string
+		var e error
+		code, r, e = transformV2ErrorForLog(err)
 		if code != "" {
 			attrs = append(attrs, "errorCode", code)
 		} else {
@@ -119,6 +131,8 @@ func v2Impl[TResp v2Resp](logger logr.Logger, f func() (TResp, error)) (TResp, e
 		attrs = append(attrs, "requestID", reqID)
 	}
 	logger.V(level).Info("OpenAPI finish", attrs...)
-	return resp, err
+	observe(product, action, code, err, duration)
+	endSpan(span, reqID, err)
+	return resp, reqID, code, err
 
 }
@@ -16,7 +16,7 @@ import (
 )
 
 func TestV2_OK(t *testing.T) {
-	logger := ktesting.NewLogger(t, ktesting.DefaultConfig)
+	_, ctx := ktesting.NewTestContext(t)
 
 	ctrl := gomock.NewController(t)
 	nasClient := cloud.NewMockNasInterface(ctrl)
@@ -32,13 +32,13 @@ func TestV2_OK(t *testing.T) {
 	}
 	nasClient.EXPECT().CreateDir(gomock.Any()).Return(resp, nil)
 
-	resp, err := V2(logger, nasClient.CreateDir)(req)
+	resp, err := V2(ctx, nasClient.CreateDir)(req)
 	assert.NoError(t, err)
 	assert.Equal(t, "test-request-id", *resp.Body.RequestId)
 }
 
 func TestV2_Error(t *testing.T) {
-	logger := ktesting.NewLogger(t, ktesting.DefaultConfig)
+	_, ctx := ktesting.NewTestContext(t)
 
 	ctrl := gomock.NewController(t)
 	nasClient := cloud.NewMockNasInterface(ctrl)
@@ -51,7 +51,7 @@ func TestV2_Error(t *testing.T) {
 	nasClient.EXPECT().CreateDir(gomock.Any()).Return(&nas20170626.CreateDirResponse{}, expectedErr)
 
 	req := &nas20170626.CreateDirRequest{}
-	resp, err := V2(logger, nasClient.CreateDir)(req)
+	resp, err := V2(ctx, nasClient.CreateDir)(req)
 
 	var teaErr *tea.SDKError
 	assert.ErrorAs(t, err, &teaErr)
@@ -65,7 +65,7 @@ func TestV2_Error(t *testing.T) {
 }
 
 func TestV2_ErrorUnknown(t *testing.T) {
-	logger := ktesting.NewLogger(t, ktesting.DefaultConfig)
+	_, ctx := ktesting.NewTestContext(t)
 
 	ctrl := gomock.NewController(t)
 	nasClient := cloud.NewMockNasInterface(ctrl)
@@ -74,7 +74,7 @@ func TestV2_ErrorUnknown(t *testing.T) {
 	nasClient.EXPECT().CreateDir(gomock.Any()).Return(nil, expectedErr)
 
 	req := &nas20170626.CreateDirRequest{}
-	resp, err := V2(logger, nasClient.CreateDir)(req)
+	resp, err := V2(ctx, nasClient.CreateDir)(req)
 
 	assert.ErrorIs(t, err, expectedErr)
 	assert.Nil(t, resp)
@@ -83,13 +83,13 @@ func TestV2_ErrorUnknown(t *testing.T) {
 func TestV2_Manual(t *testing.T) {
 	// Comment this out when testing manually, and add your findings to other cases.
 	t.Skip("Only for manual test")
-	logger := ktesting.NewLogger(t, ktesting.DefaultConfig)
+	_, ctx := ktesting.NewTestContext(t)
 	client, err := nas20170626.NewClient(&openapiutil.Config{
 		RegionId:        ptr.To("cn-hangzhou"),
 		AccessKeyId:     ptr.To("not an access key"),
 		AccessKeySecret: ptr.To("not an access key"),
 	})
 	require.NoError(t, err)
-	_, err = V2(logger, client.CreateDir)(&nas20170626.CreateDirRequest{})
+	_, err = V2(ctx, client.CreateDir)(&nas20170626.CreateDirRequest{})
 	require.ErrorContains(t, err, "OpenAPI returned error: Specified access key is not found or invalid. (InvalidAccessKeyId)")
 }
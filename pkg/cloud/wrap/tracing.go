@@ -0,0 +1,23 @@
+package wrap
+
+import (
+	"context"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func startSpan(ctx context.Context, product, action string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "OpenAPI "+product+"."+action,
+		attribute.String("rpc.system", "alibabacloud"),
+		attribute.String("rpc.service", product),
+		attribute.String("rpc.method", action))
+}
+
+func endSpan(span trace.Span, requestID string, err error) {
+	if requestID != "" {
+		span.SetAttributes(tracing.RequestIDKey.String(requestID))
+	}
+	tracing.End(span, err)
+}
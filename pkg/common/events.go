@@ -0,0 +1,30 @@
+package common
+
+import (
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// PVCRef refers to the PVC being provisioned, from CreateVolume parameters.
+// The PVC is only known if external-provisioner is started with --extra-create-metadata.
+func PVCRef(params map[string]string) *v1.ObjectReference {
+	return &v1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "PersistentVolumeClaim",
+		Namespace:  params[PVCNamespaceKey],
+		Name:       params[PVCNameKey],
+	}
+}
+
+// RecordOpenAPIError emits a warning Event on the PVC being provisioned,
+// if err is a classified OpenAPI error returned by wrap.ToStatus,
+// so that app teams see the reason and what to do without reading controller logs.
+func RecordOpenAPIError(recorder record.EventRecorder, params map[string]string, err error) {
+	info := wrap.ErrorInfo(err)
+	if info == nil || params[PVCNameKey] == "" {
+		return
+	}
+	recorder.Eventf(PVCRef(params), v1.EventTypeWarning, info.Reason,
+		"Alibaba Cloud OpenAPI returned %s: %s", info.Metadata["errorCode"], info.Metadata["hint"])
+}
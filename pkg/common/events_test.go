@@ -0,0 +1,31 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	alierrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRecordOpenAPIError(t *testing.T) {
+	params := map[string]string{PVCNamespaceKey: "default", PVCNameKey: "data"}
+	aliErr := alierrors.NewServerError(403, `{"Code": "Forbidden.RAM", "Message": "denied"}`, "")
+	err := wrap.ToStatus(codes.Internal, "create failed", fmt.Errorf("create: %w", aliErr))
+
+	recorder := record.NewFakeRecorder(10)
+	RecordOpenAPIError(recorder, params, err)
+	assert.Len(t, recorder.Events, 1)
+	event := <-recorder.Events
+	assert.Contains(t, event, "Warning PermissionDenied Alibaba Cloud OpenAPI returned Forbidden.RAM")
+
+	// not classified
+	RecordOpenAPIError(recorder, params, wrap.ToStatus(codes.Internal, "failed", errors.New("failed")))
+	// PVC unknown
+	RecordOpenAPIError(recorder, nil, err)
+	assert.Empty(t, recorder.Events)
+}
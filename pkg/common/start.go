@@ -7,8 +7,10 @@ import (
 	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/audit"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/metric"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/options"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/tracing"
 	"google.golang.org/grpc"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
@@ -20,6 +22,8 @@ type Servers struct {
 	ControllerServer      csi.ControllerServer
 	NodeServer            csi.NodeServer
 	GroupControllerServer csi.GroupControllerServer
+	// SnapshotMetadataServer is registered without the log and validator wrappers, as its RPCs are streaming
+	SnapshotMetadataServer csi.SnapshotMetadataServer
 }
 
 func ParseEndpoint(ep string) (string, string, error) {
@@ -61,7 +65,7 @@ func RunCSIServer(driverType, endpoint string, servers Servers) {
 	}
 
 	opts := []grpc.ServerOption{
-		grpc.ChainUnaryInterceptor(instrumentGRPC(driverType), earlyTimeout),
+		grpc.ChainUnaryInterceptor(tracing.UnaryServerInterceptor, audit.UnaryServerInterceptor, instrumentGRPC(driverType), earlyTimeout),
 	}
 	server := grpc.NewServer(opts...)
 
@@ -81,6 +85,9 @@ func RunCSIServer(driverType, endpoint string, servers Servers) {
 		metric.CsiGrpcExecTimeCollector.InitGRPC(csi.GroupController_ServiceDesc, driverType)
 		csi.RegisterGroupControllerServer(server, WrapGroupControllerServer(servers.GroupControllerServer))
 	}
+	if servers.SnapshotMetadataServer != nil {
+		csi.RegisterSnapshotMetadataServer(server, servers.SnapshotMetadataServer)
+	}
 
 	klog.Infof("Listening for connections on address: %#v", listener.Addr())
 
@@ -0,0 +1,88 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	sts20150401 "github.com/alibabacloud-go/sts-20150401/v2/client"
+	alicred "github.com/aliyun/credentials-go/credentials/providers"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	assumeRoleSessionName = "alibaba-cloud-csi-driver"
+	assumeRoleDuration    = time.Hour
+)
+
+// AssumeRoleCredential assumes a RAM role with the STS client, which is signed by the credentials of the plugin,
+// and refreshes the temporary credentials before they expire.
+type AssumeRoleCredential struct {
+	sts        cloud.STSInterface
+	roleArn    string
+	externalID string
+	clock      clock.PassiveClock
+
+	mu         sync.Mutex
+	cred       *sts20150401.AssumeRoleResponseBodyCredentials
+	expiration time.Time
+}
+
+func NewAssumeRoleProvider(clk clock.PassiveClock, stsClient cloud.STSInterface, roleArn, externalID string) *AssumeRoleCredential {
+	return &AssumeRoleCredential{
+		sts:        stsClient,
+		roleArn:    roleArn,
+		externalID: externalID,
+		clock:      clk,
+	}
+}
+
+func (a *AssumeRoleCredential) assumeRole() error {
+	req := &sts20150401.AssumeRoleRequest{}
+	req.SetRoleArn(a.roleArn).
+		SetRoleSessionName(assumeRoleSessionName).
+		SetDurationSeconds(int64(assumeRoleDuration / time.Second))
+	if a.externalID != "" {
+		req.SetExternalId(a.externalID)
+	}
+	resp, err := a.sts.AssumeRole(req)
+	if err != nil {
+		return fmt.Errorf("failed to assume role %s: %w", a.roleArn, err)
+	}
+	if resp.Body == nil || resp.Body.Credentials == nil {
+		return fmt.Errorf("failed to assume role %s: %w", a.roleArn, errors.New("no credentials in response"))
+	}
+	a.cred = resp.Body.Credentials
+	a.expiration, err = time.Parse(time.RFC3339, ptr.Deref(a.cred.Expiration, ""))
+	if err != nil {
+		klog.Warningf("failed to parse expiration of role %s: %v", a.roleArn, err)
+		a.expiration = a.clock.Now().Add(assumeRoleDuration)
+	}
+	klog.V(4).InfoS("assumed role", "roleArn", a.roleArn, "expireAt", a.expiration)
+	return nil
+}
+
+func (a *AssumeRoleCredential) GetProviderName() string {
+	return "assume_role"
+}
+
+func (a *AssumeRoleCredential) GetCredentials() (*alicred.Credentials, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// refresh 10 minutes before expiration
+	if a.cred == nil || a.expiration.Before(a.clock.Now().Add(10*time.Minute)) {
+		if err := a.assumeRole(); err != nil {
+			return nil, err
+		}
+	}
+	return &alicred.Credentials{
+		ProviderName:    a.GetProviderName(),
+		AccessKeyId:     ptr.Deref(a.cred.AccessKeyId, ""),
+		AccessKeySecret: ptr.Deref(a.cred.AccessKeySecret, ""),
+		SecurityToken:   ptr.Deref(a.cred.SecurityToken, ""),
+	}, nil
+}
@@ -0,0 +1,68 @@
+package credentials
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sts20150401 "github.com/alibabacloud-go/sts-20150401/v2/client"
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktest "k8s.io/utils/clock/testing"
+)
+
+func assumeRoleResponse(ak string, expiration time.Time) *sts20150401.AssumeRoleResponse {
+	cred := &sts20150401.AssumeRoleResponseBodyCredentials{}
+	cred.SetAccessKeyId(ak).
+		SetAccessKeySecret("secret-" + ak).
+		SetSecurityToken("token-" + ak).
+		SetExpiration(expiration.UTC().Format(time.RFC3339))
+	return &sts20150401.AssumeRoleResponse{
+		Body: &sts20150401.AssumeRoleResponseBody{Credentials: cred},
+	}
+}
+
+func TestAssumeRoleCredential(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	clk := clocktest.NewFakePassiveClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	stsClient := cloud.NewMockSTSInterface(ctrl)
+
+	const roleArn = "acs:ram::123456:role/storage"
+	stsClient.EXPECT().AssumeRole(gomock.Any()).DoAndReturn(func(req *sts20150401.AssumeRoleRequest) (*sts20150401.AssumeRoleResponse, error) {
+		assert.Equal(t, roleArn, *req.RoleArn)
+		assert.Equal(t, "ext-1", *req.ExternalId)
+		return assumeRoleResponse("ak1", clk.Now().Add(time.Hour)), nil
+	})
+
+	p := NewAssumeRoleProvider(clk, stsClient, roleArn, "ext-1")
+	cred, err := p.GetCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, "ak1", cred.AccessKeyId)
+	assert.Equal(t, "secret-ak1", cred.AccessKeySecret)
+	assert.Equal(t, "token-ak1", cred.SecurityToken)
+
+	// cached
+	clk.SetTime(clk.Now().Add(30 * time.Minute))
+	cred, err = p.GetCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, "ak1", cred.AccessKeyId)
+
+	// refreshed before expiration
+	clk.SetTime(clk.Now().Add(25 * time.Minute))
+	stsClient.EXPECT().AssumeRole(gomock.Any()).Return(assumeRoleResponse("ak2", clk.Now().Add(time.Hour)), nil)
+	cred, err = p.GetCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, "ak2", cred.AccessKeyId)
+}
+
+func TestAssumeRoleCredentialError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	stsClient := cloud.NewMockSTSInterface(ctrl)
+	stsClient.EXPECT().AssumeRole(gomock.Any()).Return(nil, errors.New("NoPermission"))
+
+	p := NewAssumeRoleProvider(clocktest.NewFakePassiveClock(time.Now()), stsClient, "acs:ram::123456:role/storage", "")
+	_, err := p.GetCredentials()
+	assert.ErrorContains(t, err, "NoPermission")
+}
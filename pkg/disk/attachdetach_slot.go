@@ -3,7 +3,9 @@ package disk
 import (
 	"context"
 	"errors"
+	"slices"
 	"sync"
+	"sync/atomic"
 )
 
 type AttachDetachSlots interface {
@@ -183,6 +185,148 @@ func NewSlots(detachConcurrency, attachConcurrency int) AttachDetachSlots {
 	return NewPerNodeSlots(makeSlot)
 }
 
+// ReloadableSlots limits the concurrent attach and detach operations per node like NewSlots,
+// but the limits can be changed by Resize at any time.
+// Operations in progress count against the new limits,
+// and operations waiting are admitted right away if the new limits allow.
+type ReloadableSlots struct {
+	limits atomic.Pointer[adLimits]
+
+	mu    sync.Mutex
+	nodes map[string]*resizableADSlot
+}
+
+type adLimits struct {
+	detach, attach int
+}
+
+// serial is the same special case as NewSlots: only one of attach and detach can be in progress at a time.
+func (l *adLimits) serial() bool {
+	return l.detach == 1 && l.attach == 1
+}
+
+func NewReloadableSlots(detachConcurrency, attachConcurrency int) *ReloadableSlots {
+	s := &ReloadableSlots{nodes: map[string]*resizableADSlot{}}
+	s.limits.Store(&adLimits{detach: detachConcurrency, attach: attachConcurrency})
+	return s
+}
+
+// Resize changes the limits of all nodes.
+func (s *ReloadableSlots) Resize(detachConcurrency, attachConcurrency int) {
+	s.limits.Store(&adLimits{detach: detachConcurrency, attach: attachConcurrency})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range s.nodes {
+		n.mu.Lock()
+		n.admit()
+		n.mu.Unlock()
+	}
+}
+
+func (s *ReloadableSlots) GetSlotFor(node string) adSlot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.nodes[node]
+	if !ok {
+		n = &resizableADSlot{limits: &s.limits}
+		s.nodes[node] = n
+	}
+	return n
+}
+
+const (
+	opDetach = iota
+	opAttach
+	numOps
+)
+
+// resizableADSlot counts the operations in progress on a node,
+// and admits waiting operations in order, detach first.
+type resizableADSlot struct {
+	limits *atomic.Pointer[adLimits]
+
+	mu      sync.Mutex
+	running [numOps]int
+	waiters [numOps][]*adWaiter
+}
+
+type adWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func (s *resizableADSlot) Detach() slot { return resizableSlot{s, opDetach} }
+func (s *resizableADSlot) Attach() slot { return resizableSlot{s, opAttach} }
+
+// allowed must be called with s.mu held.
+func (s *resizableADSlot) allowed(op int) bool {
+	l := s.limits.Load()
+	if l.serial() {
+		return s.running[opDetach]+s.running[opAttach] == 0
+	}
+	limit := l.attach
+	if op == opDetach {
+		limit = l.detach
+	}
+	return limit == 0 || s.running[op] < limit
+}
+
+// admit must be called with s.mu held.
+func (s *resizableADSlot) admit() {
+	for op := range numOps {
+		for len(s.waiters[op]) > 0 && s.allowed(op) {
+			w := s.waiters[op][0]
+			s.waiters[op] = s.waiters[op][1:]
+			s.running[op]++
+			w.granted = true
+			close(w.ready)
+		}
+	}
+}
+
+type resizableSlot struct {
+	*resizableADSlot
+	op int
+}
+
+func (s resizableSlot) Acquire(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	s.mu.Lock()
+	// do not overtake the waiting ones, in serial mode attach also waits for detach
+	queued := len(s.waiters[s.op]) > 0 || (s.op == opAttach && s.limits.Load().serial() && len(s.waiters[opDetach]) > 0)
+	if !queued && s.allowed(s.op) {
+		s.running[s.op]++
+		s.mu.Unlock()
+		return nil
+	}
+	w := &adWaiter{ready: make(chan struct{})}
+	s.waiters[s.op] = append(s.waiters[s.op], w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if w.granted {
+			return nil
+		}
+		s.waiters[s.op] = slices.DeleteFunc(s.waiters[s.op], func(o *adWaiter) bool { return o == w })
+		s.admit()
+		return maybeWaitingAD(ctx.Err())
+	}
+}
+
+func (s resizableSlot) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running[s.op]--
+	s.admit()
+}
+
 type waitingAD struct{}
 
 func (waitingAD) Error() string {
@@ -177,3 +177,54 @@ func TestWaitingADError(t *testing.T) {
 	assert.ErrorIs(t, err, waitingAD{})
 	assert.ErrorIs(t, err, context.DeadlineExceeded)
 }
+
+func TestReloadableSlots(t *testing.T) {
+	slots := NewReloadableSlots(1, 1)
+	s := slots.GetSlotFor("node1")
+	assert.NoError(t, s.Attach().Acquire(context.Background()))
+
+	// serial: detach waits for the attach in progress
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Detach().Acquire(ctx), context.DeadlineExceeded)
+
+	slots.Resize(0, 2)
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, s.Detach().Acquire(ctx))
+	// the attach in progress counts against the new limit
+	assert.NoError(t, s.Attach().Acquire(ctx))
+	assert.ErrorIs(t, s.Attach().Acquire(ctx), context.DeadlineExceeded)
+	assert.Same(t, s, slots.GetSlotFor("node1"))
+}
+
+func TestReloadableSlotsResizeAdmitsWaiting(t *testing.T) {
+	slots := NewReloadableSlots(0, 1)
+	s := slots.GetSlotFor("node1").Attach()
+	assert.NoError(t, s.Acquire(context.Background()))
+
+	acquired := make(chan error)
+	go func() { acquired <- s.Acquire(context.Background()) }()
+	select {
+	case <-acquired:
+		t.Fatal("should wait for the attach in progress")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	slots.Resize(0, 2)
+	select {
+	case err := <-acquired:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("should be admitted after resize")
+	}
+
+	// shrink below the operations in progress
+	slots.Resize(0, 1)
+	s.Release()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Acquire(ctx), context.DeadlineExceeded)
+	s.Release()
+	assert.NoError(t, s.Acquire(context.Background()))
+}
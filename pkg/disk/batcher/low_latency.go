@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/desc"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/tracing"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 )
@@ -53,7 +54,9 @@ func NewLowLatency[T any](ecsClient desc.Client[T], clk clock.WithTicker, perReq
 	}
 }
 
-func (w *LowLatency[T]) Describe(ctx context.Context, id string) (*T, error) {
+func (w *LowLatency[T]) Describe(ctx context.Context, id string) (res *T, err error) {
+	ctx, span := tracing.Start(ctx, "batcher.Describe", tracing.ResourceIDKey.String(id))
+	defer func() { tracing.End(span, err) }()
 	resultChan := make(chan getResponse[*T], 1) // in case this wait is canceled, don't block descBatch()
 	w.requestChan <- &getRequest[*T]{
 		ctx:        ctx,
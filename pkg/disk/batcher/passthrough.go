@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/desc"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/tracing"
 )
 
 type Passthrough[T any] struct {
@@ -16,10 +17,15 @@ func NewPassthrough[T any](client desc.Client[T]) Passthrough[T] {
 	}
 }
 
-func (w Passthrough[T]) Describe(ctx context.Context, id string) (*T, error) {
+func (w Passthrough[T]) Describe(ctx context.Context, id string) (res *T, err error) {
+	_, span := tracing.Start(ctx, "batcher.Describe", tracing.ResourceIDKey.String(id))
+	defer func() { tracing.End(span, err) }()
 	resp, err := w.client.Describe([]string{id})
 	if err != nil {
 		return nil, err
 	}
+	if len(resp.Resources) == 0 {
+		return nil, nil
+	}
 	return &resp.Resources[0], nil
 }
@@ -362,7 +362,6 @@ func forceDetachAllowed(ecsClient cloud.ECSInterface, disk *ecs.Disk) (allowed b
 
 	// case 1
 	describeDisksRequest := ecs.CreateDescribeDisksRequest()
-	describeDisksRequest.RegionId = GlobalConfigVar.Region
 	describeDisksRequest.DiskIds = "[\"" + disk.DiskId + "\"]"
 	diskResponse, err := ecsClient.DescribeDisks(describeDisksRequest)
 	if err != nil {
@@ -37,9 +37,12 @@ import (
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/batcher"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/waitstatus"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/warmpool"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/metric"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -76,6 +79,8 @@ type DiskAttachDetach struct {
 	attachThrottler *throttle.Throttler
 	detachThrottler *throttle.Throttler
 	detaching       sync.Map
+	// fenced is disk ID -> ID of the lost instance it is fenced from, see failoverController
+	fenced sync.Map
 
 	dev    *DeviceManager
 	devMap *devMap
@@ -86,6 +91,8 @@ type DiskCreateDelete struct {
 	batcher         batcher.Batcher[ecs.Disk]
 	createThrottler *throttle.Throttler
 	deleteThrottler *throttle.Throttler
+	// warmPool is only set for the default identity, nil if disabled.
+	warmPool *warmpool.Pool
 }
 
 // GetRootBlockDevice get device name
@@ -250,6 +257,9 @@ func (ad *DiskAttachDetach) attachDisk(ctx context.Context, diskID, nodeID strin
 	if disk == nil {
 		return "", status.Errorf(codes.NotFound, "AttachDisk: csi can't find disk: %s in region: %s, Please check if the cloud disk exists, if the region is correct, or if the csi permissions are correct", diskID, GlobalConfigVar.Region)
 	}
+	if key := diskTag(disk, kmsRotationKeyTag); key != "" {
+		return "", status.Errorf(codes.Aborted, "AttachDisk: disk %s is being copied to rotate KMS key to %s, change the kmsKeyId of the VolumeAttributesClass back to cancel", diskID, key)
+	}
 
 	if !fromNode && disk.SerialNumber == "" {
 		if GlobalConfigVar.ADControllerEnable {
@@ -269,7 +279,7 @@ func (ad *DiskAttachDetach) attachDisk(ctx context.Context, diskID, nodeID strin
 
 	// tag disk as k8s.aliyun.com=true
 	if GlobalConfigVar.DiskTagEnable {
-		tagDiskAsK8sAttached(diskID, GlobalConfigVar.EcsClient)
+		tagDiskAsK8sAttached(ctx, diskID, ad.ecs)
 	}
 
 	cate, ok := AllCategories[Category(disk.Category)]
@@ -282,6 +292,9 @@ func (ad *DiskAttachDetach) attachDisk(ctx context.Context, diskID, nodeID strin
 		if i, ok := ad.detaching.Load(diskID); ok && i.(string) == disk.InstanceId {
 			canForceAttach = true
 		}
+		if ad.isFenced(diskID, disk.InstanceId) {
+			canForceAttach = true
+		}
 	}
 
 	action, err := chooseAttachAction(disk, nodeID)
@@ -290,7 +303,7 @@ func (ad *DiskAttachDetach) attachDisk(ctx context.Context, diskID, nodeID strin
 	}
 	if action == detachFirst || action == forceAttach {
 		if GlobalConfigVar.DiskBdfEnable {
-			if allowed, err := forceDetachAllowed(GlobalConfigVar.EcsClient, disk); err != nil {
+			if allowed, err := forceDetachAllowed(ad.ecs, disk); err != nil {
 				return "", status.Errorf(codes.Aborted, "forceDetachAllowed failed: %v", err)
 			} else if !allowed {
 				return "", status.Errorf(codes.Aborted, "AttachDisk: Disk %s is already attached to instance %s, and depend bdf, reject force detach", disk.DiskId, disk.InstanceId)
@@ -315,7 +328,10 @@ func (ad *DiskAttachDetach) attachDisk(ctx context.Context, diskID, nodeID strin
 			return disk.SerialNumber, nil
 		}
 		if disk.SerialNumber != "" {
-			return ad.dev.WaitRootBlock(ctx, disk.SerialNumber)
+			discoverStart := time.Now()
+			device, err := ad.dev.WaitRootBlock(ctx, disk.SerialNumber)
+			metric.ObserveVolumePhase(driverType, disk.Category, metric.PhaseDeviceDiscovery, discoverStart, err)
+			return device, err
 		}
 		device, err := ad.devMap.Get(logger, diskID)
 		if err != nil {
@@ -334,7 +350,7 @@ func (ad *DiskAttachDetach) attachDisk(ctx context.Context, diskID, nodeID strin
 		for key, value := range GlobalConfigVar.RequestBaseInfo {
 			detachRequest.AppendUserAgent(key, value)
 		}
-		_, err = ad.ecs.DetachDisk(detachRequest)
+		_, err = wrap.V1(ctx, ad.ecs.DetachDisk)(detachRequest)
 		if err != nil {
 			return "", status.Errorf(codes.Aborted, "AttachDisk: Can't Detach disk %s from instance %s: with error: %v", diskID, disk.InstanceId, err)
 		}
@@ -366,7 +382,9 @@ func (ad *DiskAttachDetach) attachDisk(ctx context.Context, diskID, nodeID strin
 	for key, value := range GlobalConfigVar.RequestBaseInfo {
 		attachRequest.AppendUserAgent(key, value)
 	}
-	response, err := throttle.Throttled(ad.attachThrottler, ad.ecs.AttachDisk)(ctx, attachRequest)
+	attachStart := time.Now()
+	response, err := throttle.Throttled(ad.attachThrottler, wrap.V1(ctx, ad.ecs.AttachDisk))(ctx, attachRequest)
+	metric.ObserveVolumePhase(driverType, disk.Category, metric.PhaseAttach, attachStart, err)
 	if err != nil {
 		var aliErr *alicloudErr.ServerError
 		if errors.As(err, &aliErr) {
@@ -386,13 +404,19 @@ func (ad *DiskAttachDetach) attachDisk(ctx context.Context, diskID, nodeID strin
 
 	// Step 4: wait for disk attached
 	logger.V(2).Info("waiting for disk to attach", "requestID", response.RequestId)
-	if err := ad.waitForDiskAttached(ctx, diskID, nodeID); err != nil {
+	waitStart := time.Now()
+	err = ad.waitForDiskAttached(ctx, diskID, nodeID)
+	metric.ObserveVolumePhase(driverType, disk.Category, metric.PhaseAttachWait, waitStart, err)
+	if err != nil {
 		return "", err
 	}
+	ad.fenced.Delete(diskID)
 
 	// step 5: diff device with previous files under /dev
 	if fromNode {
+		discoverStart := time.Now()
 		device, err := ad.findDevice(ctx, diskID, disk.SerialNumber, before)
+		metric.ObserveVolumePhase(driverType, disk.Category, metric.PhaseDeviceDiscovery, discoverStart, err)
 		if err != nil {
 			return "", status.Error(codes.Aborted, err.Error())
 		}
@@ -428,7 +452,9 @@ func (ad *DiskAttachDetach) attachMultiAttachDisk(ctx context.Context, diskID, n
 	attachRequest := ecs.CreateAttachDiskRequest()
 	attachRequest.InstanceId = nodeID
 	attachRequest.DiskId = diskID
-	response, err := ad.ecs.AttachDisk(attachRequest)
+	attachStart := time.Now()
+	response, err := wrap.V1(ctx, ad.ecs.AttachDisk)(attachRequest)
+	metric.ObserveVolumePhase(driverType, disk.Category, metric.PhaseAttach, attachStart, err)
 	if err != nil {
 		if strings.Contains(err.Error(), DiskLimitExceeded) {
 			return "", status.Error(codes.Internal, err.Error()+", Node("+nodeID+")exceed the limit attachments of disk")
@@ -442,7 +468,10 @@ func (ad *DiskAttachDetach) attachMultiAttachDisk(ctx context.Context, diskID, n
 
 	// Step 4: wait for disk attached
 	klog.Infof("AttachMultiAttachDisk: Waiting for Disk %s is Attached to instance %s with RequestId: %s", diskID, nodeID, response.RequestId)
-	if err := ad.waitForDiskAttached(ctx, diskID, nodeID); err != nil {
+	waitStart := time.Now()
+	err = ad.waitForDiskAttached(ctx, diskID, nodeID)
+	metric.ObserveVolumePhase(driverType, disk.Category, metric.PhaseAttachWait, waitStart, err)
+	if err != nil {
 		return "", err
 	}
 
@@ -499,7 +528,7 @@ func (ad *DiskAttachDetach) detachDisk(ctx context.Context, ecsClient cloud.ECSI
 	for key, value := range GlobalConfigVar.RequestBaseInfo {
 		detachDiskRequest.AppendUserAgent(key, value)
 	}
-	response, err := throttle.Throttled(ad.detachThrottler, ecsClient.DetachDisk)(ctx, detachDiskRequest)
+	response, err := throttle.Throttled(ad.detachThrottler, wrap.V1(ctx, ecsClient.DetachDisk))(ctx, detachDiskRequest)
 	if err != nil {
 		return status.Errorf(codes.Aborted, "DetachDisk: Fail to detach %s: from Instance: %s with error: %v", disk.DiskId, disk.InstanceId, err)
 	}
@@ -524,7 +553,6 @@ func getDiskDescribeRequest(diskIDs []string) *ecs.DescribeDisksRequest {
 	}
 	idList = strings.TrimSuffix(idList, ",")
 	describeDisksRequest := ecs.CreateDescribeDisksRequest()
-	describeDisksRequest.RegionId = GlobalConfigVar.Region
 	describeDisksRequest.DiskIds = fmt.Sprintf("[%s]", idList)
 	return describeDisksRequest
 }
@@ -539,8 +567,7 @@ func getDisks(diskIDs []string, ecsClient cloud.ECSInterface) []ecs.Disk {
 	return diskResponse.Disks.Disk
 }
 
-func tagDiskUserTags(diskID string, tags map[string]string) {
-	ecsClient := GlobalConfigVar.EcsClient
+func tagDiskUserTags(ctx context.Context, diskID string, tags map[string]string, ecsClient cloud.ECSInterface) {
 	addTagsReq := ecs.CreateAddTagsRequest()
 	userTags := []ecs.AddTagsTag{
 		{
@@ -554,8 +581,7 @@ func tagDiskUserTags(diskID string, tags map[string]string) {
 	addTagsReq.Tag = &userTags
 	addTagsReq.ResourceType = "disk"
 	addTagsReq.ResourceId = diskID
-	addTagsReq.RegionId = GlobalConfigVar.Region
-	_, err := ecsClient.AddTags(addTagsReq)
+	_, err := wrap.V1(ctx, ecsClient.AddTags)(addTagsReq)
 	if err != nil {
 		klog.Warningf("tagDiskUserTags: AddTags error: %s, %s", diskID, err.Error())
 		return
@@ -564,7 +590,7 @@ func tagDiskUserTags(diskID string, tags map[string]string) {
 }
 
 // tag disk with: k8s.aliyun.com=true
-func tagDiskAsK8sAttached(diskID string, ecsClient *ecs.Client) {
+func tagDiskAsK8sAttached(ctx context.Context, diskID string, ecsClient cloud.ECSInterface) {
 	// Step 1: Describe disk, if tag exist, return;
 	disks := getDisks([]string{diskID}, ecsClient)
 	if len(disks) == 0 {
@@ -593,8 +619,7 @@ func tagDiskAsK8sAttached(diskID string, ecsClient *ecs.Client) {
 	addTagsRequest.Tag = &[]ecs.AddTagsTag{tmpTag}
 	addTagsRequest.ResourceType = "disk"
 	addTagsRequest.ResourceId = diskID
-	addTagsRequest.RegionId = GlobalConfigVar.Region
-	_, err = ecsClient.AddTags(addTagsRequest)
+	_, err = wrap.V1(ctx, ecsClient.AddTags)(addTagsRequest)
 	if err != nil {
 		klog.Warningf("tagAsK8sAttached: AddTags error: %s, %s", diskID, err.Error())
 		return
@@ -635,7 +660,6 @@ func (ad *DiskAttachDetach) findDiskByID(ctx context.Context, diskID string) (*e
 
 func findDiskByID(diskID string, ecsClient cloud.ECSInterface) (*ecs.Disk, error) {
 	describeDisksRequest := ecs.CreateDescribeDisksRequest()
-	describeDisksRequest.RegionId = GlobalConfigVar.Region
 	describeDisksRequest.DiskIds = "[\"" + diskID + "\"]"
 	diskResponse, err := ecsClient.DescribeDisks(describeDisksRequest)
 	if err != nil {
@@ -646,14 +670,13 @@ func findDiskByID(diskID string, ecsClient cloud.ECSInterface) (*ecs.Disk, error
 		return nil, nil
 	}
 	if len(disks) > 1 {
-		return nil, status.Errorf(codes.Internal, "FindDiskByID:FindDiskByID: Unexpected count %d for volume id %s, Get Response: %v, with Request: %v, %v", len(disks), diskID, diskResponse, describeDisksRequest.RegionId, describeDisksRequest.DiskIds)
+		return nil, status.Errorf(codes.Internal, "FindDiskByID:FindDiskByID: Unexpected count %d for volume id %s, Get Response: %v, with Request: %v", len(disks), diskID, diskResponse, describeDisksRequest.DiskIds)
 	}
 	return &disks[0], err
 }
 
 func findDiskByName(name string, ecsClient cloud.ECSInterface) (*ecs.Disk, error) {
 	describeDisksRequest := ecs.CreateDescribeDisksRequest()
-	describeDisksRequest.RegionId = GlobalConfigVar.Region
 	tags := []ecs.DescribeDisksTag{{Key: common.VolumeNameTag, Value: name}}
 	describeDisksRequest.Tag = &tags
 
@@ -671,11 +694,10 @@ func findDiskByName(name string, ecsClient cloud.ECSInterface) (*ecs.Disk, error
 	}
 	return &disks[0], err
 }
-func findDiskSnapshotByID(id string) (*ecs.Snapshot, error) {
+func findDiskSnapshotByID(id string, ecsClient cloud.ECSInterface) (*ecs.Snapshot, error) {
 	describeSnapShotRequest := ecs.CreateDescribeSnapshotsRequest()
-	describeSnapShotRequest.RegionId = GlobalConfigVar.Region
 	describeSnapShotRequest.SnapshotIds = "[\"" + id + "\"]"
-	snapshots, err := GlobalConfigVar.EcsClient.DescribeSnapshots(describeSnapShotRequest)
+	snapshots, err := ecsClient.DescribeSnapshots(describeSnapShotRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -773,7 +795,7 @@ type createSnapshotParams struct {
 	SnapshotTags    []ecs.CreateSnapshotTag
 }
 
-func requestAndCreateSnapshot(ecsClient cloud.ECSInterface, params *createSnapshotParams) (*ecs.CreateSnapshotResponse, error) {
+func requestAndCreateSnapshot(ctx context.Context, ecsClient cloud.ECSInterface, params *createSnapshotParams) (*ecs.CreateSnapshotResponse, error) {
 	// init createSnapshotRequest and parameters
 	createSnapshotRequest := ecs.CreateCreateSnapshotRequest()
 	createSnapshotRequest.DiskId = params.SourceVolumeID
@@ -798,7 +820,7 @@ func requestAndCreateSnapshot(ecsClient cloud.ECSInterface, params *createSnapsh
 	createSnapshotRequest.Tag = &snapshotTags
 
 	// Do Snapshot create
-	snapshotResponse, err := ecsClient.CreateSnapshot(createSnapshotRequest)
+	snapshotResponse, err := wrap.V1(ctx, ecsClient.CreateSnapshot)(createSnapshotRequest)
 	if err != nil {
 		var aliErr *alicloudErr.ServerError
 		if errors.As(err, &aliErr) {
@@ -814,12 +836,12 @@ func requestAndCreateSnapshot(ecsClient cloud.ECSInterface, params *createSnapsh
 	return snapshotResponse, nil
 }
 
-func requestAndDeleteSnapshot(snapshotID string) (*ecs.DeleteSnapshotResponse, error) {
+func requestAndDeleteSnapshot(ctx context.Context, ecsClient cloud.ECSInterface, snapshotID string) (*ecs.DeleteSnapshotResponse, error) {
 	// Delete Snapshot
 	deleteSnapshotRequest := ecs.CreateDeleteSnapshotRequest()
 	deleteSnapshotRequest.SnapshotId = snapshotID
 	deleteSnapshotRequest.Force = requests.NewBoolean(true)
-	response, err := GlobalConfigVar.EcsClient.DeleteSnapshot(deleteSnapshotRequest)
+	response, err := wrap.V1(ctx, ecsClient.DeleteSnapshot)(deleteSnapshotRequest)
 	if err != nil {
 		return response, err
 	}
@@ -834,7 +856,7 @@ type createGroupSnapshotParams struct {
 	SnapshotTags []ecs.CreateSnapshotGroupTag
 }
 
-func requestAndCreateSnapshotGroup(ecsClient *ecs.Client, params *createGroupSnapshotParams) (*ecs.CreateSnapshotGroupResponse, error) {
+func requestAndCreateSnapshotGroup(ctx context.Context, ecsClient *ecs.Client, params *createGroupSnapshotParams) (*ecs.CreateSnapshotGroupResponse, error) {
 	// init createSnapshotRequest and parameters
 	createSnapshotGroupRequest := ecs.CreateCreateSnapshotGroupRequest()
 	createSnapshotGroupRequest.DiskId = &params.SourceVolumeIDs
@@ -853,7 +875,7 @@ func requestAndCreateSnapshotGroup(ecsClient *ecs.Client, params *createGroupSna
 	createSnapshotGroupRequest.Tag = &snapshotTags
 
 	// Do Snapshot create
-	snapshotResponse, err := ecsClient.CreateSnapshotGroup(createSnapshotGroupRequest)
+	snapshotResponse, err := wrap.V1(ctx, ecsClient.CreateSnapshotGroup)(createSnapshotGroupRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -971,6 +993,8 @@ func (c *DiskCreateDelete) createDisk(ctx context.Context, diskName, snapshotID
 	}
 
 	messages := []string{}
+	// the last attempt failed with a classified OpenAPI error, to explain the failure to users
+	var lastClassified error
 	for _, attempt := range generateCreateAttempts(diskVol) {
 		if len(supportedTypes) > 0 {
 			if !supportedTypes.Has(attempt.Category) {
@@ -991,6 +1015,24 @@ func (c *DiskCreateDelete) createDisk(ctx context.Context, diskName, snapshotID
 			}
 			attempt.Instance = selectedInstance
 		}
+		if c.warmPool != nil && snapshotID == "" && warmPoolEligible(diskVol, attempt) {
+			diskID, existingDisk, err := c.adoptWarmDisk(ctx, diskName, diskVol, attempt)
+			switch {
+			case err != nil && diskID != "":
+				// the pooled disk is claimed, retried CreateVolume resumes its adoption
+				return "", attempt, status.Errorf(codes.Internal, "adopt disk from warm pool: %v", err)
+			case err != nil:
+				klog.FromContext(ctx).Error(err, "failed to adopt disk from warm pool, creating a new one", "attempt", attempt)
+			case existingDisk != nil:
+				attempt, err := checkExistingDisk(existingDisk, diskVol)
+				if err != nil {
+					return "", attempt, fmt.Errorf("%w: %w", ErrParameterMismatch, err)
+				}
+				return existingDisk.DiskId, attempt, nil
+			case diskID != "":
+				return diskID, attempt, nil
+			}
+		}
 	retry:
 		diskID, final, err := c.createDiskAttempt(ctx, createDiskRequest, attempt)
 		if err != nil {
@@ -1018,12 +1060,19 @@ func (c *DiskCreateDelete) createDisk(ctx context.Context, diskName, snapshotID
 				}
 				return existingDisk.DiskId, attempt, nil
 			}
+			if _, _, ok := wrap.Classify(err); ok {
+				lastClassified = err
+			}
 			messages = append(messages, fmt.Sprintf("%s: %v", attempt, err))
 			continue
 		}
 		return diskID, attempt, nil
 	}
-	return "", createAttempt{}, status.Errorf(codes.InvalidArgument, "all attempts failed: %s", strings.Join(messages, "; "))
+	msg := fmt.Sprintf("all attempts failed: %s", strings.Join(messages, "; "))
+	if lastClassified != nil {
+		return "", createAttempt{}, wrap.ToStatus(codes.InvalidArgument, msg, lastClassified)
+	}
+	return "", createAttempt{}, status.Error(codes.InvalidArgument, msg)
 }
 
 func buildCreateDiskRequest(diskVol *diskVolumeArgs) *ecs.CreateDiskRequest {
@@ -1085,7 +1134,7 @@ func (c *DiskCreateDelete) createDiskAttempt(ctx context.Context, req *ecs.Creat
 	req = finalizeCreateDiskRequest(req, attempt)
 	klog.Infof("request: request content: %++v", req)
 
-	volumeRes, err := throttle.Throttled(c.createThrottler, c.ecs.CreateDisk)(ctx, req)
+	volumeRes, err := throttle.Throttled(c.createThrottler, wrap.V1(ctx, c.ecs.CreateDisk))(ctx, req)
 	if err == nil {
 		klog.Infof("request: diskId: %s, reqId: %s", volumeRes.DiskId, volumeRes.RequestId)
 		return volumeRes.DiskId, true, nil
@@ -1094,17 +1143,17 @@ func (c *DiskCreateDelete) createDiskAttempt(ctx context.Context, req *ecs.Creat
 	if errors.As(err, &aliErr) {
 		klog.Infof("request: Create Disk for volume %s failed: %v", req.DiskName, err)
 		if strings.HasPrefix(aliErr.ErrorCode(), DiskNotAvailable) || strings.Contains(aliErr.Message(), DiskNotAvailableVer2) {
-			return "", false, fmt.Errorf("not supported in zone: %s", req.ZoneId)
+			return "", false, fmt.Errorf("not supported in zone: %s: %w", req.ZoneId, err)
 		} else if aliErr.ErrorCode() == DiskSizeNotAvailable1 || aliErr.ErrorCode() == DiskSizeNotAvailable2 {
 			// although we have checked the size above, but these limits are subject to change, so we may still encounter this error
-			return "", false, fmt.Errorf("invalid disk size: %s", req.Size)
+			return "", false, fmt.Errorf("invalid disk size: %s: %w", req.Size, err)
 		} else if aliErr.ErrorCode() == DiskPerformanceLevelNotMatch && attempt.Category == DiskESSD {
-			return "", false, fmt.Errorf("invalid disk size: %s", req.Size)
+			return "", false, fmt.Errorf("invalid disk size: %s: %w", req.Size, err)
 		} else if aliErr.ErrorCode() == DiskInvalidPL && attempt.Category == DiskESSD {
 			// observed in cn-north-2-gov-1 region, PL0 is not supported
-			return "", false, fmt.Errorf("performance level %s unsupported", req.PerformanceLevel)
+			return "", false, fmt.Errorf("performance level %s unsupported: %w", req.PerformanceLevel, err)
 		} else if aliErr.ErrorCode() == DiskIopsLimitExceeded && attempt.Category == DiskESSDAuto {
-			return "", false, fmt.Errorf("provisioned iops %s has exceeded limit", req.ProvisionedIops)
+			return "", false, fmt.Errorf("provisioned iops %s has exceeded limit: %w", req.ProvisionedIops, err)
 		} else if aliErr.ErrorCode() == IdempotentParameterMismatch {
 			return "", false, ErrParameterMismatch
 		}
@@ -1194,7 +1243,7 @@ func (c *DiskCreateDelete) deleteDisk(ctx context.Context, ecsClient cloud.ECSIn
 	var resp *ecs.DeleteDiskResponse
 	err := wait.PollUntilContextTimeout(ctx, time.Second*5, DISK_DELETE_INIT_TIMEOUT, true, func(ctx context.Context) (bool, error) {
 		var err error
-		resp, err = throttle.Throttled(c.deleteThrottler, c.ecs.DeleteDisk)(ctx, deleteDiskRequest)
+		resp, err = throttle.Throttled(c.deleteThrottler, wrap.V1(ctx, c.ecs.DeleteDisk))(ctx, deleteDiskRequest)
 		if err == nil {
 			klog.Infof("DeleteVolume: Successfully deleted volume: %s, with RequestId: %s", diskId, resp.RequestId)
 			return true, nil
@@ -1214,7 +1263,7 @@ func resizeDisk(ctx context.Context, ecsClient cloud.ECSInterface, req *ecs.Resi
 	var resp *ecs.ResizeDiskResponse
 	err := wait.PollUntilContextTimeout(ctx, time.Second*5, DISK_RESIZE_PROCESSING_TIMEOUT, true, func(ctx context.Context) (bool, error) {
 		var err error
-		resp, err = ecsClient.ResizeDisk(req)
+		resp, err = wrap.V1(ctx, ecsClient.ResizeDisk)(req)
 		if err == nil {
 			return true, nil
 		}
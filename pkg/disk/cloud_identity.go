@@ -0,0 +1,414 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	sts20150401 "github.com/alibabacloud-go/sts-20150401/v2/client"
+	alicred_old "github.com/aliyun/credentials-go/credentials"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/credentials"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	informercorev1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// keys in StorageClass parameters and volume context to select the account and region of the disk
+const (
+	RegionIDKey      = "regionId"
+	AssumeRoleArnKey = "assumeRoleArn"
+	ExternalIDKey    = "externalId"
+)
+
+// minimum interval to list StorageClasses for identities
+const identityRefreshInterval = time.Minute
+
+// cloudIdentity is the account and region to call ECS OpenAPI with.
+// The zero value is the account of the plugin in the region of the cluster.
+type cloudIdentity struct {
+	RegionID      string
+	AssumeRoleArn string
+	ExternalID    string
+}
+
+func identityFromParameters(params map[string]string) cloudIdentity {
+	id := cloudIdentity{
+		RegionID:      params[RegionIDKey],
+		AssumeRoleArn: params[AssumeRoleArnKey],
+		ExternalID:    params[ExternalIDKey],
+	}
+	if id.RegionID == GlobalConfigVar.Region {
+		id.RegionID = ""
+	}
+	if id.AssumeRoleArn == "" {
+		id.ExternalID = ""
+	}
+	return id
+}
+
+// setVolumeContext records id in the volume context, so that it is persisted in the PV.
+func (id cloudIdentity) setVolumeContext(volumeContext map[string]string) {
+	if id.RegionID != "" {
+		volumeContext[RegionIDKey] = id.RegionID
+	}
+	if id.AssumeRoleArn != "" {
+		volumeContext[AssumeRoleArnKey] = id.AssumeRoleArn
+	}
+	if id.ExternalID != "" {
+		volumeContext[ExternalIDKey] = id.ExternalID
+	}
+}
+
+func (id cloudIdentity) isDefault() bool {
+	return id == cloudIdentity{}
+}
+
+func (id cloudIdentity) region() string {
+	if id.RegionID == "" {
+		return GlobalConfigVar.Region
+	}
+	return id.RegionID
+}
+
+func (id cloudIdentity) String() string {
+	if id.AssumeRoleArn == "" {
+		return id.region()
+	}
+	return id.region() + "/" + id.AssumeRoleArn
+}
+
+// newIdentityECSClient creates an ECS client for id, assuming the RAM role with the credentials of the plugin if requested.
+func newIdentityECSClient(id cloudIdentity) (cloud.ECSInterface, error) {
+	provider, err := credentials.NewProvider()
+	if err != nil {
+		return nil, err
+	}
+	if id.AssumeRoleArn != "" {
+		cred := alicred_old.FromCredentialsProvider(provider.GetProviderName(), provider)
+		stsClient, err := sts20150401.NewClient(utils.GetStsConfig(GlobalConfigVar.Region).SetCredential(cred))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create STS client: %w", err)
+		}
+		provider = credentials.NewAssumeRoleProvider(clock.RealClock{}, stsClient, id.AssumeRoleArn, id.ExternalID)
+	}
+	client := newEcsClient(id.region(), credentials.V1ProviderAdaptor(provider))
+	if client == nil {
+		return nil, fmt.Errorf("failed to create ECS client for %s", id)
+	}
+	return client, nil
+}
+
+// identityControllerServer routes each request to a controller server of the account and region the disk lives in.
+//
+// CreateVolume and ControllerPublishVolume know the identity from StorageClass parameters or volume context.
+// CreateVolume records the identity in the volume context, so other requests on a disk find it in the PV.
+// Otherwise, the disk or snapshot ID is looked up in the identities used by StorageClasses,
+// and remembered afterwards.
+// ListSnapshots and group snapshots always use the default identity.
+type identityControllerServer struct {
+	*controllerServer // the default identity
+
+	newClient      func(cloudIdentity) (cloud.ECSInterface, error)
+	newServer      func(cloudIdentity, cloud.ECSInterface) *controllerServer
+	listIdentities func(context.Context) ([]cloudIdentity, error)
+	// pvIdentity returns the identity recorded in the PV of a disk, if the PV is found.
+	pvIdentity      func(diskID string) (cloudIdentity, bool)
+	clock           clock.PassiveClock
+	refreshInterval time.Duration
+
+	mu          sync.Mutex
+	servers     map[cloudIdentity]*controllerServer
+	known       []cloudIdentity
+	lastRefresh time.Time
+
+	// disk or snapshot ID -> cloudIdentity
+	owners sync.Map
+}
+
+func newIdentityControllerServer(base *controllerServer, newServer func(cloudIdentity, cloud.ECSInterface) *controllerServer) *identityControllerServer {
+	return &identityControllerServer{
+		controllerServer: base,
+		newClient:        newIdentityECSClient,
+		newServer:        newServer,
+		listIdentities:   listStorageClassIdentities,
+		pvIdentity:       func(string) (cloudIdentity, bool) { return cloudIdentity{}, false },
+		clock:            clock.RealClock{},
+		refreshInterval:  identityRefreshInterval,
+		servers:          map[cloudIdentity]*controllerServer{},
+	}
+}
+
+// listStorageClassIdentities returns non-default identities used by disk StorageClasses.
+func listStorageClassIdentities(ctx context.Context) ([]cloudIdentity, error) {
+	scs, err := GlobalConfigVar.ClientSet.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var ids []cloudIdentity
+	for _, sc := range scs.Items {
+		if sc.Provisioner != driverName {
+			continue
+		}
+		id := identityFromParameters(sc.Parameters)
+		if !id.isDefault() && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *identityControllerServer) serverFor(id cloudIdentity) (*controllerServer, error) {
+	if id.isDefault() {
+		return s.controllerServer, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cs, ok := s.servers[id]; ok {
+		return cs, nil
+	}
+	client, err := s.newClient(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ECS client for %s: %w", id, err)
+	}
+	klog.InfoS("created controller for cloud identity", "identity", id)
+	cs := s.newServer(id, client)
+	s.servers[id] = cs
+	if !slices.Contains(s.known, id) {
+		s.known = append(s.known, id)
+	}
+	return cs, nil
+}
+
+// candidates returns the non-default identities a disk or snapshot may belong to.
+// StorageClasses are listed again only if refresh is true and the last list is old enough.
+func (s *identityControllerServer) candidates(ctx context.Context, refresh bool) []cloudIdentity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	if refresh && now.Sub(s.lastRefresh) >= s.refreshInterval {
+		s.lastRefresh = now
+		ids, err := s.listIdentities(ctx)
+		if err != nil {
+			klog.ErrorS(err, "failed to list StorageClasses for cloud identities")
+		}
+		for _, id := range ids {
+			if !slices.Contains(s.known, id) {
+				s.known = append(s.known, id)
+			}
+		}
+	}
+	return slices.Clone(s.known)
+}
+
+// lookup finds the controller server that owns the disk or snapshot id.
+// exists reports whether the resource is visible to a controller server.
+// It falls back to the default identity only if the resource is not found in any identity,
+// e.g. it is already deleted. If any identity fails to look up, an error is returned instead,
+// so that the resource is not mistaken as deleted.
+func (s *identityControllerServer) lookup(ctx context.Context, id string, exists func(*controllerServer) (bool, error)) (*controllerServer, error) {
+	if isThinVolumeID(id) || isThinSnapshotID(id) {
+		// not cloud resources
+		return s.controllerServer, nil
+	}
+	if v, ok := s.owners.Load(id); ok {
+		return s.serverFor(v.(cloudIdentity))
+	}
+	if identity, ok := s.pvIdentity(id); ok && !identity.isDefault() {
+		cs, err := s.serverFor(identity)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to use cloud identity %s recorded in the PV of %s: %v", identity, id, err)
+		}
+		s.remember(id, identity)
+		return cs, nil
+	}
+	if len(s.candidates(ctx, true)) == 0 {
+		return s.controllerServer, nil
+	}
+	found, err := exists(s.controllerServer)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return s.controllerServer, nil
+	}
+	var errs []error
+	for _, identity := range s.candidates(ctx, false) {
+		cs, err := s.serverFor(identity)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		found, err := exists(cs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", identity, err))
+			continue
+		}
+		if found {
+			klog.V(2).InfoS("found resource in cloud identity", "id", id, "identity", identity)
+			s.owners.Store(id, identity)
+			return cs, nil
+		}
+	}
+	if len(errs) > 0 {
+		return nil, status.Errorf(codes.Unavailable, "%s not found in the default cloud identity, and failed to look up in others: %v", id, errors.Join(errs...))
+	}
+	return s.controllerServer, nil
+}
+
+const pvVolumeHandleIndex = "volumeHandle"
+
+// newPVIdentityLookup watches disk PVs, and returns the identity recorded in the volume attributes of the PV of a disk.
+func newPVIdentityLookup(client kubernetes.Interface) func(diskID string) (cloudIdentity, bool) {
+	informer := informercorev1.NewPersistentVolumeInformer(client, 0, cache.Indexers{
+		pvVolumeHandleIndex: func(obj any) ([]string, error) {
+			pv, ok := obj.(*v1.PersistentVolume)
+			if !ok || pv.Spec.CSI == nil || pv.Spec.CSI.Driver != driverName {
+				return nil, nil
+			}
+			return []string{pv.Spec.CSI.VolumeHandle}, nil
+		},
+	})
+	go informer.Run(wait.NeverStop)
+	return func(diskID string) (cloudIdentity, bool) {
+		if !informer.HasSynced() {
+			return cloudIdentity{}, false
+		}
+		objs, err := informer.GetIndexer().ByIndex(pvVolumeHandleIndex, diskID)
+		if err != nil || len(objs) == 0 {
+			return cloudIdentity{}, false
+		}
+		return identityFromParameters(objs[0].(*v1.PersistentVolume).Spec.CSI.VolumeAttributes), true
+	}
+}
+
+func (s *identityControllerServer) forDisk(ctx context.Context, diskID string) (*controllerServer, error) {
+	return s.lookup(ctx, diskID, func(cs *controllerServer) (bool, error) {
+		disk, err := cs.cd.batcher.Describe(ctx, diskID)
+		return disk != nil, err
+	})
+}
+
+func (s *identityControllerServer) forSnapshot(ctx context.Context, snapshotID string) (*controllerServer, error) {
+	return s.lookup(ctx, snapshotID, func(cs *controllerServer) (bool, error) {
+		snapshot, err := findDiskSnapshotByID(snapshotID, cs.ecs)
+		return snapshot != nil, err
+	})
+}
+
+func (s *identityControllerServer) remember(id string, identity cloudIdentity) {
+	if !identity.isDefault() {
+		s.owners.Store(id, identity)
+	}
+}
+
+func (s *identityControllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+	identity := identityFromParameters(req.Parameters)
+	cs, err := s.serverFor(identity)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cs.CreateVolume(ctx, req)
+	if err == nil {
+		s.remember(resp.Volume.VolumeId, identity)
+		if resp.Volume.VolumeContext == nil {
+			resp.Volume.VolumeContext = map[string]string{}
+		}
+		identity.setVolumeContext(resp.Volume.VolumeContext)
+	}
+	return resp, err
+}
+
+func (s *identityControllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
+	cs, err := s.forDisk(ctx, req.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cs.DeleteVolume(ctx, req)
+	if err == nil {
+		s.owners.Delete(req.VolumeId)
+	}
+	return resp, err
+}
+
+func (s *identityControllerServer) ValidateVolumeCapabilities(ctx context.Context, req *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
+	cs, err := s.serverFor(identityFromParameters(req.VolumeContext))
+	if err != nil {
+		return nil, err
+	}
+	return cs.ValidateVolumeCapabilities(ctx, req)
+}
+
+func (s *identityControllerServer) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
+	identity := identityFromParameters(req.VolumeContext)
+	cs, err := s.serverFor(identity)
+	if err != nil {
+		return nil, err
+	}
+	s.remember(req.VolumeId, identity)
+	return cs.ControllerPublishVolume(ctx, req)
+}
+
+func (s *identityControllerServer) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
+	cs, err := s.forDisk(ctx, req.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	return cs.ControllerUnpublishVolume(ctx, req)
+}
+
+func (s *identityControllerServer) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
+	cs, err := s.forDisk(ctx, req.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	return cs.ControllerExpandVolume(ctx, req)
+}
+
+func (s *identityControllerServer) ControllerModifyVolume(ctx context.Context, req *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
+	cs, err := s.forDisk(ctx, req.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	return cs.ControllerModifyVolume(ctx, req)
+}
+
+func (s *identityControllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
+	cs, err := s.forDisk(ctx, req.SourceVolumeId)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cs.CreateSnapshot(ctx, req)
+	if err == nil {
+		if v, ok := s.owners.Load(req.SourceVolumeId); ok {
+			s.remember(resp.Snapshot.SnapshotId, v.(cloudIdentity))
+		}
+	}
+	return resp, err
+}
+
+func (s *identityControllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
+	cs, err := s.forSnapshot(ctx, req.SnapshotId)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cs.DeleteSnapshot(ctx, req)
+	if err == nil {
+		s.owners.Delete(req.SnapshotId)
+	}
+	return resp, err
+}
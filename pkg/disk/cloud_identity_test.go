@@ -0,0 +1,192 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/fake"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/batcher"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/desc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	clocktest "k8s.io/utils/clock/testing"
+)
+
+func TestIdentityFromParameters(t *testing.T) {
+	GlobalConfigVar.Region = "cn-hangzhou"
+	t.Cleanup(func() { GlobalConfigVar.Region = "" })
+
+	assert.True(t, identityFromParameters(nil).isDefault())
+	assert.True(t, identityFromParameters(map[string]string{RegionIDKey: "cn-hangzhou"}).isDefault())
+	assert.True(t, identityFromParameters(map[string]string{ExternalIDKey: "ext"}).isDefault())
+
+	id := identityFromParameters(map[string]string{
+		RegionIDKey:      "cn-beijing",
+		AssumeRoleArnKey: "acs:ram::123456:role/storage",
+		ExternalIDKey:    "ext",
+	})
+	assert.Equal(t, cloudIdentity{RegionID: "cn-beijing", AssumeRoleArn: "acs:ram::123456:role/storage", ExternalID: "ext"}, id)
+	assert.Equal(t, "cn-beijing/acs:ram::123456:role/storage", id.String())
+	assert.Equal(t, "cn-hangzhou", cloudIdentity{}.String())
+}
+
+func testServerFor(c cloud.ECSInterface) *controllerServer {
+	return &controllerServer{
+		ecs: c,
+		cd: DiskCreateDelete{
+			ecs:     c,
+			batcher: batcher.NewPassthrough(desc.Disk(c)),
+		},
+	}
+}
+
+func createFakeDisk(t *testing.T, c *fake.Cloud) string {
+	t.Helper()
+	req := ecs.CreateCreateDiskRequest()
+	req.ZoneId = "cn-beijing-a"
+	req.DiskCategory = string(DiskESSD)
+	req.Size = requests.NewInteger(20)
+	resp, err := c.CreateDisk(req)
+	require.NoError(t, err)
+	return resp.DiskId
+}
+
+func TestIdentityControllerServerLookup(t *testing.T) {
+	ctx := context.Background()
+	defaultCloud := fake.New(fake.Options{})
+	defaultCloud.AddZone("cn-hangzhou-a", string(DiskESSD))
+	storageCloud := fake.New(fake.Options{RegionID: "cn-beijing"})
+	storageCloud.AddZone("cn-beijing-a", string(DiskESSD))
+	storage := cloudIdentity{RegionID: "cn-beijing", AssumeRoleArn: "acs:ram::123456:role/storage"}
+
+	s := newIdentityControllerServer(testServerFor(defaultCloud), func(_ cloudIdentity, c cloud.ECSInterface) *controllerServer {
+		return testServerFor(c)
+	})
+	clk := clocktest.NewFakePassiveClock(time.Now())
+	s.clock = clk
+	s.newClient = func(id cloudIdentity) (cloud.ECSInterface, error) {
+		require.Equal(t, storage, id)
+		return storageCloud, nil
+	}
+	var identities []cloudIdentity
+	listed := 0
+	s.listIdentities = func(context.Context) ([]cloudIdentity, error) {
+		listed++
+		return identities, nil
+	}
+
+	// no other identity, no need to look up
+	cs, err := s.forDisk(ctx, "d-unknown")
+	require.NoError(t, err)
+	assert.Same(t, s.controllerServer, cs)
+	assert.Equal(t, 1, listed)
+
+	// StorageClasses are not listed again too soon
+	identities = []cloudIdentity{storage}
+	_, err = s.forDisk(ctx, "d-unknown")
+	require.NoError(t, err)
+	assert.Equal(t, 1, listed)
+
+	clk.SetTime(clk.Now().Add(identityRefreshInterval))
+	diskID := createFakeDisk(t, storageCloud)
+	cs, err = s.forDisk(ctx, diskID)
+	require.NoError(t, err)
+	assert.Same(t, storageCloud, cs.ecs)
+	assert.Equal(t, 2, listed)
+
+	// remembered
+	storageCloud.InjectError("DescribeDisks", fake.ServerError("Throttling", "should not be called"))
+	cs, err = s.forDisk(ctx, diskID)
+	require.NoError(t, err)
+	assert.Same(t, storageCloud, cs.ecs)
+
+	// failed to look up in the other identity, do not mistake it as deleted
+	_, err = s.forDisk(ctx, "d-deleted")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	// not found anywhere, fall back to default
+	cs, err = s.forDisk(ctx, "d-deleted")
+	require.NoError(t, err)
+	assert.Same(t, s.controllerServer, cs)
+}
+
+func TestIdentityControllerServerLookupPV(t *testing.T) {
+	ctx := context.Background()
+	storageCloud := fake.New(fake.Options{RegionID: "cn-beijing"})
+	storageCloud.AddZone("cn-beijing-a", string(DiskESSD))
+	storage := cloudIdentity{RegionID: "cn-beijing", AssumeRoleArn: "acs:ram::123456:role/storage"}
+	diskID := createFakeDisk(t, storageCloud)
+
+	s := newIdentityControllerServer(testServerFor(fake.New(fake.Options{})), func(_ cloudIdentity, c cloud.ECSInterface) *controllerServer {
+		return testServerFor(c)
+	})
+	s.listIdentities = func(context.Context) ([]cloudIdentity, error) {
+		t.Fatal("should not list StorageClasses")
+		return nil, nil
+	}
+	s.pvIdentity = func(id string) (cloudIdentity, bool) {
+		if id != diskID {
+			return cloudIdentity{}, false
+		}
+		return identityFromParameters(map[string]string{RegionIDKey: storage.RegionID, AssumeRoleArnKey: storage.AssumeRoleArn}), true
+	}
+
+	// the identity recorded in the PV can not be used, no fallback
+	s.newClient = func(cloudIdentity) (cloud.ECSInterface, error) {
+		return nil, errors.New("no permission to assume role")
+	}
+	_, err := s.forDisk(ctx, diskID)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	s.newClient = func(id cloudIdentity) (cloud.ECSInterface, error) {
+		require.Equal(t, storage, id)
+		return storageCloud, nil
+	}
+	cs, err := s.forDisk(ctx, diskID)
+	require.NoError(t, err)
+	assert.Same(t, storageCloud, cs.ecs)
+}
+
+func TestCloudIdentitySetVolumeContext(t *testing.T) {
+	ctx := map[string]string{"type": "cloud_essd"}
+	cloudIdentity{}.setVolumeContext(ctx)
+	assert.Equal(t, map[string]string{"type": "cloud_essd"}, ctx)
+
+	id := cloudIdentity{RegionID: "cn-beijing", AssumeRoleArn: "acs:ram::123456:role/storage", ExternalID: "ext"}
+	id.setVolumeContext(ctx)
+	assert.Equal(t, id, identityFromParameters(ctx))
+}
+
+func TestIdentityControllerServerServerFor(t *testing.T) {
+	s := newIdentityControllerServer(testServerFor(fake.New(fake.Options{})), func(_ cloudIdentity, c cloud.ECSInterface) *controllerServer {
+		return testServerFor(c)
+	})
+	created := 0
+	s.newClient = func(cloudIdentity) (cloud.ECSInterface, error) {
+		created++
+		return fake.New(fake.Options{RegionID: "cn-beijing"}), nil
+	}
+
+	cs, err := s.serverFor(cloudIdentity{})
+	require.NoError(t, err)
+	assert.Same(t, s.controllerServer, cs)
+	assert.Equal(t, 0, created)
+
+	id := cloudIdentity{RegionID: "cn-beijing"}
+	cs1, err := s.serverFor(id)
+	require.NoError(t, err)
+	cs2, err := s.serverFor(id)
+	require.NoError(t, err)
+	assert.Same(t, cs1, cs2)
+	assert.Equal(t, 1, created)
+	assert.Equal(t, []cloudIdentity{id}, s.candidates(context.Background(), false))
+}
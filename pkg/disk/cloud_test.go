@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	alicloudErr "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
@@ -13,11 +14,15 @@ import (
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
 	gomock "github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/fake"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/batcher"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/desc"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/waitstatus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2/ktesting"
 	"k8s.io/utils/clock"
@@ -83,7 +88,7 @@ func TestDeleteDiskPassthroughError(t *testing.T) {
 	c.EXPECT().DeleteDisk(gomock.Any()).Return(nil, serverErr)
 
 	_, err := cd.deleteDisk(ctx, c, "test-disk")
-	assert.Equal(t, serverErr, err)
+	assert.ErrorIs(t, err, serverErr)
 }
 
 func TestResizeDisk(t *testing.T) {
@@ -117,7 +122,7 @@ func TestResizeDiskPassthroughError(t *testing.T) {
 	c.EXPECT().ResizeDisk(gomock.Any()).Return(nil, serverErr)
 
 	_, err := resizeDisk(context.Background(), c, resizeDiskRequest)
-	assert.Equal(t, serverErr, err)
+	assert.ErrorIs(t, err, serverErr)
 }
 
 func TestListSnapshots(t *testing.T) {
@@ -748,3 +753,80 @@ func TestAttachDisk(t *testing.T) {
 		})
 	}
 }
+
+func TestDiskLifecycleWithFakeCloud(t *testing.T) { synctest.Test(t, testDiskLifecycleWithFakeCloud) }
+func testDiskLifecycleWithFakeCloud(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	c := fake.New(fake.Options{
+		TransitionDelay: 3 * time.Second,
+		Latency:         100 * time.Millisecond,
+	})
+	c.AddInstance("i-1", "cn-hangzhou-a")
+	c.SetStockOut("cn-hangzhou-a", string(DiskESSD), true)
+
+	client := desc.Disk(c)
+	b := batcher.NewPassthrough(client)
+	cd := &DiskCreateDelete{
+		ecs:             c,
+		batcher:         b,
+		createThrottler: defaultThrottler(),
+		deleteThrottler: defaultThrottler(),
+	}
+	ad := &DiskAttachDetach{
+		slots:           NewSlots(0, 0),
+		ecs:             c,
+		waiter:          waitstatus.NewSimple(client, clock.RealClock{}),
+		batcher:         b,
+		attachThrottler: defaultThrottler(),
+		detachThrottler: defaultThrottler(),
+		dev:             DefaultDeviceManager,
+	}
+
+	args := &diskVolumeArgs{
+		ZoneID:    "cn-hangzhou-a",
+		RequestGB: 20,
+		Type:      []Category{DiskESSD, DiskESSDAuto},
+	}
+	diskID, attempt, err := cd.createDisk(ctx, "pv-fake", "", args, nil, "", false)
+	require.NoError(t, err)
+	assert.Equal(t, DiskESSDAuto, attempt.Category)
+
+	// still creating, attach is rejected by the cloud
+	_, err = ad.attachDisk(ctx, diskID, "i-1", false)
+	assert.Error(t, err)
+
+	time.Sleep(3 * time.Second)
+	c.InjectThrottling("AttachDisk", 2)
+	serial, err := ad.attachDisk(ctx, diskID, "i-1", false)
+	require.NoError(t, err)
+	d, _ := c.Disk(diskID)
+	assert.Equal(t, d.SerialNumber, serial)
+	assert.Equal(t, "i-1", d.InstanceId)
+
+	err = ad.detachDisk(ctx, c, diskID, "i-1", false)
+	require.NoError(t, err)
+	d, _ = c.Disk(diskID)
+	assert.Equal(t, fake.DiskStatusAvailable, d.Status)
+
+	_, err = cd.deleteDisk(ctx, c, diskID)
+	require.NoError(t, err)
+	_, ok := c.Disk(diskID)
+	assert.False(t, ok)
+}
+
+func TestCreateDisk_AllAttemptsFailedClassified(t *testing.T) {
+	client, cd := testCreateDelete(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	client.EXPECT().CreateDisk(gomock.Any()).Return(nil, alicloudErr.NewServerError(400, `{"Code": "InvalidDiskSize.NotSupported"}`, ""))
+	client.EXPECT().CreateDisk(gomock.Any()).Return(nil, alicloudErr.NewServerError(400, `{"Code": "InvalidDataDiskSize.ValueNotSupported"}`, ""))
+
+	args := &diskVolumeArgs{Type: []Category{DiskESSD, DiskESSDAuto}, RequestGB: 20}
+	_, _, err := cd.createDisk(ctx, "disk-name", "", args, nil, "", false)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.ErrorContains(t, err, "all attempts failed")
+	info := wrap.ErrorInfo(err)
+	require.NotNil(t, info)
+	assert.Equal(t, "InvalidParameter", info.Reason)
+	assert.Equal(t, "InvalidDiskSize.NotSupported", info.Metadata["errorCode"])
+}
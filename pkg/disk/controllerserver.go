@@ -34,6 +34,8 @@ import (
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/desc"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/waitstatus"
@@ -45,6 +47,9 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
@@ -58,6 +63,8 @@ type controllerServer struct {
 	meta           metadata.MetadataProvider
 	ecs            cloud.ECSInterface
 	snapshotWaiter waitstatus.StatusWaiter[ecs.Snapshot]
+	// modifyThrottler throttles ModifyDiskSpec of IOPS autotuning
+	modifyThrottler *throttle.Throttler
 	common.GenericControllerServer
 }
 
@@ -84,61 +91,161 @@ type diskVolumeArgs struct {
 
 var delVolumeSnap sync.Map
 
-func newSnapshotStatusWaiter() waitstatus.StatusWaiter[ecs.Snapshot] {
+func newSnapshotStatusWaiter(ecsClient cloud.ECSInterface) waitstatus.StatusWaiter[ecs.Snapshot] {
 	client := desc.Snapshots{
-		Client: GlobalConfigVar.EcsClient,
+		Client: ecsClient,
 	}
 	waiter := waitstatus.NewBatched(client, clock.RealClock{}, 1*time.Second, 3*time.Second)
 	go waiter.Run(context.Background())
 	return waiter
 }
 
-// NewControllerServer is to create controller server
-func NewControllerServer(csiCfg utils.Config, ecs cloud.ECSInterface, m metadata.MetadataProvider) csi.ControllerServer {
-	waiter, batcher := newBatcher(false)
-	c := &controllerServer{
-		recorder: utils.NewEventRecorder(),
+var (
+	detachConcurrencyKey = utils.ConfigKey{Name: "disk-detach-concurrency", Env: "DISK_DETACH_CONCURRENCY", Default: "5", Validate: utils.ValidateInt}
+	attachConcurrencyKey = utils.ConfigKey{Name: "disk-attach-concurrency", Env: "DISK_ATTACH_CONCURRENCY", Default: "32", Validate: utils.ValidateInt}
+)
+
+// adConcurrency returns the max concurrent detach and attach operations per node.
+func adConcurrency(csiCfg utils.Config) (detach, attach int) {
+	detach, attach = 1, 1
+	if features.FunctionalMutableFeatureGate.Enabled(features.DiskParallelDetach) {
+		detach = detachConcurrencyKey.GetInt(csiCfg)
+	}
+	if features.FunctionalMutableFeatureGate.Enabled(features.DiskParallelAttach) {
+		attach = attachConcurrencyKey.GetInt(csiCfg)
+	}
+	return
+}
+
+// newControllerServerWithClient creates a controller server that calls OpenAPI of identity with ecs.
+// Each identity has its own rate limits.
+func newControllerServerWithClient(identity cloudIdentity, ecs cloud.ECSInterface, m metadata.MetadataProvider,
+	recorder record.EventRecorder, slots AttachDetachSlots,
+) *controllerServer {
+	waiter, batcher := newBatcher(ecs, false)
+	return &controllerServer{
+		recorder: recorder,
 		meta:     m,
 		ecs:      ecs,
 		ad: DiskAttachDetach{
+			slots:   slots,
 			ecs:     ecs,
 			waiter:  waiter,
 			batcher: batcher,
 
-			attachThrottler: defaultThrottler(),
-			detachThrottler: defaultThrottler(),
+			attachThrottler: limitedThrottler(identity, "AttachDisk", throttle.PriorityNormal),
+			detachThrottler: limitedThrottler(identity, "DetachDisk", throttle.PriorityHigh),
 		},
 		cd: DiskCreateDelete{
 			ecs:             ecs,
 			batcher:         batcher,
-			createThrottler: defaultThrottler(),
-			deleteThrottler: defaultThrottler(),
+			createThrottler: limitedThrottler(identity, "CreateDisk", throttle.PriorityLow),
+			deleteThrottler: limitedThrottler(identity, "DeleteDisk", throttle.PriorityHigh),
 		},
-		snapshotWaiter: newSnapshotStatusWaiter(),
+		snapshotWaiter:  newSnapshotStatusWaiter(ecs),
+		modifyThrottler: limitedThrottler(identity, "ModifyDiskSpec", throttle.PriorityLow),
 	}
-	detachConcurrency := 1
-	attachConcurrency := 1
-	if features.FunctionalMutableFeatureGate.Enabled(features.DiskParallelDetach) {
-		detachConcurrency = csiCfg.GetInt("disk-detach-concurrency", "DISK_DETACH_CONCURRENCY", 5)
-		klog.InfoS("Disk parallel detach enabled", "concurrency", detachConcurrency)
+}
+
+// runLeaderElected runs fn with leaderelection.RunOrDie while this replica holds the Lease,
+// and campaigns again once it is lost, so that a background controller runs on a single replica.
+func runLeaderElected(client kubernetes.Interface, lease string, fn func(ctx context.Context)) {
+	le, err := utils.NewLeaderElectionConfig(client, lease, fn)
+	if err != nil {
+		klog.ErrorS(err, "Background controller not running", "lease", lease)
+		return
 	}
-	if features.FunctionalMutableFeatureGate.Enabled(features.DiskParallelAttach) {
-		attachConcurrency = csiCfg.GetInt("disk-attach-concurrency", "DISK_ATTACH_CONCURRENCY", 32)
-		klog.InfoS("Disk parallel attach enabled", "concurrency", attachConcurrency)
+	wait.UntilWithContext(context.Background(), func(ctx context.Context) {
+		leaderelection.RunOrDie(ctx, le)
+	}, le.RetryPeriod)
+}
+
+// NewControllerServer is to create controller server
+func NewControllerServer(csiCfg utils.Config, ecs cloud.ECSInterface, m metadata.MetadataProvider) csi.ControllerServer {
+	detachConcurrency, attachConcurrency := adConcurrency(csiCfg)
+	klog.InfoS("Disk attach/detach concurrency", "detach", detachConcurrency, "attach", attachConcurrency)
+	slots := NewReloadableSlots(detachConcurrency, attachConcurrency)
+	recorder := utils.NewEventRecorder()
+	defaultServer := newControllerServerWithClient(cloudIdentity{}, ecs, m, recorder, slots)
+	defaultServer.cd.warmPool = newWarmPool(csiCfg, ecs)
+	c := newIdentityControllerServer(
+		defaultServer,
+		func(identity cloudIdentity, client cloud.ECSInterface) *controllerServer {
+			return newControllerServerWithClient(identity, client, m, recorder, slots)
+		})
+	if GlobalConfigVar.ClientSet != nil {
+		c.pvIdentity = newPVIdentityLookup(GlobalConfigVar.ClientSet)
+	}
+	if features.FunctionalMutableFeatureGate.Enabled(features.DiskRegionalFailover) {
+		f := &failoverController{
+			client: GlobalConfigVar.ClientSet,
+			ecs:    ecs,
+			adFor: func(identity cloudIdentity) (*DiskAttachDetach, error) {
+				cs, err := c.serverFor(identity)
+				if err != nil {
+					return nil, err
+				}
+				return &cs.ad, nil
+			},
+			recorder: recorder,
+			clk:      clock.RealClock{},
+			config:   parseFailoverConfig(csiCfg),
+		}
+		go f.run(context.Background())
+		go runLeaderElected(GlobalConfigVar.ClientSet, failoverLease, f.lead)
+	}
+	if features.FunctionalMutableFeatureGate.Enabled(features.DiskKMSKeyRotation) {
+		r := &kmsRotationController{
+			client: GlobalConfigVar.ClientSet,
+			ecsFor: func(ctx context.Context, diskID string) (cloud.ECSInterface, error) {
+				cs, err := c.forDisk(ctx, diskID)
+				if err != nil {
+					return nil, err
+				}
+				return cs.ecs, nil
+			},
+			recorder: recorder,
+			clk:      clock.RealClock{},
+		}
+		go runLeaderElected(GlobalConfigVar.ClientSet, kmsRotationLease, r.run)
+	}
+	if features.FunctionalMutableFeatureGate.Enabled(features.DiskIOPSAutotune) {
+		t := &iopsAutotuner{
+			client:    GlobalConfigVar.ClientSet,
+			serverFor: c.forDisk,
+			source:    pvAnnotationIOSource{},
+			recorder:  recorder,
+			clk:       clock.RealClock{},
+		}
+		go runLeaderElected(GlobalConfigVar.ClientSet, iopsAutotuneLease, t.run)
 	}
-	c.ad.slots = NewSlots(detachConcurrency, attachConcurrency)
+
+	utils.CSIPluginConfig.RegisterKeys(detachConcurrencyKey, attachConcurrencyKey)
+	utils.CSIPluginConfig.Subscribe(func(cfg utils.Config) {
+		detach, attach := adConcurrency(cfg)
+		if detach == detachConcurrency && attach == attachConcurrency {
+			return
+		}
+		klog.InfoS("Disk attach/detach concurrency changed", "detach", detach, "attach", attach)
+		detachConcurrency, attachConcurrency = detach, attach
+		slots.Resize(detach, attach)
+	})
 	return c
 }
 
 func (cs *controllerServer) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
+	caps := []csi.ControllerServiceCapability_RPC_Type{
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+		csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+		csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+	}
+	if features.FunctionalMutableFeatureGate.Enabled(features.DiskKMSKeyRotation) {
+		caps = append(caps, csi.ControllerServiceCapability_RPC_MODIFY_VOLUME)
+	}
 	return &csi.ControllerGetCapabilitiesResponse{
-		Capabilities: common.ControllerRPCCapabilities(
-			csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
-			csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
-			csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
-			csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
-			csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
-		),
+		Capabilities: common.ControllerRPCCapabilities(caps...),
 	}, nil
 }
 
@@ -149,7 +256,7 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 
 	// 兼容 serverless 拓扑感知场景；
 	// req参数里面包含了云盘ID，则直接使用云盘ID进行返回；
-	csiVolume, err := staticVolumeCreate(req)
+	csiVolume, err := staticVolumeCreate(ctx, req, cs.ecs)
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "create static volume failed: %v", err)
 	}
@@ -198,9 +305,11 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 			case SnapshotNotFound:
 				return nil, status.Errorf(codes.NotFound, "snapshot %s not found", snapshotID)
 			default:
-				return nil, status.Error(codes.Internal, err.Error())
+				err = wrap.ToStatus(codes.Internal, err.Error(), err)
 			}
 		}
+		// all attempts failed is already converted by createDisk, with the last classified error
+		common.RecordOpenAPIError(cs.recorder, req.Parameters, err)
 		return nil, err
 	}
 
@@ -217,7 +326,7 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 
 	klog.Infof("CreateVolume: Successfully created Disk %s: id[%s], zone[%s], disktype[%s], snapshotID[%s]", req.GetName(), diskID, diskVol.ZoneID, attempt, snapshotID)
 
-	tmpVol := volumeCreate(attempt, diskID, utils.Gi2Bytes(int64(diskVol.RequestGB)), volumeContext, diskVol.ZoneID, volumeContentSource(snapshotID))
+	tmpVol := volumeCreate(attempt, diskID, utils.Gi2Bytes(int64(diskVol.RequestGB)), volumeContext, diskVol.RegionID, diskVol.ZoneID, volumeContentSource(snapshotID))
 
 	return &csi.CreateVolumeResponse{Volume: tmpVol}, nil
 }
@@ -225,7 +334,6 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 // call ecs api to delete disk
 func (cs *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
 	klog.Infof("DeleteVolume: Starting deleting volume %s", req.VolumeId)
-
 	// For now the image get unconditionally deleted, but here retention policy can be checked
 	var disk *ecs.Disk
 	describeDisk := func() (*csi.DeleteVolumeResponse, error) {
@@ -278,7 +386,7 @@ func (cs *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVol
 			}
 		}
 		klog.Infof("DeleteVolume: snapshot before delete configured")
-		err := snapshotBeforeDelete(disk, cs.ecs)
+		err := snapshotBeforeDelete(ctx, disk, cs.ecs)
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, "DeleteVolume: failed to create snapshot before delete disk, err: %v", err)
 		}
@@ -323,7 +431,6 @@ func (cs *controllerServer) ValidateVolumeCapabilities(ctx context.Context, req
 
 // ControllerPublishVolume do attach
 func (cs *controllerServer) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
-
 	if GlobalConfigVar.WaitBeforeAttach {
 		time.Sleep(5 * time.Second)
 		klog.Infof("ControllerPublishVolume: sleep 5s")
@@ -373,6 +480,11 @@ func (cs *controllerServer) ControllerUnpublishVolume(ctx context.Context, req *
 		return &csi.ControllerUnpublishVolumeResponse{}, nil
 	}
 
+	if cs.ad.isFenced(req.VolumeId, req.NodeId) {
+		klog.Infof("ControllerUnpublishVolume: disk %s is fenced from lost node %s, skip detaching, it will be force attached to the next node", req.VolumeId, req.NodeId)
+		return &csi.ControllerUnpublishVolumeResponse{}, nil
+	}
+
 	klog.Infof("ControllerUnpublishVolume: detach disk: %s from node: %s", req.VolumeId, req.NodeId)
 	err := cs.ad.detachDisk(ctx, cs.ecs, req.VolumeId, req.NodeId, false)
 	if err != nil {
@@ -492,7 +604,7 @@ func (cs *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateS
 	// init createSnapshotRequest and parameters
 	params.SourceVolumeID = sourceVolumeID
 	params.SnapshotName = req.Name
-	snapshotResponse, err := requestAndCreateSnapshot(cs.ecs, params)
+	snapshotResponse, err := requestAndCreateSnapshot(ctx, cs.ecs, params)
 
 	if err != nil {
 		return nil, err
@@ -515,7 +627,7 @@ func (cs *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateS
 	}, nil
 }
 
-func snapshotBeforeDelete(disk *ecs.Disk, ecsClient cloud.ECSInterface) error {
+func snapshotBeforeDelete(ctx context.Context, disk *ecs.Disk, ecsClient cloud.ECSInterface) error {
 	if !AllCategories[Category(disk.Category)].InstantAccessSnapshot {
 		klog.Infof("snapshotBeforeDelete: Instant Access snapshot required, but current disk.Category is: %s", disk.Category)
 		return nil
@@ -537,7 +649,7 @@ func snapshotBeforeDelete(disk *ecs.Disk, ecsClient cloud.ECSInterface) error {
 	if value, ok := delVolumeSnap.Load(volumeID); ok {
 		return createStaticSnap(volumeID, value.(string), GlobalConfigVar.SnapClient)
 	}
-	resp, err := requestAndCreateSnapshot(ecsClient, &createSnapshotParams{
+	resp, err := requestAndCreateSnapshot(ctx, ecsClient, &createSnapshotParams{
 		SourceVolumeID: volumeID,
 		SnapshotName:   deleteVolumeSnapshotName,
 		RetentionDays:  iValue,
@@ -561,7 +673,7 @@ func (cs *controllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteS
 	klog.Infof("DeleteSnapshot:: starting delete snapshot %s", snapshotID)
 
 	// Check Snapshot exist
-	snapshot, err := findDiskSnapshotByID(req.SnapshotId)
+	snapshot, err := findDiskSnapshotByID(req.SnapshotId, cs.ecs)
 	if err != nil {
 		var aliErr *alicloudErr.ServerError
 		if errors.As(err, &aliErr) && aliErr.ErrorCode() == SnapshotNotFound {
@@ -579,7 +691,7 @@ func (cs *controllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteS
 	klog.Infof("DeleteSnapshot: Snapshot %s exist with Info: %+v, %+v", snapshotID, snapshot, err)
 
 	var reqId string
-	response, err := requestAndDeleteSnapshot(snapshotID)
+	response, err := requestAndDeleteSnapshot(ctx, cs.ecs, snapshotID)
 	if response != nil {
 		reqId = response.RequestId
 	}
@@ -600,7 +712,7 @@ func (cs *controllerServer) ListSnapshots(ctx context.Context, req *csi.ListSnap
 	klog.Infof("ListSnapshots:: called with args: %+v", req)
 	snapshotID := req.GetSnapshotId()
 	if len(snapshotID) > 0 {
-		snapshot, err := findDiskSnapshotByID(snapshotID)
+		snapshot, err := findDiskSnapshotByID(snapshotID, cs.ecs)
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, "failed to find Snapshot id %s: %v", req.SnapshotId, err.Error())
 		}
@@ -627,7 +739,6 @@ func (cs *controllerServer) ListSnapshots(ctx context.Context, req *csi.ListSnap
 func (cs *controllerServer) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest,
 ) (*csi.ControllerExpandVolumeResponse, error) {
 	klog.Infof("ControllerExpandVolume:: Starting expand disk with: %v", req)
-
 	// check resize conditions
 	volSizeBytes := int64(req.GetCapacityRange().GetRequiredBytes())
 	requestGB := int((volSizeBytes + 1024*1024*1024 - 1) / (1024 * 1024 * 1024))
@@ -653,7 +764,6 @@ func (cs *controllerServer) ControllerExpandVolume(ctx context.Context, req *csi
 
 	// do resize
 	resizeDiskRequest := ecs.CreateResizeDiskRequest()
-	resizeDiskRequest.RegionId = GlobalConfigVar.Region
 	resizeDiskRequest.DiskId = disk.DiskId
 	resizeDiskRequest.NewSize = requests.NewInteger(requestGB)
 	if disk.Status == DiskStatusInuse {
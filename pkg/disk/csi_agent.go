@@ -6,9 +6,11 @@ import (
 	"context"
 	"errors"
 	"os"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/mounter"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/metric"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -84,7 +86,9 @@ func (a *CSIAgent) NodePublishVolume(ctx context.Context, req *csi.NodePublishVo
 		return &csi.NodePublishVolumeResponse{}, nil
 	}
 
+	discoverStart := time.Now()
 	device, err := DefaultDeviceManager.WaitDevice(ctx, serial)
+	metric.ObserveVolumePhase(driverType, req.VolumeContext["type"], metric.PhaseDeviceDiscovery, discoverStart, err)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "device not found: %v", err)
 	}
@@ -66,7 +66,7 @@ func (c disk) BatchSize() int {
 }
 
 type Snapshots struct {
-	*ecs.Client
+	Client cloud.ECSInterface
 }
 
 func (c Snapshots) Describe(ids []string) (Response[ecs.Snapshot], error) {
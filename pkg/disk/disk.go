@@ -27,6 +27,7 @@ import (
 	efloclient "github.com/alibabacloud-go/eflo-controller-20221215/v3/client"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
 	snapClientset "github.com/kubernetes-csi/external-snapshotter/client/v8/clientset/versioned"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
@@ -66,6 +67,10 @@ var (
 type DISK struct {
 	endpoint string
 	servers  common.Servers
+	// thinPoolServers serves thinPoolDriverName if DiskThinPool feature gate is enabled
+	thinPoolServers *common.Servers
+	// cancel stops the background work of the servers once they stop serving
+	cancel context.CancelFunc
 }
 
 // GlobalConfig save global values for plugin
@@ -95,6 +100,10 @@ type GlobalConfig struct {
 	DiskAllowAllType     bool
 	// Use topology.diskplugin.csi.alibabacloud.com prefix instead of topology.kubernetes.io as CSI topology key
 	PrivateTopologyKey bool
+	// OpenAPIQPS is the max rate of all ECS OpenAPIs sent by this process, 0 for unlimited
+	OpenAPIQPS int
+	// ThinPool configures the pool disk when DiskThinPool feature gate is enabled
+	ThinPool thinPoolConfig
 }
 
 // define global variable
@@ -112,6 +121,8 @@ func NewDriver(m metadata.MetadataProvider, endpoint string, serviceType utils.S
 	initDriver()
 	tmpdisk := &DISK{}
 	tmpdisk.endpoint = endpoint
+	ctx, cancel := context.WithCancel(context.Background())
+	tmpdisk.cancel = cancel
 
 	GlobalConfigSet(m, csiCfg)
 
@@ -141,13 +152,22 @@ func NewDriver(m metadata.MetadataProvider, endpoint string, serviceType utils.S
 	if serviceType&utils.Controller != 0 {
 		servers.ControllerServer = NewControllerServer(csiCfg, client, m)
 	}
+	var ns *nodeServer
 	if serviceType&utils.Node != 0 {
-		servers.NodeServer = NewNodeServer(client, m)
+		ns = NewNodeServer(ctx, client, m).(*nodeServer)
+		servers.NodeServer = ns
 	}
 	if features.FunctionalMutableFeatureGate.Enabled(features.EnableVolumeGroupSnapshots) {
 		servers.GroupControllerServer = NewGroupControllerServer()
 	}
+	if serviceType&utils.Controller != 0 && features.FunctionalMutableFeatureGate.Enabled(features.DiskSnapshotMetadata) {
+		servers.SnapshotMetadataServer = NewSnapshotMetadataServer(client, metadata.MustGet(m, metadata.RegionID))
+	}
 	tmpdisk.servers = servers
+	if features.FunctionalMutableFeatureGate.Enabled(features.DiskThinPool) {
+		thinPoolServers := newThinPoolServers(GlobalConfigVar.ClientSet, client, ns, serviceType&utils.Controller != 0)
+		tmpdisk.thinPoolServers = &thinPoolServers
+	}
 
 	return tmpdisk
 }
@@ -155,6 +175,12 @@ func NewDriver(m metadata.MetadataProvider, endpoint string, serviceType utils.S
 // Run start a new NodeServer
 func (disk *DISK) Run() {
 	klog.Infof("Starting csi-plugin Driver: %v version: %v", driverName, version.VERSION)
+	if disk.thinPoolServers != nil {
+		endpoint := thinPoolEndpoint(disk.endpoint)
+		klog.Infof("Starting csi-plugin Driver: %v endpoint: %v", thinPoolDriverName, endpoint)
+		go common.RunCSIServer(driverType, endpoint, *disk.thinPoolServers)
+	}
+	defer disk.cancel()
 	common.RunCSIServer(driverType, disk.endpoint, disk.servers)
 }
 
@@ -223,6 +249,8 @@ func GlobalConfigSet(m metadata.MetadataProvider, csiCfg utils.Config) {
 		OmitFilesystemCheck: csiCfg.GetBool("disable-fs-check", "DISABLE_FS_CHECK", false),
 		DiskAllowAllType:    csiCfg.GetBool("disk-allow-all-type", "DISK_ALLOW_ALL_TYPE", false),
 		PrivateTopologyKey:  csiCfg.GetBool("private-topology-key", "PRIVATE_TOPOLOGY_KEY", false),
+		OpenAPIQPS:          csiCfg.GetInt("disk-openapi-qps", "DISK_OPENAPI_QPS", 0),
+		ThinPool:            parseThinPoolConfig(csiCfg),
 	}
 	if csiCfg.GetBool("disk-multi-tenant-enable", "DISK_MULTI_TENANT_ENABLE", false) {
 		panic("Disk multi tenant support has been removed. Please remove the related config")
@@ -249,8 +277,8 @@ func GlobalConfigSet(m metadata.MetadataProvider, csiCfg utils.Config) {
 	)
 }
 
-func newBatcher(fromNode bool) (waitstatus.StatusWaiter[ecs.Disk], batcher.Batcher[ecs.Disk]) {
-	client := desc.Disk(GlobalConfigVar.EcsClient)
+func newBatcher(ecsClient cloud.ECSInterface, fromNode bool) (waitstatus.StatusWaiter[ecs.Disk], batcher.Batcher[ecs.Disk]) {
+	client := desc.Disk(ecsClient)
 	ctx := context.Background()
 	interval := 1 * time.Second
 	max := 2 * time.Second
@@ -271,6 +299,28 @@ func defaultThrottler() *throttle.Throttler {
 	return throttle.NewThrottler(clock.RealClock{}, 1*time.Second, 10*time.Second)
 }
 
+// limitedThrottler is defaultThrottler of ECS OpenAPI api with the rate limited.
+// All OpenAPIs of the same identity in this process share one limiter,
+// so that requests of higher priority p are served first when the quota is contended.
+func limitedThrottler(identity cloudIdentity, api string, p throttle.Priority) *throttle.Throttler {
+	name := "ecs"
+	if !identity.isDefault() {
+		name += "@" + identity.String()
+		api += "@" + identity.String()
+	}
+	t := defaultThrottler().Named("ecs." + api)
+	qps := float64(GlobalConfigVar.OpenAPIQPS)
+	if qps <= 0 {
+		return t
+	}
+	l := throttle.DefaultRegistry.Get(name, throttle.LimiterConfig{
+		MaxRate: qps,
+		MinRate: qps / 20,
+		Burst:   max(1, int(qps)),
+	})
+	return t.WithLimiter(l, p)
+}
+
 // parseLingjunNodeDiskTypes parses allowed disk types for Lingjun nodes from a comma-separated string.
 // If value is nil or empty, default to essd_auto & cloud_essd.
 // Example: "cloud_essd,cloud_auto". Unknown entries are ignored.
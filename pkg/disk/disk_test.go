@@ -19,8 +19,10 @@ limitations under the License.
 package disk
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -111,3 +113,27 @@ func TestParseLingjunNodeDiskTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestLimitedThrottlerSharesLimiter(t *testing.T) {
+	orig := GlobalConfigVar.OpenAPIQPS
+	t.Cleanup(func() { GlobalConfigVar.OpenAPIQPS = orig })
+	ecsLimiters := func() []string {
+		var names []string
+		for _, l := range throttle.DefaultRegistry.Limiters() {
+			if strings.HasPrefix(l.Name(), "ecs") {
+				names = append(names, l.Name())
+			}
+		}
+		return names
+	}
+
+	GlobalConfigVar.OpenAPIQPS = 0
+	limitedThrottler(cloudIdentity{}, "CreateDisk", throttle.PriorityLow)
+	assert.Empty(t, ecsLimiters(), "unlimited by default")
+
+	GlobalConfigVar.OpenAPIQPS = 10
+	limitedThrottler(cloudIdentity{}, "CreateDisk", throttle.PriorityLow)
+	limitedThrottler(cloudIdentity{}, "DetachDisk", throttle.PriorityHigh)
+	limitedThrottler(cloudIdentity{RegionID: "cn-beijing"}, "AttachDisk", throttle.PriorityNormal)
+	assert.Equal(t, []string{"ecs", "ecs@" + cloudIdentity{RegionID: "cn-beijing"}.String()}, ecsLimiters())
+}
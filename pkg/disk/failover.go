@@ -0,0 +1,316 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	failoverInterval = 10 * time.Second
+	failoverLease    = "csi-disk-failover"
+	// instanceStatusStopped is the ECS instance status required before fencing disks from its node
+	instanceStatusStopped = "Stopped"
+	// FailoverTaintAnnotation marks nodes tainted out-of-service by the failover controller,
+	// so that the taint is removed once the node is ready again.
+	FailoverTaintAnnotation = "csi.alibabacloud.com/failover-tainted"
+
+	eventVolumeFenced  = "VolumeFenced"
+	eventNodeTainted   = "OutOfServiceTainted"
+	eventNodeRecovered = "OutOfServiceUntainted"
+)
+
+type failoverConfig struct {
+	// FencingDelay is how long a node stays NotReady before its disks are fenced.
+	// Nodes with the out-of-service taint are fenced right away.
+	FencingDelay time.Duration
+	// TaintNode adds the out-of-service taint to lost nodes holding fenced disks,
+	// so that Kubernetes deletes their pods and detaches their volumes without waiting.
+	TaintNode bool
+}
+
+func parseFailoverConfig(csiCfg utils.Config) failoverConfig {
+	cfg := failoverConfig{
+		FencingDelay: time.Minute,
+		TaintNode:    csiCfg.GetBool("disk-failover-taint-node", "DISK_FAILOVER_TAINT_NODE", false),
+	}
+	if s := csiCfg.Get("disk-failover-fencing-delay", "DISK_FAILOVER_FENCING_DELAY", ""); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			klog.ErrorS(err, "Invalid disk-failover-fencing-delay, using default", "value", s, "default", cfg.FencingDelay)
+		} else {
+			cfg.FencingDelay = d
+		}
+	}
+	return cfg
+}
+
+// failoverController fences force-attachable disks (e.g. regional disks) attached to lost nodes,
+// once the instance of the node is stopped, or the node is tainted out-of-service by the administrator.
+// A fenced disk is not detached from the lost node by ControllerUnpublishVolume,
+// and is force attached to the next node by ControllerPublishVolume.
+//
+// Fencing is kept in memory of the replica attaching the disk, which is not known here,
+// so disks are fenced on every replica. Only the replica holding failoverLease records events and taints nodes.
+type failoverController struct {
+	client kubernetes.Interface
+	// ecs describes instances of nodes, which belong to the default identity
+	ecs cloud.ECSInterface
+	// adFor returns the attach/detach of the identity recorded in the PV of a disk
+	adFor    func(identity cloudIdentity) (*DiskAttachDetach, error)
+	recorder record.EventRecorder
+	clk      clock.PassiveClock
+	config   failoverConfig
+
+	leading atomic.Bool
+	// fencedIn is the attach/detach of disks fenced from each lost instance, to unfence once it is back
+	fencedIn map[string][]*DiskAttachDetach
+}
+
+// lead marks this replica as the leader until ctx is done.
+func (f *failoverController) lead(ctx context.Context) {
+	f.leading.Store(true)
+	<-ctx.Done()
+	f.leading.Store(false)
+}
+
+func (f *failoverController) run(ctx context.Context) {
+	logger := klog.FromContext(ctx).WithName("failover")
+	ctx = klog.NewContext(ctx, logger)
+	logger.Info("Disk failover started", "fencingDelay", f.config.FencingDelay, "taintNode", f.config.TaintNode)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := f.reconcile(ctx); err != nil {
+			logger.Error(err, "failed to reconcile")
+		}
+	}, failoverInterval)
+}
+
+func (f *failoverController) reconcile(ctx context.Context) error {
+	nodes, err := f.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	vas, err := f.client.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list volume attachments: %w", err)
+	}
+	byNode := map[string][]*storagev1.VolumeAttachment{}
+	for i := range vas.Items {
+		va := &vas.Items[i]
+		if va.Spec.Attacher == driverName && va.Spec.Source.PersistentVolumeName != nil {
+			byNode[va.Spec.NodeName] = append(byNode[va.Spec.NodeName], va)
+		}
+	}
+
+	var errs []error
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		instanceID := node.Labels[common.ECSInstanceIDTopologyKey]
+		if instanceID == "" {
+			continue
+		}
+		reason := f.lostReason(node)
+		if reason == "" {
+			for _, ad := range f.fencedIn[instanceID] {
+				ad.unfenceInstance(instanceID)
+			}
+			delete(f.fencedIn, instanceID)
+			if !f.leading.Load() {
+				continue
+			}
+			if err := f.untaint(ctx, node); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if len(byNode[node.Name]) == 0 {
+			continue
+		}
+		if !outOfService(node) {
+			// a node only unreachable may still be writing to its disks
+			status, err := f.instanceStatus(ctx, instanceID)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if status != instanceStatusStopped {
+				klog.FromContext(ctx).V(2).Info("instance of lost node is not stopped, not fencing", "node", node.Name, "instanceID", instanceID, "status", status)
+				continue
+			}
+		}
+		fenced := 0
+		for _, va := range byNode[node.Name] {
+			ok, err := f.fence(ctx, va, instanceID, node.Name, reason)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if ok {
+				fenced++
+			}
+		}
+		if fenced > 0 && f.config.TaintNode && f.leading.Load() {
+			if err := f.taint(ctx, node); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// outOfService reports whether the node is tainted out-of-service, not by ourselves.
+// The taint added by ourselves is ignored, so that it is removed once the node is ready.
+func outOfService(node *v1.Node) bool {
+	_, tainted := node.Annotations[FailoverTaintAnnotation]
+	return !tainted && slices.ContainsFunc(node.Spec.Taints, func(t v1.Taint) bool { return t.Key == v1.TaintNodeOutOfService })
+}
+
+// lostReason returns why the node is considered lost, or empty if it is not.
+func (f *failoverController) lostReason(node *v1.Node) string {
+	if outOfService(node) {
+		return "tainted " + v1.TaintNodeOutOfService
+	}
+	for _, c := range node.Status.Conditions {
+		if c.Type != v1.NodeReady {
+			continue
+		}
+		if c.Status == v1.ConditionTrue {
+			return ""
+		}
+		notReady := f.clk.Since(c.LastTransitionTime.Time)
+		if notReady < f.config.FencingDelay {
+			return ""
+		}
+		return fmt.Sprintf("NotReady for %s", notReady.Truncate(time.Second))
+	}
+	return ""
+}
+
+// fence fences the disk of va from the lost instance if it is force-attachable.
+func (f *failoverController) fence(ctx context.Context, va *storagev1.VolumeAttachment, instanceID, nodeName, reason string) (bool, error) {
+	pv, err := f.client.CoreV1().PersistentVolumes().Get(ctx, *va.Spec.Source.PersistentVolumeName, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get PV of %s: %w", va.Name, err)
+	}
+	if pv.Spec.CSI == nil || isThinVolumeID(pv.Spec.CSI.VolumeHandle) {
+		return false, nil
+	}
+	diskID := pv.Spec.CSI.VolumeHandle
+	identity := identityFromParameters(pv.Spec.CSI.VolumeAttributes)
+	ad, err := f.adFor(identity)
+	if err != nil {
+		return false, fmt.Errorf("failed to use cloud identity %s of disk %s: %w", identity, diskID, err)
+	}
+	disk, err := ad.batcher.Describe(ctx, diskID)
+	if err != nil {
+		return false, fmt.Errorf("failed to describe disk %s: %w", diskID, err)
+	}
+	if disk == nil || disk.InstanceId != instanceID || !AllCategories[Category(disk.Category)].ForceAttach {
+		return false, nil
+	}
+	if !slices.Contains(f.fencedIn[instanceID], ad) {
+		if f.fencedIn == nil {
+			f.fencedIn = map[string][]*DiskAttachDetach{}
+		}
+		f.fencedIn[instanceID] = append(f.fencedIn[instanceID], ad)
+	}
+	if ad.fence(diskID, instanceID) {
+		klog.FromContext(ctx).Info("fenced disk from lost node", "diskID", diskID, "node", nodeName, "reason", reason)
+		if f.leading.Load() {
+			f.recorder.Eventf(pv, v1.EventTypeWarning, eventVolumeFenced,
+				"Node %s is lost (%s), disk %s will be force attached to the next node", nodeName, reason, diskID)
+		}
+	}
+	return true, nil
+}
+
+// taint adds the out-of-service taint to the node, whose instance is already stopped to fence its disks.
+func (f *failoverController) taint(ctx context.Context, node *v1.Node) error {
+	if slices.ContainsFunc(node.Spec.Taints, func(t v1.Taint) bool { return t.Key == v1.TaintNodeOutOfService }) {
+		return nil
+	}
+	node = node.DeepCopy()
+	node.Spec.Taints = append(node.Spec.Taints, v1.Taint{
+		Key:    v1.TaintNodeOutOfService,
+		Value:  "nodeshutdown",
+		Effect: v1.TaintEffectNoExecute,
+	})
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[FailoverTaintAnnotation] = "true"
+	if _, err := f.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to taint node %s: %w", node.Name, err)
+	}
+	f.recorder.Eventf(node, v1.EventTypeWarning, eventNodeTainted, "Node is lost, tainted %s to fail over its disks", v1.TaintNodeOutOfService)
+	return nil
+}
+
+func (f *failoverController) instanceStatus(ctx context.Context, instanceID string) (string, error) {
+	req := ecs.CreateDescribeInstancesRequest()
+	req.RegionId = GlobalConfigVar.Region
+	req.InstanceIds = "[\"" + instanceID + "\"]"
+	resp, err := wrap.V1(ctx, f.ecs.DescribeInstances)(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to describe instance %s: %w", instanceID, err)
+	}
+	if len(resp.Instances.Instance) == 0 {
+		return "", fmt.Errorf("instance %s not found", instanceID)
+	}
+	return resp.Instances.Instance[0].Status, nil
+}
+
+// untaint removes the out-of-service taint added by taint.
+func (f *failoverController) untaint(ctx context.Context, node *v1.Node) error {
+	if _, ok := node.Annotations[FailoverTaintAnnotation]; !ok {
+		return nil
+	}
+	node = node.DeepCopy()
+	node.Spec.Taints = slices.DeleteFunc(node.Spec.Taints, func(t v1.Taint) bool { return t.Key == v1.TaintNodeOutOfService })
+	delete(node.Annotations, FailoverTaintAnnotation)
+	if _, err := f.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to untaint node %s: %w", node.Name, err)
+	}
+	f.recorder.Eventf(node, v1.EventTypeNormal, eventNodeRecovered, "Node is ready again, removed %s taint", v1.TaintNodeOutOfService)
+	return nil
+}
+
+// fence allows diskID to be force attached away from instanceID, and skips detaching it from instanceID.
+// Returns false if it is already fenced.
+func (ad *DiskAttachDetach) fence(diskID, instanceID string) bool {
+	old, loaded := ad.fenced.Swap(diskID, instanceID)
+	return !loaded || old.(string) != instanceID
+}
+
+func (ad *DiskAttachDetach) isFenced(diskID, instanceID string) bool {
+	i, ok := ad.fenced.Load(diskID)
+	return ok && i.(string) == instanceID
+}
+
+// unfenceInstance reverts fence of disks on instanceID, once it is back.
+func (ad *DiskAttachDetach) unfenceInstance(instanceID string) {
+	ad.fenced.Range(func(diskID, i any) bool {
+		if i.(string) == instanceID {
+			ad.fenced.CompareAndDelete(diskID, i)
+		}
+		return true
+	})
+}
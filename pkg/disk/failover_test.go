@@ -0,0 +1,272 @@
+//go:build !windows
+
+package disk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/fake"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/batcher"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/desc"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/waitstatus"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2/ktesting"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+)
+
+func failoverNode(name, instanceID string, ready v1.ConditionStatus, since time.Time) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{common.ECSInstanceIDTopologyKey: instanceID},
+		},
+		Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{
+			Type:               v1.NodeReady,
+			Status:             ready,
+			LastTransitionTime: metav1.NewTime(since),
+		}}},
+	}
+}
+
+type failoverTest struct {
+	c        *fake.Cloud
+	cs       *controllerServer
+	f        *failoverController
+	clk      *clocktesting.FakeClock
+	recorder *record.FakeRecorder
+	diskID   string
+}
+
+func testFailover(t *testing.T, category Category, objs ...runtime.Object) *failoverTest {
+	detachBeforeAttach := GlobalConfigVar.DetachBeforeAttach
+	t.Cleanup(func() { GlobalConfigVar.DetachBeforeAttach = detachBeforeAttach })
+	GlobalConfigVar.DetachBeforeAttach = true
+	_, ctx := ktesting.NewTestContext(t)
+	c := fake.New(fake.Options{})
+	c.AddInstance("i-1", "cn-hangzhou-a")
+	c.AddInstance("i-2", "cn-hangzhou-b")
+
+	client := desc.Disk(c)
+	cs := &controllerServer{
+		ecs: c,
+		ad: DiskAttachDetach{
+			slots:           NewSlots(0, 0),
+			ecs:             c,
+			waiter:          waitstatus.NewSimple(client, clock.RealClock{}),
+			batcher:         batcher.NewPassthrough(client),
+			attachThrottler: defaultThrottler(),
+			detachThrottler: defaultThrottler(),
+			dev:             DefaultDeviceManager,
+		},
+	}
+
+	req := ecs.CreateCreateDiskRequest()
+	req.DiskCategory = string(category)
+	req.Size = "20"
+	if !AllCategories[category].Regional {
+		req.ZoneId = "cn-hangzhou-a"
+	}
+	resp, err := c.CreateDisk(req)
+	require.NoError(t, err)
+	_, err = cs.ad.attachDisk(ctx, resp.DiskId, "i-1", false)
+	require.NoError(t, err)
+
+	clk := clocktesting.NewFakeClock(time.Now())
+	objs = append(objs,
+		failoverNode("node-1", "i-1", v1.ConditionUnknown, clk.Now()),
+		failoverNode("node-2", "i-2", v1.ConditionTrue, clk.Now()),
+		&v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: resp.DiskId},
+			}},
+		},
+		&storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: "va-1"},
+			Spec: storagev1.VolumeAttachmentSpec{
+				Attacher: driverName,
+				NodeName: "node-1",
+				Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: ptr.To("pv-1")},
+			},
+		},
+	)
+	recorder := record.NewFakeRecorder(10)
+	return &failoverTest{
+		c:  c,
+		cs: cs,
+		f: &failoverController{
+			client:   k8sfake.NewSimpleClientset(objs...),
+			ecs:      c,
+			adFor:    func(cloudIdentity) (*DiskAttachDetach, error) { return &cs.ad, nil },
+			recorder: recorder,
+			clk:      clk,
+			config:   failoverConfig{FencingDelay: time.Minute},
+		},
+		clk:      clk,
+		recorder: recorder,
+		diskID:   resp.DiskId,
+	}
+}
+
+func TestFailoverRegionalDisk(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	ft := testFailover(t, DiskRegional)
+	ft.f.leading.Store(true)
+
+	// not lost yet
+	require.NoError(t, ft.f.reconcile(ctx))
+	assert.False(t, ft.cs.ad.isFenced(ft.diskID, "i-1"))
+
+	// the instance may still be writing to the disk
+	ft.clk.Step(2 * time.Minute)
+	require.NoError(t, ft.f.reconcile(ctx))
+	assert.False(t, ft.cs.ad.isFenced(ft.diskID, "i-1"))
+
+	ft.c.SetInstanceStatus("i-1", "Stopped")
+	require.NoError(t, ft.f.reconcile(ctx))
+	assert.True(t, ft.cs.ad.isFenced(ft.diskID, "i-1"))
+	assert.Contains(t, <-ft.recorder.Events, eventVolumeFenced)
+
+	// fenced only once
+	require.NoError(t, ft.f.reconcile(ctx))
+	assert.Empty(t, ft.recorder.Events)
+
+	// detach from the lost node is skipped
+	ft.c.InjectError("DetachDisk", fake.ServerError("InternalError", "injected"))
+	_, err := ft.cs.ControllerUnpublishVolume(ctx, &csi.ControllerUnpublishVolumeRequest{VolumeId: ft.diskID, NodeId: "i-1"})
+	require.NoError(t, err)
+
+	// force attached to the next node
+	_, err = ft.cs.ad.attachDisk(ctx, ft.diskID, "i-2", false)
+	require.NoError(t, err)
+	d, _ := ft.c.Disk(ft.diskID)
+	assert.Equal(t, "i-2", d.InstanceId)
+	assert.False(t, ft.cs.ad.isFenced(ft.diskID, "i-1"))
+}
+
+func TestFailoverNotLeader(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	ft := testFailover(t, DiskRegional)
+	ft.f.config.TaintNode = true
+	ft.c.SetInstanceStatus("i-1", "Stopped")
+	ft.clk.Step(2 * time.Minute)
+
+	// fenced for attaching on this replica, but only the leader records events and taints
+	require.NoError(t, ft.f.reconcile(ctx))
+	assert.True(t, ft.cs.ad.isFenced(ft.diskID, "i-1"))
+	assert.Empty(t, ft.recorder.Events)
+	node, err := ft.f.client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, node.Spec.Taints)
+}
+
+func TestFailoverIdentity(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	ft := testFailover(t, DiskRegional)
+	pv, err := ft.f.client.CoreV1().PersistentVolumes().Get(ctx, "pv-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	pv.Spec.CSI.VolumeAttributes = map[string]string{AssumeRoleArnKey: "acs:ram::123:role/test"}
+	_, err = ft.f.client.CoreV1().PersistentVolumes().Update(ctx, pv, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	var identities []cloudIdentity
+	ft.f.adFor = func(identity cloudIdentity) (*DiskAttachDetach, error) {
+		identities = append(identities, identity)
+		return &ft.cs.ad, nil
+	}
+	ft.c.SetInstanceStatus("i-1", "Stopped")
+	ft.clk.Step(2 * time.Minute)
+	require.NoError(t, ft.f.reconcile(ctx))
+	assert.Equal(t, []cloudIdentity{{AssumeRoleArn: "acs:ram::123:role/test"}}, identities)
+	assert.True(t, ft.cs.ad.isFenced(ft.diskID, "i-1"))
+}
+
+func TestFailoverNotForceAttachable(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	ft := testFailover(t, DiskESSD)
+	ft.c.SetInstanceStatus("i-1", "Stopped")
+	ft.clk.Step(2 * time.Minute)
+	require.NoError(t, ft.f.reconcile(ctx))
+	assert.False(t, ft.cs.ad.isFenced(ft.diskID, "i-1"))
+}
+
+func TestFailoverOutOfServiceTaint(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	ft := testFailover(t, DiskRegional)
+	node, err := ft.f.client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	node.Spec.Taints = []v1.Taint{{Key: v1.TaintNodeOutOfService, Effect: v1.TaintEffectNoExecute}}
+	_, err = ft.f.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	// fenced right away, even if the instance is running
+	require.NoError(t, ft.f.reconcile(ctx))
+	assert.True(t, ft.cs.ad.isFenced(ft.diskID, "i-1"))
+}
+
+func TestFailoverTaintNode(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	ft := testFailover(t, DiskRegional)
+	ft.f.config.TaintNode = true
+	ft.f.leading.Store(true)
+	ft.clk.Step(2 * time.Minute)
+
+	// neither fenced nor tainted until the instance is stopped
+	require.NoError(t, ft.f.reconcile(ctx))
+	assert.False(t, ft.cs.ad.isFenced(ft.diskID, "i-1"))
+	node, err := ft.f.client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, node.Spec.Taints)
+
+	ft.c.SetInstanceStatus("i-1", "Stopped")
+	require.NoError(t, ft.f.reconcile(ctx))
+	assert.True(t, ft.cs.ad.isFenced(ft.diskID, "i-1"))
+	node, err = ft.f.client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Len(t, node.Spec.Taints, 1)
+	assert.Equal(t, v1.TaintNodeOutOfService, node.Spec.Taints[0].Key)
+	assert.Equal(t, v1.TaintEffectNoExecute, node.Spec.Taints[0].Effect)
+	assert.Contains(t, node.Annotations, FailoverTaintAnnotation)
+
+	// node is back
+	node.Status.Conditions[0].Status = v1.ConditionTrue
+	_, err = ft.f.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, ft.f.reconcile(ctx))
+	assert.False(t, ft.cs.ad.isFenced(ft.diskID, "i-1"))
+	node, err = ft.f.client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, node.Spec.Taints)
+	assert.NotContains(t, node.Annotations, FailoverTaintAnnotation)
+}
+
+func TestParseFailoverConfig(t *testing.T) {
+	cfg := parseFailoverConfig(utils.Config{})
+	assert.Equal(t, failoverConfig{FencingDelay: time.Minute}, cfg)
+
+	cfg = parseFailoverConfig(utils.Config{ConfigMap: map[string]string{
+		"disk-failover-fencing-delay": "30s",
+		"disk-failover-taint-node":    "true",
+	}})
+	assert.Equal(t, failoverConfig{FencingDelay: 30 * time.Second, TaintNode: true}, cfg)
+
+	// invalid values fall back to the default
+	for _, v := range []string{"1x", "-1m"} {
+		cfg = parseFailoverConfig(utils.Config{ConfigMap: map[string]string{"disk-failover-fencing-delay": v}})
+		assert.Equal(t, failoverConfig{FencingDelay: time.Minute}, cfg, v)
+	}
+}
@@ -0,0 +1,195 @@
+//go:build !windows
+
+package disk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+	utilsio "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils/io"
+	"k8s.io/klog/v2"
+)
+
+// FsProfileTag selects a named mkfs and mount profile in StorageClass parameters
+const FsProfileTag = "fsProfile"
+
+// fsProfileRecordFile records the applied profile for auditing, next to the staging path like vol_data.json of kubelet.
+const fsProfileRecordFile = "fs_profile.json"
+
+// fsProfile is a validated set of mkfs options, mount options and device tuning for a workload.
+// The fsType of the volume, if set, must be the filesystem type of the profile.
+type fsProfile struct {
+	FsType       string
+	MkfsOptions  []string
+	MountOptions []string
+	// SysConfigs are applied to the device before the sysConfig parameter, so that they can be overridden.
+	// Failures are only logged, as not all devices and kernels accept them.
+	SysConfigs []utilsio.SysConfig
+	// StripeAligned aligns the XFS stripe unit and width to the IO size hints of the device, if any.
+	StripeAligned bool
+}
+
+var fsProfiles = map[string]fsProfile{
+	"database-xfs": {
+		FsType:       "xfs",
+		MkfsOptions:  []string{"-m", "reflink=1,crc=1"},
+		MountOptions: []string{"noatime", "logbufs=8"},
+		SysConfigs: []utilsio.SysConfig{
+			{Key: "queue/scheduler", Value: "none"},
+			{Key: "queue/read_ahead_kb", Value: "64"},
+			{Key: "queue/nr_requests", Value: "256"},
+		},
+	},
+	"small-files-ext4": {
+		FsType: "ext4",
+		// one inode per 4KiB, instead of 16KiB by default
+		MkfsOptions:  []string{"-i", "4096"},
+		MountOptions: []string{"noatime"},
+		SysConfigs: []utilsio.SysConfig{
+			{Key: "queue/read_ahead_kb", Value: "128"},
+		},
+	},
+	"bigdata-xfs": {
+		FsType:       "xfs",
+		MountOptions: []string{"noatime", "largeio", "inode64"},
+		SysConfigs: []utilsio.SysConfig{
+			{Key: "queue/scheduler", Value: "mq-deadline"},
+			{Key: "queue/read_ahead_kb", Value: "4096"},
+			{Key: "queue/nr_requests", Value: "1024"},
+		},
+		StripeAligned: true,
+	},
+}
+
+// getFsProfile returns nil if no profile is selected.
+func getFsProfile(volumeContext map[string]string) (*fsProfile, error) {
+	name := volumeContext[FsProfileTag]
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := fsProfiles[name]
+	if !ok {
+		names := make([]string, 0, len(fsProfiles))
+		for n := range fsProfiles {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return nil, fmt.Errorf("unknown %s %q, supported: %v", FsProfileTag, name, names)
+	}
+	return &p, nil
+}
+
+// mkfsOptions returns the mkfs options of the profile for device.
+func (p *fsProfile) mkfsOptions(logger klog.Logger, m *DeviceManager, device string) []string {
+	opts := slices.Clone(p.MkfsOptions)
+	if p.StripeAligned && p.FsType == "xfs" {
+		su, sw, err := m.stripeGeometry(device)
+		if err != nil {
+			logger.Error(err, "failed to get stripe geometry, not aligned", "device", device)
+		} else if sw > 1 {
+			opts = append(opts, "-d", fmt.Sprintf("su=%d,sw=%d", su, sw))
+		}
+	}
+	return opts
+}
+
+// applySysConfigs applies the device tuning of the profile, best effort.
+func (p *fsProfile) applySysConfigs(logger klog.Logger, major, minor uint32) {
+	manager := utilsio.NewSysConfigManager(major, minor)
+	for _, entry := range p.SysConfigs {
+		if err := manager.Set(entry.Key, entry.Value); err != nil {
+			logger.Error(err, "failed to apply sysconfig of fsProfile", "key", entry.Key, "value", entry.Value)
+		}
+	}
+}
+
+// stripeGeometry returns the stripe unit in bytes and the stripe width in units,
+// from minimum_io_size and optimal_io_size of the device. sw is 0 if the device reports no hint.
+func (m *DeviceManager) stripeGeometry(device string) (su, sw uint64, err error) {
+	major, minor, err := m.DevTmpFS.DevFor(device)
+	if err != nil {
+		return 0, 0, err
+	}
+	dir, err := filepath.EvalSymlinks(m.sysfsDir(major, minor))
+	if err != nil {
+		return 0, 0, err
+	}
+	queue := filepath.Join(dir, "queue")
+	if _, err := os.Stat(queue); os.IsNotExist(err) {
+		// partition, use the queue of the whole disk
+		queue = filepath.Join(filepath.Dir(dir), "queue")
+	}
+	read := func(name string) (uint64, error) {
+		b, err := os.ReadFile(filepath.Join(queue, name))
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	}
+	ioMin, err := read("minimum_io_size")
+	if err != nil {
+		return 0, 0, err
+	}
+	ioOpt, err := read("optimal_io_size")
+	if err != nil {
+		return 0, 0, err
+	}
+	if ioMin == 0 || ioOpt <= ioMin || ioOpt%ioMin != 0 {
+		return ioMin, 0, nil
+	}
+	return ioMin, ioOpt / ioMin, nil
+}
+
+type fsProfileRecord struct {
+	Profile      string   `json:"profile"`
+	FsType       string   `json:"fsType"`
+	MkfsOptions  []string `json:"mkfsOptions,omitempty"`
+	MountOptions []string `json:"mountOptions,omitempty"`
+	SysConfigs   []string `json:"sysConfigs,omitempty"`
+}
+
+// checkFsType returns error if fsType of the volume is set, but not the filesystem type of the profile.
+func (p *fsProfile) checkFsType(name, fsType string) error {
+	if fsType != "" && fsType != p.FsType {
+		return fmt.Errorf("fsType %s conflicts with %s %s, which formats %s", fsType, FsProfileTag, name, p.FsType)
+	}
+	return nil
+}
+
+func fsProfileRecordPath(stagingPath string) string {
+	return filepath.Join(filepath.Dir(stagingPath), fsProfileRecordFile)
+}
+
+// recordFsProfile records the applied profile next to the staging path, best effort.
+// It is removed by removeFsProfileRecord once the volume is unstaged.
+func recordFsProfile(logger klog.Logger, stagingPath, name string, p *fsProfile, mkfsOptions, mountOptions []string) {
+	r := fsProfileRecord{
+		Profile:      name,
+		FsType:       p.FsType,
+		MkfsOptions:  mkfsOptions,
+		MountOptions: mountOptions,
+	}
+	for _, entry := range p.SysConfigs {
+		r.SysConfigs = append(r.SysConfigs, entry.Key+"="+entry.Value)
+	}
+	file := fsProfileRecordPath(stagingPath)
+	if err := utils.WriteJSONFile(r, file); err != nil {
+		logger.Error(err, "failed to record fsProfile", "file", file)
+		return
+	}
+	logger.V(2).Info("applied fsProfile", "file", file, "record", r)
+}
+
+// removeFsProfileRecord removes the record, so that kubelet can remove the staging directory.
+func removeFsProfileRecord(logger klog.Logger, stagingPath string) {
+	file := fsProfileRecordPath(stagingPath)
+	if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Error(err, "failed to remove fsProfile record", "file", file)
+	}
+}
@@ -0,0 +1,107 @@
+//go:build !windows
+
+package disk
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	utilsio "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils/io"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func TestGetFsProfile(t *testing.T) {
+	p, err := getFsProfile(map[string]string{})
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	p, err = getFsProfile(map[string]string{FsProfileTag: "database-xfs"})
+	require.NoError(t, err)
+	assert.Equal(t, "xfs", p.FsType)
+
+	_, err = getFsProfile(map[string]string{FsProfileTag: "unknown"})
+	assert.ErrorContains(t, err, "bigdata-xfs")
+
+	_, err = getDiskVolumeOptions(&csi.CreateVolumeRequest{
+		Parameters: map[string]string{FsProfileTag: "unknown"},
+	}, testMetadata, &record.FakeRecorder{}, "")
+	assert.Error(t, err)
+}
+
+func TestFsProfileMkfsOptions(t *testing.T) {
+	logger, _ := ktesting.NewTestContext(t)
+	m := testingManager(t)
+	sysfsDev := setupVirtIOBlockDevice(t, m.SysfsPath)
+	m.DevTmpFS.(*fakeDevTmpFS).Devs = []fakeDev{virtIODev}
+	device := filepath.Join(m.DevicePath, virtIODev.Path)
+	queue := filepath.Join(m.SysfsPath, sysfsDev, "queue")
+	require.NoError(t, os.MkdirAll(queue, 0o755))
+	setHints := func(ioMin, ioOpt string) {
+		require.NoError(t, os.WriteFile(filepath.Join(queue, "minimum_io_size"), []byte(ioMin+"\n"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(queue, "optimal_io_size"), []byte(ioOpt+"\n"), 0o644))
+	}
+
+	bigdata := fsProfiles["bigdata-xfs"]
+	setHints("4096", "0")
+	assert.Empty(t, bigdata.mkfsOptions(logger, m, device))
+
+	setHints("65536", "262144")
+	assert.Equal(t, []string{"-d", "su=65536,sw=4"}, bigdata.mkfsOptions(logger, m, device))
+
+	database := fsProfiles["database-xfs"]
+	assert.Equal(t, []string{"-m", "reflink=1,crc=1"}, database.mkfsOptions(logger, m, device))
+
+	// partition uses the queue of the whole disk
+	sysfsSetupPartition(t, m.SysfsPath, sysfsDev, "vdb23", &virtIOPart, 23)
+	m.DevTmpFS.(*fakeDevTmpFS).Devs = append(m.DevTmpFS.(*fakeDevTmpFS).Devs, virtIOPart)
+	su, sw, err := m.stripeGeometry(filepath.Join(m.DevicePath, virtIOPart.Path))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(65536), su)
+	assert.Equal(t, uint64(4), sw)
+}
+
+func TestFsProfileFsTypeConflict(t *testing.T) {
+	req := &csi.CreateVolumeRequest{
+		Parameters:    map[string]string{FsProfileTag: "database-xfs"},
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 20 << 30},
+		VolumeCapabilities: []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{FsType: "ext4"}},
+		}},
+	}
+	_, err := getDiskVolumeOptions(req, testMetadata, &record.FakeRecorder{}, "")
+	assert.ErrorContains(t, err, "conflicts with fsProfile database-xfs")
+
+	req.VolumeCapabilities[0].GetMount().FsType = "xfs"
+	_, err = getDiskVolumeOptions(req, testMetadata, &record.FakeRecorder{}, "")
+	assert.NoError(t, err)
+}
+
+func TestRecordFsProfile(t *testing.T) {
+	logger, _ := ktesting.NewTestContext(t)
+	stagingPath := filepath.Join(t.TempDir(), "globalmount")
+	p := &fsProfile{FsType: "ext4", SysConfigs: []utilsio.SysConfig{{Key: "queue/read_ahead_kb", Value: "128"}}}
+	recordFsProfile(logger, stagingPath, "small-files-ext4", p, []string{"-i", "4096"}, []string{"noatime"})
+
+	content, err := os.ReadFile(filepath.Join(filepath.Dir(stagingPath), fsProfileRecordFile))
+	require.NoError(t, err)
+	var r fsProfileRecord
+	require.NoError(t, json.Unmarshal(content, &r))
+	assert.Equal(t, fsProfileRecord{
+		Profile:      "small-files-ext4",
+		FsType:       "ext4",
+		MkfsOptions:  []string{"-i", "4096"},
+		MountOptions: []string{"noatime"},
+		SysConfigs:   []string{"queue/read_ahead_kb=128"},
+	}, r)
+
+	removeFsProfileRecord(logger, stagingPath)
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(stagingPath), fsProfileRecordFile))
+	// removed already
+	removeFsProfileRecord(logger, stagingPath)
+}
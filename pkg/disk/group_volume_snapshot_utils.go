@@ -3,6 +3,7 @@
 package disk
 
 import (
+	"context"
 	"fmt"
 	"slices"
 	"strings"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -166,11 +168,11 @@ func checkSourceVolume(disk *ecs.Disk, zoneId string, capacityInGiB int) (
 	return
 }
 
-func requestAndDeleteGroupSnapshot(groupSnapshotID string) (*ecs.DeleteSnapshotGroupResponse, error) {
+func requestAndDeleteGroupSnapshot(ctx context.Context, groupSnapshotID string) (*ecs.DeleteSnapshotGroupResponse, error) {
 	// Delete Snapshotgroup
 	deleteSnapshotGroupRequest := ecs.CreateDeleteSnapshotGroupRequest()
 	deleteSnapshotGroupRequest.SnapshotGroupId = groupSnapshotID
-	response, err := GlobalConfigVar.EcsClient.DeleteSnapshotGroup(deleteSnapshotGroupRequest)
+	response, err := wrap.V1(ctx, GlobalConfigVar.EcsClient.DeleteSnapshotGroup)(deleteSnapshotGroupRequest)
 	if err != nil {
 		return response, err
 	}
@@ -112,7 +112,7 @@ func (cs *groupControllerServer) CreateVolumeGroupSnapshot(ctx context.Context,
 	createAt := timestamppb.Now()
 	params.SourceVolumeIDs = sourceVolumeIds
 	params.SnapshotName = req.GetName()
-	snapshotResponse, err := requestAndCreateSnapshotGroup(GlobalConfigVar.EcsClient, params)
+	snapshotResponse, err := requestAndCreateSnapshotGroup(ctx, GlobalConfigVar.EcsClient, params)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "create groupSnapshot %s failed: %v", req.GetName(), err)
 	}
@@ -159,7 +159,7 @@ func (cs *groupControllerServer) DeleteVolumeGroupSnapshot(ctx context.Context,
 
 	klog.Infof("DeleteVolumeGroupSnapshot: groupSnapshot %s exist with Info: %+v, %+v", groupSnapshotId, existsGroupSnapshots, err)
 	// no need to delete each snapshot through ECS client
-	response, err := requestAndDeleteGroupSnapshot(groupSnapshotId)
+	response, err := requestAndDeleteGroupSnapshot(ctx, groupSnapshotId)
 	var requestId string
 	if response != nil {
 		requestId = response.RequestId
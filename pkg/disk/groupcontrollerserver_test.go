@@ -3,11 +3,18 @@
 package disk
 
 import (
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/fake"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestGroupControllerGetCapabilities(t *testing.T) {
@@ -30,3 +37,56 @@ func TestGroupControllerGetCapabilities(t *testing.T) {
 	}
 	assert.True(t, hasVolumeGroupSnapshotCap, "should have CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT capability")
 }
+
+func TestVolumeGroupSnapshotWithFakeCloud(t *testing.T) {
+	c := fake.New(fake.Options{})
+	c.AddZone("cn-hangzhou-a", "cloud_essd")
+	var diskIDs []string
+	for range 2 {
+		req := ecs.CreateCreateDiskRequest()
+		req.ZoneId = "cn-hangzhou-a"
+		req.DiskCategory = "cloud_essd"
+		req.Size = requests.NewInteger(20)
+		resp, err := c.CreateDisk(req)
+		require.NoError(t, err)
+		diskIDs = append(diskIDs, resp.DiskId)
+	}
+	server := httptest.NewServer(c)
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	client, err := ecs.NewClientWithAccessKey("cn-hangzhou", "fake-ak", "fake-sk")
+	require.NoError(t, err)
+	client.Domain = u.Host
+
+	origClient, origRegion := GlobalConfigVar.EcsClient, GlobalConfigVar.Region
+	GlobalConfigVar.EcsClient, GlobalConfigVar.Region = client, "cn-hangzhou"
+	t.Cleanup(func() { GlobalConfigVar.EcsClient, GlobalConfigVar.Region = origClient, origRegion })
+
+	cs := NewGroupControllerServer()
+	created, err := cs.CreateVolumeGroupSnapshot(t.Context(), &csi.CreateVolumeGroupSnapshotRequest{
+		Name:            "group-snapshot-fake",
+		SourceVolumeIds: diskIDs,
+	})
+	require.NoError(t, err)
+	id := created.GroupSnapshot.GroupSnapshotId
+
+	got, err := cs.GetVolumeGroupSnapshot(t.Context(), &csi.GetVolumeGroupSnapshotRequest{GroupSnapshotId: id})
+	require.NoError(t, err)
+	assert.True(t, got.GroupSnapshot.ReadyToUse)
+	require.Len(t, got.GroupSnapshot.Snapshots, 2)
+	var snapshotIDs, sourceIDs []string
+	for _, s := range got.GroupSnapshot.Snapshots {
+		snapshotIDs = append(snapshotIDs, s.SnapshotId)
+		sourceIDs = append(sourceIDs, s.SourceVolumeId)
+	}
+	assert.ElementsMatch(t, diskIDs, sourceIDs)
+
+	_, err = cs.DeleteVolumeGroupSnapshot(t.Context(), &csi.DeleteVolumeGroupSnapshotRequest{
+		GroupSnapshotId: id,
+		SnapshotIds:     snapshotIDs,
+	})
+	require.NoError(t, err)
+	_, err = cs.GetVolumeGroupSnapshot(t.Context(), &csi.GetVolumeGroupSnapshotRequest{GroupSnapshotId: id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
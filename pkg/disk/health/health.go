@@ -0,0 +1,227 @@
+// Package health reads the health telemetry of block devices:
+// the SMART / health information log of NVMe devices, the error counters of SCSI devices,
+// and the status of virtio-blk devices. virtio-blk has no error counters:
+// failed requests are only logged by the kernel, and its /sys/block/vdX/stat has no error field.
+package health
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+var ErrNotSupported = errors.New("health telemetry not supported by the device")
+
+type Kind string
+
+const (
+	KindNVMe   Kind = "nvme"
+	KindSCSI   Kind = "scsi"
+	KindVirtio Kind = "virtio"
+)
+
+// Critical warning bits of the NVMe SMART / health information log
+const (
+	WarningSpare uint8 = 1 << iota
+	WarningTemperature
+	WarningReliability
+	WarningReadOnly
+	WarningVolatileBackup
+	WarningPMRReadOnly
+)
+
+var warningMessages = []string{
+	"available spare below threshold",
+	"temperature out of range",
+	"reliability degraded",
+	"media in read-only mode",
+	"volatile memory backup failed",
+	"persistent memory region in read-only mode",
+}
+
+// Bits of the virtio device status, set by the driver when the device is broken
+const (
+	VirtioStatusNeedsReset uint8 = 0x40
+	VirtioStatusFailed     uint8 = 0x80
+)
+
+type Health struct {
+	Kind Kind
+	// Device is the whole disk, e.g. nvme0n1, even if a partition is asked.
+	Device string
+
+	// From NVMe SMART / health information log
+	CriticalWarning    uint8
+	TemperatureCelsius int
+	AvailableSpare     uint8 // percentage
+	PercentageUsed     uint8
+	UnsafeShutdowns    uint64
+	MediaErrors        uint64
+	ErrorLogEntries    uint64
+
+	// From SCSI device in sysfs
+	IOErrors   uint64
+	IOTimeouts uint64
+
+	// From virtio device in sysfs
+	VirtioStatus uint8
+}
+
+// Problems returns human-readable problems of the device, empty if it is healthy.
+func (h *Health) Problems() []string {
+	var problems []string
+	for i, msg := range warningMessages {
+		if h.CriticalWarning&(1<<i) != 0 {
+			problems = append(problems, msg)
+		}
+	}
+	if h.MediaErrors > 0 {
+		problems = append(problems, fmt.Sprintf("%d media errors", h.MediaErrors))
+	}
+	if h.IOErrors > 0 {
+		problems = append(problems, fmt.Sprintf("%d IO errors", h.IOErrors))
+	}
+	if h.IOTimeouts > 0 {
+		problems = append(problems, fmt.Sprintf("%d IO timeouts", h.IOTimeouts))
+	}
+	if h.VirtioStatus&VirtioStatusNeedsReset != 0 {
+		problems = append(problems, "virtio device needs reset")
+	}
+	if h.VirtioStatus&VirtioStatusFailed != 0 {
+		problems = append(problems, "virtio device failed")
+	}
+	return problems
+}
+
+type Reader struct {
+	// The path to the directory containing the device files.
+	// This is usually /dev.
+	DevicePath string
+
+	// The path to the directory mounted as sysfs.
+	// This is usually /sys.
+	SysfsPath string
+
+	// readSmartLog reads the raw log from the NVMe controller device, replaced in tests
+	readSmartLog func(ctrlPath string) ([]byte, error)
+}
+
+var DefaultReader = &Reader{
+	DevicePath: "/dev",
+	SysfsPath:  "/sys",
+}
+
+var nvmeNamespaceRe = regexp.MustCompile(`^(nvme\d+)n\d+$`)
+
+// Read reads the health of the block device dev (as in unix.Stat_t.Dev or Rdev).
+// Returns ErrNotSupported if the device has no health telemetry, e.g. device mapper.
+func (r *Reader) Read(dev uint64) (*Health, error) {
+	name, err := r.diskName(dev)
+	if err != nil {
+		return nil, err
+	}
+	if m := nvmeNamespaceRe.FindStringSubmatch(name); m != nil {
+		readSmartLog := r.readSmartLog
+		if readSmartLog == nil {
+			readSmartLog = readNVMeSmartLog
+		}
+		log, err := readSmartLog(filepath.Join(r.DevicePath, m[1]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SMART log of %s: %w", m[1], err)
+		}
+		h, err := parseSmartLog(log)
+		if err != nil {
+			return nil, err
+		}
+		h.Device = name
+		return h, nil
+	}
+	if strings.HasPrefix(name, "sd") {
+		return r.readSCSI(name)
+	}
+	if strings.HasPrefix(name, "vd") {
+		return r.readVirtio(name)
+	}
+	return nil, ErrNotSupported
+}
+
+// diskName returns the name of the whole disk containing dev, e.g. nvme0n1 for nvme0n1p1.
+func (r *Reader) diskName(dev uint64) (string, error) {
+	sysDir, err := filepath.EvalSymlinks(fmt.Sprintf("%s/dev/block/%d:%d", r.SysfsPath, unix.Major(dev), unix.Minor(dev)))
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filepath.Join(sysDir, "partition")); err == nil {
+		sysDir = filepath.Dir(sysDir)
+	}
+	return filepath.Base(sysDir), nil
+}
+
+func (r *Reader) readSCSI(name string) (*Health, error) {
+	h := &Health{Kind: KindSCSI, Device: name}
+	for file, v := range map[string]*uint64{
+		"ioerr_cnt": &h.IOErrors,
+		"iotmo_cnt": &h.IOTimeouts,
+	} {
+		content, err := os.ReadFile(filepath.Join(r.SysfsPath, "block", name, "device", file))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, ErrNotSupported
+			}
+			return nil, err
+		}
+		// in hex, e.g. 0x1
+		*v, err = strconv.ParseUint(strings.TrimSpace(string(content)), 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s of %s: %w", file, name, err)
+		}
+	}
+	return h, nil
+}
+
+// readVirtio reads the status of the virtio device, which the driver marks when the device fails.
+func (r *Reader) readVirtio(name string) (*Health, error) {
+	content, err := os.ReadFile(filepath.Join(r.SysfsPath, "block", name, "device", "status"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotSupported
+		}
+		return nil, err
+	}
+	// in hex, e.g. 0x0000000f
+	status, err := strconv.ParseUint(strings.TrimSpace(string(content)), 0, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid virtio status of %s: %w", name, err)
+	}
+	return &Health{Kind: KindVirtio, Device: name, VirtioStatus: uint8(status)}, nil
+}
+
+const smartLogSize = 512
+
+// parseSmartLog parses the SMART / health information log (log identifier 02h) defined in NVMe base specification.
+func parseSmartLog(log []byte) (*Health, error) {
+	if len(log) < smartLogSize {
+		return nil, fmt.Errorf("SMART log too short: %d bytes", len(log))
+	}
+	// 128-bit counters, the upper half is ignored
+	counter := func(offset int) uint64 {
+		return binary.LittleEndian.Uint64(log[offset:])
+	}
+	return &Health{
+		Kind:               KindNVMe,
+		CriticalWarning:    log[0],
+		TemperatureCelsius: int(binary.LittleEndian.Uint16(log[1:])) - 273,
+		AvailableSpare:     log[3],
+		PercentageUsed:     log[5],
+		UnsafeShutdowns:    counter(144),
+		MediaErrors:        counter(160),
+		ErrorLogEntries:    counter(176),
+	}, nil
+}
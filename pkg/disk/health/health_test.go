@@ -0,0 +1,132 @@
+package health
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestNVMeAdminCmdSize(t *testing.T) {
+	assert.Equal(t, uintptr(72), unsafe.Sizeof(nvmeAdminCmd{}))
+}
+
+func smartLog(criticalWarning uint8, mediaErrors uint64) []byte {
+	log := make([]byte, smartLogSize)
+	log[0] = criticalWarning
+	binary.LittleEndian.PutUint16(log[1:], 273+40)
+	log[3] = 100
+	log[5] = 3
+	binary.LittleEndian.PutUint64(log[144:], 7)
+	binary.LittleEndian.PutUint64(log[160:], mediaErrors)
+	binary.LittleEndian.PutUint64(log[176:], 12)
+	return log
+}
+
+func TestParseSmartLog(t *testing.T) {
+	h, err := parseSmartLog(smartLog(0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, &Health{
+		Kind:               KindNVMe,
+		TemperatureCelsius: 40,
+		AvailableSpare:     100,
+		PercentageUsed:     3,
+		UnsafeShutdowns:    7,
+		ErrorLogEntries:    12,
+	}, h)
+	assert.Empty(t, h.Problems())
+
+	h, err = parseSmartLog(smartLog(WarningSpare|WarningReadOnly, 2))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"available spare below threshold", "media in read-only mode", "2 media errors"}, h.Problems())
+
+	_, err = parseSmartLog(make([]byte, 64))
+	assert.Error(t, err)
+}
+
+// fakeSysfs creates /sys/block/<name> and /sys/dev/block/<major>:<minor> for each device,
+// partitions are named as <disk>/<partition>.
+func fakeSysfs(t *testing.T, devs map[string]uint64) string {
+	sysfs := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sysfs, "dev/block"), 0o755))
+	for name, dev := range devs {
+		dir := filepath.Join(sysfs, "devices", name)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		if filepath.Dir(name) != "." {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "partition"), []byte("1\n"), 0o644))
+		} else {
+			require.NoError(t, os.MkdirAll(filepath.Join(sysfs, "block"), 0o755))
+			require.NoError(t, os.Symlink(dir, filepath.Join(sysfs, "block", name)))
+		}
+		link := filepath.Join(sysfs, "dev/block", fmt.Sprintf("%d:%d", unix.Major(dev), unix.Minor(dev)))
+		require.NoError(t, os.Symlink(dir, link))
+	}
+	return sysfs
+}
+
+func TestRead(t *testing.T) {
+	nvme := unix.Mkdev(259, 0)
+	nvmePart := unix.Mkdev(259, 1)
+	sd := unix.Mkdev(8, 0)
+	vd := unix.Mkdev(253, 16)
+	sysfs := fakeSysfs(t, map[string]uint64{
+		"nvme1n1":           nvme,
+		"nvme1n1/nvme1n1p1": nvmePart,
+		"sda":               sd,
+		"vdb":               vd,
+	})
+	scsiDir := filepath.Join(sysfs, "block/sda/device")
+	require.NoError(t, os.MkdirAll(scsiDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(scsiDir, "ioerr_cnt"), []byte("0x3\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(scsiDir, "iotmo_cnt"), []byte("0x0\n"), 0o644))
+
+	var ctrls []string
+	r := &Reader{
+		DevicePath: "/dev",
+		SysfsPath:  sysfs,
+		readSmartLog: func(ctrlPath string) ([]byte, error) {
+			ctrls = append(ctrls, ctrlPath)
+			return smartLog(0, 1), nil
+		},
+	}
+
+	for _, dev := range []uint64{nvme, nvmePart} {
+		h, err := r.Read(dev)
+		require.NoError(t, err)
+		assert.Equal(t, KindNVMe, h.Kind)
+		assert.Equal(t, "nvme1n1", h.Device)
+		assert.Equal(t, uint64(1), h.MediaErrors)
+	}
+	assert.Equal(t, []string{"/dev/nvme1", "/dev/nvme1"}, ctrls)
+
+	h, err := r.Read(sd)
+	require.NoError(t, err)
+	assert.Equal(t, &Health{Kind: KindSCSI, Device: "sda", IOErrors: 3}, h)
+	assert.Equal(t, []string{"3 IO errors"}, h.Problems())
+
+	// no status of an old kernel
+	_, err = r.Read(vd)
+	assert.ErrorIs(t, err, ErrNotSupported)
+
+	virtioDir := filepath.Join(sysfs, "block/vdb/device")
+	require.NoError(t, os.MkdirAll(virtioDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(virtioDir, "status"), []byte("0x0000000f\n"), 0o644))
+	h, err = r.Read(vd)
+	require.NoError(t, err)
+	assert.Equal(t, &Health{Kind: KindVirtio, Device: "vdb", VirtioStatus: 0xf}, h)
+	assert.Empty(t, h.Problems())
+
+	require.NoError(t, os.WriteFile(filepath.Join(virtioDir, "status"), []byte("0x0000004f\n"), 0o644))
+	h, err = r.Read(vd)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"virtio device needs reset"}, h.Problems())
+
+	_, err = r.Read(unix.Mkdev(1, 1))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
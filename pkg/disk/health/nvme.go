@@ -0,0 +1,69 @@
+package health
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// struct nvme_passthru_cmd in linux/nvme_ioctl.h
+type nvmeAdminCmd struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMs   uint32
+	result      uint32
+}
+
+const (
+	nvmeIoctlAdminCmd = 0xC0484E41 // _IOWR('N', 0x41, struct nvme_admin_cmd)
+	nvmeGetLogPage    = 0x02
+	nvmeLogSmart      = 0x02
+	nvmeNSIDAll       = 0xFFFFFFFF
+)
+
+// readNVMeSmartLog issues Get Log Page admin command to the controller device, e.g. /dev/nvme0
+func readNVMeSmartLog(ctrlPath string) ([]byte, error) {
+	f, err := os.Open(ctrlPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	log := make([]byte, smartLogSize)
+	cmd := nvmeAdminCmd{
+		opcode:  nvmeGetLogPage,
+		nsid:    nvmeNSIDAll,
+		addr:    uint64(uintptr(unsafe.Pointer(&log[0]))),
+		dataLen: smartLogSize,
+		// number of dwords minus one in the upper half
+		cdw10:     nvmeLogSmart | (smartLogSize/4-1)<<16,
+		timeoutMs: 5000,
+	}
+	status, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), nvmeIoctlAdminCmd, uintptr(unsafe.Pointer(&cmd)))
+	runtime.KeepAlive(log)
+	if errno != 0 {
+		return nil, errno
+	}
+	// positive return value is the NVMe status code
+	if status != 0 {
+		return nil, fmt.Errorf("NVMe status %#x", status)
+	}
+	return log, nil
+}
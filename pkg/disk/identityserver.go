@@ -78,5 +78,14 @@ func (iden *identityServer) GetPluginCapabilities(ctx context.Context, req *csi.
 			},
 		})
 	}
+	if features.FunctionalMutableFeatureGate.Enabled(features.DiskSnapshotMetadata) {
+		resp.Capabilities = append(resp.Capabilities, &csi.PluginCapability{
+			Type: &csi.PluginCapability_Service_{
+				Service: &csi.PluginCapability_Service{
+					Type: csi.PluginCapability_Service_SNAPSHOT_METADATA_SERVICE,
+				},
+			},
+		})
+	}
 	return resp, nil
 }
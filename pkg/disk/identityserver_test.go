@@ -76,3 +76,17 @@ func TestGetPluginCapabilities(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPluginCapabilitiesSnapshotMetadata(t *testing.T) {
+	featuregatetesting.SetFeatureGateDuringTest(t, features.FunctionalMutableFeatureGate, features.DiskSnapshotMetadata, true)
+
+	resp, err := NewIdentityServer().GetPluginCapabilities(t.Context(), &csi.GetPluginCapabilitiesRequest{})
+	require.NoError(t, err)
+	assert.Contains(t, resp.Capabilities, &csi.PluginCapability{
+		Type: &csi.PluginCapability_Service_{
+			Service: &csi.PluginCapability_Service{
+				Type: csi.PluginCapability_Service_SNAPSHOT_METADATA_SERVICE,
+			},
+		},
+	})
+}
@@ -0,0 +1,328 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/metric"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// ProvisionedIopsMinTag and ProvisionedIopsMaxTag in StorageClass parameters enable the autotuning
+	// of the provisioned IOPS of cloud_auto disks within the bounds.
+	ProvisionedIopsMinTag = "provisionedIopsMin"
+	ProvisionedIopsMaxTag = "provisionedIopsMax"
+	// ProvisionedIopsCooldownTag is the minimum interval between two changes of a disk, 1h by default.
+	ProvisionedIopsCooldownTag = "provisionedIopsCooldown"
+
+	// IopsAutotunedAtAnnotation on the PV is the last time its provisioned IOPS was changed by autotuning.
+	IopsAutotunedAtAnnotation = "csi.alibabacloud.com/iops-autotuned-at"
+
+	defaultIopsAutotuneCooldown = time.Hour
+	iopsAutotuneInterval        = 5 * time.Minute
+	// observations older than this are ignored, e.g. the volume is no longer mounted
+	iopsObservationMaxAge = 15 * time.Minute
+	// the provisioned IOPS is changed only if this many consecutive windows observed by the node all ask for it,
+	// so that a single burst or idle window does not change it
+	iopsAutotuneWindows = 3
+	// Lease of the replica running iopsAutotuner
+	iopsAutotuneLease = "csi-disk-iops-autotune"
+
+	// the provisioned IOPS is changed when the utilization of the peak is out of [low, high],
+	// to make it target.
+	iopsUtilizationLow    = 0.4
+	iopsUtilizationHigh   = 0.8
+	iopsUtilizationTarget = 0.6
+	iopsAutotuneStep      = 1000
+
+	eventIopsTuned      = "ProvisionedIopsTuned"
+	eventIopsTuneFailed = "ProvisionedIopsTuneFailed"
+)
+
+// iopsAutotune is the autotuning configuration of a StorageClass.
+type iopsAutotune struct {
+	Min, Max int64
+	Cooldown time.Duration
+}
+
+// getIOPSAutotune returns nil if autotuning is not enabled in the StorageClass parameters.
+func getIOPSAutotune(params map[string]string) (*iopsAutotune, error) {
+	minValue, hasMin := params[ProvisionedIopsMinTag]
+	maxValue, hasMax := params[ProvisionedIopsMaxTag]
+	if !hasMin && !hasMax {
+		return nil, nil
+	}
+	if !hasMin || !hasMax {
+		return nil, fmt.Errorf("%s and %s must be set together", ProvisionedIopsMinTag, ProvisionedIopsMaxTag)
+	}
+	a := &iopsAutotune{Cooldown: defaultIopsAutotuneCooldown}
+	var err error
+	a.Min, err = strconv.ParseInt(minValue, 10, 64)
+	if err != nil || a.Min < 0 {
+		return nil, fmt.Errorf("invalid %s %q", ProvisionedIopsMinTag, minValue)
+	}
+	a.Max, err = strconv.ParseInt(maxValue, 10, 64)
+	if err != nil || a.Max < a.Min {
+		return nil, fmt.Errorf("invalid %s %q, should be no less than %s", ProvisionedIopsMaxTag, maxValue, ProvisionedIopsMinTag)
+	}
+	if v := params[ProvisionedIopsCooldownTag]; v != "" {
+		a.Cooldown, err = time.ParseDuration(v)
+		if err != nil || a.Cooldown < 0 {
+			return nil, fmt.Errorf("invalid %s %q", ProvisionedIopsCooldownTag, v)
+		}
+	}
+	return a, nil
+}
+
+// observeDiskIO selects the PVs of cloud_auto disks with autotuning enabled, for the node plugin to observe their IO.
+// The bounds are in the volume attributes, since CreateVolume returns the StorageClass parameters in the volume context.
+func observeDiskIO(pv *v1.PersistentVolume) bool {
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != driverName {
+		return false
+	}
+	attrs := pv.Spec.CSI.VolumeAttributes
+	a, err := getIOPSAutotune(attrs)
+	return err == nil && a != nil && Category(attrs["type"]) == DiskESSDAuto
+}
+
+// autoPLBaseline returns the IOPS and throughput in bytes per second of a cloud_auto disk without provisioned IOPS.
+// See https://www.alibabacloud.com/help/en/ecs/user-guide/essd-autopl-disks
+func autoPLBaseline(sizeGiB int) (iops, throughput float64) {
+	size := float64(sizeGiB)
+	iops = math.Min(1800+50*size, 50000)
+	throughput = math.Min(120+0.5*size, 350) * 1024 * 1024
+	return iops, throughput
+}
+
+// throughputPerProvisionedIops is the throughput in bytes per second added by each provisioned IOPS.
+const throughputPerProvisionedIops = 16 * 1024
+
+// desiredProvisionedIops returns the provisioned IOPS for the observed peak of a window, and whether it should be changed.
+func (a *iopsAutotune) desiredProvisionedIops(sizeGiB int, current int64, peak *metric.DiskIOObservation) (int64, bool) {
+	baseIOPS, baseThroughput := autoPLBaseline(sizeGiB)
+	utilization := math.Max(
+		float64(peak.PeakIOPS)/(baseIOPS+float64(current)),
+		float64(peak.PeakThroughput)/(baseThroughput+float64(current)*throughputPerProvisionedIops))
+
+	desired := current
+	if utilization < iopsUtilizationLow || utilization > iopsUtilizationHigh {
+		need := math.Max(
+			float64(peak.PeakIOPS)/iopsUtilizationTarget-baseIOPS,
+			(float64(peak.PeakThroughput)/iopsUtilizationTarget-baseThroughput)/throughputPerProvisionedIops)
+		desired = int64(math.Ceil(math.Max(need, 0)/iopsAutotuneStep)) * iopsAutotuneStep
+	}
+	desired = min(max(desired, a.Min), a.Max)
+	return desired, desired != current
+}
+
+// desiredForWindows returns the provisioned IOPS for the windows, and whether it should be changed.
+// It is changed only if every window asks for a change in the same direction,
+// to the largest IOPS asked for, so that the busiest window still fits.
+func (a *iopsAutotune) desiredForWindows(sizeGiB int, current int64, windows []metric.DiskIOObservation) (int64, bool) {
+	var desired int64
+	raise, lower := 0, 0
+	for i := range windows {
+		d, changed := a.desiredProvisionedIops(sizeGiB, current, &windows[i])
+		switch {
+		case !changed:
+			return current, false
+		case d > current:
+			raise++
+		default:
+			lower++
+		}
+		desired = max(desired, d)
+	}
+	if len(windows) == 0 || (raise > 0 && lower > 0) {
+		return current, false
+	}
+	return desired, true
+}
+
+// recentWindows returns the latest iopsAutotuneWindows consecutive windows observed after since,
+// or nil if there are not enough of them yet.
+func recentWindows(windows []metric.DiskIOObservation, since, now time.Time) []metric.DiskIOObservation {
+	if len(windows) < iopsAutotuneWindows {
+		return nil
+	}
+	recent := windows[len(windows)-iopsAutotuneWindows:]
+	if now.Sub(recent[len(recent)-1].Until) > iopsObservationMaxAge || recent[0].Since.Before(since) {
+		return nil
+	}
+	for i := 1; i < len(recent); i++ {
+		// a gap, e.g. the node plugin restarted or the volume moved to another node
+		if recent[i].Since.Sub(recent[i-1].Until) > iopsAutotuneInterval || recent[i].Node != recent[i-1].Node {
+			return nil
+		}
+	}
+	return recent
+}
+
+// iopsAutotuner changes the provisioned IOPS of cloud_auto disks periodically,
+// according to the peak IO observed by the node plugin and the bounds in the StorageClass.
+// It runs on the replica holding iopsAutotuneLease only.
+type iopsAutotuner struct {
+	client kubernetes.Interface
+	// serverFor returns the controller server of the account of the disk, for its ECS client and throttler
+	serverFor func(ctx context.Context, diskID string) (*controllerServer, error)
+	source    diskIOSource
+	recorder  record.EventRecorder
+	clk       clock.PassiveClock
+}
+
+// diskIOSource returns the windows of peak IO of a volume, oldest first.
+type diskIOSource interface {
+	Observations(ctx context.Context, pv *v1.PersistentVolume) ([]metric.DiskIOObservation, error)
+}
+
+// pvAnnotationIOSource reads the windows recorded on the PV by the node plugin.
+type pvAnnotationIOSource struct{}
+
+func (pvAnnotationIOSource) Observations(ctx context.Context, pv *v1.PersistentVolume) ([]metric.DiskIOObservation, error) {
+	return metric.ParseDiskIOObservations(pv.Annotations)
+}
+
+func (t *iopsAutotuner) run(ctx context.Context) {
+	logger := klog.FromContext(ctx).WithName("iops-autotune")
+	ctx = klog.NewContext(ctx, logger)
+	logger.Info("Disk IOPS autotuning started")
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := t.reconcile(ctx); err != nil {
+			logger.Error(err, "failed to reconcile")
+		}
+	}, iopsAutotuneInterval)
+}
+
+func (t *iopsAutotuner) reconcile(ctx context.Context) error {
+	logger := klog.FromContext(ctx)
+	scs, err := t.client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list StorageClasses: %w", err)
+	}
+	configs := map[string]*iopsAutotune{}
+	for _, sc := range scs.Items {
+		if sc.Provisioner != driverName {
+			continue
+		}
+		a, err := getIOPSAutotune(sc.Parameters)
+		if err != nil {
+			logger.Error(err, "ignore invalid IOPS autotuning", "storageClass", sc.Name)
+			continue
+		}
+		if a != nil {
+			configs[sc.Name] = a
+		}
+	}
+	if len(configs) == 0 {
+		return nil
+	}
+
+	pvs, err := t.client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list PVs: %w", err)
+	}
+	var errs []error
+	for i := range pvs.Items {
+		pv := &pvs.Items[i]
+		a := configs[pv.Spec.StorageClassName]
+		if a == nil || pv.Spec.CSI == nil || pv.Spec.CSI.Driver != driverName || isThinVolumeID(pv.Spec.CSI.VolumeHandle) ||
+			pv.Status.Phase != v1.VolumeBound || pv.Spec.ClaimRef == nil {
+			continue
+		}
+		if err := t.tune(ctx, pv, a); err != nil {
+			errs = append(errs, fmt.Errorf("PV %s: %w", pv.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *iopsAutotuner) tune(ctx context.Context, pv *v1.PersistentVolume, a *iopsAutotune) error {
+	logger := klog.FromContext(ctx).WithValues("pv", pv.Name)
+	now := t.clk.Now()
+	// windows observed with the IOPS before the last change are not relevant
+	var tunedAt time.Time
+	if v := pv.Annotations[IopsAutotunedAtAnnotation]; v != "" {
+		at, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			logger.Error(err, "ignore invalid annotation", "annotation", IopsAutotunedAtAnnotation)
+		} else if now.Sub(at) < a.Cooldown {
+			return nil
+		} else {
+			tunedAt = at
+		}
+	}
+	observations, err := t.source.Observations(ctx, pv)
+	if err != nil {
+		return err
+	}
+	windows := recentWindows(observations, tunedAt, now)
+	if windows == nil {
+		return nil
+	}
+
+	diskID := pv.Spec.CSI.VolumeHandle
+	cs, err := t.serverFor(ctx, diskID)
+	if err != nil {
+		return err
+	}
+	disk, err := findDiskByID(diskID, cs.ecs)
+	if err != nil {
+		return err
+	}
+	if disk == nil || !AllCategories[Category(disk.Category)].ProvisionedIops {
+		return nil
+	}
+	desired, changed := a.desiredForWindows(disk.Size, disk.ProvisionedIops, windows)
+	if !changed {
+		return nil
+	}
+	peak := peakOf(windows)
+
+	req := ecs.CreateModifyDiskSpecRequest()
+	req.DiskId = diskID
+	req.ProvisionedIops = requests.NewInteger64(desired)
+	if _, err := throttle.Throttled(cs.modifyThrottler, wrap.V1(ctx, cs.ecs.ModifyDiskSpec))(ctx, req); err != nil {
+		t.recorder.Eventf(pv.Spec.ClaimRef, v1.EventTypeWarning, eventIopsTuneFailed,
+			"Failed to change provisioned IOPS of disk %s from %d to %d: %v", diskID, disk.ProvisionedIops, desired, err)
+		return fmt.Errorf("failed to modify provisioned IOPS of disk %s: %w", diskID, err)
+	}
+	logger.V(2).Info("changed provisioned IOPS", "disk", diskID, "from", disk.ProvisionedIops, "to", desired,
+		"peakIOPS", peak.PeakIOPS, "peakThroughput", peak.PeakThroughput)
+	t.recorder.Eventf(pv.Spec.ClaimRef, v1.EventTypeNormal, eventIopsTuned,
+		"Changed provisioned IOPS of disk %s from %d to %d, observed peak %d IOPS and %d MiB/s between %s and %s",
+		diskID, disk.ProvisionedIops, desired, peak.PeakIOPS, peak.PeakThroughput/1024/1024,
+		peak.Since.Format(time.RFC3339), peak.Until.Format(time.RFC3339))
+
+	metav1.SetMetaDataAnnotation(&pv.ObjectMeta, IopsAutotunedAtAnnotation, now.UTC().Format(time.RFC3339))
+	if _, err := t.client.CoreV1().PersistentVolumes().Update(ctx, pv, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to record autotuning time: %w", err)
+	}
+	return nil
+}
+
+// peakOf returns the peak IOPS and throughput over all the windows.
+func peakOf(windows []metric.DiskIOObservation) *metric.DiskIOObservation {
+	peak := &metric.DiskIOObservation{Since: windows[0].Since, Until: windows[len(windows)-1].Until}
+	for _, w := range windows {
+		peak.PeakIOPS = max(peak.PeakIOPS, w.PeakIOPS)
+		peak.PeakThroughput = max(peak.PeakThroughput, w.PeakThroughput)
+	}
+	return peak
+}
@@ -0,0 +1,289 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/fake"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestGetIOPSAutotune(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		params   map[string]string
+		expected *iopsAutotune
+		err      bool
+	}{
+		{"disabled", map[string]string{}, nil, false},
+		{"default cooldown", map[string]string{"provisionedIopsMin": "0", "provisionedIopsMax": "10000"},
+			&iopsAutotune{Min: 0, Max: 10000, Cooldown: time.Hour}, false},
+		{"cooldown", map[string]string{"provisionedIopsMin": "1000", "provisionedIopsMax": "1000", "provisionedIopsCooldown": "30m"},
+			&iopsAutotune{Min: 1000, Max: 1000, Cooldown: 30 * time.Minute}, false},
+		{"min only", map[string]string{"provisionedIopsMin": "1000"}, nil, true},
+		{"max less than min", map[string]string{"provisionedIopsMin": "1000", "provisionedIopsMax": "500"}, nil, true},
+		{"negative", map[string]string{"provisionedIopsMin": "-1", "provisionedIopsMax": "500"}, nil, true},
+		{"invalid cooldown", map[string]string{"provisionedIopsMin": "0", "provisionedIopsMax": "500", "provisionedIopsCooldown": "1"}, nil, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := getIOPSAutotune(c.params)
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, a)
+		})
+	}
+}
+
+func TestObserveDiskIO(t *testing.T) {
+	autotuned := map[string]string{"type": "cloud_auto", "provisionedIopsMin": "0", "provisionedIopsMax": "10000"}
+	for _, c := range []struct {
+		name     string
+		driver   string
+		attrs    map[string]string
+		expected bool
+	}{
+		{"autotuned", driverName, autotuned, true},
+		{"not autotuned", driverName, map[string]string{"type": "cloud_auto"}, false},
+		{"not cloud_auto", driverName, map[string]string{"type": "cloud_essd", "provisionedIopsMin": "0", "provisionedIopsMax": "10000"}, false},
+		{"invalid bounds", driverName, map[string]string{"type": "cloud_auto", "provisionedIopsMin": "1000"}, false},
+		{"other driver", "nasplugin.csi.alibabacloud.com", autotuned, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			pv := &v1.PersistentVolume{Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: c.driver, VolumeAttributes: c.attrs},
+			}}}
+			assert.Equal(t, c.expected, observeDiskIO(pv))
+		})
+	}
+}
+
+func TestDesiredProvisionedIops(t *testing.T) {
+	a := &iopsAutotune{Min: 1000, Max: 20000}
+	// 100GiB: baseline 6800 IOPS, 170MiB/s
+	for _, c := range []struct {
+		name           string
+		current        int64
+		iops           uint64
+		throughput     uint64
+		desired        int64
+		expectedChange bool
+	}{
+		{"raise for IOPS", 1000, 10000, 0, 10000, true},
+		{"raise for throughput", 1000, 0, 200 << 20, 11000, true},
+		{"keep", 10000, 10000, 0, 10000, false},
+		{"lower to min", 10000, 3000, 0, 1000, true},
+		{"raise to max", 1000, 50000, 0, 20000, true},
+		{"out of bounds", 30000, 25000, 0, 20000, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			desired, changed := a.desiredProvisionedIops(100, c.current, &metric.DiskIOObservation{
+				PeakIOPS: c.iops, PeakThroughput: c.throughput,
+			})
+			assert.Equal(t, c.desired, desired)
+			assert.Equal(t, c.expectedChange, changed)
+		})
+	}
+}
+
+func TestDesiredForWindows(t *testing.T) {
+	a := &iopsAutotune{Min: 0, Max: 20000}
+	window := func(iops uint64) metric.DiskIOObservation { return metric.DiskIOObservation{PeakIOPS: iops} }
+	for _, c := range []struct {
+		name           string
+		windows        []metric.DiskIOObservation
+		desired        int64
+		expectedChange bool
+	}{
+		{"all busy", []metric.DiskIOObservation{window(8000), window(10000), window(9000)}, 10000, true},
+		{"all idle", []metric.DiskIOObservation{window(3000), window(3400), window(2000)}, 0, true},
+		{"single burst", []metric.DiskIOObservation{window(3000), window(10000), window(3000)}, 2000, false},
+		{"one in range", []metric.DiskIOObservation{window(8000), window(6000), window(9000)}, 2000, false},
+		{"none", nil, 2000, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			// 100GiB: baseline 6800 IOPS, 8800 with 2000 provisioned
+			desired, changed := a.desiredForWindows(100, 2000, c.windows)
+			assert.Equal(t, c.desired, desired)
+			assert.Equal(t, c.expectedChange, changed)
+		})
+	}
+}
+
+func TestRecentWindows(t *testing.T) {
+	now := time.Now()
+	windows := func(n int, until time.Time) []metric.DiskIOObservation {
+		var w []metric.DiskIOObservation
+		for i := n; i > 0; i-- {
+			end := until.Add(-time.Duration(i-1) * metric.DiskIOObserveInterval)
+			w = append(w, metric.DiskIOObservation{Since: end.Add(-metric.DiskIOObserveInterval), Until: end, Node: "node-1"})
+		}
+		return w
+	}
+	assert.Len(t, recentWindows(windows(5, now), time.Time{}, now), iopsAutotuneWindows)
+	assert.Nil(t, recentWindows(windows(2, now), time.Time{}, now), "not enough")
+	assert.Nil(t, recentWindows(windows(3, now.Add(-time.Hour)), time.Time{}, now), "stale")
+	assert.Nil(t, recentWindows(windows(3, now), now.Add(-12*time.Minute), now), "observed before the last change")
+
+	gap := append(windows(1, now.Add(-time.Hour)), windows(2, now)...)
+	assert.Nil(t, recentWindows(gap, time.Time{}, now), "gap")
+	moved := windows(3, now)
+	moved[2].Node = "node-2"
+	assert.Nil(t, recentWindows(moved, time.Time{}, now), "moved to another node")
+}
+
+type iopsAutotuneTest struct {
+	c      *fake.Cloud
+	clk    *clocktesting.FakeClock
+	client *k8sfake.Clientset
+	t      *iopsAutotuner
+	diskID string
+}
+
+func testIOPSAutotune(t *testing.T) *iopsAutotuneTest {
+	clk := clocktesting.NewFakeClock(time.Now())
+	c := fake.New(fake.Options{Clock: clk})
+
+	req := ecs.CreateCreateDiskRequest()
+	req.DiskCategory = string(DiskESSDAuto)
+	req.Size = "100"
+	req.ZoneId = "cn-hangzhou-a"
+	resp, err := c.CreateDisk(req)
+	require.NoError(t, err)
+
+	client := k8sfake.NewSimpleClientset(
+		&storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "auto"},
+			Provisioner: driverName,
+			Parameters:  map[string]string{"type": "cloud_auto", "provisionedIopsMin": "0", "provisionedIopsMax": "20000"},
+		},
+		&v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec: v1.PersistentVolumeSpec{
+				StorageClassName: "auto",
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: resp.DiskId},
+				},
+				ClaimRef: &v1.ObjectReference{Namespace: "default", Name: "data", UID: "pvc-uid"},
+			},
+			Status: v1.PersistentVolumeStatus{Phase: v1.VolumeBound},
+		},
+	)
+	return &iopsAutotuneTest{
+		c:      c,
+		clk:    clk,
+		client: client,
+		t: &iopsAutotuner{
+			client: client,
+			serverFor: func(ctx context.Context, diskID string) (*controllerServer, error) {
+				return &controllerServer{ecs: c, modifyThrottler: defaultThrottler()}, nil
+			},
+			source:   pvAnnotationIOSource{},
+			recorder: record.NewFakeRecorder(10),
+			clk:      clk,
+		},
+		diskID: resp.DiskId,
+	}
+}
+
+// observe records a window ending now with the peak IOPS, after the windows already recorded.
+func (k *iopsAutotuneTest) observe(t *testing.T, iops uint64) {
+	pv, err := k.client.CoreV1().PersistentVolumes().Get(t.Context(), "pv-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	windows, err := metric.ParseDiskIOObservations(pv.Annotations)
+	require.NoError(t, err)
+	windows = append(windows, metric.DiskIOObservation{
+		PeakIOPS: iops,
+		Since:    k.clk.Now().Add(-metric.DiskIOObserveInterval),
+		Until:    k.clk.Now(),
+		Node:     "node-1",
+	})
+	value, err := json.Marshal(windows)
+	require.NoError(t, err)
+	metav1.SetMetaDataAnnotation(&pv.ObjectMeta, metric.DiskIOObservationAnnotation, string(value))
+	_, err = k.client.CoreV1().PersistentVolumes().Update(t.Context(), pv, metav1.UpdateOptions{})
+	require.NoError(t, err)
+}
+
+func (k *iopsAutotuneTest) provisionedIops(t *testing.T) int64 {
+	d, ok := k.c.Disk(k.diskID)
+	require.True(t, ok)
+	return d.ProvisionedIops
+}
+
+// observeWindows records a window of each peak IOPS, 5 minutes apart.
+func (k *iopsAutotuneTest) observeWindows(t *testing.T, iops ...uint64) {
+	for _, v := range iops {
+		k.clk.Step(metric.DiskIOObserveInterval)
+		k.observe(t, v)
+	}
+}
+
+func TestIOPSAutotune(t *testing.T) {
+	k := testIOPSAutotune(t)
+
+	// no observation yet
+	require.NoError(t, k.t.reconcile(t.Context()))
+	assert.Equal(t, int64(0), k.provisionedIops(t))
+
+	// not enough windows
+	k.observeWindows(t, 10000, 10000)
+	require.NoError(t, k.t.reconcile(t.Context()))
+	assert.Equal(t, int64(0), k.provisionedIops(t))
+
+	k.observeWindows(t, 10000)
+	require.NoError(t, k.t.reconcile(t.Context()))
+	assert.Equal(t, int64(10000), k.provisionedIops(t))
+	pv, err := k.client.CoreV1().PersistentVolumes().Get(t.Context(), "pv-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, pv.Annotations, IopsAutotunedAtAnnotation)
+	assert.Contains(t, <-k.t.recorder.(*record.FakeRecorder).Events, eventIopsTuned)
+
+	// cooldown
+	k.observeWindows(t, 1000, 1000, 1000, 1000, 1000, 1000)
+	require.NoError(t, k.t.reconcile(t.Context()))
+	assert.Equal(t, int64(10000), k.provisionedIops(t))
+
+	// stale observation
+	k.clk.Step(time.Hour)
+	require.NoError(t, k.t.reconcile(t.Context()))
+	assert.Equal(t, int64(10000), k.provisionedIops(t))
+
+	// a single burst does not change it
+	k.observeWindows(t, 1000, 12000, 1000)
+	require.NoError(t, k.t.reconcile(t.Context()))
+	assert.Equal(t, int64(10000), k.provisionedIops(t))
+
+	k.observeWindows(t, 1000, 1000)
+	require.NoError(t, k.t.reconcile(t.Context()))
+	assert.Equal(t, int64(0), k.provisionedIops(t))
+}
+
+func TestIOPSAutotuneSkipCategory(t *testing.T) {
+	k := testIOPSAutotune(t)
+	req := ecs.CreateModifyDiskSpecRequest()
+	req.DiskId = k.diskID
+	req.DiskCategory = string(DiskESSD)
+	req.PerformanceLevel = "PL1"
+	_, err := k.c.ModifyDiskSpec(req)
+	require.NoError(t, err)
+
+	k.observe(t, 10000)
+	require.NoError(t, k.t.reconcile(t.Context()))
+	assert.Equal(t, int64(0), k.provisionedIops(t))
+}
@@ -0,0 +1,810 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/features"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// KMSKeyRotationParameter is the mutable parameter of VolumeAttributesClass to rotate the KMS key of disks to.
+	KMSKeyRotationParameter = "kmsKeyId"
+
+	// KMSRotationOldDiskAnnotation on the PV is the disk it was swapped from, kept until the rotation is confirmed.
+	KMSRotationOldDiskAnnotation = "csi.alibabacloud.com/kms-rotation-old-disk"
+	// KMSRotationConfirmLabel set to "true" on the PVC confirms that the old disk can be deleted.
+	KMSRotationConfirmLabel = "csi.alibabacloud.com/kms-rotation-confirm"
+	// KMSKeyRotationCondition of the PVC tracks the progress of the rotation.
+	KMSKeyRotationCondition v1.PersistentVolumeClaimConditionType = "KMSKeyRotation"
+
+	// tags of the source disk while rotating. Attaching the disk is refused while kmsRotationKeyTag is present.
+	kmsRotationKeyTag   = "csi.alibabacloud.com/kms-rotation-key"
+	kmsRotationPVTag    = "csi.alibabacloud.com/kms-rotation-pv"
+	kmsRotationClaimTag = "csi.alibabacloud.com/kms-rotation-pvc"
+	// tag of the snapshot and the new disk, the value is the source disk ID
+	kmsRotationSourceTag = "csi.alibabacloud.com/kms-rotation-source"
+	// annotation of the PVC holding the new PV while it is being recreated
+	kmsRotationPVBackupAnnotation = "csi.alibabacloud.com/kms-rotation-pv-backup"
+
+	kmsRotationInterval = 30 * time.Second
+	// Lease of the replica running kmsRotationController
+	kmsRotationLease = "csi-disk-kms-rotation"
+
+	// reasons of KMSKeyRotationCondition
+	kmsRotationWaitingForDetach     = "WaitingForDetach"
+	kmsRotationSnapshotting         = "Snapshotting"
+	kmsRotationCreatingDisk         = "CreatingDisk"
+	kmsRotationAwaitingConfirmation = "AwaitingConfirmation"
+	kmsRotationCompleted            = "Completed"
+	kmsRotationCancelled            = "Cancelled"
+
+	eventKMSKeyRotated           = "KMSKeyRotated"
+	eventKMSKeyRotationConfirmed = "KMSKeyRotationConfirmed"
+)
+
+// ControllerModifyVolume rotates the KMS key of the disk to the kmsKeyId mutable parameter.
+// It is retried by the external-resizer until the rotation is done, see kmsRotation.
+func (cs *controllerServer) ControllerModifyVolume(ctx context.Context, req *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
+	if !features.FunctionalMutableFeatureGate.Enabled(features.DiskKMSKeyRotation) {
+		return nil, status.Errorf(codes.Unimplemented, "%s feature gate is not enabled", features.DiskKMSKeyRotation)
+	}
+	key, err := parseKMSRotationParameters(req.MutableParameters)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if isThinVolumeID(req.VolumeId) {
+		return nil, status.Errorf(codes.InvalidArgument, "KMS key rotation is not supported for thin pool volume %s", req.VolumeId)
+	}
+	if GlobalConfigVar.ClientSet == nil {
+		return nil, status.Error(codes.FailedPrecondition, "KMS key rotation requires access to the Kubernetes API")
+	}
+	r := &kmsRotation{client: GlobalConfigVar.ClientSet, ecs: cs.ecs, recorder: cs.recorder}
+	if err := r.rotate(ctx, req.VolumeId, key); err != nil {
+		if _, ok := status.FromError(err); !ok {
+			err = status.Error(codes.Internal, err.Error())
+		}
+		return nil, err
+	}
+	return &csi.ControllerModifyVolumeResponse{}, nil
+}
+
+func parseKMSRotationParameters(params map[string]string) (string, error) {
+	var key string
+	for k, v := range params {
+		switch {
+		case k == KMSKeyRotationParameter:
+			key = v
+		case strings.HasPrefix(k, "csi.storage.k8s.io/"):
+			// added by --extra-modify-metadata of external-resizer
+		default:
+			return "", fmt.Errorf("unsupported mutable parameter %q, only %s is supported", k, KMSKeyRotationParameter)
+		}
+	}
+	if key == "" {
+		return "", fmt.Errorf("%s is required", KMSKeyRotationParameter)
+	}
+	return key, nil
+}
+
+// kmsRotation copies a disk to a new disk encrypted with another KMS key, and swaps the disk of the PV.
+//
+// Each call advances the rotation as far as possible, and returns Unavailable until the PV is swapped.
+// The progress is recorded in tags of the disks and snapshot, so that it is resumed after the controller restarts:
+//  1. the source disk is tagged with the new key once detached, which blocks it from being attached;
+//  2. a snapshot tagged with the source disk is created;
+//  3. a disk encrypted with the new key is created from the snapshot, tagged with the source disk;
+//  4. the PV is recreated with the new disk, since its volume source is immutable.
+//
+// The source disk and the snapshot are kept until the PVC is labeled with KMSRotationConfirmLabel.
+type kmsRotation struct {
+	client   kubernetes.Interface
+	ecs      cloud.ECSInterface
+	recorder record.EventRecorder
+}
+
+func (r *kmsRotation) rotate(ctx context.Context, diskID, key string) error {
+	disk, err := r.findDisk(ctx, diskID)
+	if err != nil {
+		return err
+	}
+	if disk == nil {
+		return status.Errorf(codes.NotFound, "disk %s not found", diskID)
+	}
+	if diskTag(disk, kmsRotationKeyTag) == "" {
+		if disk.Encrypted && disk.KMSKeyId == key {
+			return nil
+		}
+		disk, err = r.start(ctx, disk, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	pvName := diskTag(disk, kmsRotationPVTag)
+	claim, err := rotationClaim(disk)
+	if err != nil {
+		return err
+	}
+	pv, err := r.client.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		// crashed while recreating the PV
+		pv, err = r.restorePV(ctx, claim, pvName)
+		if err != nil {
+			return err
+		}
+		return r.finish(ctx, disk, pv, claim)
+	case err != nil:
+		return fmt.Errorf("failed to get PV %s: %w", pvName, err)
+	case pv.Spec.CSI == nil:
+		return status.Errorf(codes.FailedPrecondition, "PV %s is not a CSI volume", pvName)
+	case pv.Spec.CSI.VolumeHandle != diskID:
+		// already swapped
+		return r.finish(ctx, disk, pv, claim)
+	}
+
+	// the old PV may be changed once backed up, the swap must be completed
+	if backup, err := r.pvBackup(ctx, claim); err != nil {
+		return err
+	} else if backup != nil && backup.Name == pv.Name && backup.Annotations[KMSRotationOldDiskAnnotation] == diskID {
+		pv, err = r.swapPV(ctx, pv, claim, backup.Spec.CSI.VolumeHandle, diskTag(disk, kmsRotationKeyTag))
+		if err != nil {
+			return err
+		}
+		return r.finish(ctx, disk, pv, claim)
+	}
+
+	if inProgress := diskTag(disk, kmsRotationKeyTag); inProgress != key {
+		if err := r.abort(ctx, disk); err != nil {
+			return err
+		}
+		return status.Errorf(codes.Aborted, "rotation of disk %s to KMS key %s cancelled, will restart", diskID, inProgress)
+	}
+	if disk.Status != DiskStatusAvailable {
+		// attached before the tag took effect
+		if err := r.abort(ctx, disk); err != nil {
+			return err
+		}
+		return status.Errorf(codes.Unavailable, "disk %s is %s during KMS key rotation, will restart once detached", diskID, disk.Status)
+	}
+
+	snapshot, err := r.ensureSnapshot(ctx, disk)
+	if err != nil {
+		return err
+	}
+	if snapshot.Status != SnapshotStatusAccomplished {
+		r.setCondition(ctx, claim, v1.ConditionTrue, kmsRotationSnapshotting,
+			fmt.Sprintf("Snapshot %s of disk %s is %s", snapshot.SnapshotId, diskID, snapshot.Progress))
+		return status.Errorf(codes.Unavailable, "waiting for snapshot %s of disk %s, progress %s", snapshot.SnapshotId, diskID, snapshot.Progress)
+	}
+
+	newDisk, err := r.ensureDisk(ctx, disk, snapshot, key)
+	if err != nil {
+		return err
+	}
+	if newDisk.Status != DiskStatusAvailable {
+		r.setCondition(ctx, claim, v1.ConditionTrue, kmsRotationCreatingDisk,
+			fmt.Sprintf("Creating disk %s encrypted with KMS key %s from snapshot %s", newDisk.DiskId, key, snapshot.SnapshotId))
+		return status.Errorf(codes.Unavailable, "waiting for disk %s to be created, status %s", newDisk.DiskId, newDisk.Status)
+	}
+
+	if stale, err := detachedAfter(disk, snapshot); err != nil {
+		return err
+	} else if stale {
+		// the snapshot may miss writes of the last attachment
+		if err := r.abort(ctx, disk); err != nil {
+			return err
+		}
+		return status.Errorf(codes.Aborted, "disk %s was attached after snapshot %s, will restart", diskID, snapshot.SnapshotId)
+	}
+
+	// the PVC goes Lost while the PV is recreated, which must not be seen by the pods using it
+	if pod, err := r.claimUser(ctx, claim); err != nil {
+		return err
+	} else if pod != "" {
+		r.setCondition(ctx, claim, v1.ConditionTrue, kmsRotationWaitingForDetach,
+			fmt.Sprintf("Stop pod %s using the volume to swap disk %s to %s", pod, diskID, newDisk.DiskId))
+		return status.Errorf(codes.Unavailable, "PVC %s/%s is used by pod %s, waiting for it to stop to swap disk %s", claim.Namespace, claim.Name, pod, diskID)
+	}
+	pv, err = r.swapPV(ctx, pv, claim, newDisk.DiskId, key)
+	if err != nil {
+		return err
+	}
+	return r.finish(ctx, disk, pv, claim)
+}
+
+// claimUser returns the name of a pod using the PVC that is not terminated, or "" if there is none.
+func (r *kmsRotation) claimUser(ctx context.Context, claim *v1.ObjectReference) (string, error) {
+	pods, err := r.client.CoreV1().Pods(claim.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list pods in %s: %w", claim.Namespace, err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == claim.Name {
+				return pod.Name, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// start tags the detached disk with the key, so that it is not attached while being copied.
+func (r *kmsRotation) start(ctx context.Context, disk *ecs.Disk, key string) (*ecs.Disk, error) {
+	logger := klog.FromContext(ctx)
+	pv, err := r.findPV(ctx, disk.DiskId)
+	if err != nil {
+		return nil, err
+	}
+	claim := pv.Spec.ClaimRef
+	if claim == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "PV %s of disk %s is not bound", pv.Name, disk.DiskId)
+	}
+	if disk.Status != DiskStatusAvailable {
+		r.setCondition(ctx, claim, v1.ConditionTrue, kmsRotationWaitingForDetach,
+			fmt.Sprintf("Stop the pods using the volume to rotate disk %s to KMS key %s", disk.DiskId, key))
+		return nil, status.Errorf(codes.Unavailable, "disk %s is %s, waiting for it to be detached to rotate KMS key", disk.DiskId, disk.Status)
+	}
+
+	req := ecs.CreateAddTagsRequest()
+	req.ResourceType = "disk"
+	req.ResourceId = disk.DiskId
+	req.Tag = &[]ecs.AddTagsTag{
+		{Key: kmsRotationKeyTag, Value: key},
+		{Key: kmsRotationPVTag, Value: pv.Name},
+		{Key: kmsRotationClaimTag, Value: claim.Namespace + "/" + claim.Name},
+	}
+	if _, err := wrap.V1(ctx, r.ecs.AddTags)(req); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to tag disk %s for KMS key rotation: %v", disk.DiskId, err)
+	}
+	logger.V(2).Info("started KMS key rotation", "pv", pv.Name, "kmsKeyId", key)
+
+	// attach may have started before the tag
+	disk, err = r.findDisk(ctx, disk.DiskId)
+	if err != nil {
+		return nil, err
+	}
+	if disk == nil {
+		return nil, status.Errorf(codes.NotFound, "disk %s not found", req.ResourceId)
+	}
+	if disk.Status != DiskStatusAvailable {
+		if err := r.abort(ctx, disk); err != nil {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Unavailable, "disk %s is %s, waiting for it to be detached to rotate KMS key", disk.DiskId, disk.Status)
+	}
+	return disk, nil
+}
+
+// abort deletes the snapshot and the new disk, and untags the source disk. The PV must not be swapped yet.
+func (r *kmsRotation) abort(ctx context.Context, disk *ecs.Disk) error {
+	logger := klog.FromContext(ctx)
+	disks, err := r.rotationDisks(ctx, disk.DiskId)
+	if err != nil {
+		return err
+	}
+	for _, d := range disks {
+		req := ecs.CreateDeleteDiskRequest()
+		req.DiskId = d.DiskId
+		if _, err := wrap.V1(ctx, r.ecs.DeleteDisk)(req); err != nil {
+			return status.Errorf(codes.Unavailable, "failed to delete disk %s copied from %s: %v", d.DiskId, disk.DiskId, err)
+		}
+		logger.V(2).Info("deleted disk of cancelled KMS key rotation", "newDisk", d.DiskId)
+	}
+	if err := r.deleteSnapshots(ctx, disk.DiskId); err != nil {
+		return err
+	}
+
+	req := ecs.CreateRemoveTagsRequest()
+	req.ResourceType = "disk"
+	req.ResourceId = disk.DiskId
+	req.Tag = &[]ecs.RemoveTagsTag{{Key: kmsRotationKeyTag}, {Key: kmsRotationPVTag}, {Key: kmsRotationClaimTag}}
+	if _, err := wrap.V1(ctx, r.ecs.RemoveTags)(req); err != nil {
+		return status.Errorf(codes.Internal, "failed to untag disk %s: %v", disk.DiskId, err)
+	}
+	if claim, err := rotationClaim(disk); err == nil {
+		r.setCondition(ctx, claim, v1.ConditionFalse, kmsRotationCancelled,
+			fmt.Sprintf("Rotation of disk %s to KMS key %s is cancelled", disk.DiskId, diskTag(disk, kmsRotationKeyTag)))
+	}
+	logger.V(2).Info("cancelled KMS key rotation", "kmsKeyId", diskTag(disk, kmsRotationKeyTag))
+	return nil
+}
+
+func (r *kmsRotation) findPV(ctx context.Context, diskID string) (*v1.PersistentVolume, error) {
+	pvs, err := r.client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVs: %w", err)
+	}
+	for i := range pvs.Items {
+		pv := &pvs.Items[i]
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == driverName && pv.Spec.CSI.VolumeHandle == diskID {
+			return pv, nil
+		}
+	}
+	return nil, status.Errorf(codes.FailedPrecondition, "no PV found for disk %s", diskID)
+}
+
+func rotationClaim(disk *ecs.Disk) (*v1.ObjectReference, error) {
+	ns, name, ok := strings.Cut(diskTag(disk, kmsRotationClaimTag), "/")
+	if !ok || diskTag(disk, kmsRotationPVTag) == "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "disk %s is missing tags %s and %s of KMS key rotation", disk.DiskId, kmsRotationPVTag, kmsRotationClaimTag)
+	}
+	return &v1.ObjectReference{Kind: "PersistentVolumeClaim", APIVersion: "v1", Namespace: ns, Name: name}, nil
+}
+
+func (r *kmsRotation) ensureSnapshot(ctx context.Context, disk *ecs.Disk) (*ecs.Snapshot, error) {
+	snapshots, err := r.rotationSnapshots(ctx, disk.DiskId)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) > 0 {
+		return &snapshots[0], nil
+	}
+
+	req := ecs.CreateCreateSnapshotRequest()
+	req.DiskId = disk.DiskId
+	req.SnapshotName = "kms-rotation-" + disk.DiskId
+	req.Description = "Created by CSI for KMS key rotation of " + disk.DiskId
+	req.ResourceGroupId = disk.ResourceGroupId
+	tags := []ecs.CreateSnapshotTag{
+		{Key: DISKTAGKEY2, Value: DISKTAGVALUE2},
+		{Key: kmsRotationSourceTag, Value: disk.DiskId},
+	}
+	if GlobalConfigVar.ClusterID != "" {
+		tags = append(tags, ecs.CreateSnapshotTag{Key: DISKTAGKEY3, Value: GlobalConfigVar.ClusterID})
+	}
+	req.Tag = &tags
+	resp, err := wrap.V1(ctx, r.ecs.CreateSnapshot)(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create snapshot of disk %s: %v", disk.DiskId, err)
+	}
+	klog.FromContext(ctx).V(2).Info("created snapshot for KMS key rotation", "snapshotID", resp.SnapshotId)
+	return &ecs.Snapshot{SnapshotId: resp.SnapshotId, SourceDiskId: disk.DiskId, Status: "progressing", Progress: "0%"}, nil
+}
+
+func (r *kmsRotation) rotationSnapshots(ctx context.Context, diskID string) ([]ecs.Snapshot, error) {
+	req := ecs.CreateDescribeSnapshotsRequest()
+	req.DiskId = diskID
+	req.Tag = &[]ecs.DescribeSnapshotsTag{{Key: kmsRotationSourceTag, Value: diskID}}
+	resp, err := wrap.V1(ctx, r.ecs.DescribeSnapshots)(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to describe snapshots of disk %s: %v", diskID, err)
+	}
+	return resp.Snapshots.Snapshot, nil
+}
+
+func (r *kmsRotation) deleteSnapshots(ctx context.Context, diskID string) error {
+	snapshots, err := r.rotationSnapshots(ctx, diskID)
+	if err != nil {
+		return err
+	}
+	for _, s := range snapshots {
+		if _, err := requestAndDeleteSnapshot(ctx, r.ecs, s.SnapshotId); err != nil {
+			return status.Errorf(codes.Unavailable, "failed to delete snapshot %s: %v", s.SnapshotId, err)
+		}
+	}
+	return nil
+}
+
+// rotationDisks returns disks copied from diskID.
+func (r *kmsRotation) rotationDisks(ctx context.Context, diskID string) ([]ecs.Disk, error) {
+	req := ecs.CreateDescribeDisksRequest()
+	req.Tag = &[]ecs.DescribeDisksTag{{Key: kmsRotationSourceTag, Value: diskID}}
+	resp, err := wrap.V1(ctx, r.ecs.DescribeDisks)(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to describe disks copied from %s: %v", diskID, err)
+	}
+	return resp.Disks.Disk, nil
+}
+
+// findDisk returns the disk of diskID, or nil if it does not exist.
+func (r *kmsRotation) findDisk(ctx context.Context, diskID string) (*ecs.Disk, error) {
+	req := ecs.CreateDescribeDisksRequest()
+	req.DiskIds = "[\"" + diskID + "\"]"
+	resp, err := wrap.V1(ctx, r.ecs.DescribeDisks)(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to describe disk %s: %v", diskID, err)
+	}
+	if len(resp.Disks.Disk) == 0 {
+		return nil, nil
+	}
+	return &resp.Disks.Disk[0], nil
+}
+
+// ensureDisk creates the disk encrypted with key from the snapshot, with the same attributes and tags as the source disk.
+func (r *kmsRotation) ensureDisk(ctx context.Context, disk *ecs.Disk, snapshot *ecs.Snapshot, key string) (*ecs.Disk, error) {
+	disks, err := r.rotationDisks(ctx, disk.DiskId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range disks {
+		if disks[i].KMSKeyId == key {
+			return &disks[i], nil
+		}
+	}
+
+	req := ecs.CreateCreateDiskRequest()
+	cate := AllCategories[Category(disk.Category)]
+	if !cate.Regional {
+		req.ZoneId = disk.ZoneId
+	}
+	req.DiskCategory = disk.Category
+	req.PerformanceLevel = disk.PerformanceLevel
+	req.Size = requests.NewInteger(disk.Size)
+	req.SnapshotId = snapshot.SnapshotId
+	req.DiskName = disk.DiskName
+	req.Description = disk.Description
+	req.ResourceGroupId = disk.ResourceGroupId
+	req.Encrypted = requests.NewBoolean(true)
+	req.KMSKeyId = key
+	if disk.MultiAttach == DiskMultiAttachEnabled {
+		req.MultiAttach = DiskMultiAttachEnabled
+	}
+	if cate.ProvisionedIops && disk.ProvisionedIops > 0 {
+		req.ProvisionedIops = requests.NewInteger64(disk.ProvisionedIops)
+	}
+	if cate.Bursting {
+		req.BurstingEnabled = requests.NewBoolean(disk.BurstingEnabled)
+	}
+	tags := []ecs.CreateDiskTag{{Key: kmsRotationSourceTag, Value: disk.DiskId}}
+	for _, t := range disk.Tags.Tag {
+		switch {
+		case strings.HasPrefix(t.TagKey, "csi.alibabacloud.com/kms-rotation-"),
+			strings.HasPrefix(t.TagKey, "acs:"):
+			continue
+		}
+		tags = append(tags, ecs.CreateDiskTag{Key: t.TagKey, Value: t.TagValue})
+	}
+	req.Tag = &tags
+	req.ClientToken = clientToken(snapshot.SnapshotId + "/" + key)
+
+	resp, err := wrap.V1(ctx, r.ecs.CreateDisk)(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create disk from snapshot %s with KMS key %s: %v", snapshot.SnapshotId, key, err)
+	}
+	klog.FromContext(ctx).V(2).Info("created disk for KMS key rotation", "newDisk", resp.DiskId, "kmsKeyId", key)
+	return &ecs.Disk{DiskId: resp.DiskId, Status: "Creating"}, nil
+}
+
+// detachedAfter reports whether disk was detached after the snapshot was created, i.e. attached during the rotation.
+func detachedAfter(disk *ecs.Disk, snapshot *ecs.Snapshot) (bool, error) {
+	if disk.DetachedTime == "" || snapshot.CreationTime == "" {
+		return false, nil
+	}
+	detached, err := time.Parse(time.RFC3339, disk.DetachedTime)
+	if err != nil {
+		return false, fmt.Errorf("invalid DetachedTime %q of disk %s: %w", disk.DetachedTime, disk.DiskId, err)
+	}
+	created, err := time.Parse(time.RFC3339, snapshot.CreationTime)
+	if err != nil {
+		return false, fmt.Errorf("invalid CreationTime %q of snapshot %s: %w", snapshot.CreationTime, snapshot.SnapshotId, err)
+	}
+	return detached.After(created), nil
+}
+
+// swapPV recreates the PV with the new disk, keeping its name, binding and the old disk.
+// The PVC goes Lost until the PV is recreated, then the PV controller binds it again.
+//
+// Every step is skipped if already done, so that swapPV is resumed by the next call after a crash:
+//  1. the new PV is backed up in an annotation of the PVC, once, before the old PV is changed;
+//  2. the old PV is retained and its finalizers are removed, again if the pv-protection controller added them back;
+//  3. the old PV is deleted, and waited for until it is gone;
+//  4. the new PV is created from the backup by restorePV.
+func (r *kmsRotation) swapPV(ctx context.Context, pv *v1.PersistentVolume, claim *v1.ObjectReference, newDiskID, key string) (*v1.PersistentVolume, error) {
+	logger := klog.FromContext(ctx)
+	oldDiskID := pv.Spec.CSI.VolumeHandle
+	backup, err := r.pvBackup(ctx, claim)
+	if err != nil {
+		return nil, err
+	}
+	if backup == nil || backup.Name != pv.Name || backup.Spec.CSI == nil || backup.Spec.CSI.VolumeHandle != newDiskID {
+		b, err := json.Marshal(swappedPV(pv, newDiskID, key))
+		if err != nil {
+			return nil, err
+		}
+		if err := r.updateClaim(ctx, claim, func(pvc *v1.PersistentVolumeClaim) {
+			metav1.SetMetaDataAnnotation(&pvc.ObjectMeta, kmsRotationPVBackupAnnotation, string(b))
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// the old disk must survive the deletion of the PV, and the pv-protection finalizer would wait for the PVC forever
+	pvs := r.client.CoreV1().PersistentVolumes()
+	if pv.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimRetain || len(pv.Finalizers) > 0 {
+		pv = pv.DeepCopy()
+		pv.Spec.PersistentVolumeReclaimPolicy = v1.PersistentVolumeReclaimRetain
+		pv.Finalizers = nil
+		pv, err = pvs.Update(ctx, pv, metav1.UpdateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to retain PV %s: %w", pv.Name, err)
+		}
+	}
+	if pv.DeletionTimestamp == nil {
+		err = pvs.Delete(ctx, pv.Name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &pv.UID}})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to delete PV %s: %w", pv.Name, err)
+		}
+		logger.V(2).Info("deleted PV to swap disk", "pv", pv.Name, "oldDisk", oldDiskID, "newDisk", newDiskID)
+	}
+	_, err = pvs.Get(ctx, pv.Name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		return nil, status.Errorf(codes.Unavailable, "waiting for PV %s of disk %s to be deleted", pv.Name, oldDiskID)
+	case !apierrors.IsNotFound(err):
+		return nil, fmt.Errorf("failed to get PV %s: %w", pv.Name, err)
+	}
+	return r.restorePV(ctx, claim, pv.Name)
+}
+
+// swappedPV returns pv with the new disk, annotated with the old disk.
+func swappedPV(pv *v1.PersistentVolume, newDiskID, key string) *v1.PersistentVolume {
+	newPV := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pv.Name,
+			Labels:      pv.Labels,
+			Annotations: map[string]string{},
+			Finalizers:  pv.Finalizers,
+		},
+		Spec: *pv.Spec.DeepCopy(),
+	}
+	for k, v := range pv.Annotations {
+		newPV.Annotations[k] = v
+	}
+	newPV.Annotations[KMSRotationOldDiskAnnotation] = pv.Spec.CSI.VolumeHandle
+	newPV.Spec.CSI.VolumeHandle = newDiskID
+	if newPV.Spec.CSI.VolumeAttributes != nil {
+		if _, ok := newPV.Spec.CSI.VolumeAttributes["kmsKeyId"]; ok {
+			newPV.Spec.CSI.VolumeAttributes["kmsKeyId"] = key
+		}
+		if _, ok := newPV.Spec.CSI.VolumeAttributes[KMSKeyID]; ok {
+			newPV.Spec.CSI.VolumeAttributes[KMSKeyID] = key
+		}
+		newPV.Spec.CSI.VolumeAttributes["encrypted"] = "true"
+	}
+	if newPV.Spec.ClaimRef != nil {
+		newPV.Spec.ClaimRef.ResourceVersion = ""
+	}
+	return newPV
+}
+
+// pvBackup returns the PV backed up in the PVC annotation by swapPV, or nil if there is none.
+func (r *kmsRotation) pvBackup(ctx context.Context, claim *v1.ObjectReference) (*v1.PersistentVolume, error) {
+	pvc, err := r.client.CoreV1().PersistentVolumeClaims(claim.Namespace).Get(ctx, claim.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PVC %s/%s: %w", claim.Namespace, claim.Name, err)
+	}
+	backup := pvc.Annotations[kmsRotationPVBackupAnnotation]
+	if backup == "" {
+		return nil, nil
+	}
+	pv := &v1.PersistentVolume{}
+	if err := json.Unmarshal([]byte(backup), pv); err != nil {
+		return nil, fmt.Errorf("invalid PV backup in PVC %s/%s: %w", claim.Namespace, claim.Name, err)
+	}
+	return pv, nil
+}
+
+// restorePV creates the PV backed up in the PVC annotation by swapPV.
+// The backup is removed by finish, once the PV is created.
+func (r *kmsRotation) restorePV(ctx context.Context, claim *v1.ObjectReference, pvName string) (*v1.PersistentVolume, error) {
+	pv, err := r.pvBackup(ctx, claim)
+	if err != nil {
+		return nil, err
+	}
+	if pv == nil || pv.Name != pvName || pv.Spec.CSI == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "PV %s is missing, and no backup in annotation %s of PVC %s/%s",
+			pvName, kmsRotationPVBackupAnnotation, claim.Namespace, claim.Name)
+	}
+	created, err := r.client.CoreV1().PersistentVolumes().Create(ctx, pv, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		created, err = r.client.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PV %s: %w", pv.Name, err)
+	}
+	if created.Spec.CSI == nil || created.Spec.CSI.VolumeHandle != pv.Spec.CSI.VolumeHandle {
+		return nil, status.Errorf(codes.FailedPrecondition, "PV %s is recreated by others", pv.Name)
+	}
+	klog.FromContext(ctx).V(2).Info("created PV with new disk", "pv", pv.Name, "newDisk", pv.Spec.CSI.VolumeHandle)
+	return created, nil
+}
+
+// finish removes the PV backup left by swapPV, and waits for the rotation to be confirmed.
+func (r *kmsRotation) finish(ctx context.Context, disk *ecs.Disk, pv *v1.PersistentVolume, claim *v1.ObjectReference) error {
+	if pv.Annotations[KMSRotationOldDiskAnnotation] != disk.DiskId {
+		return status.Errorf(codes.FailedPrecondition, "PV %s is swapped to disk %s by others", pv.Name, pv.Spec.CSI.VolumeHandle)
+	}
+	if backup, err := r.pvBackup(ctx, claim); err != nil {
+		return err
+	} else if backup != nil {
+		if err := r.updateClaim(ctx, claim, func(pvc *v1.PersistentVolumeClaim) {
+			delete(pvc.Annotations, kmsRotationPVBackupAnnotation)
+		}); err != nil {
+			return err
+		}
+	}
+	msg := fmt.Sprintf("Disk %s is replaced by %s encrypted with KMS key %s. Label the PVC with %s=true to delete the old disk",
+		disk.DiskId, pv.Spec.CSI.VolumeHandle, diskTag(disk, kmsRotationKeyTag), KMSRotationConfirmLabel)
+	if r.setCondition(ctx, claim, v1.ConditionTrue, kmsRotationAwaitingConfirmation, msg) {
+		r.recorder.Event(claim, v1.EventTypeNormal, eventKMSKeyRotated, msg)
+	}
+	return nil
+}
+
+func (r *kmsRotation) updateClaim(ctx context.Context, claim *v1.ObjectReference, update func(*v1.PersistentVolumeClaim)) error {
+	pvcs := r.client.CoreV1().PersistentVolumeClaims(claim.Namespace)
+	pvc, err := pvcs.Get(ctx, claim.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get PVC %s/%s: %w", claim.Namespace, claim.Name, err)
+	}
+	update(pvc)
+	if _, err := pvcs.Update(ctx, pvc, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update PVC %s/%s: %w", claim.Namespace, claim.Name, err)
+	}
+	return nil
+}
+
+// setCondition sets KMSKeyRotationCondition of the PVC, best effort. Returns whether the condition is changed.
+func (r *kmsRotation) setCondition(ctx context.Context, claim *v1.ObjectReference, condStatus v1.ConditionStatus, reason, message string) bool {
+	logger := klog.FromContext(ctx)
+	pvcs := r.client.CoreV1().PersistentVolumeClaims(claim.Namespace)
+	pvc, err := pvcs.Get(ctx, claim.Name, metav1.GetOptions{})
+	if err != nil {
+		logger.Error(err, "failed to get PVC for KMS key rotation condition", "pvc", klog.KRef(claim.Namespace, claim.Name))
+		return false
+	}
+	cond := v1.PersistentVolumeClaimCondition{
+		Type:               KMSKeyRotationCondition,
+		Status:             condStatus,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	}
+	found := false
+	for i, c := range pvc.Status.Conditions {
+		if c.Type != KMSKeyRotationCondition {
+			continue
+		}
+		if c.Status == condStatus && c.Reason == reason && c.Message == message {
+			return false
+		}
+		pvc.Status.Conditions[i] = cond
+		found = true
+	}
+	if !found {
+		pvc.Status.Conditions = append(pvc.Status.Conditions, cond)
+	}
+	if _, err := pvcs.UpdateStatus(ctx, pvc, metav1.UpdateOptions{}); err != nil {
+		logger.Error(err, "failed to update KMS key rotation condition", "pvc", klog.KRef(claim.Namespace, claim.Name))
+		return false
+	}
+	return true
+}
+
+func diskTag(disk *ecs.Disk, key string) string {
+	for _, t := range disk.Tags.Tag {
+		if t.TagKey == key {
+			return t.TagValue
+		}
+	}
+	return ""
+}
+
+// kmsRotationController deletes the old disk and snapshot of PVCs with KMSRotationConfirmLabel.
+// It runs on the replica holding kmsRotationLease only.
+type kmsRotationController struct {
+	client   kubernetes.Interface
+	ecsFor   func(ctx context.Context, diskID string) (cloud.ECSInterface, error)
+	recorder record.EventRecorder
+	clk      clock.PassiveClock
+}
+
+func (c *kmsRotationController) run(ctx context.Context) {
+	logger := klog.FromContext(ctx).WithName("kms-rotation")
+	ctx = klog.NewContext(ctx, logger)
+	logger.Info("Disk KMS key rotation confirmation started")
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.reconcile(ctx); err != nil {
+			logger.Error(err, "failed to reconcile")
+		}
+	}, kmsRotationInterval)
+}
+
+func (c *kmsRotationController) reconcile(ctx context.Context) error {
+	pvcs, err := c.client.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{
+		LabelSelector: KMSRotationConfirmLabel + "=true",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list PVCs: %w", err)
+	}
+	var errs []error
+	for i := range pvcs.Items {
+		if err := c.confirm(ctx, &pvcs.Items[i]); err != nil {
+			errs = append(errs, fmt.Errorf("PVC %s/%s: %w", pvcs.Items[i].Namespace, pvcs.Items[i].Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *kmsRotationController) confirm(ctx context.Context, pvc *v1.PersistentVolumeClaim) error {
+	logger := klog.FromContext(ctx).WithValues("pvc", klog.KObj(pvc))
+	claim := &v1.ObjectReference{Kind: "PersistentVolumeClaim", APIVersion: "v1", Namespace: pvc.Namespace, Name: pvc.Name, UID: pvc.UID}
+	r := &kmsRotation{client: c.client, recorder: c.recorder}
+	if pvc.Spec.VolumeName == "" {
+		return nil
+	}
+	pv, err := c.client.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	oldDiskID := pv.Annotations[KMSRotationOldDiskAnnotation]
+	if oldDiskID != "" {
+		if pv.Spec.CSI == nil || pv.Spec.CSI.VolumeHandle == oldDiskID {
+			return fmt.Errorf("PV %s is not swapped from disk %s", pv.Name, oldDiskID)
+		}
+		r.ecs, err = c.ecsFor(ctx, pv.Spec.CSI.VolumeHandle)
+		if err != nil {
+			return err
+		}
+		disk, err := r.findDisk(ctx, oldDiskID)
+		if err != nil {
+			return err
+		}
+		if err := r.deleteSnapshots(ctx, oldDiskID); err != nil {
+			return err
+		}
+		if disk != nil {
+			// never delete a disk not rotated for this PV
+			if diskTag(disk, kmsRotationPVTag) != pv.Name {
+				return fmt.Errorf("disk %s is not tagged %s=%s", oldDiskID, kmsRotationPVTag, pv.Name)
+			}
+			req := ecs.CreateDeleteDiskRequest()
+			req.DiskId = oldDiskID
+			if _, err := wrap.V1(ctx, r.ecs.DeleteDisk)(req); err != nil {
+				return fmt.Errorf("failed to delete disk %s: %w", oldDiskID, err)
+			}
+			logger.V(2).Info("deleted disk of confirmed KMS key rotation", "oldDisk", oldDiskID)
+		}
+		delete(pv.Annotations, KMSRotationOldDiskAnnotation)
+		if _, err := c.client.CoreV1().PersistentVolumes().Update(ctx, pv, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update PV %s: %w", pv.Name, err)
+		}
+	}
+	if err := r.updateClaim(ctx, claim, func(pvc *v1.PersistentVolumeClaim) {
+		delete(pvc.Labels, KMSRotationConfirmLabel)
+	}); err != nil {
+		return err
+	}
+	if oldDiskID != "" {
+		msg := fmt.Sprintf("Deleted disk %s, KMS key rotation completed", oldDiskID)
+		r.setCondition(ctx, claim, v1.ConditionFalse, kmsRotationCompleted, msg)
+		c.recorder.Event(claim, v1.EventTypeNormal, eventKMSKeyRotationConfirmed, msg)
+	}
+	return nil
+}
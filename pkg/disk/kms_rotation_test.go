@@ -0,0 +1,379 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/fake"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/batcher"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/desc"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/waitstatus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestParseKMSRotationParameters(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		params map[string]string
+		key    string
+		err    bool
+	}{
+		{"key", map[string]string{"kmsKeyId": "key-2"}, "key-2", false},
+		{"with metadata", map[string]string{"kmsKeyId": "key-2", "csi.storage.k8s.io/pvc/name": "data"}, "key-2", false},
+		{"empty", map[string]string{"kmsKeyId": ""}, "", true},
+		{"missing", map[string]string{}, "", true},
+		{"unsupported", map[string]string{"kmsKeyId": "key-2", "performanceLevel": "PL2"}, "", true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			key, err := parseKMSRotationParameters(c.params)
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.key, key)
+		})
+	}
+}
+
+type kmsRotationTest struct {
+	c      *fake.Cloud
+	clk    *clocktesting.FakeClock
+	client *k8sfake.Clientset
+	r      *kmsRotation
+	diskID string
+}
+
+func testKMSRotation(t *testing.T) *kmsRotationTest {
+	clk := clocktesting.NewFakeClock(time.Now())
+	c := fake.New(fake.Options{Clock: clk, TransitionDelay: time.Minute})
+	c.AddInstance("i-1", "cn-hangzhou-a")
+
+	req := ecs.CreateCreateDiskRequest()
+	req.DiskCategory = string(DiskESSD)
+	req.PerformanceLevel = "PL1"
+	req.Size = "20"
+	req.ZoneId = "cn-hangzhou-a"
+	req.Encrypted = requests.NewBoolean(true)
+	req.KMSKeyId = "key-1"
+	req.Tag = &[]ecs.CreateDiskTag{{Key: "app", Value: "db"}}
+	resp, err := c.CreateDisk(req)
+	require.NoError(t, err)
+	clk.Step(time.Minute)
+
+	client := k8sfake.NewSimpleClientset(
+		&v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1", Finalizers: []string{"kubernetes.io/pv-protection"}},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{
+						Driver:           driverName,
+						VolumeHandle:     resp.DiskId,
+						VolumeAttributes: map[string]string{"kmsKeyId": "key-1", "encrypted": "true"},
+					},
+				},
+				ClaimRef: &v1.ObjectReference{Namespace: "default", Name: "data", UID: "pvc-uid"},
+			},
+		},
+		&v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "data", UID: "pvc-uid"},
+			Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pv-1"},
+		},
+	)
+	return &kmsRotationTest{
+		c:      c,
+		clk:    clk,
+		client: client,
+		r:      &kmsRotation{client: client, ecs: c, recorder: record.NewFakeRecorder(10)},
+		diskID: resp.DiskId,
+	}
+}
+
+func (k *kmsRotationTest) condition(t *testing.T) *v1.PersistentVolumeClaimCondition {
+	pvc, err := k.client.CoreV1().PersistentVolumeClaims("default").Get(t.Context(), "data", metav1.GetOptions{})
+	require.NoError(t, err)
+	for _, c := range pvc.Status.Conditions {
+		if c.Type == KMSKeyRotationCondition {
+			return &c
+		}
+	}
+	return nil
+}
+
+func (k *kmsRotationTest) pv(t *testing.T) *v1.PersistentVolume {
+	pv, err := k.client.CoreV1().PersistentVolumes().Get(t.Context(), "pv-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	return pv
+}
+
+func (k *kmsRotationTest) rotateStep(t *testing.T, key string) error {
+	err := k.r.rotate(t.Context(), k.diskID, key)
+	k.clk.Step(time.Minute)
+	return err
+}
+
+func TestKMSRotation(t *testing.T) {
+	k := testKMSRotation(t)
+	attach := ecs.CreateAttachDiskRequest()
+	attach.DiskId = k.diskID
+	attach.InstanceId = "i-1"
+	_, err := k.c.AttachDisk(attach)
+	require.NoError(t, err)
+	k.clk.Step(time.Minute)
+
+	// already using the key
+	require.NoError(t, k.r.rotate(t.Context(), k.diskID, "key-1"))
+
+	err = k.rotateStep(t, "key-2")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, kmsRotationWaitingForDetach, k.condition(t).Reason)
+
+	detach := ecs.CreateDetachDiskRequest()
+	detach.DiskId = k.diskID
+	detach.InstanceId = "i-1"
+	_, err = k.c.DetachDisk(detach)
+	require.NoError(t, err)
+	k.clk.Step(time.Minute)
+
+	err = k.rotateStep(t, "key-2")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, kmsRotationSnapshotting, k.condition(t).Reason)
+	old, _ := k.c.Disk(k.diskID)
+	assert.Equal(t, "key-2", diskTag(&old, kmsRotationKeyTag))
+
+	err = k.rotateStep(t, "key-2")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, kmsRotationCreatingDisk, k.condition(t).Reason)
+
+	require.NoError(t, k.rotateStep(t, "key-2"))
+	cond := k.condition(t)
+	assert.Equal(t, kmsRotationAwaitingConfirmation, cond.Reason)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+
+	pv := k.pv(t)
+	newDiskID := pv.Spec.CSI.VolumeHandle
+	assert.NotEqual(t, k.diskID, newDiskID)
+	assert.Equal(t, k.diskID, pv.Annotations[KMSRotationOldDiskAnnotation])
+	assert.Equal(t, v1.PersistentVolumeReclaimDelete, pv.Spec.PersistentVolumeReclaimPolicy)
+	assert.Equal(t, []string{"kubernetes.io/pv-protection"}, pv.Finalizers)
+	assert.Equal(t, "key-2", pv.Spec.CSI.VolumeAttributes["kmsKeyId"])
+	assert.Equal(t, "pvc-uid", string(pv.Spec.ClaimRef.UID))
+
+	newDisk, ok := k.c.Disk(newDiskID)
+	require.True(t, ok)
+	assert.True(t, newDisk.Encrypted)
+	assert.Equal(t, "key-2", newDisk.KMSKeyId)
+	assert.Equal(t, "PL1", newDisk.PerformanceLevel)
+	assert.Equal(t, 20, newDisk.Size)
+	assert.Equal(t, "db", diskTag(&newDisk, "app"))
+	assert.Empty(t, diskTag(&newDisk, kmsRotationKeyTag))
+
+	pvc, err := k.client.CoreV1().PersistentVolumeClaims("default").Get(t.Context(), "data", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, pvc.Annotations, kmsRotationPVBackupAnnotation)
+
+	// retried by external-resizer with either disk
+	require.NoError(t, k.r.rotate(t.Context(), k.diskID, "key-2"))
+	require.NoError(t, k.r.rotate(t.Context(), newDiskID, "key-2"))
+
+	// confirm
+	controller := &kmsRotationController{
+		client:   k.client,
+		ecsFor:   func(ctx context.Context, diskID string) (cloud.ECSInterface, error) { return k.c, nil },
+		recorder: record.NewFakeRecorder(10),
+		clk:      k.clk,
+	}
+	require.NoError(t, controller.reconcile(t.Context()))
+	_, ok = k.c.Disk(k.diskID)
+	assert.True(t, ok, "not confirmed yet")
+
+	pvc.Labels = map[string]string{KMSRotationConfirmLabel: "true"}
+	_, err = k.client.CoreV1().PersistentVolumeClaims("default").Update(t.Context(), pvc, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, controller.reconcile(t.Context()))
+
+	_, ok = k.c.Disk(k.diskID)
+	assert.False(t, ok)
+	snapshots, err := k.r.rotationSnapshots(t.Context(), k.diskID)
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+	assert.NotContains(t, k.pv(t).Annotations, KMSRotationOldDiskAnnotation)
+	cond = k.condition(t)
+	assert.Equal(t, kmsRotationCompleted, cond.Reason)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	pvc, err = k.client.CoreV1().PersistentVolumeClaims("default").Get(t.Context(), "data", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, pvc.Labels, KMSRotationConfirmLabel)
+}
+
+func TestKMSRotationCancel(t *testing.T) {
+	k := testKMSRotation(t)
+
+	err := k.rotateStep(t, "key-2")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	err = k.rotateStep(t, "key-2")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	disks, err := k.r.rotationDisks(t.Context(), k.diskID)
+	require.NoError(t, err)
+	assert.Len(t, disks, 1)
+
+	// the VolumeAttributesClass is changed back
+	err = k.rotateStep(t, "key-1")
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.Equal(t, kmsRotationCancelled, k.condition(t).Reason)
+	disks, err = k.r.rotationDisks(t.Context(), k.diskID)
+	require.NoError(t, err)
+	assert.Empty(t, disks)
+	snapshots, err := k.r.rotationSnapshots(t.Context(), k.diskID)
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+	old, _ := k.c.Disk(k.diskID)
+	assert.Empty(t, diskTag(&old, kmsRotationKeyTag))
+
+	require.NoError(t, k.r.rotate(t.Context(), k.diskID, "key-1"))
+	assert.Equal(t, k.diskID, k.pv(t).Spec.CSI.VolumeHandle)
+}
+
+func TestKMSRotationAttachBlocked(t *testing.T) {
+	k := testKMSRotation(t)
+	err := k.rotateStep(t, "key-2")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	client := desc.Disk(k.c)
+	ad := DiskAttachDetach{
+		slots:           NewSlots(0, 0),
+		ecs:             k.c,
+		waiter:          waitstatus.NewSimple(client, k.clk),
+		batcher:         batcher.NewPassthrough(client),
+		attachThrottler: defaultThrottler(),
+		detachThrottler: defaultThrottler(),
+		dev:             DefaultDeviceManager,
+	}
+	_, err = ad.attachDisk(t.Context(), k.diskID, "i-1", false)
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.ErrorContains(t, err, "key-2")
+}
+
+func TestKMSRotationPodUsingClaim(t *testing.T) {
+	k := testKMSRotation(t)
+	// pending, since the disk cannot be attached during the rotation
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db-0"},
+		Spec: v1.PodSpec{Volumes: []v1.Volume{{
+			Name:         "data",
+			VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data"}},
+		}}},
+		Status: v1.PodStatus{Phase: v1.PodPending},
+	}
+	_, err := k.client.CoreV1().Pods("default").Create(t.Context(), pod, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	for range 3 {
+		err = k.rotateStep(t, "key-2")
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	assert.ErrorContains(t, err, "db-0")
+	assert.Equal(t, kmsRotationWaitingForDetach, k.condition(t).Reason)
+	assert.Equal(t, k.diskID, k.pv(t).Spec.CSI.VolumeHandle)
+
+	require.NoError(t, k.client.CoreV1().Pods("default").Delete(t.Context(), "db-0", metav1.DeleteOptions{}))
+	require.NoError(t, k.rotateStep(t, "key-2"))
+	assert.NotEqual(t, k.diskID, k.pv(t).Spec.CSI.VolumeHandle)
+}
+
+func TestKMSRotationRestorePV(t *testing.T) {
+	k := testKMSRotation(t)
+	for range 2 {
+		err := k.rotateStep(t, "key-2")
+		require.Equal(t, codes.Unavailable, status.Code(err), "%v", err)
+	}
+	pv := k.pv(t)
+
+	// crashed after the PV is deleted
+	k.client.PrependReactor("create", "persistentvolumes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("crashed")
+	})
+	err := k.rotateStep(t, "key-2")
+	require.ErrorContains(t, err, "crashed")
+	_, err = k.client.CoreV1().PersistentVolumes().Get(t.Context(), pv.Name, metav1.GetOptions{})
+	require.True(t, apierrors.IsNotFound(err))
+
+	k.client.ReactionChain = k.client.ReactionChain[1:]
+	require.NoError(t, k.rotateStep(t, "key-2"))
+	restored := k.pv(t)
+	assert.NotEqual(t, k.diskID, restored.Spec.CSI.VolumeHandle)
+	assert.Equal(t, k.diskID, restored.Annotations[KMSRotationOldDiskAnnotation])
+	assert.Equal(t, v1.PersistentVolumeReclaimDelete, restored.Spec.PersistentVolumeReclaimPolicy)
+	assert.Equal(t, kmsRotationAwaitingConfirmation, k.condition(t).Reason)
+	pvc, err := k.client.CoreV1().PersistentVolumeClaims("default").Get(t.Context(), "data", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, pvc.Annotations, kmsRotationPVBackupAnnotation)
+}
+
+func TestKMSRotationResumeSwap(t *testing.T) {
+	k := testKMSRotation(t)
+	for range 2 {
+		err := k.rotateStep(t, "key-2")
+		require.Equal(t, codes.Unavailable, status.Code(err), "%v", err)
+	}
+
+	// crashed after the PV is retained
+	k.client.PrependReactor("delete", "persistentvolumes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("crashed")
+	})
+	err := k.rotateStep(t, "key-2")
+	require.ErrorContains(t, err, "crashed")
+	pv := k.pv(t)
+	assert.Equal(t, v1.PersistentVolumeReclaimRetain, pv.Spec.PersistentVolumeReclaimPolicy)
+	assert.Empty(t, pv.Finalizers)
+
+	// the pv-protection controller added its finalizer back, the deletion is pending
+	pv.Finalizers = []string{"kubernetes.io/pv-protection"}
+	now := metav1.Now()
+	pv.DeletionTimestamp = &now
+	_, err = k.client.CoreV1().PersistentVolumes().Update(t.Context(), pv, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	k.client.ReactionChain[0] = &k8stesting.SimpleReactor{Verb: "delete", Resource: "persistentvolumes",
+		Reaction: func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("already deleting")
+		},
+	}
+	// changing the key back does not cancel the swap
+	err = k.rotateStep(t, "key-1")
+	assert.Equal(t, codes.Unavailable, status.Code(err), "%v", err)
+	assert.ErrorContains(t, err, "to be deleted")
+	assert.Empty(t, k.pv(t).Finalizers)
+	disks, err := k.r.rotationDisks(t.Context(), k.diskID)
+	require.NoError(t, err)
+	require.Len(t, disks, 1)
+
+	// the PV is gone
+	k.client.ReactionChain = k.client.ReactionChain[1:]
+	require.NoError(t, k.client.CoreV1().PersistentVolumes().Delete(t.Context(), "pv-1", metav1.DeleteOptions{}))
+	require.NoError(t, k.rotateStep(t, "key-1"))
+	restored := k.pv(t)
+	assert.Equal(t, disks[0].DiskId, restored.Spec.CSI.VolumeHandle)
+	assert.Equal(t, v1.PersistentVolumeReclaimDelete, restored.Spec.PersistentVolumeReclaimPolicy)
+	assert.Equal(t, []string{"kubernetes.io/pv-protection"}, restored.Finalizers)
+	assert.Equal(t, "key-2", restored.Spec.CSI.VolumeAttributes["kmsKeyId"])
+	assert.Equal(t, kmsRotationAwaitingConfirmation, k.condition(t).Reason)
+}
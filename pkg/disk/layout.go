@@ -0,0 +1,391 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/lvm"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// PartitionTag selects the partition to stage by its number,
+	// for disks with more than one partition, e.g. restored from a VM image.
+	PartitionTag = "partition"
+	// LogicalVolumeTag selects the LVM logical volume to stage as <vg>/<lv>,
+	// for disks with LVM physical volumes on them.
+	LogicalVolumeTag = "logicalVolume"
+)
+
+// volumeLayout selects the block device to mount from a disk that contains a partition table or LVM.
+type volumeLayout struct {
+	Partition string
+	VG, LV    string
+}
+
+// getVolumeLayout returns nil if neither partition nor logical volume is selected.
+func getVolumeLayout(volumeContext map[string]string) (*volumeLayout, error) {
+	partition := volumeContext[PartitionTag]
+	lv := volumeContext[LogicalVolumeTag]
+	switch {
+	case partition == "" && lv == "":
+		return nil, nil
+	case partition != "" && lv != "":
+		return nil, fmt.Errorf("%s and %s are mutually exclusive", PartitionTag, LogicalVolumeTag)
+	case partition != "":
+		n, err := strconv.Atoi(partition)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid %s %q, should be a positive partition number", PartitionTag, partition)
+		}
+		return &volumeLayout{Partition: strconv.Itoa(n)}, nil
+	}
+	vg, name, ok := strings.Cut(lv, "/")
+	if !ok || vg == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid %s %q, should be <vg>/<lv>", LogicalVolumeTag, lv)
+	}
+	if vg == thinPoolVGName {
+		return nil, fmt.Errorf("invalid %s %q, volume group %s is reserved", LogicalVolumeTag, lv, thinPoolVGName)
+	}
+	return &volumeLayout{VG: vg, LV: name}, nil
+}
+
+// partitionDevice returns the device path of the partition numbered number on the root device.
+func (m *DeviceManager) partitionDevice(rootDevicePath, number string) (string, error) {
+	devName, err := m.deviceName(rootDevicePath)
+	if err != nil {
+		return "", fmt.Errorf("get device name for %s failed: %w", rootDevicePath, err)
+	}
+	partitions, err := filepath.Glob(fmt.Sprintf("%s/block/%s/%s*/partition", m.SysfsPath, devName, devName))
+	if err != nil {
+		return "", err
+	}
+	for _, p := range partitions {
+		idx, err := os.ReadFile(p)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(string(idx)) == number {
+			return filepath.Join(m.DevicePath, filepath.Base(filepath.Dir(p))), nil
+		}
+	}
+	return "", fmt.Errorf("partition %s not found on %s: %w", number, devName, os.ErrNotExist)
+}
+
+// lvmBacking returns the device-mapper name of device, and the devices it is built on, e.g. vdb or vdb3.
+// Returns empty name if device is not an LVM logical volume.
+// The backing devices must be the root device or its partitions, so that a volume group of the same name on another disk is never used.
+func (m *DeviceManager) lvmBacking(device, rootDevicePath string) (dmName string, backing []string, err error) {
+	major, minor, err := m.DevTmpFS.DevFor(device)
+	if err != nil {
+		return "", nil, err
+	}
+	dir := m.sysfsDir(major, minor)
+	uuid, err := os.ReadFile(filepath.Join(dir, "dm/uuid"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.HasPrefix(string(uuid), "LVM-") {
+		return "", nil, nil
+	}
+	name, err := os.ReadFile(filepath.Join(dir, "dm/name"))
+	if err != nil {
+		return "", nil, err
+	}
+	rootName, err := m.deviceName(rootDevicePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("get device name for %s failed: %w", rootDevicePath, err)
+	}
+	slaves, err := os.ReadDir(filepath.Join(dir, "slaves"))
+	if err != nil {
+		return "", nil, err
+	}
+	for _, s := range slaves {
+		if s.Name() != rootName {
+			if _, err := os.Stat(filepath.Join(m.SysfsPath, "block", rootName, s.Name(), "partition")); err != nil {
+				return "", nil, fmt.Errorf("%s is also backed by %s, which is not on %s", device, s.Name(), rootName)
+			}
+		}
+		backing = append(backing, filepath.Join(m.DevicePath, s.Name()))
+	}
+	return strings.TrimSpace(string(name)), backing, nil
+}
+
+// lvmActiveElsewhere checks whether any logical volume of the volume group named vg is active from devices not on the root device.
+func (m *DeviceManager) lvmActiveElsewhere(vg, rootDevicePath string) (bool, error) {
+	rootName, err := m.deviceName(rootDevicePath)
+	if err != nil {
+		return false, fmt.Errorf("get device name for %s failed: %w", rootDevicePath, err)
+	}
+	dms, err := filepath.Glob(filepath.Join(m.SysfsPath, "block/dm-*"))
+	if err != nil {
+		return false, err
+	}
+	for _, dm := range dms {
+		uuid, err := os.ReadFile(filepath.Join(dm, "dm/uuid"))
+		if err != nil || !strings.HasPrefix(string(uuid), "LVM-") {
+			continue
+		}
+		name, err := os.ReadFile(filepath.Join(dm, "dm/name"))
+		if err != nil {
+			return false, err
+		}
+		if vgName, _, ok := lvm.SplitDMName(strings.TrimSpace(string(name))); !ok || vgName != vg {
+			continue
+		}
+		slaves, err := os.ReadDir(filepath.Join(dm, "slaves"))
+		if err != nil {
+			return false, err
+		}
+		for _, s := range slaves {
+			if s.Name() == rootName {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(m.SysfsPath, "block", rootName, s.Name(), "partition")); err != nil {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// diskDevices returns the root device and its partitions.
+func (m *DeviceManager) diskDevices(rootDevicePath string) ([]string, error) {
+	rootName, err := m.deviceName(rootDevicePath)
+	if err != nil {
+		return nil, fmt.Errorf("get device name for %s failed: %w", rootDevicePath, err)
+	}
+	partitions, err := filepath.Glob(fmt.Sprintf("%s/block/%s/%s*/partition", m.SysfsPath, rootName, rootName))
+	if err != nil {
+		return nil, err
+	}
+	devices := []string{filepath.Join(m.DevicePath, rootName)}
+	for _, p := range partitions {
+		devices = append(devices, filepath.Join(m.DevicePath, filepath.Base(filepath.Dir(p))))
+	}
+	return devices, nil
+}
+
+// lvmHolders returns the device-mapper names of LVM logical volumes on the root device or its partitions.
+func (m *DeviceManager) lvmHolders(rootDevicePath string) ([]string, error) {
+	devices, err := m.diskDevices(rootDevicePath)
+	if err != nil {
+		return nil, err
+	}
+	rootName := filepath.Base(devices[0])
+	var names []string
+	for _, d := range devices {
+		dir := filepath.Join(m.SysfsPath, "block", rootName)
+		if d != devices[0] {
+			dir = filepath.Join(dir, filepath.Base(d))
+		}
+		holders, err := os.ReadDir(filepath.Join(dir, "holders"))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for _, h := range holders {
+			uuid, err := os.ReadFile(filepath.Join(m.SysfsPath, "block", h.Name(), "dm/uuid"))
+			if err != nil || !strings.HasPrefix(string(uuid), "LVM-") {
+				continue
+			}
+			name, err := os.ReadFile(filepath.Join(m.SysfsPath, "block", h.Name(), "dm/name"))
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, strings.TrimSpace(string(name)))
+		}
+	}
+	return names, nil
+}
+
+// layoutManager stages and grows the partition or logical volume of a disk selected by volumeLayout.
+type layoutManager struct {
+	dev             *DeviceManager
+	newVG           func(name string) *lvm.VolumeGroup
+	expandPartition func(ctx context.Context, disk, partition string) error
+}
+
+func newVolumeGroup(name string) *lvm.VolumeGroup {
+	return lvm.New(name, "")
+}
+
+// volumeGroup returns the volume group named name on the root device only.
+// LVM commands by name would otherwise also act on volume groups of the same name on other disks.
+func (l *layoutManager) volumeGroup(rootDevicePath, name string) (*lvm.VolumeGroup, error) {
+	devices, err := l.dev.diskDevices(rootDevicePath)
+	if err != nil {
+		return nil, err
+	}
+	return l.newVG(name).OnDevices(devices...), nil
+}
+
+// cloneVGName returns the name a volume group on the disk of volumeID is renamed to,
+// if a volume group of the same name on another disk, e.g. restored from the same image, is already active.
+func cloneVGName(vg, volumeID string) string {
+	return vg + "_" + volumeID
+}
+
+// resolve returns the device to mount for the layout on the root device of volumeID.
+func (l *layoutManager) resolve(ctx context.Context, volumeID, rootDevicePath string, layout *volumeLayout) (string, error) {
+	if layout.Partition != "" {
+		return l.dev.partitionDevice(rootDevicePath, layout.Partition)
+	}
+	vg, err := l.volumeGroup(rootDevicePath, cloneVGName(layout.VG, volumeID))
+	if err != nil {
+		return "", err
+	}
+	// renamed when staged before
+	cloned, err := vg.Exists(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to find volume group %s: %w", vg.Name, err)
+	}
+	if !cloned {
+		vg, err = l.volumeGroup(rootDevicePath, layout.VG)
+		if err != nil {
+			return "", err
+		}
+		// the device-mapper names are taken if a volume group of the same name on another disk is active
+		elsewhere, err := l.dev.lvmActiveElsewhere(layout.VG, rootDevicePath)
+		if err != nil {
+			return "", err
+		}
+		if elsewhere {
+			vg, err = vg.ImportClone(ctx, cloneVGName(layout.VG, volumeID))
+			if err != nil {
+				return "", fmt.Errorf("failed to rename volume group %s: %w", layout.VG, err)
+			}
+		}
+	}
+	if err := vg.Activate(ctx); err != nil {
+		return "", fmt.Errorf("failed to activate volume group %s: %w", vg.Name, err)
+	}
+	device := filepath.Join(l.dev.DevicePath, vg.Name, layout.LV)
+	dmName, _, err := l.dev.lvmBacking(device, rootDevicePath)
+	if err != nil {
+		return "", fmt.Errorf("logical volume %s/%s: %w", vg.Name, layout.LV, err)
+	}
+	if dmName == "" {
+		return "", fmt.Errorf("%s is not a logical volume", device)
+	}
+	return device, nil
+}
+
+// volumeDevice returns the device to mount for the volume, which is the partition or logical volume if selected in volumeContext.
+func (ns *nodeServer) volumeDevice(ctx context.Context, volumeID string, volumeContext map[string]string) (string, error) {
+	logger := klog.FromContext(ctx)
+	layout, err := getVolumeLayout(volumeContext)
+	if err != nil {
+		return "", err
+	}
+	if layout == nil {
+		return ns.ad.GetVolumeDeviceName(logger, volumeID)
+	}
+	root, err := ns.ad.GetRootBlockDevice(logger, volumeID)
+	if err != nil {
+		return "", err
+	}
+	return ns.layout.resolve(ctx, volumeID, root, layout)
+}
+
+// grow extends the partition, or the physical volumes and the logical volume of device to fill the root device.
+// The filesystem should be resized afterwards.
+func (l *layoutManager) grow(ctx context.Context, rootDevicePath, device string) error {
+	logger := klog.FromContext(ctx)
+	dmName, backing, err := l.dev.lvmBacking(device, rootDevicePath)
+	if err != nil {
+		return err
+	}
+	if dmName == "" {
+		return l.growPartition(ctx, rootDevicePath, device)
+	}
+	vgName, lvName, ok := lvm.SplitDMName(dmName)
+	if !ok {
+		return fmt.Errorf("invalid device-mapper name %q of logical volume", dmName)
+	}
+	vg, err := l.volumeGroup(rootDevicePath, vgName)
+	if err != nil {
+		return err
+	}
+	for _, pv := range backing {
+		if err := l.growPartition(ctx, rootDevicePath, pv); err != nil {
+			return err
+		}
+		if err := vg.ResizePhysicalVolume(ctx, pv); err != nil {
+			return err
+		}
+	}
+	if err := vg.ExtendToFree(ctx, lvName); err != nil {
+		return err
+	}
+	logger.V(2).Info("Successful expand logical volume", "vg", vgName, "lv", lvName, "pvs", backing)
+	return nil
+}
+
+func (l *layoutManager) growPartition(ctx context.Context, rootDevicePath, device string) error {
+	major, minor, err := l.dev.DevTmpFS.DevFor(device)
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.EvalSymlinks(l.dev.sysfsDir(major, minor))
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(dir + "/partition")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	rootName, err := l.dev.deviceName(rootDevicePath)
+	if err != nil {
+		return fmt.Errorf("get device name for %s failed: %w", rootDevicePath, err)
+	}
+	if filepath.Base(filepath.Dir(dir)) != rootName {
+		return fmt.Errorf("%s is not a partition of %s", device, rootName)
+	}
+	index := strings.TrimSpace(string(b))
+	if err := l.expandPartition(ctx, rootDevicePath, index); err != nil {
+		return err
+	}
+	klog.FromContext(ctx).V(2).Info("Successful expand partition", "root", rootDevicePath, "partition", index)
+	return nil
+}
+
+// deactivate deactivates the volume groups on the root device, so that no device-mapper device is left after detach.
+func (l *layoutManager) deactivate(ctx context.Context, rootDevicePath string) error {
+	holders, err := l.dev.lvmHolders(rootDevicePath)
+	if err != nil {
+		return err
+	}
+	done := map[string]bool{}
+	for _, h := range holders {
+		vgName, _, ok := lvm.SplitDMName(h)
+		// the thin pool disk is never staged, just in case
+		if !ok || done[vgName] || vgName == thinPoolVGName {
+			continue
+		}
+		done[vgName] = true
+		vg, err := l.volumeGroup(rootDevicePath, vgName)
+		if err != nil {
+			return err
+		}
+		if err := vg.Deactivate(ctx); err != nil {
+			return fmt.Errorf("failed to deactivate volume group %s: %w", vgName, err)
+		}
+		klog.FromContext(ctx).V(2).Info("deactivated volume group", "vg", vgName)
+	}
+	return nil
+}
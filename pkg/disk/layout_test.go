@@ -0,0 +1,231 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/lvm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetVolumeLayout(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		context  map[string]string
+		expected *volumeLayout
+		err      bool
+	}{
+		{"none", map[string]string{}, nil, false},
+		{"partition", map[string]string{PartitionTag: "2"}, &volumeLayout{Partition: "2"}, false},
+		{"partition zero", map[string]string{PartitionTag: "0"}, nil, true},
+		{"partition name", map[string]string{PartitionTag: "vdb2"}, nil, true},
+		{"lv", map[string]string{LogicalVolumeTag: "rl/root"}, &volumeLayout{VG: "rl", LV: "root"}, false},
+		{"lv without vg", map[string]string{LogicalVolumeTag: "root"}, nil, true},
+		{"lv nested", map[string]string{LogicalVolumeTag: "rl/root/x"}, nil, true},
+		{"thin pool", map[string]string{LogicalVolumeTag: thinPoolVGName + "/pool"}, nil, true},
+		{"both", map[string]string{PartitionTag: "2", LogicalVolumeTag: "rl/root"}, nil, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			layout, err := getVolumeLayout(c.context)
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, layout)
+		})
+	}
+}
+
+type fakeLVMRunner struct {
+	calls []string
+	// vgs are the volume groups present, pvs the output of pvs
+	vgs []string
+	pvs string
+	// activated is called with the name of the volume group activated
+	activated func(vg string)
+}
+
+func (f *fakeLVMRunner) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := strings.Join(append([]string{name}, args...), " ")
+	f.calls = append(f.calls, cmd)
+	switch name {
+	case "vgs":
+		if _, vg, ok := strings.Cut(cmd, "--select vg_name="); ok {
+			if slices.Contains(f.vgs, vg) {
+				return []byte("  " + vg + "\n"), nil
+			}
+			return nil, nil
+		}
+		return []byte("  1073741824\n"), nil
+	case "pvs":
+		return []byte(f.pvs), nil
+	case "vgchange":
+		if f.activated != nil && args[len(args)-2] == "-ay" {
+			f.activated(args[len(args)-1])
+		}
+	}
+	return nil, nil
+}
+
+var (
+	virtIOPart2 = fakeDev{Major: 253, Minor: 18, Path: "vdb2"}
+	virtIOPart3 = fakeDev{Major: 253, Minor: 19, Path: "vdb3"}
+	lvmDev      = fakeDev{Major: 252, Minor: 0, Path: "rl/root"}
+	lvmCloneDev = fakeDev{Major: 252, Minor: 1, Path: "rl_d-2/root"}
+)
+
+// setupLVMDevice creates a virtio disk, with partition 2 and an LVM physical volume on partition 3.
+func setupLVMDevice(t *testing.T, m *DeviceManager, slave string) {
+	sysfsDev := setupVirtIOBlockDevice(t, m.SysfsPath)
+	sysfsSetupPartition(t, m.SysfsPath, sysfsDev, "vdb2", &virtIOPart2, 2)
+	sysfsSetupPartition(t, m.SysfsPath, sysfsDev, "vdb3", &virtIOPart3, 3)
+
+	dm := filepath.Join(m.SysfsPath, "devices/virtual/block/dm-0")
+	require.NoError(t, os.MkdirAll(filepath.Join(dm, "dm"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dm, "slaves", slave), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dm, "dm/uuid"), []byte("LVM-abcdef\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dm, "dm/name"), []byte("rl-root\n"), 0o644))
+	require.NoError(t, os.Symlink("../devices/virtual/block/dm-0", filepath.Join(m.SysfsPath, "block/dm-0")))
+	require.NoError(t, os.Symlink("../../devices/virtual/block/dm-0", filepath.Join(m.SysfsPath, "dev/block/252:0")))
+	require.NoError(t, os.MkdirAll(filepath.Join(m.SysfsPath, sysfsDev, "vdb3/holders/dm-0"), 0o755))
+
+	m.DevTmpFS.(*fakeDevTmpFS).Devs = []fakeDev{virtIODev, virtIOPart2, virtIOPart3, lvmDev}
+}
+
+// setupLVMCloneDevice creates the logical volume of the volume group on partition 3 renamed to rl_d-2.
+func setupLVMCloneDevice(t *testing.T, m *DeviceManager) {
+	dm := filepath.Join(m.SysfsPath, "devices/virtual/block/dm-1")
+	require.NoError(t, os.MkdirAll(filepath.Join(dm, "dm"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dm, "slaves/vdb3"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dm, "dm/uuid"), []byte("LVM-ghijkl\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dm, "dm/name"), []byte("rl_d--2-root\n"), 0o644))
+	require.NoError(t, os.Symlink("../devices/virtual/block/dm-1", filepath.Join(m.SysfsPath, "block/dm-1")))
+	require.NoError(t, os.Symlink("../../devices/virtual/block/dm-1", filepath.Join(m.SysfsPath, "dev/block/252:1")))
+	require.NoError(t, os.MkdirAll(filepath.Join(m.SysfsPath, "block/vdb/vdb3/holders/dm-1"), 0o755))
+
+	fs := m.DevTmpFS.(*fakeDevTmpFS)
+	fs.Devs = append(fs.Devs, lvmCloneDev)
+}
+
+func testingLayoutManager(t *testing.T) (*layoutManager, *fakeLVMRunner, *[]string) {
+	r := &fakeLVMRunner{}
+	var expanded []string
+	return &layoutManager{
+		dev: testingManager(t),
+		newVG: func(name string) *lvm.VolumeGroup {
+			return lvm.NewWithRunner(name, "", r.run)
+		},
+		expandPartition: func(ctx context.Context, disk, partition string) error {
+			expanded = append(expanded, filepath.Base(disk)+":"+partition)
+			return nil
+		},
+	}, r, &expanded
+}
+
+func TestLayoutPartition(t *testing.T) {
+	l, r, expanded := testingLayoutManager(t)
+	setupLVMDevice(t, l.dev, "vdb3")
+	root := filepath.Join(l.dev.DevicePath, "vdb")
+
+	device, err := l.resolve(t.Context(), "d-1", root, &volumeLayout{Partition: "2"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(l.dev.DevicePath, "vdb2"), device)
+
+	require.NoError(t, l.grow(t.Context(), root, device))
+	assert.Equal(t, []string{"vdb:2"}, *expanded)
+	assert.Empty(t, r.calls)
+
+	_, err = l.resolve(t.Context(), "d-1", root, &volumeLayout{Partition: "4"})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLayoutLogicalVolume(t *testing.T) {
+	l, r, expanded := testingLayoutManager(t)
+	setupLVMDevice(t, l.dev, "vdb3")
+	root := filepath.Join(l.dev.DevicePath, "vdb")
+
+	device, err := l.resolve(t.Context(), "d-1", root, &volumeLayout{VG: "rl", LV: "root"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(l.dev.DevicePath, "rl/root"), device)
+
+	require.NoError(t, l.grow(t.Context(), root, device))
+	assert.Equal(t, []string{"vdb:3"}, *expanded)
+
+	require.NoError(t, l.deactivate(t.Context(), root))
+	// only the physical volumes on this disk are scanned
+	config := fmt.Sprintf(`--config devices { use_devicesfile=0 filter=["a|^%s$|","a|^%s$|","a|^%s$|","r|.*|"] } `,
+		regexp.QuoteMeta(root), regexp.QuoteMeta(root+"2"), regexp.QuoteMeta(root+"3"))
+	assert.Equal(t, []string{
+		"vgs " + config + "--noheadings -o vg_name --select vg_name=rl_d-1",
+		"vgchange " + config + "-ay rl",
+		"pvresize " + config + filepath.Join(l.dev.DevicePath, "vdb3"),
+		"vgs " + config + "--noheadings --units b --nosuffix -o vg_free rl",
+		"lvextend " + config + "-l +100%FREE rl/root",
+		"vgchange " + config + "-an rl",
+	}, r.calls)
+}
+
+func TestLayoutLogicalVolumeOnOtherDisk(t *testing.T) {
+	l, r, expanded := testingLayoutManager(t)
+	// the volume group of the same name is already active from the other disk restored from the same image
+	setupLVMDevice(t, l.dev, "vdc1")
+	require.NoError(t, os.RemoveAll(filepath.Join(l.dev.SysfsPath, "block/vdb/vdb3/holders")))
+	root := filepath.Join(l.dev.DevicePath, "vdb")
+	r.pvs = "  " + root + "3\n"
+	r.activated = func(vg string) {
+		if vg == "rl_d-2" {
+			setupLVMCloneDevice(t, l.dev)
+		}
+	}
+
+	device, err := l.resolve(t.Context(), "d-2", root, &volumeLayout{VG: "rl", LV: "root"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(l.dev.DevicePath, "rl_d-2/root"), device)
+
+	require.NoError(t, l.grow(t.Context(), root, device))
+	assert.Equal(t, []string{"vdb:3"}, *expanded)
+
+	// staged again, the volume group is renamed already
+	r.vgs = []string{"rl_d-2"}
+	r.activated = nil
+	device, err = l.resolve(t.Context(), "d-2", root, &volumeLayout{VG: "rl", LV: "root"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(l.dev.DevicePath, "rl_d-2/root"), device)
+
+	// the volume group of the other disk is left alone
+	require.NoError(t, l.deactivate(t.Context(), root))
+	config := fmt.Sprintf(`--config devices { use_devicesfile=0 filter=["a|^%s$|","a|^%s$|","a|^%s$|","r|.*|"] } `,
+		regexp.QuoteMeta(root), regexp.QuoteMeta(root+"2"), regexp.QuoteMeta(root+"3"))
+	assert.Equal(t, []string{
+		"vgs " + config + "--noheadings -o vg_name --select vg_name=rl_d-2",
+		"pvs " + config + "--noheadings -o pv_name --select vg_name=rl",
+		"vgimportclone " + config + "--basevgname rl_d-2 " + root + "3",
+		"vgchange " + config + "-ay rl_d-2",
+		"pvresize " + config + root + "3",
+		"vgs " + config + "--noheadings --units b --nosuffix -o vg_free rl_d-2",
+		"lvextend " + config + "-l +100%FREE rl_d-2/root",
+		"vgs " + config + "--noheadings -o vg_name --select vg_name=rl_d-2",
+		"vgchange " + config + "-ay rl_d-2",
+		"vgchange " + config + "-an rl_d-2",
+	}, r.calls)
+}
+
+func TestLayoutGrowWholeDisk(t *testing.T) {
+	l, r, expanded := testingLayoutManager(t)
+	setupLVMDevice(t, l.dev, "vdb3")
+	root := filepath.Join(l.dev.DevicePath, "vdb")
+
+	require.NoError(t, l.grow(t.Context(), root, root))
+	assert.Empty(t, *expanded)
+	assert.Empty(t, r.calls)
+}
@@ -0,0 +1,398 @@
+// Package lvm manages LVM thin pools and thin volumes with the lvm2 command line tools.
+package lvm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	utilsos "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils/os"
+	"k8s.io/klog/v2"
+)
+
+// ErrNotFound is returned if the volume group or logical volume does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Runner runs a command and returns its stdout.
+type Runner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+func execRunner(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return out, fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), utilsos.ErrWithStderr(err))
+	}
+	return out, nil
+}
+
+// LogicalVolume is a line of lvs report.
+type LogicalVolume struct {
+	Name string
+	// Size in bytes. For thin volumes, it is the virtual size.
+	Size int64
+	// Pool is the thin pool of a thin volume.
+	Pool string
+	// Origin is the source of a snapshot.
+	Origin string
+	// DataPercent is the used percentage of a thin pool or a thin volume.
+	DataPercent float64
+	// MetadataPercent is the used percentage of the metadata of a thin pool.
+	MetadataPercent float64
+	// MetadataSize is the size of the metadata of a thin pool in bytes.
+	MetadataSize int64
+	Tags         []string
+}
+
+// VolumeGroup is a volume group with a thin pool on a single physical volume.
+type VolumeGroup struct {
+	Name string
+	Pool string
+	run  Runner
+}
+
+// New returns a VolumeGroup named name, with a thin pool named pool.
+func New(name, pool string) *VolumeGroup {
+	return &VolumeGroup{Name: name, Pool: pool, run: execRunner}
+}
+
+// NewWithRunner is like New, but runs commands with run.
+func NewWithRunner(name, pool string, run Runner) *VolumeGroup {
+	return &VolumeGroup{Name: name, Pool: pool, run: run}
+}
+
+// OnDevices returns the volume group with commands only scanning devices for physical volumes,
+// so that a volume group of the same name on other disks, e.g. cloned from the same image, is ignored.
+func (vg *VolumeGroup) OnDevices(devices ...string) *VolumeGroup {
+	config := devicesConfig(devices)
+	run := vg.run
+	return &VolumeGroup{
+		Name: vg.Name,
+		Pool: vg.Pool,
+		run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return run(ctx, name, append([]string{"--config", config}, args...)...)
+		},
+	}
+}
+
+// devicesConfig returns the lvm.conf devices section accepting only devices.
+// The devices file is disabled, since it lists the devices of the host only.
+func devicesConfig(devices []string) string {
+	filter := make([]string, 0, len(devices)+1)
+	for _, d := range devices {
+		filter = append(filter, fmt.Sprintf(`"a|^%s$|"`, regexp.QuoteMeta(d)))
+	}
+	filter = append(filter, `"r|.*|"`)
+	return fmt.Sprintf("devices { use_devicesfile=0 filter=[%s] }", strings.Join(filter, ","))
+}
+
+// DevicePath returns the device path of the logical volume.
+func (vg *VolumeGroup) DevicePath(lv string) string {
+	return filepath.Join("/dev", vg.Name, lv)
+}
+
+func (vg *VolumeGroup) path(lv string) string {
+	return vg.Name + "/" + lv
+}
+
+// PhysicalVolume returns the device of the physical volume in the volume group.
+func (vg *VolumeGroup) PhysicalVolume(ctx context.Context) (string, error) {
+	pvs, err := vg.PhysicalVolumes(ctx)
+	if err != nil {
+		return "", err
+	}
+	return pvs[0], nil
+}
+
+// PhysicalVolumes returns the devices of all physical volumes in the volume group.
+func (vg *VolumeGroup) PhysicalVolumes(ctx context.Context) ([]string, error) {
+	out, err := vg.run(ctx, "pvs", "--noheadings", "-o", "pv_name", "--select", "vg_name="+vg.Name)
+	if err != nil {
+		return nil, err
+	}
+	pvs := strings.Fields(string(out))
+	if len(pvs) == 0 {
+		return nil, fmt.Errorf("volume group %s: %w", vg.Name, ErrNotFound)
+	}
+	return pvs, nil
+}
+
+// ImportClone renames the volume group to name, and changes the UUIDs of it and its physical volumes with vgimportclone,
+// so that it can be activated along with the volume group it is cloned from, e.g. on another disk restored from the same image.
+func (vg *VolumeGroup) ImportClone(ctx context.Context, name string) (*VolumeGroup, error) {
+	pvs, err := vg.PhysicalVolumes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := vg.run(ctx, "vgimportclone", append([]string{"--basevgname", name}, pvs...)...); err != nil {
+		return nil, err
+	}
+	klog.FromContext(ctx).V(2).Info("imported cloned volume group", "vg", vg.Name, "newName", name, "pvs", pvs)
+	return &VolumeGroup{Name: name, Pool: vg.Pool, run: vg.run}, nil
+}
+
+// Exists checks whether the volume group is present on this node.
+func (vg *VolumeGroup) Exists(ctx context.Context) (bool, error) {
+	out, err := vg.run(ctx, "vgs", "--noheadings", "-o", "vg_name", "--select", "vg_name="+vg.Name)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(out)) == vg.Name, nil
+}
+
+// Create initializes device as the only physical volume of the volume group,
+// and creates a thin pool on it.
+func (vg *VolumeGroup) Create(ctx context.Context, device string) error {
+	logger := klog.FromContext(ctx)
+	if _, err := vg.run(ctx, "pvcreate", "-y", device); err != nil {
+		return err
+	}
+	if _, err := vg.run(ctx, "vgcreate", vg.Name, device); err != nil {
+		return err
+	}
+	// Leave some extents for the spare metadata volume and metadata growth.
+	if _, err := vg.run(ctx, "lvcreate", "-y", "--type", "thin-pool", "-l", "95%FREE", "-n", vg.Pool, vg.Name); err != nil {
+		return err
+	}
+	logger.V(2).Info("created thin pool", "device", device, "vg", vg.Name, "pool", vg.Pool)
+	return nil
+}
+
+// Grow extends the physical volume to the size of its device, and the thin pool to use the new space.
+// The metadata of the pool is extended by metadataSize bytes first, if not zero,
+// since extending the data of a thin pool does not extend its metadata.
+func (vg *VolumeGroup) Grow(ctx context.Context, metadataSize int64) error {
+	pv, err := vg.PhysicalVolume(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := vg.run(ctx, "pvresize", pv); err != nil {
+		return err
+	}
+	if metadataSize > 0 {
+		if _, err := vg.run(ctx, "lvextend", "--poolmetadatasize", "+"+sizeArg(metadataSize), vg.path(vg.Pool)); err != nil {
+			return err
+		}
+	}
+	_, err = vg.run(ctx, "lvextend", "-l", "+95%FREE", vg.path(vg.Pool))
+	return err
+}
+
+// Activate activates all logical volumes in the volume group.
+func (vg *VolumeGroup) Activate(ctx context.Context) error {
+	_, err := vg.run(ctx, "vgchange", "-ay", vg.Name)
+	return err
+}
+
+// Deactivate deactivates all logical volumes in the volume group, so that its physical volumes can be removed.
+func (vg *VolumeGroup) Deactivate(ctx context.Context) error {
+	_, err := vg.run(ctx, "vgchange", "-an", vg.Name)
+	return err
+}
+
+// Free returns the unallocated space of the volume group in bytes.
+func (vg *VolumeGroup) Free(ctx context.Context) (int64, error) {
+	out, err := vg.run(ctx, "vgs", "--noheadings", "--units", "b", "--nosuffix", "-o", "vg_free", vg.Name)
+	if err != nil {
+		return 0, err
+	}
+	free, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid free space of volume group %s: %w", vg.Name, err)
+	}
+	return free, nil
+}
+
+// ResizePhysicalVolume extends the physical volume pv to the size of its device.
+func (vg *VolumeGroup) ResizePhysicalVolume(ctx context.Context, pv string) error {
+	_, err := vg.run(ctx, "pvresize", pv)
+	return err
+}
+
+// ExtendToFree extends the logical volume to use all the free space in the volume group.
+// It is a no-op if there is no free space.
+func (vg *VolumeGroup) ExtendToFree(ctx context.Context, name string) error {
+	free, err := vg.Free(ctx)
+	if err != nil {
+		return err
+	}
+	if free == 0 {
+		return nil
+	}
+	_, err = vg.run(ctx, "lvextend", "-l", "+100%FREE", vg.path(name))
+	return err
+}
+
+// List returns all logical volumes in the volume group, including the thin pool.
+func (vg *VolumeGroup) List(ctx context.Context) ([]LogicalVolume, error) {
+	out, err := vg.run(ctx, "lvs", "--reportformat", "json", "--units", "b", "--nosuffix",
+		"-o", "lv_name,lv_size,pool_lv,origin,data_percent,metadata_percent,lv_metadata_size,lv_tags", vg.Name)
+	if err != nil {
+		return nil, err
+	}
+	return parseLVs(out)
+}
+
+// Get returns the logical volume named name.
+func (vg *VolumeGroup) Get(ctx context.Context, name string) (*LogicalVolume, error) {
+	lvs, err := vg.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lvs {
+		if lvs[i].Name == name {
+			return &lvs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("logical volume %s: %w", vg.path(name), ErrNotFound)
+}
+
+// CreateThin creates a thin volume of size bytes in the thin pool.
+func (vg *VolumeGroup) CreateThin(ctx context.Context, name string, size int64, tags ...string) error {
+	args := []string{"-y", "--thin", "-V", sizeArg(size), "-n", name}
+	for _, t := range tags {
+		args = append(args, "--addtag", t)
+	}
+	_, err := vg.run(ctx, "lvcreate", append(args, vg.path(vg.Pool))...)
+	return err
+}
+
+// CreateSnapshot creates a thin snapshot of source.
+// The snapshot is not activated, use Restore to create a writable volume from it.
+func (vg *VolumeGroup) CreateSnapshot(ctx context.Context, name, source string, tags ...string) error {
+	args := []string{"-y", "--snapshot", "-n", name}
+	for _, t := range tags {
+		args = append(args, "--addtag", t)
+	}
+	_, err := vg.run(ctx, "lvcreate", append(args, vg.path(source))...)
+	return err
+}
+
+// Restore creates a thin volume from the snapshot, and extends it to size bytes if it is larger than the snapshot.
+func (vg *VolumeGroup) Restore(ctx context.Context, name, snapshot string, size int64, tags ...string) error {
+	args := []string{"-y", "--snapshot", "--setactivationskip", "n", "-n", name}
+	for _, t := range tags {
+		args = append(args, "--addtag", t)
+	}
+	if _, err := vg.run(ctx, "lvcreate", append(args, vg.path(snapshot))...); err != nil {
+		return err
+	}
+	if _, err := vg.run(ctx, "lvchange", "-ay", vg.path(name)); err != nil {
+		return err
+	}
+	return vg.Extend(ctx, name, size)
+}
+
+// Extend extends the logical volume to size bytes. It is a no-op if the volume is already large enough.
+func (vg *VolumeGroup) Extend(ctx context.Context, name string, size int64) error {
+	lv, err := vg.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if lv.Size >= size {
+		return nil
+	}
+	_, err = vg.run(ctx, "lvextend", "-L", sizeArg(size), vg.path(name))
+	return err
+}
+
+// Remove removes the logical volume. It is a no-op if the volume does not exist.
+func (vg *VolumeGroup) Remove(ctx context.Context, name string) error {
+	_, err := vg.Get(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = vg.run(ctx, "lvremove", "-y", vg.path(name))
+	return err
+}
+
+// SplitDMName returns the volume group and logical volume of a device-mapper name created by LVM.
+// Hyphens in the names are doubled by LVM, e.g. "vg--1-lv" is "vg-1" and "lv".
+// The logical volume may have a suffix of the internal layer, e.g. "pool-tpool".
+func SplitDMName(name string) (vg, lv string, ok bool) {
+	for i := 0; i < len(name); i++ {
+		if name[i] != '-' {
+			continue
+		}
+		if i+1 < len(name) && name[i+1] == '-' {
+			i++
+			continue
+		}
+		vg = strings.ReplaceAll(name[:i], "--", "-")
+		lv = strings.ReplaceAll(name[i+1:], "--", "-")
+		return vg, lv, vg != "" && lv != ""
+	}
+	return "", "", false
+}
+
+func sizeArg(size int64) string {
+	return strconv.FormatInt(size, 10) + "b"
+}
+
+type lvsReport struct {
+	Report []struct {
+		LV []struct {
+			Name            string `json:"lv_name"`
+			Size            string `json:"lv_size"`
+			Pool            string `json:"pool_lv"`
+			Origin          string `json:"origin"`
+			DataPercent     string `json:"data_percent"`
+			MetadataPercent string `json:"metadata_percent"`
+			MetadataSize    string `json:"lv_metadata_size"`
+			Tags            string `json:"lv_tags"`
+		} `json:"lv"`
+	} `json:"report"`
+}
+
+func parseLVs(out []byte) ([]LogicalVolume, error) {
+	var report lvsReport
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse lvs report: %w", err)
+	}
+	var lvs []LogicalVolume
+	for _, r := range report.Report {
+		for _, l := range r.LV {
+			size, err := strconv.ParseInt(l.Size, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid size of %s: %w", l.Name, err)
+			}
+			lv := LogicalVolume{
+				Name:            l.Name,
+				Size:            size,
+				Pool:            l.Pool,
+				Origin:          l.Origin,
+				DataPercent:     parsePercent(l.DataPercent),
+				MetadataPercent: parsePercent(l.MetadataPercent),
+			}
+			if l.MetadataSize != "" {
+				if lv.MetadataSize, err = strconv.ParseInt(l.MetadataSize, 10, 64); err != nil {
+					return nil, fmt.Errorf("invalid metadata size of %s: %w", l.Name, err)
+				}
+			}
+			if l.Tags != "" {
+				lv.Tags = strings.Split(l.Tags, ",")
+			}
+			lvs = append(lvs, lv)
+		}
+	}
+	return lvs, nil
+}
+
+// parsePercent returns -1 if s is empty, e.g. data_percent of an inactive volume.
+func parsePercent(s string) float64 {
+	if s == "" {
+		return -1
+	}
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return -1
+	}
+	return p
+}
@@ -0,0 +1,198 @@
+package lvm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testReport = `{
+  "report": [
+    {
+      "lv": [
+        {"lv_name":"pool", "lv_size":"107374182400", "pool_lv":"", "origin":"", "data_percent":"12.50", "metadata_percent":"1.02", "lv_metadata_size":"104857600", "lv_tags":""},
+        {"lv_name":"pvc-a", "lv_size":"5368709120", "pool_lv":"pool", "origin":"", "data_percent":"40.00", "metadata_percent":"", "lv_metadata_size":"", "lv_tags":"csi,pv=pvc-a"},
+        {"lv_name":"snapshot-a", "lv_size":"5368709120", "pool_lv":"pool", "origin":"pvc-a", "data_percent":"", "metadata_percent":"", "lv_metadata_size":"", "lv_tags":""}
+      ]
+    }
+  ]
+}`
+
+type fakeRunner struct {
+	outputs map[string]string
+	calls   []string
+}
+
+func (f *fakeRunner) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := strings.Join(append([]string{name}, args...), " ")
+	f.calls = append(f.calls, cmd)
+	for prefix, out := range f.outputs {
+		if strings.HasPrefix(cmd, prefix) {
+			return []byte(out), nil
+		}
+	}
+	if name == "lvs" || name == "pvs" || name == "vgs" {
+		return nil, fmt.Errorf("unexpected command %q", cmd)
+	}
+	return nil, nil
+}
+
+func TestParseLVs(t *testing.T) {
+	lvs, err := parseLVs([]byte(testReport))
+	require.NoError(t, err)
+	require.Len(t, lvs, 3)
+
+	assert.Equal(t, LogicalVolume{
+		Name: "pool", Size: 100 << 30, DataPercent: 12.5, MetadataPercent: 1.02, MetadataSize: 100 << 20,
+	}, lvs[0])
+	assert.Equal(t, "pool", lvs[1].Pool)
+	assert.Equal(t, []string{"csi", "pv=pvc-a"}, lvs[1].Tags)
+	assert.Equal(t, -1.0, lvs[1].MetadataPercent)
+	assert.Equal(t, "pvc-a", lvs[2].Origin)
+	assert.Equal(t, -1.0, lvs[2].DataPercent)
+}
+
+func TestParseLVsInvalid(t *testing.T) {
+	_, err := parseLVs([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestCreate(t *testing.T) {
+	r := &fakeRunner{}
+	vg := NewWithRunner("vg", "pool", r.run)
+	require.NoError(t, vg.Create(t.Context(), "/dev/vdb"))
+	assert.Equal(t, []string{
+		"pvcreate -y /dev/vdb",
+		"vgcreate vg /dev/vdb",
+		"lvcreate -y --type thin-pool -l 95%FREE -n pool vg",
+	}, r.calls)
+}
+
+func TestGrow(t *testing.T) {
+	r := &fakeRunner{outputs: map[string]string{"pvs": "  /dev/vdb\n"}}
+	vg := NewWithRunner("vg", "pool", r.run)
+	require.NoError(t, vg.Grow(t.Context(), 0))
+	assert.Equal(t, []string{
+		"pvs --noheadings -o pv_name --select vg_name=vg",
+		"pvresize /dev/vdb",
+		"lvextend -l +95%FREE vg/pool",
+	}, r.calls)
+}
+
+func TestGrowMetadata(t *testing.T) {
+	r := &fakeRunner{outputs: map[string]string{"pvs": "  /dev/vdb\n"}}
+	vg := NewWithRunner("vg", "pool", r.run)
+	require.NoError(t, vg.Grow(t.Context(), 100<<20))
+	assert.Equal(t, []string{
+		"pvs --noheadings -o pv_name --select vg_name=vg",
+		"pvresize /dev/vdb",
+		"lvextend --poolmetadatasize +104857600b vg/pool",
+		"lvextend -l +95%FREE vg/pool",
+	}, r.calls)
+}
+
+func TestOnDevices(t *testing.T) {
+	r := &fakeRunner{}
+	vg := NewWithRunner("vg", "", r.run).OnDevices("/dev/vdb", "/dev/vdb1")
+	require.NoError(t, vg.Activate(t.Context()))
+	assert.Equal(t, []string{
+		`vgchange --config devices { use_devicesfile=0 filter=["a|^/dev/vdb$|","a|^/dev/vdb1$|","r|.*|"] } -ay vg`,
+	}, r.calls)
+}
+
+func TestImportClone(t *testing.T) {
+	r := &fakeRunner{outputs: map[string]string{"pvs": "  /dev/vdb2\n  /dev/vdb3\n"}}
+	vg, err := NewWithRunner("rl", "", r.run).ImportClone(t.Context(), "rl_d-1")
+	require.NoError(t, err)
+	assert.Equal(t, "rl_d-1", vg.Name)
+	require.NoError(t, vg.Activate(t.Context()))
+	assert.Equal(t, []string{
+		"pvs --noheadings -o pv_name --select vg_name=rl",
+		"vgimportclone --basevgname rl_d-1 /dev/vdb2 /dev/vdb3",
+		"vgchange -ay rl_d-1",
+	}, r.calls)
+}
+
+func TestCreateThin(t *testing.T) {
+	r := &fakeRunner{}
+	vg := NewWithRunner("vg", "pool", r.run)
+	require.NoError(t, vg.CreateThin(t.Context(), "pvc-b", 1<<30, "csi"))
+	assert.Equal(t, []string{"lvcreate -y --thin -V 1073741824b -n pvc-b --addtag csi vg/pool"}, r.calls)
+	assert.Equal(t, "/dev/vg/pvc-b", vg.DevicePath("pvc-b"))
+}
+
+func TestRestore(t *testing.T) {
+	r := &fakeRunner{outputs: map[string]string{"lvs": `{"report":[{"lv":[{"lv_name":"pvc-c","lv_size":"5368709120"}]}]}`}}
+	vg := NewWithRunner("vg", "pool", r.run)
+	require.NoError(t, vg.Restore(t.Context(), "pvc-c", "snapshot-a", 10<<30))
+	assert.Equal(t, []string{
+		"lvcreate -y --snapshot --setactivationskip n -n pvc-c vg/snapshot-a",
+		"lvchange -ay vg/pvc-c",
+		"lvs --reportformat json --units b --nosuffix -o lv_name,lv_size,pool_lv,origin,data_percent,metadata_percent,lv_metadata_size,lv_tags vg",
+		"lvextend -L 10737418240b vg/pvc-c",
+	}, r.calls)
+}
+
+func TestExtendNoop(t *testing.T) {
+	r := &fakeRunner{outputs: map[string]string{"lvs": testReport}}
+	vg := NewWithRunner("vg", "pool", r.run)
+	require.NoError(t, vg.Extend(t.Context(), "pvc-a", 1<<30))
+	assert.Len(t, r.calls, 1)
+}
+
+func TestRemove(t *testing.T) {
+	r := &fakeRunner{outputs: map[string]string{"lvs": testReport}}
+	vg := NewWithRunner("vg", "pool", r.run)
+	require.NoError(t, vg.Remove(t.Context(), "pvc-a"))
+	require.NoError(t, vg.Remove(t.Context(), "pvc-gone"))
+	assert.Equal(t, "lvremove -y vg/pvc-a", r.calls[1])
+	assert.Len(t, r.calls, 3)
+}
+
+func TestGetNotFound(t *testing.T) {
+	r := &fakeRunner{outputs: map[string]string{"lvs": testReport}}
+	vg := NewWithRunner("vg", "pool", r.run)
+	_, err := vg.Get(t.Context(), "pvc-x")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestExtendToFree(t *testing.T) {
+	r := &fakeRunner{outputs: map[string]string{"vgs": "  4194304\n"}}
+	vg := NewWithRunner("rl", "", r.run)
+	require.NoError(t, vg.ResizePhysicalVolume(t.Context(), "/dev/vdb3"))
+	require.NoError(t, vg.ExtendToFree(t.Context(), "root"))
+	assert.Equal(t, []string{
+		"pvresize /dev/vdb3",
+		"vgs --noheadings --units b --nosuffix -o vg_free rl",
+		"lvextend -l +100%FREE rl/root",
+	}, r.calls)
+
+	r = &fakeRunner{outputs: map[string]string{"vgs": "  0\n"}}
+	vg = NewWithRunner("rl", "", r.run)
+	require.NoError(t, vg.ExtendToFree(t.Context(), "root"))
+	assert.Len(t, r.calls, 1)
+}
+
+func TestSplitDMName(t *testing.T) {
+	for _, c := range []struct {
+		name, vg, lv string
+		ok           bool
+	}{
+		{"rl-root", "rl", "root", true},
+		{"vg--1-lv--a", "vg-1", "lv-a", true},
+		{"vg-pool-tpool", "vg", "pool-tpool", true},
+		{"crypt", "", "", false},
+		{"vg--", "", "", false},
+	} {
+		vg, lv, ok := SplitDMName(c.name)
+		assert.Equal(t, c.ok, ok, c.name)
+		if c.ok {
+			assert.Equal(t, c.vg, vg, c.name)
+			assert.Equal(t, c.lv, lv, c.name)
+		}
+	}
+}
@@ -31,17 +31,23 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/health"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/mounter"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/sfdisk"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/features"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/metric"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/tracing"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
 	utilsio "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils/io"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils/rund/directvolume"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sys/unix"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -66,6 +72,10 @@ type nodeServer struct {
 	clientSet    *kubernetes.Clientset
 	ad           DiskAttachDetach
 	locks        *utils.VolumeLocks
+	// thinPool is nil unless DiskThinPool feature gate is enabled
+	thinPool *thinPool
+	repair   *diskRepairer
+	layout   *layoutManager
 	common.GenericNodeServer
 }
 
@@ -162,7 +172,7 @@ func parseVolumeCountEnv() (int, error) {
 }
 
 // NewNodeServer creates node server
-func NewNodeServer(ecs cloud.ECSInterface, m metadata.MetadataProvider) csi.NodeServer {
+func NewNodeServer(ctx context.Context, ecs cloud.ECSInterface, m metadata.MetadataProvider) csi.NodeServer {
 	// Create Directory
 	err := os.MkdirAll(RundSocketDir, os.FileMode(0755))
 	if err != nil {
@@ -203,8 +213,8 @@ func NewNodeServer(ecs cloud.ECSInterface, m metadata.MetadataProvider) csi.Node
 		klog.Fatalf("failed to list devices: %v", err)
 	}
 
-	waiter, batcher := newBatcher(true)
-	return &nodeServer{
+	waiter, batcher := newBatcher(GlobalConfigVar.EcsClient, true)
+	ns := &nodeServer{
 		metadata:     m,
 		mounter:      utils.NewMounter(),
 		kataBMIOType: kataBMIOType,
@@ -219,17 +229,35 @@ func NewNodeServer(ecs cloud.ECSInterface, m metadata.MetadataProvider) csi.Node
 			// if ADController is not enabled, we need serial attach to recognize old disk
 			slots: NewSlots(1, 1),
 
-			attachThrottler: defaultThrottler(),
-			detachThrottler: defaultThrottler(),
+			attachThrottler: limitedThrottler(cloudIdentity{}, "AttachDisk", throttle.PriorityNormal),
+			detachThrottler: limitedThrottler(cloudIdentity{}, "DetachDisk", throttle.PriorityHigh),
 
 			dev:    DefaultDeviceManager,
 			devMap: devMap,
 		},
 		locks: utils.NewVolumeLocks(),
+		layout: &layoutManager{
+			dev:             DefaultDeviceManager,
+			newVG:           newVolumeGroup,
+			expandPartition: sfdisk.ExpandPartition,
+		},
 		GenericNodeServer: common.GenericNodeServer{
 			NodeID: GlobalConfigVar.NodeID,
 		},
 	}
+	if features.FunctionalMutableFeatureGate.Enabled(features.DiskRepair) && GlobalConfigVar.ClientSet != nil {
+		ns.repair = newDiskRepairer(ctx, GlobalConfigVar.ClientSet, utils.NewEventRecorder(),
+			(&k8smount.SafeFormatAndMount{Exec: utilexec.New()}).GetDiskFormat, runCommand)
+	}
+	if features.FunctionalMutableFeatureGate.Enabled(features.DiskThinPool) {
+		ns.thinPool = newThinPool(GlobalConfigVar.ThinPool, ecs, &ns.ad, GlobalConfigVar.ClientSet,
+			os.Getenv(kubeNodeName), GlobalConfigVar.NodeID, metadata.MustGet(m, metadata.ZoneID))
+		go ns.thinPool.run(ctx)
+	}
+	if features.FunctionalMutableFeatureGate.Enabled(features.DiskIOPSAutotune) && GlobalConfigVar.ClientSet != nil {
+		go metric.RunDiskIOObserver(ctx, GlobalConfigVar.ClientSet, os.Getenv(kubeNodeName), observeDiskIO)
+	}
+	return ns
 }
 
 func (ns *nodeServer) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
@@ -256,10 +284,18 @@ func (ns *nodeServer) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetC
 		},
 	}
 
-	// Disk Metric enable config
-	nodeSvcCap := []*csi.NodeServiceCapability{nscap, nscap2}
+	nscap4 := &csi.NodeServiceCapability{
+		Type: &csi.NodeServiceCapability_Rpc{
+			Rpc: &csi.NodeServiceCapability_RPC{
+				Type: csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+			},
+		},
+	}
+
+	// Disk Metric enable config, the volume condition is reported anyway
+	nodeSvcCap := []*csi.NodeServiceCapability{nscap, nscap2, nscap4}
 	if GlobalConfigVar.MetricEnable {
-		nodeSvcCap = []*csi.NodeServiceCapability{nscap, nscap2, nscap3}
+		nodeSvcCap = append(nodeSvcCap, nscap3)
 	}
 
 	return &csi.NodeGetCapabilitiesResponse{
@@ -267,6 +303,57 @@ func (ns *nodeServer) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetC
 	}, nil
 }
 
+// NodeGetVolumeStats reports the usage of the volume if MetricEnable, and its condition from the device health.
+func (ns *nodeServer) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
+	resp := &csi.NodeGetVolumeStatsResponse{}
+	if GlobalConfigVar.MetricEnable {
+		var err error
+		resp, err = ns.GenericNodeServer.NodeGetVolumeStats(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	} else if _, err := os.Stat(req.VolumePath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, status.Errorf(codes.NotFound, "VolumePath %s not found: %v", req.VolumePath, err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to stat VolumePath %s: %v", req.VolumePath, err)
+	}
+	if !isThinVolumeID(req.VolumeId) {
+		resp.VolumeCondition = volumeCondition(klog.FromContext(ctx), req.VolumeId)
+	}
+	return resp, nil
+}
+
+// volumeCondition returns nil if the device of the volume has no health telemetry.
+func volumeCondition(logger klog.Logger, volumeID string) *csi.VolumeCondition {
+	// health is read from the disk, whichever partition or logical volume is staged
+	device, err := DefaultDeviceManager.GetRootBlockBySerial(strings.TrimPrefix(volumeID, "d-"))
+	if err != nil {
+		logger.V(2).Info("device not found for volume condition", "err", err)
+		return nil
+	}
+	major, minor, err := DefaultDeviceManager.DevTmpFS.DevFor(device)
+	if err != nil {
+		logger.V(2).Info("failed to get device number for volume condition", "device", device, "err", err)
+		return nil
+	}
+	h, err := health.DefaultReader.Read(unix.Mkdev(major, minor))
+	if err != nil {
+		if !errors.Is(err, health.ErrNotSupported) {
+			logger.Error(err, "failed to read device health", "device", device)
+		}
+		return nil
+	}
+	problems := h.Problems()
+	if len(problems) == 0 {
+		return &csi.VolumeCondition{Message: "device is healthy"}
+	}
+	return &csi.VolumeCondition{
+		Abnormal: true,
+		Message:  fmt.Sprintf("device %s is unhealthy: %s", device, strings.Join(problems, ", ")),
+	}
+}
+
 // csi disk driver: bind directory from global to pod.
 func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
 	logger := klog.FromContext(ctx)
@@ -366,9 +453,9 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 	if sourceNotMounted {
-		device, err := DefaultDeviceManager.GetDeviceByVolumeID(req.GetVolumeId())
+		device, err := ns.volumeDevice(ctx, req.GetVolumeId(), req.VolumeContext)
 		if err == nil {
-			if err := ns.mountDeviceToGlobal(logger, req.VolumeCapability, req.VolumeContext, device, sourcePath); err != nil {
+			if err := ns.mountDeviceToGlobal(ctx, req.VolumeCapability, req.VolumeContext, device, sourcePath); err != nil {
 				return nil, status.Errorf(codes.Internal, "remount disk to sourcePath %s: %v", sourcePath, err)
 			}
 			logger.V(2).Info("SourcePath not mounted, remounted with device", "source", sourcePath, "device", device)
@@ -378,7 +465,7 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	}
 
 	// check device name available
-	expectName, err := ns.ad.GetVolumeDeviceName(logger, req.VolumeId)
+	expectName, err := ns.volumeDevice(ctx, req.VolumeId, req.VolumeContext)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "get device name: %v", err)
 	}
@@ -424,7 +511,12 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	}
 
 	logger.V(2).Info("Starting mount", "options", options, "fsType", fsType)
-	if err = ns.k8smounter.Mount(sourcePath, targetPath, fsType, options); err != nil {
+	_, span := tracing.Start(ctx, "Mount", attribute.String("source", sourcePath))
+	publishStart := time.Now()
+	err = ns.k8smounter.Mount(sourcePath, targetPath, fsType, options)
+	metric.ObserveVolumePhase(driverType, req.VolumeContext["type"], metric.PhasePublish, publishStart, err)
+	tracing.End(span, err)
+	if err != nil {
 		return nil, status.Errorf(codes.Internal, "mount %s to %s: %v", sourcePath, targetPath, err)
 	}
 
@@ -536,6 +628,14 @@ func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 	if mounted {
 		return &csi.NodeStageVolumeResponse{}, nil
 	}
+	if isThinVolumeID(req.VolumeId) {
+		return ns.stageThinVolume(ctx, req, targetPath)
+	}
+
+	layout, err := getVolumeLayout(req.VolumeContext)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 
 	isMultiAttach := false
 	if value, ok := req.VolumeContext[MultiAttach]; ok {
@@ -554,7 +654,9 @@ func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 			// for capability with old controller
 			serial = strings.TrimPrefix(req.VolumeId, "d-")
 		}
+		discoverStart := time.Now()
 		device, err = ns.ad.findDevice(ctx, req.VolumeId, serial, nil)
+		metric.ObserveVolumePhase(driverType, req.VolumeContext["type"], metric.PhaseDeviceDiscovery, discoverStart, err)
 		if err != nil {
 			if GlobalConfigVar.ADControllerEnable || isMultiAttach {
 				return nil, status.Errorf(defaultErrCode, "ADController Enabled, but disk can't be found: %v", err)
@@ -576,9 +678,21 @@ func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 	}
 
 	if req.VolumeCapability.GetMount() != nil {
-		device, err = DefaultDeviceManager.adaptDevicePartition(device)
-		if err != nil {
-			return nil, status.Errorf(codes.Aborted, "failed to adapt partition %s: %v", device, err)
+		if layout != nil {
+			root := device
+			device, err = ns.layout.resolve(ctx, req.VolumeId, root, layout)
+			if err != nil {
+				return nil, status.Errorf(codes.Aborted, "failed to find selected device on %s: %v", root, err)
+			}
+			// restored from a smaller image or snapshot
+			if err := ns.layout.grow(ctx, root, device); err != nil {
+				return nil, status.Errorf(codes.Aborted, "failed to grow %s: %v", device, err)
+			}
+		} else {
+			device, err = DefaultDeviceManager.adaptDevicePartition(device)
+			if err != nil {
+				return nil, status.Errorf(codes.Aborted, "failed to adapt partition %s: %v", device, err)
+			}
 		}
 	}
 
@@ -591,6 +705,17 @@ func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	profile, err := getFsProfile(req.VolumeContext)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if profile != nil && len(profile.SysConfigs) > 0 {
+		major, minor, err := DefaultDeviceManager.DevTmpFS.DevFor(device)
+		if err != nil {
+			return nil, status.Errorf(defaultErrCode, "failed to get device number: %v", err)
+		}
+		profile.applySysConfigs(logger, major, minor)
+	}
 	// set sysConfigs
 	if len(sysConfigs) > 0 {
 		major, minor, err := DefaultDeviceManager.DevTmpFS.DevFor(device)
@@ -607,6 +732,10 @@ func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 
 	err = ns.setupDisk(ctx, device, targetPath, req)
 	if err != nil {
+		// e.g. Aborted while the filesystem is being repaired
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
 		return nil, status.Error(defaultErrCode, err.Error())
 	}
 	return &csi.NodeStageVolumeResponse{}, nil
@@ -639,13 +768,31 @@ func (ns *nodeServer) setupDisk(ctx context.Context, device, targetPath string,
 		return nil
 	}
 
+	if ns.repair != nil {
+		if err := ns.repair.repairIfRequested(ctx, req.GetVolumeId(), device); err != nil {
+			return err
+		}
+	}
+
 	// Step 5 Start to format
+	profile, err := getFsProfile(volumeContext)
+	if err != nil {
+		return err
+	}
 	mnt := req.GetVolumeCapability().GetMount()
 	options := append(mnt.MountFlags, "shared")
 	fsType := "ext4"
 	if mnt.FsType != "" {
 		fsType = mnt.FsType
 	}
+	if profile != nil {
+		// e.g. a static PV with a different fsType
+		if err := profile.checkFsType(volumeContext[FsProfileTag], mnt.FsType); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		fsType = profile.FsType
+		options = append(slices.Clone(profile.MountOptions), options...)
+	}
 	mountOptions := collectMountOptions(fsType, options)
 	if err := os.MkdirAll(targetPath, 0755); err != nil {
 		return err
@@ -653,21 +800,38 @@ func (ns *nodeServer) setupDisk(ctx context.Context, device, targetPath string,
 
 	// Set mkfs options for ext3, ext4
 	mkfsOptions := make([]string, 0)
+	if profile != nil {
+		mkfsOptions = profile.mkfsOptions(logger, DefaultDeviceManager, device)
+	}
 	if value, ok := volumeContext[MkfsOptions]; ok {
-		mkfsOptions = strings.Split(value, " ")
+		mkfsOptions = append(mkfsOptions, strings.Split(value, " ")...)
 	}
 
 	volumeId := req.GetVolumeId()
 	// do format-mount or mount
 	diskMounter := &k8smount.SafeFormatAndMount{Interface: ns.k8smounter, Exec: utilexec.New()}
-	if err := utils.FormatAndMount(diskMounter, device, targetPath, fsType, mkfsOptions, mountOptions, omitfsck); err != nil {
+	category := volumeContext["type"]
+	_, span := tracing.Start(ctx, "FormatAndMount", attribute.String("device", device), attribute.String("fsType", fsType))
+	formatStart := time.Now()
+	err = utils.FormatAndMount(diskMounter, device, targetPath, fsType, mkfsOptions, mountOptions, omitfsck)
+	metric.ObserveVolumePhase(driverType, category, metric.PhaseFormat, formatStart, err)
+	tracing.End(span, err)
+	if err != nil {
 		return fmt.Errorf("FormatAndMount fail with mkfsOptions %s, %s, %s, %s, %s with error: %w", device, targetPath, fsType, mkfsOptions, mountOptions, err)
 	}
+	if profile != nil {
+		recordFsProfile(logger, targetPath, volumeContext[FsProfileTag], profile, mkfsOptions, mountOptions)
+	}
 	logger.V(2).Info("mount successful", "target", targetPath, "device", device, "mkfsOptions", mkfsOptions, "options", mountOptions)
 
 	r := k8smount.NewResizeFs(diskMounter.Exec)
 	logger.V(3).Info("resizing volume")
-	if _, err := r.Resize(device, targetPath); err != nil {
+	_, span = tracing.Start(ctx, "ResizeFs", attribute.String("device", device))
+	resizeStart := time.Now()
+	_, err = r.Resize(device, targetPath)
+	metric.ObserveVolumePhase(driverType, category, metric.PhaseResize, resizeStart, err)
+	tracing.End(span, err)
+	if err != nil {
 		return fmt.Errorf("could not resize volume %s: %w", volumeId, err)
 	}
 	return nil
@@ -730,6 +894,11 @@ func (ns *nodeServer) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstag
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	removeFsProfileRecord(logger, req.StagingTargetPath)
+	if isThinVolumeID(req.VolumeId) {
+		// the logical volume is kept until the PV is deleted
+		return &csi.NodeUnstageVolumeResponse{}, nil
+	}
 
 	if IsVFNode() {
 		if err := unbindBdfDisk(req.VolumeId); err != nil {
@@ -762,6 +931,12 @@ func (ns *nodeServer) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstag
 		if err != nil {
 			logger.Error(err, "setDiskXattr failed")
 		}
+		// Unlike other device errors, the disk should not be detached with logical volumes still active on it.
+		if ns.layout != nil {
+			if err := ns.layout.deactivate(ctx, device); err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to deactivate LVM on %s: %v", device, err)
+			}
+		}
 	}
 
 	if GlobalConfigVar.ADControllerEnable {
@@ -877,6 +1052,10 @@ func (ns *nodeServer) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoReque
 	if maxVolumesNum == 0 {
 		segments = map[string]string{}
 	}
+	// Thin volumes are counted by thinPoolDriverName, but the pool disk takes a slot.
+	if maxVolumesNum > 1 && ns.thinPool != nil {
+		maxVolumesNum--
+	}
 
 	return &csi.NodeGetInfoResponse{
 		NodeId:             ns.NodeID,
@@ -890,6 +1069,11 @@ func (ns *nodeServer) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandV
 	logger := klog.FromContext(ctx)
 	logger.V(2).Info("starting", "req", req)
 
+	// thin volumes are extended on the node, including block volumes
+	if isThinVolumeID(req.VolumeId) {
+		return ns.expandThinVolume(ctx, req)
+	}
+
 	if req.VolumeCapability != nil && req.VolumeCapability.GetBlock() != nil {
 		logger.V(2).Info("skipping expand for block volume")
 		return &csi.NodeExpandVolumeResponse{}, nil
@@ -920,32 +1104,38 @@ func (ns *nodeServer) localExpandVolume(ctx context.Context, req *csi.NodeExpand
 	diskID := req.GetVolumeId()
 	logger := klog.FromContext(ctx)
 
-	devicePath, err := ns.ad.GetVolumeDeviceName(logger, diskID)
+	rootPath, err := ns.ad.GetRootBlockDevice(logger, diskID)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil, status.Errorf(codes.NotFound, "can't get devicePath for: %s", diskID)
 		}
 		return nil, status.Errorf(codes.Internal, "get device name: %v", err)
 	}
+	// the partition or logical volume may be selected by volume context, which is not available here
+	devicePath, _, err := k8smount.GetDeviceNameFromMount(ns.k8smounter, volumePath)
+	if err != nil || devicePath == "" {
+		logger.V(2).Info("mounted device not found, fallback to adapt partition", "volumePath", volumePath, "err", err)
+		devicePath, err = ns.ad.dev.adaptDevicePartition(rootPath)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "get device name: %v", err)
+		}
+	}
 	logger = logger.WithValues("device", devicePath)
 	ctx = klog.NewContext(ctx, logger)
 
-	rootPath, index, err := DefaultDeviceManager.GetDeviceRootAndPartitionIndex(devicePath)
+	_, span := tracing.Start(ctx, "ExpandPartition", attribute.String("device", rootPath))
+	err = ns.layout.grow(ctx, rootPath, devicePath)
+	tracing.End(span, err)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "GetDeviceRootAndIndex(%s): %v", diskID, err)
-	}
-	if index != "" {
-		err := sfdisk.ExpandPartition(ctx, rootPath, index)
-		if err != nil {
-			return nil, status.Error(codes.Internal, err.Error())
-		}
-		logger.V(2).Info("Successful expand partition", "root", rootPath, "partition", index)
+		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	logger.V(2).Info("Expand filesystem start", "volumePath", volumePath)
 	// use resizer to expand volume filesystem
 	r := k8smount.NewResizeFs(utilexec.New())
+	_, span = tracing.Start(ctx, "ResizeFs", attribute.String("device", devicePath))
 	ok, err := r.Resize(devicePath, volumePath)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "resize %s: %v", volumePath, err)
 	}
@@ -985,7 +1175,8 @@ func (ns *nodeServer) unmountStageTarget(logger klog.Logger, targetPath string)
 	return nil
 }
 
-func (ns *nodeServer) mountDeviceToGlobal(logger klog.Logger, capability *csi.VolumeCapability, volumeContext map[string]string, device, sourcePath string) error {
+func (ns *nodeServer) mountDeviceToGlobal(ctx context.Context, capability *csi.VolumeCapability, volumeContext map[string]string, device, sourcePath string) error {
+	logger := klog.FromContext(ctx)
 	mnt := capability.GetMount()
 	options := append(mnt.MountFlags, "shared")
 	fsType := "ext4"
@@ -1005,7 +1196,12 @@ func (ns *nodeServer) mountDeviceToGlobal(logger klog.Logger, capability *csi.Vo
 
 	// do format-mount or mount
 	diskMounter := &k8smount.SafeFormatAndMount{Interface: ns.k8smounter, Exec: utilexec.New()}
-	if err := utils.FormatAndMount(diskMounter, device, sourcePath, fsType, mkfsOptions, mountOptions, GlobalConfigVar.OmitFilesystemCheck); err != nil {
+	_, span := tracing.Start(ctx, "FormatAndMount", attribute.String("device", device), attribute.String("fsType", fsType))
+	formatStart := time.Now()
+	err := utils.FormatAndMount(diskMounter, device, sourcePath, fsType, mkfsOptions, mountOptions, GlobalConfigVar.OmitFilesystemCheck)
+	metric.ObserveVolumePhase(driverType, volumeContext["type"], metric.PhaseFormat, formatStart, err)
+	tracing.End(span, err)
+	if err != nil {
 		logger.Error(err, "FormatAndMount failed", "device", device, "source", sourcePath, "fsType", fsType, "mkfsOptions", mkfsOptions, "mountOptions", mountOptions)
 		return fmt.Errorf("FormatAndMount %s to %s: %w", device, sourcePath, err)
 	}
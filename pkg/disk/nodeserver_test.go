@@ -15,6 +15,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/klog/v2/ktesting"
 	k8smount "k8s.io/mount-utils"
 )
@@ -326,6 +328,20 @@ func TestCollectMountOptions(t *testing.T) {
 	}
 }
 
+func TestNodeGetVolumeStatsWithoutMetrics(t *testing.T) {
+	originalMetricEnable := GlobalConfigVar.MetricEnable
+	defer func() { GlobalConfigVar.MetricEnable = originalMetricEnable }()
+	GlobalConfigVar.MetricEnable = false
+
+	ns := &nodeServer{}
+	resp, err := ns.NodeGetVolumeStats(t.Context(), &csi.NodeGetVolumeStatsRequest{VolumeId: "d-notexist", VolumePath: t.TempDir()})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Usage)
+
+	_, err = ns.NodeGetVolumeStats(t.Context(), &csi.NodeGetVolumeStatsRequest{VolumeId: "d-notexist", VolumePath: "/notexist"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
 func TestNodeGetCapabilities(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -335,12 +351,12 @@ func TestNodeGetCapabilities(t *testing.T) {
 		{
 			name:          "metrics disabled",
 			metricEnable:  false,
-			expectedCount: 2, // STAGE_UNSTAGE_VOLUME and EXPAND_VOLUME
+			expectedCount: 3, // STAGE_UNSTAGE_VOLUME, EXPAND_VOLUME and VOLUME_CONDITION
 		},
 		{
 			name:          "metrics enabled",
 			metricEnable:  true,
-			expectedCount: 3, // STAGE_UNSTAGE_VOLUME, EXPAND_VOLUME, and GET_VOLUME_STATS
+			expectedCount: 4, // STAGE_UNSTAGE_VOLUME, EXPAND_VOLUME, GET_VOLUME_STATS and VOLUME_CONDITION
 		},
 	}
 
@@ -364,6 +380,7 @@ func TestNodeGetCapabilities(t *testing.T) {
 			hasStageUnstage := false
 			hasExpand := false
 			hasGetStats := false
+			hasCondition := false
 			for _, cap := range resp.Capabilities {
 				if cap.GetRpc().GetType() == csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME {
 					hasStageUnstage = true
@@ -374,10 +391,14 @@ func TestNodeGetCapabilities(t *testing.T) {
 				if cap.GetRpc().GetType() == csi.NodeServiceCapability_RPC_GET_VOLUME_STATS {
 					hasGetStats = true
 				}
+				if cap.GetRpc().GetType() == csi.NodeServiceCapability_RPC_VOLUME_CONDITION {
+					hasCondition = true
+				}
 			}
 			assert.True(t, hasStageUnstage, "STAGE_UNSTAGE_VOLUME should always be present")
 			assert.True(t, hasExpand, "EXPAND_VOLUME should always be present")
 			assert.Equal(t, tt.metricEnable, hasGetStats, "GET_VOLUME_STATS should match metricEnable")
+			assert.True(t, hasCondition, "VOLUME_CONDITION should always be present")
 		})
 	}
 }
@@ -0,0 +1,294 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	informercorev1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// RepairLabel on a PVC requests the node plugin to repair the filesystem before the next NodeStageVolume mounts it.
+	// It is a label rather than an annotation, so that the node plugin can find the PVC without the PV name.
+	RepairLabel = "csi.alibabacloud.com/disk-repair"
+	// RepairPolicyAnnotation is what to do if the repair fails: RepairPolicyStrict (default) or RepairPolicyBestEffort
+	RepairPolicyAnnotation = "csi.alibabacloud.com/disk-repair-policy"
+	// RepairResultAnnotation and RepairOutputAnnotation are set by the node plugin after the repair.
+	RepairResultAnnotation = "csi.alibabacloud.com/disk-repair-result"
+	RepairOutputAnnotation = "csi.alibabacloud.com/disk-repair-output"
+
+	// RepairPolicyStrict refuses to mount if the repair fails. The label is kept, so that it is retried on the next stage.
+	RepairPolicyStrict = "Strict"
+	// RepairPolicyBestEffort mounts the filesystem even if the repair fails.
+	RepairPolicyBestEffort = "BestEffort"
+
+	RepairResultSucceeded = "Succeeded"
+	RepairResultFailed    = "Failed"
+	RepairResultSkipped   = "Skipped"
+
+	eventRepairSucceeded = "DiskRepaired"
+	eventRepairFailed    = "DiskRepairFailed"
+
+	repairTimeout = 30 * time.Minute
+	// event messages are truncated to 1KiB by the API server
+	repairEventOutputLimit = 512
+	repairAnnotationLimit  = 4096
+)
+
+// diskRepairer runs e2fsck or xfs_repair on the device of a volume, if requested by RepairLabel on its PVC.
+// The repair runs in the background, NodeStageVolume is aborted until it is done.
+type diskRepairer struct {
+	client   kubernetes.Interface
+	recorder record.EventRecorder
+	// diskFormat returns the filesystem on the device, empty if not formatted
+	diskFormat func(device string) (string, error)
+	run        func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+	// requests lists the PVCs with RepairLabel from the informer, once synced
+	requests corelisters.PersistentVolumeClaimLister
+	synced   cache.InformerSynced
+
+	mu sync.Mutex
+	// repairs is the repair of each volume, until its result is taken by NodeStageVolume
+	repairs map[string]*repairRun
+	// volumeHandles is the volume handle of the PV bound to each PVC requesting repair
+	volumeHandles map[types.UID]string
+}
+
+type repairRun struct {
+	done chan struct{}
+	// err is set before done is closed, not nil if the volume must not be mounted
+	err error
+}
+
+// newDiskRepairer watches PVCs requesting repair until ctx is done.
+func newDiskRepairer(ctx context.Context, client kubernetes.Interface, recorder record.EventRecorder,
+	diskFormat func(device string) (string, error),
+	run func(ctx context.Context, name string, args ...string) ([]byte, error),
+) *diskRepairer {
+	// only PVCs requesting repair are watched, which are few
+	informer := informercorev1.NewFilteredPersistentVolumeClaimInformer(client, v1.NamespaceAll, 0, cache.Indexers{}, func(options *metav1.ListOptions) {
+		options.LabelSelector = RepairLabel + "=true"
+	})
+	go informer.Run(ctx.Done())
+	return &diskRepairer{
+		client:        client,
+		recorder:      recorder,
+		diskFormat:    diskFormat,
+		run:           run,
+		requests:      corelisters.NewPersistentVolumeClaimLister(informer.GetIndexer()),
+		synced:        informer.HasSynced,
+		repairs:       map[string]*repairRun{},
+		volumeHandles: map[types.UID]string{},
+	}
+}
+
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
+
+// repairCommand returns the command to repair fsType, and whether the exit code means success.
+func repairCommand(fsType, device string) ([]string, func(code int) bool) {
+	switch fsType {
+	case "ext2", "ext3", "ext4":
+		// 1: errors corrected, 2: errors corrected, system should be rebooted (only for the root filesystem)
+		return []string{"e2fsck", "-f", "-y", device}, func(code int) bool { return code <= 2 }
+	case "xfs":
+		return []string{"xfs_repair", device}, func(code int) bool { return code == 0 }
+	}
+	return nil, nil
+}
+
+// findRequest returns the PVC of the volume, if it requests a repair.
+// The PV is only read once for each PVC requesting repair.
+func (r *diskRepairer) findRequest(ctx context.Context, volumeID string) (*v1.PersistentVolumeClaim, error) {
+	if !cache.WaitForCacheSync(ctx.Done(), r.synced) {
+		return nil, errors.New("PVC informer not synced")
+	}
+	pvcs, err := r.requests.List(labels.SelectorFromSet(labels.Set{RepairLabel: "true"}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVCs requesting repair: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	handles := make(map[types.UID]string, len(pvcs))
+	var found *v1.PersistentVolumeClaim
+	for _, pvc := range pvcs {
+		if pvc.Spec.VolumeName == "" {
+			continue
+		}
+		handle, ok := r.volumeHandles[pvc.UID]
+		if !ok {
+			pv, err := r.client.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get PV %s: %w", pvc.Spec.VolumeName, err)
+			}
+			if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == driverName {
+				handle = pv.Spec.CSI.VolumeHandle
+			}
+		}
+		handles[pvc.UID] = handle
+		if handle == volumeID {
+			found = pvc
+		}
+	}
+	r.volumeHandles = handles
+	return found, nil
+}
+
+// repairIfRequested starts repairing the filesystem on device if requested,
+// and returns codes.Aborted until the repair is done.
+// Then returns error only if the repair failed and the policy is RepairPolicyStrict.
+func (r *diskRepairer) repairIfRequested(ctx context.Context, volumeID, device string) error {
+	if done, err := r.result(volumeID); done {
+		return err
+	}
+	pvc, err := r.findRequest(ctx, volumeID)
+	if err != nil {
+		// don't block mounting volumes not requesting repair
+		klog.FromContext(ctx).Error(err, "failed to check repair request")
+		return nil
+	}
+	if pvc == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.repairs[volumeID]; !ok {
+		run := &repairRun{done: make(chan struct{})}
+		r.repairs[volumeID] = run
+		// not cancelled with NodeStageVolume, which is retried until the repair is done
+		ctx := context.WithoutCancel(ctx)
+		go func() {
+			defer close(run.done)
+			run.err = r.repairPVC(ctx, pvc, device)
+		}()
+	}
+	return status.Errorf(codes.Aborted, "filesystem repair of %s requested by PVC %s/%s is in progress", device, pvc.Namespace, pvc.Name)
+}
+
+// result returns the result of the repair of the volume, if any is done, and forgets it.
+// Returns codes.Aborted if it is still in progress.
+func (r *diskRepairer) result(volumeID string) (done bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.repairs[volumeID]
+	if !ok {
+		return false, nil
+	}
+	select {
+	case <-run.done:
+		delete(r.repairs, volumeID)
+		return true, run.err
+	default:
+		return true, status.Errorf(codes.Aborted, "filesystem repair of volume %s is in progress", volumeID)
+	}
+}
+
+// repairPVC repairs the filesystem on device, and records the result on the PVC.
+func (r *diskRepairer) repairPVC(ctx context.Context, pvc *v1.PersistentVolumeClaim, device string) error {
+	logger := klog.FromContext(ctx)
+	policy := pvc.Annotations[RepairPolicyAnnotation]
+	strict := policy != RepairPolicyBestEffort
+
+	result, output, repairErr := r.repair(ctx, device)
+	ref := &v1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "PersistentVolumeClaim",
+		Namespace:  pvc.Namespace,
+		Name:       pvc.Name,
+		UID:        pvc.UID,
+	}
+	if repairErr != nil {
+		logger.Error(repairErr, "filesystem repair failed", "device", device, "output", output)
+		r.recorder.Eventf(ref, v1.EventTypeWarning, eventRepairFailed, "Repair of %s on node %s failed: %v, output: %s",
+			device, GlobalConfigVar.NodeID, repairErr, tail(output, repairEventOutputLimit))
+	} else {
+		logger.Info("filesystem repair done", "device", device, "result", result, "output", output)
+		r.recorder.Eventf(ref, v1.EventTypeNormal, eventRepairSucceeded, "Repair of %s on node %s %s, output: %s",
+			device, GlobalConfigVar.NodeID, result, tail(output, repairEventOutputLimit))
+	}
+
+	if err := r.patchResult(ctx, pvc, result, output, repairErr == nil || !strict); err != nil {
+		logger.Error(err, "failed to update repair result of PVC", "pvc", klog.KObj(pvc))
+	}
+
+	if repairErr != nil && strict {
+		return fmt.Errorf("repair of %s failed, refusing to mount as %s is %s: %w", device, RepairPolicyAnnotation, RepairPolicyStrict, repairErr)
+	}
+	return nil
+}
+
+// patchResult records the result on the PVC, and removes RepairLabel if unlabel.
+// The PVC from the informer may be stale, so only the keys changed are patched.
+func (r *diskRepairer) patchResult(ctx context.Context, pvc *v1.PersistentVolumeClaim, result, output string, unlabel bool) error {
+	metadata := map[string]any{
+		"annotations": map[string]string{
+			RepairResultAnnotation: result,
+			RepairOutputAnnotation: tail(output, repairAnnotationLimit),
+		},
+	}
+	if unlabel {
+		// null removes the key in merge patch
+		metadata["labels"] = map[string]any{RepairLabel: nil}
+	}
+	patch, err := json.Marshal(map[string]any{"metadata": metadata})
+	if err != nil {
+		return err
+	}
+	_, err = r.client.CoreV1().PersistentVolumeClaims(pvc.Namespace).Patch(ctx, pvc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func (r *diskRepairer) repair(ctx context.Context, device string) (result, output string, err error) {
+	fsType, err := r.diskFormat(device)
+	if err != nil {
+		return RepairResultFailed, "", fmt.Errorf("failed to detect filesystem: %w", err)
+	}
+	if fsType == "" {
+		return RepairResultSkipped, "device is not formatted", nil
+	}
+	cmd, ok := repairCommand(fsType, device)
+	if cmd == nil {
+		return RepairResultFailed, "", fmt.Errorf("repairing %s is not supported", fsType)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, repairTimeout)
+	defer cancel()
+	out, err := r.run(ctx, cmd[0], cmd[1:]...)
+	output = string(out)
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) && ok(exitErr.ExitCode()) {
+		err = nil
+	}
+	if err != nil {
+		return RepairResultFailed, output, fmt.Errorf("%s: %w", cmd[0], err)
+	}
+	return RepairResultSucceeded, output, nil
+}
+
+func tail(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return "..." + s[len(s)-limit:]
+}
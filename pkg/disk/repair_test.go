@@ -0,0 +1,206 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2/ktesting"
+)
+
+type fakeExitError int
+
+func (e fakeExitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e fakeExitError) ExitCode() int { return int(e) }
+
+func testRepairer(t *testing.T, fsType string, labels, annotations map[string]string) (*diskRepairer, *record.FakeRecorder, *[]string) {
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pvc-1", Labels: labels, Annotations: annotations},
+		Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pv-1"},
+	}
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+		Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+			CSI: &v1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: "d-1"},
+		}},
+	}
+	recorder := record.NewFakeRecorder(10)
+	var cmds []string
+	r := newDiskRepairer(t.Context(), k8sfake.NewSimpleClientset(pvc, pv), recorder,
+		func(string) (string, error) { return fsType, nil },
+		func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			cmds = append(cmds, name+" "+strings.Join(args, " "))
+			return []byte("repair output"), nil
+		})
+	return r, recorder, &cmds
+}
+
+// repairAndWait stages d-1 until the repair is done.
+func repairAndWait(t *testing.T, ctx context.Context, r *diskRepairer) error {
+	t.Helper()
+	err := r.repairIfRequested(ctx, "d-1", "/dev/vdb")
+	if status.Code(err) != codes.Aborted {
+		return err
+	}
+	r.mu.Lock()
+	run := r.repairs["d-1"]
+	r.mu.Unlock()
+	<-run.done
+	return r.repairIfRequested(ctx, "d-1", "/dev/vdb")
+}
+
+// waitUnlabeled waits for the informer to see the label removed.
+func waitUnlabeled(t *testing.T, r *diskRepairer) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		pvc, err := r.requests.PersistentVolumeClaims("default").Get("pvc-1")
+		return err == nil && pvc.Labels[RepairLabel] == ""
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func getPVC(t *testing.T, r *diskRepairer) *v1.PersistentVolumeClaim {
+	pvc, err := r.client.CoreV1().PersistentVolumeClaims("default").Get(t.Context(), "pvc-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	return pvc
+}
+
+func TestRepairNotRequested(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	r, recorder, cmds := testRepairer(t, "ext4", nil, nil)
+	require.NoError(t, repairAndWait(t, ctx, r))
+	assert.Empty(t, *cmds)
+	assert.Empty(t, recorder.Events)
+}
+
+func TestRepair(t *testing.T) {
+	cases := []struct {
+		fsType string
+		cmd    string
+	}{
+		{fsType: "ext4", cmd: "e2fsck -f -y /dev/vdb"},
+		{fsType: "xfs", cmd: "xfs_repair /dev/vdb"},
+	}
+	for _, c := range cases {
+		t.Run(c.fsType, func(t *testing.T) {
+			_, ctx := ktesting.NewTestContext(t)
+			r, recorder, cmds := testRepairer(t, c.fsType, map[string]string{RepairLabel: "true"}, nil)
+			require.NoError(t, repairAndWait(t, ctx, r))
+			assert.Equal(t, []string{c.cmd}, *cmds)
+			assert.Contains(t, <-recorder.Events, eventRepairSucceeded)
+
+			pvc := getPVC(t, r)
+			assert.NotContains(t, pvc.Labels, RepairLabel)
+			assert.Equal(t, RepairResultSucceeded, pvc.Annotations[RepairResultAnnotation])
+			assert.Equal(t, "repair output", pvc.Annotations[RepairOutputAnnotation])
+
+			// not repaired again
+			waitUnlabeled(t, r)
+			require.NoError(t, r.repairIfRequested(ctx, "d-1", "/dev/vdb"))
+			assert.Len(t, *cmds, 1)
+		})
+	}
+}
+
+func TestRepairKeepsConcurrentChanges(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	r, _, _ := testRepairer(t, "ext4", map[string]string{RepairLabel: "true"}, nil)
+	r.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		// changed after the repair started from the PVC in the informer
+		pvc := getPVC(t, r)
+		pvc.Labels["app"] = "test"
+		_, err := r.client.CoreV1().PersistentVolumeClaims("default").Update(ctx, pvc, metav1.UpdateOptions{})
+		require.NoError(t, err)
+		return nil, nil
+	}
+	require.NoError(t, repairAndWait(t, ctx, r))
+	pvc := getPVC(t, r)
+	assert.Equal(t, map[string]string{"app": "test"}, pvc.Labels)
+	assert.Equal(t, RepairResultSucceeded, pvc.Annotations[RepairResultAnnotation])
+}
+
+func TestRepairErrorsCorrected(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	r, _, _ := testRepairer(t, "ext4", map[string]string{RepairLabel: "true"}, nil)
+	r.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("FILE SYSTEM WAS MODIFIED"), fakeExitError(1)
+	}
+	require.NoError(t, repairAndWait(t, ctx, r))
+	assert.Equal(t, RepairResultSucceeded, getPVC(t, r).Annotations[RepairResultAnnotation])
+}
+
+func TestRepairFailed(t *testing.T) {
+	for _, policy := range []string{"", RepairPolicyStrict, RepairPolicyBestEffort} {
+		t.Run(policy, func(t *testing.T) {
+			_, ctx := ktesting.NewTestContext(t)
+			r, recorder, _ := testRepairer(t, "ext4",
+				map[string]string{RepairLabel: "true"}, map[string]string{RepairPolicyAnnotation: policy})
+			r.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				return []byte(strings.Repeat("x", 10000)), fakeExitError(8)
+			}
+			err := repairAndWait(t, ctx, r)
+			assert.Contains(t, <-recorder.Events, eventRepairFailed)
+			pvc := getPVC(t, r)
+			assert.Equal(t, RepairResultFailed, pvc.Annotations[RepairResultAnnotation])
+			assert.LessOrEqual(t, len(pvc.Annotations[RepairOutputAnnotation]), repairAnnotationLimit+3)
+			if policy == RepairPolicyBestEffort {
+				assert.NoError(t, err)
+				assert.NotContains(t, pvc.Labels, RepairLabel)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, pvc.Labels, RepairLabel, "kept to retry on next stage")
+			}
+		})
+	}
+}
+
+func TestRepairUnsupported(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	r, _, cmds := testRepairer(t, "btrfs", map[string]string{RepairLabel: "true"}, nil)
+	assert.Error(t, repairAndWait(t, ctx, r))
+	assert.Empty(t, *cmds)
+
+	r, _, _ = testRepairer(t, "", map[string]string{RepairLabel: "true"}, nil)
+	require.NoError(t, repairAndWait(t, ctx, r))
+	assert.Equal(t, RepairResultSkipped, getPVC(t, r).Annotations[RepairResultAnnotation])
+
+	r, _, _ = testRepairer(t, "ext4", map[string]string{RepairLabel: "true"}, nil)
+	r.diskFormat = func(string) (string, error) { return "", errors.New("blkid failed") }
+	assert.Error(t, repairAndWait(t, ctx, r))
+}
+
+func TestRepairInProgress(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	r, recorder, _ := testRepairer(t, "ext4", map[string]string{RepairLabel: "true"}, nil)
+	unblock := make(chan struct{})
+	var runs atomic.Int32
+	r.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		runs.Add(1)
+		<-unblock
+		return nil, ctx.Err()
+	}
+
+	// the repair outlives the NodeStageVolume starting it
+	stageCtx, cancel := context.WithCancel(ctx)
+	assert.Equal(t, codes.Aborted, status.Code(r.repairIfRequested(stageCtx, "d-1", "/dev/vdb")))
+	cancel()
+	assert.Equal(t, codes.Aborted, status.Code(r.repairIfRequested(ctx, "d-1", "/dev/vdb")))
+
+	close(unblock)
+	require.NoError(t, repairAndWait(t, ctx, r))
+	assert.Equal(t, int32(1), runs.Load())
+	assert.Contains(t, <-recorder.Events, eventRepairSucceeded)
+}
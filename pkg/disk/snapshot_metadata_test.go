@@ -0,0 +1,154 @@
+package disk
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	alicloudErr "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeSnapshotBlocksECS serves pages of blocks keyed by NextToken
+type fakeSnapshotBlocksECS struct {
+	pages    map[string]string
+	requests []*requests.CommonRequest
+}
+
+func (f *fakeSnapshotBlocksECS) ProcessCommonRequest(req *requests.CommonRequest) (*responses.CommonResponse, error) {
+	f.requests = append(f.requests, req)
+	if req.QueryParams["SnapshotId"] == "s-missing" {
+		return nil, alicloudErr.NewServerError(404, `{"Code":"InvalidSnapshotId.NotFound"}`, "")
+	}
+	page, ok := f.pages[req.QueryParams["NextToken"]]
+	if !ok {
+		return nil, fmt.Errorf("unexpected NextToken %q", req.QueryParams["NextToken"])
+	}
+	resp := responses.NewCommonResponse()
+	cloud.UnmarshalAcsResponse([]byte(page), resp)
+	return resp, nil
+}
+
+type fakeAllocatedStream struct {
+	csi.SnapshotMetadata_GetMetadataAllocatedServer
+	ctx   context.Context
+	resps []*csi.GetMetadataAllocatedResponse
+}
+
+func (s *fakeAllocatedStream) Context() context.Context { return s.ctx }
+func (s *fakeAllocatedStream) Send(resp *csi.GetMetadataAllocatedResponse) error {
+	s.resps = append(s.resps, resp)
+	return nil
+}
+
+type fakeDeltaStream struct {
+	csi.SnapshotMetadata_GetMetadataDeltaServer
+	ctx   context.Context
+	resps []*csi.GetMetadataDeltaResponse
+}
+
+func (s *fakeDeltaStream) Context() context.Context { return s.ctx }
+func (s *fakeDeltaStream) Send(resp *csi.GetMetadataDeltaResponse) error {
+	s.resps = append(s.resps, resp)
+	return nil
+}
+
+const testBlockSize = 512 * 1024
+
+func newFakeSnapshotBlocksECS() *fakeSnapshotBlocksECS {
+	return &fakeSnapshotBlocksECS{pages: map[string]string{
+		"":       `{"BlockSize":524288,"VolumeSize":20,"Blocks":{"Block":[{"BlockIndex":0},{"BlockIndex":1},{"BlockIndex":5}]},"NextToken":"page-2"}`,
+		"page-2": `{"BlockSize":524288,"VolumeSize":20,"Blocks":{"Block":[{"BlockIndex":8},{"BlockIndex":9}]}}`,
+	}}
+}
+
+func blockOffsets(blocks []*csi.BlockMetadata) []int64 {
+	var offsets []int64
+	for _, b := range blocks {
+		offsets = append(offsets, b.ByteOffset/testBlockSize)
+		if b.SizeBytes != testBlockSize {
+			panic("unexpected block size")
+		}
+	}
+	return offsets
+}
+
+func TestGetMetadataAllocated(t *testing.T) {
+	fake := newFakeSnapshotBlocksECS()
+	s := NewSnapshotMetadataServer(fake, "cn-hangzhou")
+	stream := &fakeAllocatedStream{ctx: t.Context()}
+	err := s.GetMetadataAllocated(&csi.GetMetadataAllocatedRequest{
+		SnapshotId:     "s-1",
+		StartingOffset: testBlockSize + 1, // within block 1
+		MaxResults:     2,
+	}, stream)
+	require.NoError(t, err)
+
+	require.Len(t, stream.resps, 2)
+	assert.Equal(t, []int64{1, 5}, blockOffsets(stream.resps[0].BlockMetadata))
+	assert.Equal(t, []int64{8, 9}, blockOffsets(stream.resps[1].BlockMetadata))
+	for _, resp := range stream.resps {
+		assert.Equal(t, csi.BlockMetadataType_FIXED_LENGTH, resp.BlockMetadataType)
+		assert.Equal(t, int64(20*GBSIZE), resp.VolumeCapacityBytes)
+	}
+
+	require.Len(t, fake.requests, 2)
+	assert.Equal(t, "ListSnapshotBlocks", fake.requests[0].ApiName)
+	assert.Equal(t, "s-1", fake.requests[0].QueryParams["SnapshotId"])
+	assert.Equal(t, "cn-hangzhou", fake.requests[0].RegionId)
+	assert.Equal(t, "page-2", fake.requests[1].QueryParams["NextToken"])
+}
+
+func TestGetMetadataDelta(t *testing.T) {
+	fake := newFakeSnapshotBlocksECS()
+	s := NewSnapshotMetadataServer(fake, "cn-hangzhou")
+	stream := &fakeDeltaStream{ctx: t.Context()}
+	err := s.GetMetadataDelta(&csi.GetMetadataDeltaRequest{
+		BaseSnapshotId:   "s-1",
+		TargetSnapshotId: "s-2",
+	}, stream)
+	require.NoError(t, err)
+
+	// all blocks in one response by default
+	require.Len(t, stream.resps, 1)
+	assert.Equal(t, []int64{0, 1, 5, 8, 9}, blockOffsets(stream.resps[0].BlockMetadata))
+
+	assert.Equal(t, "ListChangedBlocks", fake.requests[0].ApiName)
+	assert.Equal(t, "s-1", fake.requests[0].QueryParams["FirstSnapshotId"])
+	assert.Equal(t, "s-2", fake.requests[0].QueryParams["SecondSnapshotId"])
+}
+
+func TestSnapshotMetadataErrors(t *testing.T) {
+	s := NewSnapshotMetadataServer(newFakeSnapshotBlocksECS(), "cn-hangzhou")
+	cases := []struct {
+		name string
+		req  *csi.GetMetadataAllocatedRequest
+		code codes.Code
+	}{
+		{"no snapshot", &csi.GetMetadataAllocatedRequest{}, codes.InvalidArgument},
+		{"negative offset", &csi.GetMetadataAllocatedRequest{SnapshotId: "s-1", StartingOffset: -1}, codes.InvalidArgument},
+		{"negative max results", &csi.GetMetadataAllocatedRequest{SnapshotId: "s-1", MaxResults: -1}, codes.InvalidArgument},
+		{"beyond volume", &csi.GetMetadataAllocatedRequest{SnapshotId: "s-1", StartingOffset: 20 * GBSIZE}, codes.OutOfRange},
+		{"snapshot not found", &csi.GetMetadataAllocatedRequest{SnapshotId: "s-missing"}, codes.NotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stream := &fakeAllocatedStream{ctx: t.Context()}
+			err := s.GetMetadataAllocated(c.req, stream)
+			assert.Equal(t, c.code, status.Code(err), "%v", err)
+			assert.Empty(t, stream.resps)
+		})
+	}
+
+	t.Run("no base snapshot", func(t *testing.T) {
+		err := s.GetMetadataDelta(&csi.GetMetadataDeltaRequest{TargetSnapshotId: "s-2"}, &fakeDeltaStream{ctx: t.Context()})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
@@ -0,0 +1,837 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/lvm"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/features"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	informercorev1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	k8smount "k8s.io/mount-utils"
+	utilexec "k8s.io/utils/exec"
+)
+
+const (
+	// thinPoolDriverName serves thin volumes apart from cloud disks,
+	// so that Kubernetes counts them against their own volume limit, and tracks their capacity.
+	thinPoolDriverName = "thinpool." + driverName
+	// thinPoolSocket is the socket of thinPoolDriverName, next to the socket of the disk driver.
+	thinPoolSocket = "thinpool.sock"
+
+	// volume context keys of thin volumes
+	thinPoolNodeKey     = "thinPoolNode"
+	thinPoolInstanceKey = "thinPoolInstance"
+	thinVolumeSizeKey   = "thinVolumeSize"
+	thinSnapshotKey     = "thinSnapshot"
+
+	thinVolumeIDPrefix   = "thin-"
+	thinSnapshotIDPrefix = "thinsnap-"
+
+	thinPoolVGName = "csi-thinpool"
+	thinPoolLVName = "pool"
+	// LVM tags of logical volumes created by us
+	thinVolumeLVTag   = "csi-volume"
+	thinSnapshotLVTag = "csi-snapshot"
+
+	// ThinPoolDiskTagKey marks the pool disk, the value is the ECS instance ID.
+	ThinPoolDiskTagKey = "csi.alibabacloud.com/thin-pool"
+
+	// thinPoolStatePrefix, followed by the node name, is the ConfigMap holding the state of the thin pool of a node.
+	// It is owned by the Node, so it is deleted with the Node.
+	thinPoolStatePrefix = "csi-thinpool-"
+	// thinPoolStateNamespace is the namespace of the state ConfigMaps, the same as the csi-plugin ConfigMap.
+	thinPoolStateNamespace = utils.CSIPluginConfigMapNamespace
+	// thinPoolFreeKey in the state is the capacity in bytes left for new thin volumes, published by the node plugin.
+	thinPoolFreeKey = "free"
+	// thinSnapshotKeyPrefix in the state, followed by the snapshot name, holds a thinSnapshotRequest.
+	thinSnapshotKeyPrefix = "snapshot."
+	// thinVolumeKeyPrefix in the state, followed by the volume name, holds a thinVolumeRequest.
+	thinVolumeKeyPrefix = "volume."
+
+	thinPoolReconcileInterval = 30 * time.Second
+	maxStateUpdateRetries     = 5
+	// thinPoolMetadataFullPercent is the metadata usage of the pool from which no more capacity is published,
+	// since the pool turns read-only once its metadata is full.
+	thinPoolMetadataFullPercent = 95
+)
+
+// thinPoolConfig is the configuration of the node plugin for thin pools.
+type thinPoolConfig struct {
+	Category Category
+	// InitialGB is the size of the pool disk when created.
+	InitialGB int
+	// MaxGB is the size the pool disk will not grow beyond.
+	MaxGB int
+	// GrowThreshold is the data or metadata usage percentage of the pool to trigger growth.
+	GrowThreshold int
+	// MaxVolumes is the MaxVolumesPerNode reported for thinPoolDriverName.
+	MaxVolumes int
+}
+
+func parseThinPoolConfig(csiCfg utils.Config) thinPoolConfig {
+	return thinPoolConfig{
+		Category:      Category(csiCfg.Get("disk-thin-pool-category", "DISK_THIN_POOL_CATEGORY", string(DiskESSD))),
+		InitialGB:     csiCfg.GetInt("disk-thin-pool-initial-size", "DISK_THIN_POOL_INITIAL_SIZE", 100),
+		MaxGB:         csiCfg.GetInt("disk-thin-pool-max-size", "DISK_THIN_POOL_MAX_SIZE", 2048),
+		GrowThreshold: csiCfg.GetInt("disk-thin-pool-grow-threshold", "DISK_THIN_POOL_GROW_THRESHOLD", 80),
+		MaxVolumes:    csiCfg.GetInt("disk-thin-pool-max-volumes", "DISK_THIN_POOL_MAX_VOLUMES", 128),
+	}
+}
+
+func isThinVolumeID(id string) bool {
+	return strings.HasPrefix(id, thinVolumeIDPrefix)
+}
+
+func isThinSnapshotID(id string) bool {
+	return strings.HasPrefix(id, thinSnapshotIDPrefix)
+}
+
+// thinVolumeName returns the name of the logical volume, which is also the name of the PV.
+func thinVolumeName(volumeID string) string {
+	return strings.TrimPrefix(volumeID, thinVolumeIDPrefix)
+}
+
+// thinSnapshotID encodes the node name into the snapshot ID, since snapshots can only be restored on the same node.
+// Neither node names nor snapshot names can contain "_".
+func thinSnapshotID(nodeName, snapshotName string) string {
+	return thinSnapshotIDPrefix + nodeName + "_" + snapshotName
+}
+
+func parseThinSnapshotID(id string) (nodeName, snapshotName string, err error) {
+	s, ok := strings.CutPrefix(id, thinSnapshotIDPrefix)
+	if ok {
+		nodeName, snapshotName, ok = strings.Cut(s, "_")
+	}
+	if !ok || nodeName == "" || snapshotName == "" {
+		return "", "", fmt.Errorf("invalid thin snapshot ID %q", id)
+	}
+	return nodeName, snapshotName, nil
+}
+
+// Snapshot states in thinSnapshotRequest.
+const (
+	thinSnapshotPending  = "Pending"
+	thinSnapshotReady    = "Ready"
+	thinSnapshotDeleting = "Deleting"
+	thinSnapshotFailed   = "Failed"
+)
+
+// thinSnapshotRequest is the state used by the controller to ask the node plugin to create or delete a snapshot.
+type thinSnapshotRequest struct {
+	Source       string `json:"source"`
+	State        string `json:"state"`
+	SizeBytes    int64  `json:"sizeBytes"`
+	CreationTime int64  `json:"creationTime"`
+	Message      string `json:"message,omitempty"`
+}
+
+// Volume states in thinVolumeRequest.
+const (
+	// thinVolumeReserved is set by CreateVolume, until the volume is created on the node.
+	thinVolumeReserved = "Reserved"
+	// thinVolumeExpanding is set by ControllerExpandVolume, until the volume is extended on the node.
+	thinVolumeExpanding = "Expanding"
+	// thinVolumeDeleting is set by DeleteVolume, until the volume is removed on the node.
+	// Logical volumes are never removed without it, e.g. when the PV is deleted with Retain reclaim policy.
+	thinVolumeDeleting = "Deleting"
+)
+
+// thinVolumeRequest is the state used by the controller to reserve capacity for a volume, or delete it.
+type thinVolumeRequest struct {
+	State string `json:"state"`
+	// SizeBytes is the capacity reserved, not yet taken by the logical volume.
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+}
+
+func thinPoolStateName(nodeName string) string {
+	return thinPoolStatePrefix + nodeName
+}
+
+func getThinPoolState(ctx context.Context, client kubernetes.Interface, nodeName string) (*v1.ConfigMap, error) {
+	return client.CoreV1().ConfigMaps(thinPoolStateNamespace).Get(ctx, thinPoolStateName(nodeName), metav1.GetOptions{})
+}
+
+func thinVolumeRequestOf(state *v1.ConfigMap, volumeName string) (*thinVolumeRequest, error) {
+	v, ok := state.Data[thinVolumeKeyPrefix+volumeName]
+	if !ok {
+		return nil, nil
+	}
+	r := &thinVolumeRequest{}
+	if err := json.Unmarshal([]byte(v), r); err != nil {
+		return nil, fmt.Errorf("invalid volume request in ConfigMap %s: %w", state.Name, err)
+	}
+	return r, nil
+}
+
+func setThinVolumeRequest(state *v1.ConfigMap, volumeName string, r *thinVolumeRequest) error {
+	if r == nil {
+		delete(state.Data, thinVolumeKeyPrefix+volumeName)
+		return nil
+	}
+	return setStateJSON(state, thinVolumeKeyPrefix+volumeName, r)
+}
+
+func thinSnapshotRequestOf(state *v1.ConfigMap, snapshotName string) (*thinSnapshotRequest, error) {
+	v, ok := state.Data[thinSnapshotKeyPrefix+snapshotName]
+	if !ok {
+		return nil, nil
+	}
+	r := &thinSnapshotRequest{}
+	if err := json.Unmarshal([]byte(v), r); err != nil {
+		return nil, fmt.Errorf("invalid snapshot request in ConfigMap %s: %w", state.Name, err)
+	}
+	return r, nil
+}
+
+func setThinSnapshotRequest(state *v1.ConfigMap, snapshotName string, r *thinSnapshotRequest) error {
+	if r == nil {
+		delete(state.Data, thinSnapshotKeyPrefix+snapshotName)
+		return nil
+	}
+	return setStateJSON(state, thinSnapshotKeyPrefix+snapshotName, r)
+}
+
+func setStateJSON(state *v1.ConfigMap, key string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if state.Data == nil {
+		state.Data = map[string]string{}
+	}
+	state.Data[key] = string(b)
+	return nil
+}
+
+// hasThinPoolWork reports whether the controller requested something of the node plugin in the state.
+func hasThinPoolWork(state *v1.ConfigMap) bool {
+	for key := range state.Data {
+		if name, ok := strings.CutPrefix(key, thinVolumeKeyPrefix); ok {
+			r, err := thinVolumeRequestOf(state, name)
+			if err == nil && r.State == thinVolumeDeleting {
+				return true
+			}
+		}
+		if name, ok := strings.CutPrefix(key, thinSnapshotKeyPrefix); ok {
+			r, err := thinSnapshotRequestOf(state, name)
+			if err == nil && (r.State == thinSnapshotPending || r.State == thinSnapshotDeleting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// updateThinPoolState updates the state of the thin pool of the node with optimistic concurrency,
+// so that concurrent reservations are not lost.
+func updateThinPoolState(ctx context.Context, client kubernetes.Interface, nodeName string, update func(state *v1.ConfigMap) error) (err error) {
+	for range maxStateUpdateRetries {
+		err = tryUpdateThinPoolState(ctx, client, nodeName, update)
+		if !apierrors.IsConflict(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func tryUpdateThinPoolState(ctx context.Context, client kubernetes.Interface, nodeName string, update func(state *v1.ConfigMap) error) error {
+	state, err := getThinPoolState(ctx, client, nodeName)
+	if err != nil {
+		return err
+	}
+	if err := update(state); err != nil {
+		return err
+	}
+	_, err = client.CoreV1().ConfigMaps(thinPoolStateNamespace).Update(ctx, state, metav1.UpdateOptions{})
+	return err
+}
+
+// thinPool manages the pool disk of this node and the thin volumes carved from it.
+type thinPool struct {
+	config     thinPoolConfig
+	vg         *lvm.VolumeGroup
+	ecs        cloud.ECSInterface
+	ad         *DiskAttachDetach
+	client     kubernetes.Interface
+	nodeName   string
+	instanceID string
+	zoneID     string
+
+	mu     sync.Mutex
+	diskID string
+
+	// lvMu serializes the changes of thin volumes and the publishing of the capacity left,
+	// so that the published capacity is never older than the volumes.
+	lvMu     sync.Mutex
+	lastFree string
+}
+
+// ensure creates, attaches and initializes the pool disk if the volume group is not present yet.
+func (p *thinPool) ensure(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	exists, err := p.vg.Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	logger := klog.FromContext(ctx)
+	disk, err := p.findPoolDisk(ctx)
+	if err != nil {
+		return err
+	}
+	diskID := ""
+	if disk != nil {
+		diskID = disk.DiskId
+	} else {
+		diskID, err = p.createPoolDisk(ctx)
+		if err != nil {
+			return err
+		}
+		logger.V(1).Info("created thin pool disk", "disk", diskID)
+	}
+	p.diskID = diskID
+
+	device, err := p.ad.attachDisk(ctx, diskID, p.instanceID, true)
+	if err != nil {
+		return fmt.Errorf("failed to attach thin pool disk %s: %w", diskID, err)
+	}
+	// The volume group may be activated automatically after attached.
+	exists, err = p.vg.Exists(ctx)
+	if err != nil || exists {
+		return err
+	}
+	return p.vg.Create(ctx, device)
+}
+
+func (p *thinPool) findPoolDisk(ctx context.Context) (*ecs.Disk, error) {
+	req := ecs.CreateDescribeDisksRequest()
+	req.RegionId = GlobalConfigVar.Region
+	req.ZoneId = p.zoneID
+	req.Tag = &[]ecs.DescribeDisksTag{{Key: ThinPoolDiskTagKey, Value: p.instanceID}}
+	resp, err := wrap.V1(ctx, p.ecs.DescribeDisks)(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe thin pool disk: %w", err)
+	}
+	if len(resp.Disks.Disk) == 0 {
+		return nil, nil
+	}
+	return &resp.Disks.Disk[0], nil
+}
+
+func (p *thinPool) createPoolDisk(ctx context.Context) (string, error) {
+	req := ecs.CreateCreateDiskRequest()
+	req.RegionId = GlobalConfigVar.Region
+	req.ZoneId = p.zoneID
+	req.DiskCategory = string(p.config.Category)
+	req.Size = requests.NewInteger(p.config.InitialGB)
+	req.DiskName = "csi-thinpool-" + p.instanceID
+	req.ClientToken = "csi-thinpool-" + p.instanceID
+	req.Tag = &[]ecs.CreateDiskTag{
+		{Key: ThinPoolDiskTagKey, Value: p.instanceID},
+		{Key: DISKTAGKEY2, Value: DISKTAGVALUE2},
+	}
+	resp, err := wrap.V1(ctx, p.ecs.CreateDisk)(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to create thin pool disk: %w", err)
+	}
+	return resp.DiskId, nil
+}
+
+// stage creates the thin volume if not exists and returns its device.
+func (p *thinPool) stage(ctx context.Context, volumeID string, volumeContext map[string]string) (string, error) {
+	if err := p.ensure(ctx); err != nil {
+		return "", err
+	}
+	p.lvMu.Lock()
+	defer p.lvMu.Unlock()
+
+	name := thinVolumeName(volumeID)
+	lvs, err := p.vg.List(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !containsLV(lvs, name) {
+		size, err := strconv.ParseInt(volumeContext[thinVolumeSizeKey], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s in volume context: %w", thinVolumeSizeKey, err)
+		}
+		if free := thinPoolFree(lvs, p.config.MaxGB); free < size {
+			return "", status.Errorf(codes.ResourceExhausted, "thin pool has %d bytes left, %d requested", free, size)
+		}
+		if snapshot := volumeContext[thinSnapshotKey]; snapshot != "" {
+			err = p.vg.Restore(ctx, name, snapshot, size, thinVolumeLVTag)
+		} else {
+			err = p.vg.CreateThin(ctx, name, size, thinVolumeLVTag)
+		}
+		if err != nil {
+			return "", err
+		}
+		klog.FromContext(ctx).V(2).Info("created thin volume", "lv", name, "size", size, "snapshot", volumeContext[thinSnapshotKey])
+	}
+	// The reservation is taken by the volume now.
+	if err := p.publish(ctx, name, thinVolumeReserved); err != nil {
+		return "", fmt.Errorf("failed to release the reservation of %s: %w", name, err)
+	}
+	return p.vg.DevicePath(name), nil
+}
+
+// expand extends the thin volume to size.
+func (p *thinPool) expand(ctx context.Context, volumeID string, size int64) error {
+	p.lvMu.Lock()
+	defer p.lvMu.Unlock()
+
+	name := thinVolumeName(volumeID)
+	lvs, err := p.vg.List(ctx)
+	if err != nil {
+		return err
+	}
+	lv := findLV(lvs, name)
+	if lv == nil {
+		return status.Errorf(codes.NotFound, "thin volume %s not found", name)
+	}
+	if grow := size - lv.Size; grow > 0 {
+		if free := thinPoolFree(lvs, p.config.MaxGB); free < grow {
+			return status.Errorf(codes.ResourceExhausted, "thin pool has %d bytes left, %d more requested", free, grow)
+		}
+		if err := p.vg.Extend(ctx, name, size); err != nil {
+			return err
+		}
+	}
+	if err := p.publish(ctx, name, thinVolumeExpanding); err != nil {
+		return fmt.Errorf("failed to release the reservation of %s: %w", name, err)
+	}
+	return nil
+}
+
+// run reconciles the pool periodically until ctx is done,
+// or as soon as the controller requests something of the node plugin.
+func (p *thinPool) run(ctx context.Context) {
+	wake := make(chan struct{}, 1)
+	go p.watch(ctx, wake)
+	ticker := time.NewTicker(thinPoolReconcileInterval)
+	defer ticker.Stop()
+	for {
+		if err := p.reconcile(ctx); err != nil {
+			klog.ErrorS(err, "failed to reconcile thin pool")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// watch notifies wake when the state of the pool has requests to process, e.g. a volume is deleted.
+func (p *thinPool) watch(ctx context.Context, wake chan<- struct{}) {
+	informer := informercorev1.NewFilteredConfigMapInformer(p.client, thinPoolStateNamespace, 0, nil, func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", thinPoolStateName(p.nodeName)).String()
+	})
+	notify := func(obj any) {
+		if state, ok := obj.(*v1.ConfigMap); ok && hasThinPoolWork(state) {
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    notify,
+		UpdateFunc: func(_, obj any) { notify(obj) },
+	})
+	if err != nil {
+		klog.ErrorS(err, "failed to watch thin pool state")
+		return
+	}
+	informer.Run(ctx.Done())
+}
+
+func (p *thinPool) reconcile(ctx context.Context) error {
+	exists, err := p.vg.Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return p.publishFree(ctx)
+	}
+	lvs, err := p.vg.List(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	errs = append(errs, p.processVolumes(ctx))
+	errs = append(errs, p.processSnapshots(ctx, lvs))
+	errs = append(errs, p.grow(ctx, lvs))
+	errs = append(errs, p.publishFree(ctx))
+	return errors.Join(errs...)
+}
+
+// processVolumes removes thin volumes deleted by DeleteVolume through the state of the pool.
+// DeleteVolume can not do it, since it is called on the controller.
+func (p *thinPool) processVolumes(ctx context.Context) error {
+	state, err := getThinPoolState(ctx, p.client, p.nodeName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p.lvMu.Lock()
+	defer p.lvMu.Unlock()
+
+	logger := klog.FromContext(ctx)
+	var errs []error
+	for key := range state.Data {
+		name, ok := strings.CutPrefix(key, thinVolumeKeyPrefix)
+		if !ok {
+			continue
+		}
+		r, err := thinVolumeRequestOf(state, name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if r.State != thinVolumeDeleting {
+			continue
+		}
+		if err := p.vg.Remove(ctx, name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		logger.V(2).Info("removed deleted thin volume", "lv", name)
+		errs = append(errs, p.publish(ctx, name, thinVolumeDeleting))
+	}
+	return errors.Join(errs...)
+}
+
+// processSnapshots creates or deletes snapshots requested by the controller through the state of the pool.
+func (p *thinPool) processSnapshots(ctx context.Context, lvs []lvm.LogicalVolume) error {
+	state, err := getThinPoolState(ctx, p.client, p.nodeName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	logger := klog.FromContext(ctx)
+	var errs []error
+	for key := range state.Data {
+		name, ok := strings.CutPrefix(key, thinSnapshotKeyPrefix)
+		if !ok {
+			continue
+		}
+		r, err := thinSnapshotRequestOf(state, name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		switch r.State {
+		case thinSnapshotPending:
+			if !containsLV(lvs, name) {
+				if err := p.vg.CreateSnapshot(ctx, name, r.Source, thinSnapshotLVTag); err != nil {
+					r.State = thinSnapshotFailed
+					r.Message = err.Error()
+					logger.Error(err, "failed to create thin snapshot", "snapshot", name, "source", r.Source)
+				}
+			}
+			if r.State == thinSnapshotPending {
+				r.State = thinSnapshotReady
+				logger.V(2).Info("created thin snapshot", "snapshot", name, "source", r.Source)
+			}
+			errs = append(errs, updateThinPoolState(ctx, p.client, p.nodeName, func(state *v1.ConfigMap) error {
+				return setThinSnapshotRequest(state, name, r)
+			}))
+		case thinSnapshotDeleting:
+			if err := p.vg.Remove(ctx, name); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			logger.V(2).Info("removed thin snapshot", "snapshot", name)
+			errs = append(errs, updateThinPoolState(ctx, p.client, p.nodeName, func(state *v1.ConfigMap) error {
+				return setThinSnapshotRequest(state, name, nil)
+			}))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// grow resizes the pool disk when the data or metadata usage of the pool crosses the threshold.
+func (p *thinPool) grow(ctx context.Context, lvs []lvm.LogicalVolume) error {
+	pool := findLV(lvs, thinPoolLVName)
+	if pool == nil {
+		return nil
+	}
+	metadataFull := pool.MetadataPercent >= float64(p.config.GrowThreshold)
+	if pool.DataPercent < float64(p.config.GrowThreshold) && !metadataFull {
+		return nil
+	}
+	logger := klog.FromContext(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.diskID == "" {
+		disk, err := p.findPoolDisk(ctx)
+		if err != nil {
+			return err
+		}
+		if disk == nil {
+			return fmt.Errorf("thin pool disk of %s not found", p.instanceID)
+		}
+		p.diskID = disk.DiskId
+	}
+	disk, err := findDiskByID(p.diskID, p.ecs)
+	if err != nil {
+		return err
+	}
+	if disk == nil {
+		return fmt.Errorf("thin pool disk %s not found", p.diskID)
+	}
+	newGB := nextThinPoolSize(disk.Size, p.config.MaxGB)
+	if newGB <= disk.Size {
+		logger.Info("thin pool is almost full but the pool disk reached the max size",
+			"dataPercent", pool.DataPercent, "metadataPercent", pool.MetadataPercent, "disk", p.diskID, "sizeGB", disk.Size)
+		return nil
+	}
+
+	req := ecs.CreateResizeDiskRequest()
+	req.DiskId = p.diskID
+	req.NewSize = requests.NewInteger(newGB)
+	req.Type = "online"
+	if _, err := resizeDisk(ctx, p.ecs, req); err != nil {
+		return fmt.Errorf("failed to resize thin pool disk %s: %w", p.diskID, err)
+	}
+
+	pv, err := p.vg.PhysicalVolume(ctx)
+	if err != nil {
+		return err
+	}
+	// The block device may not grow immediately after ResizeDisk returns.
+	err = wait.PollUntilContextTimeout(ctx, time.Second, time.Minute, true, func(ctx context.Context) (bool, error) {
+		return getBlockDeviceCapacity(pv) >= utils.Gi2Bytes(int64(newGB)), nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for %s to grow to %dGiB: %w", pv, newGB, err)
+	}
+	// double the metadata along with the disk
+	var metadataGrow int64
+	if metadataFull {
+		metadataGrow = pool.MetadataSize
+	}
+	if err := p.vg.Grow(ctx, metadataGrow); err != nil {
+		return err
+	}
+	logger.V(1).Info("grew thin pool", "disk", p.diskID, "fromGB", disk.Size, "toGB", newGB,
+		"dataPercent", pool.DataPercent, "metadataPercent", pool.MetadataPercent, "metadataGrowBytes", metadataGrow)
+	return nil
+}
+
+// nextThinPoolSize doubles the pool disk size, up to maxGB.
+func nextThinPoolSize(currentGB, maxGB int) int {
+	return min(currentGB*2, maxGB)
+}
+
+// thinPoolFree returns the bytes left for new thin volumes.
+// The pool is not over-committed: the sum of the virtual sizes of thin volumes and snapshots is bounded by the max size of the pool disk,
+// since every block of a snapshot may diverge from its origin.
+// The data written to the pool by other logical volumes is also taken, as reported by data_percent.
+// Nothing is left once the metadata of the pool is almost full.
+// The node plugin refuses to create or extend volumes beyond it, and the controller reserves capacity against it.
+func thinPoolFree(lvs []lvm.LogicalVolume, maxGB int) int64 {
+	maxBytes := utils.Gi2Bytes(int64(maxGB))
+	free := maxBytes
+	for _, lv := range lvs {
+		if hasTag(lv, thinVolumeLVTag) || hasTag(lv, thinSnapshotLVTag) {
+			free -= lv.Size
+		}
+	}
+	if pool := findLV(lvs, thinPoolLVName); pool != nil {
+		if pool.MetadataPercent >= thinPoolMetadataFullPercent {
+			return 0
+		}
+		if pool.DataPercent > 0 {
+			free = min(free, maxBytes-int64(float64(pool.Size)*pool.DataPercent/100))
+		}
+	}
+	return max(free, 0)
+}
+
+// publish updates the capacity left in the pool in the state, and removes the request of the volume in one of states.
+// Both are done in one update, so that the controller never counts a volume twice, or not at all.
+// p.lvMu must be held.
+func (p *thinPool) publish(ctx context.Context, name string, states ...string) error {
+	var lvs []lvm.LogicalVolume
+	exists, err := p.vg.Exists(ctx)
+	if err == nil && exists {
+		lvs, err = p.vg.List(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	free := strconv.FormatInt(thinPoolFree(lvs, p.config.MaxGB), 10)
+	if name == "" && free == p.lastFree {
+		return nil
+	}
+	err = p.updateState(ctx, func(state *v1.ConfigMap) error {
+		if state.Data == nil {
+			state.Data = map[string]string{}
+		}
+		state.Data[thinPoolFreeKey] = free
+		if name == "" {
+			return nil
+		}
+		r, err := thinVolumeRequestOf(state, name)
+		if err != nil {
+			return err
+		}
+		if r != nil && slices.Contains(states, r.State) {
+			return setThinVolumeRequest(state, name, nil)
+		}
+		return nil
+	})
+	if err == nil {
+		p.lastFree = free
+	}
+	return err
+}
+
+// updateState is like updateThinPoolState, but creates the state owned by the Node if not found.
+func (p *thinPool) updateState(ctx context.Context, update func(state *v1.ConfigMap) error) error {
+	err := updateThinPoolState(ctx, p.client, p.nodeName, update)
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+	node, err := p.client.CoreV1().Nodes().Get(ctx, p.nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	state := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      thinPoolStateName(p.nodeName),
+			Namespace: thinPoolStateNamespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Node",
+				Name:       node.Name,
+				UID:        node.UID,
+			}},
+		},
+	}
+	if err := update(state); err != nil {
+		return err
+	}
+	_, err = p.client.CoreV1().ConfigMaps(thinPoolStateNamespace).Create(ctx, state, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return updateThinPoolState(ctx, p.client, p.nodeName, update)
+	}
+	return err
+}
+
+func (p *thinPool) publishFree(ctx context.Context) error {
+	p.lvMu.Lock()
+	defer p.lvMu.Unlock()
+	return p.publish(ctx, "")
+}
+
+func hasTag(lv lvm.LogicalVolume, tag string) bool {
+	return slices.Contains(lv.Tags, tag)
+}
+
+func findLV(lvs []lvm.LogicalVolume, name string) *lvm.LogicalVolume {
+	for i := range lvs {
+		if lvs[i].Name == name {
+			return &lvs[i]
+		}
+	}
+	return nil
+}
+
+func containsLV(lvs []lvm.LogicalVolume, name string) bool {
+	return findLV(lvs, name) != nil
+}
+
+func newThinPool(config thinPoolConfig, ecsClient cloud.ECSInterface, ad *DiskAttachDetach, client kubernetes.Interface, nodeName, instanceID, zoneID string) *thinPool {
+	return &thinPool{
+		config:     config,
+		vg:         lvm.New(thinPoolVGName, thinPoolLVName),
+		ecs:        ecsClient,
+		ad:         ad,
+		client:     client,
+		nodeName:   nodeName,
+		instanceID: instanceID,
+		zoneID:     zoneID,
+	}
+}
+
+func (ns *nodeServer) stageThinVolume(ctx context.Context, req *csi.NodeStageVolumeRequest, targetPath string) (*csi.NodeStageVolumeResponse, error) {
+	if ns.thinPool == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "thin volume %s staged on a node without %s feature gate", req.VolumeId, features.DiskThinPool)
+	}
+	device, err := ns.thinPool.stage(ctx, req.VolumeId, req.VolumeContext)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "failed to prepare thin volume: %v", err)
+	}
+	if err := ns.setupDisk(ctx, device, targetPath, req); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &csi.NodeStageVolumeResponse{}, nil
+}
+
+func (ns *nodeServer) expandThinVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
+	if ns.thinPool == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "thin volume %s expanded on a node without %s feature gate", req.VolumeId, features.DiskThinPool)
+	}
+	logger := klog.FromContext(ctx)
+	requestBytes := req.GetCapacityRange().GetRequiredBytes()
+	if err := ns.thinPool.expand(ctx, req.VolumeId, requestBytes); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "failed to extend thin volume: %v", err)
+	}
+	device := ns.thinPool.vg.DevicePath(thinVolumeName(req.VolumeId))
+	if req.VolumeCapability.GetBlock() == nil {
+		r := k8smount.NewResizeFs(utilexec.New())
+		if _, err := r.Resize(device, req.GetVolumePath()); err != nil {
+			return nil, status.Errorf(codes.Internal, "resize %s: %v", req.GetVolumePath(), err)
+		}
+	}
+	logger.V(2).Info("Expand thin volume successful", "device", device, "capacity", DiskSize{requestBytes})
+	return &csi.NodeExpandVolumeResponse{CapacityBytes: requestBytes}, nil
+}
@@ -0,0 +1,542 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/features"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// Lease of the replica deleting the pool disks of deleted nodes
+	thinPoolLease = "csi-disk-thin-pool"
+
+	thinPoolCollectInterval = 10 * time.Minute
+	// thinPoolDiskGracePeriod is how long a pool disk is kept after its node is found deleted.
+	thinPoolDiskGracePeriod = time.Hour
+
+	thinVolumeDeletePollInterval = time.Second
+	// thinVolumeDeleteTimeout is how long DeleteVolume waits for the node plugin to remove the volume, before retried.
+	thinVolumeDeleteTimeout = time.Minute
+)
+
+// thinPoolController is the controller server of thinPoolDriverName.
+// The node plugin owns the pool disk, and is asked to take snapshots or delete volumes
+// through the state ConfigMap of the pool of the node.
+// Capacity is reserved in the state by the controller too, until the node plugin creates or extends the volume.
+// The only OpenAPIs called are to delete the pool disks of deleted nodes.
+type thinPoolController struct {
+	common.GenericControllerServer
+	client kubernetes.Interface
+	ecs    cloud.ECSInterface
+	clk    clock.PassiveClock
+
+	// orphanSince is when pool disks were first found without their node, by disk ID
+	orphanSince map[string]time.Time
+}
+
+func newThinPoolController(client kubernetes.Interface, ecsClient cloud.ECSInterface) *thinPoolController {
+	return &thinPoolController{
+		client:      client,
+		ecs:         ecsClient,
+		clk:         clock.RealClock{},
+		orphanSince: map[string]time.Time{},
+	}
+}
+
+func (c *thinPoolController) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
+	return &csi.ControllerGetCapabilitiesResponse{
+		Capabilities: common.ControllerRPCCapabilities(
+			csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+			csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+			csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+			csi.ControllerServiceCapability_RPC_GET_CAPACITY,
+		),
+	}, nil
+}
+
+func (c *thinPoolController) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+	if !features.FunctionalMutableFeatureGate.Enabled(features.DiskThinPool) {
+		return nil, status.Errorf(codes.InvalidArgument, "thin volumes require %s feature gate", features.DiskThinPool)
+	}
+	multiAttachRequired, err := validateCapabilities(req.VolumeCapabilities)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if multiAttachRequired {
+		return nil, status.Error(codes.InvalidArgument, "thin volumes can not be attached to multiple nodes")
+	}
+	size := max(req.GetCapacityRange().GetRequiredBytes(), utils.Gi2Bytes(1))
+
+	nodeName := req.Parameters[NodeScheduleTag]
+	snapshotID, err := parseSnapshotID(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	snapshotName := ""
+	if snapshotID != "" {
+		var snapshotNode string
+		snapshotNode, snapshotName, err = parseThinSnapshotID(snapshotID)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if nodeName != "" && nodeName != snapshotNode {
+			return nil, status.Errorf(codes.ResourceExhausted, "thin snapshot %s can only be restored on node %s, but %s is selected", snapshotID, snapshotNode, nodeName)
+		}
+		nodeName = snapshotNode
+	}
+	if nodeName == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "thin volumes require volumeBindingMode: WaitForFirstConsumer")
+	}
+
+	node, err := c.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.ResourceExhausted, "node %s not found: %v", nodeName, err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get node %s: %v", nodeName, err)
+	}
+	instance := node.Labels[common.ECSInstanceIDTopologyKey]
+	if instance == "" {
+		return nil, status.Errorf(codes.ResourceExhausted, "node %s is not registered by the disk plugin", nodeName)
+	}
+	if snapshotName != "" {
+		state, err := getThinPoolState(ctx, c.client, nodeName)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, status.Errorf(codes.NotFound, "thin snapshot %s not found: %v", snapshotID, err)
+			}
+			return nil, status.Errorf(codes.Internal, "failed to get thin pool state of node %s: %v", nodeName, err)
+		}
+		r, err := thinSnapshotRequestOf(state, snapshotName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if r == nil || r.State != thinSnapshotReady {
+			return nil, status.Errorf(codes.NotFound, "thin snapshot %s is not ready", snapshotID)
+		}
+		size = max(size, r.SizeBytes)
+	}
+	volumeName := thinVolumeName(thinVolumeIDPrefix + req.Name)
+	if err := c.reserve(ctx, nodeName, volumeName, func(r *thinVolumeRequest) (*thinVolumeRequest, error) {
+		if r != nil && r.State != thinVolumeReserved {
+			return nil, status.Errorf(codes.AlreadyExists, "thin volume %s is %s on node %s", volumeName, r.State, nodeName)
+		}
+		// retried CreateVolume replaces the previous reservation
+		return &thinVolumeRequest{State: thinVolumeReserved, SizeBytes: size}, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	volumeContext := updateVolumeContext(req.Parameters)
+	if volumeContext == nil {
+		volumeContext = map[string]string{}
+	}
+	volumeContext[thinPoolNodeKey] = nodeName
+	volumeContext[thinPoolInstanceKey] = instance
+	volumeContext[thinVolumeSizeKey] = strconv.FormatInt(size, 10)
+	if snapshotName != "" {
+		volumeContext[thinSnapshotKey] = snapshotName
+	}
+	klog.FromContext(ctx).V(2).Info("thin volume created", "node", nodeName, "size", size, "snapshot", snapshotID)
+	return &csi.CreateVolumeResponse{
+		Volume: &csi.Volume{
+			VolumeId:      thinVolumeIDPrefix + req.Name,
+			CapacityBytes: size,
+			VolumeContext: volumeContext,
+			AccessibleTopology: []*csi.Topology{{
+				Segments: map[string]string{common.ECSInstanceIDTopologyKey: instance},
+			}},
+			ContentSource: volumeContentSource(snapshotID),
+		},
+	}, nil
+}
+
+// DeleteVolume asks the node plugin to remove the volume, and waits for it.
+// The capacity taken by the volume is only published after the removal,
+// so it is retried by the provisioner until then, with the PV kept Released.
+func (c *thinPoolController) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
+	pv, err := c.client.CoreV1().PersistentVolumes().Get(ctx, thinVolumeName(req.VolumeId), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			klog.FromContext(ctx).Info("PV of thin volume not found, the logical volume is kept", "volumeID", req.VolumeId)
+			return &csi.DeleteVolumeResponse{}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get PV of %s: %v", req.VolumeId, err)
+	}
+	if pv.Spec.CSI == nil || pv.Spec.CSI.VolumeAttributes[thinPoolNodeKey] == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "PV %s is not a thin volume", pv.Name)
+	}
+	nodeName := pv.Spec.CSI.VolumeAttributes[thinPoolNodeKey]
+	logger := klog.FromContext(ctx)
+	err = updateThinPoolState(ctx, c.client, nodeName, func(state *v1.ConfigMap) error {
+		return setThinVolumeRequest(state, pv.Name, &thinVolumeRequest{State: thinVolumeDeleting})
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// thin volumes can not outlive their node, and the state is deleted with the Node
+			return &csi.DeleteVolumeResponse{}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "failed to request volume deletion on node %s: %v", nodeName, err)
+	}
+	logger.V(2).Info("requested thin volume deletion", "node", nodeName, "pv", pv.Name)
+
+	err = wait.PollUntilContextTimeout(ctx, thinVolumeDeletePollInterval, thinVolumeDeleteTimeout, true, func(ctx context.Context) (bool, error) {
+		state, err := getThinPoolState(ctx, c.client, nodeName)
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			logger.Error(err, "failed to get thin pool state", "node", nodeName)
+			return false, nil
+		}
+		_, ok := state.Data[thinVolumeKeyPrefix+pv.Name]
+		return !ok, nil
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.DeadlineExceeded, "thin volume %s is not yet removed by the node plugin on %s: %v", pv.Name, nodeName, err)
+	}
+	logger.V(2).Info("thin volume removed", "node", nodeName, "pv", pv.Name)
+	return &csi.DeleteVolumeResponse{}, nil
+}
+
+func (c *thinPoolController) ValidateVolumeCapabilities(ctx context.Context, req *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
+	return thinVolumeCapabilitiesConfirmed(req), nil
+}
+
+// ControllerExpandVolume reserves the capacity to grow on the node. The volume is extended by the node plugin.
+func (c *thinPoolController) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
+	pv, err := c.client.CoreV1().PersistentVolumes().Get(ctx, thinVolumeName(req.VolumeId), metav1.GetOptions{})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get PV of %s: %v", req.VolumeId, err)
+	}
+	if pv.Spec.CSI == nil || pv.Spec.CSI.VolumeAttributes[thinPoolNodeKey] == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "PV %s is not a thin volume", pv.Name)
+	}
+	nodeName := pv.Spec.CSI.VolumeAttributes[thinPoolNodeKey]
+	size := req.GetCapacityRange().GetRequiredBytes()
+	capacity := pv.Spec.Capacity[v1.ResourceStorage]
+	// The PV is updated to the new size once this returns, so a retry grows nothing more.
+	if grow := size - capacity.Value(); grow > 0 {
+		err := c.reserve(ctx, nodeName, pv.Name, func(r *thinVolumeRequest) (*thinVolumeRequest, error) {
+			if r == nil {
+				return &thinVolumeRequest{State: thinVolumeExpanding, SizeBytes: grow}, nil
+			}
+			if r.State == thinVolumeDeleting {
+				return nil, status.Errorf(codes.FailedPrecondition, "thin volume %s is being deleted", pv.Name)
+			}
+			// not yet created or extended on the node
+			r.SizeBytes += grow
+			return r, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &csi.ControllerExpandVolumeResponse{CapacityBytes: size, NodeExpansionRequired: true}, nil
+}
+
+// reserve updates the request of the volume on the node, if the pool has enough capacity left for it.
+func (c *thinPoolController) reserve(ctx context.Context, nodeName, volumeName string,
+	update func(r *thinVolumeRequest) (*thinVolumeRequest, error),
+) error {
+	err := updateThinPoolState(ctx, c.client, nodeName, func(state *v1.ConfigMap) error {
+		free, ok, err := thinPoolAvailable(state, volumeName)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if !ok {
+			return status.Errorf(codes.ResourceExhausted, "thin pool is not enabled on node %s", nodeName)
+		}
+		r, err := thinVolumeRequestOf(state, volumeName)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		r, err = update(r)
+		if err != nil {
+			return err
+		}
+		if free < r.SizeBytes {
+			return status.Errorf(codes.ResourceExhausted, "thin pool on node %s has %d bytes left, %d requested", nodeName, free, r.SizeBytes)
+		}
+		return setThinVolumeRequest(state, volumeName, r)
+	})
+	if apierrors.IsNotFound(err) {
+		return status.Errorf(codes.ResourceExhausted, "thin pool is not enabled on node %s: %v", nodeName, err)
+	}
+	if _, ok := status.FromError(err); !ok {
+		return status.Errorf(codes.Internal, "failed to reserve capacity on node %s: %v", nodeName, err)
+	}
+	return err
+}
+
+func (c *thinPoolController) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
+	pv, err := c.client.CoreV1().PersistentVolumes().Get(ctx, thinVolumeName(req.SourceVolumeId), metav1.GetOptions{})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get PV of %s: %v", req.SourceVolumeId, err)
+	}
+	if pv.Spec.CSI == nil || pv.Spec.CSI.VolumeAttributes[thinPoolNodeKey] == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "PV %s is not a thin volume", pv.Name)
+	}
+	nodeName := pv.Spec.CSI.VolumeAttributes[thinPoolNodeKey]
+	state, err := getThinPoolState(ctx, c.client, nodeName)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get thin pool state of node %s: %v", nodeName, err)
+	}
+	r, err := thinSnapshotRequestOf(state, req.Name)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if r == nil {
+		capacity := pv.Spec.Capacity[v1.ResourceStorage]
+		r = &thinSnapshotRequest{
+			Source:       pv.Name,
+			State:        thinSnapshotPending,
+			SizeBytes:    capacity.Value(),
+			CreationTime: time.Now().Unix(),
+		}
+		err := updateThinPoolState(ctx, c.client, nodeName, func(state *v1.ConfigMap) error {
+			return setThinSnapshotRequest(state, req.Name, r)
+		})
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to request snapshot on node %s: %v", nodeName, err)
+		}
+		klog.FromContext(ctx).V(2).Info("requested thin snapshot", "node", nodeName, "source", pv.Name)
+	}
+	if r.Source != pv.Name {
+		return nil, status.Errorf(codes.AlreadyExists, "snapshot %s already exists with source %s", req.Name, r.Source)
+	}
+	if r.State == thinSnapshotFailed {
+		return nil, status.Errorf(codes.Internal, "failed to create snapshot on node %s: %s", nodeName, r.Message)
+	}
+	return &csi.CreateSnapshotResponse{
+		Snapshot: &csi.Snapshot{
+			SnapshotId:     thinSnapshotID(nodeName, req.Name),
+			SourceVolumeId: req.SourceVolumeId,
+			SizeBytes:      r.SizeBytes,
+			CreationTime:   timestamppb.New(time.Unix(r.CreationTime, 0)),
+			ReadyToUse:     r.State == thinSnapshotReady,
+		},
+	}, nil
+}
+
+func (c *thinPoolController) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
+	nodeName, snapshotName, err := parseThinSnapshotID(req.SnapshotId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	state, err := getThinPoolState(ctx, c.client, nodeName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// thin snapshots can not outlive their node, and the state is deleted with the Node
+			return &csi.DeleteSnapshotResponse{}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get thin pool state of node %s: %v", nodeName, err)
+	}
+	r, err := thinSnapshotRequestOf(state, snapshotName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if r == nil || r.State == thinSnapshotDeleting {
+		return &csi.DeleteSnapshotResponse{}, nil
+	}
+	r.State = thinSnapshotDeleting
+	err = updateThinPoolState(ctx, c.client, nodeName, func(state *v1.ConfigMap) error {
+		return setThinSnapshotRequest(state, snapshotName, r)
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to request snapshot deletion on node %s: %v", nodeName, err)
+	}
+	return &csi.DeleteSnapshotResponse{}, nil
+}
+
+// GetCapacity reports the capacity left in the thin pool of a node, excluding the reservations.
+func (c *thinPoolController) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
+	instance := req.GetAccessibleTopology().GetSegments()[common.ECSInstanceIDTopologyKey]
+	if instance == "" {
+		return &csi.GetCapacityResponse{}, nil
+	}
+	nodes, err := c.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{
+		LabelSelector: common.ECSInstanceIDTopologyKey + "=" + instance,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list nodes of %s: %v", instance, err)
+	}
+	for _, node := range nodes.Items {
+		state, err := getThinPoolState(ctx, c.client, node.Name)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get thin pool state of node %s: %v", node.Name, err)
+		}
+		free, ok, err := thinPoolAvailable(state, "")
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if ok {
+			return &csi.GetCapacityResponse{AvailableCapacity: free}, nil
+		}
+	}
+	return &csi.GetCapacityResponse{}, nil
+}
+
+// thinPoolAvailable returns the capacity published by the node plugin,
+// minus the capacity reserved for volumes other than excludeVolume.
+func thinPoolAvailable(state *v1.ConfigMap, excludeVolume string) (int64, bool, error) {
+	v, ok := state.Data[thinPoolFreeKey]
+	if !ok {
+		return 0, false, nil
+	}
+	free, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid free capacity in ConfigMap %s: %w", state.Name, err)
+	}
+	for key := range state.Data {
+		name, ok := strings.CutPrefix(key, thinVolumeKeyPrefix)
+		if !ok || name == excludeVolume {
+			continue
+		}
+		r, err := thinVolumeRequestOf(state, name)
+		if err != nil {
+			return 0, false, err
+		}
+		free -= r.SizeBytes
+	}
+	return max(free, 0), true, nil
+}
+
+// run deletes the pool disks of deleted nodes periodically until ctx is done.
+func (c *thinPoolController) run(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.collectPoolDisks(ctx); err != nil {
+			klog.ErrorS(err, "failed to collect thin pool disks")
+		}
+	}, thinPoolCollectInterval)
+}
+
+// collectPoolDisks deletes the pool disks whose node has been deleted for thinPoolDiskGracePeriod.
+// The node plugin can not do it, since it is gone with the node.
+// The grace period covers the time between the creation of the pool disk and the registration of the node,
+// and nodes deleted and registered again.
+func (c *thinPoolController) collectPoolDisks(ctx context.Context) error {
+	nodes, err := c.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	instances := map[string]bool{}
+	for _, node := range nodes.Items {
+		if instance := node.Labels[common.ECSInstanceIDTopologyKey]; instance != "" {
+			instances[instance] = true
+		}
+	}
+
+	req := ecs.CreateDescribeDisksRequest()
+	req.RegionId = GlobalConfigVar.Region
+	req.Tag = &[]ecs.DescribeDisksTag{{Key: ThinPoolDiskTagKey}}
+	req.MaxResults = requests.NewInteger(100)
+	var orphans []ecs.Disk
+	seen := map[string]bool{}
+	for {
+		resp, err := wrap.V1(ctx, c.ecs.DescribeDisks)(req)
+		if err != nil {
+			return fmt.Errorf("failed to describe thin pool disks: %w", err)
+		}
+		for _, disk := range resp.Disks.Disk {
+			instance := thinPoolDiskInstance(&disk)
+			if instance == "" || instances[instance] {
+				continue
+			}
+			seen[disk.DiskId] = true
+			if _, ok := c.orphanSince[disk.DiskId]; !ok {
+				c.orphanSince[disk.DiskId] = c.clk.Now()
+			}
+			if c.clk.Since(c.orphanSince[disk.DiskId]) >= thinPoolDiskGracePeriod {
+				orphans = append(orphans, disk)
+			}
+		}
+		if resp.NextToken == "" {
+			break
+		}
+		req.NextToken = resp.NextToken
+	}
+	for id := range c.orphanSince {
+		if !seen[id] {
+			delete(c.orphanSince, id)
+		}
+	}
+
+	logger := klog.FromContext(ctx)
+	var errs []error
+	for _, disk := range orphans {
+		switch disk.Status {
+		case DiskStatusInuse:
+			// deleted on the next run once detached
+			req := ecs.CreateDetachDiskRequest()
+			req.DiskId = disk.DiskId
+			req.InstanceId = disk.InstanceId
+			if _, err := wrap.V1(ctx, c.ecs.DetachDisk)(req); err != nil {
+				errs = append(errs, fmt.Errorf("failed to detach thin pool disk %s: %w", disk.DiskId, err))
+				continue
+			}
+			logger.V(1).Info("detaching thin pool disk of deleted node", "disk", disk.DiskId, "instance", disk.InstanceId)
+		case DiskStatusAvailable:
+			req := ecs.CreateDeleteDiskRequest()
+			req.DiskId = disk.DiskId
+			if _, err := wrap.V1(ctx, c.ecs.DeleteDisk)(req); err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete thin pool disk %s: %w", disk.DiskId, err))
+				continue
+			}
+			delete(c.orphanSince, disk.DiskId)
+			logger.V(1).Info("deleted thin pool disk of deleted node", "disk", disk.DiskId, "instance", thinPoolDiskInstance(&disk))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func thinPoolDiskInstance(disk *ecs.Disk) string {
+	for _, tag := range disk.Tags.Tag {
+		if tag.TagKey == ThinPoolDiskTagKey {
+			return tag.TagValue
+		}
+	}
+	return ""
+}
+
+func thinVolumeCapabilitiesConfirmed(req *csi.ValidateVolumeCapabilitiesRequest) *csi.ValidateVolumeCapabilitiesResponse {
+	multiAttachRequired, err := validateCapabilities(req.VolumeCapabilities)
+	if err == nil && multiAttachRequired {
+		err = fmt.Errorf("thin volumes can not be attached to multiple nodes")
+	}
+	if err != nil {
+		return &csi.ValidateVolumeCapabilitiesResponse{Message: err.Error()}
+	}
+	return &csi.ValidateVolumeCapabilitiesResponse{
+		Confirmed: &csi.ValidateVolumeCapabilitiesResponse_Confirmed{
+			VolumeCapabilities: req.VolumeCapabilities,
+		},
+	}
+}
@@ -0,0 +1,87 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
+	"k8s.io/client-go/kubernetes"
+)
+
+// thinPoolEndpoint returns the endpoint of thinPoolDriverName, in the same directory as the disk driver endpoint.
+func thinPoolEndpoint(endpoint string) string {
+	return endpoint[:strings.LastIndex(endpoint, "/")+1] + thinPoolSocket
+}
+
+// newThinPoolServers returns the servers of thinPoolDriverName.
+// The node server shares the thin pool with the disk node server ns, which can be nil on the controller.
+// The controller also deletes the pool disks of deleted nodes, on the replica holding thinPoolLease.
+func newThinPoolServers(client kubernetes.Interface, ecsClient cloud.ECSInterface, ns *nodeServer, controller bool) common.Servers {
+	servers := common.Servers{
+		IdentityServer: &thinPoolIdentityServer{
+			GenericIdentityServer: common.GenericIdentityServer{Name: thinPoolDriverName},
+		},
+	}
+	if controller {
+		c := newThinPoolController(client, ecsClient)
+		go runLeaderElected(client, thinPoolLease, c.run)
+		servers.ControllerServer = c
+	}
+	if ns != nil {
+		servers.NodeServer = &thinPoolNodeServer{nodeServer: ns}
+	}
+	return servers
+}
+
+type thinPoolIdentityServer struct {
+	common.GenericIdentityServer
+}
+
+func (iden *thinPoolIdentityServer) GetPluginCapabilities(ctx context.Context, req *csi.GetPluginCapabilitiesRequest) (*csi.GetPluginCapabilitiesResponse, error) {
+	return &csi.GetPluginCapabilitiesResponse{
+		Capabilities: []*csi.PluginCapability{
+			{
+				Type: &csi.PluginCapability_Service_{
+					Service: &csi.PluginCapability_Service{
+						Type: csi.PluginCapability_Service_CONTROLLER_SERVICE,
+					},
+				},
+			},
+			{
+				Type: &csi.PluginCapability_Service_{
+					Service: &csi.PluginCapability_Service{
+						Type: csi.PluginCapability_Service_VOLUME_ACCESSIBILITY_CONSTRAINTS,
+					},
+				},
+			},
+			{
+				Type: &csi.PluginCapability_VolumeExpansion_{
+					VolumeExpansion: &csi.PluginCapability_VolumeExpansion{
+						Type: csi.PluginCapability_VolumeExpansion_ONLINE,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// thinPoolNodeServer serves thin volumes with the disk node server,
+// but reports its own volume limit and topology, since thin volumes do not take attachment slots.
+type thinPoolNodeServer struct {
+	*nodeServer
+}
+
+func (ns *thinPoolNodeServer) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+	return &csi.NodeGetInfoResponse{
+		NodeId:            ns.NodeID,
+		MaxVolumesPerNode: int64(ns.thinPool.config.MaxVolumes),
+		AccessibleTopology: &csi.Topology{Segments: map[string]string{
+			common.ECSInstanceIDTopologyKey: metadata.MustGet(ns.metadata, metadata.InstanceID),
+		}},
+	}, nil
+}
@@ -0,0 +1,498 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/fake"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/lvm"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/features"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	featuregatetesting "k8s.io/component-base/featuregate/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func thinPoolNode() *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-1",
+			UID:    "node-1-uid",
+			Labels: map[string]string{common.ECSInstanceIDTopologyKey: "i-1"},
+		},
+	}
+}
+
+func thinPoolState(free string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: thinPoolStateName("node-1"), Namespace: thinPoolStateNamespace},
+		Data:       map[string]string{thinPoolFreeKey: free},
+	}
+}
+
+func getTestThinPoolState(t *testing.T, client kubernetes.Interface) *v1.ConfigMap {
+	t.Helper()
+	state, err := getThinPoolState(t.Context(), client, "node-1")
+	require.NoError(t, err)
+	return state
+}
+
+func thinCreateVolumeRequest(node string) *csi.CreateVolumeRequest {
+	return &csi.CreateVolumeRequest{
+		Name:          "pvc-1",
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 5 << 30},
+		Parameters:    map[string]string{NodeScheduleTag: node},
+		VolumeCapabilities: []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+		}},
+	}
+}
+
+func TestThinSnapshotID(t *testing.T) {
+	id := thinSnapshotID("node-1.example", "snapshot-1")
+	assert.True(t, isThinSnapshotID(id))
+	node, name, err := parseThinSnapshotID(id)
+	require.NoError(t, err)
+	assert.Equal(t, "node-1.example", node)
+	assert.Equal(t, "snapshot-1", name)
+
+	for _, id := range []string{"s-123", "thinsnap-node", "thinsnap-_snapshot", "thinsnap-node_"} {
+		_, _, err := parseThinSnapshotID(id)
+		assert.Error(t, err, id)
+	}
+}
+
+func TestThinPoolFree(t *testing.T) {
+	lvs := []lvm.LogicalVolume{
+		{Name: "pool", Size: 100 << 30, DataPercent: 5, MetadataPercent: 10},
+		{Name: "pvc-a", Size: 10 << 30, Pool: "pool", Tags: []string{thinVolumeLVTag}},
+		{Name: "snapshot-a", Size: 10 << 30, Pool: "pool", Origin: "pvc-a", Tags: []string{thinSnapshotLVTag}},
+	}
+	// snapshots take their size too
+	assert.Equal(t, int64(80<<30), thinPoolFree(lvs, 100))
+	assert.Equal(t, int64(0), thinPoolFree(lvs, 5))
+	assert.Equal(t, int64(100<<30), thinPoolFree(nil, 100))
+
+	// data written by other logical volumes
+	lvs[0].DataPercent = 50
+	assert.Equal(t, int64(50<<30), thinPoolFree(lvs, 100))
+
+	// metadata almost full
+	lvs[0].DataPercent = 5
+	lvs[0].MetadataPercent = thinPoolMetadataFullPercent
+	assert.Equal(t, int64(0), thinPoolFree(lvs, 100))
+}
+
+func TestNextThinPoolSize(t *testing.T) {
+	assert.Equal(t, 200, nextThinPoolSize(100, 2048))
+	assert.Equal(t, 2048, nextThinPoolSize(1500, 2048))
+	assert.Equal(t, 2048, nextThinPoolSize(2048, 2048))
+}
+
+func TestThinCreateVolume(t *testing.T) {
+	featuregatetesting.SetFeatureGateDuringTest(t, features.FunctionalMutableFeatureGate, features.DiskThinPool, true)
+	c := thinPoolController{client: k8sfake.NewClientset(thinPoolNode(), thinPoolState("107374182400"))}
+
+	resp, err := c.CreateVolume(t.Context(), thinCreateVolumeRequest("node-1"))
+	require.NoError(t, err)
+	vol := resp.Volume
+	assert.Equal(t, "thin-pvc-1", vol.VolumeId)
+	assert.Equal(t, int64(5<<30), vol.CapacityBytes)
+	assert.Equal(t, "node-1", vol.VolumeContext[thinPoolNodeKey])
+	assert.Equal(t, "5368709120", vol.VolumeContext[thinVolumeSizeKey])
+	assert.NotContains(t, vol.VolumeContext, NodeScheduleTag)
+	assert.Equal(t, map[string]string{common.ECSInstanceIDTopologyKey: "i-1"}, vol.AccessibleTopology[0].Segments)
+
+	r, err := thinVolumeRequestOf(getTestThinPoolState(t, c.client), "pvc-1")
+	require.NoError(t, err)
+	assert.Equal(t, &thinVolumeRequest{State: thinVolumeReserved, SizeBytes: 5 << 30}, r)
+
+	// retry does not reserve twice
+	_, err = c.CreateVolume(t.Context(), thinCreateVolumeRequest("node-1"))
+	require.NoError(t, err)
+	free, _, err := thinPoolAvailable(getTestThinPoolState(t, c.client), "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(95<<30), free)
+}
+
+func TestThinCreateVolumeReserved(t *testing.T) {
+	featuregatetesting.SetFeatureGateDuringTest(t, features.FunctionalMutableFeatureGate, features.DiskThinPool, true)
+	state := thinPoolState("10737418240")
+	require.NoError(t, setThinVolumeRequest(state, "pvc-0", &thinVolumeRequest{State: thinVolumeReserved, SizeBytes: 6 << 30}))
+	c := thinPoolController{client: k8sfake.NewClientset(thinPoolNode(), state)}
+
+	// 10Gi published, but 6Gi is reserved by a volume not yet created on the node
+	_, err := c.CreateVolume(t.Context(), thinCreateVolumeRequest("node-1"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), err)
+}
+
+func TestThinCreateVolumeErrors(t *testing.T) {
+	featuregatetesting.SetFeatureGateDuringTest(t, features.FunctionalMutableFeatureGate, features.DiskThinPool, true)
+	cases := []struct {
+		name    string
+		objects []runtime.Object
+		req     *csi.CreateVolumeRequest
+		code    codes.Code
+	}{
+		{"immediate binding", []runtime.Object{thinPoolNode(), thinPoolState("107374182400")}, thinCreateVolumeRequest(""), codes.InvalidArgument},
+		{"node not found", []runtime.Object{thinPoolNode(), thinPoolState("107374182400")}, thinCreateVolumeRequest("node-2"), codes.ResourceExhausted},
+		{"pool disabled", []runtime.Object{thinPoolNode()}, thinCreateVolumeRequest("node-1"), codes.ResourceExhausted},
+		{"pool full", []runtime.Object{thinPoolNode(), thinPoolState("1073741824")}, thinCreateVolumeRequest("node-1"), codes.ResourceExhausted},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := thinPoolController{client: k8sfake.NewClientset(tc.objects...)}
+			_, err := c.CreateVolume(t.Context(), tc.req)
+			assert.Equal(t, tc.code, status.Code(err), err)
+		})
+	}
+}
+
+func TestThinCreateVolumeFeatureDisabled(t *testing.T) {
+	c := thinPoolController{client: k8sfake.NewClientset(thinPoolNode(), thinPoolState("107374182400"))}
+	_, err := c.CreateVolume(t.Context(), thinCreateVolumeRequest("node-1"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestThinGetCapacity(t *testing.T) {
+	c := thinPoolController{client: k8sfake.NewClientset(thinPoolNode(), thinPoolState("1073741824"))}
+	topology := &csi.Topology{Segments: map[string]string{common.ECSInstanceIDTopologyKey: "i-1"}}
+
+	resp, err := c.GetCapacity(t.Context(), &csi.GetCapacityRequest{
+		AccessibleTopology: topology,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1<<30), resp.AvailableCapacity)
+
+	resp, err = c.GetCapacity(t.Context(), &csi.GetCapacityRequest{
+		AccessibleTopology: &csi.Topology{Segments: map[string]string{common.ECSInstanceIDTopologyKey: "i-2"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), resp.AvailableCapacity)
+
+	state := thinPoolState("1073741824")
+	require.NoError(t, setThinVolumeRequest(state, "pvc-0", &thinVolumeRequest{State: thinVolumeExpanding, SizeBytes: 1 << 29}))
+	c = thinPoolController{client: k8sfake.NewClientset(thinPoolNode(), state)}
+	resp, err = c.GetCapacity(t.Context(), &csi.GetCapacityRequest{AccessibleTopology: topology})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1<<29), resp.AvailableCapacity)
+}
+
+func thinPV(name string, size string) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PersistentVolumeSpec{
+			Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse(size)},
+			PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{
+				Driver:           thinPoolDriverName,
+				VolumeHandle:     thinVolumeIDPrefix + name,
+				VolumeAttributes: map[string]string{thinPoolNodeKey: "node-1"},
+			}},
+		},
+	}
+}
+
+func TestThinControllerExpandVolume(t *testing.T) {
+	client := k8sfake.NewClientset(thinPoolNode(), thinPoolState("10737418240"), thinPV("pvc-1", "5Gi"))
+	c := thinPoolController{client: client}
+	expand := func(size int64) error {
+		_, err := c.ControllerExpandVolume(t.Context(), &csi.ControllerExpandVolumeRequest{
+			VolumeId:      "thin-pvc-1",
+			CapacityRange: &csi.CapacityRange{RequiredBytes: size},
+		})
+		return err
+	}
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(expand(20<<30)))
+	require.NoError(t, expand(8<<30))
+	require.NoError(t, expand(5<<30))
+
+	r, err := thinVolumeRequestOf(getTestThinPoolState(t, client), "pvc-1")
+	require.NoError(t, err)
+	assert.Equal(t, &thinVolumeRequest{State: thinVolumeExpanding, SizeBytes: 3 << 30}, r)
+}
+
+func TestThinDeleteVolume(t *testing.T) {
+	client := k8sfake.NewClientset(thinPoolNode(), thinPoolState("10737418240"), thinPV("pvc-1", "5Gi"))
+	c := thinPoolController{client: client}
+	runner := &fakeLVM{lvs: `{"report":[{"lv":[{"lv_name":"pvc-1","lv_size":"5368709120","pool_lv":"pool","lv_tags":"csi-volume"}]}]}`}
+	p := &thinPool{
+		config:   thinPoolConfig{MaxGB: 100},
+		vg:       lvm.NewWithRunner(thinPoolVGName, thinPoolLVName, runner.run),
+		client:   client,
+		nodeName: "node-1",
+	}
+
+	// the node plugin does not remove it in time
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	_, err := c.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: "thin-pvc-1"})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err), err)
+	r, err := thinVolumeRequestOf(getTestThinPoolState(t, client), "pvc-1")
+	require.NoError(t, err)
+	assert.Equal(t, &thinVolumeRequest{State: thinVolumeDeleting}, r)
+
+	// removed by the node plugin later, with its capacity published at once
+	require.NoError(t, p.processVolumes(t.Context()))
+	assert.Contains(t, runner.calls, "lvremove -y csi-thinpool/pvc-1")
+	state := getTestThinPoolState(t, client)
+	assert.NotContains(t, state.Data, thinVolumeKeyPrefix+"pvc-1")
+	assert.Equal(t, "102005473280", state.Data[thinPoolFreeKey])
+}
+
+func TestThinDeleteVolumeWaits(t *testing.T) {
+	client := k8sfake.NewClientset(thinPoolNode(), thinPoolState("10737418240"), thinPV("pvc-1", "5Gi"))
+	c := thinPoolController{client: client}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// removed by the node plugin
+		_ = wait.PollUntilContextTimeout(t.Context(), 10*time.Millisecond, 10*time.Second, true, func(ctx context.Context) (bool, error) {
+			err := updateThinPoolState(ctx, client, "node-1", func(state *v1.ConfigMap) error {
+				r, err := thinVolumeRequestOf(state, "pvc-1")
+				if err != nil || r == nil {
+					return errors.New("not requested yet")
+				}
+				return setThinVolumeRequest(state, "pvc-1", nil)
+			})
+			return err == nil, nil
+		})
+	}()
+	_, err := c.DeleteVolume(t.Context(), &csi.DeleteVolumeRequest{VolumeId: "thin-pvc-1"})
+	require.NoError(t, err)
+	<-done
+	assert.NotContains(t, getTestThinPoolState(t, client).Data, thinVolumeKeyPrefix+"pvc-1")
+
+	// nothing to do without the PV
+	_, err = c.DeleteVolume(t.Context(), &csi.DeleteVolumeRequest{VolumeId: "thin-pvc-2"})
+	require.NoError(t, err)
+
+	// the state is deleted with the node
+	require.NoError(t, client.CoreV1().ConfigMaps(thinPoolStateNamespace).Delete(t.Context(), thinPoolStateName("node-1"), metav1.DeleteOptions{}))
+	_, err = c.DeleteVolume(t.Context(), &csi.DeleteVolumeRequest{VolumeId: "thin-pvc-1"})
+	require.NoError(t, err)
+}
+
+type fakeLVM struct {
+	lvs   string
+	calls []string
+}
+
+func (f *fakeLVM) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, strings.Join(append([]string{name}, args...), " "))
+	switch name {
+	case "lvs":
+		return []byte(f.lvs), nil
+	case "vgs":
+		return []byte(thinPoolVGName), nil
+	}
+	return nil, nil
+}
+
+func TestThinSnapshotLifecycle(t *testing.T) {
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+		Spec: v1.PersistentVolumeSpec{
+			Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse("5Gi")},
+			PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{
+				VolumeHandle:     "thin-pvc-1",
+				VolumeAttributes: map[string]string{thinPoolNodeKey: "node-1"},
+			}},
+		},
+	}
+	client := k8sfake.NewClientset(thinPoolNode(), thinPoolState("107374182400"), pv)
+	c := thinPoolController{client: client}
+	req := &csi.CreateSnapshotRequest{Name: "snapshot-1", SourceVolumeId: "thin-pvc-1"}
+
+	resp, err := c.CreateSnapshot(t.Context(), req)
+	require.NoError(t, err)
+	assert.False(t, resp.Snapshot.ReadyToUse)
+	assert.Equal(t, "thinsnap-node-1_snapshot-1", resp.Snapshot.SnapshotId)
+	assert.Equal(t, int64(5<<30), resp.Snapshot.SizeBytes)
+
+	runner := &fakeLVM{lvs: `{"report":[{"lv":[{"lv_name":"pvc-1","lv_size":"5368709120","pool_lv":"pool","lv_tags":"csi-volume"}]}]}`}
+	p := &thinPool{
+		vg:       lvm.NewWithRunner(thinPoolVGName, thinPoolLVName, runner.run),
+		client:   client,
+		nodeName: "node-1",
+	}
+	lvs, err := p.vg.List(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, p.processSnapshots(t.Context(), lvs))
+	assert.Contains(t, runner.calls, "lvcreate -y --snapshot -n snapshot-1 --addtag csi-snapshot csi-thinpool/pvc-1")
+
+	resp, err = c.CreateSnapshot(t.Context(), req)
+	require.NoError(t, err)
+	assert.True(t, resp.Snapshot.ReadyToUse)
+
+	_, err = c.DeleteSnapshot(t.Context(), &csi.DeleteSnapshotRequest{SnapshotId: resp.Snapshot.SnapshotId})
+	require.NoError(t, err)
+	r, err := thinSnapshotRequestOf(getTestThinPoolState(t, client), "snapshot-1")
+	require.NoError(t, err)
+	assert.Equal(t, thinSnapshotDeleting, r.State)
+
+	runner.lvs = `{"report":[{"lv":[{"lv_name":"snapshot-1","lv_size":"5368709120","pool_lv":"pool","origin":"pvc-1"}]}]}`
+	require.NoError(t, p.processSnapshots(t.Context(), nil))
+	assert.Contains(t, runner.calls, "lvremove -y csi-thinpool/snapshot-1")
+	assert.NotContains(t, getTestThinPoolState(t, client).Data, thinSnapshotKeyPrefix+"snapshot-1")
+}
+
+func TestThinPoolProcessVolumes(t *testing.T) {
+	state := thinPoolState("0")
+	require.NoError(t, setThinVolumeRequest(state, "pvc-2", &thinVolumeRequest{State: thinVolumeDeleting}))
+	client := k8sfake.NewClientset(thinPoolNode(), state)
+	runner := &fakeLVM{lvs: `{"report":[{"lv":[
+		{"lv_name":"pool","lv_size":"107374182400"},
+		{"lv_name":"pvc-1","lv_size":"5368709120","pool_lv":"pool","lv_tags":"csi-volume"},
+		{"lv_name":"pvc-2","lv_size":"5368709120","pool_lv":"pool","lv_tags":"csi-volume"},
+		{"lv_name":"other","lv_size":"5368709120","pool_lv":"pool"}
+	]}]}`}
+	p := &thinPool{
+		config:   thinPoolConfig{MaxGB: 100},
+		vg:       lvm.NewWithRunner(thinPoolVGName, thinPoolLVName, runner.run),
+		client:   client,
+		nodeName: "node-1",
+	}
+	// pvc-1 has no PV, but is not deleted by DeleteVolume, e.g. with Retain reclaim policy
+	require.NoError(t, p.processVolumes(t.Context()))
+	var removed []string
+	for _, c := range runner.calls {
+		if strings.HasPrefix(c, "lvremove") {
+			removed = append(removed, c)
+		}
+	}
+	assert.Equal(t, []string{"lvremove -y csi-thinpool/pvc-2"}, removed)
+
+	state = getTestThinPoolState(t, client)
+	assert.NotContains(t, state.Data, thinVolumeKeyPrefix+"pvc-2")
+	assert.NotEqual(t, "0", state.Data[thinPoolFreeKey])
+}
+
+func TestThinPoolPublishCreatesState(t *testing.T) {
+	client := k8sfake.NewClientset(thinPoolNode())
+	runner := &fakeLVM{lvs: `{"report":[{"lv":[
+		{"lv_name":"pool","lv_size":"107374182400"},
+		{"lv_name":"pvc-1","lv_size":"5368709120","pool_lv":"pool","lv_tags":"csi-volume"}
+	]}]}`}
+	p := &thinPool{
+		config:   thinPoolConfig{MaxGB: 10},
+		vg:       lvm.NewWithRunner(thinPoolVGName, thinPoolLVName, runner.run),
+		client:   client,
+		nodeName: "node-1",
+	}
+	require.NoError(t, p.publishFree(t.Context()))
+	state := getTestThinPoolState(t, client)
+	assert.Equal(t, "5368709120", state.Data[thinPoolFreeKey])
+	// deleted with the node
+	require.Len(t, state.OwnerReferences, 1)
+	assert.Equal(t, metav1.OwnerReference{APIVersion: "v1", Kind: "Node", Name: "node-1", UID: "node-1-uid"}, state.OwnerReferences[0])
+}
+
+func TestHasThinPoolWork(t *testing.T) {
+	state := thinPoolState("0")
+	require.NoError(t, setThinVolumeRequest(state, "pvc-1", &thinVolumeRequest{State: thinVolumeReserved, SizeBytes: 1 << 30}))
+	require.NoError(t, setThinSnapshotRequest(state, "snapshot-1", &thinSnapshotRequest{State: thinSnapshotReady}))
+	assert.False(t, hasThinPoolWork(state))
+
+	require.NoError(t, setThinSnapshotRequest(state, "snapshot-2", &thinSnapshotRequest{State: thinSnapshotPending}))
+	assert.True(t, hasThinPoolWork(state))
+	require.NoError(t, setThinSnapshotRequest(state, "snapshot-2", nil))
+	require.NoError(t, setThinVolumeRequest(state, "pvc-1", &thinVolumeRequest{State: thinVolumeDeleting}))
+	assert.True(t, hasThinPoolWork(state))
+}
+
+func TestThinPoolExpand(t *testing.T) {
+	state := thinPoolState("0")
+	require.NoError(t, setThinVolumeRequest(state, "pvc-1", &thinVolumeRequest{State: thinVolumeExpanding, SizeBytes: 3 << 30}))
+	client := k8sfake.NewClientset(thinPoolNode(), state)
+	runner := &fakeLVM{lvs: `{"report":[{"lv":[
+		{"lv_name":"pool","lv_size":"107374182400"},
+		{"lv_name":"pvc-1","lv_size":"5368709120","pool_lv":"pool","lv_tags":"csi-volume"}
+	]}]}`}
+	p := &thinPool{
+		config:   thinPoolConfig{MaxGB: 10},
+		vg:       lvm.NewWithRunner(thinPoolVGName, thinPoolLVName, runner.run),
+		client:   client,
+		nodeName: "node-1",
+	}
+
+	err := p.expand(t.Context(), "thin-pvc-1", 11<<30)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), err)
+
+	require.NoError(t, p.expand(t.Context(), "thin-pvc-1", 8<<30))
+	assert.Contains(t, runner.calls, "lvextend -L 8589934592b csi-thinpool/pvc-1")
+	assert.NotContains(t, getTestThinPoolState(t, client).Data, thinVolumeKeyPrefix+"pvc-1")
+}
+
+func TestThinPoolEndpoint(t *testing.T) {
+	assert.Equal(t, "unix://csi/diskplugin.csi.alibabacloud.com/thinpool.sock",
+		thinPoolEndpoint("unix://csi/diskplugin.csi.alibabacloud.com/csi.sock"))
+}
+
+func TestThinPoolCollectPoolDisks(t *testing.T) {
+	cloud := fake.New(fake.Options{})
+	cloud.AddZone("cn-hangzhou-a", string(DiskESSD))
+	createPoolDisk := func(instance string) string {
+		req := ecs.CreateCreateDiskRequest()
+		req.ZoneId = "cn-hangzhou-a"
+		req.DiskCategory = string(DiskESSD)
+		req.Size = requests.NewInteger(100)
+		req.Tag = &[]ecs.CreateDiskTag{{Key: ThinPoolDiskTagKey, Value: instance}}
+		resp, err := cloud.CreateDisk(req)
+		require.NoError(t, err)
+		return resp.DiskId
+	}
+	kept := createPoolDisk("i-1")
+	detached := createPoolDisk("i-2")
+	attached := createPoolDisk("i-3")
+	cloud.AddInstance("i-3", "cn-hangzhou-a")
+	attach := ecs.CreateAttachDiskRequest()
+	attach.DiskId = attached
+	attach.InstanceId = "i-3"
+	_, err := cloud.AttachDisk(attach)
+	require.NoError(t, err)
+
+	clk := clocktesting.NewFakeClock(time.Now())
+	c := newThinPoolController(k8sfake.NewClientset(thinPoolNode()), cloud)
+	c.clk = clk
+	exists := func(diskID string) bool {
+		_, ok := cloud.Disk(diskID)
+		return ok
+	}
+
+	// nodes of i-2 and i-3 are not found, but may be registered soon
+	require.NoError(t, c.collectPoolDisks(t.Context()))
+	assert.True(t, exists(detached))
+	assert.True(t, exists(attached))
+
+	clk.Step(thinPoolDiskGracePeriod)
+	require.NoError(t, c.collectPoolDisks(t.Context()))
+	assert.False(t, exists(detached))
+	// detached first
+	d, _ := cloud.Disk(attached)
+	assert.Empty(t, d.InstanceId)
+
+	require.NoError(t, c.collectPoolDisks(t.Context()))
+	assert.False(t, exists(attached))
+	assert.True(t, exists(kept))
+	assert.Empty(t, c.orphanSince)
+}
@@ -401,15 +401,6 @@ func parseTags(params map[string]string) (map[string]string, error) {
 	return seenTags, nil
 }
 
-func pvcRef(params map[string]string) *v1.ObjectReference {
-	return &v1.ObjectReference{
-		APIVersion: "v1",
-		Kind:       "PersistentVolumeClaim",
-		Namespace:  params[common.PVCNamespaceKey],
-		Name:       params[common.PVCNameKey],
-	}
-}
-
 var ErrUnknownZone = errors.New("unknown zone")
 
 func getZone(req *csi.CreateVolumeRequest, recorder record.EventRecorder) (string, error) {
@@ -451,7 +442,7 @@ func getZone(req *csi.CreateVolumeRequest, recorder record.EventRecorder) (strin
 		if len(missedZones) > 0 {
 			msg = fmt.Sprintf("conflicting zone, parameters specified: %s, accessibility requires: %v", zoneID, sets.List(missedZones))
 		}
-		recorder.Event(pvcRef(req.Parameters), v1.EventTypeWarning, "ConflictingZone", msg)
+		recorder.Event(common.PVCRef(req.Parameters), v1.EventTypeWarning, "ConflictingZone", msg)
 	}
 
 	// Best effort to pick what we have
@@ -496,6 +487,24 @@ func getDiskVolumeOptions(
 		}
 	}
 
+	profile, err := getFsProfile(volOptions)
+	if err != nil {
+		return nil, err
+	}
+	if profile != nil {
+		for _, cap := range req.GetVolumeCapabilities() {
+			if err := profile.checkFsType(volOptions[FsProfileTag], cap.GetMount().GetFsType()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err := getVolumeLayout(volOptions); err != nil {
+		return nil, err
+	}
+	if _, err := getIOPSAutotune(volOptions); err != nil {
+		return nil, err
+	}
+
 	// disk Type
 	diskType, err := validateDiskType(volOptions)
 	if err != nil {
@@ -901,12 +910,12 @@ func patchForNode(node *v1.Node, maxVolumesNum int, diskTypes []string) []byte {
 	return patch
 }
 
-func volumeCreate(attempt createAttempt, diskID string, volSizeBytes int64, volumeContext map[string]string, zoneID string, contextSource *csi.VolumeContentSource) *csi.Volume {
+func volumeCreate(attempt createAttempt, diskID string, volSizeBytes int64, volumeContext map[string]string, regionID, zoneID string, contextSource *csi.VolumeContentSource) *csi.Volume {
 	segments := map[string]string{}
 	cateDesc := AllCategories[attempt.Category]
-	volumeContext[labelAppendPrefix+TopologyRegionKey] = GlobalConfigVar.Region
+	volumeContext[labelAppendPrefix+TopologyRegionKey] = regionID
 	if cateDesc.Regional {
-		segments[RegionalDiskTopologyKey] = GlobalConfigVar.Region
+		segments[RegionalDiskTopologyKey] = regionID
 	} else {
 		segments[ZonalDiskTopologyKey] = zoneID
 		volumeContext[labelAppendPrefix+TopologyZoneKey] = zoneID
@@ -984,14 +993,13 @@ func volumeContentSource(snapshotID string) *csi.VolumeContentSource {
 
 // staticVolumeCreate 检查输入参数，如果包含了云盘ID，则直接使用云盘进行返回；
 // 根据云盘ID请求云盘的具体属性，并作为pv参数返回；
-func staticVolumeCreate(req *csi.CreateVolumeRequest) (*csi.Volume, error) {
+func staticVolumeCreate(ctx context.Context, req *csi.CreateVolumeRequest, ecsClient cloud.ECSInterface) (*csi.Volume, error) {
 	paras := req.GetParameters()
 	diskID := paras[annDiskID]
 	if diskID == "" {
 		return nil, nil
 	}
 
-	ecsClient := GlobalConfigVar.EcsClient
 	disk, err := findDiskByID(diskID, ecsClient)
 	if err != nil {
 		return nil, err
@@ -1019,13 +1027,17 @@ func staticVolumeCreate(req *csi.CreateVolumeRequest) (*csi.Volume, error) {
 	if err != nil {
 		return nil, err
 	}
-	tagDiskUserTags(diskID, tags)
+	tagDiskUserTags(ctx, diskID, tags, ecsClient)
 
 	attempt := createAttempt{
 		Category(disk.Category), PerformanceLevel(disk.PerformanceLevel),
 		"", // no instanceID for virtual-kubelet.
 	}
-	return volumeCreate(attempt, diskID, volSizeBytes, volumeContext, disk.ZoneId, volumeContentSource(snapshotID)), nil
+	regionID := req.Parameters[RegionIDKey]
+	if regionID == "" {
+		regionID = GlobalConfigVar.Region
+	}
+	return volumeCreate(attempt, diskID, volSizeBytes, volumeContext, regionID, disk.ZoneId, volumeContentSource(snapshotID)), nil
 }
 
 // updateVolumeContext remove unnecessary volume context
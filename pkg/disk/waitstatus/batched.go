@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/desc"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/tracing"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 )
@@ -65,7 +66,9 @@ func NewBatched[T any](ecsClient desc.Client[T], clk clock.WithTicker, defaultIn
 	}
 }
 
-func (w *Batched[T]) WaitFor(ctx context.Context, id string, pred StatusPredicate[*T]) (*T, error) {
+func (w *Batched[T]) WaitFor(ctx context.Context, id string, pred StatusPredicate[*T]) (res *T, err error) {
+	ctx, span := tracing.Start(ctx, "StatusWaiter.WaitFor", tracing.ResourceIDKey.String(id))
+	defer func() { tracing.End(span, err) }()
 	resultChan := make(chan waitResponse[*T], 1) // in case this wait is canceled, don't block poll()
 	w.requestChan <- &waitRequest[*T]{
 		ctx:        ctx,
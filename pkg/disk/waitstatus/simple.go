@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/desc"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/tracing"
 	"k8s.io/utils/clock"
 )
 
@@ -20,7 +21,9 @@ func NewSimple[T any](client desc.Client[T], clk clock.WithTicker) *Simple[T] {
 	}
 }
 
-func (w *Simple[T]) WaitFor(ctx context.Context, id string, pred StatusPredicate[*T]) (*T, error) {
+func (w *Simple[T]) WaitFor(ctx context.Context, id string, pred StatusPredicate[*T]) (res *T, err error) {
+	ctx, span := tracing.Start(ctx, "StatusWaiter.WaitFor", tracing.ResourceIDKey.String(id))
+	defer func() { tracing.End(span, err) }()
 	ticker := w.clk.NewTicker(pollInterval)
 	defer ticker.Stop()
 	for {
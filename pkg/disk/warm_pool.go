@@ -0,0 +1,116 @@
+//go:build !windows
+
+package disk
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/warmpool"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+	"k8s.io/klog/v2"
+)
+
+const warmPoolLease = "csi-disk-warm-pool"
+
+// newWarmPool starts the warm pool of the default identity, following changes of the csi-plugin ConfigMap.
+func newWarmPool(csiCfg utils.Config, ecsClient cloud.ECSInterface) *warmpool.Pool {
+	cfg, err := warmpool.ParseConfig(csiCfg)
+	if err != nil {
+		klog.ErrorS(err, "Warm pool disabled")
+	}
+	klog.InfoS("Disk warm pool", "specs", cfg.Specs, "ttl", cfg.TTL)
+	pool := warmpool.New(ecsClient, warmpool.Options{
+		RegionID:        GlobalConfigVar.Region,
+		Tags:            getDefaultDiskTags(&diskVolumeArgs{}),
+		VolumeTagKey:    common.VolumeNameTag,
+		CreateThrottler: limitedThrottler(cloudIdentity{}, "CreateDisk", throttle.PriorityLow),
+		DeleteThrottler: limitedThrottler(cloudIdentity{}, "DeleteDisk", throttle.PriorityLow),
+		// adoption serves CreateVolume
+		ResizeThrottler: limitedThrottler(cloudIdentity{}, "ResizeDisk", throttle.PriorityNormal),
+		ModifyThrottler: limitedThrottler(cloudIdentity{}, "ModifyDiskAttribute", throttle.PriorityNormal),
+		TagThrottler:    limitedThrottler(cloudIdentity{}, "AddTags", throttle.PriorityNormal),
+	})
+	pool.SetConfig(cfg)
+
+	utils.CSIPluginConfig.RegisterKeys(warmpool.SpecsKey, warmpool.TTLKey)
+	utils.CSIPluginConfig.Subscribe(func(c utils.Config) {
+		newCfg, err := warmpool.ParseConfig(c)
+		if err != nil {
+			klog.ErrorS(err, "Invalid warm pool config, ignored")
+			return
+		}
+		if slices.Equal(newCfg.Specs, cfg.Specs) && newCfg.TTL == cfg.TTL {
+			return
+		}
+		klog.InfoS("Disk warm pool changed", "specs", newCfg.Specs, "ttl", newCfg.TTL)
+		cfg = newCfg
+		pool.SetConfig(cfg)
+	})
+	if GlobalConfigVar.ClientSet == nil {
+		go pool.Run(context.Background())
+		return pool
+	}
+	// Any replica adopts from the pool, but only the leader refills it, otherwise replicas would create too many disks.
+	go runLeaderElected(GlobalConfigVar.ClientSet, warmPoolLease, pool.Run)
+	return pool
+}
+
+// warmPoolEligible reports whether a pooled disk, created with default parameters, can serve the volume.
+func warmPoolEligible(diskVol *diskVolumeArgs, attempt createAttempt) bool {
+	desc := AllCategories[attempt.Category]
+	return !diskVol.Encrypted && !diskVol.MultiAttach && len(diskVol.ARN) == 0 &&
+		diskVol.ResourceGroupID == "" && diskVol.StorageClusterID == "" &&
+		!(desc.ProvisionedIops && diskVol.ProvisionedIops > 0) &&
+		!(desc.Bursting && diskVol.BurstingEnabled) &&
+		!desc.Regional && !desc.SingleInstance
+}
+
+// adoptWarmDisk tries to serve the volume with a disk from the warm pool.
+// An empty ID is returned if no pooled disk fits, and the disk should be created as usual.
+// existingDisk is returned if the volume already has a disk, after finishing its adoption if interrupted.
+// If the adoption fails after a pooled disk is claimed, its ID is returned with the error,
+// and the volume must not fall back to a new disk.
+func (c *DiskCreateDelete) adoptWarmDisk(ctx context.Context, diskName string, diskVol *diskVolumeArgs, attempt createAttempt) (diskID string, existingDisk *ecs.Disk, err error) {
+	req := warmpool.Request{
+		Zone:             diskVol.ZoneID,
+		Category:         string(attempt.Category),
+		PerformanceLevel: string(attempt.PerformanceLevel),
+		SizeGB:           diskVol.RequestGB,
+	}
+	if !c.warmPool.Serves(req) {
+		return "", nil, nil
+	}
+	// Unlike CreateDisk, adoption is not protected by ClientToken.
+	// A previous call may have adopted a disk, but its response was lost.
+	existingDisk, err = findDiskByName(diskName, c.ecs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	req.Volume = diskName
+	if isValidDiskName(diskName) {
+		req.DiskName = diskName
+	}
+	for _, t := range getDefaultDiskTags(diskVol) {
+		req.Tags = append(req.Tags, ecs.AddTagsTag{Key: t.Key, Value: t.Value})
+	}
+	if existingDisk != nil {
+		if err := c.warmPool.Resume(ctx, *existingDisk, req); err != nil {
+			return existingDisk.DiskId, nil, err
+		}
+		// refresh the size and name changed by the adoption
+		existingDisk, err = findDiskByName(diskName, c.ecs)
+		if err == nil && existingDisk == nil {
+			err = fmt.Errorf("disk of volume %s disappeared", diskName)
+		}
+		return "", existingDisk, err
+	}
+	diskID, err = c.warmPool.Adopt(ctx, req)
+	return diskID, nil, err
+}
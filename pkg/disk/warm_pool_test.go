@@ -0,0 +1,125 @@
+//go:build !windows
+
+package disk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/fake"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/batcher"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/desc"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/warmpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func testWarmPool(t *testing.T) (*fake.Cloud, *DiskCreateDelete) {
+	_, ctx := ktesting.NewTestContext(t)
+	c := fake.New(fake.Options{})
+	pool := warmpool.New(c, warmpool.Options{
+		Tags:            getDefaultDiskTags(&diskVolumeArgs{}),
+		VolumeTagKey:    common.VolumeNameTag,
+		CreateThrottler: defaultThrottler(),
+		DeleteThrottler: defaultThrottler(),
+		ResizeThrottler: defaultThrottler(),
+		ModifyThrottler: defaultThrottler(),
+		TagThrottler:    defaultThrottler(),
+	})
+	pool.SetConfig(warmpool.Config{
+		Specs: []warmpool.Spec{{Zone: "cn-hangzhou-a", Category: string(DiskESSD), PerformanceLevel: "PL1", SizeGB: 20, Count: 1}},
+		TTL:   time.Hour,
+	})
+	// create, then wait for the disk available
+	require.NoError(t, pool.Refill(ctx))
+	require.NoError(t, pool.Refill(ctx))
+	return c, &DiskCreateDelete{
+		ecs:             c,
+		batcher:         batcher.NewPassthrough(desc.Disk(c)),
+		createThrottler: defaultThrottler(),
+		deleteThrottler: defaultThrottler(),
+		warmPool:        pool,
+	}
+}
+
+func TestCreateDiskFromWarmPool(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	c, cd := testWarmPool(t)
+	args := &diskVolumeArgs{
+		ZoneID:           "cn-hangzhou-a",
+		RequestGB:        40,
+		Type:             []Category{DiskESSD},
+		PerformanceLevel: []PerformanceLevel{PERFORMANCE_LEVEL1},
+		DelAutoSnap:      "true",
+	}
+	diskID, attempt, err := cd.createDisk(ctx, "pv-warm", "", args, nil, "", false)
+	require.NoError(t, err)
+	assert.Equal(t, createAttempt{Category: DiskESSD, PerformanceLevel: PERFORMANCE_LEVEL1}, attempt)
+
+	d, _ := c.Disk(diskID)
+	assert.Equal(t, "pv-warm", d.DiskName)
+	assert.Equal(t, 40, d.Size)
+	tags := map[string]string{}
+	for _, tag := range d.Tags.Tag {
+		tags[tag.TagKey] = tag.TagValue
+	}
+	assert.Equal(t, "pv-warm", tags[common.VolumeNameTag])
+	assert.Equal(t, "true", tags[VolumeDeleteAutoSnapshotKey])
+	assert.NotContains(t, tags, warmpool.TagKey)
+
+	// retried after the adoption, e.g. the response is lost
+	retriedID, _, err := cd.createDisk(ctx, "pv-warm", "", args, nil, "", false)
+	require.NoError(t, err)
+	assert.Equal(t, diskID, retriedID)
+}
+
+func TestCreateDiskWarmPoolResume(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	c, cd := testWarmPool(t)
+	args := &diskVolumeArgs{
+		ZoneID:           "cn-hangzhou-a",
+		RequestGB:        40,
+		Type:             []Category{DiskESSD},
+		PerformanceLevel: []PerformanceLevel{PERFORMANCE_LEVEL1},
+	}
+	c.InjectError("ResizeDisk", fake.ServerError("InternalError", "injected"))
+	_, _, err := cd.createDisk(ctx, "pv-warm", "", args, nil, "", false)
+	require.Error(t, err)
+
+	// the claimed disk is adopted instead of creating a new one
+	diskID, _, err := cd.createDisk(ctx, "pv-warm", "", args, nil, "", false)
+	require.NoError(t, err)
+	d, _ := c.Disk(diskID)
+	assert.Equal(t, 40, d.Size)
+	assert.Equal(t, "pv-warm", d.DiskName)
+	for _, tag := range d.Tags.Tag {
+		assert.NotEqual(t, warmpool.TagKey, tag.TagKey)
+	}
+}
+
+func TestCreateDiskWarmPoolNotEligible(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	c, cd := testWarmPool(t)
+	args := &diskVolumeArgs{
+		ZoneID:           "cn-hangzhou-a",
+		RequestGB:        20,
+		Type:             []Category{DiskESSD},
+		PerformanceLevel: []PerformanceLevel{PERFORMANCE_LEVEL1},
+		Encrypted:        true,
+	}
+	diskID, _, err := cd.createDisk(ctx, "pv-encrypted", "", args, nil, "", false)
+	require.NoError(t, err)
+	d, _ := c.Disk(diskID)
+	assert.True(t, d.Encrypted)
+
+	// the pooled disk is left for other volumes
+	req := ecs.CreateDescribeDisksRequest()
+	req.Tag = &[]ecs.DescribeDisksTag{{Key: warmpool.TagKey}}
+	resp, err := c.DescribeDisks(req)
+	require.NoError(t, err)
+	require.Len(t, resp.Disks.Disk, 1)
+	assert.NotEqual(t, diskID, resp.Disks.Disk[0].DiskId)
+}
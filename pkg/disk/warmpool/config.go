@@ -0,0 +1,112 @@
+package warmpool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+)
+
+var (
+	SpecsKey = utils.ConfigKey{Name: "disk-warm-pool", Env: "DISK_WARM_POOL", Validate: validateSpecs}
+	TTLKey   = utils.ConfigKey{Name: "disk-warm-pool-ttl", Env: "DISK_WARM_POOL_TTL", Default: "24h", Validate: validateTTL}
+)
+
+// Spec describes a group of identical disks kept in the pool.
+type Spec struct {
+	Zone             string
+	Category         string
+	PerformanceLevel string
+	SizeGB           int64
+	Count            int
+}
+
+// Key identifies the disks of the spec. It is the value of the pool tag.
+func (s Spec) Key() string {
+	return fmt.Sprintf("%s:%s:%d", s.Zone, s.category(), s.SizeGB)
+}
+
+func (s Spec) category() string {
+	if s.PerformanceLevel == "" {
+		return s.Category
+	}
+	return s.Category + "." + s.PerformanceLevel
+}
+
+// Config is the desired state of the pool.
+type Config struct {
+	Specs []Spec
+	// TTL is how long a pooled disk is kept before being replaced by a new one.
+	TTL time.Duration
+}
+
+// ParseConfig reads the pool config. No disk is pooled if no spec is configured.
+func ParseConfig(cfg utils.Config) (Config, error) {
+	specs, err := ParseSpecs(cfg.Get(SpecsKey.Name, SpecsKey.Env, SpecsKey.Default))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", SpecsKey.Name, err)
+	}
+	ttl, err := time.ParseDuration(cfg.Get(TTLKey.Name, TTLKey.Env, TTLKey.Default))
+	if err == nil && ttl <= 0 {
+		err = fmt.Errorf("%s is not positive", ttl)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", TTLKey.Name, err)
+	}
+	return Config{Specs: specs, TTL: ttl}, nil
+}
+
+// ParseSpecs parses comma-separated specs in the form of zone:category[.PL]:sizeGB:count,
+// e.g. "cn-hangzhou-h:cloud_essd.PL1:20:5,cn-hangzhou-h:cloud_auto:40:2".
+func ParseSpecs(s string) ([]Spec, error) {
+	var specs []Spec
+	seen := map[string]bool{}
+	for item := range strings.SplitSeq(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("%q: expect zone:category[.PL]:sizeGB:count", item)
+		}
+		spec := Spec{Zone: parts[0]}
+		spec.Category, spec.PerformanceLevel, _ = strings.Cut(parts[1], ".")
+		if spec.Zone == "" || spec.Category == "" {
+			return nil, fmt.Errorf("%q: zone and category are required", item)
+		}
+		var err error
+		spec.SizeGB, err = strconv.ParseInt(parts[2], 10, 64)
+		if err != nil || spec.SizeGB <= 0 {
+			return nil, fmt.Errorf("%q: invalid size %q", item, parts[2])
+		}
+		spec.Count, err = strconv.Atoi(parts[3])
+		if err != nil || spec.Count < 0 {
+			return nil, fmt.Errorf("%q: invalid count %q", item, parts[3])
+		}
+		if seen[spec.Key()] {
+			return nil, fmt.Errorf("%q: duplicated", item)
+		}
+		seen[spec.Key()] = true
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func validateSpecs(s string) error {
+	_, err := ParseSpecs(s)
+	return err
+}
+
+func validateTTL(s string) error {
+	ttl, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("%s is not positive", ttl)
+	}
+	return nil
+}
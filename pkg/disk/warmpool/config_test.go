@@ -0,0 +1,55 @@
+package warmpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpecs(t *testing.T) {
+	specs, err := ParseSpecs(" cn-hangzhou-h:cloud_essd.PL1:20:5, cn-hangzhou-h:cloud_auto:40:0,")
+	require.NoError(t, err)
+	assert.Equal(t, []Spec{
+		{Zone: "cn-hangzhou-h", Category: "cloud_essd", PerformanceLevel: "PL1", SizeGB: 20, Count: 5},
+		{Zone: "cn-hangzhou-h", Category: "cloud_auto", SizeGB: 40, Count: 0},
+	}, specs)
+	assert.Equal(t, "cn-hangzhou-h:cloud_essd.PL1:20", specs[0].Key())
+	assert.Equal(t, "cn-hangzhou-h:cloud_auto:40", specs[1].Key())
+
+	specs, err = ParseSpecs("")
+	require.NoError(t, err)
+	assert.Empty(t, specs)
+
+	for _, s := range []string{
+		"cn-hangzhou-h:cloud_essd:20",
+		":cloud_essd:20:1",
+		"cn-hangzhou-h::20:1",
+		"cn-hangzhou-h:cloud_essd:0:1",
+		"cn-hangzhou-h:cloud_essd:20:-1",
+		"cn-hangzhou-h:cloud_essd:20:1,cn-hangzhou-h:cloud_essd:20:2",
+	} {
+		_, err := ParseSpecs(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig(utils.Config{})
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Specs)
+	assert.Equal(t, 24*time.Hour, cfg.TTL)
+
+	cfg, err = ParseConfig(utils.Config{ConfigMap: map[string]string{
+		SpecsKey.Name: "cn-hangzhou-h:cloud_auto:40:2",
+		TTLKey.Name:   "1h",
+	}})
+	require.NoError(t, err)
+	assert.Len(t, cfg.Specs, 1)
+	assert.Equal(t, time.Hour, cfg.TTL)
+
+	_, err = ParseConfig(utils.Config{ConfigMap: map[string]string{TTLKey.Name: "0s"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package warmpool
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// AvailableDisks is the number of disks ready for adoption, per pool spec.
+	AvailableDisks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "csi",
+		Subsystem: "disk_warm_pool",
+		Name:      "available_disks",
+		Help:      "Number of pre-created disks ready to be adopted by new volumes.",
+	}, []string{"pool"})
+
+	// AdoptionsTotal counts the volumes provisioned with the pool enabled, by whether a pooled disk was used.
+	AdoptionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "csi",
+		Subsystem: "disk_warm_pool",
+		Name:      "adoptions_total",
+		Help:      "Number of volumes that tried to adopt a pre-created disk, by result: hit, miss or error.",
+	}, []string{"result"})
+
+	// CreatedTotal counts the disks created to refill the pool.
+	CreatedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "csi",
+		Subsystem: "disk_warm_pool",
+		Name:      "created_disks_total",
+		Help:      "Number of disks created to refill the pool.",
+	}, []string{"pool"})
+
+	// DeletedTotal counts the pooled disks deleted without being adopted.
+	DeletedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "csi",
+		Subsystem: "disk_warm_pool",
+		Name:      "deleted_disks_total",
+		Help:      "Number of pooled disks deleted without being adopted, by reason: expired or unwanted.",
+	}, []string{"reason"})
+)
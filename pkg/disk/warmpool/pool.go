@@ -0,0 +1,452 @@
+// Package warmpool keeps disks pre-created, so that new volumes adopt them
+// instead of waiting for CreateDisk.
+package warmpool
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// TagKey marks pooled disks. The value is the Key of the spec.
+const TagKey = "csi.alibabacloud.com/warm-pool"
+
+const (
+	refillInterval = 30 * time.Second
+	listPageSize   = 100
+	// expiryMargin is how long before expiring pooled disks are no longer adopted,
+	// so that the refill loop on another replica does not delete a disk being adopted.
+	expiryMargin = 5 * time.Minute
+	// maxClaimAttempts is how many pooled disks are tried if the claim is lost to other replicas.
+	maxClaimAttempts = 3
+)
+
+type Options struct {
+	RegionID string
+	// Tags are added to pooled disks, and used to find them.
+	Tags []ecs.CreateDiskTag
+	// VolumeTagKey is the tag key identifying the volume. Disks are claimed by adding it.
+	VolumeTagKey    string
+	CreateThrottler *throttle.Throttler
+	DeleteThrottler *throttle.Throttler
+	// ResizeThrottler, ModifyThrottler and TagThrottler limit the OpenAPIs of adoption.
+	ResizeThrottler *throttle.Throttler
+	ModifyThrottler *throttle.Throttler
+	TagThrottler    *throttle.Throttler
+	// Clock defaults to the real clock.
+	Clock clock.Clock
+}
+
+// Pool adopts pooled disks for new volumes, and refills the pool in the background.
+// Adoption works on any replica, while the refill loop should only run on one.
+type Pool struct {
+	ecs  cloud.ECSInterface
+	opts Options
+	// trigger wakes up the refill loop
+	trigger chan struct{}
+
+	mu     sync.Mutex
+	config Config
+	// dirty is true if there may be pooled disks to clean up with no spec configured.
+	dirty bool
+}
+
+func New(ecsClient cloud.ECSInterface, opts Options) *Pool {
+	if opts.Clock == nil {
+		opts.Clock = clock.RealClock{}
+	}
+	return &Pool{
+		ecs:     ecsClient,
+		opts:    opts,
+		trigger: make(chan struct{}, 1),
+		dirty:   true,
+	}
+}
+
+// SetConfig replaces the desired state of the pool. Disks no longer wanted are deleted on next refill.
+func (p *Pool) SetConfig(cfg Config) {
+	p.mu.Lock()
+	p.config = cfg
+	p.mu.Unlock()
+	p.Trigger()
+}
+
+// Trigger asks the refill loop to run as soon as possible.
+func (p *Pool) Trigger() {
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run refills the pool periodically until ctx is done.
+func (p *Pool) Run(ctx context.Context) {
+	logger := klog.FromContext(ctx).WithName("warm-pool")
+	ctx = klog.NewContext(ctx, logger)
+	// another replica reports the pool once it takes over
+	defer AvailableDisks.Reset()
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := p.Refill(ctx); err != nil {
+			logger.Error(err, "failed to refill")
+		}
+		// the period is waited here, so that a trigger ends it early
+		select {
+		case <-ctx.Done():
+		case <-p.opts.Clock.After(refillInterval):
+		case <-p.trigger:
+		}
+	}, 0)
+}
+
+// Request describes the disk wanted by a new volume.
+type Request struct {
+	Zone             string
+	Category         string
+	PerformanceLevel string
+	SizeGB           int64
+	// Volume is the value of the VolumeTagKey tag, which claims the disk for the volume.
+	Volume string
+	// DiskName is set on the adopted disk if not empty.
+	DiskName string
+	// Tags are added to the adopted disk.
+	Tags []ecs.AddTagsTag
+}
+
+func (r Request) matches(s Spec) bool {
+	return r.Zone == s.Zone && r.Category == s.Category && r.PerformanceLevel == s.PerformanceLevel && s.SizeGB <= r.SizeGB
+}
+
+// Serves reports whether req may be served by the pool, even if no disk is available now.
+func (p *Pool) Serves(req Request) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.ContainsFunc(p.config.Specs, req.matches)
+}
+
+// Adopt turns a pooled disk into the disk of a volume, and returns its ID.
+// An empty ID is returned if no pooled disk matches req, and the disk should be created as usual.
+//
+// The disk is first claimed by tagging it with req.Volume, then resized, renamed, tagged with req.Tags,
+// and finally the pool tag is removed. Since tags can not be compared and set atomically,
+// the claim is read back, and the next pooled disk is tried if another replica claimed it afterwards.
+// If a step after claiming fails, the ID is returned with the error. The disk belongs to the volume,
+// and Resume finishes the adoption.
+func (p *Pool) Adopt(ctx context.Context, req Request) (string, error) {
+	p.mu.Lock()
+	cfg := p.config
+	p.mu.Unlock()
+	var specs []Spec
+	for _, s := range cfg.Specs {
+		if req.matches(s) {
+			specs = append(specs, s)
+		}
+	}
+	// the largest spec needs the least resizing
+	slices.SortStableFunc(specs, func(a, b Spec) int { return cmp.Compare(b.SizeGB, a.SizeGB) })
+
+	now := p.opts.Clock.Now()
+	attempts := 0
+	for _, spec := range specs {
+		disks, err := p.list(ctx, spec.Key())
+		if err != nil {
+			AdoptionsTotal.WithLabelValues("error").Inc()
+			return "", fmt.Errorf("failed to list pooled disks: %w", err)
+		}
+		for _, d := range disks {
+			if d.Status != "Available" || int64(d.Size) != spec.SizeGB || tagValue(d.Tags.Tag, p.opts.VolumeTagKey) != "" {
+				continue
+			}
+			if created, ok := parseTime(d.CreationTime); !ok || now.Sub(created) > cfg.TTL-expiryMargin {
+				continue
+			}
+			if attempts == maxClaimAttempts {
+				break
+			}
+			attempts++
+			logger := klog.FromContext(ctx).WithValues("diskID", d.DiskId, "pool", spec.Key())
+			claimed, err := p.claim(ctx, d.DiskId, req.Volume)
+			if err != nil {
+				AdoptionsTotal.WithLabelValues("error").Inc()
+				return "", fmt.Errorf("failed to claim pooled disk %s: %w", d.DiskId, err)
+			}
+			if claimed == nil {
+				logger.V(2).Info("pooled disk claimed by another volume")
+				continue
+			}
+			p.Trigger()
+			if err := p.adopt(ctx, *claimed, req); err != nil {
+				AdoptionsTotal.WithLabelValues("error").Inc()
+				return d.DiskId, fmt.Errorf("failed to adopt pooled disk %s: %w", d.DiskId, err)
+			}
+			AdoptionsTotal.WithLabelValues("hit").Inc()
+			logger.V(2).Info("adopted pooled disk", "sizeGB", req.SizeGB)
+			return d.DiskId, nil
+		}
+		if attempts == maxClaimAttempts {
+			break
+		}
+	}
+	AdoptionsTotal.WithLabelValues("miss").Inc()
+	return "", nil
+}
+
+// Resume finishes the adoption of disk, which is claimed by req.Volume but may still carry the pool tag.
+func (p *Pool) Resume(ctx context.Context, disk ecs.Disk, req Request) error {
+	if tagValue(disk.Tags.Tag, TagKey) == "" {
+		return nil
+	}
+	if err := p.adopt(ctx, disk, req); err != nil {
+		return fmt.Errorf("failed to adopt pooled disk %s: %w", disk.DiskId, err)
+	}
+	klog.FromContext(ctx).V(2).Info("resumed adoption of pooled disk", "diskID", disk.DiskId)
+	return nil
+}
+
+// claim tags the disk with the volume, and returns the disk if the tag is still ours when read back.
+func (p *Pool) claim(ctx context.Context, diskID, volume string) (*ecs.Disk, error) {
+	if err := p.addTags(ctx, diskID, []ecs.AddTagsTag{{Key: p.opts.VolumeTagKey, Value: volume}}); err != nil {
+		return nil, err
+	}
+	req := ecs.CreateDescribeDisksRequest()
+	req.RegionId = p.opts.RegionID
+	req.DiskIds = fmt.Sprintf(`["%s"]`, diskID)
+	resp, err := wrap.V1(ctx, p.ecs.DescribeDisks)(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Disks.Disk) == 0 {
+		// deleted by the refill loop
+		return nil, nil
+	}
+	d := resp.Disks.Disk[0]
+	if tagValue(d.Tags.Tag, p.opts.VolumeTagKey) != volume {
+		return nil, nil
+	}
+	return &d, nil
+}
+
+// adopt finishes the adoption of a claimed disk. Each step is skipped if already done.
+func (p *Pool) adopt(ctx context.Context, disk ecs.Disk, req Request) error {
+	if req.SizeGB > int64(disk.Size) {
+		resizeReq := ecs.CreateResizeDiskRequest()
+		resizeReq.RegionId = p.opts.RegionID
+		resizeReq.DiskId = disk.DiskId
+		resizeReq.NewSize = requests.NewInteger64(req.SizeGB)
+		if _, err := throttle.Throttled(p.opts.ResizeThrottler, wrap.V1(ctx, p.ecs.ResizeDisk))(ctx, resizeReq); err != nil {
+			return err
+		}
+	}
+	if req.DiskName != "" && req.DiskName != disk.DiskName {
+		modifyReq := ecs.CreateModifyDiskAttributeRequest()
+		modifyReq.RegionId = p.opts.RegionID
+		modifyReq.DiskId = disk.DiskId
+		modifyReq.DiskName = req.DiskName
+		if _, err := throttle.Throttled(p.opts.ModifyThrottler, wrap.V1(ctx, p.ecs.ModifyDiskAttribute))(ctx, modifyReq); err != nil {
+			return err
+		}
+	}
+	if len(req.Tags) > 0 {
+		if err := p.addTags(ctx, disk.DiskId, req.Tags); err != nil {
+			return err
+		}
+	}
+	if err := p.removePoolTag(ctx, disk.DiskId); err != nil {
+		// the disk is already claimed, retried by the refill loop once attached
+		klog.FromContext(ctx).Error(err, "failed to remove pool tag", "diskID", disk.DiskId)
+	}
+	return nil
+}
+
+func (p *Pool) addTags(ctx context.Context, diskID string, tags []ecs.AddTagsTag) error {
+	req := ecs.CreateAddTagsRequest()
+	req.RegionId = p.opts.RegionID
+	req.ResourceType = "disk"
+	req.ResourceId = diskID
+	req.Tag = &tags
+	_, err := throttle.Throttled(p.opts.TagThrottler, wrap.V1(ctx, p.ecs.AddTags))(ctx, req)
+	return err
+}
+
+func (p *Pool) removePoolTag(ctx context.Context, diskID string) error {
+	req := ecs.CreateRemoveTagsRequest()
+	req.RegionId = p.opts.RegionID
+	req.ResourceType = "disk"
+	req.ResourceId = diskID
+	req.Tag = &[]ecs.RemoveTagsTag{{Key: TagKey}}
+	_, err := throttle.Throttled(p.opts.TagThrottler, wrap.V1(ctx, p.ecs.RemoveTags))(ctx, req)
+	return err
+}
+
+// Refill lists pooled disks, deletes the expired or unwanted ones, and creates disks to reach the count of each spec.
+func (p *Pool) Refill(ctx context.Context) error {
+	p.mu.Lock()
+	cfg := p.config
+	dirty := p.dirty
+	p.mu.Unlock()
+	if len(cfg.Specs) == 0 && !dirty {
+		return nil
+	}
+	logger := klog.FromContext(ctx)
+
+	disks, err := p.list(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list pooled disks: %w", err)
+	}
+	specs := map[string]Spec{}
+	for _, s := range cfg.Specs {
+		specs[s.Key()] = s
+	}
+	now := p.opts.Clock.Now()
+	available := map[string]int{}
+	pending := map[string]int{}
+	remaining := 0
+	var errs []error
+	for _, d := range disks {
+		remaining++
+		key := tagValue(d.Tags.Tag, TagKey)
+		if tagValue(d.Tags.Tag, p.opts.VolumeTagKey) != "" {
+			// Claimed by a volume. The pool tag is left alone while the disk may still be adopted,
+			// so that a retried CreateVolume resumes the adoption.
+			if d.Status != "Available" {
+				if err := p.removePoolTag(ctx, d.DiskId); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			continue
+		}
+		spec, wanted := specs[key]
+		switch d.Status {
+		case "Creating":
+			pending[key]++
+			continue
+		case "Available":
+		default:
+			// attached by someone else, not ours to manage anymore
+			continue
+		}
+		reason := ""
+		if !wanted || int64(d.Size) != spec.SizeGB {
+			reason = "unwanted"
+		} else if created, ok := parseTime(d.CreationTime); ok && now.Sub(created) > cfg.TTL {
+			reason = "expired"
+		}
+		if reason == "" {
+			available[key]++
+			continue
+		}
+		if err := p.delete(ctx, d.DiskId); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		remaining--
+		DeletedTotal.WithLabelValues(reason).Inc()
+		logger.V(2).Info("deleted pooled disk", "diskID", d.DiskId, "pool", key, "reason", reason)
+	}
+
+	p.mu.Lock()
+	p.dirty = remaining > 0
+	p.mu.Unlock()
+	AvailableDisks.Reset()
+	for _, s := range cfg.Specs {
+		AvailableDisks.WithLabelValues(s.Key()).Set(float64(available[s.Key()]))
+	}
+
+	for _, s := range cfg.Specs {
+		missing := s.Count - available[s.Key()] - pending[s.Key()]
+		for range missing {
+			diskID, err := p.create(ctx, s)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to create disk for pool %s: %w", s.Key(), err))
+				break
+			}
+			p.mu.Lock()
+			p.dirty = true
+			p.mu.Unlock()
+			CreatedTotal.WithLabelValues(s.Key()).Inc()
+			logger.V(2).Info("created pooled disk", "diskID", diskID, "pool", s.Key())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// list returns the pooled disks of the spec key, or all of them if key is empty, oldest first.
+func (p *Pool) list(ctx context.Context, key string) ([]ecs.Disk, error) {
+	tags := []ecs.DescribeDisksTag{{Key: TagKey, Value: key}}
+	for _, t := range p.opts.Tags {
+		tags = append(tags, ecs.DescribeDisksTag{Key: t.Key, Value: t.Value})
+	}
+	req := ecs.CreateDescribeDisksRequest()
+	req.RegionId = p.opts.RegionID
+	req.Tag = &tags
+	req.MaxResults = requests.NewInteger(listPageSize)
+	var disks []ecs.Disk
+	for {
+		resp, err := wrap.V1(ctx, p.ecs.DescribeDisks)(req)
+		if err != nil {
+			return nil, err
+		}
+		disks = append(disks, resp.Disks.Disk...)
+		if resp.NextToken == "" {
+			break
+		}
+		req.NextToken = resp.NextToken
+	}
+	// adopt the oldest disks first, before they expire
+	slices.SortStableFunc(disks, func(a, b ecs.Disk) int { return cmp.Compare(a.CreationTime, b.CreationTime) })
+	return disks, nil
+}
+
+func (p *Pool) create(ctx context.Context, s Spec) (string, error) {
+	tags := append(slices.Clone(p.opts.Tags), ecs.CreateDiskTag{Key: TagKey, Value: s.Key()})
+	req := ecs.CreateCreateDiskRequest()
+	req.RegionId = p.opts.RegionID
+	req.ZoneId = s.Zone
+	req.DiskCategory = s.Category
+	req.PerformanceLevel = s.PerformanceLevel
+	req.Size = requests.NewInteger64(s.SizeGB)
+	req.Tag = &tags
+	resp, err := throttle.Throttled(p.opts.CreateThrottler, wrap.V1(ctx, p.ecs.CreateDisk))(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return resp.DiskId, nil
+}
+
+func (p *Pool) delete(ctx context.Context, diskID string) error {
+	req := ecs.CreateDeleteDiskRequest()
+	req.DiskId = diskID
+	_, err := throttle.Throttled(p.opts.DeleteThrottler, wrap.V1(ctx, p.ecs.DeleteDisk))(ctx, req)
+	return err
+}
+
+func tagValue(tags []ecs.Tag, key string) string {
+	for _, t := range tags {
+		if t.TagKey == key {
+			return t.TagValue
+		}
+	}
+	return ""
+}
+
+// parseTime parses the CreationTime of disks, which may omit seconds.
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,247 @@
+package warmpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/fake"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+const volumeTagKey = "csi.alibabacloud.com/volume-name"
+
+var testSpec = Spec{Zone: "cn-hangzhou-a", Category: "cloud_essd", PerformanceLevel: "PL1", SizeGB: 20, Count: 2}
+
+func testPool(t *testing.T) (*Pool, *fake.Cloud, *clocktesting.FakeClock) {
+	clk := clocktesting.NewFakeClock(time.Now())
+	c := fake.New(fake.Options{Clock: clk})
+	return newTestPool(c, clk), c, clk
+}
+
+func newTestPool(c cloud.ECSInterface, clk *clocktesting.FakeClock) *Pool {
+	newThrottler := func() *throttle.Throttler { return throttle.NewThrottler(clk, time.Second, 10*time.Second) }
+	p := New(c, Options{
+		Tags:            []ecs.CreateDiskTag{{Key: "createdby", Value: "alibabacloud-csi-plugin"}},
+		VolumeTagKey:    volumeTagKey,
+		CreateThrottler: newThrottler(),
+		DeleteThrottler: newThrottler(),
+		ResizeThrottler: newThrottler(),
+		ModifyThrottler: newThrottler(),
+		TagThrottler:    newThrottler(),
+		Clock:           clk,
+	})
+	p.SetConfig(Config{Specs: []Spec{testSpec}, TTL: time.Hour})
+	return p
+}
+
+// fill creates the disks, and makes them available.
+func fill(t *testing.T, p *Pool, c *fake.Cloud) {
+	t.Helper()
+	require.NoError(t, p.Refill(t.Context()))
+	require.NoError(t, p.Refill(t.Context()))
+	require.Len(t, availablePooled(t, c), testSpec.Count)
+}
+
+// availablePooled returns the pooled disks ready for adoption.
+func availablePooled(t *testing.T, c *fake.Cloud) []ecs.Disk {
+	t.Helper()
+	var disks []ecs.Disk
+	for _, d := range listPooled(t, c) {
+		if d.Status == "Available" && tagValue(d.Tags.Tag, volumeTagKey) == "" {
+			disks = append(disks, d)
+		}
+	}
+	return disks
+}
+
+var testRequest = Request{
+	Zone:             "cn-hangzhou-a",
+	Category:         "cloud_essd",
+	PerformanceLevel: "PL1",
+	SizeGB:           30,
+	Volume:           "pv-1",
+	DiskName:         "pv-1",
+	Tags:             []ecs.AddTagsTag{{Key: "createdby", Value: "alibabacloud-csi-plugin"}},
+}
+
+func listPooled(t *testing.T, c *fake.Cloud) []ecs.Disk {
+	t.Helper()
+	req := ecs.CreateDescribeDisksRequest()
+	req.Tag = &[]ecs.DescribeDisksTag{{Key: TagKey}}
+	resp, err := c.DescribeDisks(req)
+	require.NoError(t, err)
+	return resp.Disks.Disk
+}
+
+func TestRefill(t *testing.T) {
+	p, c, _ := testPool(t)
+	require.NoError(t, p.Refill(t.Context()))
+	disks := listPooled(t, c)
+	require.Len(t, disks, 2)
+	for _, d := range disks {
+		assert.Equal(t, "cloud_essd", d.Category)
+		assert.Equal(t, "PL1", d.PerformanceLevel)
+		assert.Equal(t, 20, d.Size)
+		assert.Equal(t, testSpec.Key(), tagValue(d.Tags.Tag, TagKey))
+		assert.Equal(t, "alibabacloud-csi-plugin", tagValue(d.Tags.Tag, "createdby"))
+	}
+
+	// nothing more to create
+	require.NoError(t, p.Refill(t.Context()))
+	assert.Len(t, listPooled(t, c), 2)
+	assert.Len(t, availablePooled(t, c), 2)
+}
+
+func TestAdoptOnOtherReplica(t *testing.T) {
+	leader, c, clk := testPool(t)
+	fill(t, leader, c)
+
+	// never refilled
+	replica := newTestPool(c, clk)
+	diskID, err := replica.Adopt(t.Context(), testRequest)
+	require.NoError(t, err)
+	require.NotEmpty(t, diskID)
+	d, _ := c.Disk(diskID)
+	assert.Equal(t, "pv-1", tagValue(d.Tags.Tag, volumeTagKey))
+	assert.Len(t, availablePooled(t, c), 1)
+}
+
+func TestAdopt(t *testing.T) {
+	p, c, _ := testPool(t)
+	for _, r := range []Request{
+		{Zone: "cn-hangzhou-b", Category: "cloud_essd", PerformanceLevel: "PL1", SizeGB: 30},
+		{Zone: "cn-hangzhou-a", Category: "cloud_essd", PerformanceLevel: "PL0", SizeGB: 30},
+		{Zone: "cn-hangzhou-a", Category: "cloud_essd", PerformanceLevel: "PL1", SizeGB: 10},
+	} {
+		assert.False(t, p.Serves(r), r)
+	}
+	assert.True(t, p.Serves(testRequest))
+	// not filled yet
+	diskID, err := p.Adopt(t.Context(), testRequest)
+	require.NoError(t, err)
+	assert.Empty(t, diskID)
+
+	fill(t, p, c)
+	diskID, err = p.Adopt(t.Context(), testRequest)
+	require.NoError(t, err)
+	d, ok := c.Disk(diskID)
+	require.True(t, ok)
+	assert.Equal(t, 30, d.Size)
+	assert.Equal(t, "pv-1", d.DiskName)
+	assert.Equal(t, "pv-1", tagValue(d.Tags.Tag, volumeTagKey))
+	assert.Equal(t, "alibabacloud-csi-plugin", tagValue(d.Tags.Tag, "createdby"))
+	assert.Empty(t, tagValue(d.Tags.Tag, TagKey))
+
+	// refilled to the count
+	require.NoError(t, p.Refill(t.Context()))
+	assert.Len(t, listPooled(t, c), 2)
+	assert.NotContains(t, availablePooled(t, c), d)
+}
+
+func TestAdoptSkipsExpiring(t *testing.T) {
+	p, c, clk := testPool(t)
+	fill(t, p, c)
+
+	clk.Step(time.Hour - time.Minute)
+	diskID, err := p.Adopt(t.Context(), testRequest)
+	require.NoError(t, err)
+	assert.Empty(t, diskID)
+}
+
+// claimRacer tags the disk with another volume right after the claim of the pool.
+type claimRacer struct {
+	*fake.Cloud
+	raced bool
+}
+
+func (r *claimRacer) AddTags(req *ecs.AddTagsRequest) (*ecs.AddTagsResponse, error) {
+	resp, err := r.Cloud.AddTags(req)
+	if err == nil && !r.raced {
+		r.raced = true
+		other := ecs.CreateAddTagsRequest()
+		other.ResourceType = "disk"
+		other.ResourceId = req.ResourceId
+		other.Tag = &[]ecs.AddTagsTag{{Key: volumeTagKey, Value: "pv-other"}}
+		_, err = r.Cloud.AddTags(other)
+	}
+	return resp, err
+}
+
+func TestAdoptClaimLost(t *testing.T) {
+	p, c, clk := testPool(t)
+	fill(t, p, c)
+
+	racer := &claimRacer{Cloud: c}
+	diskID, err := newTestPool(racer, clk).Adopt(t.Context(), testRequest)
+	require.NoError(t, err)
+	require.True(t, racer.raced)
+	d, _ := c.Disk(diskID)
+	assert.Equal(t, "pv-1", tagValue(d.Tags.Tag, volumeTagKey))
+	assert.Empty(t, availablePooled(t, c))
+}
+
+func TestAdoptResume(t *testing.T) {
+	p, c, _ := testPool(t)
+	fill(t, p, c)
+	c.InjectError("ModifyDiskAttribute", fake.ServerError("InternalError", "injected"))
+
+	diskID, err := p.Adopt(t.Context(), testRequest)
+	require.Error(t, err)
+	require.NotEmpty(t, diskID)
+
+	// claimed, left alone by the refill loop
+	require.NoError(t, p.Refill(t.Context()))
+	d, ok := c.Disk(diskID)
+	require.True(t, ok)
+	assert.Equal(t, testSpec.Key(), tagValue(d.Tags.Tag, TagKey))
+	assert.Equal(t, "pv-1", tagValue(d.Tags.Tag, volumeTagKey))
+
+	require.NoError(t, p.Resume(t.Context(), d, testRequest))
+	d, _ = c.Disk(diskID)
+	assert.Equal(t, 30, d.Size)
+	assert.Equal(t, "pv-1", d.DiskName)
+	assert.Empty(t, tagValue(d.Tags.Tag, TagKey))
+}
+
+func TestAdoptRemovePoolTagFailed(t *testing.T) {
+	p, c, _ := testPool(t)
+	fill(t, p, c)
+	c.InjectError("RemoveTags", fake.ServerError("InternalError", "injected"))
+
+	diskID, err := p.Adopt(t.Context(), testRequest)
+	require.NoError(t, err)
+	d, _ := c.Disk(diskID)
+	assert.Equal(t, testSpec.Key(), tagValue(d.Tags.Tag, TagKey))
+
+	// neither deleted nor counted as available
+	require.NoError(t, p.Refill(t.Context()))
+	_, ok := c.Disk(diskID)
+	assert.True(t, ok)
+	require.NoError(t, p.Refill(t.Context()))
+	assert.Len(t, availablePooled(t, c), 2)
+}
+
+func TestRefillDeletes(t *testing.T) {
+	p, c, clk := testPool(t)
+	fill(t, p, c)
+	old := listPooled(t, c)
+
+	clk.Step(2 * time.Hour)
+	require.NoError(t, p.Refill(t.Context()))
+	disks := listPooled(t, c)
+	require.Len(t, disks, 2)
+	for _, d := range old {
+		_, ok := c.Disk(d.DiskId)
+		assert.False(t, ok, "expired disk %s should be deleted", d.DiskId)
+	}
+
+	p.SetConfig(Config{TTL: time.Hour})
+	require.NoError(t, p.Refill(t.Context()))
+	assert.Empty(t, listPooled(t, c))
+	assert.False(t, p.dirty)
+}
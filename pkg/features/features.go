@@ -50,11 +50,41 @@ const (
 
 	RundCSIProtocol3 featuregate.Feature = "RundCSIProtocol3"
 
+	// Recommend fuse container resources from the usage reported by kubelet stats summary.
+	// The node plugin records the recommendation on the PV, and it is applied
+	// the next time a fuse pod is created for the volume.
+	FuseResourceRecommender featuregate.Feature = "FuseResourceRecommender"
+
 	// Enable volume group snapshots.
 	// This feature allows users to use the volume group snapshot functionality,
 	// enabling snapshots of related disks under a workload through ECS's snapshot-group capability.
 	EnableVolumeGroupSnapshots featuregate.Feature = "EnableVolumeGroupSnapshots"
 
+	// Carve disk volumes from an LVM thin pool on a per-node pool disk,
+	// served by the thinpool.diskplugin.csi.alibabacloud.com driver.
+	// The node plugin creates and grows the pool disk.
+	DiskThinPool featuregate.Feature = "DiskThinPool"
+
+	// Fence force-attachable disks (e.g. regional disks) on nodes lost for the fencing delay,
+	// so that they are force attached to the next node without waiting for detach.
+	DiskRegionalFailover featuregate.Feature = "DiskRegionalFailover"
+
+	// Serve the CSI SnapshotMetadata service for the changed block tracking of disk snapshots,
+	// so that backup tools can read only the blocks changed since the last backup.
+	DiskSnapshotMetadata featuregate.Feature = "DiskSnapshotMetadata"
+
+	// Rotate the KMS key of encrypted disks through the kmsKeyId parameter of VolumeAttributesClass,
+	// by copying the detached disk from a snapshot and swapping the disk of the PV.
+	DiskKMSKeyRotation featuregate.Feature = "DiskKMSKeyRotation"
+
+	// Record the peak IO of disk volumes on their PVs from the node plugin,
+	// and tune the provisioned IOPS of cloud_auto disks within StorageClass bounds from the controller.
+	DiskIOPSAutotune featuregate.Feature = "DiskIOPSAutotune"
+
+	// Repair the filesystem of disk volumes before mounting them, if requested by a label on the PVC.
+	// The node plugin watches PVCs with the label.
+	DiskRepair featuregate.Feature = "DiskRepair"
+
 	// Use cnfs-alinas-daemon instead of csiplugin-connector for alinas and efc mounting.
 	AlinasMountProxy featuregate.Feature = "AlinasMountProxy"
 )
@@ -68,10 +98,17 @@ var (
 		DisableExpandAutoSnapshots: {Default: true, PreRelease: featuregate.GA, LockToDefault: true},
 		EnableVolumeGroupSnapshots: {Default: false, PreRelease: featuregate.Alpha},
 		EnableDeleteAutoSnapshots:  {Default: false, PreRelease: featuregate.Alpha},
+		DiskThinPool:               {Default: false, PreRelease: featuregate.Alpha},
+		DiskRegionalFailover:       {Default: false, PreRelease: featuregate.Alpha},
+		DiskSnapshotMetadata:       {Default: false, PreRelease: featuregate.Alpha},
+		DiskKMSKeyRotation:         {Default: false, PreRelease: featuregate.Alpha},
+		DiskIOPSAutotune:           {Default: false, PreRelease: featuregate.Alpha},
+		DiskRepair:                 {Default: false, PreRelease: featuregate.Alpha},
 	}
 
 	defaultOSSFeatureGate = map[featuregate.Feature]featuregate.FeatureSpec{
-		UpdatedOssfsVersion: {Default: true, PreRelease: featuregate.Beta},
+		UpdatedOssfsVersion:     {Default: true, PreRelease: featuregate.Beta},
+		FuseResourceRecommender: {Default: false, PreRelease: featuregate.Alpha},
 	}
 
 	defaultNasFeatureGate = map[featuregate.Feature]featuregate.FeatureSpec{
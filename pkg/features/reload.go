@@ -0,0 +1,77 @@
+package features
+
+import (
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+	"k8s.io/component-base/featuregate"
+	"k8s.io/klog/v2"
+)
+
+// featureGatesKey in csi-plugin ConfigMap sets feature gates in the same format as --feature-gates.
+// Gates set by the flag take precedence.
+var featureGatesKey = utils.ConfigKey{Name: "feature-gates", Validate: validateFeatureGates}
+
+func validateFeatureGates(s string) error {
+	return FunctionalMutableFeatureGate.DeepCopy().Set(s)
+}
+
+// parseFeatureGates parses a validated value of featureGatesKey.
+func parseFeatureGates(s string) map[string]bool {
+	m := map[string]bool{}
+	for _, g := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(g, "=")
+		if !ok {
+			continue
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		m[strings.TrimSpace(k)] = b
+	}
+	return m
+}
+
+// WatchConfig applies the feature gates in the config now and every time they change.
+// It should be called after flags are parsed, and before the gates are used.
+//
+// Gates checked per request take effect right away, e.g. DiskParallelAttach.
+// Gates checked only at startup, e.g. the ones starting controllers, still need a restart.
+func WatchConfig(w *utils.WatchedConfig) {
+	fromFlag := parseFeatureGates(FunctionalMutableFeatureGate.String())
+	w.RegisterKeys(featureGatesKey)
+
+	applied := map[string]bool{}
+	update := func(c utils.Config) {
+		gates := parseFeatureGates(featureGatesKey.Get(c))
+		for k := range fromFlag {
+			delete(gates, k)
+		}
+		changes := map[string]bool{}
+		all := FunctionalMutableFeatureGate.GetAll()
+		for k := range applied {
+			if _, ok := gates[k]; !ok {
+				changes[k] = all[featuregate.Feature(k)].Default
+			}
+		}
+		for k, v := range gates {
+			if old, ok := applied[k]; !ok || old != v {
+				changes[k] = v
+			}
+		}
+		if len(changes) == 0 {
+			return
+		}
+		if err := FunctionalMutableFeatureGate.SetFromMap(changes); err != nil {
+			klog.ErrorS(err, "Failed to apply feature gates from csi-plugin ConfigMap")
+			return
+		}
+		klog.InfoS("Feature gates changed by csi-plugin ConfigMap", "gates", changes)
+		applied = maps.Clone(gates)
+	}
+	update(w.Current())
+	w.Subscribe(update)
+}
@@ -0,0 +1,35 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchConfig(t *testing.T) {
+	t.Cleanup(func() {
+		assert.NoError(t, FunctionalMutableFeatureGate.SetFromMap(map[string]bool{
+			string(DiskParallelAttach): false,
+			string(DiskParallelDetach): false,
+		}))
+	})
+
+	// set by flag
+	assert.NoError(t, FunctionalMutableFeatureGate.Set("DiskParallelDetach=true"))
+
+	w := utils.NewWatchedConfig()
+	w.Update(map[string]string{"feature-gates": "DiskParallelAttach=true,DiskParallelDetach=false"})
+	WatchConfig(w)
+	assert.True(t, FunctionalMutableFeatureGate.Enabled(DiskParallelAttach))
+	assert.True(t, FunctionalMutableFeatureGate.Enabled(DiskParallelDetach), "flag takes precedence")
+
+	// invalid value is rejected
+	w.Update(map[string]string{"feature-gates": "NoSuchGate=true"})
+	assert.True(t, FunctionalMutableFeatureGate.Enabled(DiskParallelAttach))
+
+	// removed, back to default
+	w.Update(nil)
+	assert.False(t, FunctionalMutableFeatureGate.Enabled(DiskParallelAttach))
+	assert.True(t, FunctionalMutableFeatureGate.Enabled(DiskParallelDetach))
+}
@@ -28,6 +28,7 @@ const (
 	csiNamespace                            = "csi"
 	nodeNamespace                           = "node"
 	grpcSubsystem                           = "grpc"
+	openAPISubsystem                        = "openapi"
 	scrapeSubsystem                         = "scrape"
 	volumeSubsystem                         = "volume"
 	diskSectorSize                          = 512
@@ -38,6 +39,7 @@ const (
 	latencyTooHigh                          = "LatencyTooHigh"
 	capacityNotEnough                       = "NotEnoughDiskSpace"
 	ioHang                                  = "IOHang"
+	diskUnhealthy                           = "DiskUnhealthy"
 )
 
 const (
@@ -0,0 +1,99 @@
+//go:build !windows
+
+package metric
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/health"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+var diskHealthLabelNames = slices.Concat(diskStatLabelNames, []string{"pv"})
+
+func diskHealthDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(nodeNamespace, volumeSubsystem, "health_"+name),
+		help,
+		diskHealthLabelNames, diskStatConstLabels,
+	)
+}
+
+// from NVMe SMART / health information log
+var (
+	diskHealthCriticalWarningDesc = diskHealthDesc("critical_warning", "Critical warning bits of the NVMe SMART log, 0 if healthy.")
+	diskHealthTemperatureDesc     = diskHealthDesc("temperature_celsius", "The composite temperature of the NVMe device.")
+	diskHealthAvailableSpareDesc  = diskHealthDesc("available_spare_percent", "The remaining spare capacity of the NVMe device.")
+	diskHealthPercentageUsedDesc  = diskHealthDesc("percentage_used", "The estimated percentage of the NVMe device life used.")
+	diskHealthUnsafeShutdownsDesc = diskHealthDesc("unsafe_shutdowns_total", "The number of unsafe shutdowns of the NVMe device.")
+	diskHealthMediaErrorsDesc     = diskHealthDesc("media_errors_total", "The number of unrecovered data integrity errors of the NVMe device.")
+	diskHealthErrorLogEntriesDesc = diskHealthDesc("error_log_entries_total", "The number of error information log entries of the NVMe device.")
+)
+
+// from SCSI device in sysfs
+var (
+	diskHealthIOErrorsDesc   = diskHealthDesc("io_errors_total", "The number of IO errors of the SCSI device.")
+	diskHealthIOTimeoutsDesc = diskHealthDesc("io_timeouts_total", "The number of IO timeouts of the SCSI device.")
+)
+
+// from virtio device in sysfs
+var diskHealthVirtioStatusDesc = diskHealthDesc("virtio_status", "The status bits of the virtio device, 0x40 or 0x80 set if broken.")
+
+// updateHealth exports the health of the volume device, and records an event once its problems change.
+func (p *diskStatCollector) updateHealth(pvName string, info diskInfo, labels []string, ch chan<- prometheus.Metric) {
+	h, err := p.health.Read(info.Dev)
+	if err != nil {
+		if !errors.Is(err, health.ErrNotSupported) {
+			klog.ErrorS(err, "Get disk health failed", "disk", info.DiskID)
+		}
+		return
+	}
+	labels = append(slices.Clone(labels), pvName)
+	send := func(desc *prometheus.Desc, valueType prometheus.ValueType, v float64) {
+		ch <- prometheus.MustNewConstMetric(desc, valueType, v, labels...)
+	}
+	switch h.Kind {
+	case health.KindNVMe:
+		send(diskHealthCriticalWarningDesc, prometheus.GaugeValue, float64(h.CriticalWarning))
+		send(diskHealthTemperatureDesc, prometheus.GaugeValue, float64(h.TemperatureCelsius))
+		send(diskHealthAvailableSpareDesc, prometheus.GaugeValue, float64(h.AvailableSpare))
+		send(diskHealthPercentageUsedDesc, prometheus.GaugeValue, float64(h.PercentageUsed))
+		send(diskHealthUnsafeShutdownsDesc, prometheus.CounterValue, float64(h.UnsafeShutdowns))
+		send(diskHealthMediaErrorsDesc, prometheus.CounterValue, float64(h.MediaErrors))
+		send(diskHealthErrorLogEntriesDesc, prometheus.CounterValue, float64(h.ErrorLogEntries))
+	case health.KindSCSI:
+		send(diskHealthIOErrorsDesc, prometheus.CounterValue, float64(h.IOErrors))
+		send(diskHealthIOTimeoutsDesc, prometheus.CounterValue, float64(h.IOTimeouts))
+	case health.KindVirtio:
+		send(diskHealthVirtioStatusDesc, prometheus.GaugeValue, float64(h.VirtioStatus))
+	}
+
+	problems := strings.Join(h.Problems(), ", ")
+	p.healthLock.Lock()
+	last := p.lastHealthProblems[info.Dev]
+	p.lastHealthProblems[info.Dev] = problems
+	p.healthLock.Unlock()
+	if problems != "" && problems != last {
+		p.recorder.Eventf(info.PVCRef, v1.EventTypeWarning, diskUnhealthy, "Disk unhealthy on Persistent Volume %s, nodeName:%s, diskID:%s, Device:%s, problems: %s",
+			pvName, p.nodeName, info.DiskID, h.Device, problems)
+	}
+}
+
+// forgetHealth drops the problems of devices no longer used by any volume.
+func (p *diskStatCollector) forgetHealth() {
+	devs := make(map[uint64]struct{}, len(p.lastPvDiskInfoMap))
+	for _, info := range p.lastPvDiskInfoMap {
+		devs[info.Dev] = struct{}{}
+	}
+	p.healthLock.Lock()
+	defer p.healthLock.Unlock()
+	for dev := range p.lastHealthProblems {
+		if _, ok := devs[dev]; !ok {
+			delete(p.lastHealthProblems, dev)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+//go:build !windows
+
+package metric
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/health"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestUpdateHealth(t *testing.T) {
+	sysfs := t.TempDir()
+	devDir := filepath.Join(sysfs, "devices/sdb")
+	require.NoError(t, os.MkdirAll(filepath.Join(devDir, "device"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(sysfs, "dev/block"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(sysfs, "block"), 0o755))
+	require.NoError(t, os.Symlink(devDir, filepath.Join(sysfs, "dev/block/8:16")))
+	require.NoError(t, os.Symlink(devDir, filepath.Join(sysfs, "block/sdb")))
+	setCounters := func(ioErrors string) {
+		require.NoError(t, os.WriteFile(filepath.Join(devDir, "device/ioerr_cnt"), []byte(ioErrors+"\n"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(devDir, "device/iotmo_cnt"), []byte("0x0\n"), 0o644))
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	p := &diskStatCollector{
+		recorder:           recorder,
+		health:             &health.Reader{SysfsPath: sysfs, DevicePath: "/dev"},
+		lastHealthProblems: map[uint64]string{},
+	}
+	info := diskInfo{
+		PVCRef: &v1.ObjectReference{Kind: "PersistentVolumeClaim", Namespace: "default", Name: "pvc-1"},
+		DiskID: "d-1",
+		Dev:    unix.Mkdev(8, 16),
+	}
+	update := func() map[string]float64 {
+		ch := make(chan prometheus.Metric, 10)
+		p.updateHealth("pv-1", info, []string{"default", "pvc-1", "/dev/sdb"}, ch)
+		close(ch)
+		values := map[string]float64{}
+		for m := range ch {
+			var out dto.Metric
+			require.NoError(t, m.Write(&out))
+			assert.Len(t, out.Label, 5) // type, namespace, pvc, device, pv
+			values[m.Desc().String()] = out.Counter.GetValue()
+		}
+		return values
+	}
+
+	setCounters("0x0")
+	values := update()
+	assert.Equal(t, map[string]float64{
+		diskHealthIOErrorsDesc.String():   0,
+		diskHealthIOTimeoutsDesc.String(): 0,
+	}, values)
+	assert.Empty(t, recorder.Events)
+
+	setCounters("0x2")
+	assert.Equal(t, float64(2), update()[diskHealthIOErrorsDesc.String()])
+	assert.Contains(t, <-recorder.Events, "2 IO errors")
+
+	// no more events until the problems change
+	update()
+	assert.Empty(t, recorder.Events)
+}
@@ -0,0 +1,252 @@
+//go:build !windows
+
+package metric
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/procfs/blockdevice"
+	"golang.org/x/sys/unix"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"k8s.io/mount-utils"
+	"k8s.io/utils/clock"
+)
+
+// DiskIOObservationAnnotation on disk PVs is the peak IO of the volume in the latest windows observed by the node plugin,
+// in JSON of []DiskIOObservation, oldest first. It is read by the IOPS autotuning of the controller.
+const DiskIOObservationAnnotation = "csi.alibabacloud.com/observed-io"
+
+const (
+	// DiskIOObserveInterval is the length of a window.
+	DiskIOObserveInterval = 5 * time.Minute
+	// DiskIOObservationWindows is the number of latest windows kept in DiskIOObservationAnnotation.
+	DiskIOObservationWindows = 6
+
+	diskIOSampleInterval = 30 * time.Second
+)
+
+// DiskIOObservation is the peak IO of a volume between Since and Until,
+// averaged over the intervals between two samples.
+type DiskIOObservation struct {
+	PeakIOPS uint64 `json:"peakIOPS"`
+	// PeakThroughput in bytes per second
+	PeakThroughput uint64    `json:"peakThroughput"`
+	Since          time.Time `json:"since"`
+	Until          time.Time `json:"until"`
+	Node           string    `json:"node,omitempty"`
+}
+
+// ParseDiskIOObservations returns nil if the annotation is not set.
+func ParseDiskIOObservations(annotations map[string]string) ([]DiskIOObservation, error) {
+	value := annotations[DiskIOObservationAnnotation]
+	if value == "" {
+		return nil, nil
+	}
+	var o []DiskIOObservation
+	if err := json.Unmarshal([]byte(value), &o); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", DiskIOObservationAnnotation, err)
+	}
+	return o, nil
+}
+
+type ioSample struct {
+	ios, sectors uint64
+	at           time.Time
+}
+
+// diskIOObserver samples the IO of disk volumes on this node by its own ticker, independent of the scrapes of the metrics,
+// and records the peak IOPS and throughput of each window on the PV, so that the controller can tune the provisioned IOPS.
+// The last node to record wins if the disk is attached to multiple nodes.
+type diskIOObserver struct {
+	client   kubernetes.Interface
+	nodeName string
+	clk      clock.WithTicker
+	// volumes returns the device number of the disk volumes mounted on this node by PV name
+	volumes func(ctx context.Context) (map[string]uint64, error)
+	stats   func() ([]blockdevice.Diskstats, error)
+	// observed selects the PVs to observe, checked once when the volume is found on this node
+	observed func(pv *v1.PersistentVolume) bool
+
+	selected map[string]bool     // by PV name
+	last     map[string]ioSample // by PV name
+	peaks    map[string]*DiskIOObservation
+	windows  map[string][]DiskIOObservation
+}
+
+// RunDiskIOObserver samples the IO of the disk volumes mounted on this node selected by observed until ctx is done,
+// for the IOPS autotuning of the controller.
+func RunDiskIOObserver(ctx context.Context, client kubernetes.Interface, nodeName string, observed func(pv *v1.PersistentVolume) bool) {
+	diskStats, err := NewDefaultProcDiskStats()
+	if err != nil {
+		klog.ErrorS(err, "Failed to read diskstats, not observing disk IO")
+		return
+	}
+	mounter := mount.NewWithoutSystemd("")
+	o := newDiskIOObserver(client, nodeName, clock.RealClock{}, observed)
+	o.stats = diskStats.GetStats
+	o.volumes = func(ctx context.Context) (map[string]uint64, error) {
+		paths, err := findVolJSON(podsRootPath)
+		if err != nil {
+			return nil, err
+		}
+		devs := map[string]uint64{}
+		for pvName, info := range findDiskVolumes(ctx, mounter, paths, diskDriverName) {
+			devs[pvName] = info.Dev
+		}
+		return devs, nil
+	}
+	o.run(ctx)
+}
+
+func newDiskIOObserver(client kubernetes.Interface, nodeName string, clk clock.WithTicker, observed func(pv *v1.PersistentVolume) bool) *diskIOObserver {
+	return &diskIOObserver{
+		client:   client,
+		nodeName: nodeName,
+		clk:      clk,
+		observed: observed,
+		selected: map[string]bool{},
+		last:     map[string]ioSample{},
+		peaks:    map[string]*DiskIOObservation{},
+		windows:  map[string][]DiskIOObservation{},
+	}
+}
+
+func (o *diskIOObserver) run(ctx context.Context) {
+	klog.InfoS("Disk IO observation started", "interval", diskIOSampleInterval)
+	ticker := o.clk.NewTicker(diskIOSampleInterval)
+	defer ticker.Stop()
+	for {
+		if err := o.sample(ctx); err != nil {
+			klog.ErrorS(err, "failed to sample disk IO")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// sample observes the IO counters of all disk volumes on this node.
+func (o *diskIOObserver) sample(ctx context.Context) error {
+	volumes, err := o.volumes(ctx)
+	if err != nil {
+		return err
+	}
+	o.forget(volumes)
+	diskStats, err := o.stats()
+	if err != nil {
+		return fmt.Errorf("couldn't get diskstats: %w", err)
+	}
+	byDev := make(map[uint64]*blockdevice.IOStats, len(diskStats))
+	for i, s := range diskStats {
+		byDev[unix.Mkdev(s.MajorNumber, s.MinorNumber)] = &diskStats[i].IOStats
+	}
+	for pvName, dev := range volumes {
+		selected, err := o.selects(ctx, pvName)
+		if err != nil {
+			klog.ErrorS(err, "failed to check whether to observe disk IO", "pv", pvName)
+			continue
+		}
+		if stats, ok := byDev[dev]; ok && selected {
+			o.observe(ctx, pvName, stats)
+		}
+	}
+	return nil
+}
+
+// selects checks whether the PV is observed, getting it only the first time.
+func (o *diskIOObserver) selects(ctx context.Context, pvName string) (bool, error) {
+	if selected, ok := o.selected[pvName]; ok {
+		return selected, nil
+	}
+	pv, err := o.client.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		o.selected[pvName] = false
+	case err != nil:
+		return false, fmt.Errorf("failed to get PV %s: %w", pvName, err)
+	default:
+		o.selected[pvName] = o.observed(pv)
+	}
+	return o.selected[pvName], nil
+}
+
+func (o *diskIOObserver) observe(ctx context.Context, pvName string, stats *blockdevice.IOStats) {
+	now := o.clk.Now()
+	sample := ioSample{
+		ios:     stats.ReadIOs + stats.WriteIOs,
+		sectors: stats.ReadSectors + stats.WriteSectors,
+		at:      now,
+	}
+
+	last, ok := o.last[pvName]
+	o.last[pvName] = sample
+	peak := o.peaks[pvName]
+	if peak == nil {
+		peak = &DiskIOObservation{Since: now, Node: o.nodeName}
+		o.peaks[pvName] = peak
+	}
+	// counters restart from 0 if the disk is attached again
+	if elapsed := now.Sub(last.at).Seconds(); ok && elapsed > 0 && sample.ios >= last.ios && sample.sectors >= last.sectors {
+		peak.PeakIOPS = max(peak.PeakIOPS, uint64(float64(sample.ios-last.ios)/elapsed))
+		peak.PeakThroughput = max(peak.PeakThroughput, uint64(float64(sample.sectors-last.sectors)*512/elapsed))
+	}
+	if now.Sub(peak.Since) < DiskIOObserveInterval {
+		return
+	}
+	peak.Until = now
+	windows := append(o.windows[pvName], *peak)
+	if len(windows) > DiskIOObservationWindows {
+		windows = windows[len(windows)-DiskIOObservationWindows:]
+	}
+	o.windows[pvName] = windows
+	o.peaks[pvName] = &DiskIOObservation{Since: now, Node: o.nodeName}
+	if err := o.record(ctx, pvName, windows); err != nil {
+		klog.ErrorS(err, "failed to record observed disk IO", "pv", pvName)
+	}
+}
+
+// forget drops the volumes no longer on this node.
+func (o *diskIOObserver) forget(volumes map[string]uint64) {
+	for pvName := range o.selected {
+		if _, ok := volumes[pvName]; !ok {
+			delete(o.selected, pvName)
+			delete(o.last, pvName)
+			delete(o.peaks, pvName)
+			delete(o.windows, pvName)
+		}
+	}
+}
+
+func (o *diskIOObserver) record(ctx context.Context, pvName string, windows []DiskIOObservation) error {
+	value, err := json.Marshal(windows)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{DiskIOObservationAnnotation: string(value)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = o.client.CoreV1().PersistentVolumes().Patch(ctx, pvName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err == nil {
+		last := windows[len(windows)-1]
+		klog.V(4).InfoS("recorded observed disk IO", "pv", pvName, "peakIOPS", last.PeakIOPS, "peakThroughput", last.PeakThroughput)
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+//go:build !windows
+
+package metric
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/procfs/blockdevice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestDiskIOObserver(t *testing.T) {
+	pv := &corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-disk", Labels: map[string]string{"observed": "true"}}}
+	other := &corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-other"}}
+	client := fake.NewSimpleClientset(pv, other)
+
+	clk := clocktesting.NewFakeClock(time.Now())
+	start := clk.Now()
+	o := newDiskIOObserver(client, "node-1", clk, func(pv *corev1.PersistentVolume) bool { return pv.Labels["observed"] == "true" })
+	volumes := map[string]uint64{"pv-disk": unix.Mkdev(253, 16), "pv-other": unix.Mkdev(253, 32), "pv-gone": unix.Mkdev(253, 48)}
+	o.volumes = func(ctx context.Context) (map[string]uint64, error) { return volumes, nil }
+	var current blockdevice.IOStats
+	o.stats = func() ([]blockdevice.Diskstats, error) {
+		return []blockdevice.Diskstats{
+			{Info: blockdevice.Info{MajorNumber: 253, MinorNumber: 0}, IOStats: blockdevice.IOStats{ReadIOs: 1 << 30}},
+			{Info: blockdevice.Info{MajorNumber: 253, MinorNumber: 16}, IOStats: current},
+			{Info: blockdevice.Info{MajorNumber: 253, MinorNumber: 32}, IOStats: current},
+			{Info: blockdevice.Info{MajorNumber: 253, MinorNumber: 48}, IOStats: current},
+		}, nil
+	}
+	ctx := context.Background()
+	observations := func() []DiskIOObservation {
+		pv, err := client.CoreV1().PersistentVolumes().Get(ctx, "pv-disk", metav1.GetOptions{})
+		require.NoError(t, err)
+		ob, err := ParseDiskIOObservations(pv.Annotations)
+		require.NoError(t, err)
+		return ob
+	}
+	sample := func(ios, sectors uint64) {
+		current = blockdevice.IOStats{ReadIOs: ios, WriteSectors: sectors}
+		require.NoError(t, o.sample(ctx))
+	}
+
+	sample(1000, 2048)
+	clk.Step(time.Minute)
+	sample(1000+60*3000, 2048+60*2048*100) // 3000 IOPS, 100 MiB/s
+	clk.Step(time.Minute)
+	sample(1000+60*3000+60*500, 2048+60*2048*200) // 500 IOPS, 100 MiB/s
+	assert.Nil(t, observations(), "should wait for the interval")
+
+	// counters reset on reattach
+	clk.Step(DiskIOObserveInterval)
+	sample(10, 10)
+	ob := observations()
+	require.Len(t, ob, 1)
+	assert.Equal(t, uint64(3000), ob[0].PeakIOPS)
+	assert.Equal(t, uint64(100<<20), ob[0].PeakThroughput)
+	assert.Equal(t, "node-1", ob[0].Node)
+	assert.True(t, ob[0].Since.Equal(start))
+	assert.True(t, ob[0].Until.Equal(clk.Now()))
+
+	// peak is reset after recorded, the latest windows are kept
+	for i := range DiskIOObservationWindows {
+		clk.Step(DiskIOObserveInterval)
+		sample(10+uint64(i+1)*300*100, 10)
+	}
+	ob = observations()
+	require.Len(t, ob, DiskIOObservationWindows)
+	assert.Equal(t, uint64(100), ob[len(ob)-1].PeakIOPS)
+	assert.Equal(t, uint64(0), ob[len(ob)-1].PeakThroughput)
+	assert.True(t, ob[len(ob)-1].Since.Equal(ob[len(ob)-2].Until))
+
+	// not selected, each PV is got once only
+	other, err := client.CoreV1().PersistentVolumes().Get(ctx, "pv-other", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, other.Annotations, DiskIOObservationAnnotation)
+	assert.NotContains(t, o.windows, "pv-other")
+	assert.Equal(t, map[string]bool{"pv-disk": true, "pv-other": false, "pv-gone": false}, o.selected)
+	client.ClearActions()
+	require.NoError(t, o.sample(ctx))
+	assert.Empty(t, client.Actions())
+
+	volumes = map[string]uint64{}
+	require.NoError(t, o.sample(ctx))
+	assert.Empty(t, o.selected)
+	assert.Empty(t, o.last)
+	assert.Empty(t, o.peaks)
+	assert.Empty(t, o.windows)
+}
+
+func TestDiskIOObserverRun(t *testing.T) {
+	clk := clocktesting.NewFakeClock(time.Now())
+	o := newDiskIOObserver(fake.NewSimpleClientset(), "node-1", clk, nil)
+	sampled := make(chan struct{}, 10)
+	o.volumes = func(ctx context.Context) (map[string]uint64, error) {
+		sampled <- struct{}{}
+		return nil, nil
+	}
+	o.stats = func() ([]blockdevice.Diskstats, error) { return nil, nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go o.run(ctx)
+	<-sampled
+	// sampled by the ticker without any scrape
+	require.Eventually(t, func() bool {
+		clk.Step(diskIOSampleInterval)
+		select {
+		case <-sampled:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestParseDiskIOObservations(t *testing.T) {
+	ob, err := ParseDiskIOObservations(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, ob)
+
+	_, err = ParseDiskIOObservations(map[string]string{DiskIOObservationAnnotation: "{"})
+	assert.Error(t, err)
+}
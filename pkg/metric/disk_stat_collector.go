@@ -9,7 +9,6 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -25,6 +24,7 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/mount-utils"
 
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/health"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/options"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
 )
@@ -85,8 +85,8 @@ type diskInfo struct {
 }
 
 type diskStatCollector struct {
-	milliSecondsLatencyThreshold float64 //Unit: milliseconds
-	capacityPercentageThreshold  float64
+	milliSecondsLatencyThreshold *threshold //Unit: milliseconds
+	capacityPercentageThreshold  *threshold
 	pvInfoLock                   sync.Mutex
 	lastPvDiskInfoMap            map[string]diskInfo
 	lastPvStatsMap               atomic.Pointer[map[uint64]*blockdevice.Diskstats]
@@ -95,30 +95,15 @@ type diskStatCollector struct {
 	recorder                     record.EventRecorder
 	mounter                      mount.Interface
 	nodeName                     string
+	health                       *health.Reader
+	healthLock                   sync.Mutex
+	lastHealthProblems           map[uint64]string // device -> problems
 }
 
 func init() {
 	registerCollector("disk_stat", NewDiskStatCollector, diskDriverName)
 }
 
-func getDiskLatencyThreshold() float64 {
-	latencyStr := strings.ToLower(strings.Trim(os.Getenv("DISK_LATENCY_THRESHOLD"), " "))
-	if len(latencyStr) != 0 {
-		latency, _ := parseLatencyThreshold(latencyStr, diskDefaultsLatencyThreshold)
-		return latency
-	}
-	return 0
-}
-
-func getDiskCapacityThreshold() float64 {
-	capacityStr := strings.ToLower(strings.Trim(os.Getenv("DISK_CAPACITY_THRESHOLD_PERCENTAGE"), " "))
-	if len(capacityStr) != 0 {
-		capacity, _ := parseCapacityThreshold(capacityStr, diskDefaultsCapacityPercentageThreshold)
-		return capacity
-	}
-	return 0
-}
-
 // NewDiskStatCollector returns a new Collector exposing disk stats.
 func NewDiskStatCollector() (Collector, error) {
 	recorder := utils.NewEventRecorder()
@@ -143,11 +128,13 @@ func NewDiskStatCollector() (Collector, error) {
 		lastPvDiskInfoMap:            make(map[string]diskInfo, 0),
 		diskStats:                    diskStats,
 		clientSet:                    clientset,
-		milliSecondsLatencyThreshold: getDiskLatencyThreshold(),
-		capacityPercentageThreshold:  getDiskCapacityThreshold(),
+		milliSecondsLatencyThreshold: diskLatencyThreshold(),
+		capacityPercentageThreshold:  diskCapacityThreshold(),
 		recorder:                     recorder,
 		mounter:                      mount.NewWithoutSystemd(""),
 		nodeName:                     nodeName,
+		health:                       health.DefaultReader,
+		lastHealthProblems:           map[uint64]string{},
 	}, nil
 }
 
@@ -158,6 +145,7 @@ func (p *diskStatCollector) Update(ctx context.Context, pvcs sets.Set[string], c
 		return err
 	}
 	p.updateMap(ctx, &p.lastPvDiskInfoMap, volJSONPaths, diskDriverName)
+	p.forgetHealth()
 
 	diskStats, err := p.diskStats.GetStats()
 	if err != nil {
@@ -201,6 +189,7 @@ func (p *diskStatCollector) Update(ctx context.Context, pvcs sets.Set[string], c
 			p.recorder.Eventf(info.PVCRef, v1.EventTypeWarning, ioHang, "IO Hang on Persistent Volume %s, nodeName:%s, diskID:%s, Device:%s",
 				pvName, p.nodeName, info.DiskID, devPath)
 		}
+		p.updateHealth(pvName, info, labels, ch)
 
 		capStats, err := getDiskCapacityMetric(info.DiskID)
 		if err != nil {
@@ -216,7 +205,8 @@ func (p *diskStatCollector) Update(ctx context.Context, pvcs sets.Set[string], c
 }
 
 func (p *diskStatCollector) latencyEventAlert(stat, lastStat *blockdevice.IOStats, ref *v1.ObjectReference) {
-	if p.milliSecondsLatencyThreshold <= 0 {
+	latencyThreshold := p.milliSecondsLatencyThreshold.Get()
+	if latencyThreshold <= 0 {
 		return
 	}
 
@@ -225,11 +215,11 @@ func (p *diskStatCollector) latencyEventAlert(stat, lastStat *blockdevice.IOStat
 			return
 		}
 		l := float64(dLatency) / float64(dIOPS)
-		if l <= p.milliSecondsLatencyThreshold {
+		if l <= latencyThreshold {
 			return
 		}
 		p.recorder.Eventf(ref, v1.EventTypeWarning, latencyTooHigh, "PVC %s/%s latency is too high, nodeName: %s, latency:%.2f ms, threshold:%.2f ms",
-			ref.Namespace, ref.Name, op, p.nodeName, l, p.milliSecondsLatencyThreshold)
+			ref.Namespace, ref.Name, op, p.nodeName, l, latencyThreshold)
 	}
 	// Note that this `lastStat.ReadTicks-stat.ReadTicks` even works when the counter overflows and wraps around.
 	alert("read", lastStat.ReadTicks-stat.ReadTicks, lastStat.ReadIOs-stat.ReadIOs)
@@ -237,15 +227,16 @@ func (p *diskStatCollector) latencyEventAlert(stat, lastStat *blockdevice.IOStat
 }
 
 func (p *diskStatCollector) capacityEventAlert(usage []*csi.VolumeUsage, ref *v1.ObjectReference) {
-	if p.capacityPercentageThreshold <= 0 {
+	capacityThreshold := p.capacityPercentageThreshold.Get()
+	if capacityThreshold <= 0 {
 		return
 	}
 	for _, stat := range usage {
 		usedPercentage := (float64(stat.Used) / float64(stat.Total)) * 100
-		if usedPercentage >= p.capacityPercentageThreshold {
+		if usedPercentage >= capacityThreshold {
 			p.recorder.Eventf(ref, v1.EventTypeWarning, capacityNotEnough,
 				"PVC %s/%s has not enough disk %v capacity, nodeName:%s, used:%.2f%%, threshold:%.2f%%",
-				ref.Namespace, ref.Name, stat.Unit, p.nodeName, usedPercentage, p.capacityPercentageThreshold)
+				ref.Namespace, ref.Name, stat.Unit, p.nodeName, usedPercentage, capacityThreshold)
 		}
 	}
 }
@@ -275,6 +266,14 @@ func (p *diskStatCollector) sendCapStats(stats []*csi.VolumeUsage, labels []stri
 }
 
 func (p *diskStatCollector) updateMap(ctx context.Context, lastPvDiskInfoMap *map[string]diskInfo, jsonPaths []string, driverName string) {
+	thisPvDiskInfoMap := findDiskVolumes(ctx, p.mounter, jsonPaths, driverName)
+
+	//If there is a change: add, modify, delete
+	p.updateDiskInfoMap(ctx, thisPvDiskInfoMap, lastPvDiskInfoMap)
+}
+
+// findDiskVolumes returns the mounted volumes of driverName by PV name, without PVCRef.
+func findDiskVolumes(ctx context.Context, mounter mount.Interface, jsonPaths []string, driverName string) map[string]diskInfo {
 	thisPvDiskInfoMap := make(map[string]diskInfo, 0)
 	for _, path := range jsonPaths {
 		if ctx.Err() != nil {
@@ -290,7 +289,7 @@ func (p *diskStatCollector) updateMap(ctx context.Context, lastPvDiskInfoMap *ma
 		}
 
 		mountPoint := filepath.Join(path, "../mount")
-		notMounted, err := p.mounter.IsLikelyNotMountPoint(mountPoint)
+		notMounted, err := mounter.IsLikelyNotMountPoint(mountPoint)
 		if err != nil {
 			if !errors.Is(err, fs.ErrNotExist) {
 				klog.Errorf("Check if %s is mount point failed: %v", mountPoint, err)
@@ -314,9 +313,7 @@ func (p *diskStatCollector) updateMap(ctx context.Context, lastPvDiskInfoMap *ma
 		}
 		thisPvDiskInfoMap[pvName] = diskInfo
 	}
-
-	//If there is a change: add, modify, delete
-	p.updateDiskInfoMap(ctx, thisPvDiskInfoMap, lastPvDiskInfoMap)
+	return thisPvDiskInfoMap
 }
 
 func (p *diskStatCollector) updateDiskInfoMap(ctx context.Context, thisPvDiskInfoMap map[string]diskInfo, lastPvDiskInfoMap *map[string]diskInfo) {
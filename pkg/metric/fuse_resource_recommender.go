@@ -0,0 +1,188 @@
+package metric
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	fpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	statsapi "k8s.io/kubelet/pkg/apis/stats/v1alpha1"
+)
+
+const (
+	fuseResourceSampleInterval    = 30 * time.Second
+	fuseResourceRecommendInterval = 10 * time.Minute
+)
+
+type fusePodPeak struct {
+	volumeID string
+	cpu      *resource.Quantity
+	memory   *resource.Quantity
+	// recommended is the last time the peak was written to the PV
+	recommended time.Time
+}
+
+// fuseResourceRecommender tracks the peak usage of fuse pods on this node,
+// and records the recommended resources on the PV periodically.
+// The recommendation only grows, as the same PV may be mounted on multiple nodes.
+// Remove the annotation from the PV to reset it.
+type fuseResourceRecommender struct {
+	client   kubernetes.Interface
+	summary  func(context.Context) (*statsapi.Summary, error)
+	interval time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	peaks map[string]*fusePodPeak // by pod UID
+}
+
+func newFuseResourceRecommender(client kubernetes.Interface) *fuseResourceRecommender {
+	return &fuseResourceRecommender{
+		client:   client,
+		interval: fuseResourceRecommendInterval,
+		now:      time.Now,
+		peaks:    map[string]*fusePodPeak{},
+	}
+}
+
+// RunFuseResourceRecommender samples the usage of fuse pods on this node from the kubelet until ctx is done,
+// independent of the metrics scrapes.
+func RunFuseResourceRecommender(ctx context.Context, client kubernetes.Interface) {
+	kubelet, err := newKubeletClient()
+	if err != nil {
+		klog.ErrorS(err, "Failed to create kubelet client, not recommending fuse resources")
+		return
+	}
+	r := newFuseResourceRecommender(client)
+	r.summary = func(ctx context.Context) (*statsapi.Summary, error) {
+		return getKubeletStatsSummary(ctx, kubelet)
+	}
+	r.run(ctx)
+}
+
+func (r *fuseResourceRecommender) run(ctx context.Context) {
+	klog.InfoS("Fuse resources recommendation started", "interval", fuseResourceSampleInterval)
+	ticker := time.NewTicker(fuseResourceSampleInterval)
+	defer ticker.Stop()
+	for {
+		summary, err := r.summary(ctx)
+		if err != nil {
+			klog.ErrorS(err, "failed to sample fuse pods usage")
+		} else {
+			r.observe(ctx, summary)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *fuseResourceRecommender) observe(ctx context.Context, summary *statsapi.Summary) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := map[string]bool{}
+	for _, pod := range summary.Pods {
+		if pod.PodRef.Namespace != fpm.FusePodNamespace {
+			continue
+		}
+		seen[pod.PodRef.UID] = true
+		peak, ok := r.peaks[pod.PodRef.UID]
+		if !ok {
+			p, err := r.client.CoreV1().Pods(pod.PodRef.Namespace).Get(ctx, pod.PodRef.Name, metav1.GetOptions{})
+			if err != nil {
+				klog.ErrorS(err, "failed to get fuse pod", "pod", pod.PodRef.Name)
+				continue
+			}
+			peak = &fusePodPeak{
+				volumeID:    p.Annotations[fpm.FuseVolumeIdAnnoKey],
+				recommended: r.now(),
+			}
+			r.peaks[pod.PodRef.UID] = peak
+		}
+		if peak.volumeID == "" {
+			continue
+		}
+		var cpuNano, memoryBytes uint64
+		for _, container := range pod.Containers {
+			if container.CPU != nil && container.CPU.UsageNanoCores != nil {
+				cpuNano += *container.CPU.UsageNanoCores
+			}
+			if container.Memory != nil && container.Memory.WorkingSetBytes != nil {
+				memoryBytes += *container.Memory.WorkingSetBytes
+			}
+		}
+		peak.cpu = maxQuantity(peak.cpu, resource.NewMilliQuantity(int64(cpuNano/1e6), resource.DecimalSI))
+		peak.memory = maxQuantity(peak.memory, resource.NewQuantity(int64(memoryBytes), resource.BinarySI))
+
+		if r.now().Sub(peak.recommended) < r.interval {
+			continue
+		}
+		if err := r.recommend(ctx, peak); err != nil {
+			klog.ErrorS(err, "failed to record fuse resources recommendation", "volumeID", peak.volumeID)
+			continue
+		}
+		peak.recommended = r.now()
+	}
+	for uid := range r.peaks {
+		if !seen[uid] {
+			delete(r.peaks, uid)
+		}
+	}
+}
+
+func (r *fuseResourceRecommender) recommend(ctx context.Context, peak *fusePodPeak) error {
+	pv, err := r.client.CoreV1().PersistentVolumes().Get(ctx, peak.volumeID, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	recommended := fpm.RecommendResources(peak.cpu, peak.memory)
+	existing, err := fpm.ParseResourcesRecommendation(pv.Annotations[fpm.FuseResourcesRecommendationAnnoKey])
+	if err != nil {
+		klog.ErrorS(err, "ignore invalid fuse resources recommendation", "pv", pv.Name)
+	} else if existing != nil {
+		changed := false
+		for name, quantity := range recommended.Requests {
+			if old, ok := existing.Requests[name]; ok && old.Cmp(quantity) >= 0 {
+				recommended.Requests[name] = old
+			} else {
+				changed = true
+			}
+		}
+		if !changed {
+			return nil
+		}
+	}
+	data, err := json.Marshal(recommended)
+	if err != nil {
+		return fmt.Errorf("marshal recommendation: %w", err)
+	}
+	if pv.Annotations == nil {
+		pv.Annotations = map[string]string{}
+	}
+	pv.Annotations[fpm.FuseResourcesRecommendationAnnoKey] = string(data)
+	_, err = r.client.CoreV1().PersistentVolumes().Update(ctx, pv, metav1.UpdateOptions{})
+	if err == nil {
+		klog.V(2).InfoS("recorded fuse resources recommendation", "pv", pv.Name, "requests", recommended.Requests)
+	}
+	return err
+}
+
+func maxQuantity(a, b *resource.Quantity) *resource.Quantity {
+	if a == nil || b.Cmp(*a) > 0 {
+		return b
+	}
+	return a
+}
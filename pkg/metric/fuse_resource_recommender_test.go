@@ -0,0 +1,84 @@
+package metric
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	fpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	statsapi "k8s.io/kubelet/pkg/apis/stats/v1alpha1"
+)
+
+func fuseStatsSummary(cpuNano, memoryBytes uint64) *statsapi.Summary {
+	return &statsapi.Summary{
+		Pods: []statsapi.PodStats{{
+			PodRef: statsapi.PodReference{Namespace: fpm.FusePodNamespace, Name: "csi-fuse-ossfs2-abc", UID: "uid-1"},
+			Containers: []statsapi.ContainerStats{{
+				Name:   "ossfs2",
+				CPU:    &statsapi.CPUStats{UsageNanoCores: &cpuNano},
+				Memory: &statsapi.MemoryStats{WorkingSetBytes: &memoryBytes},
+			}},
+		}},
+	}
+}
+
+func TestFuseResourceRecommender(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   fpm.FusePodNamespace,
+		Name:        "csi-fuse-ossfs2-abc",
+		Annotations: map[string]string{fpm.FuseVolumeIdAnnoKey: "pv-oss"},
+	}}
+	pv := &corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-oss"}}
+	client := fake.NewSimpleClientset(pod, pv)
+
+	now := time.Now()
+	r := newFuseResourceRecommender(client)
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+	getRecommendation := func() string {
+		pv, err := client.CoreV1().PersistentVolumes().Get(ctx, "pv-oss", metav1.GetOptions{})
+		require.NoError(t, err)
+		return pv.Annotations[fpm.FuseResourcesRecommendationAnnoKey]
+	}
+
+	r.observe(ctx, fuseStatsSummary(500e6, 200<<20))
+	assert.Empty(t, getRecommendation(), "should wait for the interval")
+
+	// lower usage keeps the peak
+	now = now.Add(fuseResourceRecommendInterval)
+	r.observe(ctx, fuseStatsSummary(100e6, 100<<20))
+	assert.JSONEq(t, `{"requests":{"cpu":"600m","memory":"240Mi"}}`, getRecommendation())
+
+	// recommendation never shrinks
+	r.peaks = map[string]*fusePodPeak{}
+	r.observe(ctx, fuseStatsSummary(100e6, 100<<20))
+	now = now.Add(fuseResourceRecommendInterval)
+	r.observe(ctx, fuseStatsSummary(100e6, 300<<20))
+	assert.JSONEq(t, `{"requests":{"cpu":"600m","memory":"360Mi"}}`, getRecommendation())
+
+	// pods gone are forgotten
+	r.observe(ctx, &statsapi.Summary{})
+	assert.Empty(t, r.peaks)
+}
+
+func TestFuseResourceRecommenderRun(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   fpm.FusePodNamespace,
+		Name:        "csi-fuse-ossfs2-abc",
+		Annotations: map[string]string{fpm.FuseVolumeIdAnnoKey: "pv-oss"},
+	}})
+	r := newFuseResourceRecommender(client)
+	ctx, cancel := context.WithCancel(context.Background())
+	r.summary = func(context.Context) (*statsapi.Summary, error) {
+		cancel()
+		return fuseStatsSummary(500e6, 200<<20), nil
+	}
+	r.run(ctx)
+	require.Contains(t, r.peaks, "uid-1")
+	assert.Equal(t, "500m", r.peaks["uid-1"].cpu.String())
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
 	"github.com/prometheus/client_golang/prometheus"
@@ -328,6 +329,31 @@ var (
 		".",
 		usFsStatLabelNames, nil,
 	)
+	cacheHitTotalCounterDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(nodeNamespace, volumeSubsystem, "cache_hit_total_counter"),
+		"Number of reads served from the local data cache.",
+		usFsStatLabelNames, nil,
+	)
+	cacheMissTotalCounterDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(nodeNamespace, volumeSubsystem, "cache_miss_total_counter"),
+		"Number of reads that missed the local data cache.",
+		usFsStatLabelNames, nil,
+	)
+	cacheUsedBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(nodeNamespace, volumeSubsystem, "cache_used_bytes"),
+		"Bytes used by the local data cache.",
+		usFsStatLabelNames, nil,
+	)
+	credentialAgeSecondsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(nodeNamespace, volumeSubsystem, "credential_age_seconds"),
+		"Seconds since the credential used by the fuse client was last rotated.",
+		usFsStatLabelNames, nil,
+	)
+	credentialExpirationTimestampDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(nodeNamespace, volumeSubsystem, "credential_expiration_timestamp_seconds"),
+		"Unix time when the credential used by the fuse client expires.",
+		usFsStatLabelNames, nil,
+	)
 )
 
 type fuseInfo struct {
@@ -367,6 +393,7 @@ type usFsStatCollector struct {
 	latencyMillisecondsCounterDesc        latencyMillisecondsCounterDesc
 	posixCounterDesc                      posixCounterDesc
 	ossObjectCounterDesc                  ossObjectCounterDesc
+	cacheCounterDesc                      cacheCounterDesc
 	backendThroughputBytesCounterDesc     backendThroughputBytesCounterDesc
 	backendIOPSCompletedCounterDesc       backendIOPSCompletedCounterDesc
 	backendLatencyMillisecondsCounterDesc backendLatencyMillisecondsCounterDesc
@@ -375,6 +402,8 @@ type usFsStatCollector struct {
 	mountPointStatus                      *typedFactorDesc
 	mountPointFailoverTotalCounter        *typedFactorDesc
 	lastFuseClientExitReason              *typedFactorDesc
+	credentialAgeSeconds                  *typedFactorDesc
+	credentialExpirationTimestamp         *typedFactorDesc
 }
 
 type capacityBytesCounterDesc struct {
@@ -400,6 +429,10 @@ type posixCounterDesc struct {
 	descs []typedFactorDesc
 }
 
+type cacheCounterDesc struct {
+	descs []typedFactorDesc
+}
+
 type ossObjectCounterDesc struct {
 	descs []typedFactorDesc
 }
@@ -488,6 +521,13 @@ func NewFuseStatCollector() (Collector, error) {
 				{desc: ossPostObjectTotalCounterDesc, valueType: prometheus.CounterValue},
 			},
 		},
+		cacheCounterDesc: cacheCounterDesc{
+			descs: []typedFactorDesc{
+				{desc: cacheHitTotalCounterDesc, valueType: prometheus.CounterValue},
+				{desc: cacheMissTotalCounterDesc, valueType: prometheus.CounterValue},
+				{desc: cacheUsedBytesDesc, valueType: prometheus.GaugeValue},
+			},
+		},
 		backendThroughputBytesCounterDesc: backendThroughputBytesCounterDesc{
 			descs: []typedFactorDesc{
 				{desc: backendReadBytesTotalCounterDesc, valueType: prometheus.CounterValue},
@@ -526,6 +566,8 @@ func NewFuseStatCollector() (Collector, error) {
 		mountPointStatus:               &typedFactorDesc{desc: mountPointStatusDesc, valueType: prometheus.GaugeValue},
 		mountPointFailoverTotalCounter: &typedFactorDesc{desc: mountPointFailoverTotalCountDesc, valueType: prometheus.CounterValue},
 		lastFuseClientExitReason:       &typedFactorDesc{desc: lastFuseClientExitReasonDesc, valueType: prometheus.GaugeValue},
+		credentialAgeSeconds:           &typedFactorDesc{desc: credentialAgeSecondsDesc, valueType: prometheus.GaugeValue},
+		credentialExpirationTimestamp:  &typedFactorDesc{desc: credentialExpirationTimestampDesc, valueType: prometheus.GaugeValue},
 	}, nil
 }
 
@@ -607,6 +649,11 @@ func (p *usFsStatCollector) postCounterMetrics(counterType string, fsClientInfo
 				return
 			}
 			ch <- p.ossObjectCounterDesc.descs[i].mustNewConstMetric(valueFloat64, labels...)
+		case "cache_counter":
+			if i >= len(p.cacheCounterDesc.descs) {
+				return
+			}
+			ch <- p.cacheCounterDesc.descs[i].mustNewConstMetric(valueFloat64, labels...)
 		default:
 			klog.Errorf("Unknown counterType:%s", counterType)
 		}
@@ -680,6 +727,10 @@ func (p *usFsStatCollector) postMountPointStatusMetrics(statusType string, fsCli
 			ch <- p.mountPointStatus.mustNewConstMetric(valueFloat64, labels...)
 		case utils.MetricsMountPointFailoverCount:
 			ch <- p.mountPointFailoverTotalCounter.mustNewConstMetric(valueFloat64, labels...)
+		case utils.MetricsCredentialRotationTimestamp:
+			ch <- p.credentialAgeSeconds.mustNewConstMetric(float64(time.Now().Unix())-valueFloat64, labels...)
+		case utils.MetricsCredentialExpirationTimestamp:
+			ch <- p.credentialExpirationTimestamp.mustNewConstMetric(valueFloat64, labels...)
 		}
 	}
 }
@@ -84,22 +84,50 @@ type kubeletStatsSummaryCollector struct {
 	client *http.Client
 }
 
-func (c *kubeletStatsSummaryCollector) Update(ctx context.Context, pvcs sets.Set[string], ch chan<- prometheus.Metric) error {
+// getKubeletStatsSummary reads the stats summary from the kubelet on this node.
+func getKubeletStatsSummary(ctx context.Context, client *http.Client) (*statsapi.Summary, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, kubeletStatsSummaryUrl, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	resp, err := c.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to get kubelet stats summary: %d: %q", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("failed to get kubelet stats summary: %d: %q", resp.StatusCode, string(body))
 	}
 	var summary statsapi.Summary
 	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+func newKubeletClient() (*http.Client, error) {
+	config := &transport.Config{
+		UserAgent: rest.DefaultKubernetesUserAgent(),
+		TLS: transport.TLSConfig{
+			// kubelet cert SANs do not contains localhost or 127.0.0.1
+			Insecure: true,
+		},
+		BearerTokenFile: saTokenFile,
+	}
+	tr, err := transport.New(config)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: tr,
+		Timeout:   kubeletHttpTimeout,
+	}, nil
+}
+
+func (c *kubeletStatsSummaryCollector) Update(ctx context.Context, pvcs sets.Set[string], ch chan<- prometheus.Metric) error {
+	summary, err := getKubeletStatsSummary(ctx, c.client)
+	if err != nil {
 		return err
 	}
 	for _, pod := range summary.Pods {
@@ -127,24 +155,9 @@ func (c *kubeletStatsSummaryCollector) Update(ctx context.Context, pvcs sets.Set
 }
 
 func NewKubeletStatsSummaryCollector() (Collector, error) {
-	config := &transport.Config{
-		UserAgent: rest.DefaultKubernetesUserAgent(),
-		TLS: transport.TLSConfig{
-			// kubelet cert SANs do not contains localhost or 127.0.0.1
-			Insecure: true,
-		},
-		BearerTokenFile: saTokenFile,
-	}
-	tr, err := transport.New(config)
+	client, err := newKubeletClient()
 	if err != nil {
 		return nil, err
 	}
-
-	collector := &kubeletStatsSummaryCollector{
-		client: &http.Client{
-			Transport: tr,
-			Timeout:   kubeletHttpTimeout,
-		},
-	}
-	return collector, nil
+	return &kubeletStatsSummaryCollector{client: client}, nil
 }
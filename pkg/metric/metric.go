@@ -7,6 +7,9 @@ import (
 	"strings"
 
 	"github.com/go-logr/logr"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/disk/warmpool"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
 	"github.com/prometheus/client_golang/prometheus"
 	versioncollector "github.com/prometheus/client_golang/prometheus/collectors/version"
@@ -63,7 +66,10 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	reg := prometheus.NewRegistry()
 	reg.MustRegister(
 		versioncollector.NewCollector("alibaba_cloud_csi_driver"),
-		&csiCollector, &CsiGrpcExecTimeCollector)
+		&csiCollector, &CsiGrpcExecTimeCollector, &OpenAPILimiterCollector,
+		wrap.RequestDuration, wrap.RequestsTotal, wrap.ErrorReasonsTotal, throttle.WaitDuration,
+		warmpool.AvailableDisks, warmpool.AdoptionsTotal, warmpool.CreatedTotal, warmpool.DeletedTotal,
+		VolumeStatCollector.PhaseDurationMetric)
 	handler := promhttp.InstrumentMetricHandler(reg, promhttp.HandlerFor(
 		prometheus.Gatherers{reg, legacyregistry.DefaultGatherer},
 		promhttp.HandlerOpts{
@@ -1,9 +1,11 @@
 package metric
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
 	"github.com/stretchr/testify/assert"
@@ -58,3 +60,19 @@ func TestHandler_ServeHTTP_ConcurrencyLimit(t *testing.T) {
 	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
 	assert.Contains(t, rec.Body.String(), "Limit of concurrent requests reached")
 }
+
+func TestHandler_ServeHTTP_VolumePhaseDuration(t *testing.T) {
+	podsRootPath = t.TempDir()
+	ObserveVolumePhase("disk", "cloud_essd", PhaseAttach, time.Now(), errors.New("failed"))
+	const expected = `csi_volume_phase_duration_seconds_count{driver="disk",phase="attach",result="error",variant="cloud_essd"} 1`
+
+	for _, serviceType := range []utils.ServiceType{utils.Node, utils.Controller, utils.Node | utils.Controller} {
+		handler := NewMetricHandler([]string{diskDriverName}, serviceType)
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), expected)
+	}
+}
@@ -181,7 +181,7 @@ type nfsStatCollector struct {
 	crdClient                   dynamic.Interface
 	monitorClient               *StorageMonitorClient
 	recorder                    record.EventRecorder
-	capacityPercentageThreshold float64
+	capacityPercentageThreshold *threshold
 	mounter                     mount.Interface
 }
 
@@ -189,15 +189,6 @@ func init() {
 	registerCollector("nfs_stat", NewNfsStatCollector, nasDriverName)
 }
 
-func getNfsCapacityThreshold() float64 {
-	capacityStr := strings.ToLower(strings.Trim(os.Getenv("NFS_CAPACITY_THRESHOLD_PERCENTAGE"), " "))
-	if len(capacityStr) != 0 {
-		capacity, _ := parseCapacityThreshold(capacityStr, nfsDefaultsCapacityPercentageThreshold)
-		return capacity
-	}
-	return 0
-}
-
 // NewNfsStatCollector returns a new Collector exposing nfs stats.
 func NewNfsStatCollector() (Collector, error) {
 	config, err := options.GetRestConfig()
@@ -248,7 +239,7 @@ func NewNfsStatCollector() (Collector, error) {
 		crdClient:                   crdClient,
 		recorder:                    recorder,
 		monitorClient:               NewStorageMonitorClient(clientset),
-		capacityPercentageThreshold: getNfsCapacityThreshold(),
+		capacityPercentageThreshold: nfsCapacityThreshold(),
 		mounter:                     mount.NewWithoutSystemd(""),
 	}, nil
 }
@@ -456,9 +447,9 @@ func getTotalAndUsedSize(info *nfsCapacityInfo) (int64, int64) {
 
 func (p *nfsStatCollector) capacityEventAlert(totalSize int64, usedSize int64, pvName string, info nfsInfo) {
 	total, used, gibSize := float64(totalSize), float64(usedSize), float64(GiBSize)
-	if p.capacityPercentageThreshold > 0 {
+	if capacityThreshold := p.capacityPercentageThreshold.Get(); capacityThreshold > 0 {
 		usedPercentage := 100 * used / total
-		if usedPercentage >= float64(p.capacityPercentageThreshold) {
+		if usedPercentage >= capacityThreshold {
 			ref := &v1.ObjectReference{
 				Kind:      "PersistentVolumeClaim",
 				Name:      info.PvcName,
@@ -466,7 +457,7 @@ func (p *nfsStatCollector) capacityEventAlert(totalSize int64, usedSize int64, p
 				Namespace: info.PvcNamespace,
 			}
 			reason := fmt.Sprintf("PVC %s/%s (PV %s) is running out of capacity, totalSize:%fGi, usedSize:%fGi, usedPercentage:%.2f%%, threshold:%.2f%%",
-				info.PvcNamespace, info.PvcName, pvName, total/gibSize, used/gibSize, usedPercentage, p.capacityPercentageThreshold)
+				info.PvcNamespace, info.PvcName, pvName, total/gibSize, used/gibSize, usedPercentage, capacityThreshold)
 			utils.CreateEvent(p.recorder, ref, v1.EventTypeWarning, capacityNotEnough, reason)
 		}
 	}
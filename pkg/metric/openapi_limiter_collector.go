@@ -0,0 +1,51 @@
+package metric
+
+import (
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	openAPILimiterRateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(csiNamespace, openAPISubsystem, "limiter_rate"),
+		"Current learned rate of OpenAPI in requests per second.",
+		[]string{"api"}, nil)
+	openAPILimiterTokensDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(csiNamespace, openAPISubsystem, "limiter_tokens"),
+		"Number of OpenAPI requests that can be sent immediately.",
+		[]string{"api"}, nil)
+	openAPILimiterWaitingDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(csiNamespace, openAPISubsystem, "limiter_waiting"),
+		"Number of OpenAPI requests waiting for the rate limiter.",
+		[]string{"api", "priority"}, nil)
+	openAPILimiterThrottledDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(csiNamespace, openAPISubsystem, "limiter_throttled_total"),
+		"Total number of Throttling errors returned by OpenAPI.",
+		[]string{"api"}, nil)
+)
+
+// openAPILimiterCollector exports the state of OpenAPI rate limiters in registry.
+type openAPILimiterCollector struct {
+	registry *throttle.Registry
+}
+
+var OpenAPILimiterCollector = openAPILimiterCollector{registry: throttle.DefaultRegistry}
+
+func (c *openAPILimiterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- openAPILimiterRateDesc
+	ch <- openAPILimiterTokensDesc
+	ch <- openAPILimiterWaitingDesc
+	ch <- openAPILimiterThrottledDesc
+}
+
+func (c *openAPILimiterCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, l := range c.registry.Limiters() {
+		s := l.Stats()
+		ch <- prometheus.MustNewConstMetric(openAPILimiterRateDesc, prometheus.GaugeValue, s.Rate, s.Name)
+		ch <- prometheus.MustNewConstMetric(openAPILimiterTokensDesc, prometheus.GaugeValue, s.Tokens, s.Name)
+		for p, n := range s.Waiting {
+			ch <- prometheus.MustNewConstMetric(openAPILimiterWaitingDesc, prometheus.GaugeValue, float64(n), s.Name, throttle.Priority(p).String())
+		}
+		ch <- prometheus.MustNewConstMetric(openAPILimiterThrottledDesc, prometheus.CounterValue, float64(s.Throttled), s.Name)
+	}
+}
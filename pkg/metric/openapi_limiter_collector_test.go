@@ -0,0 +1,39 @@
+package metric
+
+import (
+	"testing"
+
+	alierrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/clock"
+)
+
+func TestOpenAPILimiterCollector(t *testing.T) {
+	r := throttle.NewRegistry(clock.RealClock{})
+	l := r.Get("ecs.CreateDisk", throttle.LimiterConfig{MaxRate: 10, MinRate: 1, Burst: 10})
+	l.Observe(alierrors.NewServerError(400, `{"Code": "Throttling"}`, ""))
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(&openAPILimiterCollector{registry: r})
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	values := map[string][]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			assert.Equal(t, "ecs.CreateDisk", m.GetLabel()[0].GetValue())
+			if m.GetGauge() != nil {
+				values[f.GetName()] = append(values[f.GetName()], m.GetGauge().GetValue())
+			} else {
+				values[f.GetName()] = append(values[f.GetName()], m.GetCounter().GetValue())
+			}
+		}
+	}
+	assert.Equal(t, []float64{5}, values["csi_openapi_limiter_rate"])
+	assert.Equal(t, []float64{1}, values["csi_openapi_limiter_throttled_total"])
+	assert.Equal(t, []float64{0, 0, 0}, values["csi_openapi_limiter_waiting"])
+	assert.Len(t, values["csi_openapi_limiter_tokens"], 1)
+}
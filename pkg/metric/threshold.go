@@ -0,0 +1,70 @@
+package metric
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+)
+
+// threshold is an event alert threshold that follows its key in csi-plugin ConfigMap.
+// 0 disables the alert.
+type threshold struct {
+	parse func(string) (float64, error)
+	value atomic.Uint64 // math.Float64bits
+}
+
+// Each threshold subscribes to the config only once, and is shared by all collectors.
+var (
+	diskLatencyThreshold = sync.OnceValue(func() *threshold {
+		return newThreshold(utils.CSIPluginConfig, utils.ConfigKey{Name: "disk-latency-threshold", Env: "DISK_LATENCY_THRESHOLD"},
+			func(s string) (float64, error) { return parseLatencyThreshold(s, diskDefaultsLatencyThreshold) })
+	})
+	diskCapacityThreshold = sync.OnceValue(func() *threshold {
+		return newThreshold(utils.CSIPluginConfig, utils.ConfigKey{Name: "disk-capacity-threshold-percentage", Env: "DISK_CAPACITY_THRESHOLD_PERCENTAGE"},
+			func(s string) (float64, error) {
+				return parseCapacityThreshold(s, diskDefaultsCapacityPercentageThreshold)
+			})
+	})
+	nfsCapacityThreshold = sync.OnceValue(func() *threshold {
+		return newThreshold(utils.CSIPluginConfig, utils.ConfigKey{Name: "nfs-capacity-threshold-percentage", Env: "NFS_CAPACITY_THRESHOLD_PERCENTAGE"},
+			func(s string) (float64, error) {
+				return parseCapacityThreshold(s, nfsDefaultsCapacityPercentageThreshold)
+			})
+	})
+)
+
+func normalizeThreshold(s string) string {
+	return strings.ToLower(strings.Trim(s, " "))
+}
+
+func newThreshold(w *utils.WatchedConfig, key utils.ConfigKey, parse func(string) (float64, error)) *threshold {
+	t := &threshold{parse: parse}
+	key.Validate = func(s string) error {
+		_, err := parse(normalizeThreshold(s))
+		return err
+	}
+	w.RegisterKeys(key)
+	update := func(c utils.Config) { t.set(key.Get(c)) }
+	update(w.Current())
+	w.Subscribe(update)
+	return t
+}
+
+func (t *threshold) set(s string) {
+	var v float64
+	if s = normalizeThreshold(s); s != "" {
+		v, _ = t.parse(s)
+	}
+	t.value.Store(math.Float64bits(v))
+}
+
+// Get returns the current value. A nil threshold is disabled.
+func (t *threshold) Get() float64 {
+	if t == nil {
+		return 0
+	}
+	return math.Float64frombits(t.value.Load())
+}
@@ -0,0 +1,30 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreshold(t *testing.T) {
+	w := utils.NewWatchedConfig()
+	w.Update(map[string]string{"test-latency": "2s"})
+	th := newThreshold(w, utils.ConfigKey{Name: "test-latency", Env: "TEST_LATENCY_THRESHOLD"},
+		func(s string) (float64, error) { return parseLatencyThreshold(s, diskDefaultsLatencyThreshold) })
+	assert.Equal(t, 2000.0, th.Get())
+
+	w.Update(map[string]string{"test-latency": "50ms"})
+	assert.Equal(t, 50.0, th.Get())
+
+	// invalid value is rejected
+	w.Update(map[string]string{"test-latency": "fast"})
+	assert.Equal(t, 50.0, th.Get())
+
+	// removed, alert disabled
+	w.Update(nil)
+	assert.Equal(t, 0.0, th.Get())
+
+	var disabled *threshold
+	assert.Equal(t, 0.0, disabled.Get())
+}
@@ -2,6 +2,7 @@ package metric
 
 import (
 	"context"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -14,11 +15,35 @@ const (
 
 var volumeStatLabels = []string{VolumeStatsLabelType}
 
+// variant is the disk category for disk, the mount protocol for NAS, and the fuse type for OSS.
+var volumePhaseLabels = []string{"driver", "variant", "phase", "result"}
+
+// Phases of making a volume available to a pod.
+const (
+	// PhaseAttach is the OpenAPI call to attach a disk.
+	PhaseAttach = "attach"
+	// PhaseAttachWait is waiting for the disk to be attached after the OpenAPI call.
+	PhaseAttachWait = "attach_wait"
+	// PhaseDeviceDiscovery is waiting for the block device of an attached disk to show up on the node.
+	PhaseDeviceDiscovery = "device_discovery"
+	// PhaseFormat is fsck or mkfs, and mounting the filesystem.
+	PhaseFormat = "format"
+	// PhaseResize is growing the filesystem to the size of the device.
+	PhaseResize = "resize"
+	// PhasePublish is bind-mounting the volume into the pod.
+	PhasePublish = "publish"
+	// PhaseFusePodReady is scheduling and starting the fuse pod.
+	PhaseFusePodReady = "fuse_pod_ready"
+	// PhaseMount is the mount call of NAS or fuse filesystems.
+	PhaseMount = "mount"
+)
+
 type VolumeStatType uint8
 
 type volumeStatCollector struct {
 	AttachmentCountMetric     *prometheus.CounterVec
 	AttachmentTimeTotalMetric *prometheus.CounterVec
+	PhaseDurationMetric       *prometheus.HistogramVec
 }
 
 const VolumeAttachTimeStat VolumeStatType = 0
@@ -36,6 +61,24 @@ var VolumeStatCollector = volumeStatCollector{
 		Name:      "attachment_time_total",
 		Help:      "Volume attachment time in total.",
 	}, volumeStatLabels),
+	// observed on both nodes and the controller, and always registered by the metric handler
+	PhaseDurationMetric: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: csiNamespace,
+		Subsystem: volumeSubsystem,
+		Name:      "phase_duration_seconds",
+		Help:      "Time spent in each phase of attaching and mounting volumes.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, volumePhaseLabels),
+}
+
+// ObserveVolumePhase records the time since start in phase.
+// variant is the disk category for disk, the mount protocol for NAS, and the fuse type for OSS.
+func ObserveVolumePhase(driver, variant, phase string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	VolumeStatCollector.PhaseDurationMetric.WithLabelValues(driver, variant, phase, result).Observe(time.Since(start).Seconds())
 }
 
 func init() {
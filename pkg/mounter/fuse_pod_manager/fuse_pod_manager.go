@@ -22,6 +22,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 	watchtools "k8s.io/client-go/tools/watch"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
 )
 
 const (
@@ -61,6 +62,56 @@ type RrsaConfig struct {
 
 type PodTemplateConfig struct {
 	DnsPolicy corev1.DNSPolicy
+	// Cache is the local data cache attached to the fuse pod, nil if disabled
+	Cache *CacheConfig
+	// Resources overrides the fuse container resources from config
+	Resources *corev1.ResourceRequirements
+}
+
+type CacheMedium string
+
+const (
+	// CacheMediumDisk uses an emptyDir on the node's ephemeral storage (e.g. local NVMe)
+	CacheMediumDisk CacheMedium = "disk"
+	// CacheMediumHostPath uses a directory on the node
+	CacheMediumHostPath CacheMedium = "hostpath"
+	// CacheMediumMemory uses a memory-backed emptyDir
+	CacheMediumMemory CacheMedium = "memory"
+)
+
+// CacheConfig describes the volume used by the fuse client for local data cache
+type CacheConfig struct {
+	Medium CacheMedium
+	// HostPath is the directory on the node, only for CacheMediumHostPath
+	HostPath string
+	// SizeLimit is the capacity of the cache, also used as the emptyDir size limit
+	SizeLimit *resource.Quantity
+}
+
+// VolumeSource returns the volume source of the cache in fuse pod
+func (c *CacheConfig) VolumeSource() corev1.VolumeSource {
+	switch c.Medium {
+	case CacheMediumHostPath:
+		return corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: c.HostPath,
+				Type: ptr.To(corev1.HostPathDirectoryOrCreate),
+			},
+		}
+	case CacheMediumMemory:
+		return corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{
+				Medium:    corev1.StorageMediumMemory,
+				SizeLimit: c.SizeLimit,
+			},
+		}
+	default:
+		return corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{
+				SizeLimit: c.SizeLimit,
+			},
+		}
+	}
 }
 
 const (
@@ -284,6 +335,7 @@ func (fpm *FusePodManager) Create(c *FusePodContext, target string) (*corev1.Pod
 			rawPod.Annotations = make(map[string]string)
 		}
 		rawPod.Annotations[FuseMountPathAnnoKey] = target
+		rawPod.Annotations[FuseVolumeIdAnnoKey] = c.VolumeId
 		rawPod.Annotations[FuseSafeToEvictAnnoKey] = "true"
 
 		logger.V(2).Info("creating fuse pod", "target", target)
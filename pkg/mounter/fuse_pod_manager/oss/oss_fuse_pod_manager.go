@@ -68,7 +68,10 @@ type Options struct {
 	// - Node server should check the skipAttach field to determine if it's rund (skipAttach=true) or coco (skipAttach=false)
 	// Otherwise, the runtime is runc
 	DirectAssigned bool
-	CNFSName       string
+	// GuestMount mounts ossfs2 inside the coco guest with the same options as runc,
+	// instead of passing the raw options to the guest.
+	GuestMount bool
+	CNFSName   string
 
 	// oss options
 	Bucket string `json:"bucket"`
@@ -106,4 +109,24 @@ type Options struct {
 
 	// pod template
 	DnsPolicy corev1.DNSPolicy `json:"dnsPolicy"`
+
+	// fuse container resources, override the ones in config
+	FuseCPURequest    string `json:"fuseCpuRequest"`
+	FuseCPULimit      string `json:"fuseCpuLimit"`
+	FuseMemoryRequest string `json:"fuseMemoryRequest"`
+	FuseMemoryLimit   string `json:"fuseMemoryLimit"`
+
+	// local data cache, only for ossfs2
+	CacheMedium         fpm.CacheMedium `json:"cacheMedium"`
+	CachePath           string          `json:"cachePath"`
+	CacheSize           string          `json:"cacheSize"`
+	CacheEvictionPolicy string          `json:"cacheEvictionPolicy"`
+	PrefetchPrefixes    []string        `json:"prefetchPrefixes"`
+	// WarmupPrefixes are scheduled from the PVC annotation, loaded after mounting with progress reported
+	WarmupPrefixes []string `json:"-"`
 }
+
+const (
+	CacheEvictionLRU  = "lru"
+	CacheEvictionFIFO = "fifo"
+)
@@ -214,7 +214,7 @@ func (f *fuseOssfs) buildPodSpec(c *fpm.FusePodContext, target string) (spec cor
 	container := corev1.Container{
 		Name:      f.Name(),
 		Image:     f.config.Image,
-		Resources: f.config.Resources,
+		Resources: fpm.MergeResources(f.config.Resources, c.PodTemplateConfig.Resources),
 		VolumeMounts: []corev1.VolumeMount{
 			{
 				Name:             targetDirVolume.Name,
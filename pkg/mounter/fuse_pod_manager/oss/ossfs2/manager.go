@@ -25,7 +25,7 @@ import (
 func init() {
 	ossfpm.RegisterFuseMounter(mounterutils.OssFs2Type, NewFuseOssfs)
 	ossfpm.RegisterFuseMounterPath(mounterutils.OssFs2Type, "/usr/local/bin/ossfs2")
-	ossfpm.RegisterFuseInterceptors(mounterutils.OssFs2Type, []mounter.MountInterceptor{interceptors.Ossfs2SecretInterceptor})
+	ossfpm.RegisterFuseInterceptors(mounterutils.OssFs2Type, []mounter.MountInterceptor{interceptors.Ossfs2SecretInterceptor, interceptors.Ossfs2WarmupInterceptor})
 }
 
 var defaultOssfs2Dbglevel = fpm.DebugLevelInfo
@@ -164,10 +164,38 @@ func (f *fuseOssfs) MakeMountOptions(o *ossfpm.Options, m metadata.MetadataProvi
 	region, _ := m.Get(metadata.RegionID)
 	authOptions := f.getAuthOptions(o, region)
 	mountOptions = append(mountOptions, authOptions...)
+	mountOptions = append(mountOptions, getCacheOptions(o)...)
 
 	return
 }
 
+// CacheDir is where the cache volume is mounted in the fuse pod
+const CacheDir = "/var/cache/ossfs2"
+
+func getCacheOptions(o *ossfpm.Options) (mountOptions []string) {
+	if o.CacheMedium == "" {
+		return nil
+	}
+	mountOptions = append(mountOptions, "data_cache_dir="+CacheDir)
+	if o.CacheSize != "" {
+		size, err := resource.ParseQuantity(o.CacheSize)
+		if err == nil {
+			mountOptions = append(mountOptions, fmt.Sprintf("data_cache_max_size=%d", size.Value()))
+		}
+	}
+	if o.CacheEvictionPolicy != "" {
+		mountOptions = append(mountOptions, "data_cache_eviction_policy="+o.CacheEvictionPolicy)
+	}
+	for _, prefix := range o.PrefetchPrefixes {
+		mountOptions = append(mountOptions, "prefetch_prefix="+prefix)
+	}
+	// read by Ossfs2WarmupInterceptor to report the progress
+	for _, prefix := range o.WarmupPrefixes {
+		mountOptions = append(mountOptions, mounterutils.WarmupPrefixOption+"="+prefix)
+	}
+	return
+}
+
 func (f *fuseOssfs) PodTemplateSpec(c *fpm.FusePodContext, target string) (*corev1.PodTemplateSpec, error) {
 	spec, err := f.buildPodSpec(c, target)
 	if err != nil {
@@ -254,6 +282,9 @@ func (f *fuseOssfs) buildPodSpec(c *fpm.FusePodContext, target string) (spec cor
 	container := corev1.Container{
 		Name:  f.Name(),
 		Image: f.config.Image,
+		// The resources in csi-plugin ConfigMap were never applied to ossfs2,
+		// only the ones set or recommended for the volume are, to keep existing fuse pods unchanged.
+		Resources: fpm.MergeResources(corev1.ResourceRequirements{}, c.PodTemplateConfig.Resources),
 		VolumeMounts: []corev1.VolumeMount{
 			{
 				Name:             targetDirVolume.Name,
@@ -281,6 +312,7 @@ func (f *fuseOssfs) buildPodSpec(c *fpm.FusePodContext, target string) (spec cor
 	}
 
 	f.buildAuthSpec(c, target, &spec, &container)
+	buildCacheSpec(c, &spec, &container)
 
 	container.Args = []string{"--socket=" + socketPath, "-v=4"}
 
@@ -345,6 +377,21 @@ func (f *fuseOssfs) AddDefaultMountOptions(options []string) []string {
 	return options
 }
 
+func buildCacheSpec(c *fpm.FusePodContext, spec *corev1.PodSpec, container *corev1.Container) {
+	if c.PodTemplateConfig == nil || c.PodTemplateConfig.Cache == nil {
+		return
+	}
+	cacheVolume := corev1.Volume{
+		Name:         "cache-dir",
+		VolumeSource: c.PodTemplateConfig.Cache.VolumeSource(),
+	}
+	spec.Volumes = append(spec.Volumes, cacheVolume)
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      cacheVolume.Name,
+		MountPath: CacheDir,
+	})
+}
+
 func (f *fuseOssfs) buildAuthSpec(c *fpm.FusePodContext, target string, spec *corev1.PodSpec, container *corev1.Container) {
 	if spec == nil || container == nil {
 		return
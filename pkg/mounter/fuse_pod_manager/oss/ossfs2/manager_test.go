@@ -15,6 +15,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/utils/ptr"
 )
 
@@ -367,6 +368,32 @@ func TestMakeMountOptions_ossfs2(t *testing.T) {
 				"metrics_top=5",
 			},
 		},
+		{
+			name: "cache",
+			opts: &ossfpm.Options{
+				AccessKey: ossfpm.AccessKey{
+					AkID:     "test-ak",
+					AkSecret: "test-ak-secret",
+				},
+				Bucket:              "test-bucket",
+				Path:                "/",
+				URL:                 "oss://test-bucket/",
+				CacheMedium:         fpm.CacheMediumDisk,
+				CacheSize:           "1Gi",
+				CacheEvictionPolicy: ossfpm.CacheEvictionLRU,
+				PrefetchPrefixes:    []string{"train/", "eval/"},
+			},
+			expected: []string{
+				"oss_endpoint=oss://test-bucket/",
+				"oss_bucket=test-bucket",
+				"oss_bucket_prefix=/",
+				"data_cache_dir=/var/cache/ossfs2",
+				"data_cache_max_size=1073741824",
+				"data_cache_eviction_policy=lru",
+				"prefetch_prefix=train/",
+				"prefetch_prefix=eval/",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -621,3 +648,60 @@ func TestBuildAuthSpec_ossfs2(t *testing.T) {
 	assert.Contains(t, "/var/run/secrets/ack.alibabacloud.com/rrsa-tokens", volumeMount.MountPath)
 	assert.Contains(t, "rrsa-oidc-token", volumeMount.Name)
 }
+
+func TestBuildCacheSpec_ossfs2(t *testing.T) {
+	size := resource.MustParse("1Gi")
+	c := &fpm.FusePodContext{
+		PodTemplateConfig: &fpm.PodTemplateConfig{
+			Cache: &fpm.CacheConfig{
+				Medium:    fpm.CacheMediumMemory,
+				SizeLimit: &size,
+			},
+		},
+	}
+	spec := &corev1.PodSpec{}
+	container := &corev1.Container{}
+	buildCacheSpec(c, spec, container)
+	assert.Equal(t, []corev1.Volume{{
+		Name: "cache-dir",
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{
+				Medium:    corev1.StorageMediumMemory,
+				SizeLimit: &size,
+			},
+		},
+	}}, spec.Volumes)
+	assert.Equal(t, []corev1.VolumeMount{{Name: "cache-dir", MountPath: CacheDir}}, container.VolumeMounts)
+
+	spec = &corev1.PodSpec{}
+	container = &corev1.Container{}
+	buildCacheSpec(&fpm.FusePodContext{PodTemplateConfig: &fpm.PodTemplateConfig{}}, spec, container)
+	assert.Empty(t, spec.Volumes)
+	assert.Empty(t, container.VolumeMounts)
+}
+
+func TestBuildPodSpecResources_ossfs2(t *testing.T) {
+	f := &fuseOssfs{config: fpm.FuseContainerConfig{
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		},
+	}}
+	c := &fpm.FusePodContext{
+		Context:           context.Background(),
+		VolumeId:          "pv-oss",
+		AuthConfig:        &fpm.AuthConfig{},
+		PodTemplateConfig: &fpm.PodTemplateConfig{},
+	}
+	spec, err := f.buildPodSpec(c, "/var/lib/kubelet/target")
+	require.NoError(t, err)
+	assert.Empty(t, spec.Containers[0].Resources, "resources in ConfigMap should not be applied")
+
+	c.PodTemplateConfig.Resources = &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+	}
+	spec, err = f.buildPodSpec(c, "/var/lib/kubelet/target")
+	require.NoError(t, err)
+	assert.Equal(t, corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+	}, spec.Containers[0].Resources)
+}
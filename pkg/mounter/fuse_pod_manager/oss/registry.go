@@ -68,10 +68,15 @@ func GetAllRegisteredFuseTypes() []string {
 // GetAllOSSFusePodManagers creates a map of all registered OSS fuse pod managers
 // configmap can be nil if not available (e.g., in CSI agent mode)
 // client can be nil if not needed (e.g., in CSI agent mode)
+// If client is set, the managers follow changes of fuse config in csi-plugin ConfigMap.
 func GetAllOSSFusePodManagers(csiCfg utils.Config, m metadata.MetadataProvider, client kubernetes.Interface) map[string]*OSSFusePodManager {
 	fusePodManagers := make(map[string]*OSSFusePodManager, len(fstypeToFactory))
 	for fstype, factory := range fstypeToFactory {
-		fusePodManagers[fstype] = NewOSSFusePodManager(factory(csiCfg, m), client)
+		if client == nil {
+			fusePodManagers[fstype] = NewOSSFusePodManager(factory(csiCfg, m), client)
+		} else {
+			fusePodManagers[fstype] = NewOSSFusePodManager(newReloadingMounter(fstype, csiCfg, m, factory), client)
+		}
 	}
 	return fusePodManagers
 }
@@ -94,6 +94,28 @@ func TestGetAllOSSFusePodManagers(t *testing.T) {
 	assert.IsType(t, &OSSFusePodManager{}, managers[testType])
 }
 
+func TestReloadingMounter(t *testing.T) {
+	t.Cleanup(func() { utils.CSIPluginConfig.Update(nil) })
+
+	fakeMeta := metadata.NewMetadata()
+	factory := func(cfg utils.Config, _ metadata.MetadataProvider) OSSFuseMounterType {
+		return &testFuseMounter{name: cfg.Get("fuse-test-reload", "", "")}
+	}
+	r := newReloadingMounter("test-reload", utils.Config{ConfigMap: map[string]string{"fuse-test-reload": "image-tag=v1"}}, fakeMeta, factory)
+	assert.Equal(t, "image-tag=v1", r.Name())
+
+	utils.CSIPluginConfig.Update(map[string]string{"fuse-test-reload": "image-tag=v2"})
+	assert.Equal(t, "image-tag=v2", r.Name())
+
+	// unrelated change keeps the mounter
+	before := r.get()
+	utils.CSIPluginConfig.Update(map[string]string{"fuse-test-reload": "image-tag=v2", "other": "value"})
+	assert.Same(t, before, r.get())
+
+	// later calls share the same mounter, without subscribing again
+	assert.Same(t, r, newReloadingMounter("test-reload", utils.Config{}, fakeMeta, factory))
+}
+
 // testFuseMounter is a minimal implementation for testing
 type testFuseMounter struct {
 	name string
@@ -0,0 +1,85 @@
+package oss
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
+	fpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// reloadingMounter rebuilds the mounter when its fuse config in csi-plugin ConfigMap changes,
+// so that fuse pods created afterwards use the new image, resources, etc.
+// Existing fuse pods are not touched.
+type reloadingMounter struct {
+	mu      sync.RWMutex
+	current OSSFuseMounterType
+}
+
+var (
+	reloadingMountersLock sync.Mutex
+	// reloadingMounters are shared by all callers, so that each fstype subscribes to the config only once.
+	reloadingMounters = map[string]*reloadingMounter{}
+)
+
+func newReloadingMounter(fstype string, csiCfg utils.Config, m metadata.MetadataProvider,
+	factory func(utils.Config, metadata.MetadataProvider) OSSFuseMounterType,
+) *reloadingMounter {
+	reloadingMountersLock.Lock()
+	defer reloadingMountersLock.Unlock()
+	if r, ok := reloadingMounters[fstype]; ok {
+		return r
+	}
+	r := &reloadingMounter{current: factory(csiCfg, m)}
+	reloadingMounters[fstype] = r
+	key := utils.ConfigKey{Name: "fuse-" + fstype, Env: "OSS_FUSE_" + strings.ToUpper(fstype)}
+	utils.CSIPluginConfig.RegisterKeys(key)
+
+	last := csiCfg.Get(key.Name, key.Env, "")
+	utils.CSIPluginConfig.Subscribe(func(cfg utils.Config) {
+		content := cfg.Get(key.Name, key.Env, "")
+		if content == last {
+			return
+		}
+		last = content
+		klog.InfoS("fuse config changed, apply to new fuse pods", "fstype", fstype)
+		mounter := factory(cfg, m)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.current = mounter
+	})
+	return r
+}
+
+func (r *reloadingMounter) get() OSSFuseMounterType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+func (r *reloadingMounter) Name() string {
+	return r.get().Name()
+}
+
+func (r *reloadingMounter) PodTemplateSpec(c *fpm.FusePodContext, target string) (*corev1.PodTemplateSpec, error) {
+	return r.get().PodTemplateSpec(c, target)
+}
+
+func (r *reloadingMounter) AddDefaultMountOptions(options []string) []string {
+	return r.get().AddDefaultMountOptions(options)
+}
+
+func (r *reloadingMounter) PrecheckAuthConfig(o *Options, onNode bool) error {
+	return r.get().PrecheckAuthConfig(o, onNode)
+}
+
+func (r *reloadingMounter) MakeAuthConfig(o *Options, m metadata.MetadataProvider) (*fpm.AuthConfig, error) {
+	return r.get().MakeAuthConfig(o, m)
+}
+
+func (r *reloadingMounter) MakeMountOptions(o *Options, m metadata.MetadataProvider) ([]string, error) {
+	return r.get().MakeMountOptions(o, m)
+}
@@ -30,6 +30,10 @@ func SetDefaultImage(fuseType string, m metadata.MetadataProvider, config *fpm.F
 	if err != nil {
 		klog.Warningf("Failed to get region from metadata: %v", err)
 	}
+	if metadata.IsExternalNode(m) {
+		// VPC registry is not reachable from external nodes
+		region = ""
+	}
 	prefix := utils.GetRepositoryPrefix(region)
 
 	if config.ImageTag == "" {
@@ -0,0 +1,96 @@
+package fuse_pod_manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// FusePodNamespace is where fuse pods are created
+	FusePodNamespace = "ack-csi-fuse"
+	// FuseVolumeIdAnnoKey holds the original volume ID on fuse pod,
+	// as FuseVolumeIdLabelKey may be hashed.
+	FuseVolumeIdAnnoKey = "csi.alibabacloud.com/volume-id"
+	// FuseResourcesRecommendationAnnoKey on PV holds the resources recommended for the fuse container
+	// in JSON format of corev1.ResourceRequirements. It is applied the next time a fuse pod is created.
+	FuseResourcesRecommendationAnnoKey = "csi.alibabacloud.com/fuse-resources-recommendation"
+)
+
+// MergeResources returns base overridden by the resources set in override.
+func MergeResources(base corev1.ResourceRequirements, override *corev1.ResourceRequirements) corev1.ResourceRequirements {
+	res := *base.DeepCopy()
+	if override == nil {
+		return res
+	}
+	if len(override.Requests) > 0 {
+		if res.Requests == nil {
+			res.Requests = make(corev1.ResourceList)
+		}
+		maps.Copy(res.Requests, override.Requests)
+	}
+	if len(override.Limits) > 0 {
+		if res.Limits == nil {
+			res.Limits = make(corev1.ResourceList)
+		}
+		maps.Copy(res.Limits, override.Limits)
+	}
+	// keep it schedulable if only the request is raised
+	for name, request := range res.Requests {
+		if limit, ok := res.Limits[name]; ok && limit.Cmp(request) < 0 {
+			res.Limits[name] = request
+		}
+	}
+	return res
+}
+
+// ValidateResources checks the requests and limits are consistent and fit into the node allocatable.
+func ValidateResources(res *corev1.ResourceRequirements, allocatable corev1.ResourceList) error {
+	if res == nil {
+		return nil
+	}
+	for name, request := range res.Requests {
+		if limit, ok := res.Limits[name]; ok && limit.Cmp(request) < 0 {
+			return fmt.Errorf("%s request %s exceeds limit %s", name, request.String(), limit.String())
+		}
+		if total, ok := allocatable[name]; ok && total.Cmp(request) < 0 {
+			return fmt.Errorf("%s request %s exceeds node allocatable %s", name, request.String(), total.String())
+		}
+	}
+	return nil
+}
+
+// ParseResourcesRecommendation parses the value of FuseResourcesRecommendationAnnoKey.
+func ParseResourcesRecommendation(value string) (*corev1.ResourceRequirements, error) {
+	if value == "" {
+		return nil, nil
+	}
+	res := new(corev1.ResourceRequirements)
+	if err := json.Unmarshal([]byte(value), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RecommendResources recommends requests from the observed peak usage with some headroom.
+func RecommendResources(peakCPU, peakMemory *resource.Quantity) *corev1.ResourceRequirements {
+	const (
+		headroomPercent = 120
+		minCPUMilli     = 10
+		minMemoryMi     = 50
+	)
+	res := &corev1.ResourceRequirements{Requests: corev1.ResourceList{}}
+	if peakCPU != nil {
+		milli := max(peakCPU.MilliValue()*headroomPercent/100, minCPUMilli)
+		res.Requests[corev1.ResourceCPU] = *resource.NewMilliQuantity(milli, resource.DecimalSI)
+	}
+	if peakMemory != nil {
+		// round up to Mi
+		mi := max((peakMemory.Value()*headroomPercent/100+(1<<20)-1)>>20, minMemoryMi)
+		res.Requests[corev1.ResourceMemory] = *resource.NewQuantity(mi<<20, resource.BinarySI)
+	}
+	return res
+}
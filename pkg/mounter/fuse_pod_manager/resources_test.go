@@ -0,0 +1,58 @@
+package fuse_pod_manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestMergeResources(t *testing.T) {
+	base := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("50Mi")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+	assert.Equal(t, base, MergeResources(base, nil))
+
+	got := MergeResources(base, &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("2Gi"),
+		},
+	})
+	assert.Equal(t, corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("2Gi"),
+		},
+		Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+	}, got)
+	// base is not modified
+	assert.Equal(t, resource.MustParse("50Mi"), base.Requests[corev1.ResourceMemory])
+}
+
+func TestValidateResources(t *testing.T) {
+	allocatable := corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")}
+	assert.NoError(t, ValidateResources(nil, allocatable))
+	assert.NoError(t, ValidateResources(&corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}, allocatable))
+	assert.Error(t, ValidateResources(&corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")},
+	}, allocatable))
+	assert.Error(t, ValidateResources(&corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+	}, nil))
+}
+
+func TestRecommendResources(t *testing.T) {
+	res := RecommendResources(resource.NewMilliQuantity(1000, resource.DecimalSI), resource.NewQuantity(100<<20, resource.BinarySI))
+	assert.Equal(t, int64(1200), res.Requests.Cpu().MilliValue())
+	assert.Equal(t, int64(120<<20), res.Requests.Memory().Value())
+
+	res = RecommendResources(resource.NewMilliQuantity(1, resource.DecimalSI), resource.NewQuantity(1<<20, resource.BinarySI))
+	assert.Equal(t, int64(10), res.Requests.Cpu().MilliValue())
+	assert.Equal(t, int64(50<<20), res.Requests.Memory().Value())
+}
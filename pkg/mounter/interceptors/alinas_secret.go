@@ -5,49 +5,78 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter"
+	mounterutils "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/utils"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
 	"k8s.io/klog/v2"
+	mountutils "k8s.io/mount-utils"
 )
 
 var credDir = os.TempDir()
 var _ mounter.MountInterceptor = AlinasSecretInterceptor
 
 func AlinasSecretInterceptor(ctx context.Context, op *mounter.MountOperation, handler mounter.MountHandler) error {
+	return alinasSecretInterceptorWithMounter(ctx, op, handler, rawMounter)
+}
+
+// alinasSecretInterceptorWithMounter is the internal implementation that accepts a mounter parameter.
+// This allows tests to inject a fake mounter to simulate different mount point states.
+//
+// Both fixed AK (akId/akSecret) and STS token (AccessKeyId/AccessKeySecret/SecurityToken) are supported.
+// The credential file is kept at a stable path and replaced atomically, so that
+// the alinas client picks up the new token when NodePublishVolume is called again
+// (RequiresRepublish) before the old one expires.
+func alinasSecretInterceptorWithMounter(ctx context.Context, op *mounter.MountOperation, handler mounter.MountHandler, mountInterface mountutils.Interface) error {
 	if op == nil || op.Secrets == nil {
 		return handler(ctx, op)
 	}
 
-	tmpCredFile, err := os.CreateTemp(credDir, op.VolumeID+"-*.credentials")
+	credFilePath := path.Join(credDir, op.VolumeID+".credentials")
+	rotated, err := rotatePasswdFile(credFilePath, makeCredFileContent(op.Secrets), 0o600)
 	if err != nil {
-		return err
-	}
-	defer func() {
-		if err = os.Remove(tmpCredFile.Name()); err != nil && !os.IsNotExist(err) {
-			klog.ErrorS(err, "Failed to remove temporary alinas credential file", "path", tmpCredFile.Name())
-		}
-	}()
-
-	credFileContent := makeCredFileContent(op.Secrets)
-	if _, err = tmpCredFile.Write(credFileContent); err != nil {
-		return err
+		return fmt.Errorf("failed to write alinas credential file: %w", err)
 	}
-	if err = tmpCredFile.Close(); err != nil {
-		return err
+	if rotated {
+		klog.V(4).InfoS("Updated alinas credential file", "path", credFilePath)
+		updateCredentialMetrics(op.MetricsPath, op.Secrets)
 	}
 
-	credFilePath := path.Join(credDir, op.VolumeID+".credentials")
-	if err = os.Rename(tmpCredFile.Name(), credFilePath); err != nil {
-		return err
+	// Only STS tokens can be rotated without remounting.
+	// For a mounted target, the credential file has already been refreshed above,
+	// so the running alinas client will reload it on its own.
+	if isAlinasTokenSecrets(op.Secrets) && mountInterface != nil {
+		notMnt, err := mounterutils.IsNotMountPoint(mountInterface, op.Target)
+		if err != nil {
+			return fmt.Errorf("failed to check if target %s is a mountpoint: %w", op.Target, err)
+		}
+		if !notMnt {
+			klog.V(4).InfoS("mount point already exists, skipping mount for token rotation", "target", op.Target)
+			return mounter.ErrSkipMount
+		}
 	}
 
-	klog.V(4).InfoS("Created alinas credential file", "path", credFilePath)
 	op.Options = append(op.Options, "ram_config_file="+credFilePath)
-
 	return handler(ctx, op)
 }
 
+func isAlinasTokenSecrets(secrets map[string]string) bool {
+	return secrets[mounterutils.KeySecurityToken] != ""
+}
+
 func makeCredFileContent(secrets map[string]string) []byte {
+	if isAlinasTokenSecrets(secrets) {
+		return fmt.Appendf(
+			nil,
+			"[NASCredentials]\naccessKeyID=%s\naccessKeySecret=%s\nsecurityToken=%s",
+			secrets[mounterutils.KeyAccessKeyId],
+			secrets[mounterutils.KeyAccessKeySecret],
+			secrets[mounterutils.KeySecurityToken],
+		)
+	}
 	return fmt.Appendf(
 		nil,
 		"[NASCredentials]\naccessKeyID=%s\naccessKeySecret=%s",
@@ -55,3 +84,30 @@ func makeCredFileContent(secrets map[string]string) []byte {
 		secrets["akSecret"],
 	)
 }
+
+// updateCredentialMetrics records when the credential was last written and when it expires,
+// so that the fuse stat collector can report the token age.
+func updateCredentialMetrics(metricsPath string, secrets map[string]string) {
+	if metricsPath == "" {
+		return
+	}
+	if err := os.MkdirAll(metricsPath, 0o755); err != nil {
+		klog.ErrorS(err, "Failed to create metrics path directory", "path", metricsPath)
+		return
+	}
+	rotationFile := filepath.Join(metricsPath, utils.MetricsCredentialRotationTimestamp)
+	if err := os.WriteFile(rotationFile, []byte(strconv.FormatInt(time.Now().Unix(), 10)), 0o644); err != nil {
+		klog.ErrorS(err, "Failed to update metrics", "key", utils.MetricsCredentialRotationTimestamp)
+	}
+
+	expirationFile := filepath.Join(metricsPath, utils.MetricsCredentialExpirationTimestamp)
+	expiration, err := time.Parse(time.RFC3339, secrets[mounterutils.KeyExpiration])
+	if err != nil {
+		// fixed AK or unknown expiration
+		removeIgnoreNotExist(expirationFile)
+		return
+	}
+	if err := os.WriteFile(expirationFile, []byte(strconv.FormatInt(expiration.Unix(), 10)), 0o644); err != nil {
+		klog.ErrorS(err, "Failed to update metrics", "key", utils.MetricsCredentialExpirationTimestamp)
+	}
+}
@@ -6,12 +6,17 @@ import (
 	"maps"
 	"os"
 	"path"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter"
+	mounterutils "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/utils"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	mountutils "k8s.io/mount-utils"
 )
 
 var (
@@ -136,3 +141,58 @@ func TestAlinasSecretInterceptor(t *testing.T) {
 		})
 	}
 }
+
+func TestAlinasSecretInterceptorTokenRotation(t *testing.T) {
+	credDir = t.TempDir()
+	target := filepath.Join(t.TempDir(), "target")
+	require.NoError(t, os.MkdirAll(target, 0o755))
+	evalTarget, err := filepath.EvalSymlinks(target)
+	require.NoError(t, err)
+	metricsPath := t.TempDir()
+
+	newOp := func(token string) *mounter.MountOperation {
+		return &mounter.MountOperation{
+			Target:      target,
+			VolumeID:    "volume-id",
+			MetricsPath: metricsPath,
+			Secrets: map[string]string{
+				mounterutils.KeyAccessKeyId:     "akid",
+				mounterutils.KeyAccessKeySecret: "aksecret",
+				mounterutils.KeySecurityToken:   token,
+				mounterutils.KeyExpiration:      "2024-12-31T23:59:59Z",
+			},
+		}
+	}
+	credFile := path.Join(credDir, "volume-id.credentials")
+
+	// first mount
+	handlerCalled := false
+	handler := func(ctx context.Context, op *mounter.MountOperation) error {
+		handlerCalled = true
+		return nil
+	}
+	op := newOp("token1")
+	err = alinasSecretInterceptorWithMounter(context.Background(), op, handler, mountutils.NewFakeMounter(nil))
+	assert.NoError(t, err)
+	assert.True(t, handlerCalled)
+	assert.Contains(t, op.Options, "ram_config_file="+credFile)
+	content, err := os.ReadFile(credFile)
+	require.NoError(t, err)
+	assert.Equal(t, "[NASCredentials]\naccessKeyID=akid\naccessKeySecret=aksecret\nsecurityToken=token1", string(content))
+	assert.FileExists(t, filepath.Join(metricsPath, utils.MetricsCredentialRotationTimestamp))
+	expiration, err := os.ReadFile(filepath.Join(metricsPath, utils.MetricsCredentialExpirationTimestamp))
+	require.NoError(t, err)
+	assert.Equal(t, "1735689599", string(expiration))
+
+	// republish with a new token on the mounted target
+	handlerCalled = false
+	mounted := mountutils.NewFakeMounter([]mountutils.MountPoint{
+		{Device: "nas", Path: evalTarget, Type: "alinas"},
+	})
+	err = alinasSecretInterceptorWithMounter(context.Background(), newOp("token2"), handler, mounted)
+	assert.ErrorIs(t, err, mounter.ErrSkipMount)
+	assert.False(t, handlerCalled)
+	content, err = os.ReadFile(credFile)
+	require.NoError(t, err)
+	assert.Equal(t, "[NASCredentials]\naccessKeyID=akid\naccessKeySecret=aksecret\nsecurityToken=token2", string(content))
+}
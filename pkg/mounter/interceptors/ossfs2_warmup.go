@@ -0,0 +1,170 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter"
+	mounterutils "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/utils"
+	"k8s.io/klog/v2"
+)
+
+var _ mounter.MountInterceptor = Ossfs2WarmupInterceptor
+
+// warmupReportInterval is how often the progress is written while reading.
+var warmupReportInterval = 10 * time.Second
+
+// Ossfs2WarmupInterceptor reads the warm-up prefixes through the mount point after mounting,
+// so that the data is loaded into the ossfs2 data cache, and reports the progress in the metrics path.
+// The warm-up runs in background and never fails the mount.
+func Ossfs2WarmupInterceptor(ctx context.Context, op *mounter.MountOperation, handler mounter.MountHandler) error {
+	if op == nil {
+		return handler(ctx, op)
+	}
+	var prefixes []string
+	options := op.Options[:0:0]
+	for _, o := range op.Options {
+		if prefix, ok := strings.CutPrefix(o, mounterutils.WarmupPrefixOption+"="); ok {
+			prefixes = append(prefixes, prefix)
+		} else {
+			options = append(options, o)
+		}
+	}
+	op.Options = options
+
+	err := handler(ctx, op)
+	if err != nil || len(prefixes) == 0 {
+		return err
+	}
+
+	target, metricsPath := op.Target, op.MetricsPath
+	report := func(p *mounterutils.WarmupProgress) {
+		if metricsPath == "" {
+			return
+		}
+		p.UpdateTime = time.Now()
+		if err := mounterutils.WriteWarmupProgress(metricsPath, p); err != nil {
+			klog.ErrorS(err, "failed to report warm-up progress", "target", target)
+		}
+	}
+	go func() {
+		klog.InfoS("Start warm-up", "target", target, "prefixes", prefixes)
+		if err := warmup(context.Background(), target, prefixes, report); err != nil {
+			klog.ErrorS(err, "Warm-up failed", "target", target)
+			return
+		}
+		klog.InfoS("Warm-up completed", "target", target)
+	}()
+	return nil
+}
+
+type warmupFile struct {
+	path string
+	size int64
+}
+
+// listWarmupFiles returns the files under root whose path relative to root starts with prefix.
+func listWarmupFiles(root, prefix string, seen map[string]bool) (files []warmupFile, err error) {
+	full := filepath.Join(root, prefix)
+	walkRoot := full
+	if !strings.HasSuffix(prefix, "/") {
+		// key prefix, e.g. "data/2024-" matches "data/2024-01/a" and "data/2024-02.csv"
+		walkRoot = filepath.Dir(full)
+	}
+	err = filepath.WalkDir(walkRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == walkRoot && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if path != walkRoot && !strings.HasPrefix(path, full) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || seen[path] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		seen[path] = true
+		files = append(files, warmupFile{path: path, size: info.Size()})
+		return nil
+	})
+	return files, err
+}
+
+func warmup(ctx context.Context, root string, prefixes []string, report func(*mounterutils.WarmupProgress)) error {
+	progress := &mounterutils.WarmupProgress{Phase: mounterutils.WarmupRunning}
+	fail := func(err error) error {
+		progress.Phase = mounterutils.WarmupFailed
+		progress.Message = err.Error()
+		report(progress)
+		return err
+	}
+	report(progress)
+
+	var files []warmupFile
+	seen := map[string]bool{}
+	for _, prefix := range prefixes {
+		f, err := listWarmupFiles(root, prefix, seen)
+		if err != nil {
+			return fail(err)
+		}
+		files = append(files, f...)
+	}
+	for _, f := range files {
+		progress.TotalFiles++
+		progress.TotalBytes += f.size
+	}
+	report(progress)
+
+	lastReport := time.Now()
+	var failed int
+	var firstErr error
+	for _, f := range files {
+		if ctx.Err() != nil {
+			return fail(ctx.Err())
+		}
+		n, err := readFile(f.path)
+		progress.Bytes += n
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			progress.Files++
+		}
+		if time.Since(lastReport) >= warmupReportInterval {
+			report(progress)
+			lastReport = time.Now()
+		}
+	}
+	if failed > 0 {
+		return fail(fmt.Errorf("failed to read %d files: %w", failed, firstErr))
+	}
+	progress.Phase = mounterutils.WarmupCompleted
+	report(progress)
+	return nil
+}
+
+func readFile(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(io.Discard, f)
+}
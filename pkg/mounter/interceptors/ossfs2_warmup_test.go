@@ -0,0 +1,71 @@
+package interceptors
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter"
+	mounterutils "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeWarmupTestFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	root := t.TempDir()
+	writeWarmupTestFiles(t, root, map[string]string{
+		"models/a":          "aaaa",
+		"models/sub/b":      "bb",
+		"data/2024-01/x":    "x",
+		"data/2024-02.csv":  "yy",
+		"data/2023-12/z":    "zzz",
+		"other/not-in-list": "o",
+	})
+
+	var last mounterutils.WarmupProgress
+	err := warmup(context.Background(), root, []string{"models/", "data/2024-", "models/sub/", "missing/"}, func(p *mounterutils.WarmupProgress) {
+		last = *p
+	})
+	require.NoError(t, err)
+	assert.Equal(t, mounterutils.WarmupProgress{
+		Phase:      mounterutils.WarmupCompleted,
+		Files:      4,
+		TotalFiles: 4,
+		Bytes:      9,
+		TotalBytes: 9,
+	}, last)
+}
+
+func TestOssfs2WarmupInterceptor(t *testing.T) {
+	root := t.TempDir()
+	metricsPath := t.TempDir()
+	writeWarmupTestFiles(t, root, map[string]string{"models/a": "aaaa"})
+
+	var mountOptions []string
+	op := &mounter.MountOperation{
+		Target:      root,
+		MetricsPath: metricsPath,
+		Options:     []string{"allow_other", "warmup_prefix=models/"},
+	}
+	err := Ossfs2WarmupInterceptor(context.Background(), op, func(ctx context.Context, op *mounter.MountOperation) error {
+		mountOptions = op.Options
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"allow_other"}, mountOptions, "warm-up options should not be passed to ossfs2")
+
+	assert.Eventually(t, func() bool {
+		p, err := mounterutils.ReadWarmupProgress(metricsPath)
+		return err == nil && p != nil && p.Phase == mounterutils.WarmupCompleted && p.Bytes == 4
+	}, 5*time.Second, 10*time.Millisecond)
+}
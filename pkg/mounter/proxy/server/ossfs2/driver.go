@@ -46,6 +46,7 @@ func NewDriver() *Driver {
 	driver.Mounter = mounter.NewForMounter(
 		m,
 		interceptors.Ossfs2SecretInterceptor,
+		interceptors.Ossfs2WarmupInterceptor,
 		interceptors.OssfsMonitorInterceptor,
 	)
 	return driver
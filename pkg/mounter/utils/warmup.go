@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+)
+
+// WarmupPrefixOption is the mount option passing an OSS prefix to warm up to the fuse pod.
+// It is consumed by Ossfs2WarmupInterceptor, and never passed to ossfs2.
+const WarmupPrefixOption = "warmup_prefix"
+
+type WarmupPhase string
+
+const (
+	WarmupRunning   WarmupPhase = "Running"
+	WarmupCompleted WarmupPhase = "Completed"
+	WarmupFailed    WarmupPhase = "Failed"
+)
+
+// WarmupProgress is reported by the fuse pod in the metrics directory of the volume.
+type WarmupProgress struct {
+	Phase      WarmupPhase `json:"phase"`
+	Files      int64       `json:"files"`
+	TotalFiles int64       `json:"totalFiles"`
+	Bytes      int64       `json:"bytes"`
+	TotalBytes int64       `json:"totalBytes"`
+	Message    string      `json:"message,omitempty"`
+	UpdateTime time.Time   `json:"updateTime"`
+}
+
+// WriteWarmupProgress replaces the progress file in metricsPath atomically.
+func WriteWarmupProgress(metricsPath string, p *WarmupProgress) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(metricsPath, utils.WarmupStatusFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadWarmupProgress returns nil if no progress is reported yet.
+func ReadWarmupProgress(metricsPath string) (*WarmupProgress, error) {
+	data, err := os.ReadFile(filepath.Join(metricsPath, utils.WarmupStatusFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	p := &WarmupProgress{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("invalid warm-up progress: %w", err)
+	}
+	return p, nil
+}
@@ -109,7 +109,7 @@ func (c *accesspointController) CreateVolume(ctx context.Context, req *csi.Creat
 			QuotaType:    new("Enforcement"),
 			UserType:     new("AllUsers"),
 		}); err != nil {
-			return nil, status.Errorf(codes.Internal, "nas:SetDirQuota failed: %v", err)
+			return nil, wrap.ToStatus(codes.Internal, fmt.Sprintf("nas:SetDirQuota failed: %v", err), err)
 		}
 	}
 	return resp, nil
@@ -187,7 +187,7 @@ func (c *accesspointController) createAccesspoint(ctx context.Context, name, bas
 
 	resp, err := c.nasClient.CreateAccesspoint(ctx, req)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "nas:CreateAccesspoint: %v", err)
+		return nil, wrap.ToStatus(codes.Internal, fmt.Sprintf("nas:CreateAccesspoint: %v", err), err)
 	}
 	return resp, nil
 }
@@ -4,8 +4,8 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/nas/interfaces"
-	"golang.org/x/time/rate"
 	"k8s.io/klog/v2"
 )
 
@@ -13,7 +13,7 @@ const defaultQps = 2
 
 type NasClientFactory struct {
 	// ratelimiter only takes effect on v2 client
-	limiter *rate.Limiter
+	limiter *throttle.Limiter
 }
 
 func NewNasClientFactory() *NasClientFactory {
@@ -29,7 +29,11 @@ func NewNasClientFactory() *NasClientFactory {
 		}
 	}
 	return &NasClientFactory{
-		limiter: rate.NewLimiter(rate.Limit(qps), 10),
+		limiter: throttle.DefaultRegistry.Get("nas", throttle.LimiterConfig{
+			MaxRate: float64(qps),
+			MinRate: float64(qps) / 10,
+			Burst:   10,
+		}),
 	}
 }
 
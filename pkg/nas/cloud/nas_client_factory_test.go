@@ -3,29 +3,26 @@ package cloud
 import (
 	"testing"
 
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
 	"github.com/stretchr/testify/assert"
-	"golang.org/x/time/rate"
 )
 
 func TestNasClientFactory(t *testing.T) {
 	t.Parallel()
 	actual := NewNasClientFactory()
-	expected := &NasClientFactory{rate.NewLimiter(defaultQps, 10)}
-	assert.Equal(t, expected, actual)
+	assert.Equal(t, throttle.LimiterConfig{MaxRate: defaultQps, MinRate: defaultQps / 10.0, Burst: 10}, actual.limiter.Config())
 }
 
 func TestNasClientFactoryValidEnv(t *testing.T) {
 	t.Setenv("NAS_LIMIT_PERSECOND", "3")
-	expected := &NasClientFactory{rate.NewLimiter(3, 10)}
 	actual := NewNasClientFactory()
-	assert.Equal(t, expected, actual)
+	assert.Equal(t, 3.0, actual.limiter.Config().MaxRate)
 }
 
 func TestNasClientFactoryInvalidEnv(t *testing.T) {
 	t.Setenv("NAS_LIMIT_PERSECOND", "3i")
-	expected := &NasClientFactory{rate.NewLimiter(defaultQps, 10)}
 	actual := NewNasClientFactory()
-	assert.Equal(t, expected, actual)
+	assert.Equal(t, float64(defaultQps), actual.limiter.Config().MaxRate)
 }
 
 func TestNasClientFactoryV1(t *testing.T) {
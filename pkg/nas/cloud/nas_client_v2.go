@@ -12,10 +12,12 @@ import (
 	"github.com/alibabacloud-go/tea/tea"
 	alicred_old "github.com/aliyun/credentials-go/credentials"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/credentials"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/tracing"
 	utilshttp "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils/http"
-	"golang.org/x/time/rate"
+	"go.opentelemetry.io/otel/attribute"
 	"k8s.io/klog/v2"
 )
 
@@ -63,7 +65,7 @@ func NewNasClientV2(region string) (*sdk.Client, error) {
 
 type NasClientV2 struct {
 	region  string
-	limiter *rate.Limiter
+	limiter *throttle.Limiter
 	client  cloud.NasInterface
 }
 
@@ -72,14 +74,20 @@ var (
 	// throttled (via the provided rateLimiter) for more than longThrottleLatency will
 	// be logged.
 	longThrottleLatency = 250 * time.Millisecond
+	// minTracedWait is the minimum wait time to be recorded as a span.
+	minTracedWait = 10 * time.Millisecond
 )
 
-func (c *NasClientV2) wait(ctx context.Context, logger klog.Logger) error {
+func (c *NasClientV2) wait(ctx context.Context, logger klog.Logger, p throttle.Priority) error {
 	t0 := time.Now()
-	if err := c.limiter.Wait(ctx); err != nil {
+	if err := c.limiter.Wait(ctx, p); err != nil {
 		return fmt.Errorf("error while waiting for rate limiter: %w", err)
 	}
 	t := time.Since(t0)
+	if t >= minTracedWait {
+		tracing.Record(ctx, "throttle.Wait", t0, attribute.String("api", c.limiter.Name()))
+	}
+	throttle.WaitDuration.WithLabelValues(c.limiter.Name()).Observe(t.Seconds())
 	if t > longThrottleLatency {
 		logger.V(3).Info("throttled NAS request", "elapsed", t)
 	}
@@ -88,21 +96,30 @@ func (c *NasClientV2) wait(ctx context.Context, logger klog.Logger) error {
 
 func (c *NasClientV2) CreateDir(ctx context.Context, req *sdk.CreateDirRequest) error {
 	logger := klog.FromContext(ctx)
-	if err := c.wait(ctx, logger); err != nil {
+	if err := c.wait(ctx, logger, throttle.PriorityLow); err != nil {
 		return err
 	}
-	_, err := wrap.V2(logger, c.client.CreateDir)(req)
+	_, err := wrap.V2(ctx, observed(c.limiter, c.client.CreateDir))(req)
 	return err
 }
 
+// observed feeds the result of f back to the limiter, to learn the rate from throttling.
+func observed[TReq, TResp any](l *throttle.Limiter, f func(TReq) (TResp, error)) func(TReq) (TResp, error) {
+	return func(req TReq) (TResp, error) {
+		resp, err := f(req)
+		l.Observe(err)
+		return resp, err
+	}
+}
+
 var ErrNotSuccess = errors.New("response indicates a failure")
 
 func (c *NasClientV2) SetDirQuota(ctx context.Context, req *sdk.SetDirQuotaRequest) error {
 	logger := klog.FromContext(ctx)
-	if err := c.wait(ctx, logger); err != nil {
+	if err := c.wait(ctx, logger, throttle.PriorityLow); err != nil {
 		return err
 	}
-	resp, err := wrap.V2(logger, c.client.SetDirQuota)(req)
+	resp, err := wrap.V2(ctx, observed(c.limiter, c.client.SetDirQuota))(req)
 	if err == nil && resp.Body != nil && !tea.BoolValue(resp.Body.Success) {
 		err = ErrNotSuccess
 	}
@@ -111,10 +128,10 @@ func (c *NasClientV2) SetDirQuota(ctx context.Context, req *sdk.SetDirQuotaReque
 
 func (c *NasClientV2) CancelDirQuota(ctx context.Context, req *sdk.CancelDirQuotaRequest) error {
 	logger := klog.FromContext(ctx)
-	if err := c.wait(ctx, logger); err != nil {
+	if err := c.wait(ctx, logger, throttle.PriorityHigh); err != nil {
 		return err
 	}
-	resp, err := wrap.V2(logger, c.client.CancelDirQuota)(req)
+	resp, err := wrap.V2(ctx, observed(c.limiter, c.client.CancelDirQuota))(req)
 	if err == nil {
 		if !tea.BoolValue(resp.Body.Success) {
 			err = ErrNotSuccess
@@ -130,40 +147,40 @@ func (c *NasClientV2) CancelDirQuota(ctx context.Context, req *sdk.CancelDirQuot
 
 func (c *NasClientV2) GetRecycleBinAttribute(ctx context.Context, filesystemId string) (*sdk.GetRecycleBinAttributeResponse, error) {
 	logger := klog.FromContext(ctx)
-	if err := c.wait(ctx, logger); err != nil {
+	if err := c.wait(ctx, logger, throttle.PriorityNormal); err != nil {
 		return nil, err
 	}
 	req := &sdk.GetRecycleBinAttributeRequest{FileSystemId: &filesystemId}
-	return wrap.V2(logger, c.client.GetRecycleBinAttribute)(req)
+	return wrap.V2(ctx, observed(c.limiter, c.client.GetRecycleBinAttribute))(req)
 }
 
 func (c *NasClientV2) CreateAccesspoint(ctx context.Context, req *sdk.CreateAccessPointRequest) (*sdk.CreateAccessPointResponse, error) {
 	logger := klog.FromContext(ctx)
-	if err := c.wait(ctx, logger); err != nil {
+	if err := c.wait(ctx, logger, throttle.PriorityLow); err != nil {
 		return nil, err
 	}
-	return wrap.V2(logger, c.client.CreateAccessPoint)(req)
+	return wrap.V2(ctx, observed(c.limiter, c.client.CreateAccessPoint))(req)
 }
 
 func (c *NasClientV2) DeleteAccesspoint(ctx context.Context, filesystemId, accessPointId string) error {
 	logger := klog.FromContext(ctx)
-	if err := c.wait(ctx, logger); err != nil {
-		return nil
+	if err := c.wait(ctx, logger, throttle.PriorityHigh); err != nil {
+		return err
 	}
 	req := &sdk.DeleteAccessPointRequest{
 		AccessPointId: &accessPointId,
 		FileSystemId:  &filesystemId,
 	}
-	_, err := wrap.V2(logger, c.client.DeleteAccessPoint)(req)
+	_, err := wrap.V2(ctx, observed(c.limiter, c.client.DeleteAccessPoint))(req)
 	return err
 }
 
 func (c *NasClientV2) DescribeAccesspoint(ctx context.Context, filesystemId, accessPointId string) (*sdk.DescribeAccessPointResponse, error) {
 	logger := klog.FromContext(ctx)
-	if err := c.wait(ctx, logger); err != nil {
+	if err := c.wait(ctx, logger, throttle.PriorityNormal); err != nil {
 		return nil, err
 	}
-	return wrap.V2(logger, c.client.DescribeAccessPoint)(&sdk.DescribeAccessPointRequest{
+	return wrap.V2(ctx, observed(c.limiter, c.client.DescribeAccessPoint))(&sdk.DescribeAccessPointRequest{
 		AccessPointId: &accessPointId,
 		FileSystemId:  &filesystemId,
 	})
@@ -171,10 +188,10 @@ func (c *NasClientV2) DescribeAccesspoint(ctx context.Context, filesystemId, acc
 
 func (c *NasClientV2) DescribeFileSystems(ctx context.Context, filesystemID string) (*sdk.DescribeFileSystemsResponse, error) {
 	logger := klog.FromContext(ctx)
-	if err := c.wait(ctx, logger); err != nil {
+	if err := c.wait(ctx, logger, throttle.PriorityNormal); err != nil {
 		return nil, err
 	}
-	return wrap.V2(logger, c.client.DescribeFileSystems)(&sdk.DescribeFileSystemsRequest{
+	return wrap.V2(ctx, observed(c.limiter, c.client.DescribeFileSystems))(&sdk.DescribeFileSystemsRequest{
 		FileSystemId: &filesystemID,
 	})
 }
@@ -1,14 +1,18 @@
 package cloud
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	nas "github.com/alibabacloud-go/nas-20170626/v4/client"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/throttle"
 	"github.com/stretchr/testify/assert"
-	"golang.org/x/time/rate"
+	"k8s.io/utils/clock"
+	testclock "k8s.io/utils/clock/testing"
 )
 
 const nasV2Region = "cn-hangzhou"
@@ -39,7 +43,7 @@ func newNasClientV2ForTest(t *testing.T, mockExpects func(*cloud.MockNasInterfac
 	mockExpects(mockNas)
 	return &NasClientV2{
 		region:  nasV2Region,
-		limiter: rate.NewLimiter(2, 10),
+		limiter: throttle.NewLimiter(clock.RealClock{}, "nas", throttle.LimiterConfig{MaxRate: 2, Burst: 10}),
 		client:  mockNas,
 	}
 }
@@ -287,6 +291,18 @@ func TestDeleteAccessPointError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestDeleteAccessPointWaitError(t *testing.T) {
+	t.Parallel()
+	client := newNasClientV2ForTest(t, func(mockNas *cloud.MockNasInterface) {})
+	client.limiter = throttle.NewLimiter(testclock.NewFakeClock(time.Now()), "nas", throttle.LimiterConfig{MaxRate: 1, Burst: 1})
+	assert.NoError(t, client.limiter.Wait(t.Context(), throttle.PriorityHigh))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	err := client.DeleteAccesspoint(ctx, "", "")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
 func TestDescribeAccessPointSuccess(t *testing.T) {
 	t.Parallel()
 	client := newNasClientV2ForTest(t, func(mockNas *cloud.MockNasInterface) {
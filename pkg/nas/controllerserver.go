@@ -30,6 +30,7 @@ import (
 	"google.golang.org/grpc/status"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 )
 
@@ -45,6 +46,7 @@ func init() {
 type controllerServer struct {
 	*internal.ControllerFactory
 	kubeClient kubernetes.Interface
+	recorder   record.EventRecorder
 	locks      *utils.VolumeLocks
 	common.GenericControllerServer
 }
@@ -57,6 +59,7 @@ func newControllerServer(config *internal.ControllerConfig) (*controllerServer,
 	c := &controllerServer{
 		ControllerFactory: fac,
 		kubeClient:        config.KubeClient,
+		recorder:          utils.NewEventRecorder(),
 		locks:             utils.NewVolumeLocks(),
 	}
 	return c, nil
@@ -81,6 +84,7 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 	}
 	resp, err := controller.CreateVolume(ctx, req)
 	if err != nil {
+		common.RecordOpenAPIError(cs.recorder, req.Parameters, err)
 		return nil, err
 	}
 
@@ -31,6 +31,7 @@ import (
 	aliNas "github.com/aliyun/alibaba-cloud-sdk-go/services/nas"
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/nas/cloud"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/nas/internal"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
@@ -188,11 +189,11 @@ func (cs *filesystemController) CreateVolume(ctx context.Context, req *csi.Creat
 		}
 		klog.Infof("CreateVolume: Volume: %s, Create Nas filesystem with: %v, %v", pvName, nasVol.RegionID, nasVol)
 
-		createFileSystemsResponse, err := nasClient.CreateFileSystem(createFileSystemsRequest)
+		createFileSystemsResponse, err := wrap.V1(ctx, nasClient.CreateFileSystem)(createFileSystemsRequest)
 		if err != nil {
 			klog.Errorf("CreateVolume: requestId[%s], fail to create nas filesystems %s: with %v", createFileSystemsResponse.RequestId, req.GetName(), err)
 			errMsg := utils.FindSuggestionByErrorMessage(err.Error(), utils.NasFilesystemCreate)
-			return nil, status.Error(codes.Internal, errMsg)
+			return nil, wrap.ToStatus(codes.Internal, errMsg, err)
 		}
 		fileSystemID = createFileSystemsResponse.FileSystemId
 		fileSystemType = createFileSystemsRequest.FileSystemType
@@ -209,7 +210,7 @@ func (cs *filesystemController) CreateVolume(ctx context.Context, req *csi.Creat
 			tagResourcesRequest.Tag = &[]aliNas.TagResourcesTag{{Key: NASTAGKEY1, Value: NASTAGVALUE1}, {Key: NASTAGKEY2, Value: NASTAGVALUE2}}
 		}
 		tagResourcesRequest.ResourceType = "filesystem"
-		tagResourcesResponse, err := nasClient.TagResources(tagResourcesRequest)
+		tagResourcesResponse, err := wrap.V1(ctx, nasClient.TagResources)(tagResourcesRequest)
 		if err != nil {
 			str := fmt.Sprintf("CreateVolume: responseID[%s], fail to add default tags filesystem with ID: %s, err: %s", tagResourcesResponse.RequestId, fileSystemID, err.Error())
 			e := status.Error(codes.Internal, str)
@@ -235,11 +236,11 @@ func (cs *filesystemController) CreateVolume(ctx context.Context, req *csi.Creat
 		createMountTargetRequest.AccessGroupName = nasVol.AccessGroupName
 		klog.Infof("CreateVolume: Volume(%s), Create Nas mountTarget with: %v, %v, %v, %v, %v", pvName, fileSystemID, nasVol.NetworkType, nasVol.VpcID, nasVol.VSwitchID, nasVol.AccessGroupName)
 
-		createMountTargetResponse, err := nasClient.CreateMountTarget(createMountTargetRequest)
+		createMountTargetResponse, err := wrap.V1(ctx, nasClient.CreateMountTarget)(createMountTargetRequest)
 		if err != nil {
 			klog.Errorf("CreateVolume: requestId[%s], fail to create nas mountTarget %s: with %v", createMountTargetResponse.RequestId, req.GetName(), err)
 			errMsg := utils.FindSuggestionByErrorMessage(err.Error(), utils.NasMountTargetCreate)
-			return nil, status.Error(codes.Internal, errMsg)
+			return nil, wrap.ToStatus(codes.Internal, errMsg, err)
 		}
 		// extreme nas not return TargetDomain with filesystem create
 		if mountTargetDomain == "" && nasVol.FileSystemType == "extreme" {
@@ -493,7 +494,7 @@ func (cs *filesystemController) DeleteVolume(ctx context.Context, req *csi.Delet
 			deleteMountTargetRequest := aliNas.CreateDeleteMountTargetRequest()
 			deleteMountTargetRequest.FileSystemId = fileSystemID
 			deleteMountTargetRequest.MountTargetDomain = nfsServer
-			deleteMountTargetResponse, err := nasClient.DeleteMountTarget(deleteMountTargetRequest)
+			deleteMountTargetResponse, err := wrap.V1(ctx, nasClient.DeleteMountTarget)(deleteMountTargetRequest)
 			if err != nil {
 				klog.Errorf("DeleteVolume: requestId[%s], volume[%s], fail to delete nas mountTarget %s: with %v", deleteMountTargetResponse.RequestId, req.VolumeId, nfsServer, err)
 				errMsg := utils.FindSuggestionByErrorMessage(err.Error(), utils.NasMountTargetDelete)
@@ -506,7 +507,7 @@ func (cs *filesystemController) DeleteVolume(ctx context.Context, req *csi.Delet
 
 		deleteFileSystemRequest := aliNas.CreateDeleteFileSystemRequest()
 		deleteFileSystemRequest.FileSystemId = fileSystemID
-		deleteFileSystemResponse, err := nasClient.DeleteFileSystem(deleteFileSystemRequest)
+		deleteFileSystemResponse, err := wrap.V1(ctx, nasClient.DeleteFileSystem)(deleteFileSystemRequest)
 		if err != nil {
 			klog.Errorf("DeleteVolume: requestId[%s], volume %s fail to delete nas filesystem %s: with %v", deleteFileSystemResponse.RequestId, req.VolumeId, fileSystemID, err)
 			errMsg := utils.FindSuggestionByErrorMessage(err.Error(), utils.NasFilesystemDelete)
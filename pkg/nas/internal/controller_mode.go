@@ -2,10 +2,14 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
 )
 
 type Controller interface {
@@ -25,14 +29,25 @@ func RegisterControllerMode(f ControllerInitFunc) {
 
 type ControllerFactory struct {
 	Modes map[string]Controller
+	// errors of modes failed to initialize on nodes outside ECS, e.g. no region ID
+	initErrors []error
 }
 
+// NewControllerFactory initializes all registered modes.
+// On nodes declared outside ECS, modes failed to initialize are logged and left out, so that other modes still work.
 func NewControllerFactory(config *ControllerConfig, defaultVolumeAs string) (*ControllerFactory, error) {
+	external := config.Metadata != nil && metadata.IsExternalNode(config.Metadata)
 	modes := map[string]Controller{}
+	var initErrors []error
 	for _, f := range controllerInitFuncs {
 		mode, err := f(config)
 		if err != nil {
-			return nil, err
+			if !external {
+				return nil, err
+			}
+			klog.ErrorS(err, "NAS controller mode disabled")
+			initErrors = append(initErrors, err)
+			continue
 		}
 		volumeAs := mode.VolumeAs()
 		modes[volumeAs] = mode
@@ -40,12 +55,18 @@ func NewControllerFactory(config *ControllerConfig, defaultVolumeAs string) (*Co
 			modes[""] = mode
 		}
 	}
-	return &ControllerFactory{Modes: modes}, nil
+	if len(modes) == 0 && len(initErrors) > 0 {
+		return nil, errors.Join(initErrors...)
+	}
+	return &ControllerFactory{Modes: modes, initErrors: initErrors}, nil
 }
 
 func (fac *ControllerFactory) VolumeAs(what string) (Controller, error) {
 	c, ok := fac.Modes[what]
 	if !ok {
+		if len(fac.initErrors) > 0 {
+			return nil, fmt.Errorf("invalid or disabled volumeAs: %q: %w", what, errors.Join(fac.initErrors...))
+		}
 		return nil, fmt.Errorf("invalid volumeAs: %q", what)
 	}
 	return c, nil
@@ -2,8 +2,11 @@ package internal
 
 import (
 	"errors"
-	"github.com/stretchr/testify/assert"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
 )
 
 func newMockController(*ControllerConfig) (Controller, error) {
@@ -111,3 +114,23 @@ func TestVolumeAs(t *testing.T) {
 		})
 	}
 }
+
+func TestNewControllerFactoryPartialFailure(t *testing.T) {
+	clearControllerInitFuncs()
+	RegisterControllerMode(newMockController)
+	RegisterControllerMode(newErrorController)
+	t.Cleanup(clearControllerInitFuncs)
+
+	_, err := NewControllerFactory(&ControllerConfig{}, MockVolumeAs)
+	assert.Error(t, err, "init errors are only tolerated on external nodes")
+
+	external := metadata.FakeProvider{Values: map[metadata.MetadataKey]string{metadata.HostType: metadata.HostTypeExternal}}
+	fac, err := NewControllerFactory(&ControllerConfig{Metadata: external}, MockVolumeAs)
+	assert.NoError(t, err)
+	c, err := fac.VolumeAs(MockVolumeAs)
+	assert.NoError(t, err)
+	assert.Equal(t, MockController{}, c)
+
+	_, err = fac.VolumeAs("subpath")
+	assert.ErrorContains(t, err, "disabled")
+}
@@ -36,7 +36,9 @@ import (
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/dadi"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/features"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/losetup"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/metric"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter"
+	mounterutils "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/utils"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/nas/internal"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
 	utilsio "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils/io"
@@ -101,6 +103,9 @@ type Options struct {
 	SysConfigs    []utilsio.SysConfig
 	AkID          string
 	AkSecret      string
+	SecurityToken string
+	Expiration    string
+	MetricsPath   string
 }
 
 // RunvNasOptions struct definition
@@ -256,6 +261,13 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	}
 	opt.AkID = req.Secrets[akIDKey]
 	opt.AkSecret = req.Secrets[akSecretKey]
+	if token := req.Secrets[mounterutils.KeySecurityToken]; token != "" {
+		// STS token, refreshed by RequiresRepublish
+		opt.AkID = req.Secrets[mounterutils.KeyAccessKeyId]
+		opt.AkSecret = req.Secrets[mounterutils.KeyAccessKeySecret]
+		opt.SecurityToken = token
+		opt.Expiration = req.Secrets[mounterutils.KeyExpiration]
+	}
 
 	var err error
 	opt.SysConfigs, err = utilsio.ParseSysConfigs(req.VolumeContext["sysConfig"], allowSysConfigKey)
@@ -420,8 +432,18 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		return &csi.NodePublishVolumeResponse{}, nil
 	}
 
+	if opt.MountProtocol == MountProtocolEFC {
+		opt.MetricsPath = writeEFCMetricsInfo(req, opt)
+	}
+
 	if !notMounted {
 		klog.Infof("NodePublishVolume: %s already mounted", mountPath)
+		if opt.SecurityToken != "" && (ns.config.AgentMode || ns.config.MountProxySocket != "") {
+			if err := rotateCredentials(ns.mounter, opt, mountPath, req.VolumeId); err != nil {
+				return nil, status.Errorf(codes.Internal, "rotate credentials: %v", err)
+			}
+			klog.Infof("NodePublishVolume: successfully rotated credentials for volume %s on %s", req.VolumeId, mountPath)
+		}
 		if err := setSysConfigs(mountPath, opt.SysConfigs); err != nil {
 			return nil, status.Errorf(codes.Aborted, "set sysconfig: %v", err)
 		}
@@ -455,24 +477,12 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	}
 
 	//mount nas client
-	if err := doMount(ns.mounter, opt, mountPath, req.VolumeId, podUID, ns.config.AgentMode); err != nil {
+	mountStart := time.Now()
+	err = doMount(ns.mounter, opt, mountPath, req.VolumeId, podUID, ns.config.AgentMode)
+	metric.ObserveVolumePhase(driverType, opt.MountProtocol, metric.PhaseMount, mountStart, err)
+	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
-	if opt.MountProtocol == "efc" {
-		metricsPathPrefix := getMetricsPathPrefix()
-		if strings.Contains(opt.Server, ".nas.aliyuncs.com") {
-			fsID := getNASIDFromMapOrServer(req.VolumeContext, opt.Server)
-			if len(fsID) != 0 {
-				utils.WriteMetricsInfo(metricsPathPrefix, req, "10", "efc", "nas", fsID)
-			}
-		} else {
-			fsID := getCPFSIDFromMapOrServer(req.VolumeContext, opt.Server)
-			if len(fsID) != 0 {
-				utils.WriteMetricsInfo(metricsPathPrefix, req, "10", "efc", "cpfs", fsID)
-			}
-		}
-
-	}
 	// change the mode
 	if opt.Mode != "" && opt.Path != "/" {
 		var args []string
@@ -32,6 +32,7 @@ import (
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/wrap"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/nas/cloud"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/nas/interfaces"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/nas/internal"
@@ -155,7 +156,7 @@ func (cs *subpathController) CreateVolume(ctx context.Context, req *csi.CreateVo
 		Permission:    new("0777"),
 		RootDirectory: &path,
 	}); err != nil {
-		return nil, status.Errorf(codes.Internal, "nas:CreateDir failed: %v", err)
+		return nil, wrap.ToStatus(codes.Internal, fmt.Sprintf("nas:CreateDir failed: %v", err), err)
 	}
 	// set dir quota
 	if parameters["mountType"] != "losetup" && (parameters["volumeCapacity"] == "true" || parameters["allowVolumeExpansion"] == "true") {
@@ -169,7 +170,7 @@ func (cs *subpathController) CreateVolume(ctx context.Context, req *csi.CreateVo
 			QuotaType:    new("Enforcement"),
 			UserType:     new("AllUsers"),
 		}); err != nil {
-			return nil, status.Errorf(codes.Internal, "nas:SetDirQuota failed: %v", err)
+			return nil, wrap.ToStatus(codes.Internal, fmt.Sprintf("nas:SetDirQuota failed: %v", err), err)
 		}
 	}
 
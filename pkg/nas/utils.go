@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/losetup"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter"
 	mounterutils "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/utils"
@@ -91,10 +92,7 @@ func doMount(m mounter.Mounter, opt *Options, targetPath, volumeId, podUid strin
 		combinedOptions = append(combinedOptions, opt.Options)
 	}
 	if opt.AkID != "" && opt.AkSecret != "" {
-		secrets = map[string]string{
-			akIDKey:     opt.AkID,
-			akSecretKey: opt.AkSecret,
-		}
+		secrets = makeMountSecrets(opt)
 	}
 
 	switch opt.ClientType {
@@ -145,12 +143,13 @@ func doMount(m mounter.Mounter, opt *Options, targetPath, volumeId, podUid strin
 	}
 
 	err := m.ExtendedMount(context.Background(), &mounter.MountOperation{
-		Source:   source,
-		Target:   targetPath,
-		FsType:   mountFstype,
-		Options:  combinedOptions,
-		Secrets:  secrets,
-		VolumeID: volumeId,
+		Source:      source,
+		Target:      targetPath,
+		FsType:      mountFstype,
+		Options:     combinedOptions,
+		Secrets:     secrets,
+		VolumeID:    volumeId,
+		MetricsPath: opt.MetricsPath,
 	})
 	if err == nil {
 		return nil
@@ -174,12 +173,13 @@ func doMount(m mounter.Mounter, opt *Options, targetPath, volumeId, podUid strin
 	}
 	defer os.Remove(tmpPath)
 	if err := m.ExtendedMount(context.Background(), &mounter.MountOperation{
-		Source:   rootSource,
-		Target:   tmpPath,
-		FsType:   mountFstype,
-		Options:  combinedOptions,
-		Secrets:  secrets,
-		VolumeID: volumeId,
+		Source:      rootSource,
+		Target:      tmpPath,
+		FsType:      mountFstype,
+		Options:     combinedOptions,
+		Secrets:     secrets,
+		VolumeID:    volumeId,
+		MetricsPath: opt.MetricsPath,
 	}); err != nil {
 		return err
 	}
@@ -190,15 +190,61 @@ func doMount(m mounter.Mounter, opt *Options, targetPath, volumeId, podUid strin
 		klog.Errorf("failed to cleanup tmp mountpoint %s: %v", tmpPath, err)
 	}
 	return m.ExtendedMount(context.Background(), &mounter.MountOperation{
-		Source:   source,
-		Target:   targetPath,
-		FsType:   mountFstype,
-		Options:  combinedOptions,
-		Secrets:  secrets,
-		VolumeID: volumeId,
+		Source:      source,
+		Target:      targetPath,
+		FsType:      mountFstype,
+		Options:     combinedOptions,
+		Secrets:     secrets,
+		VolumeID:    volumeId,
+		MetricsPath: opt.MetricsPath,
+	})
+}
+
+func makeMountSecrets(opt *Options) map[string]string {
+	if opt.SecurityToken != "" {
+		return map[string]string{
+			mounterutils.KeyAccessKeyId:     opt.AkID,
+			mounterutils.KeyAccessKeySecret: opt.AkSecret,
+			mounterutils.KeySecurityToken:   opt.SecurityToken,
+			mounterutils.KeyExpiration:      opt.Expiration,
+		}
+	}
+	return map[string]string{
+		akIDKey:     opt.AkID,
+		akSecretKey: opt.AkSecret,
+	}
+}
+
+// rotateCredentials refreshes the STS token of an already mounted alinas volume.
+// AlinasSecretInterceptor rewrites the credential file in place and skips the mount,
+// the running client reloads the new token by itself.
+func rotateCredentials(m mounter.Mounter, opt *Options, targetPath, volumeId string) error {
+	return m.ExtendedMount(context.Background(), &mounter.MountOperation{
+		Source:      fmt.Sprintf("%s:%s", opt.Server, opt.Path),
+		Target:      targetPath,
+		FsType:      MountProtocolAliNas,
+		Secrets:     makeMountSecrets(opt),
+		VolumeID:    volumeId,
+		MetricsPath: opt.MetricsPath,
 	})
 }
 
+func writeEFCMetricsInfo(req *csi.NodePublishVolumeRequest, opt *Options) string {
+	metricsPathPrefix := getMetricsPathPrefix()
+	if strings.Contains(opt.Server, ".nas.aliyuncs.com") {
+		fsID := getNASIDFromMapOrServer(req.VolumeContext, opt.Server)
+		if len(fsID) != 0 {
+			return utils.WriteMetricsInfo(metricsPathPrefix, req, "10", "efc", "nas", fsID)
+		}
+	} else {
+		fsID := getCPFSIDFromMapOrServer(req.VolumeContext, opt.Server)
+		if len(fsID) != 0 {
+			return utils.WriteMetricsInfo(metricsPathPrefix, req, "10", "efc", "cpfs", fsID)
+		}
+	}
+	return ""
+}
+
 func getMountRootAndRelPath(mountFsType string, opt *Options) (rootSource, relPath string) {
 	if opt == nil {
 		return
@@ -0,0 +1,79 @@
+//go:build !windows
+
+package oss
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils"
+)
+
+// cachePathRootsKey is the comma-separated directories on nodes under which hostpath caches are allowed.
+// hostpath caches are rejected if it is empty.
+var cachePathRootsKey = utils.ConfigKey{
+	Name: "oss-cache-path-roots", Env: "OSS_CACHE_PATH_ROOTS",
+	Validate: func(s string) error {
+		_, err := parseCachePathRoots(s)
+		return err
+	},
+}
+
+// cachePathRoots follows cachePathRootsKey in csi-plugin ConfigMap, and subscribes to the config only once.
+var cachePathRoots = sync.OnceValue(func() *atomic.Pointer[[]string] {
+	roots := &atomic.Pointer[[]string]{}
+	w := utils.CSIPluginConfig
+	w.RegisterKeys(cachePathRootsKey)
+	update := func(c utils.Config) {
+		r, _ := parseCachePathRoots(cachePathRootsKey.Get(c))
+		roots.Store(&r)
+	}
+	update(w.Current())
+	w.Subscribe(update)
+	return roots
+})
+
+func parseCachePathRoots(s string) ([]string, error) {
+	var roots []string
+	for root := range strings.SplitSeq(s, ",") {
+		root = strings.TrimSpace(root)
+		if root == "" {
+			continue
+		}
+		if !filepath.IsAbs(root) || hasDotDot(root) {
+			return nil, fmt.Errorf("%q is not an absolute path", root)
+		}
+		roots = append(roots, filepath.Clean(root))
+	}
+	return roots, nil
+}
+
+func hasDotDot(p string) bool {
+	return slices.Contains(strings.Split(p, "/"), "..")
+}
+
+// checkCachePath ensures the hostpath cache p is under one of the roots allowed by the cluster admin,
+// so that a volume cannot expose arbitrary directories of the node to its fuse pod.
+func checkCachePath(p string, roots []string) error {
+	if !filepath.IsAbs(p) {
+		return fmt.Errorf("cachePath %q must be an absolute path", p)
+	}
+	if hasDotDot(p) {
+		return fmt.Errorf("cachePath %q must not contain ..", p)
+	}
+	if len(roots) == 0 {
+		return fmt.Errorf("cachePath is not allowed, no %s is configured in csi-plugin ConfigMap", cachePathRootsKey.Name)
+	}
+	p = filepath.Clean(p)
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, p)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return nil
+		}
+	}
+	return fmt.Errorf("cachePath %q is not under any of %s %v", p, cachePathRootsKey.Name, roots)
+}
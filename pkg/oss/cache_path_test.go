@@ -0,0 +1,35 @@
+//go:build !windows
+
+package oss
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCachePathRoots(t *testing.T) {
+	roots, err := parseCachePathRoots(" /mnt/nvme/ ,,/data")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/mnt/nvme", "/data"}, roots)
+
+	roots, err = parseCachePathRoots("")
+	assert.NoError(t, err)
+	assert.Empty(t, roots)
+
+	_, err = parseCachePathRoots("/mnt,cache")
+	assert.Error(t, err)
+	_, err = parseCachePathRoots("/mnt/../etc")
+	assert.Error(t, err)
+}
+
+func TestCheckCachePath(t *testing.T) {
+	roots := []string{"/mnt/nvme", "/data"}
+	for _, p := range []string{"/mnt/nvme", "/mnt/nvme/cache", "/data/a/b/", "/data//a"} {
+		assert.NoError(t, checkCachePath(p, roots), p)
+	}
+	for _, p := range []string{"cache", "/mnt/nvme2", "/mnt", "/", "/mnt/nvme/../nvme2", "/mnt/nvme/a/../b"} {
+		assert.Error(t, checkCachePath(p, roots), p)
+	}
+	assert.Error(t, checkCachePath("/mnt/nvme/cache", nil), "not allowed without roots")
+}
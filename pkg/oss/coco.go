@@ -6,16 +6,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"path/filepath"
 	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	fpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager"
 	ossfpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager/oss"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager/oss/ossfs2"
+	mounterutils "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/utils"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils/kata/directvolume"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/klog/v2"
 )
 
 const (
 	optDirectAssigned  = "direct"
+	optGuestMount      = "guestMount"
 	optAnnotations     = "annotations"
 	optEncrypted       = "encrypted"
 	optEncPasswd       = "encPasswd"
@@ -23,6 +31,8 @@ const (
 	fsTypeSecureMount  = "secure_mount"
 	volumeTypeOSS      = "alibaba-cloud-oss"
 	sealedSecretPrefix = "sealed."
+	// guestRoot is where the agent in the guest prepares the config and cache of each ossfs2 guest mount
+	guestRoot = "/run/ossfs2"
 )
 
 func (ns *nodeServer) publishDirectVolume(ctx context.Context, req *csi.NodePublishVolumeRequest, opt *ossfpm.Options) (*csi.NodePublishVolumeResponse, error) {
@@ -33,7 +43,11 @@ func (ns *nodeServer) publishDirectVolume(ctx context.Context, req *csi.NodePubl
 	)
 
 	volumePath := req.TargetPath
-	if isDirectVolumePath(volumePath) {
+	guestMount := opt.FuseType == mounterutils.OssFs2Type && opt.GuestMount
+	if info, err := directvolume.VolumeMountInfo(volumePath); err == nil {
+		if guestMount && opt.SecurityToken != "" {
+			return ns.rotateDirectVolumeToken(ctx, volumePath, info, opt)
+		}
 		logger.Info("NodePublishVolume: The mount info for DirectVolume already exist")
 		return &csi.NodePublishVolumeResponse{}, nil
 	}
@@ -60,6 +74,22 @@ func (ns *nodeServer) publishDirectVolume(ctx context.Context, req *csi.NodePubl
 		}
 	}
 
+	options := strings.Fields(opt.OtherOpts)
+	var credentials map[string]string
+	if guestMount {
+		// ossfs2 is mounted inside the guest with the same options as runc
+		var err error
+		options, err = ns.makeGuestMountOptions(req, opt)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		credentials, err = makeSealedCredentials(opt)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		fsType = opt.FuseType
+	}
+
 	if annotationsObj["kata_fs_type"] != "" {
 		fsType = annotationsObj["kata_fs_type"]
 	}
@@ -86,7 +116,19 @@ func (ns *nodeServer) publishDirectVolume(ctx context.Context, req *csi.NodePubl
 		"readonly":    fmt.Sprintf("%v", req.Readonly),
 		"targetPath":  req.GetTargetPath(),
 	}
-	if opt.AkSecret != "" && strings.HasPrefix(opt.AkSecret, sealedSecretPrefix) {
+	if guestMount {
+		metadata["fuseType"] = opt.FuseType
+		metadata["guestDir"] = guestDir(req.TargetPath)
+		if opt.SecretRef != "" {
+			// materialized by the agent into the guest config dir, in place of the secret volume of the fuse pod
+			metadata["secretRef"] = fpm.FusePodNamespace + "/" + opt.SecretRef
+		}
+		if opt.CacheMedium != "" {
+			metadata["cacheMedium"] = string(opt.CacheMedium)
+			metadata["cacheSize"] = opt.CacheSize
+		}
+		maps.Copy(metadata, credentials)
+	} else if opt.AkSecret != "" && strings.HasPrefix(opt.AkSecret, sealedSecretPrefix) {
 		// `AkID` and `AkSecret` are fixed for the protocol
 		metadata[AkID] = opt.AkID
 		metadata[AkSecret] = opt.AkSecret
@@ -100,7 +142,7 @@ func (ns *nodeServer) publishDirectVolume(ctx context.Context, req *csi.NodePubl
 		Device:     device,
 		FsType:     fsType,
 		Metadata:   metadata,
-		Options:    strings.Fields(opt.OtherOpts),
+		Options:    options,
 	}
 
 	logger.Info("NodePublishVolume:: Starting add mount info for DirectVolume")
@@ -114,6 +156,109 @@ func (ns *nodeServer) publishDirectVolume(ctx context.Context, req *csi.NodePubl
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
+// guestDir is the directory in the guest holding the config and cache of the volume published to target.
+func guestDir(target string) string {
+	return filepath.Join(guestRoot, mounterutils.ComputeMountPathHash(target))
+}
+
+// makeGuestMountOptions makes the options for ossfs2 running in the guest,
+// they are the same as the ones passed to the fuse pod in runc,
+// except that paths of the fuse pod are translated to the guest dir.
+func (ns *nodeServer) makeGuestMountOptions(req *csi.NodePublishVolumeRequest, opt *ossfpm.Options) ([]string, error) {
+	if opt.CacheMedium == fpm.CacheMediumHostPath {
+		return nil, fmt.Errorf("cacheMedium %s is not supported for guest mount, the guest cannot access the directories of the node", opt.CacheMedium)
+	}
+	m := ns.fusePodManagers[opt.FuseType]
+	if err := checkOssOptions(opt, m); err != nil {
+		return nil, err
+	}
+	options, err := makeMountOptions(opt, m, ns.metadata, req.VolumeCapability)
+	if err != nil {
+		return nil, err
+	}
+	return translateGuestPaths(m.AddDefaultMountOptions(options), opt.FuseType, guestDir(req.TargetPath)), nil
+}
+
+// translateGuestPaths replaces the paths where the fuse pod mounts the secretRef and the cache
+// with the ones under dir in the guest.
+func translateGuestPaths(options []string, fuseType, dir string) []string {
+	replacer := strings.NewReplacer(
+		"="+mounterutils.GetConfigDir(fuseType)+"/", "="+filepath.Join(dir, "config")+"/",
+		"="+ossfs2.CacheDir, "="+filepath.Join(dir, "cache"),
+	)
+	translated := make([]string, 0, len(options))
+	for _, o := range options {
+		translated = append(translated, replacer.Replace(o))
+	}
+	return translated
+}
+
+// makeSealedCredentials returns the credentials passed to the guest through mount info.
+// Mount info is readable on the host, so only sealed secrets are accepted,
+// they are unsealed inside the guest.
+func makeSealedCredentials(opt *ossfpm.Options) (map[string]string, error) {
+	var credentials map[string]string
+	var secrets []string
+	switch {
+	case opt.SecurityToken != "":
+		credentials = map[string]string{
+			mounterutils.KeyAccessKeyId:     opt.AccessKeyId,
+			mounterutils.KeyAccessKeySecret: opt.AccessKeySecret,
+			mounterutils.KeySecurityToken:   opt.SecurityToken,
+		}
+		if opt.Expiration != "" {
+			credentials[mounterutils.KeyExpiration] = opt.Expiration
+		}
+		secrets = []string{mounterutils.KeyAccessKeySecret, mounterutils.KeySecurityToken}
+	case opt.AkSecret != "":
+		// `AkID` and `AkSecret` are fixed for the protocol
+		credentials = map[string]string{
+			AkID:     opt.AkID,
+			AkSecret: opt.AkSecret,
+		}
+		secrets = []string{AkSecret}
+	default:
+		// RRSA, ECS RAM role or secretRef, nothing to pass
+		return nil, nil
+	}
+	for _, key := range secrets {
+		if !strings.HasPrefix(credentials[key], sealedSecretPrefix) {
+			return nil, fmt.Errorf("%s must be a sealed secret for guest mount", key)
+		}
+	}
+	return credentials, nil
+}
+
+// rotateDirectVolumeToken replaces the sealed token in mount info.
+// The agent in the guest watches the mount info and reloads the token into ossfs2.
+func (ns *nodeServer) rotateDirectVolumeToken(ctx context.Context, volumePath string, info *directvolume.MountInfo, opt *ossfpm.Options) (*csi.NodePublishVolumeResponse, error) {
+	logger := klog.FromContext(ctx).WithValues("target", volumePath)
+	credentials, err := makeSealedCredentials(opt)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if info.Metadata == nil {
+		info.Metadata = map[string]string{}
+	}
+	changed := false
+	for key, value := range credentials {
+		if info.Metadata[key] != value {
+			info.Metadata[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		logger.V(4).Info("NodePublishVolume: token for DirectVolume not changed")
+		return &csi.NodePublishVolumeResponse{}, nil
+	}
+	if err := directvolume.AddMountInfo(volumePath, *info); err != nil {
+		logger.Error(err, "NodePublishVolume:: Rotate token for DirectVolume failed")
+		return nil, err
+	}
+	logger.Info("NodePublishVolume:: Rotated token for DirectVolume")
+	return &csi.NodePublishVolumeResponse{}, nil
+}
+
 func (ns *nodeServer) unPublishDirectVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
 	logger := klog.FromContext(ctx).WithValues(
 		"nodeServer", "OSSNodeServer",
@@ -7,9 +7,14 @@ import (
 	"testing"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	fpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager"
 	ossfpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager/oss"
+	mounterutils "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/utils"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/utils/kata/directvolume"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	mountutils "k8s.io/mount-utils"
 )
 
 func Test_nodeServer_publishDirectVolume(t *testing.T) {
@@ -123,3 +128,159 @@ func Test_nodeServer_unPublishDirectVolume(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
 }
+
+func Test_nodeServer_publishDirectVolume_ossfs2(t *testing.T) {
+	ns := setupTestNodeServer(t, mountutils.NewFakeMounter(nil), false)
+	req := &csi.NodePublishVolumeRequest{
+		VolumeId:   "test-id",
+		TargetPath: "/foo/bar/path/for/publish/ossfs2",
+	}
+	opts := &ossfpm.Options{
+		URL:        "https://oss-cn-hangzhou.aliyuncs.com",
+		Bucket:     "test-bucket",
+		Path:       "/path",
+		FuseType:   mounterutils.OssFs2Type,
+		GuestMount: true,
+		TokenSecret: ossfpm.TokenSecret{
+			AccessKeyId:     "sealed.id",
+			AccessKeySecret: "sealed.secret",
+			SecurityToken:   "sealed.token",
+		},
+	}
+	resp, err := ns.publishDirectVolume(context.TODO(), req, opts)
+	assert.NoError(t, err)
+	assert.NotNil(t, resp)
+	defer directvolume.Remove(req.TargetPath)
+
+	info, err := directvolume.VolumeMountInfo(req.TargetPath)
+	assert.NoError(t, err)
+	assert.Equal(t, mounterutils.OssFs2Type, info.FsType)
+	assert.Contains(t, info.Options, "oss_bucket=test-bucket")
+	assert.Contains(t, info.Options, "log_dir=/dev/stdout")
+	assert.Equal(t, "sealed.token", info.Metadata[mounterutils.KeySecurityToken])
+	assert.Equal(t, "sealed.secret", info.Metadata[mounterutils.KeyAccessKeySecret])
+
+	// republish rotates the token
+	opts.SecurityToken = "sealed.token2"
+	_, err = ns.publishDirectVolume(context.TODO(), req, opts)
+	assert.NoError(t, err)
+	info, err = directvolume.VolumeMountInfo(req.TargetPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "sealed.token2", info.Metadata[mounterutils.KeySecurityToken])
+	assert.Contains(t, info.Options, "oss_bucket=test-bucket")
+}
+
+func Test_nodeServer_publishDirectVolume_ossfs2_without_guestMount(t *testing.T) {
+	ns := setupTestNodeServer(t, mountutils.NewFakeMounter(nil), false)
+	req := &csi.NodePublishVolumeRequest{
+		VolumeId:   "test-id",
+		TargetPath: "/foo/bar/path/for/publish/ossfs2-raw",
+	}
+	opts := &ossfpm.Options{
+		Bucket:    "test-bucket",
+		FuseType:  mounterutils.OssFs2Type,
+		OtherOpts: "-o close_to_open=false",
+		AccessKey: ossfpm.AccessKey{AkID: "id", AkSecret: "secret"},
+	}
+	_, err := ns.publishDirectVolume(context.TODO(), req, opts)
+	assert.NoError(t, err)
+	defer directvolume.Remove(req.TargetPath)
+
+	info, err := directvolume.VolumeMountInfo(req.TargetPath)
+	assert.NoError(t, err)
+	assert.Equal(t, fsTypeSecureMount, info.FsType)
+	assert.Equal(t, []string{"-o", "close_to_open=false"}, info.Options)
+	assert.NotContains(t, info.Metadata, AkSecret, "plaintext secrets are never passed")
+}
+
+func Test_nodeServer_publishDirectVolume_guestMount_paths(t *testing.T) {
+	ns := setupTestNodeServer(t, mountutils.NewFakeMounter(nil), false)
+	req := &csi.NodePublishVolumeRequest{
+		VolumeId:   "test-id",
+		TargetPath: "/foo/bar/path/for/publish/ossfs2-paths",
+	}
+	opts := &ossfpm.Options{
+		URL:         "https://oss-cn-hangzhou.aliyuncs.com",
+		Bucket:      "test-bucket",
+		Path:        "/path",
+		FuseType:    mounterutils.OssFs2Type,
+		GuestMount:  true,
+		SecretRef:   "oss-secret",
+		CacheMedium: fpm.CacheMediumMemory,
+		CacheSize:   "1Gi",
+	}
+	_, err := ns.publishDirectVolume(context.TODO(), req, opts)
+	assert.NoError(t, err)
+	defer directvolume.Remove(req.TargetPath)
+
+	info, err := directvolume.VolumeMountInfo(req.TargetPath)
+	assert.NoError(t, err)
+	dir := guestDir(req.TargetPath)
+	assert.Equal(t, dir, info.Metadata["guestDir"])
+	assert.Equal(t, fpm.FusePodNamespace+"/oss-secret", info.Metadata["secretRef"])
+	assert.Equal(t, "memory", info.Metadata["cacheMedium"])
+	assert.Contains(t, info.Options, "data_cache_dir="+dir+"/cache")
+	assert.Contains(t, info.Options, "oss_sts_multi_conf_token_file="+dir+"/config/passwd-ossfs2/"+mounterutils.KeySecurityToken)
+	for _, o := range info.Options {
+		assert.NotContains(t, o, "=/etc/ossfs2/")
+		assert.NotContains(t, o, "=/var/cache/ossfs2")
+	}
+
+	opts.CacheMedium = fpm.CacheMediumHostPath
+	opts.CachePath = "/mnt/cache"
+	_, err = ns.publishDirectVolume(context.TODO(), &csi.NodePublishVolumeRequest{
+		VolumeId:   "test-id",
+		TargetPath: "/foo/bar/path/for/publish/ossfs2-hostpath",
+	}, opts)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMakeSealedCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     ossfpm.Options
+		expected map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "no credentials",
+			opts: ossfpm.Options{AuthType: ossfpm.AuthTypeRRSA},
+		},
+		{
+			name:     "sealed AK",
+			opts:     ossfpm.Options{AccessKey: ossfpm.AccessKey{AkID: "id", AkSecret: "sealed.secret"}},
+			expected: map[string]string{AkID: "id", AkSecret: "sealed.secret"},
+		},
+		{
+			name:    "plaintext AK",
+			opts:    ossfpm.Options{AccessKey: ossfpm.AccessKey{AkID: "id", AkSecret: "secret"}},
+			wantErr: true,
+		},
+		{
+			name: "sealed token",
+			opts: ossfpm.Options{TokenSecret: ossfpm.TokenSecret{AccessKeyId: "id", AccessKeySecret: "sealed.secret", SecurityToken: "sealed.token", Expiration: "2026-01-01T00:00:00Z"}},
+			expected: map[string]string{
+				mounterutils.KeyAccessKeyId:     "id",
+				mounterutils.KeyAccessKeySecret: "sealed.secret",
+				mounterutils.KeySecurityToken:   "sealed.token",
+				mounterutils.KeyExpiration:      "2026-01-01T00:00:00Z",
+			},
+		},
+		{
+			name:    "plaintext token",
+			opts:    ossfpm.Options{TokenSecret: ossfpm.TokenSecret{AccessKeyId: "id", AccessKeySecret: "sealed.secret", SecurityToken: "token"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := makeSealedCredentials(&tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
@@ -20,13 +20,16 @@ package oss
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
 	cnfsv1beta1 "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cnfs/v1beta1"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/metric"
 	fpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager"
 	ossfpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager/oss"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/utils"
@@ -41,7 +44,7 @@ import (
 )
 
 const (
-	fusePodNamespace = "ack-csi-fuse"
+	fusePodNamespace = fpm.FusePodNamespace
 	mountProxySocket = "mountPorxySocket"
 )
 
@@ -190,10 +193,17 @@ func (cs *controllerServer) ControllerPublishVolume(ctx context.Context, req *cs
 	}
 	// make pod template config
 	ptCfg := makePodTemplateConfig(opts)
+	if err := cs.applyFuseResources(ctx, req.VolumeId, nodeName, ptCfg); err != nil {
+		if errors.Is(err, ParamError) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get node %s: %v", nodeName, err)
+	}
 	// make mount options
 	controllerPublishPath := mounterutils.GetAttachPath(req.VolumeId)
 
 	// launch ossfs pod
+	fusePodStart := time.Now()
 	fusePod, err := cs.fusePodManagers[opts.FuseType].Create(&fpm.FusePodContext{
 		Context:           ctx,
 		Namespace:         fusePodNamespace,
@@ -203,17 +213,20 @@ func (cs *controllerServer) ControllerPublishVolume(ctx context.Context, req *cs
 		PodTemplateConfig: ptCfg,
 		FuseType:          opts.FuseType,
 	}, controllerPublishPath)
+	metric.ObserveVolumePhase(driverType, opts.FuseType, metric.PhaseFusePodReady, fusePodStart, err)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create %s pod: %v", opts.FuseType, err)
 	}
 
+	publishContext := map[string]string{
+		mountProxySocket: mounterutils.GetMountProxySocketPath(req.VolumeId),
+		// make the fuse pod name visible in the VolumeAttachment status
+		"fusePod": fmt.Sprintf("%s/%s", fusePod.Namespace, fusePod.Name),
+	}
+
 	klog.Infof("ControllerPublishVolume: successfully published volume %s on node %s", req.VolumeId, req.NodeId)
 	return &csi.ControllerPublishVolumeResponse{
-		PublishContext: map[string]string{
-			mountProxySocket: mounterutils.GetMountProxySocketPath(req.VolumeId),
-			// make the fuse pod name visible in the VolumeAttachment status
-			"fusePod": fmt.Sprintf("%s/%s", fusePod.Namespace, fusePod.Name),
-		},
+		PublishContext: publishContext,
 	}, nil
 }
 
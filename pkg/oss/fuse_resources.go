@@ -0,0 +1,74 @@
+//go:build !windows
+
+package oss
+
+import (
+	"context"
+	"maps"
+
+	fpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// applyFuseResources completes the fuse container resources of ptCfg.
+// Resources set in volume attributes must fit into the node allocatable,
+// requests recommended on the PV fill the rest if they fit.
+func (cs *controllerServer) applyFuseResources(ctx context.Context, volumeID, nodeName string, ptCfg *fpm.PodTemplateConfig) error {
+	if cs.client == nil {
+		return nil
+	}
+	node, err := cs.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	allocatable := node.Status.Allocatable
+	if err := fpm.ValidateResources(ptCfg.Resources, allocatable); err != nil {
+		return WrapOssError(ParamError, "fuse resources: %v", err)
+	}
+
+	recommended, err := cs.getRecommendedFuseResources(ctx, volumeID)
+	if err != nil {
+		klog.ErrorS(err, "failed to get recommended fuse resources", "volumeID", volumeID)
+		return nil
+	}
+	if recommended == nil || len(recommended.Requests) == 0 {
+		return nil
+	}
+	res := ptCfg.Resources
+	if res == nil {
+		res = &corev1.ResourceRequirements{}
+	} else {
+		res = res.DeepCopy()
+	}
+	if res.Requests == nil {
+		res.Requests = corev1.ResourceList{}
+	}
+	requests := maps.Clone(recommended.Requests)
+	// explicit settings always win
+	maps.Copy(requests, res.Requests)
+	res.Requests = requests
+	if err := fpm.ValidateResources(res, allocatable); err != nil {
+		klog.InfoS("ignore recommended fuse resources", "volumeID", volumeID, "node", nodeName, "reason", err.Error())
+		return nil
+	}
+	klog.V(2).InfoS("apply recommended fuse resources", "volumeID", volumeID, "requests", res.Requests)
+	ptCfg.Resources = res
+	return nil
+}
+
+func (cs *controllerServer) getRecommendedFuseResources(ctx context.Context, volumeID string) (*corev1.ResourceRequirements, error) {
+	pv, err := cs.client.CoreV1().PersistentVolumes().Get(ctx, volumeID, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return fpm.ParseResourcesRecommendation(pv.Annotations[fpm.FuseResourcesRecommendationAnnoKey])
+}
@@ -0,0 +1,85 @@
+//go:build !windows
+
+package oss
+
+import (
+	"context"
+	"testing"
+
+	fpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestApplyFuseResources(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+		},
+	}
+	newPV := func(recommendation string) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pv-oss",
+				Annotations: map[string]string{fpm.FuseResourcesRecommendationAnnoKey: recommendation},
+			},
+		}
+	}
+	tests := []struct {
+		name           string
+		recommendation string
+		resources      *corev1.ResourceRequirements
+		expected       *corev1.ResourceRequirements
+		wantErr        bool
+	}{
+		{
+			name: "no override",
+		},
+		{
+			name:      "override exceeds allocatable",
+			resources: &corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Gi")}},
+			wantErr:   true,
+		},
+		{
+			name:           "recommendation applied",
+			recommendation: `{"requests":{"cpu":"200m","memory":"1Gi"}}`,
+			resources:      &corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+			expected: &corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			}},
+		},
+		{
+			name:           "recommendation exceeds allocatable",
+			recommendation: `{"requests":{"memory":"16Gi"}}`,
+		},
+		{
+			name:           "invalid recommendation",
+			recommendation: `{`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &controllerServer{client: fake.NewSimpleClientset(node, newPV(tt.recommendation))}
+			ptCfg := &fpm.PodTemplateConfig{Resources: tt.resources}
+			err := cs.applyFuseResources(context.Background(), "pv-oss", "node-1", ptCfg)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ParamError)
+				return
+			}
+			assert.NoError(t, err)
+			expected := tt.expected
+			if expected == nil {
+				expected = tt.resources
+			}
+			assert.Equal(t, expected, ptCfg.Resources)
+		})
+	}
+}
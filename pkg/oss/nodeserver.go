@@ -22,12 +22,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
 	cnfsv1beta1 "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cnfs/v1beta1"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/features"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/metric"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter"
 	ossfpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager/oss"
 	mounterutils "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/utils"
@@ -50,6 +52,8 @@ type nodeServer struct {
 	ossfsPaths      map[string]string
 	common.GenericNodeServer
 	skipAttach bool
+	// warmup is nil without clientset
+	warmup *warmupReporter
 }
 
 const (
@@ -106,6 +110,8 @@ func validateNodePublishVolumeRequest(req *csi.NodePublishVolumeRequest) error {
 //	- Update token files in the credential directory
 //	- Skip the mount operation (return ErrSkipMount) if mount point already exists
 //	- Allow the existing ossfs client to automatically reload the new token
+//	For COCO with ossfs2 and guestMount, the sealed token in the DirectVolume mount info is replaced,
+//	and reloaded by the agent in the guest.
 func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
 	klog.Infof("NodePublishVolume:: Starting Mount volume: %s", req.VolumeId)
 	if !ns.locks.TryAcquire(req.VolumeId) {
@@ -133,6 +139,8 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	}
 
 	socketPath := req.PublishContext[mountProxySocket]
+	// prefixes to warm up, scheduled by NodeStageVolume
+	opts.WarmupPrefixes = ns.warmup.prefixes(req.VolumeId)
 
 	// Determine runtime type based on directAssigned, socketPath, and skipAttach
 	// See DetermineRuntimeType for the support matrix.
@@ -156,7 +164,7 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		return nil, err
 	}
 
-	// Handle COCO scenario: do not support republish
+	// Handle COCO scenario: only ossfs2 with guestMount supports republish for token rotation
 	if runtimeType == RuntimeTypeCOCO {
 		if !notMntTarget {
 			klog.Infof("NodePublishVolume: %s already mounted", targetPath)
@@ -249,6 +257,7 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 			// new mounts
 			metricsPath = utils.WriteMetricsInfo(metricsPathPrefix, req, opts.MetricsTop, opts.FuseType, "oss", opts.Bucket)
 		}
+		mountStart := time.Now()
 		err := ossfsMounter.ExtendedMount(ctx, &mounter.MountOperation{
 			Source:      mountSource,
 			Target:      targetPath,
@@ -257,9 +266,13 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 			Secrets:     authCfg.Secrets,
 			MetricsPath: metricsPath,
 		})
+		metric.ObserveVolumePhase(driverType, opts.FuseType, metric.PhaseMount, mountStart, err)
 		if err != nil {
 			return nil, status.Error(codes.Internal, err.Error())
 		}
+		if len(opts.WarmupPrefixes) > 0 {
+			ns.warmup.track(req.VolumeId, metricsPath)
+		}
 		if !notMntTarget {
 			// For the scenario where targetPath is already mounted, if token rotation is not needed,
 			// it would have exited early. Therefore, this log is reasonable.
@@ -285,6 +298,7 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 			// new mounts
 			metricsPath = utils.WriteSharedMetricsInfo(metricsPathPrefix, req, opts.FuseType, "oss", opts.Bucket, attachPath)
 		}
+		mountStart := time.Now()
 		err = ossfsMounter.ExtendedMount(ctx, &mounter.MountOperation{
 			Source:      mountSource,
 			Target:      attachPath,
@@ -293,9 +307,13 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 			Secrets:     authCfg.Secrets,
 			MetricsPath: metricsPath,
 		})
+		metric.ObserveVolumePhase(driverType, opts.FuseType, metric.PhaseMount, mountStart, err)
 		if err != nil {
 			return nil, status.Error(codes.Internal, err.Error())
 		}
+		if len(opts.WarmupPrefixes) > 0 {
+			ns.warmup.track(req.VolumeId, metricsPath)
+		}
 		if !notMntTarget {
 			klog.Infof("NodePublishVolume: successfully rotated token for volume %s on %s", req.VolumeId, attachPath)
 			return &csi.NodePublishVolumeResponse{}, nil
@@ -307,7 +325,10 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	// If attachPath is mounted, we only need bind mount (no ExtendedMount)
 	// Note: Since targetPath does not exist in this scenario, options validation is still performed.
 	// This behavior is consistent with previous implementations.
-	if err := ns.rawMounter.Mount(attachPath, targetPath, "", []string{"bind"}); err != nil {
+	publishStart := time.Now()
+	err = ns.rawMounter.Mount(attachPath, targetPath, "", []string{"bind"})
+	metric.ObserveVolumePhase(driverType, opts.FuseType, metric.PhasePublish, publishStart, err)
+	if err != nil {
 		return nil, status.Errorf(codes.Internal, "bind mount failed: %v", err)
 	}
 	klog.Infof("NodePublishVolume: bind mounted %s to %s", attachPath, targetPath)
@@ -350,6 +371,15 @@ func (ns *nodeServer) NodeStageVolume(
 	ctx context.Context,
 	req *csi.NodeStageVolumeRequest) (
 	*csi.NodeStageVolumeResponse, error) {
+	// The volume is mounted by NodePublishVolume, only the warm-up is scheduled here.
+	if ns.warmup != nil {
+		opts, err := parseOptions(ctx, ns.cnfsGetter, req.GetVolumeContext(), req.GetSecrets(), []*csi.VolumeCapability{req.GetVolumeCapability()}, false, "", true, ns.metadata)
+		if err != nil {
+			klog.ErrorS(err, "NodeStageVolume: failed to parse options, skip warm-up", "volumeID", req.VolumeId)
+		} else {
+			ns.warmup.schedule(ctx, opts, req.VolumeId)
+		}
+	}
 	return &csi.NodeStageVolumeResponse{}, nil
 }
 
@@ -370,6 +400,7 @@ func (ns *nodeServer) NodeUnstageVolume(
 	}
 
 	// The metricsPath in fuse Pod will be cleaned and not allowed to update the metrics
+	ns.warmup.unschedule(ctx, req.VolumeId)
 	utils.RemoveMetrics(metricsPathPrefix, req)
 
 	// In the legacy mount process, NodePublishVolume creates ossfs pods in kube-system namespace to mount ossfpm.
@@ -19,11 +19,14 @@ limitations under the License.
 package oss
 
 import (
+	"context"
 	"os"
 
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cloud/metadata"
 	cnfsv1beta1 "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/cnfs/v1beta1"
 	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/common"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/features"
+	"github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/metric"
 	ossfpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager/oss"
 	_ "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager/oss/ossfs"
 	_ "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager/oss/ossfs2"
@@ -90,6 +93,14 @@ func NewDriver(endpoint string, m metadata.MetadataProvider, serviceType utils.S
 		}
 	}
 	if serviceType&utils.Node != 0 {
+		var warmup *warmupReporter
+		if clientset != nil {
+			warmup = newWarmupReporter(clientset, nodeName)
+			go warmup.run(context.Background())
+			if features.FunctionalMutableFeatureGate.Enabled(features.FuseResourceRecommender) {
+				go metric.RunFuseResourceRecommender(context.Background(), clientset)
+			}
+		}
 		servers.NodeServer = &nodeServer{
 			metadata:        m,
 			locks:           utils.NewVolumeLocks(),
@@ -98,6 +109,7 @@ func NewDriver(endpoint string, m metadata.MetadataProvider, serviceType utils.S
 			cnfsGetter:      cnfsGetter,
 			rawMounter:      mountutils.NewWithoutSystemd(""),
 			fusePodManagers: fusePodManagers,
+			warmup:          warmup,
 			GenericNodeServer: common.GenericNodeServer{
 				NodeID: nodeName,
 			},
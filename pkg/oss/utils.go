@@ -37,6 +37,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/klog/v2"
 )
 
@@ -203,6 +204,12 @@ func parseOptions(ctx context.Context, cnfsGetter cnfsv1beta1.CNFSGetter, volOpt
 			opts.CNFSName = value
 		case optDirectAssigned:
 			directAssignedValue = value
+		case strings.ToLower(optGuestMount):
+			if res, err := strconv.ParseBool(value); err == nil {
+				opts.GuestMount = res
+			} else {
+				klog.Warning(WrapOssError(ParamError, "the value(%q) of %q is invalid", v, k).Error())
+			}
 		case "encrypted":
 			opts.Encrypted = strings.ToLower(value)
 		case "kmskeyid":
@@ -230,6 +237,28 @@ func parseOptions(ctx context.Context, cnfsGetter cnfsv1beta1.CNFSGetter, volOpt
 			}
 		case "runtimeclass":
 			runtimeClassValue = value
+		case "cachemedium":
+			opts.CacheMedium = fpm.CacheMedium(strings.ToLower(value))
+		case "cachepath":
+			opts.CachePath = value
+		case "cachesize":
+			opts.CacheSize = value
+		case "cacheevictionpolicy":
+			opts.CacheEvictionPolicy = strings.ToLower(value)
+		case "fusecpurequest":
+			opts.FuseCPURequest = value
+		case "fusecpulimit":
+			opts.FuseCPULimit = value
+		case "fusememoryrequest":
+			opts.FuseMemoryRequest = value
+		case "fusememorylimit":
+			opts.FuseMemoryLimit = value
+		case "prefetchprefixes":
+			for prefix := range strings.SplitSeq(value, ",") {
+				if prefix = strings.TrimSpace(prefix); prefix != "" {
+					opts.PrefetchPrefixes = append(opts.PrefetchPrefixes, prefix)
+				}
+			}
 		// deprecated:
 		case strings.ToLower(AkID):
 			opts.AkID = value
@@ -287,7 +316,9 @@ func parseOptions(ctx context.Context, cnfsGetter cnfsv1beta1.CNFSGetter, volOpt
 
 	case ossfpm.AuthTypeSTS:
 		// try to get default ECS worker role from metadata server
-		if opts.RoleName == "" {
+		if opts.RoleName == "" && metadata.IsExternalNode(m) {
+			klog.Warning("ECS worker role is not available on external node, please specify roleName or secretRef")
+		} else if opts.RoleName == "" {
 			workerRole, err := m.Get(metadata.RAMRoleName)
 			if err != nil {
 				klog.ErrorS(err, "get worker role name failed")
@@ -374,7 +405,8 @@ func setCNFSOptions(ctx context.Context, cnfsGetter cnfsv1beta1.CNFSGetter, opts
 func normalizeOSSURL(originURL string, m metadata.MetadataProvider) string {
 	url := originURL
 	region, _ := m.Get(metadata.RegionID)
-	if region != "" && utils.GetNetworkType() == "vpc" {
+	// internal endpoint is only reachable from Alibaba Cloud VPC
+	if region != "" && utils.GetNetworkType() == "vpc" && !metadata.IsExternalNode(m) {
 		url, _ = setNetworkType(url, region)
 	}
 	url, _ = setTransmissionProtocol(url)
@@ -514,6 +546,52 @@ func checkOssOptions(opt *ossfpm.Options, fpm *ossfpm.OSSFusePodManager) error {
 		}
 	}
 
+	if _, err := makeFuseResources(opt); err != nil {
+		return WrapOssError(ParamError, "%v", err)
+	}
+	return checkCacheOptions(opt)
+}
+
+func checkCacheOptions(opt *ossfpm.Options) error {
+	if opt.CacheMedium == "" {
+		if opt.CachePath != "" || opt.CacheSize != "" || opt.CacheEvictionPolicy != "" || len(opt.PrefetchPrefixes) > 0 {
+			return WrapOssError(ParamError, "cacheMedium is required when cache options are set")
+		}
+		return nil
+	}
+	if opt.FuseType != mounterutils.OssFs2Type {
+		return WrapOssError(ParamError, "local cache is only supported by %s", mounterutils.OssFs2Type)
+	}
+	switch opt.CacheMedium {
+	case fpm.CacheMediumHostPath:
+		if opt.CachePath == "" {
+			return WrapOssError(ParamError, "cachePath is required when cacheMedium is %s", opt.CacheMedium)
+		}
+		if err := checkCachePath(opt.CachePath, *cachePathRoots().Load()); err != nil {
+			return WrapOssError(ParamError, "%v", err)
+		}
+	case fpm.CacheMediumDisk, fpm.CacheMediumMemory:
+		if opt.CachePath != "" {
+			return WrapOssError(ParamError, "cachePath is only supported when cacheMedium is %s", fpm.CacheMediumHostPath)
+		}
+	default:
+		return WrapOssError(ParamError, "invalid cacheMedium %q, only support %s, %s and %s",
+			opt.CacheMedium, fpm.CacheMediumDisk, fpm.CacheMediumHostPath, fpm.CacheMediumMemory)
+	}
+	if opt.CacheSize != "" {
+		size, err := resource.ParseQuantity(opt.CacheSize)
+		if err != nil || size.Sign() <= 0 {
+			return WrapOssError(ParamError, "invalid cacheSize %q", opt.CacheSize)
+		}
+	} else if opt.CacheMedium == fpm.CacheMediumMemory {
+		return WrapOssError(ParamError, "cacheSize is required when cacheMedium is %s", opt.CacheMedium)
+	}
+	switch opt.CacheEvictionPolicy {
+	case "", ossfpm.CacheEvictionLRU, ossfpm.CacheEvictionFIFO:
+	default:
+		return WrapOssError(ParamError, "invalid cacheEvictionPolicy %q, only support %s and %s",
+			opt.CacheEvictionPolicy, ossfpm.CacheEvictionLRU, ossfpm.CacheEvictionFIFO)
+	}
 	return nil
 }
 
@@ -529,9 +607,58 @@ func makeAuthConfig(opt *ossfpm.Options, fpm *ossfpm.OSSFusePodManager, m metada
 	return authCfg, nil
 }
 func makePodTemplateConfig(opt *ossfpm.Options) *fpm.PodTemplateConfig {
-	return &fpm.PodTemplateConfig{
+	ptCfg := &fpm.PodTemplateConfig{
 		DnsPolicy: opt.DnsPolicy,
 	}
+	if opt.CacheMedium != "" {
+		ptCfg.Cache = &fpm.CacheConfig{
+			Medium:   opt.CacheMedium,
+			HostPath: opt.CachePath,
+		}
+		// already validated by checkCacheOptions
+		if size, err := resource.ParseQuantity(opt.CacheSize); err == nil {
+			ptCfg.Cache.SizeLimit = &size
+		}
+	}
+	// already validated by checkOssOptions
+	ptCfg.Resources, _ = makeFuseResources(opt)
+	return ptCfg
+}
+
+// makeFuseResources returns the fuse container resources overridden by volume attributes,
+// nil if none is set.
+func makeFuseResources(opt *ossfpm.Options) (*corev1.ResourceRequirements, error) {
+	res := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{},
+		Limits:   corev1.ResourceList{},
+	}
+	for _, item := range []struct {
+		key   string
+		value string
+		list  corev1.ResourceList
+		name  corev1.ResourceName
+	}{
+		{"fuseCpuRequest", opt.FuseCPURequest, res.Requests, corev1.ResourceCPU},
+		{"fuseCpuLimit", opt.FuseCPULimit, res.Limits, corev1.ResourceCPU},
+		{"fuseMemoryRequest", opt.FuseMemoryRequest, res.Requests, corev1.ResourceMemory},
+		{"fuseMemoryLimit", opt.FuseMemoryLimit, res.Limits, corev1.ResourceMemory},
+	} {
+		if item.value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(item.value)
+		if err != nil || quantity.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid %s %q", item.key, item.value)
+		}
+		item.list[item.name] = quantity
+	}
+	if len(res.Requests) == 0 && len(res.Limits) == 0 {
+		return nil, nil
+	}
+	if err := fpm.ValidateResources(&res, nil); err != nil {
+		return nil, err
+	}
+	return &res, nil
 }
 
 func makeMountOptions(opt *ossfpm.Options, fpm *ossfpm.OSSFusePodManager, m metadata.MetadataProvider, volumeCapability *csi.VolumeCapability) (
@@ -37,6 +37,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 type fakeCNFSGetter struct {
@@ -1158,6 +1159,8 @@ func Test_checkOssOptions(t *testing.T) {
 		mounterutils.OssFsType:  ossfpm.NewOSSFusePodManager(ossfs, nil),
 		mounterutils.OssFs2Type: ossfpm.NewOSSFusePodManager(ossfs2, nil),
 	}
+	utils.CSIPluginConfig.Update(map[string]string{cachePathRootsKey.Name: "/mnt/nvme"})
+	t.Cleanup(func() { utils.CSIPluginConfig.Update(nil) })
 
 	tests := []struct {
 		name    string
@@ -1269,6 +1272,100 @@ func Test_checkOssOptions(t *testing.T) {
 			},
 			errType: nil,
 		},
+		{
+			name: "cache on ossfs2",
+			opts: &ossfpm.Options{
+				URL:                 "1.1.1.1",
+				Bucket:              "aliyun",
+				Path:                "/path",
+				FuseType:            mounterutils.OssFs2Type,
+				CacheMedium:         fpm.CacheMediumHostPath,
+				CachePath:           "/mnt/nvme/cache",
+				CacheSize:           "100Gi",
+				CacheEvictionPolicy: ossfpm.CacheEvictionLRU,
+			},
+			errType: nil,
+		},
+		{
+			name: "cache on ossfs",
+			opts: &ossfpm.Options{
+				URL:         "1.1.1.1",
+				Bucket:      "aliyun",
+				Path:        "/path",
+				FuseType:    mounterutils.OssFsType,
+				CacheMedium: fpm.CacheMediumDisk,
+			},
+			errType: ParamError,
+		},
+		{
+			name: "cache options without medium",
+			opts: &ossfpm.Options{
+				URL:       "1.1.1.1",
+				Bucket:    "aliyun",
+				Path:      "/path",
+				FuseType:  mounterutils.OssFs2Type,
+				CacheSize: "10Gi",
+			},
+			errType: ParamError,
+		},
+		{
+			name: "memory cache without size",
+			opts: &ossfpm.Options{
+				URL:         "1.1.1.1",
+				Bucket:      "aliyun",
+				Path:        "/path",
+				FuseType:    mounterutils.OssFs2Type,
+				CacheMedium: fpm.CacheMediumMemory,
+			},
+			errType: ParamError,
+		},
+		{
+			name: "hostpath cache without path",
+			opts: &ossfpm.Options{
+				URL:         "1.1.1.1",
+				Bucket:      "aliyun",
+				Path:        "/path",
+				FuseType:    mounterutils.OssFs2Type,
+				CacheMedium: fpm.CacheMediumHostPath,
+			},
+			errType: ParamError,
+		},
+		{
+			name: "hostpath cache outside roots",
+			opts: &ossfpm.Options{
+				URL:         "1.1.1.1",
+				Bucket:      "aliyun",
+				Path:        "/path",
+				FuseType:    mounterutils.OssFs2Type,
+				CacheMedium: fpm.CacheMediumHostPath,
+				CachePath:   "/etc/kubernetes",
+			},
+			errType: ParamError,
+		},
+		{
+			name: "hostpath cache escaping roots",
+			opts: &ossfpm.Options{
+				URL:         "1.1.1.1",
+				Bucket:      "aliyun",
+				Path:        "/path",
+				FuseType:    mounterutils.OssFs2Type,
+				CacheMedium: fpm.CacheMediumHostPath,
+				CachePath:   "/mnt/nvme/../../etc",
+			},
+			errType: ParamError,
+		},
+		{
+			name: "invalid eviction policy",
+			opts: &ossfpm.Options{
+				URL:                 "1.1.1.1",
+				Bucket:              "aliyun",
+				Path:                "/path",
+				FuseType:            mounterutils.OssFs2Type,
+				CacheMedium:         fpm.CacheMediumDisk,
+				CacheEvictionPolicy: "random",
+			},
+			errType: ParamError,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1395,6 +1492,51 @@ func TestMakePodTemplateConfig(t *testing.T) {
 	}))
 
 	assert.Equal(t, &fpm.PodTemplateConfig{}, makePodTemplateConfig(&ossfpm.Options{}))
+
+	size := resource.MustParse("1Gi")
+	assert.Equal(t, &fpm.PodTemplateConfig{
+		Cache: &fpm.CacheConfig{
+			Medium:    fpm.CacheMediumMemory,
+			SizeLimit: &size,
+		},
+	}, makePodTemplateConfig(&ossfpm.Options{
+		CacheMedium: fpm.CacheMediumMemory,
+		CacheSize:   "1Gi",
+	}))
+
+	assert.Equal(t, &fpm.PodTemplateConfig{
+		Resources: &corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+			Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+		},
+	}, makePodTemplateConfig(&ossfpm.Options{
+		FuseMemoryRequest: "1Gi",
+		FuseCPULimit:      "2",
+	}))
+}
+
+func TestMakeFuseResources(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ossfpm.Options
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "valid", opts: ossfpm.Options{FuseCPURequest: "500m", FuseCPULimit: "1", FuseMemoryRequest: "1Gi"}},
+		{name: "invalid quantity", opts: ossfpm.Options{FuseMemoryLimit: "1GG"}, wantErr: true},
+		{name: "negative", opts: ossfpm.Options{FuseCPURequest: "-1"}, wantErr: true},
+		{name: "request exceeds limit", opts: ossfpm.Options{FuseMemoryRequest: "2Gi", FuseMemoryLimit: "1Gi"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := makeFuseResources(&tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
 }
 
 func TestGetDirectAssignedValue(t *testing.T) {
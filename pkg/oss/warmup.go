@@ -0,0 +1,331 @@
+//go:build !windows
+
+package oss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	ossfpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager/oss"
+	mounterutils "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/utils"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// WarmupPrefixesAnnotation on PVC lists comma-separated OSS prefixes, relative to the volume path,
+	// to be loaded into the fuse cache on every node consuming the PVC.
+	WarmupPrefixesAnnotation = "csi.alibabacloud.com/oss-warmup-prefixes"
+	// WarmupStatusAnnotation on PVC is maintained by the node plugins,
+	// it holds a JSON object of node name to WarmupStatus.
+	WarmupStatusAnnotation = "csi.alibabacloud.com/oss-warmup-status"
+
+	maxWarmupStatusRetries = 5
+	warmupReportInterval   = 30 * time.Second
+)
+
+type WarmupPhase = mounterutils.WarmupPhase
+
+const (
+	// WarmupScheduled means the volume is staged on the node with the prefixes,
+	// the fuse pod starts loading them right after the volume is mounted.
+	WarmupScheduled WarmupPhase = "Scheduled"
+	// WarmupUnsupported means the volume has no local cache configured.
+	WarmupUnsupported WarmupPhase = "Unsupported"
+	// Running, Completed and Failed are reported by the fuse pod through the node plugin.
+	WarmupRunning   = mounterutils.WarmupRunning
+	WarmupCompleted = mounterutils.WarmupCompleted
+	WarmupFailed    = mounterutils.WarmupFailed
+)
+
+// WarmupStatus of a node in WarmupStatusAnnotation.
+type WarmupStatus struct {
+	Phase      WarmupPhase `json:"phase"`
+	Files      int64       `json:"files,omitempty"`
+	TotalFiles int64       `json:"totalFiles,omitempty"`
+	Bytes      int64       `json:"bytes,omitempty"`
+	TotalBytes int64       `json:"totalBytes,omitempty"`
+	Message    string      `json:"message,omitempty"`
+}
+
+func parseWarmupPrefixes(value string) (prefixes []string) {
+	for prefix := range strings.SplitSeq(value, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return
+}
+
+// getWarmupPVC returns the PVC bound to the volume if it requests a warm-up.
+// OSS volumes use the PV name as volume handle, so the PV can be fetched directly.
+func getWarmupPVC(ctx context.Context, client kubernetes.Interface, volumeID string) (*corev1.PersistentVolumeClaim, error) {
+	if client == nil {
+		return nil, nil
+	}
+	pv, err := client.CoreV1().PersistentVolumes().Get(ctx, volumeID, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ref := pv.Spec.ClaimRef
+	if ref == nil || pv.Spec.CSI == nil || pv.Spec.CSI.VolumeHandle != volumeID {
+		return nil, nil
+	}
+	pvc, err := client.CoreV1().PersistentVolumeClaims(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if pvc.Annotations[WarmupPrefixesAnnotation] == "" {
+		return nil, nil
+	}
+	return pvc, nil
+}
+
+// updateWarmupStatus sets the status of the node in warm-up status, nil status removes the node.
+func updateWarmupStatus(ctx context.Context, client kubernetes.Interface, namespace, name, nodeName string, status *WarmupStatus) (err error) {
+	// retry on conflict, as the PVC may be published to multiple nodes concurrently
+	for range maxWarmupStatusRetries {
+		err = tryUpdateWarmupStatus(ctx, client, namespace, name, nodeName, status)
+		if !apierrors.IsConflict(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func tryUpdateWarmupStatus(ctx context.Context, client kubernetes.Interface, namespace, name, nodeName string, status *WarmupStatus) error {
+	pvc, err := client.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	statuses := map[string]WarmupStatus{}
+	if s := pvc.Annotations[WarmupStatusAnnotation]; s != "" {
+		if err := json.Unmarshal([]byte(s), &statuses); err != nil {
+			klog.ErrorS(err, "ignoring invalid warm-up status", "pvc", klog.KObj(pvc))
+			statuses = map[string]WarmupStatus{}
+		}
+	}
+	current, ok := statuses[nodeName]
+	if status == nil {
+		if !ok {
+			return nil
+		}
+		delete(statuses, nodeName)
+	} else {
+		if ok && current == *status {
+			return nil
+		}
+		statuses[nodeName] = *status
+	}
+	data, err := json.Marshal(statuses)
+	if err != nil {
+		return fmt.Errorf("marshal warm-up status: %w", err)
+	}
+	// the resourceVersion makes the patch fail with conflict if another node changed the status meanwhile
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"resourceVersion": pvc.ResourceVersion,
+			"annotations":     map[string]string{WarmupStatusAnnotation: string(data)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// warmupReporter schedules the warm-up of the volumes staged on this node,
+// and copies the progress written by the fuse pods in the metrics path of the volumes to the PVCs.
+type warmupReporter struct {
+	client   kubernetes.Interface
+	nodeName string
+
+	mu        sync.Mutex
+	scheduled map[string][]string // volume ID to prefixes
+	volumes   map[string]string   // volume ID to metrics path
+	reported  map[string]time.Time
+}
+
+func newWarmupReporter(client kubernetes.Interface, nodeName string) *warmupReporter {
+	return &warmupReporter{
+		client:    client,
+		nodeName:  nodeName,
+		scheduled: map[string][]string{},
+		volumes:   map[string]string{},
+		reported:  map[string]time.Time{},
+	}
+}
+
+// schedule looks up the warm-up prefixes on the PVC bound to the volume when it is staged on this node,
+// so that static volumes, and volumes already attached, are warmed up too.
+// Warm-up is best effort, failures are logged and never block staging the volume.
+func (r *warmupReporter) schedule(ctx context.Context, opts *ossfpm.Options, volumeID string) {
+	if r == nil {
+		return
+	}
+	pvc, err := getWarmupPVC(ctx, r.client, volumeID)
+	if err != nil {
+		klog.ErrorS(err, "failed to get PVC for warm-up", "volumeID", volumeID)
+		return
+	}
+	if pvc == nil {
+		return
+	}
+	prefixes := parseWarmupPrefixes(pvc.Annotations[WarmupPrefixesAnnotation])
+	if len(prefixes) == 0 {
+		return
+	}
+
+	phase := WarmupScheduled
+	if opts.FuseType != mounterutils.OssFs2Type || opts.CacheMedium == "" {
+		klog.InfoS("skip warm-up as local cache is not configured", "volumeID", volumeID, "fuseType", opts.FuseType)
+		phase = WarmupUnsupported
+	} else {
+		r.mu.Lock()
+		r.scheduled[volumeID] = prefixes
+		r.mu.Unlock()
+	}
+
+	if err := updateWarmupStatus(ctx, r.client, pvc.Namespace, pvc.Name, r.nodeName, &WarmupStatus{Phase: phase}); err != nil {
+		klog.ErrorS(err, "failed to update warm-up status", "pvc", klog.KObj(pvc), "node", r.nodeName)
+	}
+}
+
+// prefixes returns the prefixes scheduled when the volume was staged.
+func (r *warmupReporter) prefixes(volumeID string) []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.scheduled[volumeID]
+}
+
+// unschedule stops reporting, and drops this node from the warm-up status after the volume is unstaged.
+func (r *warmupReporter) unschedule(ctx context.Context, volumeID string) {
+	if r == nil {
+		return
+	}
+	r.untrack(volumeID)
+	r.mu.Lock()
+	delete(r.scheduled, volumeID)
+	r.mu.Unlock()
+
+	pvc, err := getWarmupPVC(ctx, r.client, volumeID)
+	if err != nil {
+		klog.ErrorS(err, "failed to get PVC for warm-up", "volumeID", volumeID)
+		return
+	}
+	if pvc == nil || pvc.Annotations[WarmupStatusAnnotation] == "" {
+		return
+	}
+	if err := updateWarmupStatus(ctx, r.client, pvc.Namespace, pvc.Name, r.nodeName, nil); err != nil {
+		klog.ErrorS(err, "failed to update warm-up status", "pvc", klog.KObj(pvc), "node", r.nodeName)
+	}
+}
+
+// track starts reporting the progress of a volume mounted with warm-up prefixes.
+func (r *warmupReporter) track(volumeID, metricsPath string) {
+	if r == nil || metricsPath == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.volumes[volumeID] = metricsPath
+	delete(r.reported, volumeID)
+}
+
+func (r *warmupReporter) untrack(volumeID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.volumes, volumeID)
+	delete(r.reported, volumeID)
+}
+
+func (r *warmupReporter) run(ctx context.Context) {
+	ticker := time.NewTicker(warmupReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.report(ctx)
+		}
+	}
+}
+
+func (r *warmupReporter) report(ctx context.Context) {
+	r.mu.Lock()
+	volumes := maps.Clone(r.volumes)
+	r.mu.Unlock()
+
+	for volumeID, metricsPath := range volumes {
+		if err := r.reportVolume(ctx, volumeID, metricsPath); err != nil {
+			klog.ErrorS(err, "failed to report warm-up progress", "volumeID", volumeID)
+		}
+	}
+}
+
+func (r *warmupReporter) reportVolume(ctx context.Context, volumeID, metricsPath string) error {
+	progress, err := mounterutils.ReadWarmupProgress(metricsPath)
+	if err != nil || progress == nil {
+		return err
+	}
+	r.mu.Lock()
+	reported := r.reported[volumeID]
+	r.mu.Unlock()
+	if progress.UpdateTime.Equal(reported) {
+		return nil
+	}
+
+	pvc, err := getWarmupPVC(ctx, r.client, volumeID)
+	if err != nil {
+		return err
+	}
+	if pvc != nil {
+		err = updateWarmupStatus(ctx, r.client, pvc.Namespace, pvc.Name, r.nodeName, &WarmupStatus{
+			Phase:      progress.Phase,
+			Files:      progress.Files,
+			TotalFiles: progress.TotalFiles,
+			Bytes:      progress.Bytes,
+			TotalBytes: progress.TotalBytes,
+			Message:    progress.Message,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.volumes[volumeID]; !ok {
+		return nil
+	}
+	if pvc == nil || progress.Phase == WarmupCompleted || progress.Phase == WarmupFailed {
+		delete(r.volumes, volumeID)
+		delete(r.reported, volumeID)
+	} else {
+		r.reported[volumeID] = progress.UpdateTime
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+//go:build !windows
+
+package oss
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ossfpm "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/fuse_pod_manager/oss"
+	mounterutils "github.com/kubernetes-sigs/alibaba-cloud-csi-driver/pkg/mounter/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseWarmupPrefixes(t *testing.T) {
+	assert.Nil(t, parseWarmupPrefixes(""))
+	assert.Equal(t, []string{"a/", "b/c"}, parseWarmupPrefixes(" a/, ,b/c,"))
+}
+
+func newWarmupTestObjects(prefixes string) (*corev1.PersistentVolume, *corev1.PersistentVolumeClaim) {
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-oss"},
+		Spec: corev1.PersistentVolumeSpec{
+			ClaimRef: &corev1.ObjectReference{Namespace: "default", Name: "pvc-oss"},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{VolumeHandle: "pv-oss"},
+			},
+		},
+	}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "pvc-oss",
+			Annotations: map[string]string{WarmupPrefixesAnnotation: prefixes},
+		},
+	}
+	return pv, pvc
+}
+
+func TestScheduleWarmup(t *testing.T) {
+	tests := []struct {
+		name             string
+		prefixes         string
+		opts             ossfpm.Options
+		expectedPrefixes []string
+		expectedStatus   string
+	}{
+		{
+			name:             "ossfs2 with cache",
+			prefixes:         "models/, data/",
+			opts:             ossfpm.Options{FuseType: mounterutils.OssFs2Type, CacheMedium: "disk"},
+			expectedPrefixes: []string{"models/", "data/"},
+			expectedStatus:   `{"node-1":{"phase":"Scheduled"}}`,
+		},
+		{
+			name:           "ossfs2 without cache",
+			prefixes:       "models/",
+			opts:           ossfpm.Options{FuseType: mounterutils.OssFs2Type},
+			expectedStatus: `{"node-1":{"phase":"Unsupported"}}`,
+		},
+		{
+			name:           "ossfs",
+			prefixes:       "models/",
+			opts:           ossfpm.Options{FuseType: mounterutils.OssFsType, CacheMedium: "disk"},
+			expectedStatus: `{"node-1":{"phase":"Unsupported"}}`,
+		},
+		{
+			name: "no annotation",
+			opts: ossfpm.Options{FuseType: mounterutils.OssFs2Type, CacheMedium: "disk"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pv, pvc := newWarmupTestObjects(tt.prefixes)
+			client := fake.NewSimpleClientset(pv, pvc)
+			r := newWarmupReporter(client, "node-1")
+			ctx := context.Background()
+
+			r.schedule(ctx, &tt.opts, "pv-oss")
+			assert.Equal(t, tt.expectedPrefixes, r.prefixes("pv-oss"))
+
+			got, err := client.CoreV1().PersistentVolumeClaims("default").Get(ctx, "pvc-oss", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, got.Annotations[WarmupStatusAnnotation])
+			for _, action := range client.Actions() {
+				assert.NotEqual(t, "update", action.GetVerb(), "status should be patched")
+			}
+
+			r.unschedule(ctx, "pv-oss")
+			assert.Nil(t, r.prefixes("pv-oss"))
+			got, err = client.CoreV1().PersistentVolumeClaims("default").Get(ctx, "pvc-oss", metav1.GetOptions{})
+			require.NoError(t, err)
+			if tt.expectedStatus != "" {
+				assert.Equal(t, "{}", got.Annotations[WarmupStatusAnnotation])
+			}
+		})
+	}
+}
+
+func TestWarmupReporter(t *testing.T) {
+	pv, pvc := newWarmupTestObjects("models/")
+	client := fake.NewSimpleClientset(pv, pvc)
+	ctx := context.Background()
+	metricsPath := t.TempDir()
+	getStatus := func() string {
+		got, err := client.CoreV1().PersistentVolumeClaims("default").Get(ctx, "pvc-oss", metav1.GetOptions{})
+		require.NoError(t, err)
+		return got.Annotations[WarmupStatusAnnotation]
+	}
+
+	r := newWarmupReporter(client, "node-1")
+	r.track("pv-oss", metricsPath)
+	r.report(ctx)
+	assert.Empty(t, getStatus(), "nothing reported by fuse pod yet")
+
+	now := time.Now()
+	require.NoError(t, mounterutils.WriteWarmupProgress(metricsPath, &mounterutils.WarmupProgress{
+		Phase: mounterutils.WarmupRunning, Files: 1, TotalFiles: 4, Bytes: 100, TotalBytes: 400, UpdateTime: now,
+	}))
+	r.report(ctx)
+	assert.JSONEq(t, `{"node-1":{"phase":"Running","files":1,"totalFiles":4,"bytes":100,"totalBytes":400}}`, getStatus())
+	assert.Contains(t, r.volumes, "pv-oss")
+
+	require.NoError(t, mounterutils.WriteWarmupProgress(metricsPath, &mounterutils.WarmupProgress{
+		Phase: mounterutils.WarmupCompleted, Files: 4, TotalFiles: 4, Bytes: 400, TotalBytes: 400, UpdateTime: now.Add(time.Minute),
+	}))
+	r.report(ctx)
+	assert.JSONEq(t, `{"node-1":{"phase":"Completed","files":4,"totalFiles":4,"bytes":400,"totalBytes":400}}`, getStatus())
+	assert.NotContains(t, r.volumes, "pv-oss", "should stop tracking after completed")
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"k8s.io/klog/v2"
+)
+
+const defaultServiceName = "alibaba-cloud-csi-driver"
+
+// InitFromEnv enables tracing if an OTLP endpoint is configured with the standard environment variables:
+//
+//   - OTEL_EXPORTER_OTLP_TRACES_ENDPOINT: full URL to send spans to, or
+//   - OTEL_EXPORTER_OTLP_ENDPOINT: base URL, "/v1/traces" is appended
+//   - OTEL_EXPORTER_OTLP_HEADERS: extra HTTP headers, e.g. "key1=value1,key2=value2"
+//   - OTEL_SERVICE_NAME: defaults to alibaba-cloud-csi-driver
+//   - OTEL_TRACES_SAMPLER_ARG: the ratio of root spans to sample, defaults to 1,
+//     unless OTEL_TRACES_SAMPLER selects another sampler
+//
+// Spans are exported with OTLP/HTTP in background until ctx is done.
+func InitFromEnv(ctx context.Context, attrs ...attribute.KeyValue) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" {
+		return
+	}
+
+	exp, err := otlptracehttp.New(ctx)
+	if err != nil {
+		klog.ErrorS(err, "failed to create OTLP exporter, tracing disabled")
+		return
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(append([]attribute.KeyValue{attribute.String("service.name", defaultServiceName)}, attrs...)...),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		// partial resource is still usable
+		klog.ErrorS(err, "failed to detect tracing resource")
+	}
+
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithBatcher(exp), sdktrace.WithResource(res)}
+	ratio := 1.0
+	if _, ok := os.LookupEnv("OTEL_TRACES_SAMPLER"); !ok {
+		if arg := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); arg != "" {
+			r, err := strconv.ParseFloat(arg, 64)
+			if err != nil || r < 0 || r > 1 {
+				klog.ErrorS(err, "invalid OTEL_TRACES_SAMPLER_ARG, using 1", "value", arg)
+			} else {
+				ratio = r
+			}
+		}
+		opts = append(opts, sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))))
+	}
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	klog.InfoS("OpenTelemetry tracing enabled", "ratio", ratio)
+
+	go func() {
+		<-ctx.Done()
+		// ctx is done, flush the queued spans with a fresh one
+		if err := tp.Shutdown(context.Background()); err != nil {
+			klog.ErrorS(err, "failed to flush spans")
+		}
+	}()
+}
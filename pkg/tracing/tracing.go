@@ -0,0 +1,53 @@
+// Package tracing provides optional OpenTelemetry tracing for CSI requests.
+//
+// Tracing is disabled unless an OTLP endpoint is configured by environment variables,
+// see InitFromEnv. When disabled, all spans are non-recording and almost free.
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/kubernetes-sigs/alibaba-cloud-csi-driver"
+
+const (
+	// VolumeIDKey is the CSI volume ID the span is working on.
+	VolumeIDKey = attribute.Key("csi.volume_id")
+	// RequestIDKey is the request ID returned by Alibaba Cloud OpenAPI.
+	RequestIDKey = attribute.Key("alibabacloud.request_id")
+	// ResourceIDKey is the cloud resource (e.g. disk or snapshot) the span is working on.
+	ResourceIDKey = attribute.Key("alibabacloud.resource_id")
+)
+
+// tracer returns the tracer of the global provider set by otel.SetTracerProvider,
+// which is a no-op one until InitFromEnv enables tracing.
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start creates a span as a child of the span in ctx, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err, if any, then ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Record creates a span that started at start and ends now, as a child of the span in ctx.
+// It is useful for recording waits only if they turn out to be long.
+func Record(ctx context.Context, name string, start time.Time, attrs ...attribute.KeyValue) {
+	_, span := tracer().Start(ctx, name, trace.WithTimestamp(start), trace.WithAttributes(attrs...))
+	span.End()
+}
@@ -0,0 +1,151 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func useRecorder(t *testing.T, ratio float64) *tracetest.SpanRecorder {
+	r := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(r),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)))))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	return r
+}
+
+func withTraceparent(traceparent string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", traceparent))
+}
+
+func TestDisabled(t *testing.T) {
+	ctx, span := Start(context.Background(), "test")
+	assert.False(t, span.IsRecording())
+	End(span, errors.New("ignored"))
+	assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	r := useRecorder(t, 1)
+	ctx := withTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/ControllerPublishVolume"}
+	req := &csi.ControllerPublishVolumeRequest{VolumeId: "d-1"}
+
+	_, err := UnaryServerInterceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+		_, span := Start(ctx, "child", RequestIDKey.String("req-1"))
+		End(span, errors.New("failed"))
+		return nil, errors.New("failed")
+	})
+	require.Error(t, err)
+
+	spans := r.Ended()
+	require.Len(t, spans, 2)
+	child, server := spans[0], spans[1]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.True(t, server.Parent().IsRemote())
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Contains(t, server.Attributes(), VolumeIDKey.String("d-1"))
+	assert.Equal(t, codes.Error, server.Status().Code)
+
+	assert.Equal(t, server.SpanContext().TraceID(), child.SpanContext().TraceID())
+	assert.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+	assert.Equal(t, "failed", child.Status().Description)
+	require.Len(t, child.Events(), 1)
+	assert.Equal(t, "exception", child.Events()[0].Name)
+}
+
+func TestUnaryServerInterceptorInvalidTraceparent(t *testing.T) {
+	r := useRecorder(t, 1)
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Node/NodeStageVolume"}
+	for _, invalid := range []string{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	} {
+		_, err := UnaryServerInterceptor(withTraceparent(invalid), nil, info, func(ctx context.Context, req any) (any, error) {
+			return nil, nil
+		})
+		require.NoError(t, err)
+	}
+	for _, s := range r.Ended() {
+		assert.False(t, s.Parent().IsValid(), "root span expected")
+	}
+}
+
+func TestSampling(t *testing.T) {
+	r := useRecorder(t, 0)
+	_, span := Start(context.Background(), "not sampled")
+	assert.False(t, span.IsRecording())
+	span.End()
+
+	// parent decision is respected
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Node/NodeStageVolume"}
+	_, err := UnaryServerInterceptor(withTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"), nil, info,
+		func(ctx context.Context, req any) (any, error) {
+			_, span := Start(ctx, "sampled")
+			assert.True(t, span.IsRecording())
+			span.End()
+			span.End() // ending twice is no-op
+			return nil, nil
+		})
+	require.NoError(t, err)
+
+	spans := r.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "sampled", spans[0].Name())
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var mu sync.Mutex
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", server.URL+"/v1/traces")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authentication=token")
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	InitFromEnv(ctx)
+	tp := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+
+	sctx, parent := Start(context.Background(), "parent")
+	_, child := Start(sctx, "child", RequestIDKey.String("req-1"))
+	End(child, errors.New("failed"))
+	parent.End()
+
+	require.NoError(t, tp.Shutdown(context.Background())) // flushes queued spans
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "token", header.Get("Authentication"))
+	assert.Equal(t, "application/x-protobuf", header.Get("Content-Type"))
+	assert.Contains(t, string(body), "req-1")
+	assert.Contains(t, string(body), defaultServiceName)
+}
@@ -30,13 +30,22 @@ func (c *Config) GetBool(configKey, env string, defaultValue bool) bool {
 		return defaultValue
 	}
 
-	switch strings.ToLower(strValue) {
+	b, err := ParseBool(strValue)
+	if err != nil {
+		panic(fmt.Sprintf("invalid bool value: %s for %s", strValue, configKey))
+	}
+	return b
+}
+
+// ParseBool accepts true/false, yes/no and enable/disable, case-insensitively.
+func ParseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
 	case "true", "yes", "enable":
-		return true
+		return true, nil
 	case "false", "no", "disable":
-		return false
+		return false, nil
 	default:
-		panic(fmt.Sprintf("invalid bool value: %s for %s", strValue, configKey))
+		return false, fmt.Errorf("invalid bool value: %s", s)
 	}
 }
 
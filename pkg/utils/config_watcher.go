@@ -0,0 +1,250 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	informercorev1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	CSIPluginConfigMapNamespace = "kube-system"
+	CSIPluginConfigMapName      = "csi-plugin"
+)
+
+// CSIPluginConfig follows the csi-plugin ConfigMap after it is started by main.
+var CSIPluginConfig = NewWatchedConfig()
+
+// ConfigKey is a key in the csi-plugin ConfigMap that can be changed at runtime.
+type ConfigKey struct {
+	Name    string
+	Env     string
+	Default string
+	// Validate rejects invalid values, so that a typo does not take effect. nil accepts any value.
+	Validate func(string) error
+}
+
+// Get returns the value of k in c. An invalid value is logged and the default is returned,
+// so that a typo does not break the startup.
+func (k ConfigKey) Get(c Config) string {
+	v := c.Get(k.Name, k.Env, k.Default)
+	if k.Validate != nil && v != k.Default {
+		if err := k.Validate(v); err != nil {
+			klog.ErrorS(err, "Invalid config, use the default", "key", k.Name, "env", k.Env, "value", v, "default", k.Default)
+			return k.Default
+		}
+	}
+	return v
+}
+
+// GetInt is Get for keys validated by ValidateInt.
+func (k ConfigKey) GetInt(c Config) int {
+	i, _ := strconv.Atoi(k.Get(c))
+	return i
+}
+
+func ValidateInt(s string) error {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	if i < 0 {
+		return fmt.Errorf("%d is negative", i)
+	}
+	return nil
+}
+
+func ValidateBool(s string) error {
+	_, err := ParseBool(s)
+	return err
+}
+
+// WatchedConfig holds the latest valid content of a ConfigMap,
+// and notifies subscribers when it changes.
+type WatchedConfig struct {
+	mu       sync.RWMutex
+	data     map[string]string
+	keys     map[string]ConfigKey
+	rejected map[string]string
+
+	// subscribers are called one by one, never concurrently.
+	subscribeLock sync.Mutex
+	subscribers   []func(Config)
+}
+
+func NewWatchedConfig() *WatchedConfig {
+	return &WatchedConfig{
+		data:     map[string]string{},
+		keys:     map[string]ConfigKey{},
+		rejected: map[string]string{},
+	}
+}
+
+// Current returns a snapshot of the config.
+func (w *WatchedConfig) Current() Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return Config{ConfigMap: maps.Clone(w.data)}
+}
+
+// RegisterKeys declares keys that can be reloaded. Their current values are validated right away.
+func (w *WatchedConfig) RegisterKeys(keys ...ConfigKey) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, k := range keys {
+		w.keys[k.Name] = k
+		if v, ok := w.data[k.Name]; ok {
+			if err := w.validate(k.Name, v); err != nil {
+				delete(w.data, k.Name)
+			}
+		}
+	}
+}
+
+// Subscribe calls f with the new config every time it changes.
+// f should check whether the keys it cares about have changed.
+func (w *WatchedConfig) Subscribe(f func(Config)) {
+	w.subscribeLock.Lock()
+	defer w.subscribeLock.Unlock()
+	w.subscribers = append(w.subscribers, f)
+}
+
+// validate must be called with w.mu held.
+func (w *WatchedConfig) validate(name, value string) error {
+	k, ok := w.keys[name]
+	if !ok || k.Validate == nil {
+		delete(w.rejected, name)
+		return nil
+	}
+	if err := k.Validate(value); err != nil {
+		klog.ErrorS(err, "Invalid value in csi-plugin ConfigMap, ignored", "key", name, "value", value)
+		w.rejected[name] = fmt.Sprintf("%q: %v", value, err)
+		return err
+	}
+	delete(w.rejected, name)
+	return nil
+}
+
+// Update replaces the config with data. Invalid values of registered keys are rejected,
+// and the previous value is kept.
+func (w *WatchedConfig) Update(data map[string]string) {
+	w.subscribeLock.Lock()
+	defer w.subscribeLock.Unlock()
+
+	w.mu.Lock()
+	newData := make(map[string]string, len(data))
+	for k, v := range data {
+		if err := w.validate(k, v); err != nil {
+			if old, ok := w.data[k]; ok {
+				newData[k] = old
+			}
+			continue
+		}
+		newData[k] = v
+	}
+	for k := range w.rejected {
+		if _, ok := data[k]; !ok {
+			delete(w.rejected, k)
+		}
+	}
+	changed := !maps.Equal(w.data, newData)
+	if changed {
+		for k := range newData {
+			if w.data[k] != newData[k] {
+				klog.InfoS("csi-plugin config changed", "key", k, "value", newData[k])
+			}
+		}
+		for k := range w.data {
+			if _, ok := newData[k]; !ok {
+				klog.InfoS("csi-plugin config removed", "key", k)
+			}
+		}
+		w.data = newData
+	}
+	w.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	cfg := Config{ConfigMap: maps.Clone(newData)}
+	for _, f := range w.subscribers {
+		f(cfg)
+	}
+}
+
+// Run watches the csi-plugin ConfigMap until ctx is done.
+// If the ConfigMap is deleted, the last config is kept.
+func (w *WatchedConfig) Run(ctx context.Context, client kubernetes.Interface) {
+	informer := informercorev1.NewFilteredConfigMapInformer(client, CSIPluginConfigMapNamespace, 0, nil, func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", CSIPluginConfigMapName).String()
+	})
+	update := func(obj any) {
+		if cm, ok := obj.(*corev1.ConfigMap); ok {
+			w.Update(cm.Data)
+		}
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    update,
+		UpdateFunc: func(_, obj any) { update(obj) },
+		DeleteFunc: func(any) {
+			klog.Warning("csi-plugin ConfigMap deleted, keep using the last config")
+		},
+	})
+	if err != nil {
+		klog.ErrorS(err, "failed to watch csi-plugin ConfigMap")
+		return
+	}
+	informer.Run(ctx.Done())
+}
+
+type effectiveValue struct {
+	Key    string `json:"key"`
+	Env    string `json:"env,omitempty"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// ServeHTTP writes the effective value of all registered keys as JSON,
+// and all other keys present in the ConfigMap.
+func (w *WatchedConfig) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.mu.RLock()
+	var values []effectiveValue
+	for _, name := range slices.Sorted(maps.Keys(w.keys)) {
+		k := w.keys[name]
+		v := effectiveValue{Key: name, Env: k.Env, Value: k.Default, Source: "default"}
+		if value, ok := os.LookupEnv(k.Env); ok && k.Env != "" {
+			v.Value, v.Source = value, "env"
+		} else if value, ok := w.data[name]; ok {
+			v.Value, v.Source = value, "configmap"
+		}
+		values = append(values, v)
+	}
+	for _, name := range slices.Sorted(maps.Keys(w.data)) {
+		if _, ok := w.keys[name]; !ok {
+			values = append(values, effectiveValue{Key: name, Value: w.data[name], Source: "configmap"})
+		}
+	}
+	out := struct {
+		Values   []effectiveValue  `json:"values"`
+		Rejected map[string]string `json:"rejected,omitempty"`
+	}{values, maps.Clone(w.rejected)}
+	w.mu.RUnlock()
+
+	rw.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(rw)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(out)
+}
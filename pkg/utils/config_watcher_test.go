@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var testConcurrencyKey = ConfigKey{Name: "test-concurrency", Env: "TEST_CONCURRENCY", Default: "5", Validate: ValidateInt}
+
+func TestWatchedConfigUpdate(t *testing.T) {
+	w := NewWatchedConfig()
+	w.RegisterKeys(testConcurrencyKey)
+
+	var received []Config
+	w.Subscribe(func(c Config) { received = append(received, c) })
+
+	w.Update(map[string]string{"test-concurrency": "10", "other": "a"})
+	cfg := w.Current()
+	assert.Equal(t, 10, cfg.GetInt("test-concurrency", "", 5))
+	require.Len(t, received, 1)
+
+	// no change, no notification
+	w.Update(map[string]string{"test-concurrency": "10", "other": "a"})
+	assert.Len(t, received, 1)
+
+	// invalid value is rejected, previous value kept
+	w.Update(map[string]string{"test-concurrency": "ten", "other": "b"})
+	assert.Equal(t, "10", w.Current().ConfigMap["test-concurrency"])
+	assert.Equal(t, "b", w.Current().ConfigMap["other"])
+	assert.Contains(t, w.rejected, "test-concurrency")
+	require.Len(t, received, 2)
+
+	// removing the key falls back to default
+	w.Update(map[string]string{"other": "b"})
+	cfg = w.Current()
+	assert.Equal(t, 5, cfg.GetInt("test-concurrency", "", 5))
+	assert.Empty(t, w.rejected)
+	assert.Len(t, received, 3)
+}
+
+func TestWatchedConfigRegisterInvalid(t *testing.T) {
+	w := NewWatchedConfig()
+	w.Update(map[string]string{"test-concurrency": "-1"})
+	w.RegisterKeys(testConcurrencyKey)
+	assert.NotContains(t, w.Current().ConfigMap, "test-concurrency")
+}
+
+func TestConfigKeyGetInt(t *testing.T) {
+	assert.Equal(t, 5, testConcurrencyKey.GetInt(Config{}))
+	assert.Equal(t, 10, testConcurrencyKey.GetInt(Config{ConfigMap: map[string]string{"test-concurrency": "10"}}))
+	// invalid initial value does not panic
+	assert.Equal(t, 5, testConcurrencyKey.GetInt(Config{ConfigMap: map[string]string{"test-concurrency": "ten"}}))
+
+	t.Setenv("TEST_CONCURRENCY", "-1")
+	assert.Equal(t, 5, testConcurrencyKey.GetInt(Config{}))
+}
+
+func TestWatchedConfigServeHTTP(t *testing.T) {
+	t.Setenv("TEST_ENV_KEY", "from-env")
+	w := NewWatchedConfig()
+	w.RegisterKeys(testConcurrencyKey, ConfigKey{Name: "test-env-key", Env: "TEST_ENV_KEY", Default: "x"})
+	w.Update(map[string]string{"test-env-key": "from-cm", "other": "a"})
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/config", nil))
+
+	var out struct {
+		Values []effectiveValue
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	assert.Equal(t, []effectiveValue{
+		{Key: "test-concurrency", Env: "TEST_CONCURRENCY", Value: "5", Source: "default"},
+		{Key: "test-env-key", Env: "TEST_ENV_KEY", Value: "from-env", Source: "env"},
+		{Key: "other", Value: "a", Source: "configmap"},
+	}, out.Values)
+}
+
+func TestWatchedConfigRun(t *testing.T) {
+	client := fake.NewClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: CSIPluginConfigMapNamespace, Name: CSIPluginConfigMapName},
+		Data:       map[string]string{"test-concurrency": "1"},
+	})
+	w := NewWatchedConfig()
+	changed := make(chan Config, 10)
+	w.Subscribe(func(c Config) { changed <- c })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx, client)
+
+	select {
+	case c := <-changed:
+		assert.Equal(t, "1", c.ConfigMap["test-concurrency"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for initial config")
+	}
+
+	_, err := client.CoreV1().ConfigMaps(CSIPluginConfigMapNamespace).Update(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: CSIPluginConfigMapNamespace, Name: CSIPluginConfigMapName},
+		Data:       map[string]string{"test-concurrency": "2"},
+	}, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	select {
+	case c := <-changed:
+		assert.Equal(t, "2", c.ConfigMap["test-concurrency"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for config change")
+	}
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+// LeaderElectionNamespace holds the Leases of background controllers of the plugin.
+const LeaderElectionNamespace = CSIPluginConfigMapNamespace
+
+// same as the defaults of the CSI sidecars
+const (
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewDeadline = 10 * time.Second
+	defaultRetryPeriod   = 5 * time.Second
+)
+
+// NewLeaderElectionConfig returns the config for leaderelection.RunOrDie to run fn only while this replica holds the Lease,
+// so that a background controller runs on a single replica of the controller plugin.
+// The ctx of fn is cancelled when the Lease is lost, and the Lease is released when the ctx of RunOrDie is done.
+func NewLeaderElectionConfig(client kubernetes.Interface, lease string, fn func(ctx context.Context)) (leaderelection.LeaderElectionConfig, error) {
+	identity, err := os.Hostname()
+	if err != nil {
+		return leaderelection.LeaderElectionConfig{}, fmt.Errorf("failed to get hostname for leader election: %w", err)
+	}
+	return leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: LeaderElectionNamespace, Name: lease},
+			Client:     client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration:   defaultLeaseDuration,
+		RenewDeadline:   defaultRenewDeadline,
+		RetryPeriod:     defaultRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            lease,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				klog.InfoS("Started leading", "lease", lease)
+				fn(ctx)
+			},
+			OnStoppedLeading: func() {
+				klog.InfoS("Stopped leading", "lease", lease)
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection"
+)
+
+func TestNewLeaderElectionConfig(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	started := make(chan struct{})
+	cfg, err := NewLeaderElectionConfig(client, "test", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		leaderelection.RunOrDie(ctx, cfg)
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for leader")
+	}
+	lease, err := client.CoordinationV1().Leases(LeaderElectionNamespace).Get(t.Context(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, *lease.Spec.HolderIdentity)
+
+	// released on cancel
+	cancel()
+	<-done
+	lease, err = client.CoordinationV1().Leases(LeaderElectionNamespace).Get(t.Context(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, *lease.Spec.HolderIdentity)
+}
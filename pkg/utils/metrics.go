@@ -63,6 +63,8 @@ func GetMetrics(path string) (*csi.NodeGetVolumeStatsResponse, error) {
 const (
 	PodInfoFile        = "pod_info"
 	MountPointInfoFile = "mount_point_info"
+	// WarmupStatusFile is written by the fuse pod with the progress of OSS warm-up, in JSON
+	WarmupStatusFile = "warmup_status"
 )
 
 const (
@@ -71,6 +73,9 @@ const (
 	MetricsMountPointFailoverCount  = "mount_point_failover_count"
 	MetricsLastFuseClientExitReason = "last_fuse_client_exit_reason"
 
+	MetricsCredentialRotationTimestamp   = "credential_rotation_timestamp"
+	MetricsCredentialExpirationTimestamp = "credential_expiration_timestamp"
+
 	MetricsHotSpotReadFileTop  = "hot_spot_read_file_top"
 	MetricsHotSpotWriteFileTop = "hot_spot_write_file_top"
 	MetricsHotSpotHeadFileTop  = "hot_spot_head_file_top"
@@ -82,6 +87,7 @@ const (
 	MetricsLatencyCounter    = "latency_counter"
 	MetricsPosixCounter      = "posix_counter"
 	MetricsOssObjectCounter  = "oss_object_counter"
+	MetricsCacheCounter      = "cache_counter"
 )
 
 var MountpointMetricsArray = []string{
@@ -89,6 +95,8 @@ var MountpointMetricsArray = []string{
 	MetricsMountPointStatus,
 	MetricsMountPointFailoverCount,
 	MetricsLastFuseClientExitReason,
+	MetricsCredentialRotationTimestamp,
+	MetricsCredentialExpirationTimestamp,
 }
 
 var CounterTypeMetricsArray = []string{
@@ -99,6 +107,7 @@ var CounterTypeMetricsArray = []string{
 	MetricsLatencyCounter,
 	MetricsPosixCounter,
 	MetricsOssObjectCounter,
+	MetricsCacheCounter,
 }
 
 var HotSpotMetricsArray = []string{
@@ -122,7 +131,7 @@ func RemoveMetrics(
 		MountpointMetricsArray,
 		CounterTypeMetricsArray,
 		HotSpotMetricsArray,
-		{MountPointInfoFile},
+		{MountPointInfoFile, WarmupStatusFile},
 	}
 
 	// Remove all metrics files from the three arrays